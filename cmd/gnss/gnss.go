@@ -0,0 +1,1233 @@
+// Command-line tool for GNSS data, e.g. for statistics, multipath and editing of RINEX observation files and
+// merging of navigation files.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/archive"
+	"github.com/de-bkg/gognss/pkg/batch"
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/ingest"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/spp"
+	"github.com/de-bkg/gognss/pkg/synth"
+	"github.com/urfave/cli/v2"
+)
+
+const version = "v0.0.1"
+
+func main() {
+	rinex.DefaultHistory.Program = "gnss " + version
+	jsonFlag := &cli.BoolFlag{Name: "json", Usage: "print the result as JSON"}
+	outFlag := &cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "output file, default is stdout"}
+	bestFlag := &cli.BoolFlag{Name: "best", Usage: "keep one ephemeris per satellite and epoch"}
+	healthyFlag := &cli.BoolFlag{Name: "healthy", Usage: "drop the ephemerides of unhealthy satellites"}
+	progressFlag := &cli.BoolFlag{Name: "progress", Usage: "show the progress on stderr"}
+	rnxVersionFlag := &cli.Float64Flag{Name: "rinex-version", Usage: "RINEX version of the output, by default 3.04 for RINEX 2 and 2.11 for RINEX 3 input"}
+
+	app := &cli.App{
+		Version:   version,
+		Compiled:  time.Now(),
+		Copyright: "(c) 2020 BKG Frankfurt",
+		HelpName:  "gnss",
+		Usage:     "GNSS data toolkit",
+		Commands: []*cli.Command{
+			{
+				Name:  "obs",
+				Usage: "handle RINEX observation files",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "stat",
+						Usage:     "print the statistics of an observation file",
+						UsageText: "gnss obs stat [--format text|json|html] [--nav navfile] file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "format", Value: "text", Usage: "output format: text, json or html"},
+							&cli.StringFlag{Name: "nav", Usage: "broadcast navigation file to check the header position and the ephemerides of the observed satellites"},
+							jsonFlag,
+						},
+						Action: obsStat,
+					},
+					{
+						Name:      "avail",
+						Usage:     "print the hourly availability of the observations per signal",
+						UsageText: "gnss obs avail [--format text|json|html] file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "format", Value: "text", Usage: "output format: text, json or html"},
+							jsonFlag,
+						},
+						Action: obsAvail,
+					},
+					{
+						Name:      "multipath",
+						Usage:     "export the multipath combinations per satellite and signal for plotting",
+						UsageText: "gnss obs multipath [--format csv|json] [--nav navfile] [-o output] file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "format", Value: "csv", Usage: "output format: csv or json"},
+							&cli.StringFlag{Name: "nav", Usage: "broadcast navigation file to add the elevations"},
+							&cli.DurationFlag{Name: "max-gap", Value: rinex.DefaultMultipathMaxGap, Usage: "a longer data gap starts a new arc"},
+							&cli.IntFlag{Name: "min-arc", Value: rinex.DefaultMultipathMinArcEpochs, Usage: "minimum number of epochs of an arc"},
+							outFlag,
+						},
+						Action: obsMultipath,
+					},
+					{
+						Name:      "sky",
+						Usage:     "print the skyplot grids and the SNR versus elevation curves as JSON",
+						UsageText: "gnss obs sky --nav navfile [--az-step 10] [--el-step 5] [-o output] file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "nav", Required: true, Usage: "broadcast navigation file for the satellite positions"},
+							&cli.Float64Flag{Name: "az-step", Value: rinex.DefaultSkyAzStep, Usage: "azimuth bin size in degrees"},
+							&cli.Float64Flag{Name: "el-step", Value: rinex.DefaultSkyElStep, Usage: "elevation bin size in degrees"},
+							outFlag,
+						},
+						Action: obsSky,
+					},
+					{
+						Name:      "diff",
+						Usage:     "compare two observation files",
+						UsageText: "gnss obs diff [--satsys GE] file1 file2",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "satsys", Usage: "satellite systems to compare, e.g. GRE, default all"},
+							jsonFlag,
+						},
+						Action: obsDiff,
+					},
+					{
+						Name:      "headerdiff",
+						Usage:     "compare the headers of two observation files, exits with 1 on critical differences like an antenna change",
+						UsageText: "gnss obs headerdiff [--max-position-jump 5] file1 file2",
+						Flags: []cli.Flag{
+							&cli.Float64Flag{Name: "max-position-jump", Value: rinex.DefaultMaxPositionJump, Usage: "change of the approximate position in meters above which it is critical"},
+							jsonFlag,
+						},
+						Action: obsHeaderDiff,
+					},
+					{
+						Name:      "crop",
+						Usage:     "cut an observation file to a time window",
+						UsageText: "gnss obs crop [--from time] [--to time] [-o output] file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "from", Usage: "first epoch, e.g. 2020-10-20T19:00:00Z"},
+							&cli.StringFlag{Name: "to", Usage: "end of the time window, exclusive"},
+							outFlag,
+							jsonFlag,
+						},
+						Action: obsCrop,
+					},
+					{
+						Name:      "merge",
+						Usage:     "merge observation files in time order",
+						UsageText: "gnss obs merge [-o output] [--progress] file...",
+						Flags:     []cli.Flag{outFlag, jsonFlag, progressFlag},
+						Action:    obsMerge,
+					},
+					{
+						Name:      "split",
+						Usage:     "split an observation file into files of the given period",
+						UsageText: "gnss obs split [--period 1H] [--dir dir] file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "period", Value: "1H", Usage: "file period, e.g. 15M, 1H, 1D or a duration like 30m"},
+							&cli.StringFlag{Name: "dir", Value: ".", Usage: "output directory"},
+							jsonFlag,
+						},
+						Action: obsSplit,
+					},
+					{
+						Name:      "fixheader",
+						Usage:     "correct and normalize the header of observation files in place",
+						UsageText: "gnss obs fixheader [--marker-name REYK00ISL] [--antenna \"LEIAR25.R4 LEIT\"] [--position x,y,z] file...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "marker-name", Usage: "marker name"},
+							&cli.StringFlag{Name: "marker-number", Usage: "marker number, e.g. the DOMES number"},
+							&cli.StringFlag{Name: "receiver", Usage: "receiver type"},
+							&cli.StringFlag{Name: "receiver-number", Usage: "receiver serial number"},
+							&cli.StringFlag{Name: "receiver-version", Usage: "receiver firmware version"},
+							&cli.StringFlag{Name: "antenna", Usage: "antenna type incl. radome"},
+							&cli.StringFlag{Name: "antenna-number", Usage: "antenna serial number"},
+							&cli.StringFlag{Name: "position", Usage: "approximate position X,Y,Z in meters"},
+							&cli.BoolFlag{Name: "no-normalize", Usage: "only set the given fields, see ObsHeader.Normalize"},
+						},
+						Action: obsFixHeader,
+					},
+					{
+						Name:      "patchheader",
+						Usage:     "replace header records of observation files in place, the data and the compression are kept, .Z files are replaced by .gz",
+						UsageText: "gnss obs patchheader --set \"MARKER NAME=REYK00ISL\" [--set \"LABEL=value\"]... file...",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "set", Usage: "header record `LABEL=value`, an empty value removes the records", Required: true},
+						},
+						Action: obsPatchHeader,
+					},
+					{
+						Name:      "convert",
+						Usage:     "convert an observation file between RINEX 2 and RINEX 3",
+						UsageText: "gnss obs convert [--rinex-version 2.11] [--attr G:L2=L] [--priority G:2=LW] [--nav file] [-o output] [--progress] file",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "attr", Usage: "RINEX 3 attribute of a RINEX 2 code, e.g. G:L2=L for L2C receivers"},
+							&cli.StringSliceFlag{Name: "priority", Usage: "RINEX 3 attributes of a band in order of preference for RINEX 2 output, e.g. G:2=LW"},
+							&cli.StringFlag{Name: "nav", Usage: "GLONASS navigation file for the GLONASS SLOT / FRQ # record"},
+							rnxVersionFlag,
+							outFlag,
+							jsonFlag,
+							progressFlag,
+						},
+						Action: obsConvert,
+					},
+				},
+			},
+			{
+				Name:  "nav",
+				Usage: "handle RINEX navigation files",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "merge",
+						Usage:     "merge navigation files and remove duplicate ephemerides",
+						UsageText: "gnss nav merge [-o output] [--best] [--healthy] file...",
+						Flags:     []cli.Flag{outFlag, bestFlag, healthyFlag, jsonFlag},
+						Action:    navMerge,
+					},
+					{
+						Name:      "brdc",
+						Usage:     "build the daily broadcast file of all systems from the navigation files of a directory",
+						UsageText: "gnss nav brdc [--name BRDC00WRD] [--source S] [--dir dir] [--out dir] day",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "name", Value: "BRDC00WRD", Usage: "nine char ID of the daily file"},
+							&cli.StringFlag{Name: "source", Value: "S", Usage: "data source: R for receiver, S for stream or U"},
+							&cli.StringFlag{Name: "run-by", Usage: "agency of the PGM / RUN BY / DATE record"},
+							&cli.StringFlag{Name: "dir", Value: ".", Usage: "directory of the navigation files"},
+							&cli.StringFlag{Name: "out", Value: ".", Usage: "output directory"},
+							bestFlag, healthyFlag, jsonFlag,
+						},
+						Action: navBRDC,
+					},
+					{
+						Name:      "convert",
+						Usage:     "convert a RINEX 2 navigation file to RINEX 3",
+						UsageText: "gnss nav convert [-o output] file",
+						Flags:     []cli.Flag{rnxVersionFlag, outFlag, jsonFlag},
+						Action:    navConvert,
+					},
+				},
+			},
+			{
+				Name:      "batch",
+				Usage:     "process many files with a chain of steps, resumable with a state file",
+				UsageText: "gnss batch [--steps decompress,convert,qc,compress,rename,move] [--dest dir] [--state file] [--report file] files...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "steps", Value: "decompress,convert,qc,compress,rename,move", Usage: "the chain of steps, of decompress, validate, convert, qc, compress, rename and move"},
+					&cli.StringFlag{Name: "dest", Usage: "destination directory of the move step, default the directory of the input"},
+					&cli.StringFlag{Name: "work-dir", Usage: "directory of the intermediate files, default the system's temp directory"},
+					&cli.StringFlag{Name: "state", Usage: "state file to resume an interrupted run and skip finished files"},
+					&cli.StringFlag{Name: "report", Usage: "JSON report of the run, default is stdout"},
+					&cli.IntFlag{Name: "workers", Value: batch.DefaultWorkers, Usage: "number of files processed in parallel"},
+					&cli.BoolFlag{Name: "retry-failed", Usage: "process the files again that failed in a previous run"},
+					&cli.Float64Flag{Name: "min-availability", Usage: "percentage of the expected epochs below which the qc step fails"},
+					&cli.BoolFlag{Name: "hatanaka", Usage: "Hatanaka compress observation files before gzip, requires RNX2CRX"},
+					&cli.StringSliceFlag{Name: "country", Usage: "country code or nine char ID for renaming RINEX 2 files, e.g. BRST=FRA"},
+					rnxVersionFlag,
+				},
+				Action: batchRun,
+			},
+			{
+				Name:      "ingest",
+				Usage:     "watch incoming directories and file the checked files into the archive, bad files into quarantine",
+				UsageText: "gnss ingest --incoming dir --archive dir --quarantine dir [--steps validate,qc,rename] [--once]",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "incoming", Required: true, Usage: "incoming directory, repeatable"},
+					&cli.StringFlag{Name: "archive", Required: true, Usage: "root directory of the archive"},
+					&cli.StringFlag{Name: "layout", Value: "site", Usage: "path of a file in the archive: site (YYYY/DOY/site), igs, bkg or a template like {{.Year}}/{{.Doy}}/{{.Name}}"},
+					&cli.StringFlag{Name: "quarantine", Required: true, Usage: "directory of the bad files and their reasons log"},
+					&cli.StringFlag{Name: "steps", Value: "validate,qc,rename", Usage: "the checks of a file, of the batch steps"},
+					&cli.StringFlag{Name: "work-dir", Usage: "directory of the intermediate files, default the system's temp directory"},
+					&cli.IntFlag{Name: "workers", Value: batch.DefaultWorkers, Usage: "number of files processed in parallel"},
+					&cli.DurationFlag{Name: "min-age", Value: ingest.DefaultMinAge, Usage: "time a file must be unmodified before it is picked up"},
+					&cli.DurationFlag{Name: "rescan", Value: ingest.DefaultRescan, Usage: "interval of the periodic rescan of the incoming directories"},
+					&cli.BoolFlag{Name: "once", Usage: "ingest the files once and exit, e.g. for a cron job"},
+					&cli.Float64Flag{Name: "min-availability", Usage: "percentage of the expected epochs below which the qc step fails"},
+					&cli.BoolFlag{Name: "hatanaka", Usage: "Hatanaka compress observation files before gzip, requires RNX2CRX"},
+					&cli.StringSliceFlag{Name: "country", Usage: "country code or nine char ID for renaming RINEX 2 files, e.g. BRST=FRA"},
+					rnxVersionFlag,
+					jsonFlag,
+				},
+				Action: ingestRun,
+			},
+			{
+				Name:      "latency",
+				Usage:     "latency statistics per station of the files, the time between the nominal end of the data and the arrival",
+				UsageText: "gnss latency [--limit 10m] [--json] files...",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{Name: "limit", Usage: "files with a higher latency are counted as late"},
+					jsonFlag,
+				},
+				Action: latency,
+			},
+			{
+				Name:      "synth",
+				Usage:     "generate a synthetic observation and navigation file for test corpora",
+				UsageText: "gnss synth --start 2020-06-17 [--duration 1h] [--interval 30s] [--satsys GE] [--noise] [--seed 1] [--dir .]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "start", Required: true, Usage: "GPS time of the first epoch, e.g. 2020-06-17T00:00:00Z"},
+					&cli.DurationFlag{Name: "duration", Value: synth.DefaultDuration, Usage: "time span of the epochs"},
+					&cli.DurationFlag{Name: "interval", Value: synth.DefaultInterval, Usage: "sampling interval"},
+					&cli.StringFlag{Name: "station", Value: synth.DefaultStation.Name, Usage: "nine char station name"},
+					&cli.StringFlag{Name: "position", Usage: "station position X,Y,Z in meters"},
+					&cli.StringFlag{Name: "satsys", Value: "GE", Usage: "satellite systems, G and E are supported"},
+					&cli.BoolFlag{Name: "noise", Usage: "add the noise of a geodetic receiver"},
+					&cli.BoolFlag{Name: "atmosphere", Usage: "add the ionospheric and tropospheric delays"},
+					&cli.Int64Flag{Name: "seed", Usage: "seed of the noise, the satellite clocks and the phase ambiguities"},
+					&cli.StringFlag{Name: "dir", Value: ".", Usage: "output directory"},
+				},
+				Action: synthFiles,
+			},
+		},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func obsStat(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("stat needs a file as argument", 1)
+	}
+	format, err := rinex.ParseReportFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		format = rinex.ReportJSON
+	}
+	path := c.Args().First()
+	dec, closeIn, err := openObs(path)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	var opts []rinex.ReportOption
+	if nav := c.String("nav"); nav != "" {
+		if dec.EphemerisStore, err = readEphemerides(nav); err != nil {
+			return err
+		}
+		opts = append(opts, rinex.WithPositionCheck(spp.NewSolver(dec.EphemerisStore), rinex.DefaultMaxPositionDiff))
+	}
+	rep, err := rinex.NewReport(dec, opts...)
+	if err != nil {
+		return err
+	}
+	rep.File = path
+	return rep.Write(c.App.Writer, format)
+}
+
+func obsAvail(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("avail needs a file as argument", 1)
+	}
+	format, err := rinex.ParseReportFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		format = rinex.ReportJSON
+	}
+	dec, closeIn, err := openObs(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	av, err := rinex.NewAvailability(dec)
+	if err != nil {
+		return err
+	}
+	return av.Write(c.App.Writer, format)
+}
+
+func obsMultipath(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("multipath needs a file as argument", 1)
+	}
+	write := rinex.WriteMultipathCSV
+	switch format := c.String("format"); format {
+	case "csv":
+	case "json":
+		write = rinex.WriteMultipathJSON
+	default:
+		return fmt.Errorf("invalid format: %q", format)
+	}
+	dec, closeIn, err := openObs(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	if nav := c.String("nav"); nav != "" {
+		if dec.EphemerisStore, err = readEphemerides(nav); err != nil {
+			return err
+		}
+	}
+	series, err := rinex.Multipath(dec, rinex.MultipathOptions{MaxGap: c.Duration("max-gap"), MinArcEpochs: c.Int("min-arc")})
+	if err != nil {
+		return err
+	}
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	err = write(w, series)
+	if err := closeOut(); err != nil {
+		return err
+	}
+	return err
+}
+
+func obsSky(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("sky needs a file as argument", 1)
+	}
+	dec, closeIn, err := openObs(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	if dec.EphemerisStore, err = readEphemerides(c.String("nav")); err != nil {
+		return err
+	}
+	st, err := rinex.SkyPlot(dec, rinex.SkyOptions{AzStep: c.Float64("az-step"), ElStep: c.Float64("el-step")})
+	if err != nil {
+		return err
+	}
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	err = printJSON(w, st)
+	if err := closeOut(); err != nil {
+		return err
+	}
+	return err
+}
+
+func obsDiff(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.Exit("diff needs two files to compare", 1)
+	}
+	// the filenames do not need to follow the RINEX conventions
+	obs1 := &rinex.ObsFile{RnxFil: &rinex.RnxFil{Path: c.Args().Get(0)}}
+	obs2 := &rinex.ObsFile{RnxFil: &rinex.RnxFil{Path: c.Args().Get(1)}}
+	satSys, err := gnss.ParseSystemSet(strings.ToUpper(c.String("satsys")))
+	if err != nil {
+		return err
+	}
+	obs1.Opts.SatSys = satSys
+	diffs, err := obs1.Differences(obs2)
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		if diffs == nil {
+			diffs = []string{}
+		}
+		return printJSON(c.App.Writer, struct {
+			Diffs []string `json:"diffs"`
+		}{diffs})
+	}
+	for _, diff := range diffs {
+		fmt.Fprintln(c.App.Writer, diff)
+	}
+	return nil
+}
+
+func obsHeaderDiff(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.Exit("headerdiff needs two files to compare", 1)
+	}
+	var hdrs [2]*rinex.ObsHeader
+	for i, path := range c.Args().Slice() {
+		dec, closeIn, err := openObs(path)
+		if err != nil {
+			return err
+		}
+		hdrs[i] = &dec.Header
+		closeIn()
+	}
+	diffs := rinex.HeaderDiffWithOptions(hdrs[0], hdrs[1], rinex.HeaderDiffOptions{MaxPositionJump: c.Float64("max-position-jump")})
+	if c.Bool("json") {
+		if diffs == nil {
+			diffs = []rinex.HeaderFieldDiff{}
+		}
+		if err := printJSON(c.App.Writer, struct {
+			Diffs []rinex.HeaderFieldDiff `json:"diffs"`
+		}{diffs}); err != nil {
+			return err
+		}
+	} else {
+		for _, diff := range diffs {
+			fmt.Fprintln(c.App.Writer, diff)
+		}
+	}
+	if sev, ok := rinex.MaxSeverity(diffs); ok && sev == rinex.SeverityCritical {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// summary is printed by the editing commands with --json. crop and merge write it to stderr, as stdout
+// may carry the RINEX data.
+type summary struct {
+	Epochs      int      `json:"epochs,omitempty"`
+	Ephemerides int      `json:"ephemerides,omitempty"`
+	Output      string   `json:"output,omitempty"`
+	Files       []string `json:"files,omitempty"`
+}
+
+func obsCrop(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("crop needs a file as argument", 1)
+	}
+	var from, to time.Time
+	var err error
+	if s := c.String("from"); s != "" {
+		if from, err = parseTime(s); err != nil {
+			return err
+		}
+	}
+	if s := c.String("to"); s != "" {
+		if to, err = parseTime(s); err != nil {
+			return err
+		}
+	}
+
+	dec, closeIn, err := openObs(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	n, err := rinex.CropObs(w, dec, from, to)
+	if err := closeOut(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(os.Stderr, summary{Epochs: n, Output: c.String("output")})
+	}
+	return nil
+}
+
+func obsMerge(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return cli.Exit("merge needs at least two files", 1)
+	}
+	progress := newProgress(c)
+	var decs []*rinex.ObsDecoder
+	for _, path := range c.Args().Slice() {
+		dec, closeIn, err := openObsWithProgress(path, progress)
+		if err != nil {
+			return err
+		}
+		defer closeIn()
+		decs = append(decs, dec)
+	}
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	stop := showProgress(progress)
+	n, err := rinex.MergeObs(w, decs...)
+	stop()
+	if err := closeOut(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(os.Stderr, summary{Epochs: n, Output: c.String("output")})
+	}
+	return nil
+}
+
+func obsSplit(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("split needs a file as argument", 1)
+	}
+	period, err := parsePeriod(c.String("period"))
+	if err != nil {
+		return err
+	}
+	path := c.Args().First()
+	dec, closeIn, err := openObs(path)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	var files []string
+	_, err = rinex.SplitObs(dec, period, func(start time.Time) (io.WriteCloser, error) {
+		name := filepath.Join(c.String("dir"), splitFilename(path, start, period))
+		files = append(files, name)
+		return os.Create(name)
+	})
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(c.App.Writer, summary{Files: files})
+	}
+	for _, name := range files {
+		fmt.Fprintln(c.App.Writer, name)
+	}
+	return nil
+}
+
+func obsFixHeader(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.Exit("fixheader needs at least one file", 1)
+	}
+	var pos *rinex.Coord
+	if s := c.String("position"); s != "" {
+		coord, err := parseCoord(s)
+		if err != nil {
+			return err
+		}
+		pos = &coord
+	}
+
+	for _, path := range c.Args().Slice() {
+		dec, closeIn, err := openObs(path)
+		if err != nil {
+			return err
+		}
+		hdr := dec.Header
+		closeIn()
+
+		for flag, field := range map[string]*string{
+			"marker-name": &hdr.MarkerName, "marker-number": &hdr.MarkerNumber,
+			"receiver": &hdr.ReceiverType, "receiver-number": &hdr.ReceiverNumber, "receiver-version": &hdr.ReceiverVersion,
+			"antenna": &hdr.AntennaType, "antenna-number": &hdr.AntennaNumber,
+		} {
+			if c.IsSet(flag) {
+				*field = c.String(flag)
+			}
+		}
+		if pos != nil {
+			hdr.Position = *pos
+		}
+		if !c.Bool("no-normalize") {
+			hdr.Normalize()
+		}
+		obsFil := &rinex.ObsFile{RnxFil: &rinex.RnxFil{Path: path}}
+		if err := obsFil.FixHeader(hdr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func obsPatchHeader(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.Exit("patchheader needs at least one file", 1)
+	}
+	changes := make(map[string]string)
+	for _, set := range c.StringSlice("set") {
+		i := strings.IndexByte(set, '=')
+		if i < 0 {
+			return fmt.Errorf("invalid header record: %q", set)
+		}
+		label, val := set[:i], set[i+1:]
+		if prev, ok := changes[label]; ok {
+			val = prev + "\n" + val
+		}
+		changes[label] = val
+	}
+	for _, path := range c.Args().Slice() {
+		obsFil := &rinex.ObsFile{RnxFil: &rinex.RnxFil{Path: path}}
+		if err := obsFil.PatchHeader(changes); err != nil {
+			return err
+		}
+		if obsFil.Path != path {
+			log.Printf("%s: replaced by %s", path, obsFil.Path)
+		}
+	}
+	return nil
+}
+
+func obsConvert(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("convert needs a file as argument", 1)
+	}
+	opts := rinex.ConvertOptions{Version: float32(c.Float64("rinex-version")), Mapper: rinex.NewObsCodeMapper()}
+	for _, attr := range c.StringSlice("attr") {
+		// G:L2=L
+		if len(attr) != 6 || attr[1] != ':' || attr[4] != '=' {
+			return fmt.Errorf("invalid attribute: %q", attr)
+		}
+		sys, ok := gnss.SystemByAbbr(attr[:1])
+		if !ok {
+			return fmt.Errorf("invalid satellite system: %q", attr)
+		}
+		if opts.Mapper.Attributes[sys] == nil {
+			opts.Mapper.Attributes[sys] = make(map[string]byte)
+		}
+		opts.Mapper.Attributes[sys][attr[2:4]] = attr[5]
+	}
+	for _, prio := range c.StringSlice("priority") {
+		// G:2=LW
+		if len(prio) < 5 || prio[1] != ':' || prio[3] != '=' {
+			return fmt.Errorf("invalid priority: %q", prio)
+		}
+		sys, ok := gnss.SystemByAbbr(prio[:1])
+		if !ok {
+			return fmt.Errorf("invalid satellite system: %q", prio)
+		}
+		if opts.Mapper.Priorities[sys] == nil {
+			opts.Mapper.Priorities[sys] = make(map[byte]string)
+		}
+		opts.Mapper.Priorities[sys][prio[2]] = prio[4:]
+	}
+	if nav := c.String("nav"); nav != "" {
+		slots, err := readGloSlots(nav)
+		if err != nil {
+			return err
+		}
+		opts.GloSlots = slots
+	}
+
+	progress := newProgress(c)
+	dec, closeIn, err := openObsWithProgress(c.Args().First(), progress)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	stop := showProgress(progress)
+	n, err := rinex.ConvertObs(w, dec, opts)
+	stop()
+	if err := closeOut(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(os.Stderr, summary{Epochs: n, Output: c.String("output")})
+	}
+	return nil
+}
+
+func navMerge(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return cli.Exit("merge needs at least two files", 1)
+	}
+	var decs []*rinex.NavDecoder
+	for _, path := range c.Args().Slice() {
+		r, err := rinex.OpenFile(path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		dec, err := rinex.NewNavDecoder(r)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		dec.Name = filepath.Base(path)
+		decs = append(decs, dec)
+	}
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	opts := rinex.NavMergeOptions{Best: c.Bool("best"), HealthyOnly: c.Bool("healthy")}
+	n, err := rinex.MergeNav(w, opts, decs...)
+	if err := closeOut(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(os.Stderr, summary{Ephemerides: n, Output: c.String("output")})
+	}
+	return nil
+}
+
+func navBRDC(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("brdc needs the day as argument, e.g. 2020-06-17", 1)
+	}
+	day, err := parseTime(c.Args().First())
+	if err != nil {
+		return err
+	}
+	b := &rinex.BRDCBuilder{Name: c.String("name"), DataSource: c.String("source"), RunBy: c.String("run-by"),
+		Best: c.Bool("best"), HealthyOnly: c.Bool("healthy")}
+	path, n, err := b.BuildDir(c.String("dir"), c.String("out"), day)
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(c.App.Writer, summary{Ephemerides: n, Output: path})
+	}
+	fmt.Fprintln(c.App.Writer, path)
+	return nil
+}
+
+func navConvert(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("convert needs a file as argument", 1)
+	}
+	path := c.Args().First()
+	r, err := rinex.OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	dec, err := rinex.NewNavDecoder(r)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	dec.Name = filepath.Base(path)
+	w, closeOut, err := createOutput(c)
+	if err != nil {
+		return err
+	}
+	n, err := rinex.ConvertNav(w, dec, rinex.ConvertOptions{Version: float32(c.Float64("rinex-version"))})
+	if err := closeOut(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		return printJSON(os.Stderr, summary{Ephemerides: n, Output: c.String("output")})
+	}
+	return nil
+}
+
+func batchRun(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.Exit("batch needs files as arguments", 1)
+	}
+	steps, err := batchSteps(c)
+	if err != nil {
+		return err
+	}
+	r := &batch.Runner{
+		Steps:       steps,
+		Workers:     c.Int("workers"),
+		WorkDir:     c.String("work-dir"),
+		OutDir:      c.String("dest"),
+		StateFile:   c.String("state"),
+		RetryFailed: c.Bool("retry-failed"),
+		OnJob: func(job batch.Job) {
+			if job.Status == batch.StatusFailed {
+				log.Printf("%s: %s", job.Input, job.Err)
+			}
+		},
+	}
+
+	// finish the running steps on Ctrl-C, the run is resumed with the state file
+	ctx, cancel := interruptContext()
+	defer cancel()
+	rep, err := r.Run(ctx, c.Args().Slice())
+	if rep != nil {
+		w := c.App.Writer
+		if path := c.String("report"); path != "" {
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := rep.Write(w); err != nil {
+			return err
+		}
+		if rep.Failed > 0 && err == nil {
+			return cli.Exit(fmt.Sprintf("%d of %d files failed", rep.Failed, rep.Total), 1)
+		}
+	}
+	return err
+}
+
+func ingestRun(c *cli.Context) error {
+	steps, err := batchSteps(c)
+	if err != nil {
+		return err
+	}
+	layout := archive.Layout(c.String("layout"))
+	if l, ok := archive.Layouts[c.String("layout")]; ok {
+		layout = l
+	}
+	in := &ingest.Ingester{
+		Dirs:       c.StringSlice("incoming"),
+		Archive:    c.String("archive"),
+		Layout:     layout,
+		Quarantine: c.String("quarantine"),
+		Steps:      steps,
+		Workers:    c.Int("workers"),
+		WorkDir:    c.String("work-dir"),
+		MinAge:     c.Duration("min-age"),
+		Rescan:     c.Duration("rescan"),
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if c.Bool("once") {
+		results, err := in.Scan(ctx)
+		if err != nil {
+			return err
+		}
+		if c.Bool("json") {
+			return printJSON(c.App.Writer, results)
+		}
+		return nil
+	}
+	if err := in.Run(ctx); err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+func latency(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.Exit("latency needs files as arguments", 1)
+	}
+	var lats []rinex.FileLatency
+	for _, path := range c.Args().Slice() {
+		l, err := rinex.Latency(path)
+		if err != nil {
+			log.Printf("%v", err)
+			continue
+		}
+		lats = append(lats, l)
+	}
+	stats := rinex.LatencyStatsByStation(lats, c.Duration("limit"))
+	if c.Bool("json") {
+		return printJSON(c.App.Writer, stats)
+	}
+	fmt.Fprintf(c.App.Writer, "%-9s %6s %6s %7s %9s %9s %9s %9s %9s\n", "station", "files", "late", "ontime%", "min[s]", "mean[s]", "median[s]", "p95[s]", "max[s]")
+	for _, st := range stats {
+		fmt.Fprintf(c.App.Writer, "%-9s %6d %6d %7.1f %9.0f %9.0f %9.0f %9.0f %9.0f\n", st.Station, st.Files, st.Late, st.OnTime,
+			st.Min, st.Mean, st.Median, st.P95, st.Max)
+	}
+	return nil
+}
+
+func synthFiles(c *cli.Context) error {
+	start, err := parseTime(c.String("start"))
+	if err != nil {
+		return err
+	}
+	satSys, err := gnss.ParseSystemSet(strings.ToUpper(c.String("satsys")))
+	if err != nil {
+		return err
+	}
+	cfg := synth.Config{
+		Station:    synth.DefaultStation,
+		Signals:    map[gnss.System][]string{},
+		Start:      start,
+		Interval:   c.Duration("interval"),
+		Duration:   c.Duration("duration"),
+		Atmosphere: c.Bool("atmosphere"),
+		Seed:       c.Int64("seed"),
+	}
+	cfg.Station.Name = strings.ToUpper(c.String("station"))
+	if s := c.String("position"); s != "" {
+		if cfg.Station.Position, err = parseCoord(s); err != nil {
+			return err
+		}
+	}
+	for _, sys := range satSys.Systems() {
+		cfg.Signals[sys] = synth.DefaultSignals[sys]
+	}
+	if c.Bool("noise") {
+		cfg.Noise = synth.DefaultNoise
+	}
+	gen, err := synth.New(cfg)
+	if err != nil {
+		return err
+	}
+	obsPath, navPath, err := gen.WriteFiles(c.String("dir"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.App.Writer, obsPath)
+	fmt.Fprintln(c.App.Writer, navPath)
+	return nil
+}
+
+// batchSteps returns the chain of batch steps of the --steps flag.
+func batchSteps(c *cli.Context) ([]batch.Step, error) {
+	countries := rinex.CountryMap{}
+	for _, cc := range c.StringSlice("country") {
+		kv := strings.SplitN(cc, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid country: %q", cc)
+		}
+		countries[strings.ToUpper(kv[0])] = kv[1]
+	}
+
+	var steps []batch.Step
+	for _, name := range strings.Split(c.String("steps"), ",") {
+		switch strings.TrimSpace(name) {
+		case "decompress":
+			steps = append(steps, batch.Decompress())
+		case "validate":
+			steps = append(steps, batch.Validate())
+		case "convert":
+			steps = append(steps, batch.Convert(rinex.ConvertOptions{Version: float32(c.Float64("rinex-version"))}))
+		case "qc":
+			steps = append(steps, batch.QC(c.Float64("min-availability")))
+		case "compress":
+			steps = append(steps, batch.Compress(c.Bool("hatanaka")))
+		case "rename":
+			steps = append(steps, batch.Rename(rinex.FilenameConverter{Country: countries.Lookup}))
+		case "move":
+			if c.String("dest") == "" {
+				return nil, cli.Exit("the move step needs --dest", 1)
+			}
+			steps = append(steps, batch.Move(c.String("dest")))
+		default:
+			return nil, fmt.Errorf("invalid step: %q", name)
+		}
+	}
+	return steps, nil
+}
+
+// interruptContext returns a context that is canceled on Ctrl-C, to finish the running steps.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, cancel
+}
+
+// openObs opens the, possibly compressed, observation file and returns its decoder.
+func openObs(path string) (*rinex.ObsDecoder, func() error, error) {
+	return openObsWithProgress(path, nil)
+}
+
+// openObsWithProgress opens the observation file like openObs and reports the bytes read from the file
+// and the epochs to p, if it is not nil.
+func openObsWithProgress(path string, p *rinex.ProgressTracker) (*rinex.ObsDecoder, func() error, error) {
+	var r io.ReadCloser
+	var err error
+	var opts []rinex.Option
+	if p != nil {
+		r, err = rinex.OpenFileWithProgress(path, p)
+		opts = append(opts, rinex.WithProgress(p))
+	} else {
+		r, err = rinex.OpenFile(path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	dec, err := rinex.NewObsDecoder(r, opts...)
+	if err != nil {
+		r.Close()
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	dec.Name = filepath.Base(path)
+	return dec, r.Close, nil
+}
+
+// readEphemerides reads the broadcast ephemerides of the navigation file.
+func readEphemerides(path string) (*rinex.EphemerisStore, error) {
+	r, err := rinex.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	dec, err := rinex.NewNavDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	ephs, err := rinex.NewEphemerides(dec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return ephs, nil
+}
+
+// readGloSlots reads the GLONASS frequency channels of the navigation file.
+func readGloSlots(path string) (rinex.GloSlots, error) {
+	r, err := rinex.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	dec, err := rinex.NewNavDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var ephs []rinex.Eph
+	for dec.NextEphemeris() {
+		ephs = append(ephs, dec.Ephemeris())
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return rinex.NewGloSlots(ephs), nil
+}
+
+// createOutput returns the writer for the --output flag, stdout if not set.
+func createOutput(c *cli.Context) (io.Writer, func() error, error) {
+	path := c.String("output")
+	if path == "" {
+		return c.App.Writer, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// parseTime parses a time in RFC3339 format or as "2006-01-02 15:04:05" in UTC.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q", s)
+}
+
+// parseCoord parses a position given as X,Y,Z in meters.
+func parseCoord(s string) (rinex.Coord, error) {
+	xyz := strings.Split(s, ",")
+	if len(xyz) != 3 {
+		return rinex.Coord{}, fmt.Errorf("invalid position: %q", s)
+	}
+	var vals [3]float64
+	for i, v := range xyz {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return rinex.Coord{}, fmt.Errorf("invalid position: %q", s)
+		}
+		vals[i] = f
+	}
+	return rinex.Coord{X: vals[0], Y: vals[1], Z: vals[2]}, nil
+}
+
+// parsePeriod parses a RINEX filename period like 15M, 1H or 1D, or a Go duration like 30m.
+func parsePeriod(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'M': time.Minute, 'H': time.Hour, 'D': 24 * time.Hour}
+	if len(s) > 1 {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n > 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period: %q", s)
+	}
+	return d, nil
+}
+
+// periodCode returns the RINEX 3 filename code of the period, e.g. 15M or 01D.
+func periodCode(period time.Duration) string {
+	switch {
+	case period%(24*time.Hour) == 0:
+		return fmt.Sprintf("%02dD", int(period/(24*time.Hour)))
+	case period%time.Hour == 0:
+		return fmt.Sprintf("%02dH", int(period/time.Hour))
+	}
+	return fmt.Sprintf("%02dM", int(period/time.Minute))
+}
+
+// splitFilename returns the name of a piece of the file path. RINEX 3 names get the start time and period
+// of the piece, other names the start time.
+func splitFilename(path string, start time.Time, period time.Duration) string {
+	fi, err := rinex.ParseFilename(path)
+	base := filepath.Base(path)
+	if err == nil && fi.CountryCode != "" && len(base) >= 34 {
+		return fmt.Sprintf("%s%s%03d%s_%s%s.rnx", base[:12], start.Format("2006"), start.YearDay(),
+			start.Format("1504"), periodCode(period), base[27:34])
+	}
+	if i := strings.IndexByte(base, '.'); i > 0 {
+		base = base[:i]
+	}
+	return fmt.Sprintf("%s_%s.rnx", base, start.Format("200601021504"))
+}
+
+// newProgress returns a progress tracker if the progress flag is set, otherwise nil.
+func newProgress(c *cli.Context) *rinex.ProgressTracker {
+	if !c.Bool("progress") {
+		return nil
+	}
+	return rinex.NewProgressTracker()
+}
+
+// showProgress prints the progress to stderr every second until stop is called. A nil tracker prints nothing.
+func showProgress(p *rinex.ProgressTracker) (stop func()) {
+	if p == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				printProgress(os.Stderr, p.Status())
+			case <-done:
+				printProgress(os.Stderr, p.Status())
+				fmt.Fprintln(os.Stderr)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// printProgress prints the progress in one line, overwriting the previous one.
+func printProgress(w io.Writer, st rinex.ProgressStatus) {
+	pct, eta := "    ?", "?"
+	if p := st.Percent(); p >= 0 {
+		pct, eta = fmt.Sprintf("%5.1f", p), st.ETA.Round(time.Second).String()
+	}
+	fmt.Fprintf(w, "\r%s%% %d epochs, %s elapsed, ETA %s   ", pct, st.Epochs, st.Elapsed.Round(time.Second), eta)
+}
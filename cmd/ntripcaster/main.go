@@ -0,0 +1,234 @@
+// ntripcaster runs an Ntrip 2.0 caster with the mountpoints and credentials from a YAML config file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/caster"
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	version = "0.1"
+)
+
+// Config is the caster configuration.
+type Config struct {
+	Listen string `yaml:"listen"` // listen address, defaults to :2101
+	TLS    struct {
+		Cert string `yaml:"cert"`
+		Key  string `yaml:"key"`
+	} `yaml:"tls"`
+	Inspect     time.Duration      `yaml:"inspect"` // inspection of the uploaded streams, 0 disables it
+	Mountpoints []MountpointConfig `yaml:"mountpoints"`
+	Users       []UserConfig       `yaml:"users"`   // for the mountpoints without own users
+	AuthURL     string             `yaml:"authURL"` // external authentication service instead of users
+	Relay       RelayConfig        `yaml:"relay"`
+}
+
+// RelayConfig configures the failover of the relay mountpoints.
+type RelayConfig struct {
+	Timeout       time.Duration `yaml:"timeout"`       // switch to the next upstream without data
+	CheckInterval time.Duration `yaml:"checkInterval"` // health checks of the preferred upstreams
+}
+
+// UserConfig configures a user with permissions and limits, see caster.User.
+type UserConfig struct {
+	Name           string   `yaml:"name"`
+	Password       string   `yaml:"password"`
+	Mountpoints    []string `yaml:"mountpoints"`
+	MaxConnections int      `yaml:"maxConnections"`
+	Quota          int64    `yaml:"quota"` // bytes per day
+}
+
+// MountpointConfig configures a mountpoint and its sourcetable record.
+type MountpointConfig struct {
+	Name          string            `yaml:"name"`
+	Identifier    string            `yaml:"identifier"`
+	Format        string            `yaml:"format"`
+	FormatDetails string            `yaml:"formatDetails"`
+	Carrier       int               `yaml:"carrier"`
+	SatSystem     []string          `yaml:"satSystem"`
+	Network       string            `yaml:"network"`
+	Country       string            `yaml:"country"`
+	Lat           float32           `yaml:"lat"`
+	Lon           float32           `yaml:"lon"`
+	Generator     string            `yaml:"generator"`
+	Bitrate       int               `yaml:"bitrate"`
+	Source        Credentials       `yaml:"source"`
+	Users         map[string]string `yaml:"users"` // username: password
+	MaxClients    int               `yaml:"maxClients"`
+	Upstreams     []UpstreamConfig  `yaml:"upstreams"` // relay, the preferred upstream first
+}
+
+// UpstreamConfig configures a remote stream of a relay mountpoint, see caster.Upstream.
+type UpstreamConfig struct {
+	Caster     string `yaml:"caster"` // e.g. ntrips://caster.example.com:443
+	Mountpoint string `yaml:"mountpoint"`
+	User       string `yaml:"user"`
+	Password   string `yaml:"password"`
+}
+
+// Credentials are a username and password.
+type Credentials struct {
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+}
+
+func main() {
+	fs := flag.NewFlagSet("ntripcaster/"+version, flag.ExitOnError)
+	confPath := fs.String("config", "ntripcaster.yml", "Configuration file.")
+	listen := fs.String("listen", "", "Listen address, overrides the configuration, e.g. :2101.")
+
+	fs.Usage = func() {
+		fmt.Println(`ntripcaster - run an Ntrip 2.0 caster
+
+Usage:
+    ntripcaster [flags]
+
+Flags:`)
+		fs.PrintDefaults()
+		fmt.Println(`
+Example configuration:
+    listen: ":2101"
+    tls:                      # optional
+      cert: server.crt
+      key: server.key
+    inspect: 10s              # fill the empty sourcetable fields from the uploaded streams
+    mountpoints:
+      - name: WTZR00DEU0
+        identifier: Wettzell
+        format: RTCM 3.3
+        country: DEU
+        lat: 49.14
+        lon: 12.88
+        source: {user: wtzr, password: secret}
+        users: {alice: pw1, bob: pw2}  # the global users if empty
+        maxClients: 100
+      - name: FFMJ00DEU0       # relay of remote streams
+        identifier: Frankfurt
+        upstreams:             # the preferred upstream first
+          - {caster: "ntrips://caster1.example.com:443", mountpoint: FFMJ00DEU0, user: u, password: p}
+          - {caster: "http://caster2.example.com:2101", mountpoint: FFMJ00DEU0}
+    relay:                    # optional
+      timeout: 30s            # switch to the next upstream without data
+      checkInterval: 5m       # health checks of the preferred upstreams
+    users:                    # optional, no authentication if empty
+      - name: carol
+        password: pw3
+        mountpoints: ["WTZR*"]  # all if empty
+        maxConnections: 2
+        quota: 100000000        # bytes per day
+    authURL: https://auth.example.com/ntrip  # optional, instead of users`)
+		fmt.Printf("\nVersion: ntripcaster %s\n", version)
+	}
+	fs.Parse(os.Args[1:])
+
+	conf, err := readConfig(*confPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if *listen != "" {
+		conf.Listen = *listen
+	}
+
+	var mounts []caster.Mountpoint
+	for _, mc := range conf.Mountpoints {
+		auth := "N"
+		if len(mc.Users) > 0 || len(conf.Users) > 0 || conf.AuthURL != "" {
+			auth = "B"
+		}
+		var ups []caster.Upstream
+		for _, uc := range mc.Upstreams {
+			ups = append(ups, caster.Upstream{Caster: uc.Caster, Mountpoint: uc.Mountpoint,
+				Options: ntrip.Options{Username: uc.User, Password: uc.Password}})
+		}
+		mounts = append(mounts, caster.Mountpoint{
+			Stream: ntrip.Stream{MP: mc.Name, Identifier: mc.Identifier, Format: mc.Format, FormatDetails: mc.FormatDetails,
+				Carrier: mc.Carrier, SatSystem: mc.SatSystem, Network: mc.Network, Country: mc.Country, Lat: mc.Lat, Lon: mc.Lon,
+				Generator: mc.Generator, Auth: auth, Bitrate: mc.Bitrate},
+			SourceUser:     mc.Source.User,
+			SourcePassword: mc.Source.Password,
+			Users:          mc.Users,
+			MaxClients:     mc.MaxClients,
+			Upstreams:      ups,
+		})
+	}
+
+	cs := caster.NewServer(mounts)
+	cs.InspectTime = conf.Inspect
+	cs.RelayTimeout, cs.RelayCheckInterval = conf.Relay.Timeout, conf.Relay.CheckInterval
+	if conf.AuthURL != "" {
+		cs.Users = &caster.HTTPAuth{URL: conf.AuthURL}
+	} else if len(conf.Users) > 0 {
+		users := make(caster.StaticUsers, len(conf.Users))
+		for _, uc := range conf.Users {
+			users[uc.Name] = caster.StaticUser{Password: uc.Password, User: caster.User{
+				Mountpoints: uc.Mountpoints, MaxConnections: uc.MaxConnections, Quota: uc.Quota}}
+		}
+		cs.Users = users
+	}
+	go cs.RunRelays(context.Background())
+	srv := &http.Server{Addr: conf.Listen, Handler: cs}
+	log.Printf("ntripcaster %s listening on %s with %d mountpoints", version, conf.Listen, len(mounts))
+	if conf.TLS.Cert != "" {
+		err = srv.ListenAndServeTLS(conf.TLS.Cert, conf.TLS.Key)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	log.Fatalf("%v", err)
+}
+
+// readConfig reads and checks the configuration file.
+func readConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := &Config{Listen: ":2101", Inspect: caster.DefaultInspectTime,
+		Relay: RelayConfig{Timeout: caster.DefaultRelayTimeout, CheckInterval: caster.DefaultRelayCheckInterval}}
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(conf.Mountpoints) == 0 {
+		return nil, fmt.Errorf("%s: no mountpoints configured", path)
+	}
+	seen := make(map[string]bool, len(conf.Mountpoints))
+	for _, mc := range conf.Mountpoints {
+		if mc.Name == "" {
+			return nil, fmt.Errorf("%s: mountpoint without name", path)
+		}
+		if seen[mc.Name] {
+			return nil, fmt.Errorf("%s: duplicate mountpoint %s", path, mc.Name)
+		}
+		seen[mc.Name] = true
+		if mc.Source.User != "" && mc.Source.Password == "" {
+			return nil, fmt.Errorf("%s: mountpoint %s: source user %s without password", path, mc.Name, mc.Source.User)
+		}
+		for _, uc := range mc.Upstreams {
+			if uc.Caster == "" || uc.Mountpoint == "" {
+				return nil, fmt.Errorf("%s: mountpoint %s: upstream without caster or mountpoint", path, mc.Name)
+			}
+		}
+	}
+	if conf.Relay.Timeout <= 0 || conf.Relay.CheckInterval <= 0 {
+		return nil, fmt.Errorf("%s: relay timeout and check interval must be positive", path)
+	}
+	for _, uc := range conf.Users {
+		if uc.Name == "" || uc.Password == "" {
+			return nil, fmt.Errorf("%s: user without name or password", path)
+		}
+	}
+	if (conf.TLS.Cert == "") != (conf.TLS.Key == "") {
+		return nil, fmt.Errorf("%s: tls needs cert and key", path)
+	}
+	return conf, nil
+}
@@ -0,0 +1,134 @@
+// rnx2rtcm replays a RINEX observation file as RTCM 3 stream, either to stdout
+// or uploaded to an NtripCaster. Instead of a file, a synthetic station can be streamed in real time.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+	"github.com/de-bkg/gognss/pkg/synth"
+)
+
+const (
+	version = "0.1"
+)
+
+func main() {
+	opts := ntrip.Options{}
+	opts.UserAgent = "NTRIP rnx2rtcm/" + version
+	fs := flag.NewFlagSet("rnx2rtcm/"+version, flag.ExitOnError)
+	navPath := fs.String("nav", "", "RINEX navigation file, the GPS ephemerides are sent as message 1019.")
+	speed := fs.Float64("speed", 1, "Replay speed factor, 0 writes the messages without pacing.")
+	stationID := fs.Uint("station", 0, "RTCM reference station ID.")
+	simulate := fs.Bool("simulate", false, "Stream a synthetic GPS and Galileo station in real time instead of a file.")
+	interval := fs.Duration("interval", time.Second, "Sampling interval of the synthetic station.")
+	casterAddr := fs.String("caster", "", "Upload the stream to the NtripCaster, e.g. http://localhost:2101.")
+	mountpoint := fs.String("mp", "", "Mountpoint on the caster.")
+	fs.StringVar(&opts.Username, "username", "", "Username to connect to the caster.")
+	fs.StringVar(&opts.Password, "pw", "", "Password.")
+
+	fs.Usage = func() {
+		fmt.Println(`rnx2rtcm - replay a RINEX observation file as RTCM 3 MSM7 stream
+
+Usage:
+    rnx2rtcm [flags] <obsfile>
+    rnx2rtcm -simulate [flags]
+
+Flags:`)
+		fs.PrintDefaults()
+		fmt.Println(`
+Examples:
+    # Write the stream as fast as possible to a file
+    $ rnx2rtcm -speed=0 -nav=BRDC00WRD_R_20201690000_01D_MN.rnx WTZR00DEU_R_20201690000_01D_30S_MO.rnx >wtzr.rtcm3
+
+    # Upload the stream in real time to a caster
+    $ rnx2rtcm -caster=http://localhost:2101 -mp=WTZR00DEU0 -username=xxx -pw=xxx WTZR00DEU_R_20201690000_01D_30S_MO.rnx
+
+    # Upload a synthetic 1 Hz stream to a caster
+    $ rnx2rtcm -simulate -caster=http://localhost:2101 -mp=SYNT00DEU0 -username=xxx -pw=xxx`)
+		fmt.Printf("\nVersion: rnx2rtcm %s\n", version)
+	}
+
+	fs.Parse(os.Args[1:])
+	if *simulate != (fs.NArg() == 0) || fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var stream func(ctx context.Context, w io.Writer) error
+	if *simulate {
+		gen, err := synth.New(synth.Config{Start: time.Now(), Interval: *interval, Noise: synth.DefaultNoise})
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		sim := synth.NewSimulator(gen, synth.SimOptions{Live: true, StationID: uint16(*stationID), Ephemerides: true})
+		stream = sim.RunRTCM
+	} else {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer f.Close()
+		dec, err := rinex.NewObsDecoder(f)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		replayOpts := rtcm3.ReplayOptions{StationID: uint16(*stationID), Speed: *speed}
+		if *navPath != "" {
+			nf, err := os.Open(*navPath)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			defer nf.Close()
+			if replayOpts.Nav, err = rinex.NewNavDecoder(nf); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		stream = func(ctx context.Context, w io.Writer) error {
+			return rtcm3.Replay(ctx, w, dec, replayOpts)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	if *casterAddr == "" {
+		if err := stream(ctx, os.Stdout); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	if *mountpoint == "" {
+		log.Fatalf("no mountpoint given")
+	}
+	c, err := ntrip.NewClient(*casterAddr, opts)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer c.CloseIdleConnections()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(stream(ctx, pw))
+	}()
+	if err := c.PostStream(*mountpoint, pr); err != nil {
+		log.Fatalf("upload to %s: %v", *mountpoint, err)
+	}
+}
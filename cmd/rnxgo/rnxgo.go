@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/de-bkg/gognss/pkg/rinex"
-	"github.com/mholt/archiver/v3"
 	"github.com/urfave/cli/v2"
 )
 
@@ -137,6 +136,38 @@ func main() {
 					return err
 				},
 			},
+			{
+				Name:      "report",
+				Usage:     "print a report of a RINEX observation file",
+				UsageText: "rnxgo report [--format text|json|html] file",
+				HelpName:  "rnxgo report",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format: text, json or html",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						fmt.Fprintf(c.App.Writer, "ERROR: missing file as argument\n\n")
+						cli.ShowCommandHelpAndExit(c, "report", 1)
+					}
+					format, err := rinex.ParseReportFormat(c.String("format"))
+					if err != nil {
+						return err
+					}
+					obsFil, err := rinex.NewObsFile(c.Args().First())
+					if err != nil {
+						return err
+					}
+					rep, err := obsFil.Report()
+					if err != nil {
+						return err
+					}
+					return rep.Write(c.App.Writer, format)
+				},
+			},
 		},
 	}
 
@@ -172,7 +203,7 @@ func compressRINEXFiles(rootdir string) error {
 			log.Printf("file is not Hatanaka compressed, decompress first: %s", path)
 			ext := filepath.Ext(path)
 			tmpPath := strings.TrimSuffix(path, ext)
-			err := archiver.DecompressFile(path, tmpPath)
+			err := rinex.DecompressFile(path, tmpPath)
 			if err != nil {
 				log.Printf("decompress file: %v", err)
 				return nil
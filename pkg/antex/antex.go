@@ -0,0 +1,401 @@
+// Package antex provides functions for reading ANTEX files, containing phase center
+// offsets (PCO) and variations (PCV) of GNSS receiver and satellite antennas.
+//
+// The format is described in "ANTEX: The Antenna Exchange Format, Version 1.4".
+package antex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoHeader is returned when reading ANTEX data that does not begin with a ANTEX Header.
+var ErrNoHeader = errors.New("ANTEX: no header")
+
+// A Header provides the ANTEX Header information.
+type Header struct {
+	Version  float32 // format version
+	SatSys   string  // satellite system code, M for mixed
+	PCVType  string  // A for absolute, R for relative values
+	RefAnt   string  // reference antenna type for relative values
+	Comments []string
+	warnings []string
+}
+
+// Antenna contains the calibration of a receiver or satellite antenna.
+type Antenna struct {
+	Type       string // antenna type incl. radome, e.g. "LEIAR25.R3      LEIT", or satellite antenna type
+	Serial     string // serial number, blank for type mean values, or satellite code sNN for satellite antennas
+	SVN        string // satellite SVN code
+	COSPAR     string // satellite COSPAR ID
+	Method     string // calibration method, e.g. ROBOT, CHAMBER, FIELD, COPIED
+	Agency     string
+	NumAnt     int // number of individual antennas calibrated
+	Date       string
+	DAzi       float64 // azimuth increment in degrees, 0 if only non-azimuth-dependent values are given
+	Zen1, Zen2 float64 // zenith, resp. nadir for satellites, grid definition in degrees
+	DZen       float64
+	ValidFrom  time.Time // only for satellite antennas
+	ValidUntil time.Time
+	SinexCode  string
+	Comments   []string
+	Freqs      []*Frequency
+}
+
+// Frequency contains the phase center offset and variations for a frequency, all given in mm.
+type Frequency struct {
+	Code  string      // satellite system and frequency, e.g. G01
+	N     float64     // north, resp. x for satellites
+	E     float64     // east, resp. y for satellites
+	Up    float64     // up, resp. z for satellites
+	NoAzi []float64   // non-azimuth-dependent pattern
+	PCV   [][]float64 // azimuth-dependent pattern, per azimuth row
+}
+
+// IsSatellite reports whether the antenna is a satellite antenna.
+func (ant *Antenna) IsSatellite() bool {
+	return ant.SVN != "" || ant.COSPAR != ""
+}
+
+// Freq returns the calibration for the frequency code, e.g. G01.
+func (ant *Antenna) Freq(code string) (*Frequency, error) {
+	for _, f := range ant.Freqs {
+		if f.Code == code {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("antenna %q: no calibration for frequency %s", ant.Type, code)
+}
+
+// PCVAt returns the phase center variation in mm for the frequency code at the given azimuth and
+// zenith (resp. nadir) angle in degrees, linearly interpolated from the grid. If the antenna has no
+// azimuth-dependent pattern, the azimuth is ignored.
+func (ant *Antenna) PCVAt(code string, azi, zen float64) (float64, error) {
+	f, err := ant.Freq(code)
+	if err != nil {
+		return 0, err
+	}
+
+	if ant.DZen <= 0 {
+		return 0, fmt.Errorf("antenna %q: invalid zenith increment %.1f", ant.Type, ant.DZen)
+	}
+	if zen < ant.Zen1 || zen > ant.Zen2 {
+		return 0, fmt.Errorf("antenna %q: zenith angle %.1f out of range", ant.Type, zen)
+	}
+	q := (zen - ant.Zen1) / ant.DZen
+
+	if ant.DAzi == 0 || len(f.PCV) == 0 {
+		return interpolate(f.NoAzi, q)
+	}
+
+	azi = math.Mod(azi, 360)
+	if azi < 0 {
+		azi += 360
+	}
+	p := azi / ant.DAzi
+	i := int(p)
+	if i >= len(f.PCV)-1 {
+		i = len(f.PCV) - 2
+	}
+	v1, err := interpolate(f.PCV[i], q)
+	if err != nil {
+		return 0, err
+	}
+	v2, err := interpolate(f.PCV[i+1], q)
+	if err != nil {
+		return 0, err
+	}
+	p -= float64(i)
+	return (1-p)*v1 + p*v2, nil
+}
+
+// interpolate linearly interpolates the values at the fractional index q.
+func interpolate(vals []float64, q float64) (float64, error) {
+	if len(vals) == 0 || q < 0 || q > float64(len(vals)-1) {
+		return 0, fmt.Errorf("index %.2f out of range", q)
+	}
+	i := int(q)
+	if i == len(vals)-1 {
+		return vals[i], nil
+	}
+	q -= float64(i)
+	return (1-q)*vals[i] + q*vals[i+1], nil
+}
+
+// Decoder reads and decodes header and antennas from an ANTEX input stream.
+type Decoder struct {
+	// The Header is valid after NewDecoder. The header must exist.
+	Header Header
+
+	sc      *bufio.Scanner
+	ant     *Antenna
+	lineNum int
+	err     error
+}
+
+// NewDecoder creates a new decoder for ANTEX data.
+// The header will be read implicitly. The header must exist.
+//
+// It is the caller's responsibility to call Close on the underlying reader when done!
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	dec := &Decoder{sc: bufio.NewScanner(r)}
+	dec.Header, dec.err = dec.readHeader()
+	return dec, dec.err
+}
+
+// Err returns the first non-EOF error that was encountered by the decoder.
+func (dec *Decoder) Err() error {
+	if dec.err == io.EOF {
+		return nil
+	}
+	return dec.err
+}
+
+// setErr records the first error encountered.
+func (dec *Decoder) setErr(err error) {
+	if dec.err == nil || dec.err == io.EOF {
+		dec.err = err
+	}
+}
+
+// readHeader reads the ANTEX header.
+func (dec *Decoder) readHeader() (hdr Header, err error) {
+	maxLines := 500
+read:
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+
+		if dec.lineNum == 1 && !strings.Contains(line, "ANTEX VERSION / SYST") {
+			return hdr, ErrNoHeader
+		}
+		if dec.lineNum > maxLines {
+			return hdr, fmt.Errorf("reading header failed: line %d reached without finding end of header", maxLines)
+		}
+		if len(line) < 60 {
+			continue
+		}
+
+		val := line[:60]
+		key := strings.TrimSpace(line[60:])
+
+		switch key {
+		case "ANTEX VERSION / SYST":
+			f64, err := strconv.ParseFloat(strings.TrimSpace(val[:8]), 32)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+			hdr.Version = float32(f64)
+			hdr.SatSys = strings.TrimSpace(val[20:21])
+		case "PCV TYPE / REFANT":
+			hdr.PCVType = strings.TrimSpace(val[:1])
+			hdr.RefAnt = strings.TrimSpace(val[20:40])
+		case "COMMENT":
+			hdr.Comments = append(hdr.Comments, strings.TrimSpace(val))
+		case "END OF HEADER":
+			break read
+		default:
+			hdr.warnings = append(hdr.warnings, fmt.Sprintf("header label not handled: %s", key))
+		}
+	}
+	err = dec.sc.Err()
+	return
+}
+
+// NextAntenna reads the next antenna.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (dec *Decoder) NextAntenna() bool {
+	var ant *Antenna
+	var freq *Frequency
+	skip := false // e.g. within FREQ RMS blocks
+
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		if len(line) < 60 {
+			if freq != nil && !skip {
+				if err := dec.parsePattern(ant, freq, line); err != nil {
+					dec.setErr(err)
+					return false
+				}
+			}
+			continue
+		}
+
+		val := line[:60]
+		key := strings.TrimSpace(line[60:])
+		if ant == nil && key != "START OF ANTENNA" {
+			continue
+		}
+		if skip && key != "END OF FREQ RMS" {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "START OF ANTENNA":
+			ant = &Antenna{}
+		case "TYPE / SERIAL NO":
+			ant.Type = strings.TrimSpace(val[:20])
+			ant.Serial = strings.TrimSpace(val[20:40])
+			ant.SVN = strings.TrimSpace(val[40:50])
+			ant.COSPAR = strings.TrimSpace(val[50:60])
+		case "METH / BY / # / DATE":
+			ant.Method = strings.TrimSpace(val[:20])
+			ant.Agency = strings.TrimSpace(val[20:40])
+			if s := strings.TrimSpace(val[40:46]); s != "" {
+				ant.NumAnt, err = strconv.Atoi(s)
+			}
+			ant.Date = strings.TrimSpace(val[50:60])
+		case "DAZI":
+			ant.DAzi, err = parseFloat(val[2:8])
+		case "ZEN1 / ZEN2 / DZEN":
+			var f []float64
+			if f, err = parseFloats(val[2:20], 6); err == nil && len(f) != 3 {
+				err = fmt.Errorf("expected 3 values")
+			}
+			if err == nil {
+				ant.Zen1, ant.Zen2, ant.DZen = f[0], f[1], f[2]
+			}
+		case "# OF FREQUENCIES":
+			// derived from the frequency blocks
+		case "VALID FROM":
+			ant.ValidFrom, err = parseEpoch(val[:43])
+		case "VALID UNTIL":
+			ant.ValidUntil, err = parseEpoch(val[:43])
+		case "SINEX CODE":
+			ant.SinexCode = strings.TrimSpace(val[:10])
+		case "COMMENT":
+			ant.Comments = append(ant.Comments, strings.TrimSpace(val))
+		case "START OF FREQUENCY":
+			freq = &Frequency{Code: strings.Replace(strings.TrimSpace(val[3:6]), " ", "0", 1)}
+		case "NORTH / EAST / UP":
+			if freq == nil {
+				err = fmt.Errorf("%s without START OF FREQUENCY", key)
+				break
+			}
+			var f []float64
+			if f, err = parseFloats(val[:30], 10); err == nil && len(f) != 3 {
+				err = fmt.Errorf("expected 3 values")
+			}
+			if err == nil {
+				freq.N, freq.E, freq.Up = f[0], f[1], f[2]
+			}
+		case "END OF FREQUENCY":
+			if freq != nil {
+				ant.Freqs = append(ant.Freqs, freq)
+			}
+			freq = nil
+		case "START OF FREQ RMS":
+			skip = true
+		case "END OF FREQ RMS":
+			skip = false
+		case "END OF ANTENNA":
+			dec.ant = ant
+			return true
+		default:
+			if freq != nil && !skip { // pattern line with more than 60 chars
+				err = dec.parsePattern(ant, freq, line)
+			}
+		}
+		if err != nil {
+			dec.setErr(fmt.Errorf("parsing line %d: %q: %v", dec.lineNum, line, err))
+			return false
+		}
+	}
+
+	if err := dec.sc.Err(); err != nil {
+		dec.setErr(err)
+	} else if ant != nil {
+		dec.setErr(fmt.Errorf("unexpected EOF within antenna %q", ant.Type))
+	}
+	return false
+}
+
+// parsePattern parses a NOAZI or azimuth-dependent pattern line given in the format F8.1,mF8.2.
+func (dec *Decoder) parsePattern(ant *Antenna, freq *Frequency, line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	nZen := int(math.Round((ant.Zen2-ant.Zen1)/ant.DZen)) + 1
+	vals, err := parseFloats(line[8:], 8)
+	if err != nil {
+		return fmt.Errorf("parsing pattern in line %d: %v", dec.lineNum, err)
+	}
+	if len(vals) != nZen {
+		return fmt.Errorf("line %d: got %d values, expected %d", dec.lineNum, len(vals), nZen)
+	}
+	if strings.TrimSpace(line[:8]) == "NOAZI" {
+		freq.NoAzi = vals
+	} else {
+		freq.PCV = append(freq.PCV, vals)
+	}
+	return nil
+}
+
+// Antenna returns the most recent antenna generated by a call to NextAntenna.
+func (dec *Decoder) Antenna() *Antenna {
+	return dec.ant
+}
+
+// ReadAll reads all remaining antennas.
+func (dec *Decoder) ReadAll() (Antennas, error) {
+	var ants Antennas
+	for dec.NextAntenna() {
+		ants = append(ants, dec.Antenna())
+	}
+	return ants, dec.Err()
+}
+
+// parseEpoch parses an epoch in the format 5I6,F13.7.
+func parseEpoch(s string) (time.Time, error) {
+	f := strings.Fields(s)
+	if len(f) != 6 {
+		return time.Time{}, fmt.Errorf("invalid epoch: %q", s)
+	}
+	var d [5]int
+	for i := range d {
+		n, err := strconv.Atoi(f[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+		d[i] = n
+	}
+	sec, err := strconv.ParseFloat(f[5], 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(d[0], time.Month(d[1]), d[2], d[3], d[4], 0, 0, time.UTC).Add(time.Duration(sec * float64(time.Second))), nil
+}
+
+// parseFloat parses a float with surrounding blanks.
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// parseFloats parses the floats given in fixed columns of the given width.
+func parseFloats(s string, width int) ([]float64, error) {
+	f := make([]float64, 0, len(s)/width)
+	for col := 0; col < len(s); col += width {
+		end := col + width
+		if end > len(s) {
+			end = len(s)
+		}
+		str := strings.TrimSpace(s[col:end])
+		if str == "" {
+			continue
+		}
+		f64, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, f64)
+	}
+	return f, nil
+}
@@ -0,0 +1,120 @@
+package antex
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("testdata/test.atx")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer f.Close()
+
+	dec, err := NewDecoder(f)
+	assert.NoError(err)
+	assert.Equal(float32(1.4), dec.Header.Version)
+	assert.Equal("M", dec.Header.SatSys)
+	assert.Equal("A", dec.Header.PCVType)
+	assert.Empty(dec.Header.warnings)
+
+	ants, err := dec.ReadAll()
+	assert.NoError(err)
+	if !assert.Len(ants, 3) {
+		return
+	}
+
+	sat := ants[0]
+	assert.True(sat.IsSatellite())
+	assert.Equal("BLOCK IIF", sat.Type)
+	assert.Equal("G01", sat.Serial)
+	assert.Equal("G063", sat.SVN)
+	assert.Equal("2011-036A", sat.COSPAR)
+	assert.Equal(time.Date(2011, 7, 16, 0, 0, 0, 0, time.UTC), sat.ValidFrom)
+	assert.Equal("IGS20_2247", sat.SinexCode)
+
+	rcv := ants[1]
+	assert.False(rcv.IsSatellite())
+	assert.Equal("LEIAR25.R3      LEIT", rcv.Type)
+	assert.Equal("ROBOT", rcv.Method)
+	assert.Equal(10, rcv.NumAnt)
+	assert.Equal(180.0, rcv.DAzi)
+	if assert.Len(rcv.Freqs, 2) {
+		g01 := rcv.Freqs[0]
+		assert.Equal("G01", g01.Code)
+		assert.Equal(155.12, g01.Up)
+		assert.Equal([]float64{0, -0.5, -1}, g01.NoAzi)
+		assert.Len(g01.PCV, 3, "RMS block skipped")
+	}
+}
+
+func TestAntenna_PCVAt(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("testdata/test.atx")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer f.Close()
+	dec, err := NewDecoder(f)
+	assert.NoError(err)
+	ants, err := dec.ReadAll()
+	assert.NoError(err)
+
+	rcv := ants[1]
+	pcv, err := rcv.PCVAt("G01", 0, 7.5)
+	assert.NoError(err)
+	assert.InDelta(-1.5, pcv, 1e-9)
+	pcv, err = rcv.PCVAt("G01", 90, 10)
+	assert.NoError(err)
+	assert.InDelta(-1.0, pcv, 1e-9)
+	pcv, err = rcv.PCVAt("G01", -90, 10)
+	assert.NoError(err)
+	assert.InDelta(-1.0, pcv, 1e-9)
+	_, err = rcv.PCVAt("G01", 0, 15)
+	assert.Error(err, "zenith out of range")
+	_, err = rcv.PCVAt("E01", 0, 5)
+	assert.Error(err, "unknown frequency")
+
+	pcv, err = ants[0].PCVAt("G01", 0, 3.5)
+	assert.NoError(err)
+	assert.InDelta(4.05, pcv, 1e-9)
+}
+
+func TestAntennas_Find(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("testdata/test.atx")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer f.Close()
+	dec, err := NewDecoder(f)
+	assert.NoError(err)
+	ants, err := dec.ReadAll()
+	assert.NoError(err)
+
+	assert.Equal("LEIAR25.R3      LEIT", NormalizeType("LEIAR25.R3 LEIT"))
+	assert.Equal("TRM57971.00     NONE", NormalizeType("TRM57971.00"))
+
+	ant, err := ants.Find("LEIAR25.R3 LEIT", "")
+	assert.NoError(err)
+	assert.Equal(ants[1], ant)
+	ant, err = ants.Find("LEIAR25.R3      LEIT", "12345")
+	assert.NoError(err)
+	assert.Equal(ants[2], ant, "individual calibration")
+	ant, err = ants.Find("LEIAR25.R3      LEIT", "99999")
+	assert.NoError(err)
+	assert.Equal(ants[1], ant, "type mean")
+	_, err = ants.Find("LEIAR25.R3      NONE", "")
+	assert.Error(err)
+
+	ant, err = ants.FindSatellite("G01", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(err)
+	assert.Equal(ants[0], ant)
+	_, err = ants.FindSatellite("G01", time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Error(err)
+}
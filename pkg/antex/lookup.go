@@ -0,0 +1,62 @@
+package antex
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Antennas is a list of antenna calibrations.
+type Antennas []*Antenna
+
+// NormalizeType returns the antenna type in the IGS notation with the antenna name in columns 1-16
+// and the radome in columns 17-20, e.g. "LEIAR25.R3 LEIT" becomes "LEIAR25.R3      LEIT".
+// A missing radome is set to NONE.
+func NormalizeType(typ string) string {
+	f := strings.Fields(typ)
+	switch len(f) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%-16s%4s", f[0], "NONE")
+	default:
+		return fmt.Sprintf("%-16s%4s", strings.Join(f[:len(f)-1], " "), f[len(f)-1])
+	}
+}
+
+// Find returns the calibration of the receiver antenna with the given type incl. radome. An individual
+// calibration for the serial number is preferred over the type mean values. It returns an error
+// if the antenna type is not known, which can be used to validate antenna types e.g. in RINEX headers.
+func (ants Antennas) Find(typ, serial string) (*Antenna, error) {
+	typ = NormalizeType(typ)
+	var typeMean *Antenna
+	for _, ant := range ants {
+		if ant.IsSatellite() || NormalizeType(ant.Type) != typ {
+			continue
+		}
+		if serial != "" && ant.Serial == serial {
+			return ant, nil
+		}
+		if ant.Serial == "" && typeMean == nil {
+			typeMean = ant
+		}
+	}
+	if typeMean == nil {
+		return nil, fmt.Errorf("antenna type %q not found", typ)
+	}
+	return typeMean, nil
+}
+
+// FindSatellite returns the calibration of the satellite antenna, given by its PRN e.g. G01, valid at time t.
+func (ants Antennas) FindSatellite(prn string, t time.Time) (*Antenna, error) {
+	for _, ant := range ants {
+		if !ant.IsSatellite() || ant.Serial != prn {
+			continue
+		}
+		if t.Before(ant.ValidFrom) || (!ant.ValidUntil.IsZero() && !t.Before(ant.ValidUntil)) {
+			continue
+		}
+		return ant, nil
+	}
+	return nil, fmt.Errorf("no satellite antenna for %s at %s", prn, t.Format(time.RFC3339))
+}
@@ -0,0 +1,250 @@
+// Package archive downloads RINEX files and products like SP3 orbits and clocks from the data centers of
+// the IGS and EUREF, e.g. CDDIS, BKG and IGN. The remote file structures are given as URL templates,
+// HTTP(S), FTP and FTPS (explicit TLS) URLs are supported.
+//
+// Note that CDDIS requires an Earthdata login for HTTPS, the HTTPClient must handle the redirects
+// and cookies then.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Template is the URL of a remote file with placeholders for the date and the station:
+//
+//	{YYYY} 4-digit year, {YY} 2-digit year, {DOY} day of year, {HH} hour, {MM} minute,
+//	{h} RINEX 2 hour letter a-x, {WWWW} GPS week, {D} GPS day of week,
+//	{SITE} 9 char station name, e.g. WTZR00DEU, {site} 4 char station ID in lower case, e.g. wtzr.
+type Template string
+
+// Templates for the files of some data centers.
+const (
+	CDDISDailyObs Template = "https://cddis.nasa.gov/archive/gnss/data/daily/{YYYY}/{DOY}/{YY}d/{SITE}_R_{YYYY}{DOY}0000_01D_30S_MO.crx.gz"
+	CDDISDailyNav Template = "https://cddis.nasa.gov/archive/gnss/data/daily/{YYYY}/{DOY}/{YY}p/BRDC00WRD_S_{YYYY}{DOY}0000_01D_MN.rnx.gz"
+	CDDISOrbit    Template = "https://cddis.nasa.gov/archive/gnss/products/{WWWW}/IGS0OPSFIN_{YYYY}{DOY}0000_01D_15M_ORB.SP3.gz"
+	CDDISClock    Template = "https://cddis.nasa.gov/archive/gnss/products/{WWWW}/IGS0OPSFIN_{YYYY}{DOY}0000_01D_30S_CLK.CLK.gz"
+	BKGDailyObs   Template = "https://igs.bkg.bund.de/root_ftp/IGS/obs/{YYYY}/{DOY}/{SITE}_R_{YYYY}{DOY}0000_01D_30S_MO.crx.gz"
+	BKGHourlyObs  Template = "https://igs.bkg.bund.de/root_ftp/IGS/highrate/{YYYY}/{DOY}/{SITE}_R_{YYYY}{DOY}{HH}00_01H_30S_MO.crx.gz"
+	BKGDailyNav   Template = "https://igs.bkg.bund.de/root_ftp/IGS/BRDC/{YYYY}/{DOY}/BRDC00WRD_S_{YYYY}{DOY}0000_01D_MN.rnx.gz"
+	EUREFDailyObs Template = "https://igs.bkg.bund.de/root_ftp/EUREF/obs/{YYYY}/{DOY}/{SITE}_R_{YYYY}{DOY}0000_01D_30S_MO.crx.gz"
+	IGNDailyObs   Template = "ftp://igs.ign.fr/pub/igs/data/{YYYY}/{DOY}/{SITE}_R_{YYYY}{DOY}0000_01D_30S_MO.crx.gz"
+	IGNDailyNav   Template = "ftp://igs.ign.fr/pub/igs/data/{YYYY}/{DOY}/BRDC00WRD_S_{YYYY}{DOY}0000_01D_MN.rnx.gz"
+)
+
+// URL returns the URL for the station site and the time t. site may be empty for files like products.
+func (tmpl Template) URL(site string, t time.Time) string {
+	t = t.UTC()
+	week, tow := gnsstime.GPSWeek(t)
+	site4 := site
+	if len(site4) > 4 {
+		site4 = site4[:4]
+	}
+	r := strings.NewReplacer(
+		"{YYYY}", t.Format("2006"),
+		"{YY}", t.Format("06"),
+		"{DOY}", fmt.Sprintf("%03d", t.YearDay()),
+		"{HH}", t.Format("15"),
+		"{MM}", t.Format("04"),
+		"{h}", string(rune('a'+t.Hour())),
+		"{WWWW}", fmt.Sprintf("%04d", week),
+		"{D}", strconv.Itoa(int(tow/86400)),
+		"{SITE}", strings.ToUpper(site),
+		"{site}", strings.ToLower(site4),
+	)
+	return r.Replace(string(tmpl))
+}
+
+// Result is the result of a download.
+type Result struct {
+	URL  string
+	Path string // local path
+	Err  error
+}
+
+// Client downloads files into a local directory. Its methods are safe for concurrent use.
+type Client struct {
+	Dir        string        // download directory
+	HTTPClient *http.Client  // client for HTTP(S) URLs, defaults to http.DefaultClient
+	Retries    int           // number of retries of a failed download
+	RetryWait  time.Duration // wait time before the first retry, doubled with every retry, defaults to 5 seconds
+	Parallel   int           // number of parallel downloads, defaults to 4
+	Overwrite  bool          // download existing files again
+}
+
+// Download downloads the files into the directory c.Dir. The results are in the order of urls.
+// Existing files are not downloaded again, unless Overwrite is set.
+func (c *Client) Download(ctx context.Context, urls []string) []Result {
+	results := make([]Result, len(urls))
+	parallel := c.Parallel
+	if parallel <= 0 {
+		parallel = 4
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			p, err := c.get(ctx, u)
+			results[i] = Result{URL: u, Path: p, Err: err}
+		}(i, u)
+	}
+	wg.Wait()
+	return results
+}
+
+// DownloadObs downloads the observation files of the stations for the time t and returns the files
+// that could be downloaded. The error reports the failed downloads.
+func (c *Client) DownloadObs(ctx context.Context, tmpl Template, sites []string, t time.Time) ([]*rinex.ObsFile, error) {
+	var urls []string
+	for _, site := range sites {
+		urls = append(urls, tmpl.URL(site, t))
+	}
+	var files []*rinex.ObsFile
+	err := eachPath(c.Download(ctx, urls), func(p string) error {
+		f, err := rinex.NewObsFile(p)
+		if err == nil {
+			files = append(files, f)
+		}
+		return err
+	})
+	return files, err
+}
+
+// DownloadNav downloads the navigation file for the time t.
+func (c *Client) DownloadNav(ctx context.Context, tmpl Template, t time.Time) (*rinex.NavFile, error) {
+	var f *rinex.NavFile
+	err := eachPath(c.Download(ctx, []string{tmpl.URL("", t)}), func(p string) error {
+		var err error
+		f, err = rinex.NewNavFile(p)
+		return err
+	})
+	return f, err
+}
+
+// eachPath calls fn for the paths of the successful results and returns an error for the failed ones.
+func eachPath(results []Result, fn func(path string) error) error {
+	var errs []string
+	for _, res := range results {
+		err := res.Err
+		if err == nil {
+			err = fn(res.Path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", res.URL, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d downloads failed: %s", len(errs), len(results), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// get downloads the file with retries and returns its local path.
+func (c *Client) get(ctx context.Context, rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(c.Dir, path.Base(u.Path))
+	if !c.Overwrite {
+		if _, err := os.Stat(dst); err == nil {
+			return dst, nil
+		}
+	}
+
+	wait := c.RetryWait
+	if wait <= 0 {
+		wait = 5 * time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		err = c.getOnce(ctx, u, dst)
+		if err == nil || attempt >= c.Retries || isPermanent(err) {
+			return dst, err
+		}
+		select {
+		case <-ctx.Done():
+			return dst, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// getOnce downloads the file into a temporary file that is renamed to dst at the end.
+func (c *Client) getOnce(ctx context.Context, u *url.URL, dst string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	switch u.Scheme {
+	case "http", "https":
+		err = c.httpGet(ctx, u, tmp)
+	case "ftp", "ftps":
+		err = ftpGet(ctx, u, tmp)
+	default:
+		err = permanentError{fmt.Errorf("unsupported protocol scheme: %q", u.Scheme)}
+	}
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (c *Client) httpGet(ctx context.Context, u *url.URL, w io.Writer) error {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("GET failed: %d (%s)", resp.StatusCode, resp.Status)
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized ||
+			resp.StatusCode == http.StatusForbidden {
+			return permanentError{err}
+		}
+		return err
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// permanentError is an error that is not resolved by a retry, like a missing file.
+type permanentError struct {
+	error
+}
+
+func isPermanent(err error) bool {
+	_, ok := err.(permanentError)
+	return ok
+}
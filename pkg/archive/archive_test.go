@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_URL(t *testing.T) {
+	assert := assert.New(t)
+	day := time.Date(2020, 6, 17, 13, 0, 0, 0, time.UTC)
+	assert.Equal("https://igs.bkg.bund.de/root_ftp/IGS/obs/2020/169/WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz",
+		BKGDailyObs.URL("wtzr00deu", day))
+	assert.Equal("https://cddis.nasa.gov/archive/gnss/products/2110/IGS0OPSFIN_20201690000_01D_15M_ORB.SP3.gz",
+		CDDISOrbit.URL("", day))
+	assert.Equal("ftp://host/2020/169/20d/wtzr169n.20d.Z 3",
+		Template("ftp://host/{YYYY}/{DOY}/{YY}d/{site}{DOY}{h}.{YY}d.Z {D}").URL("WTZR00DEU", day))
+}
+
+func TestClient_Download(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		n := calls[r.URL.Path]
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.URL.Path == "/flaky" && n == 1:
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+		default:
+			fmt.Fprintf(w, "content of %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "existing"), []byte("local"), 0644))
+
+	c := &Client{Dir: dir, Retries: 2, RetryWait: time.Millisecond}
+	results := c.Download(context.Background(), []string{srv.URL + "/a", srv.URL + "/flaky", srv.URL + "/missing",
+		srv.URL + "/existing"})
+	assert.Len(results, 4)
+	for _, res := range results[:2] {
+		assert.NoError(res.Err)
+		b, err := ioutil.ReadFile(res.Path)
+		assert.NoError(err)
+		assert.Equal("content of /"+filepath.Base(res.Path), string(b))
+	}
+	assert.Equal(2, calls["/flaky"])
+	assert.Error(results[2].Err)
+	assert.Equal(1, calls["/missing"], "no retries for missing files")
+	assert.NoError(results[3].Err)
+	assert.Equal(0, calls["/existing"])
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(files, 3, "no temporary files left")
+}
+
+// serveFTP serves the files of a minimal anonymous FTP server.
+func serveFTP(t *testing.T, files map[string]string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { fmt.Fprintf(conn, "%s\r\n", s) }
+		reply("220 welcome")
+		var dataLn net.Listener
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.Fields(line)
+			switch cmd[0] {
+			case "USER":
+				reply("331 password required")
+			case "PASS":
+				reply("230 logged in")
+			case "TYPE":
+				reply("200 ok")
+			case "EPSV":
+				dataLn, _ = net.Listen("tcp", "127.0.0.1:0")
+				reply(fmt.Sprintf("229 Entering Extended Passive Mode (|||%d|)", dataLn.Addr().(*net.TCPAddr).Port))
+			case "RETR":
+				content, ok := files[cmd[1]]
+				if !ok {
+					reply("550 no such file")
+					continue
+				}
+				reply("150 opening data connection")
+				dc, err := dataLn.Accept()
+				if err != nil {
+					return
+				}
+				fmt.Fprint(dc, content)
+				dc.Close()
+				dataLn.Close()
+				reply("226 transfer complete")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClient_DownloadFTP(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "archive")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	addr := serveFTP(t, map[string]string{"/pub/2020/169/file.txt": "ftp content"})
+	c := &Client{Dir: dir}
+	results := c.Download(context.Background(), []string{"ftp://" + addr + "/pub/2020/169/file.txt"})
+	assert.NoError(results[0].Err)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "file.txt"))
+	assert.NoError(err)
+	assert.Equal("ftp content", string(b))
+
+	addr = serveFTP(t, nil)
+	results = c.Download(context.Background(), []string{"ftp://" + addr + "/missing.txt"})
+	assert.Error(results[0].Err)
+	assert.True(isPermanent(results[0].Err))
+}
@@ -0,0 +1,182 @@
+package archive
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ftpDialTimeout = 30 * time.Second
+
+// ftpGet retrieves the file of the FTP URL in passive mode and writes it to w. The login is anonymous,
+// if the URL contains no user. For ftps URLs the control and data connections are secured with explicit TLS.
+func ftpGet(ctx context.Context, u *url.URL, w io.Writer) error {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "21")
+	}
+	dialer := &net.Dialer{Timeout: ftpDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// stop blocking reads and writes on cancellation
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	tp := textproto.NewConn(conn)
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return err
+	}
+
+	var tlsConf *tls.Config
+	if u.Scheme == "ftps" {
+		tlsConf = &tls.Config{ServerName: u.Hostname(), ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+		if _, err := ftpCmd(tp, 234, "AUTH TLS"); err != nil {
+			return err
+		}
+		tp = textproto.NewConn(tls.Client(conn, tlsConf))
+		if _, err := ftpCmd(tp, 200, "PBSZ 0"); err != nil {
+			return err
+		}
+		if _, err := ftpCmd(tp, 200, "PROT P"); err != nil {
+			return err
+		}
+	}
+
+	user, pass := "anonymous", "anonymous@"
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+	}
+	id, err := tp.Cmd("USER %s", user)
+	if err != nil {
+		return err
+	}
+	tp.StartResponse(id)
+	code, msg, err := tp.ReadResponse(0)
+	tp.EndResponse(id)
+	if err != nil {
+		return err
+	}
+	if code == 331 {
+		if _, err := ftpCmd(tp, 230, "PASS %s", pass); err != nil {
+			return permanentError{err}
+		}
+	} else if code != 230 {
+		return permanentError{fmt.Errorf("ftp login: %d %s", code, msg)}
+	}
+	if _, err := ftpCmd(tp, 200, "TYPE I"); err != nil {
+		return err
+	}
+
+	dataAddr, err := ftpPassive(tp, conn)
+	if err != nil {
+		return err
+	}
+	dataConn, err := dialer.DialContext(ctx, "tcp", dataAddr)
+	if err != nil {
+		return err
+	}
+	defer dataConn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			dataConn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	id, err = tp.Cmd("RETR %s", u.Path)
+	if err != nil {
+		return err
+	}
+	tp.StartResponse(id)
+	code, msg, err = tp.ReadResponse(1)
+	tp.EndResponse(id)
+	if err != nil {
+		if code == 550 {
+			return permanentError{fmt.Errorf("ftp %s: %d %s", u.Path, code, msg)}
+		}
+		return err
+	}
+
+	var data io.Reader = dataConn
+	if tlsConf != nil {
+		data = tls.Client(dataConn, tlsConf)
+	}
+	if _, err := io.Copy(w, data); err != nil {
+		return err
+	}
+	dataConn.Close()
+	if _, _, err := tp.ReadResponse(226); err != nil {
+		return err
+	}
+	tp.Cmd("QUIT")
+	return nil
+}
+
+// ftpCmd sends the command and reads the response, which must have the expected code.
+func ftpCmd(tp *textproto.Conn, expectCode int, format string, args ...interface{}) (string, error) {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+	_, msg, err := tp.ReadResponse(expectCode)
+	return msg, err
+}
+
+// ftpPassive enters the passive mode and returns the address of the data connection.
+// EPSV is tried first, PASV as fallback.
+func ftpPassive(tp *textproto.Conn, conn net.Conn) (string, error) {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return "", err
+	}
+	if msg, err := ftpCmd(tp, 229, "EPSV"); err == nil {
+		// 229 Entering Extended Passive Mode (|||6446|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start+4 {
+			return "", fmt.Errorf("ftp: invalid EPSV response: %q", msg)
+		}
+		return net.JoinHostPort(host, msg[start+4:end]), nil
+	}
+
+	msg, err := ftpCmd(tp, 227, "PASV")
+	if err != nil {
+		return "", err
+	}
+	// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2).
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("ftp: invalid PASV response: %q", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return "", fmt.Errorf("ftp: invalid PASV response: %q", msg)
+	}
+	p1, err1 := strconv.Atoi(fields[4])
+	p2, err2 := strconv.Atoi(fields[5])
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("ftp: invalid PASV response: %q", msg)
+	}
+	// the host of the response is ignored, it is often a private address behind NAT
+	return net.JoinHostPort(host, strconv.Itoa(p1<<8|p2)), nil
+}
@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Layout is the path of a file in a local archive, relative to the archive's root. It is a
+// text/template executed on the LayoutData of the file, e.g. "{{.Year}}/{{.Doy}}/{{.Site}}/{{.Name}}".
+// Paths are separated by slashes.
+type Layout string
+
+// Layouts of archives. LayoutIGS is the structure of the IGS data centers, e.g. CDDIS, with daily,
+// hourly and highrate files. LayoutBKG is the structure of the IGS and EUREF data at BKG.
+const (
+	LayoutSite Layout = "{{.Year}}/{{.Doy}}/{{.Site}}/{{.Name}}"
+	LayoutIGS  Layout = "{{if .Daily}}daily/{{.Year}}/{{.Doy}}/{{.YY}}{{.TypeLetter}}" +
+		"{{else if .Hourly}}hourly/{{.Year}}/{{.Doy}}/{{.Hour}}" +
+		"{{else}}highrate/{{.Year}}/{{.Doy}}/{{.YY}}{{.TypeLetter}}/{{.Hour}}{{end}}/{{.Name}}"
+	LayoutBKG Layout = "{{if .Daily}}obs{{else}}highrate{{end}}/{{.Year}}/{{.Doy}}/{{.Name}}"
+)
+
+// Layouts are the predefined layouts by name, e.g. for command line flags.
+var Layouts = map[string]Layout{
+	"site":  LayoutSite,
+	"igs":   LayoutIGS,
+	"bkg":   LayoutBKG,
+	"euref": LayoutBKG,
+}
+
+// LayoutData are the fields of a file that can be used in a Layout. The fields of the
+// rinex.FileInfo, e.g. .FourCharID, .CountryCode or .DataType, are available too.
+type LayoutData struct {
+	rinex.FileInfo
+	Year       string // 4-digit year, e.g. 2020
+	YY         string // 2-digit year
+	Doy        string // 3-digit day of year
+	Hour       string // 2-digit hour
+	Minute     string // 2-digit minute
+	Week       string // 4-digit GPS week
+	DayOfWeek  int    // GPS day of week
+	StationID  string // 9 char station name, e.g. WTZR00DEU, or the 4 char ID of RINEX 2 names, in upper case
+	Site       string // 4 char station ID in lower case, e.g. wtzr
+	TypeLetter string // RINEX 2 file type, e.g. d for Hatanaka compressed observation files
+	Daily      bool   // the file period is a day
+	Hourly     bool   // the file period is an hour
+}
+
+// NewLayoutData returns the layout fields of the file.
+func NewLayoutData(fi rinex.FileInfo) LayoutData {
+	t := fi.StartTime.UTC()
+	week, tow := gnsstime.GPSWeek(t)
+	d := LayoutData{
+		FileInfo:   fi,
+		Year:       t.Format("2006"),
+		YY:         t.Format("06"),
+		Doy:        fmt.Sprintf("%03d", t.YearDay()),
+		Hour:       t.Format("15"),
+		Minute:     t.Format("04"),
+		Week:       fmt.Sprintf("%04d", week),
+		DayOfWeek:  int(tow / 86400),
+		StationID:  strings.ToUpper(fi.FourCharID),
+		Site:       strings.ToLower(fi.FourCharID),
+		TypeLetter: typeLetter(fi),
+		Daily:      fi.FilePeriod == "01D",
+		Hourly:     fi.FilePeriod == "01H",
+	}
+	if fi.NamingVersion == 3 {
+		d.StationID = fmt.Sprintf("%s%d%d%s", d.StationID, fi.MonumentNumber, fi.ReceiverNumber, fi.CountryCode)
+	}
+	return d
+}
+
+// ArchivePath returns the path of the file fi in an archive of the layout, relative to the archive's
+// root and with the separators of the OS. The file info is returned by rinex.ParseFilename.
+func ArchivePath(layout Layout, fi rinex.FileInfo) (string, error) {
+	if fi.FourCharID == "" || fi.StartTime.IsZero() {
+		return "", fmt.Errorf("archive path: no station or start time for file %q", fi.Name)
+	}
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(string(layout))
+	if err != nil {
+		return "", fmt.Errorf("archive path: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, NewLayoutData(fi)); err != nil {
+		return "", fmt.Errorf("archive path: %w", err)
+	}
+	p := path.Clean(buf.String())
+	if p == "." || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("archive path: invalid path %q of layout %q", buf.String(), layout)
+	}
+	return filepath.FromSlash(p), nil
+}
+
+// typeLetter returns the RINEX 2 file type of the file, e.g. o for observation files.
+func typeLetter(fi rinex.FileInfo) string {
+	switch fi.DataType {
+	case "MO":
+		if fi.Hatanaka() {
+			return "d"
+		}
+		return "o"
+	case "MN":
+		return "p"
+	case "GN":
+		return "n"
+	case "RN":
+		return "g"
+	case "EN":
+		return "l"
+	case "CN":
+		return "f"
+	case "JN":
+		return "q"
+	case "SN":
+		return "h"
+	case "MM":
+		return "m"
+	}
+	return strings.ToLower(fi.DataType)
+}
@@ -0,0 +1,46 @@
+package archive
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchivePath(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		layout Layout
+		name   string
+		want   string
+	}{
+		{LayoutSite, "WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz", "2020/169/wtzr/WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz"},
+		{LayoutSite, "brst155h.20o", "2020/155/brst/brst155h.20o"},
+		{LayoutIGS, "WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz", "daily/2020/169/20d/WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz"},
+		{LayoutIGS, "WTZR00DEU_R_20201690000_01D_MN.rnx.gz", "daily/2020/169/20p/WTZR00DEU_R_20201690000_01D_MN.rnx.gz"},
+		{LayoutIGS, "WTZR00DEU_R_20201691300_01H_30S_MO.crx.gz", "hourly/2020/169/13/WTZR00DEU_R_20201691300_01H_30S_MO.crx.gz"},
+		{LayoutIGS, "WTZR00DEU_R_20201691315_15M_01S_MO.crx.gz", "highrate/2020/169/20d/13/WTZR00DEU_R_20201691315_15M_01S_MO.crx.gz"},
+		{LayoutBKG, "WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz", "obs/2020/169/WTZR00DEU_R_20201690000_01D_30S_MO.crx.gz"},
+		{LayoutBKG, "wtzr169n.20d.Z", "highrate/2020/169/wtzr169n.20d.Z"},
+		{"{{.StationID}}/{{.Week}}/{{.DayOfWeek}}/{{.DataType}}/{{.Name}}", "WTZR00DEU_R_20201690000_01D_30S_MO.rnx",
+			"WTZR00DEU/2110/3/MO/WTZR00DEU_R_20201690000_01D_30S_MO.rnx"},
+	}
+	for _, tt := range tests {
+		fi, err := rinex.ParseFilename(tt.name)
+		if !assert.NoError(err) {
+			continue
+		}
+		p, err := ArchivePath(tt.layout, fi)
+		assert.NoError(err)
+		assert.Equal(filepath.FromSlash(tt.want), p, tt.name)
+	}
+
+	fi, _ := rinex.ParseFilename("brst155h.20o")
+	for _, layout := range []Layout{"{{.Year", "{{.Unknown}}", "/{{.Year}}/{{.Name}}", "../{{.Name}}", ""} {
+		_, err := ArchivePath(layout, fi)
+		assert.Error(err, layout)
+	}
+	_, err := ArchivePath(LayoutSite, rinex.FileInfo{Name: "x"})
+	assert.Error(err)
+}
@@ -0,0 +1,385 @@
+// Package batch processes large batches of RINEX files with a chain of steps, e.g.
+// decompress → convert → QC → compress → rename → move, in a pool of workers.
+//
+// The status of each file is appended to a state file, so a run that was interrupted or repeated
+// skips the finished files and resumes the interrupted ones after their last completed step.
+// The Report of a run lists the status, the steps and the QC results of all files, e.g. as JSON.
+//
+//	r := &batch.Runner{
+//		Steps:     []batch.Step{batch.Decompress(), batch.Convert(rinex.ConvertOptions{}), batch.Move("/data/rinex3")},
+//		StateFile: "/data/convert.state",
+//	}
+//	rep, err := r.Run(ctx, files)
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// DefaultWorkers is the number of files processed in parallel if not set in the Runner.
+const DefaultWorkers = 4
+
+// Status is the processing status of a file.
+type Status string
+
+// Processing states of a file.
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running" // started but not finished, e.g. by an interrupted run
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped" // finished in a previous run
+)
+
+// Step is a processing step of the chain. Run processes the current file of the job, Path, and sets
+// Path to the resulting file. New files are created in the job's work directory, see TempPath.
+// Apart from Move, steps do not modify the input file.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, job *Job) error
+}
+
+// StepResult is the result of a completed step.
+type StepResult struct {
+	Name    string  `json:"name"`
+	Output  string  `json:"output"`  // the file after the step
+	Seconds float64 `json:"seconds"` // processing time
+}
+
+// Job is the processing of an input file by the chain of steps.
+type Job struct {
+	Input    string        `json:"input"`
+	Path     string        `json:"path"` // the current file, the output of the last completed step
+	Status   Status        `json:"status"`
+	Steps    []StepResult  `json:"steps,omitempty"` // the completed steps
+	Err      string        `json:"error,omitempty"`
+	Attempts int           `json:"attempts"` // number of runs that started the job
+	QC       *rinex.Report `json:"qc,omitempty"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	WorkDir  string        `json:"workDir,omitempty"` // directory of the intermediate files, removed when finished
+}
+
+// TempPath returns the path of a new file named name in the work directory of the current step.
+// Each step has its own directory, so a step can keep the name of the file.
+func (job *Job) TempPath(name string) (string, error) {
+	dir := filepath.Join(job.WorkDir, strconv.Itoa(len(job.Steps)+1))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// inWorkDir reports whether path is an intermediate file of the job.
+func (job *Job) inWorkDir(path string) bool {
+	return job.WorkDir != "" && strings.HasPrefix(path, job.WorkDir+string(filepath.Separator))
+}
+
+// Report is the result of a run.
+type Report struct {
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	Total       int       `json:"total"`
+	Done        int       `json:"done"`
+	Failed      int       `json:"failed"`
+	Skipped     int       `json:"skipped"`     // finished in a previous run
+	Interrupted int       `json:"interrupted"` // not finished because the run was canceled
+	Jobs        []*Job    `json:"jobs"`        // in the order of the inputs
+}
+
+// Write writes the report as JSON.
+func (rep *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// Runner runs the chain of steps for the input files. Files are processed in parallel, the steps of a
+// file one after the other. A failed step fails the file, the other files are not affected.
+type Runner struct {
+	Steps   []Step
+	Workers int // number of files processed in parallel, default DefaultWorkers
+
+	// WorkDir is the directory of the intermediate files, default os.TempDir().
+	WorkDir string
+
+	// OutDir is the directory of the results that are left in the work directory, i.e. of chains
+	// without a Move step. It defaults to the directory of the input file.
+	OutDir string
+
+	// StateFile is the file the status of the jobs is appended to, as JSON lines. If it exists,
+	// finished files are skipped and interrupted files resumed after their last completed step.
+	StateFile string
+
+	// RetryFailed processes the files again that failed in a previous run.
+	RetryFailed bool
+
+	// OnJob is called after each job, e.g. for logging. It must be safe for concurrent use.
+	OnJob func(job Job)
+
+	mu    sync.Mutex
+	state *os.File
+}
+
+// Run processes the input files and returns the report. If the context is canceled, no new files are
+// started and the running steps are interrupted, Run returns the report and the context's error then.
+// Errors of the files are reported in the jobs, an error is only returned if the state file can not be
+// read or written.
+func (r *Runner) Run(ctx context.Context, inputs []string) (*Report, error) {
+	rep := &Report{Started: time.Now().UTC(), Total: len(inputs)}
+	prev := map[string]*Job{}
+	if r.StateFile != "" {
+		var err error
+		if prev, err = readState(r.StateFile); err != nil {
+			return nil, err
+		}
+		r.state, err = os.OpenFile(r.StateFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			r.state.Close()
+			r.state = nil
+		}()
+	}
+
+	jobs := make(chan *Job)
+	var wg sync.WaitGroup
+	workers := r.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	var saveErr error
+	var errOnce sync.Once
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := r.process(ctx, job); err != nil {
+					errOnce.Do(func() { saveErr = err })
+				}
+				if r.OnJob != nil {
+					r.OnJob(*job)
+				}
+			}
+		}()
+	}
+
+	rep.Jobs = make([]*Job, len(inputs))
+	for i, input := range inputs {
+		job := resumeJob(prev[input], input, r.RetryFailed)
+		rep.Jobs[i] = job
+		if job.Status == StatusSkipped || ctx.Err() != nil {
+			continue
+		}
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, job := range rep.Jobs {
+		switch job.Status {
+		case StatusDone:
+			rep.Done++
+		case StatusFailed:
+			rep.Failed++
+		case StatusSkipped:
+			rep.Skipped++
+		default:
+			rep.Interrupted++
+		}
+	}
+	rep.Finished = time.Now().UTC()
+	if saveErr != nil {
+		return rep, fmt.Errorf("write state: %w", saveErr)
+	}
+	return rep, ctx.Err()
+}
+
+// resumeJob returns the job for the input, continuing the job of a previous run.
+func resumeJob(prev *Job, input string, retryFailed bool) *Job {
+	if prev == nil {
+		return &Job{Input: input, Path: input, Status: StatusPending}
+	}
+	switch {
+	case prev.Status == StatusDone, prev.Status == StatusFailed && !retryFailed:
+		prev.Status = StatusSkipped
+		return prev
+	case prev.Status == StatusFailed:
+		return &Job{Input: input, Path: input, Status: StatusPending, Attempts: prev.Attempts}
+	}
+	// interrupted: resume after the last step whose output still exists
+	for len(prev.Steps) > 0 {
+		if _, err := os.Stat(prev.Steps[len(prev.Steps)-1].Output); err == nil {
+			break
+		}
+		prev.Steps = prev.Steps[:len(prev.Steps)-1]
+	}
+	prev.Path = input
+	if len(prev.Steps) > 0 {
+		prev.Path = prev.Steps[len(prev.Steps)-1].Output
+	}
+	prev.Status, prev.Err = StatusPending, ""
+	return prev
+}
+
+// process runs the remaining steps of the job.
+func (r *Runner) process(ctx context.Context, job *Job) error {
+	job.Status = StatusRunning
+	job.Attempts++
+	job.Started = time.Now().UTC()
+	if job.WorkDir == "" || !exists(job.WorkDir) {
+		dir, err := ioutil.TempDir(r.WorkDir, "batch-")
+		if err != nil {
+			return r.finish(job, err)
+		}
+		job.WorkDir = dir
+	}
+	if err := r.save(job); err != nil {
+		return err
+	}
+
+	for i := len(job.Steps); i < len(r.Steps); i++ {
+		step := r.Steps[i]
+		if err := ctx.Err(); err != nil {
+			return nil // interrupted, resumed by the next run
+		}
+		start := time.Now()
+		if err := step.Run(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return r.finish(job, fmt.Errorf("%s: %w", step.Name, err))
+		}
+		job.Steps = append(job.Steps, StepResult{Name: step.Name, Output: job.Path, Seconds: time.Since(start).Seconds()})
+		if err := r.save(job); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if job.inWorkDir(job.Path) {
+		dir := r.OutDir
+		if dir == "" {
+			dir = filepath.Dir(job.Input)
+		}
+		dst := filepath.Join(dir, filepath.Base(job.Path))
+		if dst == job.Input {
+			err = fmt.Errorf("result would overwrite the input, set an output directory or rename the file")
+		} else if err = moveFile(job.Path, dst); err == nil {
+			job.Path = dst
+		}
+	}
+	return r.finish(job, err)
+}
+
+// finish sets the final status of the job, removes its intermediate files and saves the state.
+func (r *Runner) finish(job *Job, err error) error {
+	job.Status = StatusDone
+	if err != nil {
+		job.Status, job.Err = StatusFailed, err.Error()
+	}
+	job.Finished = time.Now().UTC()
+	if job.WorkDir != "" {
+		os.RemoveAll(job.WorkDir)
+		job.WorkDir = ""
+	}
+	return r.save(job)
+}
+
+// save appends the job to the state file.
+func (r *Runner) save(job *Job) error {
+	if r.state == nil {
+		return nil
+	}
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.state.Write(append(b, '\n'))
+	return err
+}
+
+// readState reads the state file and returns the last state of each input file. A missing file is
+// no error, an incomplete last line, e.g. of a crashed run, is ignored.
+func readState(path string) (map[string]*Job, error) {
+	jobs := make(map[string]*Job)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return jobs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		job := &Job{}
+		if err := json.Unmarshal(sc.Bytes(), job); err != nil {
+			continue
+		}
+		jobs[job.Input] = job
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	return jobs, nil
+}
+
+// moveFile moves the file src to dst, copying it if they are on different file systems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies the file src to dst. dst is removed if the copy fails.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
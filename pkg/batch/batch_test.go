@@ -0,0 +1,188 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// upper is a step that writes the file in upper case.
+var upper = Step{Name: "upper", Run: func(ctx context.Context, job *Job) error {
+	b, err := ioutil.ReadFile(job.Path)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte("bad")) {
+		return errors.New("bad content")
+	}
+	dst, err := job.TempPath(filepath.Base(job.Path) + ".up")
+	if err != nil {
+		return err
+	}
+	job.Path = dst
+	return ioutil.WriteFile(dst, bytes.ToUpper(b), 0644)
+}}
+
+// inputs creates files with the given contents and returns their paths.
+func inputs(t *testing.T, dir string, contents ...string) []string {
+	var paths []string
+	for i, c := range contents {
+		p := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := ioutil.WriteFile(p, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func TestRunner_Run(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "batch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := inputs(t, dir, "abc", "bad", "xyz")
+
+	var called int32
+	r := &Runner{
+		Steps:     []Step{upper, Move(filepath.Join(dir, "out"))},
+		Workers:   2,
+		WorkDir:   dir,
+		StateFile: filepath.Join(dir, "state"),
+		OnJob:     func(job Job) { atomic.AddInt32(&called, 1) },
+	}
+	rep, err := r.Run(context.Background(), files)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(3, rep.Total)
+	assert.Equal(2, rep.Done)
+	assert.Equal(1, rep.Failed)
+	assert.Equal(int32(3), called)
+
+	job := rep.Jobs[0]
+	assert.Equal(StatusDone, job.Status)
+	assert.Equal(filepath.Join(dir, "out", "a.txt.up"), job.Path)
+	assert.Len(job.Steps, 2)
+	assert.Equal("", job.WorkDir)
+	b, err := ioutil.ReadFile(job.Path)
+	assert.NoError(err)
+	assert.Equal("ABC", string(b))
+	assert.FileExists(files[0], "input kept")
+
+	assert.Equal(StatusFailed, rep.Jobs[1].Status)
+	assert.Equal("upper: bad content", rep.Jobs[1].Err)
+	assert.Equal(StatusDone, rep.Jobs[2].Status)
+	matches, _ := filepath.Glob(filepath.Join(dir, "batch-*"))
+	assert.Empty(matches, "work directories removed")
+
+	var buf bytes.Buffer
+	assert.NoError(rep.Write(&buf))
+	var decoded Report
+	assert.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(2, decoded.Done)
+	assert.Equal("upper: bad content", decoded.Jobs[1].Err)
+
+	// the repeated run skips the finished files
+	rep, err = r.Run(context.Background(), files)
+	assert.NoError(err)
+	assert.Equal(3, rep.Skipped)
+
+	// the failed file is processed again
+	assert.NoError(ioutil.WriteFile(files[1], []byte("good"), 0644))
+	r.RetryFailed = true
+	rep, err = r.Run(context.Background(), files)
+	assert.NoError(err)
+	assert.Equal(2, rep.Skipped)
+	assert.Equal(1, rep.Done)
+	assert.Equal(2, rep.Jobs[1].Attempts)
+}
+
+func TestRunner_resume(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "batch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := inputs(t, dir, "abc")
+
+	// the run is canceled in the second step
+	ctx, cancel := context.WithCancel(context.Background())
+	var second int32
+	interrupt := Step{Name: "interrupt", Run: func(ctx context.Context, job *Job) error {
+		if atomic.AddInt32(&second, 1) == 1 {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	var first int32
+	count := Step{Name: "count", Run: func(ctx context.Context, job *Job) error {
+		atomic.AddInt32(&first, 1)
+		return upper.Run(ctx, job)
+	}}
+	r := &Runner{Steps: []Step{count, interrupt}, WorkDir: dir, OutDir: filepath.Join(dir, "out"), StateFile: filepath.Join(dir, "state")}
+	assert.NoError(os.Mkdir(r.OutDir, 0755))
+	rep, err := r.Run(ctx, files)
+	assert.True(errors.Is(err, context.Canceled), "%v", err)
+	assert.Equal(1, rep.Interrupted)
+	assert.Equal(StatusRunning, rep.Jobs[0].Status)
+
+	// append an incomplete line, as of a crash
+	f, err := os.OpenFile(r.StateFile, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(err)
+	f.WriteString(`{"input":"`)
+	f.Close()
+
+	rep, err = r.Run(context.Background(), files)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(1, rep.Done)
+	assert.Equal(int32(1), first, "first step not repeated")
+	assert.Equal(int32(2), second)
+	job := rep.Jobs[0]
+	assert.Equal(2, job.Attempts)
+	assert.Equal(filepath.Join(dir, "out", "a.txt.up"), job.Path, "result moved to the output directory")
+	b, _ := ioutil.ReadFile(job.Path)
+	assert.Equal("ABC", string(b))
+
+	state, err := readState(r.StateFile)
+	assert.NoError(err)
+	assert.Equal(StatusDone, state[files[0]].Status)
+}
+
+func TestRunner_overwriteInput(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "batch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := inputs(t, dir, "abc")
+
+	same := Step{Name: "same", Run: func(ctx context.Context, job *Job) error {
+		dst, err := job.TempPath(filepath.Base(job.Path))
+		if err != nil {
+			return err
+		}
+		job.Path = dst
+		return copyFile(job.Input, dst)
+	}}
+	rep, err := (&Runner{Steps: []Step{same}, WorkDir: dir}).Run(context.Background(), files)
+	assert.NoError(err)
+	assert.Equal(1, rep.Failed)
+	assert.True(strings.Contains(rep.Jobs[0].Err, "overwrite the input"), rep.Jobs[0].Err)
+}
@@ -0,0 +1,313 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Decompress decompresses gzip, Unix compress (.Z) and zip files, and Hatanaka compressed observation
+// files with the external tool CRX2RNX. Uncompressed files are passed on.
+func Decompress() Step {
+	return Step{Name: "decompress", Run: func(ctx context.Context, job *Job) error {
+		path := job.Path
+		if rinex.IsCompressed(path) {
+			dst, err := job.TempPath(uncompressedName(path))
+			if err != nil {
+				return err
+			}
+			if err := rinex.DecompressFile(path, dst); err != nil {
+				return err
+			}
+			path = dst
+		}
+		info, err := detect(path)
+		if err != nil {
+			return err
+		}
+		if info.Hatanaka {
+			if !job.inWorkDir(path) { // CRX2RNX removes its input
+				dst, err := job.TempPath(filepath.Base(path))
+				if err != nil {
+					return err
+				}
+				if err := copyFile(path, dst); err != nil {
+					return err
+				}
+				path = dst
+			}
+			f, err := rinex.NewObsFile(path)
+			if err != nil {
+				return err
+			}
+			if err := f.Crx2rnx(); err != nil {
+				return err
+			}
+			path = f.Path
+		}
+		job.Path = path
+		return nil
+	}}
+}
+
+// Validate decodes the whole observation or navigation file and fails the file on errors, e.g. of
+// truncated files. Of Hatanaka compressed, meteo and clock files only the header is checked.
+func Validate() Step {
+	return Step{Name: "validate", Run: func(ctx context.Context, job *Job) error {
+		info, err := detect(job.Path)
+		if err != nil {
+			return err
+		}
+		if info.Hatanaka || info.Type != "O" && info.Type != "N" {
+			return nil
+		}
+		r, err := rinex.OpenFile(job.Path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if info.Type == "N" {
+			dec, err := rinex.NewNavDecoder(r)
+			if err != nil {
+				return err
+			}
+			for dec.NextEphemeris() {
+			}
+			return dec.Err()
+		}
+		dec, err := rinex.NewObsDecoder(r)
+		if err != nil {
+			return err
+		}
+		for dec.NextEpoch() {
+		}
+		return dec.Err()
+	}}
+}
+
+// Convert converts observation and navigation files between RINEX 2 and RINEX 3, see rinex.ConvertObs and
+// rinex.ConvertNav. If opts.Version is set, files of the same major version are passed on, so that
+// mixed batches can be converted. The file keeps its name, see Rename.
+func Convert(opts rinex.ConvertOptions) Step {
+	return Step{Name: "convert", Run: func(ctx context.Context, job *Job) error {
+		info, err := detect(job.Path)
+		if err != nil {
+			return err
+		}
+		if opts.Version != 0 && int(info.Version) == int(opts.Version) {
+			return nil
+		}
+		if info.Hatanaka {
+			return fmt.Errorf("Hatanaka compressed file, decompress it first: %s", job.Path)
+		}
+		dst, err := job.TempPath(uncompressedName(job.Path))
+		if err != nil {
+			return err
+		}
+		r, err := rinex.OpenFile(job.Path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		out, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		bw := bufio.NewWriter(out)
+
+		switch info.Type {
+		case "O":
+			var dec *rinex.ObsDecoder
+			if dec, err = rinex.NewObsDecoder(r); err == nil {
+				dec.Name = filepath.Base(job.Input)
+				_, err = rinex.ConvertObs(bw, dec, opts)
+			}
+		case "N":
+			var dec *rinex.NavDecoder
+			if dec, err = rinex.NewNavDecoder(r); err == nil {
+				dec.Name = filepath.Base(job.Input)
+				_, err = rinex.ConvertNav(bw, dec, opts)
+			}
+		default:
+			err = fmt.Errorf("no observation or navigation file: type %q", info.Type)
+		}
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err2 := out.Close(); err == nil {
+			err = err2
+		}
+		if err != nil {
+			os.Remove(dst)
+			return err
+		}
+		job.Path = dst
+		return nil
+	}}
+}
+
+// QC computes the rinex.Report of observation files, stored in the job, and fails the file if the
+// availability, the percentage of the expected epochs, is below minAvailability. Other files and
+// Hatanaka compressed ones are passed on.
+func QC(minAvailability float64) Step {
+	return Step{Name: "qc", Run: func(ctx context.Context, job *Job) error {
+		info, err := detect(job.Path)
+		if err != nil {
+			return err
+		}
+		if info.Type != "O" || info.Hatanaka {
+			return nil
+		}
+		r, err := rinex.OpenFile(job.Path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		dec, err := rinex.NewObsDecoder(r)
+		if err != nil {
+			return err
+		}
+		rep, err := rinex.NewReport(dec)
+		if err != nil {
+			return err
+		}
+		rep.File = job.Input
+		job.QC = rep
+		if rep.Availability < minAvailability {
+			return fmt.Errorf("availability %.2f %% below %.2f %%", rep.Availability, minAvailability)
+		}
+		return nil
+	}}
+}
+
+// Compress gzips the file. If hatanaka is set, observation files are Hatanaka compressed before with
+// the external tool RNX2CRX. Compressed files are passed on.
+func Compress(hatanaka bool) Step {
+	return Step{Name: "compress", Run: func(ctx context.Context, job *Job) error {
+		if rinex.IsCompressed(job.Path) {
+			return nil
+		}
+		info, err := detect(job.Path)
+		if err != nil {
+			return err
+		}
+		if !hatanaka || info.Type != "O" || info.Hatanaka {
+			dst, err := job.TempPath(filepath.Base(job.Path) + ".gz")
+			if err != nil {
+				return err
+			}
+			if err := rinex.CompressFile(job.Path, dst); err != nil {
+				return err
+			}
+			job.Path = dst
+			return nil
+		}
+
+		src := job.Path
+		if !job.inWorkDir(src) { // RNX2CRX writes next to its input
+			dst, err := job.TempPath(filepath.Base(src))
+			if err != nil {
+				return err
+			}
+			if err := copyFile(src, dst); err != nil {
+				return err
+			}
+			src = dst
+		}
+		f, err := rinex.NewObsFile(src)
+		if err != nil {
+			return err
+		}
+		if err := f.CompressWithOptions(rinex.CompressOptions{KeepSource: true}); err != nil {
+			return err
+		}
+		job.Path = f.Path
+		return nil
+	}}
+}
+
+// Rename renames the file after the filename convention of its RINEX version, e.g. brst155h.20o
+// converted to RINEX 3 to BRST00FRA_R_20201550700_01H_30S_MO.rnx, see rinex.FilenameConverter.
+// The compression extension is kept.
+func Rename(conv rinex.FilenameConverter) Step {
+	return Step{Name: "rename", Run: func(ctx context.Context, job *Job) error {
+		info, err := detect(job.Path)
+		if err != nil {
+			return err
+		}
+		var name string
+		if info.Version >= 3 {
+			name, err = conv.ToRnx3(job.Path)
+		} else {
+			name, err = conv.ToRnx2(job.Path)
+		}
+		if err != nil {
+			return err
+		}
+		base := filepath.Base(job.Path)
+		if rinex.IsCompressed(base) {
+			name += filepath.Ext(base)
+		}
+		if name == base {
+			return nil
+		}
+		dst, err := job.TempPath(name)
+		if err != nil {
+			return err
+		}
+		if job.inWorkDir(job.Path) {
+			err = os.Rename(job.Path, dst)
+		} else if err = os.Link(job.Path, dst); err != nil {
+			err = copyFile(job.Path, dst)
+		}
+		if err != nil {
+			return err
+		}
+		job.Path = dst
+		return nil
+	}}
+}
+
+// Move moves the file into the directory dir, which is created if needed. An existing file is
+// replaced. If no previous step created a new file, the input file itself is moved.
+func Move(dir string) Step {
+	return Step{Name: "move", Run: func(ctx context.Context, job *Job) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, filepath.Base(job.Path))
+		if err := moveFile(job.Path, dst); err != nil {
+			return err
+		}
+		job.Path = dst
+		return nil
+	}}
+}
+
+// detect returns the type of the, possibly compressed, RINEX file.
+func detect(path string) (rinex.TypeInfo, error) {
+	r, err := rinex.OpenFile(path)
+	if err != nil {
+		return rinex.TypeInfo{}, err
+	}
+	defer r.Close()
+	info, err := rinex.DetectType(r)
+	if err != nil {
+		return info, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return info, nil
+}
+
+// uncompressedName returns the base name of path without the compression extension.
+func uncompressedName(path string) string {
+	base := filepath.Base(path)
+	if rinex.IsCompressed(base) {
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return base
+}
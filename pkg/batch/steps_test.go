@@ -0,0 +1,75 @@
+package batch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSteps(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "batch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "brst155h.20o.gz")
+	if err := rinex.CompressFile("../rinex/testdata/white/brst155h.20o", input); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "archive")
+
+	r := &Runner{
+		Steps: []Step{
+			Decompress(),
+			Convert(rinex.ConvertOptions{Version: 3.04}),
+			QC(99),
+			Compress(false),
+			Rename(rinex.FilenameConverter{Country: rinex.CountryMap{"BRST": "FRA"}.Lookup}),
+			Move(archive),
+		},
+		WorkDir: dir,
+	}
+	rep, err := r.Run(context.Background(), []string{input})
+	if !assert.NoError(err) {
+		return
+	}
+	job := rep.Jobs[0]
+	if !assert.Equal(StatusDone, job.Status, job.Err) {
+		return
+	}
+	assert.Equal(filepath.Join(archive, "BRST00FRA_R_20201550700_01H_30S_MO.rnx.gz"), job.Path)
+	if assert.NotNil(job.QC) {
+		assert.Equal(float32(3.04), job.QC.RINEXVersion)
+		assert.Equal(input, job.QC.File)
+	}
+	names := make([]string, 0, len(job.Steps))
+	for _, res := range job.Steps {
+		names = append(names, res.Name)
+	}
+	assert.Equal([]string{"decompress", "convert", "qc", "compress", "rename", "move"}, names)
+	assert.FileExists(input)
+
+	f, err := os.Open(job.Path)
+	if !assert.NoError(err) {
+		return
+	}
+	defer f.Close()
+	info, err := rinex.DetectType(f)
+	assert.NoError(err)
+	assert.Equal(float32(3.04), info.Version)
+
+	// RINEX 3 input is passed on by the conversion, the QC fails
+	r.Steps = []Step{Decompress(), Convert(rinex.ConvertOptions{Version: 3.04}), QC(100.1)}
+	rep, err = r.Run(context.Background(), []string{job.Path})
+	assert.NoError(err)
+	job = rep.Jobs[0]
+	assert.Equal(StatusFailed, job.Status)
+	assert.Equal("qc: availability 100.00 % below 100.10 %", job.Err)
+	assert.Equal(job.Steps[0].Output, job.Steps[1].Output, "not converted")
+}
@@ -0,0 +1,163 @@
+// Package bias reads GNSS code and phase biases from SINEX-BIAS (.BSX) files and CODE DCB files
+// and applies them to the code observations of RINEX epochs.
+//
+// Observable-specific signal biases (OSB) are applied directly. Differential signal biases (DSB),
+// e.g. P1-C1 of the CODE DCB files, can be used to align the code observations of different signals.
+package bias
+
+import (
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+const speedOfLight = 299792458.0 // [m/s]
+
+// Bias types.
+const (
+	TypeOSB = "OSB" // observable-specific signal bias
+	TypeDSB = "DSB" // differential signal bias, Obs1 - Obs2
+	TypeISB = "ISB" // inter-system bias
+)
+
+// Bias is a satellite or receiver bias.
+type Bias struct {
+	Type       string    // OSB, DSB or ISB
+	SVN        string    // satellite vehicle number, e.g. G063
+	PRN        rinex.PRN // satellite, or only the system for receiver biases of all satellites of a system
+	Station    string    // station name for receiver biases, empty for satellite biases
+	Obs1, Obs2 string    // observation codes, e.g. C1C, Obs2 is empty for OSBs
+	Start, End time.Time // validity, zero if open
+	Unit       string    // ns for code biases, cyc for phase biases
+	Value      float64
+	StdDev     float64
+}
+
+// ValidAt reports whether the bias is valid at t.
+func (b *Bias) ValidAt(t time.Time) bool {
+	return (b.Start.IsZero() || !t.Before(b.Start)) && (b.End.IsZero() || t.Before(b.End))
+}
+
+type biasKey struct {
+	typ        string
+	prn        rinex.PRN
+	station    string
+	obs1, obs2 string
+}
+
+// Set holds biases for lookups by satellite or station, signal and time.
+type Set struct {
+	biases map[biasKey][]*Bias
+}
+
+// NewSet returns an empty set.
+func NewSet() *Set {
+	return &Set{biases: make(map[biasKey][]*Bias)}
+}
+
+// Add adds the bias.
+func (s *Set) Add(b Bias) {
+	k := biasKey{typ: b.Type, prn: b.PRN, station: b.Station, obs1: b.Obs1, obs2: b.Obs2}
+	s.biases[k] = append(s.biases[k], &b)
+}
+
+// Len returns the number of biases.
+func (s *Set) Len() int {
+	n := 0
+	for _, list := range s.biases {
+		n += len(list)
+	}
+	return n
+}
+
+// find returns the bias valid at t. For receivers, a bias given for the satellite's system or for all
+// satellites is returned if there is none for the satellite.
+func (s *Set) find(typ string, prn rinex.PRN, station, obs1, obs2 string, t time.Time) (*Bias, bool) {
+	prns := []rinex.PRN{prn}
+	if station != "" {
+		prns = append(prns, rinex.PRN{Sys: prn.Sys}, rinex.PRN{})
+	}
+	for _, p := range prns {
+		for _, b := range s.biases[biasKey{typ: typ, prn: p, station: station, obs1: obs1, obs2: obs2}] {
+			if b.ValidAt(t) {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// OSB returns the observable-specific signal bias in ns of the satellite, or of the station's receiver
+// if station is not empty, for the observation type obs at t.
+func (s *Set) OSB(prn rinex.PRN, station, obs string, t time.Time) (float64, bool) {
+	if b, ok := s.find(TypeOSB, prn, station, obs, "", t); ok {
+		return b.Value, true
+	}
+	return 0, false
+}
+
+// DSB returns the differential signal bias obs1-obs2 in ns of the satellite, or of the station's receiver
+// if station is not empty, at t. It is taken from the DSBs, in both directions, or computed from the OSBs.
+func (s *Set) DSB(prn rinex.PRN, station, obs1, obs2 string, t time.Time) (float64, bool) {
+	if b, ok := s.find(TypeDSB, prn, station, obs1, obs2, t); ok {
+		return b.Value, true
+	}
+	if b, ok := s.find(TypeDSB, prn, station, obs2, obs1, t); ok {
+		return -b.Value, true
+	}
+	osb1, ok1 := s.OSB(prn, station, obs1, t)
+	osb2, ok2 := s.OSB(prn, station, obs2, t)
+	if ok1 && ok2 {
+		return osb1 - osb2, true
+	}
+	return 0, false
+}
+
+// Apply subtracts the code OSBs of the satellites, and of the station's receiver if station is not empty,
+// from the code observations of the epoch. It returns the number of corrected observations.
+// Code observations without a bias are not modified.
+func (s *Set) Apply(epo *rinex.Epoch, station string) int {
+	n := 0
+	for i := range epo.ObsList {
+		satObs := &epo.ObsList[i]
+		for j, typ := range satObs.Types {
+			if j >= len(satObs.Obss) || !strings.HasPrefix(typ, "C") || satObs.Obss[j].Val == 0 {
+				continue
+			}
+			osb, ok := s.OSB(satObs.Prn, "", typ, epo.Time)
+			if station != "" {
+				if rcv, rcvOK := s.OSB(satObs.Prn, station, typ, epo.Time); rcvOK {
+					osb, ok = osb+rcv, true
+				}
+			}
+			if !ok {
+				continue
+			}
+			satObs.Obss[j].Val -= osb * 1e-9 * speedOfLight
+			n++
+		}
+	}
+	return n
+}
+
+// AlignCode converts the code observations of type from into type to, e.g. C1C into C1W, by adding the
+// satellite DSB to-from, as needed for CODE P1-C1 DCBs. It returns the number of converted observations.
+// Only the observation values are changed, not the observation types.
+func (s *Set) AlignCode(epo *rinex.Epoch, from, to string) int {
+	n := 0
+	for i := range epo.ObsList {
+		satObs := &epo.ObsList[i]
+		idx := satObs.Index(from)
+		if idx < 0 || idx >= len(satObs.Obss) || satObs.Obss[idx].Val == 0 {
+			continue
+		}
+		dsb, ok := s.DSB(satObs.Prn, "", to, from, epo.Time)
+		if !ok {
+			continue
+		}
+		satObs.Obss[idx].Val += dsb * 1e-9 * speedOfLight
+		n++
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package bias
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	g01 = rinex.PRN{Sys: gnss.SysGPS, Num: 1}
+	g02 = rinex.PRN{Sys: gnss.SysGPS, Num: 2}
+	e05 = rinex.PRN{Sys: gnss.SysGAL, Num: 5}
+	r01 = rinex.PRN{Sys: gnss.SysGLO, Num: 1}
+)
+
+func readSINEX(t *testing.T) *Set {
+	t.Helper()
+	f, err := os.Open("testdata/CODE_20201680000_01D_01D_OSB.BSX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := ReadSINEX(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestReadSINEX(t *testing.T) {
+	assert := assert.New(t)
+	s := readSINEX(t)
+	assert.Equal(7, s.Len())
+
+	day := time.Date(2020, 6, 16, 12, 0, 0, 0, time.UTC) // doy 168
+	osb, ok := s.OSB(g01, "", "C1C", day)
+	assert.True(ok)
+	assert.Equal(10.21, osb)
+	_, ok = s.OSB(g01, "", "C1C", day.Add(24*time.Hour))
+	assert.False(ok, "not valid")
+	_, ok = s.OSB(g02, "", "C1C", day)
+	assert.False(ok, "no bias")
+
+	b := s.biases[biasKey{typ: TypeOSB, prn: g01, obs1: "L1C"}][0]
+	assert.Equal(Bias{Type: TypeOSB, SVN: "G063", PRN: g01, Obs1: "L1C", Unit: "cyc", Value: 0.0123, StdDev: 0.001,
+		Start: time.Date(2020, 6, 16, 0, 0, 0, 0, time.UTC), End: time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC)}, *b)
+
+	// receiver bias for all GPS satellites, open end
+	osb, ok = s.OSB(g02, "WTZR00DEU", "C1C", day.Add(100*24*time.Hour))
+	assert.True(ok)
+	assert.Equal(3.0, osb)
+
+	// DSBs
+	dsb, ok := s.DSB(g01, "", "C1C", "C1W", day)
+	assert.True(ok, "from OSBs")
+	assert.InDelta(-0.79, dsb, 1e-9)
+	dsb, ok = s.DSB(e05, "", "C5Q", "C1C", day)
+	assert.True(ok, "reverse")
+	assert.Equal(2.5, dsb)
+	dsb, ok = s.DSB(r01, "", "C1C", "C1P", day)
+	assert.True(ok, "two-digit year")
+	assert.Equal(0.5, dsb)
+
+	_, err := ReadSINEX(strings.NewReader("IGS DCB\n"))
+	assert.Equal(ErrNoHeader, err)
+	_, err = ReadSINEX(strings.NewReader("%=BIA 1.00\n+BIAS/SOLUTION\n OSB  G063 G01           C1C       2020:168:00000\n"))
+	assert.Error(err, "short line")
+}
+
+func TestReadDCB(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("testdata/P1C12006.DCB")
+	assert.NoError(err)
+	defer f.Close()
+	s, err := ReadDCB(f)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(3, s.Len())
+
+	now := time.Now()
+	dsb, ok := s.DSB(g01, "", "C1W", "C1C", now)
+	assert.True(ok)
+	assert.Equal(-1.245, dsb)
+	dsb, ok = s.DSB(g02, "ALGO 40104M002", "C1W", "C1C", now)
+	assert.True(ok, "receiver")
+	assert.Equal(-5.101, dsb)
+
+	_, err = ReadDCB(strings.NewReader("CODE'S 30-DAY GPS DCB SOLUTION\n"))
+	assert.Error(err, "no signals")
+}
+
+func TestSet_Apply(t *testing.T) {
+	assert := assert.New(t)
+	s := readSINEX(t)
+	day := time.Date(2020, 6, 16, 12, 0, 0, 0, time.UTC)
+
+	newEpoch := func() *rinex.Epoch {
+		return &rinex.Epoch{Time: day, ObsList: []rinex.SatObs{
+			rinex.NewSatObs(g01, map[string]rinex.Obs{"C1C": {Val: 2e7}, "C2W": {Val: 2e7}, "L1C": {Val: 1e8}, "C5Q": {}}),
+			rinex.NewSatObs(g02, map[string]rinex.Obs{"C1C": {Val: 2e7}}),
+		}}
+	}
+	ns := 1e-9 * speedOfLight
+
+	epo := newEpoch()
+	assert.Equal(2, s.Apply(epo, ""))
+	c1c, _ := epo.ObsList[0].Get("C1C")
+	assert.InDelta(2e7-10.21*ns, c1c.Val, 1e-6)
+	c2w, _ := epo.ObsList[0].Get("C2W")
+	assert.InDelta(2e7-18.1234*ns, c2w.Val, 1e-6)
+	l1c, _ := epo.ObsList[0].Get("L1C")
+	assert.Equal(1e8, l1c.Val, "phase not modified")
+
+	epo = newEpoch()
+	assert.Equal(3, s.Apply(epo, "WTZR00DEU"))
+	c1c, _ = epo.ObsList[0].Get("C1C")
+	assert.InDelta(2e7-13.21*ns, c1c.Val, 1e-6, "satellite and receiver")
+	c1c, _ = epo.ObsList[1].Get("C1C")
+	assert.InDelta(2e7-3*ns, c1c.Val, 1e-6, "receiver only")
+
+	// C1C to C1W with DSB C1W-C1C = 0.79 ns
+	epo = newEpoch()
+	assert.Equal(1, s.AlignCode(epo, "C1C", "C1W"))
+	c1c, _ = epo.ObsList[0].Get("C1C")
+	assert.InDelta(2e7+0.79*ns, c1c.Val, 1e-6)
+}
@@ -0,0 +1,88 @@
+package bias
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// dcbObsTypes maps the signals of the CODE DCB files to the RINEX 3 observation types.
+var dcbObsTypes = map[string]string{"P1": "C1W", "C1": "C1C", "P2": "C2W", "C2": "C2L"}
+
+var dcbTitle = regexp.MustCompile(`\b([PC][12])-([PC][12])\b`)
+
+// ReadDCB reads a CODE DCB file, e.g. P1C12006.DCB or P1P22006.DCB, with the monthly differential code biases
+// of the satellites and receivers. The signals of the title are mapped to RINEX 3 observation types, P1-C1 to
+// the DSB C1W-C1C. The biases have no time limits, they are valid for the month of the file.
+//
+//	CODE'S 30-DAY GPS P1-C1 DCB SOLUTION, ENDING DAY 182, 2020        03-JUL-20 07:43
+//	...
+//	PRN / STATION NAME        VALUE (NS)  RMS (NS)
+//	***   ****************    *****.***   *****.***
+//	G01                          -1.245     0.012
+//	G    ALGO 40104M002          -5.101     0.045
+func ReadDCB(r io.Reader) (*Set, error) {
+	sc := bufio.NewScanner(r)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("DCB: empty file")
+	}
+	m := dcbTitle.FindStringSubmatch(sc.Text())
+	if m == nil {
+		return nil, fmt.Errorf("DCB: no signals in title: %q", sc.Text())
+	}
+	obs1, obs2 := dcbObsTypes[m[1]], dcbObsTypes[m[2]]
+
+	s := NewSet()
+	lineNum := 1
+	inData := false
+	for sc.Scan() {
+		lineNum++
+		line := sc.Text()
+		if strings.HasPrefix(line, "***") {
+			inData = true
+			continue
+		}
+		if !inData || strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("DCB: line %d: invalid bias: %q", lineNum, line)
+		}
+		b := Bias{Type: TypeDSB, Obs1: obs1, Obs2: obs2, Unit: "ns"}
+		nameFields := fields[:len(fields)-2]
+		var err error
+		if len(fields[0]) == 1 { // receiver of a system
+			if b.PRN, err = parsePRN(fields[0]); err != nil {
+				return nil, fmt.Errorf("DCB: line %d: %w", lineNum, err)
+			}
+			b.Station = strings.Join(nameFields[1:], " ")
+		} else if prn, err := rinex.ParsePRN(fields[0]); err == nil && len(nameFields) == 1 {
+			b.PRN = prn
+		} else {
+			b.Station = strings.Join(nameFields, " ")
+		}
+		if b.Station == "" && b.PRN.Num == 0 {
+			return nil, fmt.Errorf("DCB: line %d: invalid bias: %q", lineNum, line)
+		}
+		if b.Value, err = strconv.ParseFloat(fields[len(fields)-2], 64); err != nil {
+			return nil, fmt.Errorf("DCB: line %d: %w", lineNum, err)
+		}
+		if b.StdDev, err = strconv.ParseFloat(fields[len(fields)-1], 64); err != nil {
+			return nil, fmt.Errorf("DCB: line %d: %w", lineNum, err)
+		}
+		s.Add(b)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
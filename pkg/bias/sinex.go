@@ -0,0 +1,122 @@
+package bias
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// ErrNoHeader is returned when reading SINEX-BIAS data that does not begin with the %=BIA header line.
+var ErrNoHeader = errors.New("SINEX-BIAS: no header")
+
+// ReadSINEX reads the BIAS/SOLUTION block of a SINEX-BIAS file, version 1.00.
+func ReadSINEX(r io.Reader) (*Set, error) {
+	s := NewSet()
+	sc := bufio.NewScanner(r)
+	lineNum := 0
+	inSolution := false
+	for sc.Scan() {
+		lineNum++
+		line := sc.Text()
+		if lineNum == 1 && !strings.HasPrefix(line, "%=BIA") {
+			return nil, ErrNoHeader
+		}
+		switch {
+		case strings.HasPrefix(line, "+BIAS/SOLUTION"):
+			inSolution = true
+		case strings.HasPrefix(line, "-BIAS/SOLUTION"):
+			inSolution = false
+		case inSolution && strings.HasPrefix(line, " "):
+			b, err := parseSINEXBias(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			s.Add(b)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseSINEXBias parses a line of the BIAS/SOLUTION block:
+//
+//	*BIAS SVN_ PRN STATION__ OBS1 OBS2 BIAS_START____ BIAS_END______ UNIT __ESTIMATED_VALUE____ _STD_DEV___
+//	 DSB  G063 G01           C1C  C1W  2020:168:00000 2020:169:00000 ns                -0.7880      0.0064
+func parseSINEXBias(line string) (b Bias, err error) {
+	if len(line) < 91 {
+		return b, fmt.Errorf("bias line too short: %q", line)
+	}
+	b.Type = strings.TrimSpace(line[1:5])
+	b.SVN = strings.TrimSpace(line[6:10])
+	b.Station = strings.TrimSpace(line[15:24])
+	b.Obs1 = strings.TrimSpace(line[25:29])
+	b.Obs2 = strings.TrimSpace(line[30:34])
+	b.Unit = strings.TrimSpace(line[65:69])
+	if b.PRN, err = parsePRN(strings.TrimSpace(line[11:14])); err != nil {
+		return
+	}
+	if b.Start, err = parseSINEXTime(line[35:49]); err != nil {
+		return
+	}
+	if b.End, err = parseSINEXTime(line[50:64]); err != nil {
+		return
+	}
+	if b.Value, err = strconv.ParseFloat(strings.TrimSpace(line[70:91]), 64); err != nil {
+		return
+	}
+	if len(line) > 92 {
+		if s := strings.TrimSpace(line[92:]); s != "" {
+			b.StdDev, err = strconv.ParseFloat(s, 64)
+		}
+	}
+	return
+}
+
+// parsePRN parses a satellite, e.g. G01, or only the system letter as used for receiver biases.
+func parsePRN(s string) (rinex.PRN, error) {
+	if len(s) == 1 {
+		sys, ok := gnss.SystemByAbbr(s)
+		if !ok {
+			return rinex.PRN{}, fmt.Errorf("invalid satellite system: %q", s)
+		}
+		return rinex.PRN{Sys: sys}, nil
+	}
+	return rinex.ParsePRN(s)
+}
+
+// parseSINEXTime parses a SINEX time YYYY:DOY:SSSSS or YY:DOY:SSSSS. The time 0000:000:00000 is returned
+// as zero time.
+func parseSINEXTime(s string) (time.Time, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid SINEX time: %q", s)
+	}
+	var vals [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SINEX time: %q", s)
+		}
+		vals[i] = v
+	}
+	year, doy, sec := vals[0], vals[1], vals[2]
+	if year == 0 && doy == 0 && sec == 0 {
+		return time.Time{}, nil
+	}
+	if len(parts[0]) == 2 {
+		year += 2000
+		if year > 2050 {
+			year -= 100
+		}
+	}
+	return time.Date(year, 1, doy, 0, 0, sec, 0, time.UTC), nil
+}
@@ -0,0 +1,248 @@
+package caster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+)
+
+// ErrUnauthorized is returned by a UserStore for unknown users and wrong passwords.
+var ErrUnauthorized = errors.New("caster: unauthorized")
+
+// Reasons for rejecting NtripClients, see Server.Rejections.
+const (
+	RejectUnauthorized = "unauthorized"
+	RejectForbidden    = "forbidden"            // no permission for the mountpoint
+	RejectConnections  = "too many connections" // of the user or the mountpoint
+	RejectQuota        = "quota exceeded"
+	RejectAuthError    = "auth error" // the UserStore failed
+)
+
+// User is an authenticated NtripClient with its permissions and limits.
+type User struct {
+	Name string `json:"name"`
+
+	// Mountpoints are the mountpoints the user may access, with shell patterns like "WTZR*".
+	// All mountpoints if empty.
+	Mountpoints []string `json:"mountpoints"`
+
+	// MaxConnections is the maximum number of concurrent streams, unlimited if 0.
+	MaxConnections int `json:"maxConnections"`
+
+	// Quota is the maximum number of bytes per day (UTC), unlimited if 0.
+	Quota int64 `json:"quota"`
+}
+
+// CanAccess reports whether the user may access the mountpoint mp.
+func (u *User) CanAccess(mp string) bool {
+	if len(u.Mountpoints) == 0 {
+		return true
+	}
+	for _, pattern := range u.Mountpoints {
+		if ok, _ := path.Match(pattern, mp); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UserStore authenticates the NtripClients.
+type UserStore interface {
+	// Authenticate returns the user for the credentials, or ErrUnauthorized.
+	Authenticate(username, password string) (*User, error)
+}
+
+// UserFunc is a function that implements UserStore, e.g. as callback into a database.
+type UserFunc func(username, password string) (*User, error)
+
+// Authenticate implements UserStore.
+func (f UserFunc) Authenticate(username, password string) (*User, error) {
+	return f(username, password)
+}
+
+// StaticUser is a user of StaticUsers.
+type StaticUser struct {
+	Password string
+	User
+}
+
+// StaticUsers maps the usernames to the users, e.g. read from a configuration file.
+type StaticUsers map[string]StaticUser
+
+// Authenticate implements UserStore.
+func (users StaticUsers) Authenticate(username, password string) (*User, error) {
+	su, ok := users[username]
+	if !ok || !checkPassword(su.Password, password) {
+		return nil, ErrUnauthorized
+	}
+	u := su.User
+	u.Name = username
+	return &u, nil
+}
+
+// HTTPAuth delegates the authentication to an external HTTP service. The credentials are sent with
+// Basic authentication in a GET request to URL. The service answers with status 200 and the User as JSON,
+// or with 401 or 403 if the credentials are invalid.
+type HTTPAuth struct {
+	URL string
+
+	// Client is the HTTP client, defaults to a client with a timeout of 10 seconds.
+	Client *http.Client
+}
+
+// Authenticate implements UserStore.
+func (a *HTTPAuth) Authenticate(username, password string) (*User, error) {
+	req, err := http.NewRequest("GET", a.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	default:
+		return nil, fmt.Errorf("auth service %s: %s", a.URL, resp.Status)
+	}
+	u := &User{}
+	if err := json.NewDecoder(resp.Body).Decode(u); err != nil {
+		return nil, fmt.Errorf("auth service %s: %v", a.URL, err)
+	}
+	u.Name = username
+	return u, nil
+}
+
+// account holds the connections and the transferred bytes of a user.
+type account struct {
+	conns int
+	day   time.Time // of bytes
+	bytes int64
+}
+
+// authenticate checks the credentials of the request for the mountpoint. It returns the user, nil
+// for anonymous access, or the reason for the rejection.
+func (s *Server) authenticate(r *http.Request, mount Mountpoint) (*User, string) {
+	username, password, _ := r.BasicAuth()
+	switch {
+	case len(mount.Users) > 0:
+		if !checkPassword(mount.Users[username], password) {
+			return nil, RejectUnauthorized
+		}
+		return &User{Name: username}, ""
+	case s.Users != nil:
+		u, err := s.Users.Authenticate(username, password)
+		if errors.Is(err, ErrUnauthorized) {
+			return nil, RejectUnauthorized
+		}
+		if err != nil {
+			s.logf("%s: authentication of %s: %v", mount.Stream.MP, username, err)
+			return nil, RejectAuthError
+		}
+		if !u.CanAccess(mount.Stream.MP) {
+			return nil, RejectForbidden
+		}
+		return u, ""
+	}
+	return nil, ""
+}
+
+// acquire registers a stream connection of the user to the mountpoint, or returns the reason for the rejection.
+func (s *Server) acquire(u *User, mount Mountpoint, now time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mp := mount.Stream.MP
+	if mount.MaxClients > 0 && s.clients[mp] >= mount.MaxClients {
+		return RejectConnections
+	}
+	if u != nil {
+		acc := s.account(u.Name, now)
+		if u.MaxConnections > 0 && acc.conns >= u.MaxConnections {
+			return RejectConnections
+		}
+		if u.Quota > 0 && acc.bytes >= u.Quota {
+			return RejectQuota
+		}
+		acc.conns++
+	}
+	s.clients[mp]++
+	return ""
+}
+
+// release unregisters a stream connection.
+func (s *Server) release(u *User, mp string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[mp]--
+	if u != nil {
+		s.accounts[u.Name].conns--
+	}
+}
+
+// transferred adds n bytes to the account of the user and reports whether the quota is exceeded.
+func (s *Server) transferred(u *User, n int, now time.Time) bool {
+	if u == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc := s.account(u.Name, now)
+	acc.bytes += int64(n)
+	return u.Quota > 0 && acc.bytes > u.Quota
+}
+
+// account returns the account of the user, the bytes are reset at the start of a day.
+func (s *Server) account(name string, now time.Time) *account {
+	acc, ok := s.accounts[name]
+	if !ok {
+		acc = &account{}
+		s.accounts[name] = acc
+	}
+	if day := now.UTC().Truncate(24 * time.Hour); !acc.day.Equal(day) {
+		acc.day, acc.bytes = day, 0
+	}
+	return acc
+}
+
+// reject answers the request of a client with the reason, logs and counts it.
+func (s *Server) reject(w http.ResponseWriter, r *http.Request, mp, username, reason string) {
+	s.mu.Lock()
+	s.rejections[reason]++
+	s.mu.Unlock()
+	s.logf("%s: rejected client %s (user %q): %s", mp, r.RemoteAddr, username, reason)
+	switch reason {
+	case RejectUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Basic realm="/`+mp+`"`)
+		http.Error(w, reason, http.StatusUnauthorized)
+	case RejectForbidden:
+		http.Error(w, reason, http.StatusForbidden)
+	case RejectConnections, RejectQuota:
+		http.Error(w, reason, http.StatusTooManyRequests)
+	default:
+		http.Error(w, reason, http.StatusServiceUnavailable)
+	}
+}
+
+// Rejections returns the number of rejected NtripClients per reason, including the clients disconnected
+// due to exceeded quotas.
+func (s *Server) Rejections() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rej := make(map[string]uint64, len(s.rejections))
+	for reason, n := range s.rejections {
+		rej[reason] = n
+	}
+	return rej
+}
@@ -0,0 +1,155 @@
+package caster
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_CanAccess(t *testing.T) {
+	assert := assert.New(t)
+	u := &User{}
+	assert.True(u.CanAccess("WTZR00DEU0"), "all")
+	u.Mountpoints = []string{"WTZR*", "BRUX00BEL0"}
+	assert.True(u.CanAccess("WTZR00DEU0"))
+	assert.True(u.CanAccess("BRUX00BEL0"))
+	assert.False(u.CanAccess("BRUX00BEL1"))
+}
+
+func TestStaticUsers(t *testing.T) {
+	assert := assert.New(t)
+	users := StaticUsers{"alice": {Password: "pw", User: User{MaxConnections: 2}}}
+	u, err := users.Authenticate("alice", "pw")
+	assert.NoError(err)
+	assert.Equal(&User{Name: "alice", MaxConnections: 2}, u)
+	_, err = users.Authenticate("alice", "wrong")
+	assert.True(errors.Is(err, ErrUnauthorized))
+	_, err = users.Authenticate("bob", "")
+	assert.True(errors.Is(err, ErrUnauthorized))
+
+	var store UserStore = UserFunc(func(username, password string) (*User, error) {
+		return &User{Name: username}, nil
+	})
+	u, err = store.Authenticate("bob", "")
+	assert.NoError(err)
+	assert.Equal("bob", u.Name)
+}
+
+func TestHTTPAuth(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		switch {
+		case user == "alice" && pass == "pw":
+			json.NewEncoder(w).Encode(User{Mountpoints: []string{"WTZR*"}, Quota: 1e6})
+		case user == "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	a := &HTTPAuth{URL: srv.URL}
+	u, err := a.Authenticate("alice", "pw")
+	assert.NoError(err)
+	assert.Equal(&User{Name: "alice", Mountpoints: []string{"WTZR*"}, Quota: 1e6}, u)
+	_, err = a.Authenticate("alice", "wrong")
+	assert.True(errors.Is(err, ErrUnauthorized))
+	_, err = a.Authenticate("broken", "")
+	assert.Error(err)
+	assert.False(errors.Is(err, ErrUnauthorized))
+}
+
+func TestServer_access(t *testing.T) {
+	assert := assert.New(t)
+	srv := NewServer([]Mountpoint{{Stream: ntrip.Stream{MP: "TEST00DEU0"}, MaxClients: 2}})
+	srv.Logger = log.New(ioutil.Discard, "", 0)
+	srv.InspectTime = 0
+	srv.Users = UserFunc(func(username, password string) (*User, error) {
+		switch username {
+		case "alice":
+			return &User{Name: username, Mountpoints: []string{"TEST*"}, MaxConnections: 1, Quota: 20}, nil
+		case "bob":
+			return &User{Name: username, Mountpoints: []string{"OTHER"}}, nil
+		case "carol":
+			return &User{Name: username}, nil
+		case "broken":
+			return nil, errors.New("database down")
+		}
+		return nil, ErrUnauthorized
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	pr, pw := io.Pipe()
+	source, err := ntrip.NewClient(ts.URL, ntrip.Options{})
+	assert.NoError(err)
+	posted := make(chan error, 1)
+	go func() { posted <- source.PostStream("TEST00DEU0", pr) }()
+	waitOnline(t, srv, "TEST00DEU0")
+
+	get := func(user string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+"/TEST00DEU0", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth(user, "pw")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	alice := get("alice")
+	assert.Equal(http.StatusOK, alice.StatusCode)
+	for _, tc := range []struct {
+		user   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"bob", http.StatusForbidden},
+		{"alice", http.StatusTooManyRequests},
+		{"broken", http.StatusServiceUnavailable},
+	} {
+		resp := get(tc.user)
+		resp.Body.Close()
+		assert.Equal(tc.status, resp.StatusCode, tc.user)
+	}
+	carol := get("carol")
+	assert.Equal(http.StatusOK, carol.StatusCode)
+	defer carol.Body.Close()
+	resp := get("carol")
+	resp.Body.Close()
+	assert.Equal(http.StatusTooManyRequests, resp.StatusCode, "max clients of the mountpoint")
+
+	// alice is disconnected when the quota is exceeded
+	for i := 0; i < 3; i++ {
+		_, err = pw.Write([]byte("0123456789"))
+		assert.NoError(err)
+	}
+	data, err := ioutil.ReadAll(alice.Body)
+	assert.NoError(err)
+	assert.Equal("012345678901234567890123456789"[:len(data)], string(data))
+	assert.True(len(data) > 20 && len(data) <= 30, "%d bytes", len(data))
+	alice.Body.Close()
+
+	resp = get("alice")
+	resp.Body.Close()
+	assert.Equal(http.StatusTooManyRequests, resp.StatusCode, "quota")
+
+	assert.Equal(map[string]uint64{RejectUnauthorized: 1, RejectForbidden: 1, RejectConnections: 2,
+		RejectAuthError: 1, RejectQuota: 2}, srv.Rejections())
+
+	pw.Close()
+	assert.NoError(<-posted)
+}
@@ -0,0 +1,389 @@
+// Package caster implements an embeddable NtripCaster for Ntrip 2.0.
+//
+// NtripServers upload their streams with chunked POST requests to the mountpoint, NtripClients request
+// the sourcetable or a stream with GET requests. Only the configured mountpoints are accepted, the
+// sourcetable lists the mountpoints with a connected source. Ntrip 1.0 requests are not supported.
+// The empty fields of the sourcetable records, like the format details and the navigation systems,
+// are derived from the first seconds of the uploaded RTCM 3 streams.
+//
+// The NtripClients are authenticated per mountpoint or by a UserStore, e.g. StaticUsers or an external
+// HTTPAuth service, with mountpoint permissions, connection limits and daily quotas. Rejected clients
+// are logged and counted per reason, see Server.Rejections.
+//
+// Relay mountpoints pull their streams from remote casters, with failover between multiple upstreams,
+// see Server.RunRelays.
+//
+// The Server is a http.Handler, so it can be run by a http.Server, with or without TLS:
+//
+//	srv := caster.NewServer(mounts)
+//	log.Fatal(http.ListenAndServe(":2101", srv))
+package caster
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+)
+
+// clientBuffer is the number of data blocks buffered per client. Clients that fall behind are disconnected.
+const clientBuffer = 64
+
+// Mountpoint is a stream offered by the caster.
+type Mountpoint struct {
+	// Stream is the sourcetable record, Stream.MP is the mountpoint name.
+	Stream ntrip.Stream
+
+	// SourceUser and SourcePassword are the credentials of the NtripServer. No authentication if empty.
+	SourceUser, SourcePassword string
+
+	// Users maps the usernames of the NtripClients to their passwords. If empty, the clients are
+	// authenticated by the Server's UserStore, or anybody may get the stream without UserStore.
+	Users map[string]string
+
+	// MaxClients is the maximum number of concurrent NtripClients, unlimited if 0.
+	MaxClients int
+
+	// Upstreams make the mountpoint a relay that pulls its stream from remote casters, the preferred
+	// upstream first, see Server.RunRelays. NtripServers cannot upload to relay mountpoints.
+	Upstreams []Upstream
+}
+
+// Server is an NtripCaster. It is safe for concurrent use.
+type Server struct {
+	// Logger logs connections and errors, defaults to the standard logger.
+	Logger *log.Logger
+
+	// InspectTime is the time the RTCM 3 stream of a source is inspected after connecting, to fill the
+	// empty fields of its sourcetable record: format, format details, carrier, navigation systems,
+	// position, generator and bitrate. Zero disables the inspection.
+	InspectTime time.Duration
+
+	// Users authenticates the NtripClients of the mountpoints without own users, with their permissions,
+	// connection limits and quotas. Anybody may get these streams if nil.
+	Users UserStore
+
+	// RelayTimeout is the time without data after which a relay switches to the next upstream.
+	RelayTimeout time.Duration
+
+	// RelayCheckInterval is the interval of the health checks of the preferred upstreams of a relay
+	// that is fed by a fallback upstream.
+	RelayCheckInterval time.Duration
+
+	mu         sync.Mutex
+	mounts     []Mountpoint
+	sources    map[string]*source // mountpoints with connected source
+	clients    map[string]int     // number of clients per mountpoint
+	accounts   map[string]*account
+	rejections map[string]uint64
+	relays     map[string][]UpstreamStatus
+}
+
+// NewServer returns a caster for the given mountpoints.
+func NewServer(mounts []Mountpoint) *Server {
+	return &Server{
+		mounts:      mounts,
+		sources:     make(map[string]*source),
+		clients:     make(map[string]int),
+		accounts:    make(map[string]*account),
+		rejections:  make(map[string]uint64),
+		relays:      make(map[string][]UpstreamStatus),
+		InspectTime: DefaultInspectTime,
+
+		RelayTimeout:       DefaultRelayTimeout,
+		RelayCheckInterval: DefaultRelayCheckInterval,
+	}
+}
+
+// Sourcetable returns the sourcetable with the mountpoints that have a connected source.
+// The records are completed by the inspection of the streams, see InspectTime.
+func (s *Server) Sourcetable() *ntrip.Sourcetable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &ntrip.Sourcetable{}
+	for _, mount := range s.mounts {
+		if src, ok := s.sources[mount.Stream.MP]; ok {
+			st.Streams = append(st.Streams, src.record(mount.Stream))
+		}
+	}
+	return st
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mp := strings.Trim(r.URL.Path, "/")
+	mount, ok := s.mount(mp)
+	switch {
+	case r.Method == http.MethodGet && !ok:
+		s.serveSourcetable(w)
+	case r.Method == http.MethodGet:
+		s.serveStream(w, r, mount)
+	case r.Method == http.MethodPost && !ok:
+		http.Error(w, "unknown mountpoint", http.StatusNotFound)
+	case r.Method == http.MethodPost:
+		s.serveSource(w, r, mount)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) mount(mp string) (Mountpoint, bool) {
+	for _, mount := range s.mounts {
+		if mount.Stream.MP == mp {
+			return mount, true
+		}
+	}
+	return Mountpoint{}, false
+}
+
+func (s *Server) serveSourcetable(w http.ResponseWriter) {
+	var buf bytes.Buffer
+	s.Sourcetable().Write(&buf)
+	w.Header().Set("Content-Type", "gnss/sourcetable")
+	w.Header().Set("Ntrip-Version", "Ntrip/2.0")
+	w.Write(buf.Bytes())
+}
+
+// serveStream sends the stream of the mountpoint to a client. Offline mountpoints get the sourcetable.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request, mount Mountpoint) {
+	mp := mount.Stream.MP
+	username, _, _ := r.BasicAuth()
+	user, reason := s.authenticate(r, mount)
+	if reason != "" {
+		s.reject(w, r, mp, username, reason)
+		return
+	}
+
+	s.mu.Lock()
+	src := s.sources[mp]
+	s.mu.Unlock()
+	var ch chan []byte
+	if src != nil {
+		ch = src.subscribe()
+	}
+	if ch == nil {
+		s.serveSourcetable(w)
+		return
+	}
+	defer src.unsubscribe(ch)
+	if reason := s.acquire(user, mount, time.Now()); reason != "" {
+		s.reject(w, r, mp, username, reason)
+		return
+	}
+	defer s.release(user, mp)
+
+	w.Header().Set("Content-Type", "gnss/data")
+	w.Header().Set("Ntrip-Version", "Ntrip/2.0")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	s.logf("%s: client %s connected", mp, r.RemoteAddr)
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				s.logf("%s: client %s disconnected by the caster", mp, r.RemoteAddr)
+				return
+			}
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			if s.transferred(user, len(data), time.Now()) {
+				s.mu.Lock()
+				s.rejections[RejectQuota]++
+				s.mu.Unlock()
+				s.logf("%s: client %s (user %q) disconnected: %s", mp, r.RemoteAddr, username, RejectQuota)
+				return
+			}
+		case <-r.Context().Done():
+			s.logf("%s: client %s disconnected", mp, r.RemoteAddr)
+			return
+		}
+	}
+}
+
+// serveSource reads the stream of a source and sends it to the clients.
+func (s *Server) serveSource(w http.ResponseWriter, r *http.Request, mount Mountpoint) {
+	if len(mount.Upstreams) > 0 {
+		http.Error(w, "relay mountpoint", http.StatusForbidden)
+		return
+	}
+	if mount.SourceUser != "" || mount.SourcePassword != "" {
+		user, pass, _ := r.BasicAuth()
+		if user != mount.SourceUser || !checkPassword(mount.SourcePassword, pass) {
+			s.unauthorized(w, r, mount.Stream.MP)
+			return
+		}
+	}
+
+	mp := mount.Stream.MP
+	src := s.register(mp)
+	if src == nil {
+		http.Error(w, "mountpoint in use", http.StatusConflict)
+		return
+	}
+	s.logf("%s: source %s connected", mp, r.RemoteAddr)
+
+	err := s.feed(src, mount.Stream, r.Body)
+	s.unregister(mp, src)
+	if err != io.EOF {
+		s.logf("%s: source %s: %v", mp, r.RemoteAddr, err)
+	}
+	s.logf("%s: source %s disconnected", mp, r.RemoteAddr)
+}
+
+// register adds a source for the mountpoint, nil if the mountpoint has a source already.
+func (s *Server) register(mp string) *source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sources[mp]; ok {
+		return nil
+	}
+	src := &source{clients: make(map[chan []byte]struct{})}
+	s.sources[mp] = src
+	return src
+}
+
+// unregister removes the source of the mountpoint and disconnects its clients.
+func (s *Server) unregister(mp string, src *source) {
+	s.mu.Lock()
+	delete(s.sources, mp)
+	s.mu.Unlock()
+	src.close()
+}
+
+// feed sends the data read from r to the clients of the source until r returns an error, which is returned.
+// The stream is inspected to complete the sourcetable record str, see InspectTime.
+func (s *Server) feed(src *source, str ntrip.Stream, r io.Reader) error {
+	var inspectW io.Writer
+	if s.InspectTime > 0 {
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		inspectW = pw
+		stop := time.AfterFunc(s.InspectTime, func() { pw.Close() })
+		defer stop.Stop()
+		go s.inspect(src, str, pr)
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			src.publish(buf[:n])
+			if inspectW != nil {
+				if _, err := inspectW.Write(buf[:n]); err != nil { // inspection finished
+					inspectW = nil
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// inspect completes the sourcetable record str of the source by inspecting the stream read from r.
+func (s *Server) inspect(src *source, str ntrip.Stream, r io.Reader) {
+	in := newInspector(time.Now())
+	in.run(r)
+	str = in.complete(str, time.Now())
+	src.mu.Lock()
+	src.str = &str
+	src.mu.Unlock()
+	s.logf("%s: inspected: %s %s, systems %s", str.MP, str.Format, str.FormatDetails, strings.Join(str.SatSystem, "+"))
+}
+
+func (s *Server) unauthorized(w http.ResponseWriter, r *http.Request, mp string) {
+	s.logf("%s: unauthorized %s request from %s", mp, r.Method, r.RemoteAddr)
+	w.Header().Set("WWW-Authenticate", `Basic realm="/`+mp+`"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func checkPassword(want, got string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(want), []byte(got)) == 1
+}
+
+// source distributes the data of a stream to its clients.
+type source struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	closed  bool
+	str     *ntrip.Stream // sourcetable record completed by the inspection
+}
+
+// record returns the sourcetable record of the source, str if the inspection is not finished.
+func (src *source) record(str ntrip.Stream) ntrip.Stream {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.str != nil {
+		return *src.str
+	}
+	return str
+}
+
+// subscribe returns the channel for a new client, nil if the source is closed.
+func (src *source) subscribe() chan []byte {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.closed {
+		return nil
+	}
+	ch := make(chan []byte, clientBuffer)
+	src.clients[ch] = struct{}{}
+	return ch
+}
+
+func (src *source) unsubscribe(ch chan []byte) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if _, ok := src.clients[ch]; ok {
+		delete(src.clients, ch)
+		close(ch)
+	}
+}
+
+// publish sends a copy of p to all clients, clients with a full buffer are disconnected.
+func (src *source) publish(p []byte) {
+	data := append([]byte(nil), p...)
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	for ch := range src.clients {
+		select {
+		case ch <- data:
+		default:
+			delete(src.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// close disconnects all clients.
+func (src *source) close() {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.closed = true
+	for ch := range src.clients {
+		delete(src.clients, ch)
+		close(ch)
+	}
+}
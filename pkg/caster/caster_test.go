@@ -0,0 +1,159 @@
+package caster
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer() (*Server, *httptest.Server) {
+	srv := NewServer([]Mountpoint{{
+		Stream:     ntrip.Stream{MP: "TEST00DEU0", Identifier: "Frankfurt", Format: "RTCM 3.3", Country: "DEU"},
+		SourceUser: "src", SourcePassword: "srcpw",
+		Users: map[string]string{"user": "pw"},
+	}})
+	srv.Logger = log.New(ioutil.Discard, "", 0)
+	return srv, httptest.NewServer(srv)
+}
+
+// waitOnline waits until the mountpoint is in the sourcetable.
+func waitOnline(t *testing.T, srv *Server, mp string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if _, ok := srv.Sourcetable().HasStream(mp); ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("mountpoint %s not online", mp)
+}
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+	srv, ts := newTestServer()
+	defer ts.Close()
+
+	assert.Len(srv.Sourcetable().Streams, 0)
+
+	// source
+	pr, pw := io.Pipe()
+	source, err := ntrip.NewClient(ts.URL, ntrip.Options{Username: "src", Password: "srcpw"})
+	assert.NoError(err)
+	posted := make(chan error, 1)
+	go func() { posted <- source.PostStream("TEST00DEU0", pr) }()
+	waitOnline(t, srv, "TEST00DEU0")
+
+	// sourcetable
+	cl, err := ntrip.NewClient(ts.URL, ntrip.Options{Username: "user", Password: "pw"})
+	assert.NoError(err)
+	st, err := cl.ParseSourcetable()
+	assert.NoError(err)
+	if assert.Len(st.Streams, 1) {
+		assert.Equal("Frankfurt", st.Streams[0].Identifier)
+	}
+
+	// client
+	r, err := cl.GetStream("TEST00DEU0")
+	assert.NoError(err)
+	defer r.Close()
+	_, err = pw.Write([]byte("first block"))
+	assert.NoError(err)
+	buf := make([]byte, 11)
+	_, err = io.ReadFull(r, buf)
+	assert.NoError(err)
+	assert.Equal("first block", string(buf))
+
+	// a second source is rejected
+	source2, err := ntrip.NewClient(ts.URL, ntrip.Options{Username: "src", Password: "srcpw"})
+	assert.NoError(err)
+	assert.Error(source2.PostStream("TEST00DEU0", bytes.NewReader([]byte("data"))))
+
+	// the clients are disconnected with the source
+	pw.Close()
+	assert.NoError(<-posted)
+	rest, err := ioutil.ReadAll(r)
+	assert.NoError(err)
+	assert.Empty(rest)
+	assert.Len(srv.Sourcetable().Streams, 0)
+}
+
+func TestServer_auth(t *testing.T) {
+	assert := assert.New(t)
+	_, ts := newTestServer()
+	defer ts.Close()
+
+	source, err := ntrip.NewClient(ts.URL, ntrip.Options{Username: "src", Password: "wrong"})
+	assert.NoError(err)
+	assert.Error(source.PostStream("TEST00DEU0", bytes.NewReader([]byte("data"))))
+
+	for _, tc := range []struct {
+		user, pass string
+		status     int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"user", "wrong", http.StatusUnauthorized},
+		{"user", "pw", http.StatusOK}, // offline, so the sourcetable is sent
+	} {
+		req, err := http.NewRequest("GET", ts.URL+"/TEST00DEU0", nil)
+		assert.NoError(err)
+		req.SetBasicAuth(tc.user, tc.pass)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(err)
+		resp.Body.Close()
+		assert.Equal(tc.status, resp.StatusCode, tc.user+":"+tc.pass)
+	}
+
+	resp, err := http.Post(ts.URL+"/UNKNOWN", "gnss/data", bytes.NewReader(nil))
+	assert.NoError(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_inspect(t *testing.T) {
+	assert := assert.New(t)
+	srv, ts := newTestServer()
+	defer ts.Close()
+	srv.InspectTime = 200 * time.Millisecond
+
+	var data []byte
+	for _, payloads := range testFrames(t, time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC), 2) {
+		for _, payload := range payloads {
+			frame, err := rtcm3.EncodeFrame(payload)
+			assert.NoError(err)
+			data = append(data, frame...)
+		}
+	}
+	pr, pw := io.Pipe()
+	source, err := ntrip.NewClient(ts.URL, ntrip.Options{Username: "src", Password: "srcpw"})
+	assert.NoError(err)
+	posted := make(chan error, 1)
+	go func() { posted <- source.PostStream("TEST00DEU0", pr) }()
+	_, err = pw.Write(data)
+	assert.NoError(err)
+	waitOnline(t, srv, "TEST00DEU0")
+
+	var str ntrip.Stream
+	for i := 0; i < 100 && str.FormatDetails == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if st := srv.Sourcetable(); len(st.Streams) == 1 {
+			str = st.Streams[0]
+		}
+	}
+	assert.Equal("RTCM 3.3", str.Format, "configured")
+	assert.Equal("1006,1033,1077,1087", str.FormatDetails)
+	assert.Equal([]string{"GPS", "GLO"}, str.SatSystem)
+	assert.Equal("SEPT POLARX5", str.Generator)
+	assert.Greater(str.Bitrate, 0)
+
+	pw.Close()
+	assert.NoError(<-posted)
+}
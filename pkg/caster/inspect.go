@@ -0,0 +1,209 @@
+package caster
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+)
+
+// DefaultInspectTime is the default time the stream of a source is inspected after connecting.
+const DefaultInspectTime = 10 * time.Second
+
+// burst is the time within the frames of a message type are regarded as one transmission,
+// e.g. the MSMs of an epoch split into multiple messages.
+const burst = 500 * time.Millisecond
+
+// ephSystems maps the ephemeris message numbers to the satellite systems.
+var ephSystems = map[int]gnss.System{
+	1019: gnss.SysGPS, 1020: gnss.SysGLO, 1041: gnss.SysIRNSS, 1042: gnss.SysBDS, 1043: gnss.SysSBAS,
+	1044: gnss.SysQZSS, 1045: gnss.SysGAL, 1046: gnss.SysGAL,
+}
+
+// msgTimes holds the transmissions of a message type.
+type msgTimes struct {
+	n           int
+	first, last time.Time
+}
+
+// inspector derives the sourcetable record of a RTCM 3 stream from its frames.
+type inspector struct {
+	start    time.Time
+	bytes    int64
+	msgs     map[int]*msgTimes
+	systems  map[gnss.System]bool
+	bands    map[string]bool // frequency bands with phase observations
+	pos      *rinex.Coord
+	receiver string
+}
+
+func newInspector(start time.Time) *inspector {
+	return &inspector{
+		start:   start,
+		msgs:    make(map[int]*msgTimes),
+		systems: make(map[gnss.System]bool),
+		bands:   make(map[string]bool),
+	}
+}
+
+// run inspects the frames read from r until r returns an error.
+func (in *inspector) run(r io.Reader) {
+	dec := rtcm3.NewDecoder(&countingReader{r: r, n: &in.bytes})
+	for dec.NextFrame() {
+		in.add(dec.Payload(), time.Now())
+	}
+}
+
+// add inspects the message payload received at now.
+func (in *inspector) add(payload []byte, now time.Time) {
+	if len(payload) < 2 {
+		return
+	}
+	num := int(payload[0])<<4 | int(payload[1]>>4)
+	tm, ok := in.msgs[num]
+	if !ok {
+		tm = &msgTimes{first: now}
+		in.msgs[num] = tm
+	}
+	if tm.n == 0 || now.Sub(tm.last) > burst {
+		tm.n++
+		tm.last = now
+	}
+
+	switch num {
+	case 1005, 1006, 1033:
+		msg, err := rtcm3.Decode(payload)
+		if err != nil {
+			return
+		}
+		switch m := msg.(type) {
+		case *rtcm3.StationARP:
+			in.pos = &m.Position
+		case *rtcm3.Descriptor:
+			in.receiver = m.ReceiverType
+		}
+	}
+	if sys, ok := ephSystems[num]; ok {
+		in.systems[sys] = true
+	}
+	switch {
+	case num >= 1001 && num <= 1004:
+		in.systems[gnss.SysGPS] = true
+		in.legacyBands(num - 1000)
+	case num >= 1009 && num <= 1012:
+		in.systems[gnss.SysGLO] = true
+		in.legacyBands(num - 1008)
+	default:
+		if sys, codes, ok := rtcm3.MSMSignals(payload); ok {
+			in.systems[sys] = true
+			if num%10 != 1 { // MSM1 has no phase observations
+				for _, code := range codes {
+					in.bands[code[:1]] = true
+				}
+			}
+		}
+	}
+}
+
+// legacyBands adds the bands of the legacy RTK messages, typ is 1 to 4 for 1001-1004 or 1009-1012.
+func (in *inspector) legacyBands(typ int) {
+	in.bands["1"] = true
+	if typ >= 3 {
+		in.bands["2"] = true
+	}
+}
+
+// version returns the RTCM version needed for the messages.
+func (in *inspector) version() string {
+	version := "3.0"
+	for num := range in.msgs {
+		var v string
+		switch {
+		case num == 1041 || num == 1042 || num == 1044 || num == 1046 || num >= 1131 && num <= 1137:
+			v = "3.3"
+		case num >= 1071 && num <= 1127 || num == 1230:
+			v = "3.2"
+		case num == 1033 || num == 1029 || num >= 1057 && num <= 1068:
+			v = "3.1"
+		}
+		if v > version {
+			version = v
+		}
+	}
+	return version
+}
+
+// formatDetails returns the message types with their intervals in seconds, e.g. "1006(10),1077(1)".
+// Types seen only once are listed without interval.
+func (in *inspector) formatDetails() string {
+	nums := make([]int, 0, len(in.msgs))
+	for num := range in.msgs {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	details := make([]string, 0, len(nums))
+	for _, num := range nums {
+		tm := in.msgs[num]
+		if tm.n < 2 {
+			details = append(details, fmt.Sprint(num))
+			continue
+		}
+		interval := math.Round(tm.last.Sub(tm.first).Seconds() / float64(tm.n-1))
+		details = append(details, fmt.Sprintf("%d(%.0f)", num, math.Max(interval, 1)))
+	}
+	return strings.Join(details, ",")
+}
+
+// complete fills the empty fields of the sourcetable record with the inspection results at now.
+func (in *inspector) complete(str ntrip.Stream, now time.Time) ntrip.Stream {
+	if len(in.msgs) > 0 {
+		if str.Format == "" {
+			str.Format = "RTCM " + in.version()
+		}
+		if str.FormatDetails == "" {
+			str.FormatDetails = in.formatDetails()
+		}
+	}
+	if str.Carrier == 0 {
+		str.Carrier = len(in.bands)
+		if str.Carrier > 2 {
+			str.Carrier = 2
+		}
+	}
+	if len(str.SatSystem) == 0 {
+		for sys := gnss.SysGPS; sys <= gnss.SysSBAS; sys++ {
+			if in.systems[sys] {
+				str.SatSystem = append(str.SatSystem, sys.String())
+			}
+		}
+	}
+	if str.Lat == 0 && str.Lon == 0 && in.pos != nil {
+		g := in.pos.LatLonHeight(rinex.GRS80)
+		str.Lat, str.Lon = float32(g.Lat), float32(g.Lon)
+	}
+	if str.Generator == "" {
+		str.Generator = in.receiver
+	}
+	if d := now.Sub(in.start).Seconds(); str.Bitrate == 0 && d > 0 {
+		str.Bitrate = int(float64(in.bytes) * 8 / d)
+	}
+	return str
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	*cr.n += int64(n)
+	return n, err
+}
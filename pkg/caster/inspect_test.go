@@ -0,0 +1,89 @@
+package caster
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFrames returns the payloads per second of a stream with 1 Hz MSM7 for GPS and GLONASS, 1006 every 5 s
+// and 1033 once.
+func testFrames(t *testing.T, start time.Time, seconds int) [][][]byte {
+	t.Helper()
+	enc := rtcm3.NewMSMEncoder(42)
+	marshal := func(msg rtcm3.Message) []byte {
+		payload, err := msg.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+	hdr := &rinex.ObsHeader{ReceiverType: "SEPT POLARX5"}
+	arp := &rtcm3.StationARP{MsgNum: 1006, StationID: 42, GPS: true, GLONASS: true,
+		Position: rinex.Coord{X: 4075580.3515, Y: 931854.0113, Z: 4801568.1924}}
+
+	var frames [][][]byte
+	for i := 0; i < seconds; i++ {
+		var payloads [][]byte
+		epo := &rinex.Epoch{Time: start.Add(time.Duration(i) * time.Second), ObsList: []rinex.SatObs{
+			rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGPS, Num: 5}, map[string]rinex.Obs{
+				"C1C": {Val: 22331467.258}, "L1C": {Val: 117350011.123}, "C2W": {Val: 22331470.012}, "L2W": {Val: 91441539.456}}),
+			rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGLO, Num: 10}, map[string]rinex.Obs{"C1C": {Val: 20118936.104}}),
+		}}
+		for _, msg := range enc.Messages(epo) {
+			payloads = append(payloads, marshal(msg))
+		}
+		if i%5 == 0 {
+			payloads = append(payloads, marshal(arp))
+		}
+		if i == 0 {
+			payloads = append(payloads, marshal(rtcm3.NewDescriptor(42, hdr)))
+		}
+		frames = append(frames, payloads)
+	}
+	return frames
+}
+
+func TestInspector(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC)
+	in := newInspector(start)
+	for i, payloads := range testFrames(t, start, 11) {
+		for j, payload := range payloads {
+			in.add(payload, start.Add(time.Duration(i)*time.Second+time.Duration(j)*time.Millisecond))
+		}
+	}
+	in.bytes = 10000
+
+	str := in.complete(ntrip.Stream{MP: "TEST00DEU0", Country: "DEU"}, start.Add(10*time.Second))
+	assert.Equal("RTCM 3.2", str.Format)
+	assert.Equal("1006(5),1033,1077(1),1087(1)", str.FormatDetails)
+	assert.Equal(2, str.Carrier)
+	assert.Equal([]string{"GPS", "GLO"}, str.SatSystem)
+	assert.InDelta(49.14, str.Lat, 0.01)
+	assert.InDelta(12.88, str.Lon, 0.01)
+	assert.Equal("SEPT POLARX5", str.Generator)
+	assert.Equal(8000, str.Bitrate)
+	assert.Equal("DEU", str.Country)
+
+	// configured fields are kept
+	str = in.complete(ntrip.Stream{Format: "RTCM 3.3", SatSystem: []string{"GPS"}, Carrier: 1, Lat: 50, Lon: 8}, start.Add(10*time.Second))
+	assert.Equal("RTCM 3.3", str.Format)
+	assert.Equal([]string{"GPS"}, str.SatSystem)
+	assert.Equal(1, str.Carrier)
+	assert.Equal(float32(50), str.Lat)
+
+	// no RTCM
+	in = newInspector(start)
+	in.run(io.MultiReader())
+	str = in.complete(ntrip.Stream{}, start.Add(time.Second))
+	assert.Empty(str.Format)
+	assert.Empty(str.SatSystem)
+	assert.Equal(0, str.Carrier)
+}
@@ -0,0 +1,269 @@
+package caster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+)
+
+const (
+	// DefaultRelayTimeout is the default time without data after which a relay switches to the next upstream.
+	DefaultRelayTimeout = 30 * time.Second
+
+	// DefaultRelayCheckInterval is the default interval of the health checks of the preferred upstreams.
+	DefaultRelayCheckInterval = 5 * time.Minute
+)
+
+// errFailback stops the pulling of an upstream as a preferred upstream is available again.
+var errFailback = errors.New("preferred upstream available")
+
+// Upstream is a stream of a remote caster that feeds a relay mountpoint.
+type Upstream struct {
+	Caster     string        // caster address, e.g. "ntrips://caster.example.com:443", see ntrip.NewClient
+	Mountpoint string        // mountpoint on the remote caster
+	Options    ntrip.Options // credentials, TLS and proxy settings
+}
+
+// UpstreamStatus is the health of an upstream of a relay mountpoint.
+type UpstreamStatus struct {
+	Caster     string    `json:"caster"`
+	Mountpoint string    `json:"mountpoint"`
+	Active     bool      `json:"active"`  // the relay is fed by this upstream
+	Healthy    bool      `json:"healthy"` // the last connection or health check succeeded
+	LastCheck  time.Time `json:"lastCheck"`
+	LastError  string    `json:"lastError,omitempty"`
+}
+
+// Relays returns the status of the upstreams per relay mountpoint, in the order of the upstreams.
+func (s *Server) Relays() map[string][]UpstreamStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	relays := make(map[string][]UpstreamStatus, len(s.relays))
+	for mp, status := range s.relays {
+		relays[mp] = append([]UpstreamStatus(nil), status...)
+	}
+	return relays
+}
+
+// RunRelays pulls the streams of the relay mountpoints from their upstreams until ctx is canceled.
+// A relay is fed by the first available upstream and switches to the next one on errors or if no data
+// is received for RelayTimeout. While a fallback upstream is used, the preferred upstreams are checked
+// every RelayCheckInterval and the relay switches back as soon as one delivers data. The clients stay
+// connected while switching.
+func (s *Server) RunRelays(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, mount := range s.mounts {
+		if len(mount.Upstreams) == 0 {
+			continue
+		}
+		s.mu.Lock()
+		status := make([]UpstreamStatus, len(mount.Upstreams))
+		for i, up := range mount.Upstreams {
+			status[i] = UpstreamStatus{Caster: up.Caster, Mountpoint: up.Mountpoint}
+		}
+		s.relays[mount.Stream.MP] = status
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func(mount Mountpoint) {
+			defer wg.Done()
+			s.relay(ctx, mount)
+		}(mount)
+	}
+	wg.Wait()
+}
+
+// relay feeds the relay mountpoint until ctx is canceled, with exponential backoff if all upstreams fail.
+func (s *Server) relay(ctx context.Context, mount Mountpoint) {
+	mp := mount.Stream.MP
+	var src *source
+	wait := time.Second
+	for ctx.Err() == nil {
+		var fed bool
+		for i := 0; i < len(mount.Upstreams) && ctx.Err() == nil; i++ {
+			n, err := s.pullUpstream(ctx, mount, i, &src)
+			if n > 0 {
+				fed = true
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			up := mount.Upstreams[i]
+			s.logf("%s: upstream %s/%s: %v", mp, up.Caster, up.Mountpoint, err)
+			if err == errFailback {
+				i = -1 // start again with the preferred upstream
+			}
+		}
+		if src != nil {
+			s.unregister(mp, src)
+			src = nil
+		}
+		if fed {
+			wait = time.Second
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > time.Minute {
+			wait = time.Minute
+		}
+	}
+	if src != nil {
+		s.unregister(mp, src)
+	}
+}
+
+// pullUpstream feeds the relay with the stream of the upstream i until an error occurs, no data was received
+// for RelayTimeout, a preferred upstream is available again or ctx is canceled. The source of the relay is
+// registered with the first data. It returns the number of bytes received.
+func (s *Server) pullUpstream(ctx context.Context, mount Mountpoint, i int, src **source) (int64, error) {
+	mp := mount.Stream.MP
+	up := mount.Upstreams[i]
+	c, err := ntrip.NewClient(up.Caster, up.Options)
+	if err != nil {
+		s.setUpstream(mp, i, false, err)
+		return 0, err
+	}
+	defer c.CloseIdleConnections()
+	c.Timeout = 0 // the stream is read as long as data arrives, see RelayTimeout
+
+	body, err := c.GetStream(up.Mountpoint)
+	if err != nil {
+		if body != nil {
+			body.Close()
+		}
+		s.setUpstream(mp, i, false, err)
+		return 0, err
+	}
+	defer body.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var mu sync.Mutex
+	var failback bool
+	go func() {
+		if i > 0 && s.awaitPreferred(ctx, mount, i) {
+			mu.Lock()
+			failback = true
+			mu.Unlock()
+		}
+		body.Close()
+	}()
+
+	idle := time.AfterFunc(s.RelayTimeout, func() { body.Close() })
+	defer idle.Stop()
+	r := &idleReader{r: body, idle: idle, timeout: s.RelayTimeout}
+
+	first := make([]byte, 4096)
+	n, err := r.Read(first)
+	if n == 0 {
+		if err == nil {
+			err = io.ErrNoProgress
+		}
+		s.setUpstream(mp, i, false, err)
+		return 0, err
+	}
+	if *src == nil {
+		if *src = s.register(mp); *src == nil {
+			return r.n, fmt.Errorf("mountpoint in use")
+		}
+		s.logf("%s: relay connected", mp)
+	}
+	s.setUpstream(mp, i, true, nil)
+
+	err = s.feed(*src, mount.Stream, io.MultiReader(bytes.NewReader(first[:n]), r))
+	mu.Lock()
+	defer mu.Unlock()
+	if failback {
+		err = errFailback
+	} else if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	s.setUpstream(mp, i, false, err)
+	return r.n, err
+}
+
+// awaitPreferred checks the upstreams preferred to the upstream i every RelayCheckInterval until one
+// delivers data, then it returns true, or until ctx is canceled.
+func (s *Server) awaitPreferred(ctx context.Context, mount Mountpoint, i int) bool {
+	ticker := time.NewTicker(s.RelayCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		for j := 0; j < i; j++ {
+			err := s.probe(mount.Upstreams[j])
+			s.setUpstream(mount.Stream.MP, j, false, err)
+			if err == nil {
+				return true
+			}
+		}
+	}
+}
+
+// probe checks whether the upstream delivers data within RelayTimeout.
+func (s *Server) probe(up Upstream) error {
+	c, err := ntrip.NewClient(up.Caster, up.Options)
+	if err != nil {
+		return err
+	}
+	defer c.CloseIdleConnections()
+	c.Timeout = s.RelayTimeout
+	body, err := c.GetStream(up.Mountpoint)
+	if body != nil {
+		defer body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := body.Read(make([]byte, 1)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setUpstream sets the status of the upstream i of the relay mountpoint mp. An upstream without error is
+// healthy, active marks the upstream feeding the relay.
+func (s *Server) setUpstream(mp string, i int, active bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.relays[mp]
+	if i >= len(status) {
+		return
+	}
+	st := &status[i]
+	st.Active = active
+	st.Healthy = err == nil || err == errFailback
+	st.LastCheck = time.Now()
+	st.LastError = ""
+	if err != nil && err != errFailback {
+		st.LastError = err.Error()
+	}
+}
+
+// idleReader resets the idle timer on every read with data and counts the bytes.
+type idleReader struct {
+	r       io.Reader
+	idle    *time.Timer
+	timeout time.Duration
+	n       int64
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if n > 0 {
+		ir.idle.Reset(ir.timeout)
+		ir.n += int64(n)
+	}
+	return n, err
+}
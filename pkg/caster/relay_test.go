@@ -0,0 +1,137 @@
+package caster
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/stretchr/testify/assert"
+)
+
+// upstream is a remote caster that streams data while it is up.
+type upstream struct {
+	data string
+	up   int32
+}
+
+func (u *upstream) setUp(up bool) {
+	var v int32
+	if up {
+		v = 1
+	}
+	atomic.StoreInt32(&u.up, v)
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&u.up) == 0 {
+		http.Error(w, "no source", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "gnss/data")
+	for atomic.LoadInt32(&u.up) == 1 && r.Context().Err() == nil {
+		if _, err := io.WriteString(w, u.data); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readUntil reads from r until the data s arrives.
+func readUntil(t *testing.T, r io.Reader, s string) {
+	t.Helper()
+	buf := make([]byte, len(s))
+	for i := 0; i < 200; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatalf("waiting for %q: %v", s, err)
+		}
+		if string(buf) == s {
+			return
+		}
+	}
+	t.Fatalf("no data %q", s)
+}
+
+func TestServer_relay(t *testing.T) {
+	assert := assert.New(t)
+	primary, fallback := &upstream{data: "A"}, &upstream{data: "B"}
+	fallback.setUp(true)
+	tsA, tsB := httptest.NewServer(primary), httptest.NewServer(fallback)
+	defer tsA.Close()
+	defer tsB.Close()
+
+	srv := NewServer([]Mountpoint{{
+		Stream: ntrip.Stream{MP: "RELAY00DEU0"},
+		Upstreams: []Upstream{
+			{Caster: tsA.URL, Mountpoint: "TEST00DEU0"},
+			{Caster: tsB.URL, Mountpoint: "TEST00DEU0"},
+		},
+	}})
+	srv.Logger = log.New(ioutil.Discard, "", 0)
+	srv.InspectTime = 0
+	srv.RelayTimeout = time.Second
+	srv.RelayCheckInterval = 100 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.RunRelays(ctx)
+		close(done)
+	}()
+	waitOnline(t, srv, "RELAY00DEU0")
+
+	// NtripServers are rejected
+	source, err := ntrip.NewClient(ts.URL, ntrip.Options{})
+	assert.NoError(err)
+	assert.Error(source.PostStream("RELAY00DEU0", strings.NewReader("data")))
+
+	cl, err := ntrip.NewClient(ts.URL, ntrip.Options{})
+	assert.NoError(err)
+	cl.Timeout = 0
+	r, err := cl.GetStream("RELAY00DEU0")
+	assert.NoError(err)
+	defer r.Close()
+	readUntil(t, r, "B")
+	status := srv.Relays()["RELAY00DEU0"]
+	if assert.Len(status, 2) {
+		assert.False(status[0].Healthy)
+		assert.NotEmpty(status[0].LastError)
+		assert.True(status[1].Active)
+	}
+
+	// failback to the preferred upstream, the client stays connected
+	primary.setUp(true)
+	readUntil(t, r, "A")
+	status = srv.Relays()["RELAY00DEU0"]
+	assert.True(status[0].Active)
+	assert.True(status[0].Healthy)
+	assert.False(status[1].Active)
+
+	// failover
+	primary.setUp(false)
+	readUntil(t, r, "B")
+
+	// the relay goes offline with the last upstream
+	fallback.setUp(false)
+	_, err = ioutil.ReadAll(r)
+	assert.NoError(err)
+	_, ok := srv.Sourcetable().HasStream("RELAY00DEU0")
+	assert.False(ok)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunRelays not stopped")
+	}
+}
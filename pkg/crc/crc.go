@@ -0,0 +1,163 @@
+// Package crc implements the checksums used by GNSS data formats: the CRC-24Q of RTCM 3 and
+// the GPS/SBAS navigation messages, the CRC-16/CCITT of BINEX and the XOR checksum of NMEA 0183.
+//
+// Each checksum is available as function for a single buffer and as hash.Hash for streaming input.
+// Sum appends the checksum in big-endian byte order, as it is transmitted in the messages.
+package crc
+
+import "hash"
+
+// The size of the checksums in bytes.
+const (
+	Size24Q   = 3
+	SizeCCITT = 2
+	SizeNMEA  = 1
+)
+
+// Hash16 is the common interface implemented by all 16-bit hash functions.
+type Hash16 interface {
+	hash.Hash
+	Sum16() uint16
+}
+
+// Hash8 is the common interface implemented by all 8-bit hash functions.
+type Hash8 interface {
+	hash.Hash
+	Sum8() uint8
+}
+
+// CRC-24Q, polynomial 0x864CFB, initial value 0.
+
+var crc24qTable = func() [256]uint32 {
+	var tab [256]uint32
+	for i := range tab {
+		crc := uint32(i) << 16
+		for j := 0; j < 8; j++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+		tab[i] = crc & 0xFFFFFF
+	}
+	return tab
+}()
+
+func update24Q(crc uint32, p []byte) uint32 {
+	for _, b := range p {
+		crc = (crc<<8)&0xFFFFFF ^ crc24qTable[byte(crc>>16)^b]
+	}
+	return crc
+}
+
+// CRC24Q returns the CRC-24Q checksum of data.
+func CRC24Q(data []byte) uint32 {
+	return update24Q(0, data)
+}
+
+type digest24Q uint32
+
+// New24Q returns a new hash.Hash32 computing the CRC-24Q checksum.
+func New24Q() hash.Hash32 {
+	d := digest24Q(0)
+	return &d
+}
+
+func (d *digest24Q) Size() int      { return Size24Q }
+func (d *digest24Q) BlockSize() int { return 1 }
+func (d *digest24Q) Reset()         { *d = 0 }
+func (d *digest24Q) Sum32() uint32  { return uint32(*d) }
+
+func (d *digest24Q) Write(p []byte) (int, error) {
+	*d = digest24Q(update24Q(uint32(*d), p))
+	return len(p), nil
+}
+
+func (d *digest24Q) Sum(in []byte) []byte {
+	s := uint32(*d)
+	return append(in, byte(s>>16), byte(s>>8), byte(s))
+}
+
+// CRC-16/CCITT, polynomial 0x1021, initial value 0.
+
+var ccittTable = func() [256]uint16 {
+	var tab [256]uint16
+	for i := range tab {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		tab[i] = crc
+	}
+	return tab
+}()
+
+func updateCCITT(crc uint16, p []byte) uint16 {
+	for _, b := range p {
+		crc = crc<<8 ^ ccittTable[byte(crc>>8)^b]
+	}
+	return crc
+}
+
+// CCITT returns the CRC-16/CCITT checksum of data.
+func CCITT(data []byte) uint16 {
+	return updateCCITT(0, data)
+}
+
+type digestCCITT uint16
+
+// NewCCITT returns a new Hash16 computing the CRC-16/CCITT checksum.
+func NewCCITT() Hash16 {
+	d := digestCCITT(0)
+	return &d
+}
+
+func (d *digestCCITT) Size() int      { return SizeCCITT }
+func (d *digestCCITT) BlockSize() int { return 1 }
+func (d *digestCCITT) Reset()         { *d = 0 }
+func (d *digestCCITT) Sum16() uint16  { return uint16(*d) }
+
+func (d *digestCCITT) Write(p []byte) (int, error) {
+	*d = digestCCITT(updateCCITT(uint16(*d), p))
+	return len(p), nil
+}
+
+func (d *digestCCITT) Sum(in []byte) []byte {
+	s := uint16(*d)
+	return append(in, byte(s>>8), byte(s))
+}
+
+// NMEA returns the XOR checksum of data, i.e. of the characters between '$' and '*' of a sentence.
+func NMEA(data []byte) uint8 {
+	var cs uint8
+	for _, b := range data {
+		cs ^= b
+	}
+	return cs
+}
+
+type digestNMEA uint8
+
+// NewNMEA returns a new Hash8 computing the NMEA 0183 XOR checksum.
+func NewNMEA() Hash8 {
+	d := digestNMEA(0)
+	return &d
+}
+
+func (d *digestNMEA) Size() int      { return SizeNMEA }
+func (d *digestNMEA) BlockSize() int { return 1 }
+func (d *digestNMEA) Reset()         { *d = 0 }
+func (d *digestNMEA) Sum8() uint8    { return uint8(*d) }
+
+func (d *digestNMEA) Write(p []byte) (int, error) {
+	*d ^= digestNMEA(NMEA(p))
+	return len(p), nil
+}
+
+func (d *digestNMEA) Sum(in []byte) []byte {
+	return append(in, byte(*d))
+}
@@ -0,0 +1,51 @@
+package crc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var check = []byte("123456789")
+
+func TestCRC24Q(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(uint32(0xCDE703), CRC24Q(check))
+
+	// RTCM 3 message 1005 example, without the CRC
+	frame := []byte{0xD3, 0x00, 0x13, 0x3E, 0xD7, 0xD3, 0x02, 0x02, 0x98, 0x0E, 0xDE, 0xEF, 0x34, 0xB4, 0xBD,
+		0x62, 0xAC, 0x09, 0x41, 0x98, 0x6F, 0x33}
+	assert.Equal(uint32(0x360B98), CRC24Q(frame))
+
+	h := New24Q()
+	h.Write(frame[:5])
+	h.Write(frame[5:])
+	assert.Equal(uint32(0x360B98), h.Sum32())
+	assert.Equal([]byte{0x01, 0x36, 0x0B, 0x98}, h.Sum([]byte{0x01}))
+	assert.Equal(Size24Q, h.Size())
+	h.Reset()
+	assert.Equal(uint32(0), h.Sum32())
+}
+
+func TestCCITT(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(uint16(0x31C3), CCITT(check))
+
+	h := NewCCITT()
+	h.Write(check[:3])
+	h.Write(check[3:])
+	assert.Equal(uint16(0x31C3), h.Sum16())
+	assert.Equal([]byte{0x31, 0xC3}, h.Sum(nil))
+}
+
+func TestNMEA(t *testing.T) {
+	assert := assert.New(t)
+	data := []byte("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")
+	assert.Equal(uint8(0x47), NMEA(data))
+
+	h := NewNMEA()
+	h.Write(data[:10])
+	h.Write(data[10:])
+	assert.Equal(uint8(0x47), h.Sum8())
+	assert.Equal([]byte{0x47}, h.Sum(nil))
+}
@@ -1,7 +1,13 @@
 // Package gnss contains common constants and type definitions.
 package gnss
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
+
+// SpeedOfLight is the speed of light in vacuum in m/s.
+const SpeedOfLight = 299792458.0
 
 // System is a satellite system.
 type System int
@@ -38,3 +44,83 @@ func (syss Systems) String() string {
 	}
 	return strings.Join(str, "+")
 }
+
+// sysPerAbbr maps the RINEX abbreviations to the satellite systems.
+var sysPerAbbr = map[string]System{
+	"G": SysGPS,
+	"R": SysGLO,
+	"E": SysGAL,
+	"J": SysQZSS,
+	"C": SysBDS,
+	"I": SysIRNSS,
+	"S": SysSBAS,
+	"M": SysMIXED,
+}
+
+// SystemByAbbr returns the satellite system of the RINEX abbreviation, e.g. "I" for IRNSS/NavIC.
+// It returns false if the abbreviation is unknown.
+func SystemByAbbr(abbr string) (System, bool) {
+	sys, ok := sysPerAbbr[abbr]
+	return sys, ok
+}
+
+// SystemSet is a set of satellite systems. The zero value is the empty set.
+type SystemSet uint16
+
+// AllSystems contains all satellite systems, without SysMIXED.
+const AllSystems = SystemSet(1<<SysMIXED - 1<<SysGPS)
+
+// NewSystemSet returns the set of the given systems.
+func NewSystemSet(syss ...System) SystemSet {
+	var set SystemSet
+	for _, sys := range syss {
+		set = set.Add(sys)
+	}
+	return set
+}
+
+// ParseSystemSet parses a set of satellite systems given by their RINEX abbreviations, e.g. "GRE".
+func ParseSystemSet(s string) (SystemSet, error) {
+	var set SystemSet
+	for _, c := range s {
+		sys, ok := sysPerAbbr[string(c)]
+		if !ok || sys == SysMIXED {
+			return 0, fmt.Errorf("invalid satellite system: %q", c)
+		}
+		set = set.Add(sys)
+	}
+	return set, nil
+}
+
+// Add returns the set with sys added.
+func (set SystemSet) Add(sys System) SystemSet {
+	if sys < SysGPS || sys >= SysMIXED {
+		return set
+	}
+	return set | 1<<sys
+}
+
+// Contains reports whether sys is in the set.
+func (set SystemSet) Contains(sys System) bool {
+	return sys >= SysGPS && sys < SysMIXED && set&(1<<sys) != 0
+}
+
+// Systems returns the systems of the set in the order of their constants, GPS first.
+func (set SystemSet) Systems() Systems {
+	syss := make(Systems, 0, SysMIXED-SysGPS)
+	for sys := SysGPS; sys < SysMIXED; sys++ {
+		if set.Contains(sys) {
+			syss = append(syss, sys)
+		}
+	}
+	return syss
+}
+
+// String returns the abbreviations of the systems, e.g. "GRE".
+func (set SystemSet) String() string {
+	var b strings.Builder
+	for _, sys := range set.Systems() {
+		b.WriteString(sys.Abbr())
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package gnss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemByAbbr(t *testing.T) {
+	assert := assert.New(t)
+	sys, ok := SystemByAbbr("I")
+	assert.True(ok)
+	assert.Equal(SysIRNSS, sys)
+	sys, ok = SystemByAbbr("S")
+	assert.True(ok)
+	assert.Equal(SysSBAS, sys)
+	_, ok = SystemByAbbr("X")
+	assert.False(ok)
+}
+
+func TestParseSystemSet(t *testing.T) {
+	assert := assert.New(t)
+	set, err := ParseSystemSet("GREIS")
+	assert.NoError(err)
+	assert.True(set.Contains(SysGPS))
+	assert.True(set.Contains(SysIRNSS))
+	assert.True(set.Contains(SysSBAS))
+	assert.False(set.Contains(SysBDS))
+	assert.False(set.Contains(SysMIXED))
+	assert.Equal(Systems{SysGPS, SysGLO, SysGAL, SysIRNSS, SysSBAS}, set.Systems())
+	assert.Equal("GREIS", set.String())
+	assert.Equal(set, NewSystemSet(SysSBAS, SysGAL, SysGLO, SysIRNSS, SysGPS, SysGPS))
+
+	set, err = ParseSystemSet("")
+	assert.NoError(err)
+	assert.Equal(SystemSet(0), set)
+	assert.Empty(set.Systems())
+
+	_, err = ParseSystemSet("GX")
+	assert.Error(err)
+	_, err = ParseSystemSet("M")
+	assert.Error(err)
+
+	assert.Equal("GREJCIS", AllSystems.String())
+	assert.Equal(SystemSet(0), NewSystemSet(SysMIXED, 0), "invalid systems are ignored")
+}
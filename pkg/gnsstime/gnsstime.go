@@ -0,0 +1,276 @@
+// Package gnsstime provides conversions between the time scales used in GNSS: UTC, GPS time,
+// Galileo System Time, BeiDou time and GLONASS time, as well as MJD, GPS week and time of week
+// and year and day of year.
+//
+// All times are represented as time.Time in location UTC, holding the clock reading of the respective
+// time scale, as it is common practice for RINEX epochs. Galileo System Time is steered to GPS time
+// and treated as identical, the GGTO is ignored.
+//
+// The difference GPS-UTC is taken from a built-in leap second table, which can be extended with
+// AddLeapSecond or UpdateLeapSeconds, e.g. by the leap seconds given in RINEX headers or navigation
+// messages. The table is shared by the whole process and safe for concurrent use.
+package gnsstime
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The epochs of the time scales.
+var (
+	GPSEpoch = time.Date(1980, 1, 6, 0, 0, 0, 0, time.UTC)
+	GALEpoch = time.Date(1999, 8, 22, 0, 0, 0, 0, time.UTC)
+	BDSEpoch = time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// mjdEpoch is the start of the Modified Julian Date.
+var mjdEpoch = time.Date(1858, 11, 17, 0, 0, 0, 0, time.UTC)
+
+const (
+	// Week is the length of a GPS week.
+	Week = 7 * 24 * time.Hour
+
+	// BDSOffset is the difference GPS-BDT, constant since the BDT epoch.
+	BDSOffset = 14 * time.Second
+
+	// gloOffset is the difference GLONASS time - UTC.
+	gloOffset = 3 * time.Hour
+)
+
+// LeapSecond is an entry of the leap second table: from the UTC time Start on,
+// GPS-UTC is Offset seconds.
+type LeapSecond struct {
+	Start  time.Time
+	Offset int
+}
+
+var (
+	leapMu    sync.RWMutex
+	leapTable = []LeapSecond{
+		{time.Date(1981, 7, 1, 0, 0, 0, 0, time.UTC), 1},
+		{time.Date(1982, 7, 1, 0, 0, 0, 0, time.UTC), 2},
+		{time.Date(1983, 7, 1, 0, 0, 0, 0, time.UTC), 3},
+		{time.Date(1985, 7, 1, 0, 0, 0, 0, time.UTC), 4},
+		{time.Date(1988, 1, 1, 0, 0, 0, 0, time.UTC), 5},
+		{time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), 6},
+		{time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), 7},
+		{time.Date(1992, 7, 1, 0, 0, 0, 0, time.UTC), 8},
+		{time.Date(1993, 7, 1, 0, 0, 0, 0, time.UTC), 9},
+		{time.Date(1994, 7, 1, 0, 0, 0, 0, time.UTC), 10},
+		{time.Date(1996, 1, 1, 0, 0, 0, 0, time.UTC), 11},
+		{time.Date(1997, 7, 1, 0, 0, 0, 0, time.UTC), 12},
+		{time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), 13},
+		{time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC), 14},
+		{time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), 15},
+		{time.Date(2012, 7, 1, 0, 0, 0, 0, time.UTC), 16},
+		{time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), 17},
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 18},
+	}
+)
+
+// AddLeapSecond adds a leap second to the table: from the UTC time start on, GPS-UTC is offset seconds.
+// An existing entry with the same start time is replaced. AddLeapSecond is safe for concurrent use.
+func AddLeapSecond(start time.Time, offset int) {
+	leapMu.Lock()
+	defer leapMu.Unlock()
+	leapTable = insertLeapSecond(leapTable, LeapSecond{Start: start.UTC(), Offset: offset})
+}
+
+// UpdateLeapSeconds checks the leap seconds, e.g. of a RINEX header, against the table and adds the new
+// ones. As wrong leap seconds are common, a leap second after the last entry of the table is only added
+// if GPS-UTC increases by one second. If a leap second contradicts the table, UpdateLeapSeconds returns an
+// error and leaves the table unchanged. The check and the update are atomic, so concurrent updates with the
+// same leap seconds, e.g. of files decoded in parallel, are safe.
+func UpdateLeapSeconds(leaps ...LeapSecond) error {
+	leaps = append([]LeapSecond(nil), leaps...)
+	for i := range leaps {
+		leaps[i].Start = leaps[i].Start.UTC()
+	}
+	sort.Slice(leaps, func(i, j int) bool { return leaps[i].Start.Before(leaps[j].Start) })
+
+	leapMu.Lock()
+	defer leapMu.Unlock()
+	tab := append([]LeapSecond(nil), leapTable...)
+	for _, ls := range leaps {
+		var last LeapSecond
+		if len(tab) > 0 {
+			last = tab[len(tab)-1]
+		}
+		if ls.Start.After(last.Start) {
+			if ls.Offset == last.Offset {
+				continue
+			}
+			if ls.Offset == last.Offset+1 {
+				tab = append(tab, ls)
+				continue
+			}
+		}
+		if known := leapSecondsOf(tab, ls.Start); ls.Offset != known {
+			return fmt.Errorf("leap seconds %d at %s contradict the leap second table: %d", ls.Offset,
+				ls.Start.Format(time.RFC3339), known)
+		}
+	}
+	leapTable = tab
+	return nil
+}
+
+// insertLeapSecond inserts the leap second into the sorted table, replacing an entry with the same start time.
+func insertLeapSecond(tab []LeapSecond, ls LeapSecond) []LeapSecond {
+	i := sort.Search(len(tab), func(i int) bool { return !tab[i].Start.Before(ls.Start) })
+	if i < len(tab) && tab[i].Start.Equal(ls.Start) {
+		tab[i].Offset = ls.Offset
+		return tab
+	}
+	tab = append(tab, LeapSecond{})
+	copy(tab[i+1:], tab[i:])
+	tab[i] = ls
+	return tab
+}
+
+// SetLeapSecondTable replaces the leap second table, e.g. by an up-to-date table or to restore
+// a table saved by LeapSecondTable. SetLeapSecondTable is safe for concurrent use.
+func SetLeapSecondTable(tab []LeapSecond) {
+	tab = append([]LeapSecond(nil), tab...)
+	for i := range tab {
+		tab[i].Start = tab[i].Start.UTC()
+	}
+	sort.Slice(tab, func(i, j int) bool { return tab[i].Start.Before(tab[j].Start) })
+	leapMu.Lock()
+	leapTable = tab
+	leapMu.Unlock()
+}
+
+// LeapSecondTable returns a copy of the leap second table.
+func LeapSecondTable() []LeapSecond {
+	leapMu.RLock()
+	defer leapMu.RUnlock()
+	return append([]LeapSecond(nil), leapTable...)
+}
+
+// LeapSeconds returns GPS-UTC in seconds at the UTC time t.
+func LeapSeconds(t time.Time) int {
+	leapMu.RLock()
+	defer leapMu.RUnlock()
+	return leapSecondsOf(leapTable, t)
+}
+
+// leapSecondsOf returns GPS-UTC in seconds at the UTC time t from the table tab.
+func leapSecondsOf(tab []LeapSecond, t time.Time) int {
+	for i := len(tab) - 1; i >= 0; i-- {
+		if !t.Before(tab[i].Start) {
+			return tab[i].Offset
+		}
+	}
+	return 0
+}
+
+// LeapSecondsGPS returns GPS-UTC in seconds at the GPS time t.
+func LeapSecondsGPS(t time.Time) int {
+	leapMu.RLock()
+	defer leapMu.RUnlock()
+	for i := len(leapTable) - 1; i >= 0; i-- {
+		ls := leapTable[i]
+		if !t.Before(ls.Start.Add(time.Duration(ls.Offset) * time.Second)) {
+			return ls.Offset
+		}
+	}
+	return 0
+}
+
+// UTCToGPS converts UTC to GPS time.
+func UTCToGPS(t time.Time) time.Time {
+	return t.Add(time.Duration(LeapSeconds(t)) * time.Second)
+}
+
+// GPSToUTC converts GPS time to UTC.
+func GPSToUTC(t time.Time) time.Time {
+	return t.Add(-time.Duration(LeapSecondsGPS(t)) * time.Second)
+}
+
+// GPSToBDS converts GPS time to BeiDou time.
+func GPSToBDS(t time.Time) time.Time {
+	return t.Add(-BDSOffset)
+}
+
+// BDSToGPS converts BeiDou time to GPS time.
+func BDSToGPS(t time.Time) time.Time {
+	return t.Add(BDSOffset)
+}
+
+// UTCToGLO converts UTC to GLONASS time, that is UTC(SU) + 3 hours.
+func UTCToGLO(t time.Time) time.Time {
+	return t.Add(gloOffset)
+}
+
+// GLOToUTC converts GLONASS time to UTC.
+func GLOToUTC(t time.Time) time.Time {
+	return t.Add(-gloOffset)
+}
+
+// GPSToGLO converts GPS time to GLONASS time.
+func GPSToGLO(t time.Time) time.Time {
+	return UTCToGLO(GPSToUTC(t))
+}
+
+// GLOToGPS converts GLONASS time to GPS time.
+func GLOToGPS(t time.Time) time.Time {
+	return UTCToGPS(GLOToUTC(t))
+}
+
+// GPSWeek returns the continuous GPS week and the time of week in seconds of the GPS time t.
+func GPSWeek(t time.Time) (week int, tow float64) {
+	return weekTow(t, GPSEpoch)
+}
+
+// FromGPSWeek returns the GPS time for the continuous GPS week and the time of week in seconds.
+func FromGPSWeek(week int, tow float64) time.Time {
+	return fromWeekTow(GPSEpoch, week, tow)
+}
+
+// BDSWeek returns the BeiDou week and the time of week in seconds of the BeiDou time t.
+func BDSWeek(t time.Time) (week int, tow float64) {
+	return weekTow(t, BDSEpoch)
+}
+
+// FromBDSWeek returns the BeiDou time for the BeiDou week and the time of week in seconds.
+func FromBDSWeek(week int, tow float64) time.Time {
+	return fromWeekTow(BDSEpoch, week, tow)
+}
+
+func weekTow(t, epoch time.Time) (int, float64) {
+	d := t.Sub(epoch)
+	week := int(d / Week)
+	if d < 0 && d%Week != 0 {
+		week--
+	}
+	return week, (d - time.Duration(week)*Week).Seconds()
+}
+
+func fromWeekTow(epoch time.Time, week int, tow float64) time.Time {
+	return epoch.Add(time.Duration(week)*Week + time.Duration(math.Round(tow*1e9)))
+}
+
+// MJD returns the Modified Julian Date of t.
+func MJD(t time.Time) float64 {
+	return t.Sub(mjdEpoch).Hours() / 24
+}
+
+// FromMJD returns the time for the Modified Julian Date mjd, rounded to microseconds.
+func FromMJD(mjd float64) time.Time {
+	day := math.Floor(mjd)
+	frac := time.Duration(math.Round((mjd-day)*864e8)) * time.Microsecond
+	return mjdEpoch.AddDate(0, 0, int(day)).Add(frac)
+}
+
+// YearDOY returns the year and the day of year of t.
+func YearDOY(t time.Time) (year, doy int) {
+	return t.Year(), t.YearDay()
+}
+
+// FromYearDOY returns the start of the day of year doy.
+func FromYearDOY(year, doy int) time.Time {
+	return time.Date(year, 1, doy, 0, 0, 0, 0, time.UTC)
+}
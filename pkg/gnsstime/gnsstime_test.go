@@ -0,0 +1,135 @@
+package gnsstime
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeapSeconds(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(0, LeapSeconds(GPSEpoch))
+	assert.Equal(17, LeapSeconds(time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC)))
+	assert.Equal(18, LeapSeconds(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	// GPS time 2017-01-01 00:00:17 is UTC 2016-12-31 23:59:60
+	assert.Equal(17, LeapSecondsGPS(time.Date(2017, 1, 1, 0, 0, 16, 0, time.UTC)))
+	assert.Equal(18, LeapSecondsGPS(time.Date(2017, 1, 1, 0, 0, 18, 0, time.UTC)))
+
+	utc := time.Date(2020, 6, 17, 12, 0, 0, 0, time.UTC)
+	gps := UTCToGPS(utc)
+	assert.Equal(utc.Add(18*time.Second), gps)
+	assert.Equal(utc, GPSToUTC(gps))
+}
+
+func TestAddLeapSecond(t *testing.T) {
+	assert := assert.New(t)
+	defer func(tab []LeapSecond) { leapTable = tab }(LeapSecondTable())
+
+	start := time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC)
+	AddLeapSecond(start, 19)
+	assert.Equal(18, LeapSeconds(start.Add(-time.Second)))
+	assert.Equal(19, LeapSeconds(start))
+	AddLeapSecond(start, 17) // negative leap second
+	assert.Equal(17, LeapSeconds(start))
+	assert.Len(LeapSecondTable(), 19)
+
+	// insert in between
+	AddLeapSecond(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 19)
+	assert.Equal(19, LeapSeconds(time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(17, LeapSeconds(start))
+}
+
+func TestSetLeapSecondTable(t *testing.T) {
+	assert := assert.New(t)
+	tab := LeapSecondTable()
+	defer SetLeapSecondTable(tab)
+
+	SetLeapSecondTable([]LeapSecond{
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 18},
+		{time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), 17},
+	})
+	assert.Equal(0, LeapSeconds(time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(17, LeapSeconds(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(18, LeapSeconds(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	SetLeapSecondTable(tab)
+	assert.Equal(tab, LeapSecondTable())
+}
+
+func TestUpdateLeapSeconds(t *testing.T) {
+	assert := assert.New(t)
+	tab := LeapSecondTable()
+	defer SetLeapSecondTable(tab)
+
+	next := LeapSecond{Start: time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC), Offset: 19}
+	assert.NoError(UpdateLeapSeconds(LeapSecond{Start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Offset: 18}))
+	assert.Equal(tab, LeapSecondTable(), "known leap seconds")
+
+	for _, leaps := range [][]LeapSecond{
+		{{Start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Offset: 17}},
+		{{Start: next.Start, Offset: 37}},
+		{next, {Start: next.Start.AddDate(1, 0, 0), Offset: 21}},
+	} {
+		assert.Error(UpdateLeapSeconds(leaps...), "%v", leaps)
+		assert.Equal(tab, LeapSecondTable())
+	}
+
+	// concurrent updates with the same new leap second add it once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(UpdateLeapSeconds(next))
+			assert.Equal(19, LeapSeconds(next.Start))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(append(tab, next), LeapSecondTable())
+}
+
+func TestTimeScales(t *testing.T) {
+	assert := assert.New(t)
+	gps := time.Date(2020, 6, 17, 12, 0, 18, 0, time.UTC)
+	assert.Equal(time.Date(2020, 6, 17, 12, 0, 4, 0, time.UTC), GPSToBDS(gps))
+	assert.Equal(gps, BDSToGPS(GPSToBDS(gps)))
+	assert.Equal(time.Date(2020, 6, 17, 15, 0, 0, 0, time.UTC), GPSToGLO(gps))
+	assert.Equal(gps, GLOToGPS(GPSToGLO(gps)))
+}
+
+func TestWeek(t *testing.T) {
+	assert := assert.New(t)
+	gps := time.Date(2020, 6, 17, 12, 0, 0, 500000000, time.UTC)
+	week, tow := GPSWeek(gps)
+	assert.Equal(2110, week)
+	assert.Equal(3*86400+12*3600+0.5, tow)
+	assert.Equal(gps, FromGPSWeek(week, tow))
+
+	week, tow = GPSWeek(GPSEpoch.Add(-time.Hour))
+	assert.Equal(-1, week)
+	assert.Equal(float64(7*86400-3600), tow)
+
+	week, tow = BDSWeek(GPSToBDS(gps))
+	assert.Equal(754, week)
+	assert.Equal(3*86400+12*3600-14+0.5, tow)
+	assert.Equal(GPSToBDS(gps), FromBDSWeek(week, tow))
+}
+
+func TestMJD(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(44244.0, MJD(GPSEpoch))
+	tt := time.Date(2020, 6, 17, 6, 0, 0, 0, time.UTC)
+	assert.Equal(59017.25, MJD(tt))
+	assert.Equal(tt, FromMJD(59017.25))
+}
+
+func TestYearDOY(t *testing.T) {
+	assert := assert.New(t)
+	year, doy := YearDOY(time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC))
+	assert.Equal(2020, year)
+	assert.Equal(366, doy)
+	assert.Equal(time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC), FromYearDOY(2020, 169))
+}
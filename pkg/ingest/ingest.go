@@ -0,0 +1,346 @@
+// Package ingest picks up the RINEX files arriving in incoming directories, checks them and files
+// them into the archive, e.g. as daemon of a data center.
+//
+// The incoming directories are watched for new files and rescanned periodically, so files are not
+// missed if events get lost, e.g. on network file systems. A file is picked up if it was not modified
+// for MinAge, to skip files still being uploaded. The checks, e.g. validation, QC and renaming, are a
+// chain of batch steps. Good files are moved into the archive, by default in the layout YYYY/DOY/site,
+// see archive.Layout. Bad files are moved into the quarantine directory, with the reason appended to its log.
+//
+//	in := &ingest.Ingester{
+//		Dirs:       []string{"/data/incoming"},
+//		Archive:    "/data/archive",
+//		Quarantine: "/data/quarantine",
+//		Steps:      []batch.Step{batch.Validate(), batch.QC(80), batch.Rename(conv)},
+//	}
+//	err := in.Run(ctx)
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/archive"
+	"github.com/de-bkg/gognss/pkg/batch"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Defaults of the Ingester.
+const (
+	DefaultMinAge = 5 * time.Second
+	DefaultRescan = time.Minute
+)
+
+// QuarantineLog is the log in the quarantine directory, with a line per file: time, file name and
+// reason, separated by tabs.
+const QuarantineLog = "quarantine.log"
+
+// Result is the result of an ingested file.
+type Result struct {
+	File        string        `json:"file"`                  // the incoming file
+	Path        string        `json:"path"`                  // the archived or quarantined file
+	Quarantined bool          `json:"quarantined,omitempty"` // the file failed a step
+	Reason      string        `json:"reason,omitempty"`      // why the file was quarantined
+	QC          *rinex.Report `json:"qc,omitempty"`          // of observation files checked by batch.QC
+	Time        time.Time     `json:"time"`
+}
+
+// Ingester watches the incoming directories and ingests the files into the archive.
+type Ingester struct {
+	Dirs       []string       // incoming directories, without subdirectories
+	Archive    string         // root directory of the archive
+	Layout     archive.Layout // path of a file in the archive, default archive.LayoutSite
+	Quarantine string         // directory of the bad files and the QuarantineLog
+
+	// Steps are the checks of a file, by default batch.Validate. Filing the file into the archive is
+	// appended. A file is quarantined if a step fails.
+	Steps []batch.Step
+
+	Workers int    // number of files processed in parallel, default batch.DefaultWorkers
+	WorkDir string // directory of the intermediate files, default os.TempDir()
+
+	MinAge time.Duration // time a file must be unmodified before it is picked up, default DefaultMinAge
+	Rescan time.Duration // interval of the periodic rescan, default DefaultRescan
+
+	// Match reports whether a file is ingested. By default all files are, apart from hidden ones.
+	Match func(name string) bool
+
+	// OnResult is called for each ingested file, e.g. for logging.
+	OnResult func(res Result)
+
+	// Logger logs the ingested files and errors, defaults to the standard logger.
+	Logger *log.Logger
+}
+
+// Run watches the incoming directories and ingests their files until the context is canceled.
+// Run returns the context's error then.
+func (in *Ingester) Run(ctx context.Context) error {
+	if err := in.check(); err != nil {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, dir := range in.Dirs {
+		if err := w.Add(dir); err != nil {
+			return fmt.Errorf("watch %s: %w", dir, err)
+		}
+	}
+
+	rescan := time.NewTicker(durationOr(in.Rescan, DefaultRescan))
+	defer rescan.Stop()
+	settle := time.NewTimer(0) // the first scan at startup
+	defer settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-w.Events:
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) != 0 {
+				resetTimer(settle, durationOr(in.MinAge, DefaultMinAge))
+			}
+			continue
+		case err := <-w.Errors:
+			in.logf("ingest: watch: %v", err)
+			continue
+		case <-rescan.C:
+		case <-settle.C:
+		}
+
+		_, pending, err := in.scan(ctx)
+		if err != nil && ctx.Err() == nil {
+			in.logf("ingest: %v", err)
+		}
+		if pending {
+			resetTimer(settle, durationOr(in.MinAge, DefaultMinAge))
+		}
+	}
+}
+
+// Scan ingests the files of the incoming directories once, e.g. for a cron job, and returns the
+// results. Files modified within MinAge are left for the next scan.
+func (in *Ingester) Scan(ctx context.Context) ([]Result, error) {
+	if err := in.check(); err != nil {
+		return nil, err
+	}
+	res, _, err := in.scan(ctx)
+	return res, err
+}
+
+func (in *Ingester) check() error {
+	if len(in.Dirs) == 0 {
+		return errors.New("ingest: no incoming directories")
+	}
+	if in.Archive == "" || in.Quarantine == "" {
+		return errors.New("ingest: archive and quarantine directories must be set")
+	}
+	return nil
+}
+
+// scan ingests the files that are ready and reports whether files are pending, i.e. modified
+// within MinAge.
+func (in *Ingester) scan(ctx context.Context) ([]Result, bool, error) {
+	files, pending, err := in.readyFiles()
+	if len(files) == 0 {
+		return nil, pending, err
+	}
+
+	steps := in.Steps
+	if steps == nil {
+		steps = []batch.Step{batch.Validate()}
+	}
+	runner := &batch.Runner{
+		Steps:   append(steps[:len(steps):len(steps)], in.archiveStep()),
+		Workers: in.Workers,
+		WorkDir: in.WorkDir,
+	}
+	rep, runErr := runner.Run(ctx, files)
+	if rep == nil {
+		return nil, pending, runErr
+	}
+
+	var results []Result
+	for _, job := range rep.Jobs {
+		res := Result{File: job.Input, Path: job.Path, QC: job.QC, Time: time.Now().UTC()}
+		switch job.Status {
+		case batch.StatusDone:
+			os.Remove(job.Input) // if only a copy was archived, e.g. by batch.Rename
+			in.logf("ingest: %s: archived as %s", filepath.Base(job.Input), job.Path)
+		case batch.StatusFailed:
+			res.Quarantined, res.Reason = true, job.Err
+			path, qerr := in.quarantine(job.Input, job.Err, res.Time)
+			if qerr != nil {
+				in.logf("ingest: %s: quarantine: %v", filepath.Base(job.Input), qerr)
+				continue
+			}
+			res.Path = path
+			in.logf("ingest: %s: quarantined: %s", filepath.Base(job.Input), job.Err)
+		default: // interrupted, left for the next run
+			continue
+		}
+		results = append(results, res)
+		if in.OnResult != nil {
+			in.OnResult(res)
+		}
+	}
+	if err == nil {
+		err = runErr
+	}
+	return results, pending, err
+}
+
+// readyFiles returns the matching files of the incoming directories that were not modified within
+// MinAge, and reports whether other files are pending.
+func (in *Ingester) readyFiles() ([]string, bool, error) {
+	minAge := durationOr(in.MinAge, DefaultMinAge)
+	now := time.Now()
+	var files []string
+	var pending bool
+	var firstErr error
+	for _, dir := range in.Dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, fi := range infos {
+			if !fi.Mode().IsRegular() || !in.match(fi.Name()) {
+				continue
+			}
+			if now.Sub(fi.ModTime()) < minAge {
+				pending = true
+				continue
+			}
+			files = append(files, filepath.Join(dir, fi.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, pending, firstErr
+}
+
+func (in *Ingester) match(name string) bool {
+	if in.Match != nil {
+		return in.Match(name)
+	}
+	return !strings.HasPrefix(name, ".")
+}
+
+// archiveStep returns the step that moves the file into the archive, see Layout.
+func (in *Ingester) archiveStep() batch.Step {
+	layout := in.Layout
+	if layout == "" {
+		layout = archive.LayoutSite
+	}
+	return batch.Step{Name: "archive", Run: func(ctx context.Context, job *batch.Job) error {
+		fi, err := rinex.ParseFilename(job.Path)
+		if err != nil {
+			return err
+		}
+		rel, err := archive.ArchivePath(layout, fi)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(in.Archive, rel)
+		if err := batch.Move(filepath.Dir(dst)).Run(ctx, job); err != nil {
+			return err
+		}
+		if job.Path != dst { // the layout renames the file
+			if err := os.Rename(job.Path, dst); err != nil {
+				return err
+			}
+			job.Path = dst
+		}
+		return nil
+	}}
+}
+
+// quarantine moves the file into the quarantine directory and appends the reason to the log.
+// An existing file of the same name is kept, the file gets the time as suffix then.
+func (in *Ingester) quarantine(path, reason string, t time.Time) (string, error) {
+	if err := os.MkdirAll(in.Quarantine, 0755); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(in.Quarantine, filepath.Base(path))
+	if _, err := os.Stat(dst); err == nil {
+		dst += "." + t.Format("20060102T150405")
+	}
+	if err := moveFile(path, dst); err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(filepath.Join(in.Quarantine, QuarantineLog), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return dst, err
+	}
+	reason = strings.Join(strings.Fields(reason), " ")
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", t.Format(time.RFC3339), filepath.Base(dst), reason)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return dst, err
+}
+
+func (in *Ingester) logf(format string, args ...interface{}) {
+	if in.Logger != nil {
+		in.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// moveFile moves the file src to dst, copying it if they are on different file systems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// resetTimer resets the timer to d, draining its channel if it fired.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,159 @@
+package ingest
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setup returns an Ingester on a temporary directory, removed by the returned function.
+func setup(t *testing.T) (*Ingester, func()) {
+	dir, err := ioutil.TempDir("", "ingest-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := &Ingester{
+		Dirs:       []string{filepath.Join(dir, "incoming")},
+		Archive:    filepath.Join(dir, "archive"),
+		Quarantine: filepath.Join(dir, "quarantine"),
+		WorkDir:    dir,
+		MinAge:     time.Minute,
+		Logger:     log.New(ioutil.Discard, "", 0),
+	}
+	if err := os.Mkdir(in.Dirs[0], 0755); err != nil {
+		t.Fatal(err)
+	}
+	return in, func() { os.RemoveAll(dir) }
+}
+
+// deliver copies the file src into the incoming directory, modified age ago.
+func deliver(t *testing.T, in *Ingester, src, name string, age time.Duration) string {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(in.Dirs[0], name)
+	if err := ioutil.WriteFile(dst, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(dst, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+func TestIngester_Scan(t *testing.T) {
+	assert := assert.New(t)
+	in, cleanup := setup(t)
+	defer cleanup()
+
+	const obs = "../rinex/testdata/white/brst155h.20o"
+	good := deliver(t, in, obs, "brst155h.20o", time.Hour)
+	truncated, err := ioutil.ReadFile(obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(in.Dirs[0], "brst155i.20o")
+	assert.NoError(ioutil.WriteFile(bad, truncated[:len(truncated)-300], 0644))
+	old := time.Now().Add(-time.Hour)
+	assert.NoError(os.Chtimes(bad, old, old))
+	uploading := deliver(t, in, obs, "brst155j.20o", 0)
+	deliver(t, in, obs, ".brst155k.20o", time.Hour)
+
+	var called int
+	in.OnResult = func(res Result) { called++ }
+	results, err := in.Scan(context.Background())
+	if !assert.NoError(err) || !assert.Len(results, 2) {
+		return
+	}
+	assert.Equal(2, called)
+
+	res := results[0]
+	assert.Equal(good, res.File)
+	assert.False(res.Quarantined)
+	assert.Equal(filepath.Join(in.Archive, "2020", "155", "brst", "brst155h.20o"), res.Path)
+	assert.FileExists(res.Path)
+	assert.NoFileExists(good)
+
+	res = results[1]
+	assert.Equal(bad, res.File)
+	assert.True(res.Quarantined)
+	assert.True(strings.HasPrefix(res.Reason, "validate: "), res.Reason)
+	assert.Equal(filepath.Join(in.Quarantine, "brst155i.20o"), res.Path)
+	assert.FileExists(res.Path)
+	assert.NoFileExists(bad)
+
+	b, err := ioutil.ReadFile(filepath.Join(in.Quarantine, QuarantineLog))
+	assert.NoError(err)
+	fields := strings.Split(strings.TrimSuffix(string(b), "\n"), "\t")
+	if assert.Len(fields, 3) {
+		assert.Equal("brst155i.20o", fields[1])
+		assert.Equal(res.Reason, fields[2])
+	}
+
+	assert.FileExists(uploading, "not picked up within MinAge")
+	assert.FileExists(filepath.Join(in.Dirs[0], ".brst155k.20o"), "hidden")
+
+	// a second bad file of the same name is kept
+	assert.NoError(ioutil.WriteFile(bad, []byte("no RINEX"), 0644))
+	assert.NoError(os.Chtimes(bad, old, old))
+	in.MinAge = 30 * time.Minute
+	results, err = in.Scan(context.Background())
+	assert.NoError(err)
+	if assert.Len(results, 1) {
+		assert.True(results[0].Quarantined)
+		assert.NotEqual(res.Path, results[0].Path)
+		assert.FileExists(results[0].Path)
+	}
+}
+
+func TestIngester_Run(t *testing.T) {
+	assert := assert.New(t)
+	in, cleanup := setup(t)
+	defer cleanup()
+	in.MinAge = 50 * time.Millisecond
+
+	results := make(chan Result, 1)
+	in.OnResult = func(res Result) { results <- res }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- in.Run(ctx) }()
+
+	time.Sleep(100 * time.Millisecond)
+	deliver(t, in, "../rinex/testdata/white/brst155h.20o", "brst155h.20o", 0)
+	select {
+	case res := <-results:
+		assert.False(res.Quarantined, res.Reason)
+		assert.Equal(filepath.Join(in.Archive, "2020", "155", "brst", "brst155h.20o"), res.Path)
+	case <-time.After(5 * time.Second):
+		t.Error("file not ingested")
+	}
+	cancel()
+	assert.Equal(context.Canceled, <-done)
+}
+
+func TestIngester_layout(t *testing.T) {
+	assert := assert.New(t)
+	in, cleanup := setup(t)
+	defer cleanup()
+	in.Layout = "{{.StationID}}/{{.Year}}/{{.Site}}{{.Doy}}0.{{.YY}}o"
+	deliver(t, in, "../rinex/testdata/white/brst155h.20o", "brst155h.20o", time.Hour)
+	deliver(t, in, "../rinex/testdata/white/brst155h.20o", "readme.txt", time.Hour)
+
+	results, err := in.Scan(context.Background())
+	if !assert.NoError(err) || !assert.Len(results, 2) {
+		return
+	}
+	assert.Equal(filepath.Join(in.Archive, "BRST", "2020", "brst1550.20o"), results[0].Path)
+	assert.FileExists(results[0].Path)
+	assert.True(results[1].Quarantined)
+	assert.True(strings.HasPrefix(results[1].Reason, "archive: "), results[1].Reason)
+}
@@ -0,0 +1,323 @@
+// Package inventory exports the metadata of scanned RINEX observation files, i.e. the header, the
+// statistics and the QC results, into a SQL database, as an inventory of an archive.
+//
+// The database is accessed with database/sql, the driver is chosen by the application, e.g.
+// github.com/mattn/go-sqlite3 or github.com/lib/pq. A file is identified by its station, start time and
+// file period, exporting it again updates its row.
+//
+//	db, err := sql.Open("sqlite3", "inventory.db")
+//	exp := &inventory.Exporter{DB: db, Dialect: inventory.SQLite}
+//	err = exp.CreateTable(ctx)
+//	rec, err := inventory.Scan("WTZR00DEU_R_20201690000_01D_30S_MO.rnx.gz")
+//	err = exp.Export(ctx, rec)
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// DefaultTable is the name of the table if not set in the Exporter.
+const DefaultTable = "rinex_files"
+
+// Record is the metadata of a scanned observation file.
+type Record struct {
+	Station   string    // 9 char station name of RINEX 3 file names, e.g. WTZR00DEU, or the 4 char ID
+	StartTime time.Time // nominal start time of the file
+	Period    string    // file period, e.g. 01D
+
+	Path    string
+	Size    int64
+	ModTime time.Time
+
+	RINEXVersion    float32
+	MarkerName      string
+	MarkerNumber    string
+	ReceiverType    string
+	ReceiverNumber  string
+	ReceiverVersion string
+	AntennaType     string
+	AntennaNumber   string
+	Position        rinex.Coord
+	Interval        float64 // observation interval of the header in seconds
+	Systems         string  // the observed satellite systems, e.g. GRE
+
+	FirstObs     time.Time
+	LastObs      time.Time
+	NumEpochs    int
+	Sampling     int     // the sampling rate in seconds
+	Availability float64 // percentage of the expected epochs found
+	NumGaps      int
+	NumSats      int // number of different satellites
+
+	Scanned time.Time
+}
+
+// Scan reads the, possibly compressed, observation file and returns its record. The station, start
+// time and period are taken from the file name, for names not following the RINEX conventions from
+// the header. Uncompressed files are read memory-mapped, see rinex.OpenMapped.
+func Scan(path string) (*Record, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := rinex.OpenMapped(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	dec, err := rinex.NewObsDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	rep, err := rinex.NewReport(dec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	rec := NewRecord(&dec.Header, rep)
+	rec.Path, rec.Size, rec.ModTime = path, info.Size(), info.ModTime().UTC()
+
+	if fi, err := rinex.ParseFilename(path); err == nil {
+		rec.Station = strings.ToUpper(fi.FourCharID)
+		if fi.NamingVersion == 3 {
+			rec.Station += fmt.Sprintf("%d%d%s", fi.MonumentNumber, fi.ReceiverNumber, fi.CountryCode)
+		}
+		rec.StartTime, rec.Period = fi.StartTime, fi.FilePeriod
+	}
+	return rec, nil
+}
+
+// NewRecord returns the record of the header and the report. The station defaults to the first four
+// characters of the marker name, the start time to the first epoch.
+func NewRecord(hdr *rinex.ObsHeader, rep *rinex.Report) *Record {
+	rec := &Record{
+		RINEXVersion:    hdr.RINEXVersion,
+		MarkerName:      hdr.MarkerName,
+		MarkerNumber:    hdr.MarkerNumber,
+		ReceiverType:    hdr.ReceiverType,
+		ReceiverNumber:  hdr.ReceiverNumber,
+		ReceiverVersion: hdr.ReceiverVersion,
+		AntennaType:     hdr.AntennaType,
+		AntennaNumber:   hdr.AntennaNumber,
+		Position:        hdr.Position,
+		Interval:        hdr.Interval,
+		FirstObs:        hdr.TimeOfFirstObs,
+		LastObs:         hdr.TimeOfLastObs,
+		Scanned:         time.Now().UTC(),
+	}
+	if rep != nil {
+		rec.FirstObs, rec.LastObs = rep.Stat.TimeOfFirstObs, rep.Stat.TimeOfLastObs
+		rec.NumEpochs, rec.Sampling = rep.Stat.NumEpochs, rep.Stat.Sampling
+		rec.Availability, rec.NumGaps = rep.Availability, rep.NumGaps
+		var sb strings.Builder
+		for _, sys := range rep.Systems {
+			sb.WriteString(sys.System)
+			rec.NumSats += sys.NumSats
+		}
+		rec.Systems = sb.String()
+	}
+
+	rec.Station = strings.ToUpper(strings.TrimSpace(hdr.MarkerName))
+	if len(rec.Station) > 4 {
+		rec.Station = rec.Station[:4]
+	}
+	rec.StartTime = rec.FirstObs
+	return rec
+}
+
+// Dialect is the SQL dialect of the database.
+type Dialect int
+
+// Supported dialects. The upsert needs SQLite 3.24 or later.
+const (
+	SQLite Dialect = iota
+	Postgres
+	MySQL
+)
+
+// column is a column of the table and the value of a record.
+type column struct {
+	name  string
+	typ   string // SQL type, TIMESTAMP is replaced per dialect
+	value func(rec *Record) interface{}
+}
+
+// columns are the columns of the table, the first three are the primary key.
+var columns = []column{
+	{"station", "VARCHAR(9) NOT NULL", func(r *Record) interface{} { return r.Station }},
+	{"start_time", "TIMESTAMP NOT NULL", func(r *Record) interface{} { return r.StartTime.UTC() }},
+	{"period", "VARCHAR(3) NOT NULL", func(r *Record) interface{} { return r.Period }},
+	{"path", "VARCHAR(1024)", func(r *Record) interface{} { return r.Path }},
+	{"size", "BIGINT", func(r *Record) interface{} { return r.Size }},
+	{"mod_time", "TIMESTAMP", func(r *Record) interface{} { return r.ModTime.UTC() }},
+	{"rinex_version", "DOUBLE PRECISION", func(r *Record) interface{} { return roundVersion(r.RINEXVersion) }},
+	{"marker_name", "VARCHAR(60)", func(r *Record) interface{} { return r.MarkerName }},
+	{"marker_number", "VARCHAR(20)", func(r *Record) interface{} { return r.MarkerNumber }},
+	{"receiver_type", "VARCHAR(20)", func(r *Record) interface{} { return r.ReceiverType }},
+	{"receiver_number", "VARCHAR(20)", func(r *Record) interface{} { return r.ReceiverNumber }},
+	{"receiver_version", "VARCHAR(20)", func(r *Record) interface{} { return r.ReceiverVersion }},
+	{"antenna_type", "VARCHAR(20)", func(r *Record) interface{} { return r.AntennaType }},
+	{"antenna_number", "VARCHAR(20)", func(r *Record) interface{} { return r.AntennaNumber }},
+	{"x", "DOUBLE PRECISION", func(r *Record) interface{} { return r.Position.X }},
+	{"y", "DOUBLE PRECISION", func(r *Record) interface{} { return r.Position.Y }},
+	{"z", "DOUBLE PRECISION", func(r *Record) interface{} { return r.Position.Z }},
+	{"interval", "DOUBLE PRECISION", func(r *Record) interface{} { return r.Interval }},
+	{"systems", "VARCHAR(10)", func(r *Record) interface{} { return r.Systems }},
+	{"first_obs", "TIMESTAMP", func(r *Record) interface{} { return r.FirstObs.UTC() }},
+	{"last_obs", "TIMESTAMP", func(r *Record) interface{} { return r.LastObs.UTC() }},
+	{"num_epochs", "INTEGER", func(r *Record) interface{} { return r.NumEpochs }},
+	{"sampling", "INTEGER", func(r *Record) interface{} { return r.Sampling }},
+	{"availability", "DOUBLE PRECISION", func(r *Record) interface{} { return r.Availability }},
+	{"num_gaps", "INTEGER", func(r *Record) interface{} { return r.NumGaps }},
+	{"num_sats", "INTEGER", func(r *Record) interface{} { return r.NumSats }},
+	{"scanned", "TIMESTAMP", func(r *Record) interface{} { return r.Scanned.UTC() }},
+}
+
+const numKeyColumns = 3
+
+var tableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Exporter writes the records into a table of the database.
+type Exporter struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Table   string // default DefaultTable
+}
+
+func (e *Exporter) table() (string, error) {
+	if e.Table == "" {
+		return DefaultTable, nil
+	}
+	if !tableNameRe.MatchString(e.Table) {
+		return "", fmt.Errorf("inventory: invalid table name: %q", e.Table)
+	}
+	return e.Table, nil
+}
+
+// CreateTable creates the table, if it does not exist.
+func (e *Exporter) CreateTable(ctx context.Context) error {
+	stmt, err := e.createStmt()
+	if err != nil {
+		return err
+	}
+	_, err = e.DB.ExecContext(ctx, stmt)
+	return err
+}
+
+// Export inserts the records, or updates them if a record of the station, start time and period
+// exists. The records are written in a single transaction.
+func (e *Exporter) Export(ctx context.Context, recs ...*Record) error {
+	query, err := e.upsertStmt()
+	if err != nil {
+		return err
+	}
+	tx, err := e.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, len(columns))
+	for _, rec := range recs {
+		if rec.Station == "" || rec.StartTime.IsZero() {
+			tx.Rollback()
+			return fmt.Errorf("inventory: no station or start time: %s", rec.Path)
+		}
+		for i, col := range columns {
+			args[i] = col.value(rec)
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("inventory: %s: %w", rec.Path, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// createStmt returns the CREATE TABLE statement.
+func (e *Exporter) createStmt() (string, error) {
+	table, err := e.table()
+	if err != nil {
+		return "", err
+	}
+	timeType := "TIMESTAMP"
+	if e.Dialect == MySQL {
+		timeType = "DATETIME"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CREATE TABLE IF NOT EXISTS %s (\n", table)
+	for _, col := range columns {
+		fmt.Fprintf(&sb, "\t%s %s,\n", e.quote(col.name), strings.Replace(col.typ, "TIMESTAMP", timeType, 1))
+	}
+	fmt.Fprintf(&sb, "\tPRIMARY KEY (%s)\n)", strings.Join(e.keyColumns(), ", "))
+	return sb.String(), nil
+}
+
+// upsertStmt returns the INSERT statement that updates an existing record.
+func (e *Exporter) upsertStmt() (string, error) {
+	table, err := e.table()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(columns))
+	params := make([]string, len(columns))
+	var updates []string
+	for i, col := range columns {
+		names[i] = e.quote(col.name)
+		params[i] = "?"
+		if e.Dialect == Postgres {
+			params[i] = "$" + strconv.Itoa(i+1)
+		}
+		if i < numKeyColumns {
+			continue
+		}
+		if e.Dialect == MySQL {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", names[i], names[i]))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", names[i], names[i]))
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), strings.Join(params, ", "))
+	if e.Dialect == MySQL {
+		return query + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", "), nil
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", query, strings.Join(e.keyColumns(), ", "),
+		strings.Join(updates, ", ")), nil
+}
+
+func (e *Exporter) keyColumns() []string {
+	keys := make([]string, numKeyColumns)
+	for i := range keys {
+		keys[i] = e.quote(columns[i].name)
+	}
+	return keys
+}
+
+// quote quotes the column name, e.g. interval is a reserved word in MySQL and PostgreSQL.
+func (e *Exporter) quote(name string) string {
+	if e.Dialect == MySQL {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// roundVersion returns the RINEX version as float64 without the float32 rounding digits, e.g. 3.04.
+func roundVersion(v float32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'f', -1, 32), 64)
+	return f
+}
@@ -0,0 +1,157 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDB is a database/sql driver that keeps the rows of the upserts by their key.
+type fakeDB struct {
+	mu      sync.Mutex
+	queries []string
+	rows    map[string][]driver.Value
+	commits int
+}
+
+func (db *fakeDB) Open(name string) (driver.Conn, error) { return fakeConn{db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.queries = append(c.db.queries, query)
+	return fakeStmt{c.db, query}, nil
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{c.db}, nil }
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+func (tx fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.HasPrefix(s.query, "INSERT") {
+		s.db.mu.Lock()
+		defer s.db.mu.Unlock()
+		s.db.rows[fmt.Sprint(args[0], args[1], args[2])] = args
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var (
+	fake         = &fakeDB{rows: map[string][]driver.Value{}}
+	registerOnce sync.Once
+)
+
+func openFake(t *testing.T) *sql.DB {
+	registerOnce.Do(func() { sql.Register("inventory-fake", fake) })
+	db, err := sql.Open("inventory-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestScan(t *testing.T) {
+	assert := assert.New(t)
+	rec, err := Scan("../rinex/testdata/white/brst155h.20o")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("BRST", rec.Station)
+	assert.Equal(time.Date(2020, 6, 3, 7, 0, 0, 0, time.UTC), rec.StartTime)
+	assert.Equal("01H", rec.Period)
+	assert.Equal(float32(2.11), rec.RINEXVersion)
+	assert.Equal("TRIMBLE ALLOY", rec.ReceiverType)
+	assert.Equal("GRES", rec.Systems)
+	assert.Equal(120, rec.NumEpochs)
+	assert.Equal(30, rec.Sampling)
+	assert.Equal(100.0, rec.Availability)
+	assert.Equal(36, rec.NumSats)
+	assert.True(rec.Size > 0)
+
+	_, err = Scan("inventory.go")
+	assert.Error(err)
+}
+
+func TestExporter(t *testing.T) {
+	assert := assert.New(t)
+	db := openFake(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	exp := &Exporter{DB: db, Dialect: SQLite}
+	assert.NoError(exp.CreateTable(ctx))
+	rec, err := Scan("../rinex/testdata/white/brst155h.20o")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(exp.Export(ctx, rec))
+	rec.NumGaps = 2
+	assert.NoError(exp.Export(ctx, rec))
+	other := *rec
+	other.Period = "01D"
+	assert.NoError(exp.Export(ctx, &other))
+
+	assert.Len(fake.rows, 2, "updated by key")
+	row := fake.rows[fmt.Sprint("BRST", rec.StartTime, "01H")]
+	if assert.NotNil(row) {
+		assert.Equal(int64(2), row[24], "num_gaps")
+		assert.Equal(2.11, row[6], "rinex_version")
+	}
+	assert.Equal(3, fake.commits)
+	if assert.Len(fake.queries, 4) {
+		assert.True(strings.HasPrefix(fake.queries[0], "CREATE TABLE IF NOT EXISTS rinex_files (\n\t\"station\" VARCHAR(9) NOT NULL,\n"), fake.queries[0])
+		assert.True(strings.HasSuffix(fake.queries[0], "\tPRIMARY KEY (\"station\", \"start_time\", \"period\")\n)"), fake.queries[0])
+		assert.Contains(fake.queries[1], ` ON CONFLICT ("station", "start_time", "period") DO UPDATE SET "path" = excluded."path", `)
+	}
+
+	assert.Error(exp.Export(ctx, &Record{Path: "x"}), "no key")
+	exp.Table = "files; DROP TABLE x"
+	assert.Error(exp.CreateTable(ctx))
+}
+
+func TestExporter_dialects(t *testing.T) {
+	assert := assert.New(t)
+	exp := &Exporter{Dialect: Postgres, Table: "gnss.files"}
+	q, err := exp.upsertStmt()
+	assert.NoError(err)
+	assert.True(strings.HasPrefix(q, `INSERT INTO gnss.files ("station", "start_time", `), q)
+	assert.Contains(q, `VALUES ($1, $2, $3, $4, `)
+	assert.Contains(q, `"interval" = excluded."interval"`)
+
+	exp = &Exporter{Dialect: MySQL}
+	q, err = exp.upsertStmt()
+	assert.NoError(err)
+	assert.Contains(q, "VALUES (?, ?, ?, ?, ")
+	assert.Contains(q, " ON DUPLICATE KEY UPDATE `path` = VALUES(`path`), ")
+	q, err = exp.createStmt()
+	assert.NoError(err)
+	assert.Contains(q, "\t`start_time` DATETIME NOT NULL,\n")
+}
@@ -0,0 +1,95 @@
+package ionex
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Maps is a time series of TEC maps.
+type Maps []*Map
+
+// TECAt returns the TEC in TECU at the given latitude and longitude in degrees, bilinearly
+// interpolated from the four surrounding grid points.
+func (m *Map) TECAt(lat, lon float64) (float64, error) {
+	return m.Grid.interpolate(m.TEC, lat, lon)
+}
+
+// RMSAt returns the RMS of the TEC in TECU at the given latitude and longitude in degrees.
+func (m *Map) RMSAt(lat, lon float64) (float64, error) {
+	if m.RMS == nil {
+		return 0, fmt.Errorf("map %d: no RMS values", m.Num)
+	}
+	return m.Grid.interpolate(m.RMS, lat, lon)
+}
+
+// TECAt returns the TEC in TECU at the given latitude, longitude and time. It interpolates
+// between the two consecutive maps, which are rotated by the earth rotation to account for
+// the strong correlation of the ionosphere with the position of the sun, see the
+// IONEX 1.1 specification.
+func (maps Maps) TECAt(lat, lon float64, t time.Time) (float64, error) {
+	if len(maps) == 0 {
+		return 0, fmt.Errorf("no maps")
+	}
+	for i, m := range maps {
+		if t.Equal(m.Epoch) {
+			return m.TECAt(lat, lon)
+		}
+		if i == 0 || t.After(m.Epoch) {
+			continue
+		}
+		prev := maps[i-1]
+		if t.Before(prev.Epoch) {
+			break
+		}
+		dt := m.Epoch.Sub(prev.Epoch).Seconds()
+		dt1 := t.Sub(prev.Epoch).Seconds()
+		dt2 := t.Sub(m.Epoch).Seconds()
+		tec1, err := prev.TECAt(lat, lon+dt1*360/86400)
+		if err != nil {
+			return 0, err
+		}
+		tec2, err := m.TECAt(lat, lon+dt2*360/86400)
+		if err != nil {
+			return 0, err
+		}
+		return (1-dt1/dt)*tec1 + dt1/dt*tec2, nil
+	}
+	return 0, fmt.Errorf("time %s out of the range of the maps", t.Format(time.RFC3339))
+}
+
+// interpolate bilinearly interpolates the values at the given location.
+func (g Grid) interpolate(vals [][]float64, lat, lon float64) (float64, error) {
+	if g.Lon2-g.Lon1 >= 360-1e-9 { // global grid
+		lon = math.Mod(lon-g.Lon1, 360)
+		if lon < 0 {
+			lon += 360
+		}
+		lon += g.Lon1
+	}
+
+	q := (lat - g.Lat1) / g.DLat
+	p := (lon - g.Lon1) / g.DLon
+	nLat, nLon := len(vals), 0
+	if nLat > 0 {
+		nLon = len(vals[0])
+	}
+	if q < 0 || q > float64(nLat-1) || p < 0 || p > float64(nLon-1) {
+		return 0, fmt.Errorf("location %.3f/%.3f outside of the grid", lat, lon)
+	}
+
+	i, j := int(q), int(p)
+	if i == nLat-1 {
+		i--
+	}
+	if j == nLon-1 {
+		j--
+	}
+	if i < 0 || j < 0 { // grid with only one row or column
+		return 0, fmt.Errorf("grid too small for interpolation")
+	}
+	q -= float64(i)
+	p -= float64(j)
+
+	return (1-p)*(1-q)*vals[i][j] + p*(1-q)*vals[i][j+1] + (1-p)*q*vals[i+1][j] + p*q*vals[i+1][j+1], nil
+}
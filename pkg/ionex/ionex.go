@@ -0,0 +1,378 @@
+// Package ionex provides functions for reading IONEX files, containing global or regional
+// maps of the total electron content (TEC) of the ionosphere.
+//
+// The format is described in "IONEX: The IONosphere Map EXchange Format Version 1.1".
+package ionex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// missingValue flags a non-available TEC or RMS value.
+const missingValue = 9999
+
+// ErrNoHeader is returned when reading IONEX data that does not begin with a IONEX Header.
+var ErrNoHeader = errors.New("IONEX: no header")
+
+// Grid defines the latitude and longitude grid of the maps in degrees.
+type Grid struct {
+	Lat1, Lat2, DLat float64
+	Lon1, Lon2, DLon float64
+}
+
+// NumLat returns the number of latitude rows.
+func (g Grid) NumLat() int {
+	return int(math.Round((g.Lat2-g.Lat1)/g.DLat)) + 1
+}
+
+// NumLon returns the number of longitude points per row.
+func (g Grid) NumLon() int {
+	return int(math.Round((g.Lon2-g.Lon1)/g.DLon)) + 1
+}
+
+// A Header provides the IONEX Header information.
+type Header struct {
+	Version  float32 // format version
+	Type     string  // file type, I for Ionospheric maps
+	System   string  // satellite system or theoretical model, e.g. GPS, MIX, NNS
+	Pgm      string  // name of program creating this file
+	RunBy    string  // name of agency creating this file
+	Date     string  // date and time of file creation
+	Comments []string
+	Desc     []string // description lines
+	FirstMap time.Time
+	LastMap  time.Time
+	Interval int    // time interval between the maps in seconds, 0 if not constant
+	NumMaps  int    // total number of TEC/RMS/HGT maps
+	MapFunc  string // mapping function adopted for TEC determination, e.g. COSZ
+	ElevMask float64
+	ObsUsed  string  // one-line specification of the observables used
+	NumSta   int     // number of contributing stations
+	NumSat   int     // number of contributing satellites
+	BaseRad  float64 // mean earth radius or bottom of height grid in km
+	MapDim   int     // dimension of the maps, 2 or 3
+	Hgt1     float64 // height of the maps in km, for 2-dimensional maps Hgt1 equals Hgt2
+	Hgt2     float64
+	DHgt     float64
+	Grid     Grid
+	Exponent int // default exponent for the TEC values
+	warnings []string
+}
+
+// Map is a TEC map of one epoch. The values are given in TECU, not available values are NaN.
+type Map struct {
+	Num    int         // map number
+	Epoch  time.Time   // epoch of the map
+	Height float64     // height of the map in km
+	Grid   Grid        // grid of the map
+	TEC    [][]float64 // TEC values per latitude row and longitude
+	RMS    [][]float64 // RMS values of the TEC values, nil if not available
+}
+
+// Decoder reads and decodes header and maps from an IONEX input stream.
+type Decoder struct {
+	// The Header is valid after NewDecoder. The header must exist.
+	Header Header
+
+	sc      *bufio.Scanner
+	lineNum int
+}
+
+// NewDecoder creates a new decoder for IONEX data.
+// The header will be read implicitly. The header must exist.
+//
+// It is the caller's responsibility to call Close on the underlying reader when done!
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	dec := &Decoder{sc: bufio.NewScanner(r)}
+	var err error
+	dec.Header, err = dec.readHeader()
+	return dec, err
+}
+
+// readHeader reads the IONEX header.
+func (dec *Decoder) readHeader() (hdr Header, err error) {
+	hdr.Exponent = -1
+	maxLines := 1000
+read:
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+
+		if dec.lineNum == 1 && !strings.Contains(line, "IONEX VERSION / TYPE") {
+			return hdr, ErrNoHeader
+		}
+		if dec.lineNum > maxLines {
+			return hdr, fmt.Errorf("reading header failed: line %d reached without finding end of header", maxLines)
+		}
+		if len(line) < 60 {
+			continue
+		}
+
+		val := line[:60]
+		key := strings.TrimSpace(line[60:])
+
+		switch key {
+		case "IONEX VERSION / TYPE":
+			f64, err := strconv.ParseFloat(strings.TrimSpace(val[:8]), 32)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+			hdr.Version = float32(f64)
+			hdr.Type = strings.TrimSpace(val[20:21])
+			hdr.System = strings.TrimSpace(val[40:43])
+		case "PGM / RUN BY / DATE":
+			hdr.Pgm = strings.TrimSpace(val[:20])
+			hdr.RunBy = strings.TrimSpace(val[20:40])
+			hdr.Date = strings.TrimSpace(val[40:])
+		case "COMMENT":
+			hdr.Comments = append(hdr.Comments, strings.TrimSpace(val))
+		case "DESCRIPTION":
+			hdr.Desc = append(hdr.Desc, strings.TrimSpace(val))
+		case "EPOCH OF FIRST MAP":
+			if hdr.FirstMap, err = parseEpoch(val); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "EPOCH OF LAST MAP":
+			if hdr.LastMap, err = parseEpoch(val); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "INTERVAL":
+			if hdr.Interval, err = strconv.Atoi(strings.TrimSpace(val[:6])); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "# OF MAPS IN FILE":
+			if hdr.NumMaps, err = strconv.Atoi(strings.TrimSpace(val[:6])); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "MAPPING FUNCTION":
+			hdr.MapFunc = strings.TrimSpace(val[:6])
+		case "ELEVATION CUTOFF":
+			if hdr.ElevMask, err = strconv.ParseFloat(strings.TrimSpace(val[:8]), 64); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "OBSERVABLES USED":
+			hdr.ObsUsed = strings.TrimSpace(val)
+		case "# OF STATIONS":
+			if hdr.NumSta, err = strconv.Atoi(strings.TrimSpace(val[:6])); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "# OF SATELLITES":
+			if hdr.NumSat, err = strconv.Atoi(strings.TrimSpace(val[:6])); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "BASE RADIUS":
+			if hdr.BaseRad, err = strconv.ParseFloat(strings.TrimSpace(val[:8]), 64); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "MAP DIMENSION":
+			if hdr.MapDim, err = strconv.Atoi(strings.TrimSpace(val[:6])); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "HGT1 / HGT2 / DHGT":
+			f, err := parseFloats(val[2:20], 3)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+			hdr.Hgt1, hdr.Hgt2, hdr.DHgt = f[0], f[1], f[2]
+		case "LAT1 / LAT2 / DLAT":
+			f, err := parseFloats(val[2:20], 3)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+			hdr.Grid.Lat1, hdr.Grid.Lat2, hdr.Grid.DLat = f[0], f[1], f[2]
+		case "LON1 / LON2 / DLON":
+			f, err := parseFloats(val[2:20], 3)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+			hdr.Grid.Lon1, hdr.Grid.Lon2, hdr.Grid.DLon = f[0], f[1], f[2]
+		case "EXPONENT":
+			if hdr.Exponent, err = strconv.Atoi(strings.TrimSpace(val[:6])); err != nil {
+				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+			}
+		case "START OF AUX DATA":
+			// e.g. differential code biases, skip the block
+			for dec.sc.Scan() {
+				dec.lineNum++
+				if strings.Contains(dec.sc.Text(), "END OF AUX DATA") {
+					break
+				}
+			}
+		case "END OF HEADER":
+			break read
+		default:
+			hdr.warnings = append(hdr.warnings, fmt.Sprintf("header label not handled: %s", key))
+		}
+	}
+
+	if err = dec.sc.Err(); err != nil {
+		return
+	}
+	if hdr.MapDim == 3 {
+		return hdr, fmt.Errorf("3-dimensional maps are not supported")
+	}
+	if hdr.Grid.DLat == 0 || hdr.Grid.DLon == 0 {
+		return hdr, fmt.Errorf("invalid grid definition: %+v", hdr.Grid)
+	}
+	return
+}
+
+// ReadMaps reads all TEC maps and assigns the RMS maps to them.
+// The maps are sorted by their map number.
+func (dec *Decoder) ReadMaps() (Maps, error) {
+	var maps Maps
+	rmsMaps := map[int][][]float64{}
+	exp := dec.Header.Exponent
+
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		if len(line) < 60 {
+			continue
+		}
+		key := strings.TrimSpace(line[60:])
+		switch key {
+		case "START OF TEC MAP", "START OF RMS MAP", "START OF HEIGHT MAP":
+			num, err := strconv.Atoi(strings.TrimSpace(line[:6]))
+			if err != nil {
+				return nil, fmt.Errorf("parsing map number in line %d: %v", dec.lineNum, err)
+			}
+			m, err := dec.readMap(num, exp)
+			if err != nil {
+				return nil, err
+			}
+			switch key {
+			case "START OF TEC MAP":
+				maps = append(maps, m)
+			case "START OF RMS MAP":
+				rmsMaps[num] = m.TEC
+			}
+		}
+	}
+	if err := dec.sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range maps {
+		m.RMS = rmsMaps[m.Num]
+	}
+	return maps, nil
+}
+
+// readMap reads the data of one map until the end of map record.
+func (dec *Decoder) readMap(num, exp int) (*Map, error) {
+	grid := dec.Header.Grid
+	nLat, nLon := grid.NumLat(), grid.NumLon()
+	m := &Map{Num: num, Height: dec.Header.Hgt1, Grid: grid, TEC: make([][]float64, 0, nLat)}
+
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		if len(line) < 60 {
+			continue
+		}
+		key := strings.TrimSpace(line[60:])
+		switch key {
+		case "EPOCH OF CURRENT MAP":
+			t, err := parseEpoch(line[:60])
+			if err != nil {
+				return nil, fmt.Errorf("parsing epoch in line %d: %v", dec.lineNum, err)
+			}
+			m.Epoch = t
+		case "EXPONENT":
+			n, err := strconv.Atoi(strings.TrimSpace(line[:6]))
+			if err != nil {
+				return nil, fmt.Errorf("parsing exponent in line %d: %v", dec.lineNum, err)
+			}
+			exp = n
+		case "LAT/LON1/LON2/DLON/H":
+			f, err := parseFloats(line[2:32], 5)
+			if err != nil {
+				return nil, fmt.Errorf("parsing line %d: %v", dec.lineNum, err)
+			}
+			if len(m.TEC) >= nLat {
+				return nil, fmt.Errorf("too many latitude rows in line %d", dec.lineNum)
+			}
+			m.Height = f[4]
+			row, err := dec.readRow(nLon, exp)
+			if err != nil {
+				return nil, err
+			}
+			m.TEC = append(m.TEC, row)
+		case "END OF TEC MAP", "END OF RMS MAP", "END OF HEIGHT MAP":
+			if len(m.TEC) != nLat {
+				return nil, fmt.Errorf("map %d: got %d latitude rows, expected %d", num, len(m.TEC), nLat)
+			}
+			return m, nil
+		}
+	}
+	if err := dec.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("map %d: unexpected EOF", num)
+}
+
+// readRow reads the values of a latitude row, given as 16I5 per line.
+func (dec *Decoder) readRow(nLon, exp int) ([]float64, error) {
+	scale := math.Pow10(exp)
+	row := make([]float64, 0, nLon)
+	for len(row) < nLon && dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		for col := 0; col+5 <= len(line) && len(row) < nLon; col += 5 {
+			n, err := strconv.Atoi(strings.TrimSpace(line[col : col+5]))
+			if err != nil {
+				return nil, fmt.Errorf("parsing value in line %d: %v", dec.lineNum, err)
+			}
+			if n == missingValue {
+				row = append(row, math.NaN())
+			} else {
+				row = append(row, float64(n)*scale)
+			}
+		}
+	}
+	if len(row) != nLon {
+		return nil, fmt.Errorf("got %d values, expected %d: line %d", len(row), nLon, dec.lineNum)
+	}
+	return row, nil
+}
+
+// parseEpoch parses an epoch in the format 6I6.
+func parseEpoch(s string) (time.Time, error) {
+	f := strings.Fields(s)
+	if len(f) < 6 {
+		return time.Time{}, fmt.Errorf("invalid epoch: %q", s)
+	}
+	var d [6]int
+	for i := range d {
+		n, err := strconv.Atoi(f[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+		d[i] = n
+	}
+	return time.Date(d[0], time.Month(d[1]), d[2], d[3], d[4], d[5], 0, time.UTC), nil
+}
+
+// parseFloats parses n floats given in the format nF6.1.
+func parseFloats(s string, n int) ([]float64, error) {
+	f := make([]float64, 0, n)
+	for col := 0; col+6 <= len(s) && len(f) < n; col += 6 {
+		f64, err := strconv.ParseFloat(strings.TrimSpace(s[col:col+6]), 64)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, f64)
+	}
+	if len(f) != n {
+		return nil, fmt.Errorf("expected %d values: %q", n, s)
+	}
+	return f, nil
+}
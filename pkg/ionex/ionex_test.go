@@ -0,0 +1,79 @@
+package ionex
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("testdata/test.20i")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer f.Close()
+
+	dec, err := NewDecoder(f)
+	assert.NoError(err)
+	hdr := dec.Header
+	assert.Equal(float32(1.0), hdr.Version)
+	assert.Equal("I", hdr.Type)
+	assert.Equal("GPS", hdr.System)
+	assert.Equal(time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC), hdr.FirstMap)
+	assert.Equal(7200, hdr.Interval)
+	assert.Equal(2, hdr.NumMaps)
+	assert.Equal("COSZ", hdr.MapFunc)
+	assert.Equal(295, hdr.NumSta)
+	assert.Equal(450.0, hdr.Hgt1)
+	assert.Equal(Grid{Lat1: 10, Lat2: -10, DLat: -10, Lon1: -10, Lon2: 10, DLon: 10}, hdr.Grid)
+	assert.Equal(-1, hdr.Exponent)
+	assert.Empty(hdr.warnings)
+
+	maps, err := dec.ReadMaps()
+	assert.NoError(err)
+	if !assert.Len(maps, 2) {
+		return
+	}
+	m := maps[0]
+	assert.Equal(1, m.Num)
+	assert.Equal([]float64{10, 20, 30}, m.TEC[0])
+	assert.True(math.IsNaN(m.TEC[2][1]), "missing value")
+	assert.Equal([]float64{1, 2, 3}, m.RMS[0])
+	assert.Equal([]float64{1, 2, 3}, maps[1].RMS[0], "exponent in map")
+
+	tec, err := m.TECAt(5, -5)
+	assert.NoError(err)
+	assert.InDelta(15.0, tec, 1e-9)
+	tec, err = m.TECAt(10, 10)
+	assert.NoError(err)
+	assert.InDelta(30.0, tec, 1e-9)
+	rms, err := m.RMSAt(0, 5)
+	assert.NoError(err)
+	assert.InDelta(2.5, rms, 1e-9)
+	_, err = m.TECAt(20, 0)
+	assert.Error(err, "outside of grid")
+
+	tec, err = maps.TECAt(10, 0, time.Date(2020, 6, 18, 2, 0, 0, 0, time.UTC))
+	assert.NoError(err)
+	assert.InDelta(40.0, tec, 1e-9)
+	_, err = maps.TECAt(10, 0, time.Date(2020, 6, 18, 3, 0, 0, 0, time.UTC))
+	assert.Error(err, "out of time range")
+}
+
+func TestMaps_TECAt(t *testing.T) {
+	assert := assert.New(t)
+	grid := Grid{Lat1: 90, Lat2: -90, DLat: -90, Lon1: -180, Lon2: 180, DLon: 180}
+	constMap := func(epo time.Time, val float64) *Map {
+		return &Map{Epoch: epo, Grid: grid, TEC: [][]float64{{val, val, val}, {val, val, val}, {val, val, val}}}
+	}
+	t0 := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	maps := Maps{constMap(t0, 10), constMap(t0.Add(2*time.Hour), 20)}
+
+	tec, err := maps.TECAt(45, 170, t0.Add(30*time.Minute))
+	assert.NoError(err)
+	assert.InDelta(12.5, tec, 1e-9)
+}
@@ -0,0 +1,37 @@
+// Package iono provides ionosphere models for single-frequency positioning, currently the GPS Klobuchar model.
+//
+// The models return the delays in meters, e.g. for the SPP solver, and the total electron content (TEC),
+// e.g. to compare with IONEX maps.
+package iono
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// FreqL1 is the GPS L1 and Galileo E1 frequency in Hz.
+	FreqL1 = 1575.42e6
+
+	speedOfLight = 299792458.0 // [m/s]
+	k40          = 40.3e16     // delay in m per TECU is k40/f^2
+)
+
+// ErrNoParams is returned if the navigation header does not contain the parameters of the model.
+var ErrNoParams = errors.New("iono: no ionospheric correction parameters")
+
+// TECToDelay converts the total electron content in TEC units (1e16 electrons/m^2) into the ionospheric
+// delay in meters on the frequency freq in Hz.
+func TECToDelay(tec, freq float64) float64 {
+	return k40 * tec / (freq * freq)
+}
+
+// DelayToTEC converts the ionospheric delay in meters on the frequency freq in Hz into TEC units.
+func DelayToTEC(delay, freq float64) float64 {
+	return delay * freq * freq / k40
+}
+
+// secondsOfDay returns the seconds since midnight of t.
+func secondsOfDay(t time.Time) float64 {
+	return float64(t.Hour()*3600+t.Minute()*60+t.Second()) + float64(t.Nanosecond())/1e9
+}
@@ -0,0 +1,66 @@
+package iono
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/spp"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ spp.IonoModel = (*Klobuchar)(nil)
+
+func navHeader(t *testing.T) *rinex.NavHeader {
+	t.Helper()
+	r, err := os.Open("../rinex/testdata/white/AREG00PER_R_20201690000_01D_MN.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dec, err := rinex.NewNavDecoder(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &dec.Header
+}
+
+func TestKlobuchar(t *testing.T) {
+	assert := assert.New(t)
+	k, err := KlobucharFromNav(navHeader(t))
+	assert.NoError(err)
+	assert.Equal(5.5879e-09, k.Alpha[0])
+	assert.Equal(-5.2429e+05, k.Beta[3])
+
+	day := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t       time.Time
+		pos     rinex.LatLonHeight
+		az, el  float64
+		want    float64
+		comment string
+	}{
+		{day.Add(20*time.Hour + 45*time.Minute), rinex.LatLonHeight{Lat: 40, Lon: -100}, 210, 20, 5.926853, "ICD example geometry"},
+		{day.Add(12*time.Hour + 20*time.Minute), rinex.LatLonHeight{Lat: 50, Lon: 10}, 0, 90, 2.071551, "local afternoon, zenith"},
+		{day.Add(2 * time.Hour), rinex.LatLonHeight{Lat: 50, Lon: 10}, 0, 90, 1.499610, "night, 5 ns"},
+	}
+	for _, tt := range tests {
+		assert.InDelta(tt.want, k.Delay(tt.t, tt.pos, tt.az, tt.el), 1e-5, tt.comment)
+	}
+
+	zenith := k.Delay(tests[1].t, tests[1].pos, 0, 90)
+	assert.Greater(k.Delay(tests[1].t, tests[1].pos, 0, 10), 1.5*zenith, "obliquity")
+	assert.InDelta(zenith, k.VerticalDelay(tests[1].t, 50, 10), 0.01)
+	assert.InDelta(DelayToTEC(zenith, FreqL1), k.VTEC(tests[1].t, 50, 10), 0.1)
+
+	_, err = KlobucharFromNav(&rinex.NavHeader{})
+	assert.True(errors.Is(err, ErrNoParams))
+}
+
+func TestTECConversion(t *testing.T) {
+	assert := assert.New(t)
+	assert.InDelta(0.162, TECToDelay(1, FreqL1), 0.001, "1 TECU on L1")
+	assert.InDelta(42.0, DelayToTEC(TECToDelay(42, 1227.6e6), 1227.6e6), 1e-9)
+}
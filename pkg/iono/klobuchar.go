@@ -0,0 +1,90 @@
+package iono
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Klobuchar is the GPS broadcast ionosphere model according to IS-GPS-200, section 20.3.3.5.2.5.
+// The coefficients are in the units of the navigation message, i.e. seconds and semicircles.
+type Klobuchar struct {
+	Alpha [4]float64 // coefficients of the amplitude of the vertical delay
+	Beta  [4]float64 // coefficients of the period
+}
+
+// KlobucharFromNav returns the model with the GPSA and GPSB parameters of the navigation header.
+func KlobucharFromNav(hdr *rinex.NavHeader) (*Klobuchar, error) {
+	alpha, okA := hdr.IonoCorr["GPSA"]
+	beta, okB := hdr.IonoCorr["GPSB"]
+	if !okA || !okB {
+		return nil, fmt.Errorf("%w: GPSA and GPSB", ErrNoParams)
+	}
+	return &Klobuchar{Alpha: alpha, Beta: beta}, nil
+}
+
+// Delay returns the ionospheric delay in meters on L1 at the GPS time t for a receiver at pos and
+// a satellite at the azimuth and elevation az, el in degrees.
+func (k *Klobuchar) Delay(t time.Time, pos rinex.LatLonHeight, az, el float64) float64 {
+	e := el / 180 // semicircles
+	azRad := az * math.Pi / 180
+
+	// earth's central angle between the user position and the ionospheric pierce point (IPP)
+	psi := 0.0137/(e+0.11) - 0.022
+
+	latI := pos.Lat/180 + psi*math.Cos(azRad)
+	if latI > 0.416 {
+		latI = 0.416
+	} else if latI < -0.416 {
+		latI = -0.416
+	}
+	lonI := pos.Lon/180 + psi*math.Sin(azRad)/math.Cos(latI*math.Pi)
+
+	obliquity := 1 + 16*math.Pow(0.53-e, 3)
+	return obliquity * k.verticalDelay(t, latI, lonI)
+}
+
+// VerticalDelay returns the vertical ionospheric delay in meters on L1 at the GPS time t
+// at the geodetic latitude and longitude lat, lon in degrees.
+func (k *Klobuchar) VerticalDelay(t time.Time, lat, lon float64) float64 {
+	return k.verticalDelay(t, lat/180, lon/180)
+}
+
+// VTEC returns the vertical total electron content in TEC units at the GPS time t
+// at the geodetic latitude and longitude lat, lon in degrees.
+func (k *Klobuchar) VTEC(t time.Time, lat, lon float64) float64 {
+	return DelayToTEC(k.VerticalDelay(t, lat, lon), FreqL1)
+}
+
+// verticalDelay returns the vertical delay in meters at the IPP latitude and longitude in semicircles.
+func (k *Klobuchar) verticalDelay(t time.Time, latI, lonI float64) float64 {
+	// geomagnetic latitude of the IPP
+	latM := latI + 0.064*math.Cos((lonI-1.617)*math.Pi)
+
+	// local time of the IPP
+	localTime := math.Mod(4.32e4*lonI+secondsOfDay(t), 86400)
+	if localTime < 0 {
+		localTime += 86400
+	}
+
+	var amp, per float64
+	for i := 3; i >= 0; i-- {
+		amp = amp*latM + k.Alpha[i]
+		per = per*latM + k.Beta[i]
+	}
+	if amp < 0 {
+		amp = 0
+	}
+	if per < 72000 {
+		per = 72000
+	}
+
+	delay := 5e-9
+	if x := 2 * math.Pi * (localTime - 50400) / per; math.Abs(x) < 1.57 {
+		x2 := x * x
+		delay += amp * (1 - x2/2 + x2*x2/24)
+	}
+	return delay * speedOfLight
+}
@@ -0,0 +1,194 @@
+// Package metrics exports metrics of streaming GNSS decoders to Prometheus, e.g. for long-running
+// NTRIP to RINEX converters. A Stream is updated by the decoding loop, the Collector reports all
+// streams with the stream name as label.
+package metrics
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram in seconds.
+var LatencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30}
+
+// Stream contains the metrics of a data stream, like an NTRIP mountpoint.
+// The methods are safe for concurrent use.
+type Stream struct {
+	name string
+
+	bytesRead   uint64
+	epochs      uint64
+	parseErrors uint64
+	reconnects  uint64
+	sats        int64 // satellites in the last epoch
+
+	mu           sync.Mutex
+	lastEpoch    time.Time
+	latencyCount uint64
+	latencySum   float64
+	latencyBkts  []uint64 // cumulative counts per LatencyBuckets
+}
+
+func newStream(name string) *Stream {
+	return &Stream{name: name, latencyBkts: make([]uint64, len(LatencyBuckets))}
+}
+
+// Name returns the stream name.
+func (s *Stream) Name() string {
+	return s.name
+}
+
+// AddBytes adds n to the number of bytes read.
+func (s *Stream) AddBytes(n int) {
+	atomic.AddUint64(&s.bytesRead, uint64(n))
+}
+
+// Reader returns a reader that counts the bytes read from r.
+func (s *Stream) Reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, s: s}
+}
+
+// ObserveEpoch records a decoded epoch with its time in UTC and its number of satellites.
+// The latency is the difference between now and the epoch time.
+func (s *Stream) ObserveEpoch(t time.Time, numSats int) {
+	s.observeEpoch(t, numSats, time.Now())
+}
+
+func (s *Stream) observeEpoch(t time.Time, numSats int, now time.Time) {
+	atomic.AddUint64(&s.epochs, 1)
+	atomic.StoreInt64(&s.sats, int64(numSats))
+
+	latency := now.Sub(t).Seconds()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastEpoch = t
+	s.latencyCount++
+	s.latencySum += latency
+	for i, le := range LatencyBuckets {
+		if latency <= le {
+			s.latencyBkts[i]++
+		}
+	}
+}
+
+// ParseError counts a decoding error.
+func (s *Stream) ParseError() {
+	atomic.AddUint64(&s.parseErrors, 1)
+}
+
+// Reconnect counts a reconnection to the stream source.
+func (s *Stream) Reconnect() {
+	atomic.AddUint64(&s.reconnects, 1)
+}
+
+// Epochs returns the number of decoded epochs.
+func (s *Stream) Epochs() uint64 {
+	return atomic.LoadUint64(&s.epochs)
+}
+
+type countingReader struct {
+	r io.Reader
+	s *Stream
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.s.AddBytes(n)
+	return n, err
+}
+
+// Collector is a prometheus.Collector for streams.
+type Collector struct {
+	mu      sync.Mutex
+	streams map[string]*Stream
+
+	bytesRead   *prometheus.Desc
+	epochs      *prometheus.Desc
+	parseErrors *prometheus.Desc
+	reconnects  *prometheus.Desc
+	sats        *prometheus.Desc
+	lastEpoch   *prometheus.Desc
+	latency     *prometheus.Desc
+}
+
+// NewCollector returns a new collector. The metric names start with the namespace, e.g. gnss.
+func NewCollector(namespace string) *Collector {
+	labels := []string{"stream"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "stream", name), help, labels, nil)
+	}
+	return &Collector{
+		streams:     make(map[string]*Stream),
+		bytesRead:   desc("read_bytes_total", "Number of bytes read from the stream."),
+		epochs:      desc("epochs_total", "Number of decoded epochs."),
+		parseErrors: desc("parse_errors_total", "Number of decoding errors."),
+		reconnects:  desc("reconnects_total", "Number of reconnections to the stream source."),
+		sats:        desc("satellites", "Number of satellites in the last epoch."),
+		lastEpoch:   desc("last_epoch_timestamp_seconds", "Time of the last decoded epoch."),
+		latency:     desc("latency_seconds", "Delay between the epoch time and its decoding."),
+	}
+}
+
+// Stream returns the stream with the given name, it is created if necessary.
+func (c *Collector) Stream(name string) *Stream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.streams[name]
+	if !ok {
+		s = newStream(name)
+		c.streams[name] = s
+	}
+	return s
+}
+
+// Remove removes the stream with the given name, its metrics are no longer reported.
+func (c *Collector) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.streams, name)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.bytesRead, c.epochs, c.parseErrors, c.reconnects, c.sats, c.lastEpoch, c.latency} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	streams := make([]*Stream, 0, len(c.streams))
+	for _, s := range c.streams {
+		streams = append(streams, s)
+	}
+	c.mu.Unlock()
+	sort.Slice(streams, func(i, j int) bool { return streams[i].name < streams[j].name })
+
+	for _, s := range streams {
+		counter := func(d *prometheus.Desc, v *uint64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(atomic.LoadUint64(v)), s.name)
+		}
+		counter(c.bytesRead, &s.bytesRead)
+		counter(c.epochs, &s.epochs)
+		counter(c.parseErrors, &s.parseErrors)
+		counter(c.reconnects, &s.reconnects)
+		ch <- prometheus.MustNewConstMetric(c.sats, prometheus.GaugeValue, float64(atomic.LoadInt64(&s.sats)), s.name)
+
+		s.mu.Lock()
+		if !s.lastEpoch.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.lastEpoch, prometheus.GaugeValue, float64(s.lastEpoch.UnixNano())/1e9, s.name)
+		}
+		buckets := make(map[float64]uint64, len(LatencyBuckets))
+		for i, le := range LatencyBuckets {
+			buckets[le] = s.latencyBkts[i]
+		}
+		ch <- prometheus.MustNewConstHistogram(c.latency, s.latencyCount, s.latencySum, buckets, s.name)
+		s.mu.Unlock()
+	}
+}
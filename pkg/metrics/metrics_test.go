@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	assert := assert.New(t)
+	c := NewCollector("gnss")
+	reg := prometheus.NewPedanticRegistry()
+	assert.NoError(reg.Register(c))
+
+	s := c.Stream("WTZR00DEU0")
+	assert.Same(s, c.Stream("WTZR00DEU0"))
+	n, err := ioutil.ReadAll(s.Reader(strings.NewReader("0123456789")))
+	assert.NoError(err)
+	assert.Len(n, 10)
+	now := time.Date(2020, 6, 18, 12, 0, 1, 0, time.UTC)
+	s.observeEpoch(now.Add(-300*time.Millisecond), 12, now)
+	s.observeEpoch(now.Add(-3*time.Second), 11, now)
+	s.ParseError()
+	s.Reconnect()
+	s.Reconnect()
+	assert.Equal(uint64(2), s.Epochs())
+	c.Stream("BRUX00BEL0")
+
+	families, err := reg.Gather()
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	got := make(map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() != s.Name() {
+				continue
+			}
+			switch {
+			case m.Counter != nil:
+				got[mf.GetName()] = m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				got[mf.GetName()] = m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				h := m.GetHistogram()
+				got[mf.GetName()] = float64(h.GetSampleCount())
+				assert.InDelta(3.3, h.GetSampleSum(), 1e-9)
+				assert.Equal(uint64(0), h.GetBucket()[1].GetCumulativeCount(), "le 0.25")
+				assert.Equal(uint64(1), h.GetBucket()[2].GetCumulativeCount(), "le 0.5")
+				assert.Equal(uint64(2), h.GetBucket()[5].GetCumulativeCount(), "le 5")
+			}
+		}
+	}
+	assert.Equal(map[string]float64{
+		"gnss_stream_read_bytes_total":             10,
+		"gnss_stream_epochs_total":                 2,
+		"gnss_stream_parse_errors_total":           1,
+		"gnss_stream_reconnects_total":             2,
+		"gnss_stream_satellites":                   11,
+		"gnss_stream_last_epoch_timestamp_seconds": float64(now.Add(-3 * time.Second).Unix()),
+		"gnss_stream_latency_seconds":              2,
+	}, got)
+
+	c.Remove("BRUX00BEL0")
+	families, err = reg.Gather()
+	assert.NoError(err)
+	for _, mf := range families {
+		assert.Len(mf.GetMetric(), 1, mf.GetName())
+	}
+}
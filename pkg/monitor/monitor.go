@@ -0,0 +1,182 @@
+// Package monitor watches the RTCM 3 streams of NtripCaster mountpoints, e.g. as basis of a caster health
+// dashboard. It reports per stream the latency of the observation epochs, the message types and their
+// intervals, the gaps between the epochs and the outages of the data flow.
+//
+//	mon := monitor.New()
+//	mon.Add("http://www.igs-ip.net:2101", "WTZR00DEU0", ntrip.Options{Username: "user", Password: "pass"})
+//	go mon.Run(ctx)
+//	http.Handle("/streams", mon)
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+)
+
+// Monitor watches a set of streams.
+type Monitor struct {
+	// Timeout is the time without data after which the stream is regarded as down and reconnected.
+	// It must be set before adding the streams, like LeapSeconds.
+	Timeout time.Duration
+
+	// MaxWait is the maximum time between reconnection attempts.
+	MaxWait time.Duration
+
+	// LeapSeconds is the number of GPS-UTC leap seconds, 0 means the value of the gnsstime leap second table.
+	LeapSeconds int
+
+	mu      sync.Mutex
+	entries []*entry
+}
+
+type entry struct {
+	client     *ntrip.Client
+	mountpoint string
+	stream     *Stream
+}
+
+// New returns a new monitor with a timeout of 30 seconds.
+func New() *Monitor {
+	return &Monitor{Timeout: 30 * time.Second, MaxWait: time.Minute}
+}
+
+// Add adds the mountpoint of the caster addr. Each stream gets its own client.
+// Streams must be added before calling Run.
+func (m *Monitor) Add(addr, mountpoint string, opts ntrip.Options) (*Stream, error) {
+	c, err := ntrip.NewClient(addr, opts)
+	if err != nil {
+		return nil, fmt.Errorf("stream %s: %v", mountpoint, err)
+	}
+	c.Timeout = 0 // the stream is read as long as data arrives, see Monitor.Timeout
+
+	s := newStream(c.URL.Host, mountpoint, m.Timeout, m.LeapSeconds, time.Now())
+	m.mu.Lock()
+	m.entries = append(m.entries, &entry{client: c, mountpoint: mountpoint, stream: s})
+	m.mu.Unlock()
+	return s, nil
+}
+
+// Run connects to all streams and reconnects on errors and timeouts, until ctx is canceled.
+func (m *Monitor) Run(ctx context.Context) {
+	m.mu.Lock()
+	entries := append([]*entry(nil), m.entries...)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func(e *entry) {
+			defer wg.Done()
+			m.watch(ctx, e)
+		}(e)
+	}
+	wg.Wait()
+}
+
+// Stats returns the statistics of all streams in the order they were added.
+func (m *Monitor) Stats() []Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]Stats, 0, len(m.entries))
+	for _, e := range m.entries {
+		stats = append(stats, e.stream.Stats())
+	}
+	return stats
+}
+
+// ServeHTTP writes the statistics of all streams as JSON.
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m.Stats()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// watch pulls the stream until ctx is canceled, with exponential backoff between the connection attempts.
+func (m *Monitor) watch(ctx context.Context, e *entry) {
+	wait := time.Second
+	for ctx.Err() == nil {
+		n, err := m.pull(ctx, e)
+		if ctx.Err() != nil {
+			e.stream.disconnected(nil)
+			return
+		}
+		e.stream.disconnected(err)
+		if n > 0 {
+			wait = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > m.MaxWait {
+			wait = m.MaxWait
+		}
+	}
+}
+
+// pull requests the stream and reads the frames until an error occurs, no data was received for
+// the timeout or ctx is canceled. It returns the number of bytes read.
+func (m *Monitor) pull(ctx context.Context, e *entry) (int64, error) {
+	body, err := e.client.GetStream(e.mountpoint)
+	if err != nil {
+		if body != nil {
+			body.Close()
+		}
+		return 0, err
+	}
+	defer body.Close()
+	e.stream.connected()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			body.Close()
+		case <-done:
+		}
+	}()
+
+	idle := time.AfterFunc(m.Timeout, func() { body.Close() })
+	defer idle.Stop()
+	r := &idleReader{r: body, idle: idle, timeout: m.Timeout, s: e.stream}
+	dec := rtcm3.NewDecoder(r)
+	for dec.NextFrame() {
+		e.stream.observe(dec.Payload(), time.Now())
+	}
+	if err := dec.Err(); err != nil {
+		return r.n, err
+	}
+	return r.n, io.EOF
+}
+
+// idleReader resets the idle timer on every read with data and counts the bytes.
+type idleReader struct {
+	r       io.Reader
+	idle    *time.Timer
+	timeout time.Duration
+	s       *Stream
+	n       int64
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if n > 0 {
+		ir.idle.Reset(ir.timeout)
+		ir.n += int64(n)
+		ir.s.addBytes(n)
+	}
+	return n, err
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/ntrip"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+	"github.com/stretchr/testify/assert"
+)
+
+// msm7 returns the payload of a GPS MSM7 for the epoch t in GPS time.
+func msm7(t *testing.T, epoch time.Time) []byte {
+	t.Helper()
+	epo := &rinex.Epoch{Time: epoch, ObsList: []rinex.SatObs{
+		rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGPS, Num: 5}, map[string]rinex.Obs{"C1C": {Val: 22331467.258}}),
+	}}
+	msgs := rtcm3.NewMSMEncoder(42).Messages(epo)
+	payload, err := msgs[0].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+var msg1006 = []byte{0x3E, 0xE0, 0x2A, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+func TestStream_observe(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC) // UTC
+	s := newStream("localhost:2101", "TEST00DEU0", 10*time.Second, 18, start)
+	s.connected()
+
+	// 1 Hz stream with a latency of 0.5 s, epochs 10-14 are missing, no data for 20 s after epoch 30
+	for i := 0; i < 40; i++ {
+		if i >= 10 && i < 15 {
+			continue
+		}
+		epoch := start.Add(time.Duration(i)*time.Second + 18*time.Second) // GPS
+		now := start.Add(time.Duration(i)*time.Second + 500*time.Millisecond)
+		if i > 30 {
+			now = now.Add(20 * time.Second)
+		}
+		s.observe(msm7(t, epoch), now)
+		if i%10 == 0 {
+			s.observe(msg1006, now)
+		}
+	}
+
+	st := s.snapshot(start.Add(61 * time.Second))
+	assert.Equal("TEST00DEU0", st.Mountpoint)
+	assert.True(st.Connected)
+	assert.Equal(0, st.Reconnects)
+	assert.Equal(35, st.Epochs)
+	assert.Equal(start.Add(57*time.Second), st.LastEpoch)
+	assert.InDelta(20.5, st.Latency, 1e-9)
+	assert.InDelta(20.5, st.MaxLatency, 1e-9)
+	assert.InDelta((26*0.5+9*20.5)/35, st.MeanLatency, 1e-9)
+
+	assert.Equal(1.0, st.Interval)
+	assert.Equal(1, st.NumGaps)
+	assert.Equal(5, st.MissingEpochs)
+	assert.Equal([]Period{{Start: start.Add(27 * time.Second), End: start.Add(33 * time.Second)}}, st.Gaps)
+
+	assert.Equal(1, st.NumOutages)
+	assert.InDelta(21, st.Downtime, 1e-9)
+	if assert.Len(st.Outages, 1) {
+		assert.Equal(start.Add(30*time.Second+500*time.Millisecond), st.Outages[0].Start)
+	}
+
+	assert.Equal(35, st.Messages[1077].Count)
+	assert.InDelta(59.0/34, st.Messages[1077].Interval, 1e-9)
+	assert.Equal(3, st.Messages[1006].Count)
+	assert.InDelta(15, st.Messages[1006].Interval, 1e-9) // at 0, 20 and 30 s
+
+	// ongoing outage
+	st = s.snapshot(start.Add(80 * time.Second))
+	if assert.Len(st.Outages, 2) {
+		assert.True(st.Outages[1].End.IsZero())
+	}
+
+	// the snapshot is a copy
+	st.Messages[1077].Count = 0
+	assert.Equal(35, s.Stats().Messages[1077].Count)
+
+	s.disconnected(nil)
+	s.connected()
+	assert.Equal(1, s.Stats().Reconnects)
+}
+
+func TestMonitor(t *testing.T) {
+	assert := assert.New(t)
+	var frames []byte
+	now := time.Now().UTC().Truncate(time.Second).Add(18 * time.Second)
+	for i := 0; i < 3; i++ {
+		frame, err := rtcm3.EncodeFrame(msm7(t, now.Add(time.Duration(i)*time.Second)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame...)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "gnss/data")
+		w.Write(frames)
+	}))
+	defer srv.Close()
+
+	mon := New()
+	mon.LeapSeconds = 18
+	s, err := mon.Add(srv.URL, "TEST00DEU0", ntrip.Options{})
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		mon.Run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 100 && s.Stats().Epochs < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	stats := mon.Stats()
+	if !assert.Len(stats, 1) {
+		t.FailNow()
+	}
+	assert.Equal(3, stats[0].Epochs)
+	assert.Equal(int64(len(frames)), stats[0].Bytes)
+	assert.False(stats[0].Connected)
+
+	rec := httptest.NewRecorder()
+	mon.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+	var got []Stats
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal("TEST00DEU0", got[0].Mountpoint)
+	assert.Equal(3, got[0].Messages[1077].Count)
+
+	_, err = mon.Add("ftp://localhost", "TEST", ntrip.Options{})
+	assert.Error(err)
+}
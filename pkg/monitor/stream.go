@@ -0,0 +1,202 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+)
+
+// MaxEvents is the number of the most recent gaps and outages kept per stream.
+const MaxEvents = 100
+
+// Period is a time span, End is zero if the period is still ongoing.
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+}
+
+// MessageStats are the statistics of a message type.
+type MessageStats struct {
+	Count    int       `json:"count"`
+	Interval float64   `json:"interval"` // mean time between the messages in seconds
+	Last     time.Time `json:"last"`     // time of reception of the last message
+
+	first time.Time
+}
+
+// Stats is a snapshot of the statistics of a stream. The epochs are in GPS time, the other times in UTC.
+type Stats struct {
+	Caster     string `json:"caster"`
+	Mountpoint string `json:"mountpoint"`
+	Connected  bool   `json:"connected"`
+	Reconnects int    `json:"reconnects"`
+	LastError  string `json:"lastError,omitempty"`
+
+	Since    time.Time             `json:"since"` // start of monitoring
+	Bytes    int64                 `json:"bytes"`
+	Messages map[int]*MessageStats `json:"messages"`
+
+	Epochs      int       `json:"epochs"`
+	LastEpoch   time.Time `json:"lastEpoch"`
+	Latency     float64   `json:"latency"`     // of the last epoch in seconds
+	MeanLatency float64   `json:"meanLatency"` // in seconds
+	MaxLatency  float64   `json:"maxLatency"`  // in seconds
+
+	Interval      float64  `json:"interval"` // observation interval in seconds
+	NumGaps       int      `json:"numGaps"`
+	MissingEpochs int      `json:"missingEpochs"`
+	Gaps          []Period `json:"gaps"` // between the epochs before and after the gap
+
+	NumOutages int      `json:"numOutages"`
+	Downtime   float64  `json:"downtime"` // total duration of the outages in seconds
+	Outages    []Period `json:"outages"`  // periods without data, including the disconnections
+}
+
+// Stream collects the statistics of a mountpoint. The methods are safe for concurrent use.
+type Stream struct {
+	timeout     time.Duration
+	leapSeconds int
+
+	mu         sync.Mutex
+	stats      Stats
+	connects   int
+	lastData   time.Time // reception of the last frame
+	latencySum float64
+	interval   time.Duration
+}
+
+func newStream(caster, mountpoint string, timeout time.Duration, leapSeconds int, now time.Time) *Stream {
+	return &Stream{
+		timeout:     timeout,
+		leapSeconds: leapSeconds,
+		stats:       Stats{Caster: caster, Mountpoint: mountpoint, Since: now, Messages: make(map[int]*MessageStats)},
+	}
+}
+
+// connected records a (re)connection to the caster.
+func (s *Stream) connected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.connects++; s.connects > 1 {
+		s.stats.Reconnects++
+	}
+	s.stats.Connected = true
+}
+
+// disconnected records the end of the connection or a failed connection attempt.
+func (s *Stream) disconnected(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Connected = false
+	if err != nil {
+		s.stats.LastError = err.Error()
+	}
+}
+
+func (s *Stream) addBytes(n int) {
+	s.mu.Lock()
+	s.stats.Bytes += int64(n)
+	s.mu.Unlock()
+}
+
+// observe records the RTCM 3 message payload received at now.
+func (s *Stream) observe(payload []byte, now time.Time) {
+	if len(payload) < 2 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.lastData.IsZero() && now.Sub(s.lastData) > s.timeout {
+		s.stats.NumOutages++
+		s.stats.Downtime += now.Sub(s.lastData).Seconds()
+		s.stats.Outages = appendEvent(s.stats.Outages, Period{Start: s.lastData, End: now})
+	}
+	s.lastData = now
+
+	num := int(payload[0])<<4 | int(payload[1]>>4)
+	msg, ok := s.stats.Messages[num]
+	if !ok {
+		msg = &MessageStats{first: now}
+		s.stats.Messages[num] = msg
+	}
+	msg.Count++
+	msg.Last = now
+	if msg.Count > 1 {
+		msg.Interval = now.Sub(msg.first).Seconds() / float64(msg.Count-1)
+	}
+
+	leap := s.leapSeconds
+	if leap == 0 {
+		leap = gnsstime.LeapSecondsGPS(now)
+	}
+	leapDur := time.Duration(leap) * time.Second
+	epoch, ok := rtcm3.EpochTime(payload, now.Add(leapDur), leap)
+	if !ok || !epoch.After(s.stats.LastEpoch) { // further messages of the epoch
+		return
+	}
+	s.observeEpoch(epoch, now.Sub(epoch.Add(-leapDur)).Seconds())
+}
+
+// observeEpoch records a new epoch with its latency.
+func (s *Stream) observeEpoch(epoch time.Time, latency float64) {
+	st := &s.stats
+	if !st.LastEpoch.IsZero() {
+		dt := epoch.Sub(st.LastEpoch)
+		if s.interval == 0 || dt < s.interval {
+			s.interval = dt
+			st.Interval = dt.Seconds()
+		}
+		if dt > s.interval+s.interval/2 {
+			st.NumGaps++
+			st.MissingEpochs += int((dt+s.interval/2)/s.interval) - 1
+			st.Gaps = appendEvent(st.Gaps, Period{Start: st.LastEpoch, End: epoch})
+		}
+	}
+	st.Epochs++
+	st.LastEpoch = epoch
+	st.Latency = latency
+	if latency > st.MaxLatency || st.Epochs == 1 {
+		st.MaxLatency = latency
+	}
+	s.latencySum += latency
+	st.MeanLatency = s.latencySum / float64(st.Epochs)
+}
+
+// Stats returns a snapshot of the statistics. An ongoing outage is appended to the outages.
+func (s *Stream) Stats() Stats {
+	return s.snapshot(time.Now())
+}
+
+func (s *Stream) snapshot(now time.Time) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.stats
+	st.Messages = make(map[int]*MessageStats, len(s.stats.Messages))
+	for num, msg := range s.stats.Messages {
+		m := *msg
+		st.Messages[num] = &m
+	}
+	st.Gaps = append([]Period(nil), s.stats.Gaps...)
+	st.Outages = append([]Period(nil), s.stats.Outages...)
+
+	start := s.lastData
+	if start.IsZero() {
+		start = s.stats.Since
+	}
+	if now.Sub(start) > s.timeout {
+		st.Outages = append(st.Outages, Period{Start: start})
+	}
+	return st
+}
+
+// appendEvent appends p and drops the oldest events beyond MaxEvents.
+func appendEvent(events []Period, p Period) []Period {
+	events = append(events, p)
+	if len(events) > MaxEvents {
+		events = append(events[:0], events[len(events)-MaxEvents:]...)
+	}
+	return events
+}
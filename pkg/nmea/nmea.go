@@ -0,0 +1,234 @@
+// Package nmea provides functions for parsing and encoding NMEA 0183 sentences.
+//
+// Supported are the sentences GGA, RMC, GSV, GSA and ZDA, other sentences are returned as Unknown.
+// A typical use case is sending the position of a rover as GGA sentence to a VRS NtripCaster.
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/crc"
+)
+
+// Sentence is a NMEA sentence.
+type Sentence interface {
+	// Prefix returns the talker ID and the sentence type, e.g. "GPGGA".
+	Prefix() string
+
+	// String returns the encoded sentence incl. the checksum but without line terminator.
+	String() string
+}
+
+// Unknown is a sentence not decoded by this package.
+type Unknown struct {
+	Talker string
+	Type   string
+	Fields []string
+}
+
+// Prefix returns the talker ID and the sentence type.
+func (s *Unknown) Prefix() string { return s.Talker + s.Type }
+
+// String returns the encoded sentence.
+func (s *Unknown) String() string { return encode(s.Prefix(), s.Fields...) }
+
+// Checksum returns the XOR checksum of the sentence data, i.e. the characters between '$' and '*'.
+// The delimiters are skipped if data includes them.
+func Checksum(data string) byte {
+	data = strings.TrimPrefix(data, "$")
+	if i := strings.IndexByte(data, '*'); i >= 0 {
+		data = data[:i]
+	}
+	return crc.NMEA([]byte(data))
+}
+
+// Parse parses the sentence and validates its checksum. Line terminators are ignored.
+func Parse(line string) (Sentence, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "$") && !strings.HasPrefix(line, "!") {
+		return nil, fmt.Errorf("invalid sentence start: %q", line)
+	}
+	data := line[1:]
+	if i := strings.IndexByte(data, '*'); i >= 0 {
+		cs, err := strconv.ParseUint(data[i+1:], 16, 8)
+		if err != nil || len(data[i+1:]) != 2 {
+			return nil, fmt.Errorf("invalid checksum: %q", line)
+		}
+		data = data[:i]
+		if byte(cs) != Checksum(data) {
+			return nil, fmt.Errorf("checksum mismatch: got %02X, want %02X: %q", cs, Checksum(data), line)
+		}
+	} else {
+		return nil, fmt.Errorf("missing checksum: %q", line)
+	}
+
+	fields := strings.Split(data, ",")
+	prefix := fields[0]
+	if len(prefix) < 3 {
+		return nil, fmt.Errorf("invalid address field: %q", line)
+	}
+	talker, typ := prefix[:len(prefix)-3], prefix[len(prefix)-3:]
+	if strings.HasPrefix(prefix, "P") { // proprietary
+		talker, typ = "P", prefix[1:]
+	}
+
+	p := &parser{fields: fields}
+	var s Sentence
+	switch typ {
+	case "GGA":
+		s = p.gga(talker)
+	case "RMC":
+		s = p.rmc(talker)
+	case "GSV":
+		s = p.gsv(talker)
+	case "GSA":
+		s = p.gsa(talker)
+	case "ZDA":
+		s = p.zda(talker)
+	default:
+		return &Unknown{Talker: talker, Type: typ, Fields: fields[1:]}, nil
+	}
+	if p.err != nil {
+		return nil, fmt.Errorf("parse %s: %v", prefix, p.err)
+	}
+	return s, nil
+}
+
+// encode builds the sentence from the address field and the data fields.
+func encode(prefix string, fields ...string) string {
+	data := prefix
+	if len(fields) > 0 {
+		data += "," + strings.Join(fields, ",")
+	}
+	return fmt.Sprintf("$%s*%02X", data, Checksum(data))
+}
+
+// parser parses the fields of a sentence and keeps the first error.
+type parser struct {
+	fields []string
+	err    error
+}
+
+func (p *parser) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// field returns the field at index i, or an empty string if it does not exist.
+func (p *parser) field(i int) string {
+	if i >= len(p.fields) {
+		return ""
+	}
+	return strings.TrimSpace(p.fields[i])
+}
+
+func (p *parser) float(i int) float64 {
+	s := p.field(i)
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		p.setErr(fmt.Errorf("field %d: %v", i, err))
+	}
+	return f
+}
+
+func (p *parser) atoi(i int) int {
+	s := p.field(i)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		p.setErr(fmt.Errorf("field %d: %v", i, err))
+	}
+	return n
+}
+
+// latLon parses a coordinate in the format [d]ddmm.mmmm at index i, followed by its hemisphere.
+func (p *parser) latLon(i int) float64 {
+	s := p.field(i)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		p.setErr(fmt.Errorf("field %d: %v", i, err))
+		return 0
+	}
+	deg := math.Trunc(v / 100)
+	deg += (v - deg*100) / 60
+	switch p.field(i + 1) {
+	case "N", "E":
+	case "S", "W":
+		deg = -deg
+	default:
+		p.setErr(fmt.Errorf("field %d: invalid hemisphere %q", i+1, p.field(i+1)))
+	}
+	return deg
+}
+
+// timeOfDay parses the UTC time hhmmss.ss at index i. The date is set to the zero date.
+func (p *parser) timeOfDay(i int) time.Time {
+	s := p.field(i)
+	if s == "" {
+		return time.Time{}
+	}
+	if len(s) < 6 {
+		p.setErr(fmt.Errorf("field %d: invalid time %q", i, s))
+		return time.Time{}
+	}
+	hh, err1 := strconv.Atoi(s[0:2])
+	mm, err2 := strconv.Atoi(s[2:4])
+	sec, err3 := strconv.ParseFloat(s[4:], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		p.setErr(fmt.Errorf("field %d: invalid time %q", i, s))
+		return time.Time{}
+	}
+	return time.Time{}.Add(time.Duration(hh)*time.Hour + time.Duration(mm)*time.Minute +
+		time.Duration(math.Round(sec*1e3))*time.Millisecond)
+}
+
+// withDate sets the date of the time of day t.
+func withDate(t time.Time, year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// formatTime formats the time of day as hhmmss.ss.
+func formatTime(t time.Time) string {
+	t = t.Round(10 * time.Millisecond)
+	sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	return fmt.Sprintf("%02d%02d%05.2f", t.Hour(), t.Minute(), sec)
+}
+
+// formatLatLon formats the coordinate in degrees as [d]ddmm.mmmmmm and its hemisphere.
+func formatLatLon(deg float64, degDigits int, pos, neg string) (string, string) {
+	hemi := pos
+	if deg < 0 {
+		hemi, deg = neg, -deg
+	}
+	d := math.Trunc(deg)
+	minutes := (deg - d) * 60
+	if math.Round(minutes*1e6) >= 60e6 { // avoid 60 minutes due to rounding
+		d, minutes = d+1, 0
+	}
+	return fmt.Sprintf("%0*d%09.6f", degDigits, int(d), minutes), hemi
+}
+
+func formatFloat(f float64, prec int) string {
+	return strconv.FormatFloat(f, 'f', prec, 64)
+}
+
+// formatOptInt formats n, or an empty field if n is zero.
+func formatOptInt(n int, width int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%0*d", width, n)
+}
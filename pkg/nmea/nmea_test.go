@@ -0,0 +1,145 @@
+package nmea
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(byte(0x47), Checksum("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"))
+	assert.Equal(byte(0x47), Checksum("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"))
+}
+
+func TestParse_GGA(t *testing.T) {
+	assert := assert.New(t)
+	s, err := Parse("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	gga, ok := s.(*GGA)
+	if !assert.True(ok) {
+		t.FailNow()
+	}
+	assert.Equal("GPGGA", gga.Prefix())
+	assert.Equal(12, gga.Time.Hour())
+	assert.Equal(35, gga.Time.Minute())
+	assert.Equal(19, gga.Time.Second())
+	assert.InDelta(48.1173, gga.Lat, 1e-9)
+	assert.InDelta(11.516666667, gga.Lon, 1e-9)
+	assert.Equal(QualityGPS, gga.Quality)
+	assert.Equal(8, gga.NumSat)
+	assert.Equal(0.9, gga.HDOP)
+	assert.Equal(545.4, gga.Alt)
+	assert.Equal(46.9, gga.GeoidSep)
+
+	// round trip
+	enc := gga.String()
+	assert.Equal("$GPGGA,123519.00,4807.038000,N,01131.000000,E,1,08,0.9,545.400,M,46.900,M,,*69", enc)
+	s2, err := Parse(enc)
+	assert.NoError(err)
+	assert.Equal(gga, s2)
+
+	gga = &GGA{Talker: "GN", Time: time.Date(2020, 6, 1, 8, 5, 9, 500e6, time.UTC), Lat: -33.5, Lon: -70.25,
+		Quality: QualityRTKFixed, NumSat: 14, HDOP: 0.7, Alt: 520.123, GeoidSep: 30.1, DGPSAge: 1.5, DGPSStation: "0123"}
+	enc = gga.String()
+	assert.Equal("$GNGGA,080509.50,3330.000000,S,07015.000000,W,4,14,0.7,520.123,M,30.100,M,1.5,0123*", enc[:len(enc)-2])
+	_, err = Parse(enc)
+	assert.NoError(err)
+}
+
+func TestParse_RMC(t *testing.T) {
+	assert := assert.New(t)
+	s, err := Parse("$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	rmc := s.(*RMC)
+	assert.Equal(time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC), rmc.Time)
+	assert.True(rmc.Valid)
+	assert.Equal(22.4, rmc.Speed)
+	assert.Equal(84.4, rmc.Course)
+	assert.Equal(-3.1, rmc.MagVar)
+	assert.Equal("", rmc.Mode)
+
+	rmc.Mode = "A"
+	s2, err := Parse(rmc.String())
+	assert.NoError(err)
+	assert.Equal(rmc, s2)
+}
+
+func TestParse_GSA(t *testing.T) {
+	assert := assert.New(t)
+	s, err := Parse("$GNGSA,A,3,80,71,73,79,69,,,,,,,,1.83,1.09,1.47*17")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	gsa := s.(*GSA)
+	assert.Equal("A", gsa.Mode)
+	assert.Equal(3, gsa.FixType)
+	assert.Equal([]int{80, 71, 73, 79, 69}, gsa.PRNs)
+	assert.Equal(1.83, gsa.PDOP)
+	assert.Equal(1.47, gsa.VDOP)
+	assert.Equal(0, gsa.SystemID)
+
+	gsa.SystemID = 2
+	s2, err := Parse(gsa.String())
+	assert.NoError(err)
+	gsa.PDOP, gsa.HDOP, gsa.VDOP = 1.8, 1.1, 1.5 // one decimal encoded
+	assert.Equal(gsa, s2)
+}
+
+func TestParse_GSV(t *testing.T) {
+	assert := assert.New(t)
+	s, err := Parse("$GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00*74")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	gsv := s.(*GSV)
+	assert.Equal(3, gsv.NumMsgs)
+	assert.Equal(1, gsv.MsgNum)
+	assert.Equal(11, gsv.NumSats)
+	assert.Len(gsv.Sats, 4)
+	assert.Equal(GSVSat{PRN: 4, Elevation: 15, Azimuth: 270}, gsv.Sats[1])
+
+	s, err = Parse("$GPGSV,3,3,11,22,42,067,42,24,14,311,43,27,05,244,00,1*50")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	gsv = s.(*GSV)
+	assert.Len(gsv.Sats, 3)
+	assert.Equal("1", gsv.SignalID)
+	assert.Equal(GSVSat{PRN: 24, Elevation: 14, Azimuth: 311, SNR: 43}, gsv.Sats[1])
+	s2, err := Parse(gsv.String())
+	assert.NoError(err)
+	assert.Equal(gsv, s2)
+}
+
+func TestParse_ZDA(t *testing.T) {
+	assert := assert.New(t)
+	s, err := Parse("$GPZDA,201530.00,04,07,2002,00,00*60")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	zda := s.(*ZDA)
+	assert.Equal(time.Date(2002, 7, 4, 20, 15, 30, 0, time.UTC), zda.Time)
+	assert.Equal("$GPZDA,201530.00,04,07,2002,00,00*60", zda.String())
+}
+
+func TestParse_errors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := Parse("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48")
+	assert.Error(err, "checksum mismatch")
+	_, err = Parse("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")
+	assert.Error(err, "missing checksum")
+	_, err = Parse("GPGGA,123519*00")
+	assert.Error(err, "no start")
+	_, err = Parse(encode("GPGGA", "123519", "4807.038", "X", "01131.000", "E"))
+	assert.Error(err, "invalid hemisphere")
+
+	s, err := Parse("$PUBX,00,081350.00*3E")
+	assert.NoError(err)
+	assert.Equal("PUBX", s.Prefix())
+}
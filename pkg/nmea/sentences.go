@@ -0,0 +1,271 @@
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// GGA fix quality indicators.
+const (
+	QualityInvalid  = 0
+	QualityGPS      = 1
+	QualityDGPS     = 2
+	QualityPPS      = 3
+	QualityRTKFixed = 4
+	QualityRTKFloat = 5
+	QualityDR       = 6 // dead reckoning
+	QualityManual   = 7
+	QualitySimulate = 8
+)
+
+// GGA contains the time, position and fix related data of the receiver.
+type GGA struct {
+	Talker      string
+	Time        time.Time // UTC time of day, the date is zero
+	Lat         float64   // latitude in degrees, negative for south
+	Lon         float64   // longitude in degrees, negative for west
+	Quality     int       // fix quality indicator
+	NumSat      int       // number of satellites in use
+	HDOP        float64
+	Alt         float64 // altitude above mean sea level in meters
+	GeoidSep    float64 // geoid separation in meters
+	DGPSAge     float64 // age of differential corrections in seconds, zero if not available
+	DGPSStation string  // differential reference station ID
+}
+
+// Prefix returns the talker ID and the sentence type.
+func (s *GGA) Prefix() string { return s.Talker + "GGA" }
+
+// String returns the encoded sentence.
+func (s *GGA) String() string {
+	lat, ns := formatLatLon(s.Lat, 2, "N", "S")
+	lon, ew := formatLatLon(s.Lon, 3, "E", "W")
+	age := ""
+	if s.DGPSAge > 0 {
+		age = formatFloat(s.DGPSAge, 1)
+	}
+	return encode(s.Prefix(), formatTime(s.Time), lat, ns, lon, ew, strconv.Itoa(s.Quality),
+		fmt.Sprintf("%02d", s.NumSat), formatFloat(s.HDOP, 1), formatFloat(s.Alt, 3), "M",
+		formatFloat(s.GeoidSep, 3), "M", age, s.DGPSStation)
+}
+
+func (p *parser) gga(talker string) *GGA {
+	return &GGA{
+		Talker:      talker,
+		Time:        p.timeOfDay(1),
+		Lat:         p.latLon(2),
+		Lon:         p.latLon(4),
+		Quality:     p.atoi(6),
+		NumSat:      p.atoi(7),
+		HDOP:        p.float(8),
+		Alt:         p.float(9),
+		GeoidSep:    p.float(11),
+		DGPSAge:     p.float(13),
+		DGPSStation: p.field(14),
+	}
+}
+
+// RMC contains the recommended minimum specific GNSS data.
+type RMC struct {
+	Talker string
+	Time   time.Time // UTC date and time
+	Valid  bool      // status A=valid, V=invalid
+	Lat    float64   // latitude in degrees, negative for south
+	Lon    float64   // longitude in degrees, negative for west
+	Speed  float64   // speed over ground in knots
+	Course float64   // course over ground in degrees true
+	MagVar float64   // magnetic variation in degrees, negative for west
+	Mode   string    // mode indicator since NMEA 2.3, e.g. A=autonomous, D=differential
+}
+
+// Prefix returns the talker ID and the sentence type.
+func (s *RMC) Prefix() string { return s.Talker + "RMC" }
+
+// String returns the encoded sentence.
+func (s *RMC) String() string {
+	status := "V"
+	if s.Valid {
+		status = "A"
+	}
+	lat, ns := formatLatLon(s.Lat, 2, "N", "S")
+	lon, ew := formatLatLon(s.Lon, 3, "E", "W")
+	magVar, magEW := "", ""
+	if s.MagVar != 0 {
+		magVar, magEW = formatFloat(math.Abs(s.MagVar), 1), "E"
+		if s.MagVar < 0 {
+			magEW = "W"
+		}
+	}
+	fields := []string{formatTime(s.Time), status, lat, ns, lon, ew, formatFloat(s.Speed, 3),
+		formatFloat(s.Course, 2), s.Time.Format("020106"), magVar, magEW}
+	if s.Mode != "" {
+		fields = append(fields, s.Mode)
+	}
+	return encode(s.Prefix(), fields...)
+}
+
+func (p *parser) rmc(talker string) *RMC {
+	s := &RMC{
+		Talker: talker,
+		Time:   p.timeOfDay(1),
+		Valid:  p.field(2) == "A",
+		Lat:    p.latLon(3),
+		Lon:    p.latLon(5),
+		Speed:  p.float(7),
+		Course: p.float(8),
+		MagVar: p.float(10),
+		Mode:   p.field(12),
+	}
+	if p.field(11) == "W" {
+		s.MagVar = -s.MagVar
+	}
+	if date := p.field(9); date != "" {
+		t, err := time.Parse("020106", date)
+		if err != nil {
+			p.setErr(fmt.Errorf("field 9: invalid date %q", date))
+		}
+		s.Time = withDate(s.Time, t.Year(), t.Month(), t.Day())
+	}
+	return s
+}
+
+// GSA contains the DOP values and the satellites used in the navigation solution.
+type GSA struct {
+	Talker   string
+	Mode     string // M=manual, A=automatic 2D/3D
+	FixType  int    // 1=no fix, 2=2D, 3=3D
+	PRNs     []int  // IDs of the satellites used in the solution, max. 12
+	PDOP     float64
+	HDOP     float64
+	VDOP     float64
+	SystemID int // GNSS system ID since NMEA 4.10, zero if not given
+}
+
+// Prefix returns the talker ID and the sentence type.
+func (s *GSA) Prefix() string { return s.Talker + "GSA" }
+
+// String returns the encoded sentence.
+func (s *GSA) String() string {
+	fields := make([]string, 0, 18)
+	fields = append(fields, s.Mode, strconv.Itoa(s.FixType))
+	for i := 0; i < 12; i++ {
+		prn := ""
+		if i < len(s.PRNs) {
+			prn = fmt.Sprintf("%02d", s.PRNs[i])
+		}
+		fields = append(fields, prn)
+	}
+	fields = append(fields, formatFloat(s.PDOP, 1), formatFloat(s.HDOP, 1), formatFloat(s.VDOP, 1))
+	if s.SystemID != 0 {
+		fields = append(fields, strconv.Itoa(s.SystemID))
+	}
+	return encode(s.Prefix(), fields...)
+}
+
+func (p *parser) gsa(talker string) *GSA {
+	s := &GSA{
+		Talker:   talker,
+		Mode:     p.field(1),
+		FixType:  p.atoi(2),
+		PDOP:     p.float(15),
+		HDOP:     p.float(16),
+		VDOP:     p.float(17),
+		SystemID: p.atoi(18),
+	}
+	for i := 3; i < 15; i++ {
+		if p.field(i) != "" {
+			s.PRNs = append(s.PRNs, p.atoi(i))
+		}
+	}
+	return s
+}
+
+// GSVSat describes a satellite in view.
+type GSVSat struct {
+	PRN       int
+	Elevation int // degrees
+	Azimuth   int // degrees true
+	SNR       int // dBHz, zero if not tracking
+}
+
+// GSV contains the satellites in view. The satellites are split across several messages,
+// each with at most 4 satellites.
+type GSV struct {
+	Talker   string
+	NumMsgs  int // total number of messages
+	MsgNum   int // message number
+	NumSats  int // total number of satellites in view
+	Sats     []GSVSat
+	SignalID string // signal ID since NMEA 4.10, empty if not given
+}
+
+// Prefix returns the talker ID and the sentence type.
+func (s *GSV) Prefix() string { return s.Talker + "GSV" }
+
+// String returns the encoded sentence.
+func (s *GSV) String() string {
+	fields := make([]string, 0, 20)
+	fields = append(fields, strconv.Itoa(s.NumMsgs), strconv.Itoa(s.MsgNum), fmt.Sprintf("%02d", s.NumSats))
+	for _, sat := range s.Sats {
+		fields = append(fields, fmt.Sprintf("%02d", sat.PRN), fmt.Sprintf("%02d", sat.Elevation),
+			fmt.Sprintf("%03d", sat.Azimuth), formatOptInt(sat.SNR, 2))
+	}
+	if s.SignalID != "" {
+		fields = append(fields, s.SignalID)
+	}
+	return encode(s.Prefix(), fields...)
+}
+
+func (p *parser) gsv(talker string) *GSV {
+	s := &GSV{
+		Talker:  talker,
+		NumMsgs: p.atoi(1),
+		MsgNum:  p.atoi(2),
+		NumSats: p.atoi(3),
+	}
+	n := len(p.fields) - 4
+	if n < 0 {
+		p.setErr(fmt.Errorf("too few fields: %d", len(p.fields)))
+		return s
+	}
+	if n%4 == 1 {
+		s.SignalID = p.field(len(p.fields) - 1)
+	}
+	for i := 0; i < n/4; i++ {
+		j := 4 + 4*i
+		s.Sats = append(s.Sats, GSVSat{PRN: p.atoi(j), Elevation: p.atoi(j + 1), Azimuth: p.atoi(j + 2), SNR: p.atoi(j + 3)})
+	}
+	return s
+}
+
+// ZDA contains the UTC date and time and the local time zone.
+type ZDA struct {
+	Talker      string
+	Time        time.Time // UTC date and time
+	ZoneHours   int       // local zone hours
+	ZoneMinutes int       // local zone minutes
+}
+
+// Prefix returns the talker ID and the sentence type.
+func (s *ZDA) Prefix() string { return s.Talker + "ZDA" }
+
+// String returns the encoded sentence.
+func (s *ZDA) String() string {
+	return encode(s.Prefix(), formatTime(s.Time), fmt.Sprintf("%02d", s.Time.Day()), fmt.Sprintf("%02d", s.Time.Month()),
+		fmt.Sprintf("%04d", s.Time.Year()), fmt.Sprintf("%02d", s.ZoneHours), fmt.Sprintf("%02d", s.ZoneMinutes))
+}
+
+func (p *parser) zda(talker string) *ZDA {
+	s := &ZDA{
+		Talker:      talker,
+		Time:        p.timeOfDay(1),
+		ZoneHours:   p.atoi(5),
+		ZoneMinutes: p.atoi(6),
+	}
+	if p.field(4) != "" {
+		s.Time = withDate(s.Time, p.atoi(4), time.Month(p.atoi(3)), p.atoi(2))
+	}
+	return s
+}
@@ -0,0 +1,186 @@
+package ntrip
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Authorizer adds the credentials to the requests of a Client.
+type Authorizer interface {
+	// Authorize adds the credentials to the request, typically the Authorization header.
+	Authorize(req *http.Request) error
+}
+
+// Challenger is implemented by Authorizers that need the challenge of the caster, like DigestAuth.
+type Challenger interface {
+	// Challenge processes the WWW-Authenticate header of an unauthorized response and reports
+	// whether the request should be repeated.
+	Challenge(header string) bool
+}
+
+// BasicAuth is the HTTP Basic authentication.
+type BasicAuth struct {
+	Username, Password string
+}
+
+// Authorize implements Authorizer.
+func (a *BasicAuth) Authorize(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerAuth authenticates with a token, e.g. an OAuth 2.0 access token.
+type BearerAuth struct {
+	Token string
+}
+
+// Authorize implements Authorizer.
+func (a *BearerAuth) Authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// DigestAuth is the HTTP Digest authentication according to RFC 7616 with the algorithms MD5 and SHA-256,
+// and the quality of protection "auth". The first request is sent without credentials to get the challenge,
+// which is reused for the following requests. DigestAuth is safe for concurrent use.
+type DigestAuth struct {
+	Username, Password string
+
+	mu        sync.Mutex
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       bool // qop=auth
+	nc        int  // nonce count
+}
+
+// Challenge implements Challenger.
+func (a *DigestAuth) Challenge(header string) bool {
+	scheme, params := parseChallenge(header)
+	if !strings.EqualFold(scheme, "Digest") {
+		return false
+	}
+	algorithm := params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	if newHash(algorithm) == nil {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if params["nonce"] == a.nonce && !strings.EqualFold(params["stale"], "true") {
+		return false // the credentials were rejected
+	}
+	a.realm, a.nonce, a.opaque, a.algorithm = params["realm"], params["nonce"], params["opaque"], algorithm
+	a.qop = false
+	for _, qop := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			a.qop = true
+		}
+	}
+	a.nc = 0
+	return true
+}
+
+// Authorize implements Authorizer. No credentials are added before the first challenge.
+func (a *DigestAuth) Authorize(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.nonce == "" {
+		return nil
+	}
+
+	h := func(s string) string {
+		hs := newHash(a.algorithm)
+		hs.Write([]byte(s))
+		return hex.EncodeToString(hs.Sum(nil))
+	}
+	uri := req.URL.RequestURI()
+	ha1 := h(a.Username + ":" + a.realm + ":" + a.Password)
+	ha2 := h(req.Method + ":" + uri)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s`,
+		a.Username, a.realm, a.nonce, uri, a.algorithm)
+	if a.qop {
+		a.nc++
+		cnonce, err := newCnonce()
+		if err != nil {
+			return err
+		}
+		nc := fmt.Sprintf("%08x", a.nc)
+		response := h(ha1 + ":" + a.nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+		fmt.Fprintf(&b, `, qop=auth, nc=%s, cnonce="%s", response="%s"`, nc, cnonce, response)
+	} else {
+		fmt.Fprintf(&b, `, response="%s"`, h(ha1+":"+a.nonce+":"+ha2))
+	}
+	if a.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, a.opaque)
+	}
+	req.Header.Set("Authorization", b.String())
+	return nil
+}
+
+// newHash returns the hash of the digest algorithm, nil if not supported.
+func newHash(algorithm string) hash.Hash {
+	switch strings.ToUpper(algorithm) {
+	case "MD5":
+		return md5.New()
+	case "SHA-256":
+		return sha256.New()
+	}
+	return nil
+}
+
+func newCnonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// parseChallenge splits the WWW-Authenticate header into the scheme and its parameters.
+func parseChallenge(header string) (string, map[string]string) {
+	header = strings.TrimSpace(header)
+	i := strings.IndexByte(header, ' ')
+	if i < 0 {
+		return header, nil
+	}
+	scheme, rest := header[:i], header[i+1:]
+	params := make(map[string]string)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.Trim(rest[:eq], " ,"))
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+		var val string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			val, rest = rest[1:end+1], rest[end+1:]
+			rest = strings.TrimPrefix(rest, `"`)
+		} else if end := strings.IndexByte(rest, ','); end >= 0 {
+			val, rest = rest[:end], rest[end:]
+		} else {
+			val, rest = rest, ""
+		}
+		params[key] = strings.TrimSpace(val)
+		rest = strings.TrimLeft(rest, " ,")
+	}
+	return scheme, params
+}
@@ -0,0 +1,118 @@
+package ntrip
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChallenge(t *testing.T) {
+	assert := assert.New(t)
+	scheme, params := parseChallenge(`Digest realm="/TEST00DEU0", qop="auth,auth-int", nonce="dcd98b7102dd2f0e", algorithm=MD5, stale=FALSE, opaque="5ccc"`)
+	assert.Equal("Digest", scheme)
+	assert.Equal(map[string]string{"realm": "/TEST00DEU0", "qop": "auth,auth-int", "nonce": "dcd98b7102dd2f0e",
+		"algorithm": "MD5", "stale": "FALSE", "opaque": "5ccc"}, params)
+
+	scheme, params = parseChallenge(`Basic realm="x, y"`)
+	assert.Equal("Basic", scheme)
+	assert.Equal("x, y", params["realm"])
+
+	scheme, params = parseChallenge("Bearer")
+	assert.Equal("Bearer", scheme)
+	assert.Empty(params)
+}
+
+// digestServer returns a server for the stream TEST00DEU0 with Digest authentication of user:pass.
+// It counts the challenges and stores the posted data.
+func digestServer(t *testing.T, challenges *int, posted *string) *httptest.Server {
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, p := parseChallenge(r.Header.Get("Authorization"))
+		ha1 := md5hex("user:ntrip:pass")
+		ha2 := md5hex(r.Method + ":" + p["uri"])
+		want := md5hex(ha1 + ":" + p["nonce"] + ":" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+		if scheme != "Digest" || p["nonce"] != "n1" || p["opaque"] != "op" || p["response"] != want {
+			*challenges++
+			w.Header().Add("WWW-Authenticate", `Basic realm="ntrip"`)
+			w.Header().Add("WWW-Authenticate", `Digest realm="ntrip", qop="auth", nonce="n1", opaque="op"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == "POST" {
+			b, _ := ioutil.ReadAll(r.Body)
+			*posted = string(b)
+			return
+		}
+		w.Header().Set("Content-Type", "gnss/data")
+		w.Write([]byte("data"))
+	}))
+}
+
+func TestDigestAuth(t *testing.T) {
+	assert := assert.New(t)
+	var challenges int
+	var posted string
+	srv := digestServer(t, &challenges, &posted)
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, Options{Auth: &DigestAuth{Username: "user", Password: "pass"}})
+	assert.NoError(err)
+	for i := 0; i < 2; i++ {
+		r, err := c.GetStream("TEST00DEU0")
+		if assert.NoError(err) {
+			b, _ := ioutil.ReadAll(r)
+			assert.Equal("data", string(b))
+			r.Close()
+		}
+	}
+	assert.Equal(1, challenges, "the challenge is reused")
+
+	c, err = NewClient(srv.URL, Options{Auth: &DigestAuth{Username: "user", Password: "pass"}})
+	assert.NoError(err)
+	assert.NoError(c.PostStream("TEST00DEU0", strings.NewReader("some data")))
+	assert.Equal("some data", posted, "no data lost by the challenge")
+
+	challenges = 0
+	c, err = NewClient(srv.URL, Options{Auth: &DigestAuth{Username: "user", Password: "wrong"}})
+	assert.NoError(err)
+	r, err := c.GetStream("TEST00DEU0")
+	assert.Error(err)
+	r.Close()
+	assert.Equal(2, challenges, "no endless retries")
+
+	a := &DigestAuth{}
+	assert.False(a.Challenge(`Digest realm="x", nonce="n", algorithm=SHA-512-256`), "unsupported algorithm")
+	assert.False(a.Challenge(`Basic realm="x"`))
+}
+
+func TestBearerAuth(t *testing.T) {
+	assert := assert.New(t)
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "gnss/data")
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, Options{Auth: &BearerAuth{Token: "t0k3n"}})
+	assert.NoError(err)
+	r, err := c.GetStream("TEST00DEU0")
+	assert.NoError(err)
+	r.Close()
+	assert.Equal("Bearer t0k3n", auth)
+
+	c.Auth = &BasicAuth{Username: "user", Password: "pass"}
+	r, err = c.Reconnect()
+	assert.NoError(err)
+	r.Close()
+	assert.Equal("Basic dXNlcjpwYXNz", auth)
+}
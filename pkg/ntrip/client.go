@@ -20,10 +20,12 @@ package ntrip
 import (
 	"bufio"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"strconv"
 	"strings"
@@ -40,11 +42,9 @@ type Options struct {
 	// Password is the Caster password
 	Password string
 
-	// Proxy configures the Proxy function on the HTTP client.
-	//Proxy func(req *http.Request) (*url.URL, error)
-	//Proxy string
-
-	//request *http.Request
+	// Proxy is the URL of the HTTP or HTTPS proxy, e.g. "http://proxy.example.com:3128".
+	// If empty, the proxy is taken from the $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY environment variables.
+	Proxy string
 
 	// UserAgent is the http User Agent, defaults to "InfluxDBClient".
 	// For accessing the (BKG) casters websites (not sourcetable), it must not contain "NTRIP",
@@ -54,17 +54,88 @@ type Options struct {
 	// Timeout for GET requests in seconds. Defaults to 5 seconds.
 	Timeout int
 
+	// DialTimeout is the timeout for establishing the TCP connection, defaults to 10 seconds.
+	DialTimeout time.Duration
+
+	// ResponseHeaderTimeout is the time to wait for the response header of the caster, e.g. when using
+	// the wrong proxy. Defaults to 10 seconds.
+	ResponseHeaderTimeout time.Duration
+
 	// UnsafeSSL gets passed to the http client, if true, it will skip https certificate verification.
 	// Defaults to false.
 	UnsafeSSL bool
 
+	// CAFile is a PEM file with the root certificates to verify the caster, e.g. of a private CA.
+	// The system certificates are used if empty.
+	CAFile string
+
+	// CertFile and KeyFile are the PEM files with the client certificate and its key, for casters
+	// that authenticate the clients by certificates.
+	CertFile, KeyFile string
+
 	// TLSConfig allows the user to set their own TLS config for the HTTP
-	// Client. If set, this option overrides UnsafeSSL.
+	// Client. If set, this option overrides UnsafeSSL, CAFile, CertFile and KeyFile.
 	TLSConfig *tls.Config
 
-	// Transfered
-	//DataChan chan []byte
-	//ErrorChan chan error //  errorChan := make(chan error)
+	// Auth is the authentication for streams, e.g. BearerAuth or DigestAuth. If nil, Basic
+	// authentication with Username and Password is used.
+	Auth Authorizer
+}
+
+// tlsConfig returns the TLS configuration of the options.
+func (opts *Options) tlsConfig() (*tls.Config, error) {
+	if opts.TLSConfig != nil {
+		return opts.TLSConfig, nil
+	}
+	conf := &tls.Config{InsecureSkipVerify: opts.UnsafeSSL}
+	if opts.CAFile != "" {
+		data, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		conf.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// transport returns the HTTP transport with the proxy, TLS and timeout settings of the options.
+func (opts *Options) transport() (*http.Transport, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse proxy %s: %v", opts.Proxy, err)
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConf, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	tr.TLSClientConfig = tlsConf
+
+	dialTimeout := 10 * time.Second
+	if opts.DialTimeout != 0 {
+		dialTimeout = opts.DialTimeout
+	}
+	tr.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	tr.ResponseHeaderTimeout = 10 * time.Second
+	if opts.ResponseHeaderTimeout != 0 {
+		tr.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+	return tr, nil
 }
 
 // Client is a caster client for http connections.
@@ -77,6 +148,13 @@ type Client struct {
 	Password  string
 	Useragent string
 
+	// Auth authenticates the requests for streams, Basic authentication with Username and Password if nil.
+	Auth Authorizer
+
+	// GGA is an optional NMEA GGA sentence with the approximate position of the client. It is sent
+	// in the Ntrip-GGA header when requesting a stream, e.g. for VRS or nearest base station selection.
+	GGA string
+
 	req *http.Request // save request for reconnect
 
 	// Quit chan struct{}
@@ -85,40 +163,33 @@ type Client struct {
 }
 
 // NewClient returns a new Ntrip Client with the given caster address and additional options.
-// The caster addr should have the form "http://host:port". It uses HTTP proxies
-// as directed by the $HTTP_PROXY and $NO_PROXY (or $http_proxy and
-// $no_proxy) environment variables.
+// The caster addr should have the form "http://host:port", "https://host:port" or "ntrips://host:port"
+// for casters with TLS. It uses HTTP proxies as set in the options or as directed by the $HTTP_PROXY,
+// $HTTPS_PROXY and $NO_PROXY (or $http_proxy, $https_proxy and $no_proxy) environment variables.
 func NewClient(addr string, opts Options) (*Client, error) {
 	casterURL, err := url.Parse(addr)
 	if err != nil {
 		return nil, err
 	}
-	if casterURL.Scheme != "http" && casterURL.Scheme != "https" {
-		return nil, fmt.Errorf("Unsupported protocol scheme: %s: your address must start with http:// or https://", casterURL.Scheme)
+	switch casterURL.Scheme {
+	case "http", "https":
+	case "ntrip":
+		casterURL.Scheme = "http"
+	case "ntrips":
+		casterURL.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("Unsupported protocol scheme: %s: your address must start with http://, https:// or ntrips://", casterURL.Scheme)
 	}
 
 	if opts.UserAgent == "" {
 		opts.UserAgent = "NTRIP Go Client" // Must start with NTRIP !!!!!!!!!!!!
 	}
 
-	// Transport see http DefaultTransport settings
-	/* 	tr := &http.Transport{
-	Proxy: ProxyFromEnvironment,
-	TLSClientConfig: &tls.Config{InsecureSkipVerify: opts.UnsafeSSL},
-	} */
-
-	// Set proxy
-	/* 	if opts.Proxy != "" {
-		proxyURL, err := url.Parse(opts.Proxy)
-		if err != nil {
-			return nil, fmt.Errorf("Could not parse proxy %s", opts.Proxy)
-		}
-		tr.Proxy = http.ProxyURL(proxyURL)
-	} */
+	tr, err := opts.transport()
+	if err != nil {
+		return nil, err
+	}
 
-	// if opts.TLSConfig != nil {
-	// 	tr.TLSClientConfig = opts.TLSConfig
-	// }
 	var timeout time.Duration = 15 * time.Second // default
 	if opts.Timeout != 0 {
 		timeout = time.Duration(opts.Timeout) * time.Second
@@ -126,13 +197,14 @@ func NewClient(addr string, opts Options) (*Client, error) {
 
 	return &Client{
 		Client: &http.Client{
-			Timeout: timeout,
-			//Transport: tr, // see http DefaultTransport settings
+			Timeout:   timeout,
+			Transport: tr,
 		},
 		URL:       casterURL,
 		Username:  opts.Username,
 		Password:  opts.Password,
 		Useragent: opts.UserAgent,
+		Auth:      opts.Auth,
 	}, nil
 }
 
@@ -278,7 +350,6 @@ func (c *Client) GetStream(mp string) (io.ReadCloser, error) {
 
 	req.Header.Set("User-Agent", c.Useragent)
 	req.Header.Add("Ntrip-Version", "Ntrip/2.0")
-	req.SetBasicAuth(c.Username, c.Password)
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Connection", "close")
 	c.req = req
@@ -286,14 +357,26 @@ func (c *Client) GetStream(mp string) (io.ReadCloser, error) {
 }
 
 func (c *Client) do() (io.ReadCloser, error) {
-	re, _ := httputil.DumpRequest(c.req, false)
-
+	if c.GGA != "" {
+		c.req.Header.Set("Ntrip-GGA", c.GGA)
+	}
 	// Send request
+	if err := c.authorize(c.req); err != nil {
+		return nil, err
+	}
 	resp, err := c.Do(c.req)
 	if err != nil {
-		fmt.Print(string(re)) // if verbose
 		return nil, err
 	}
+	if c.challenge(resp) {
+		resp.Body.Close()
+		if err := c.authorize(c.req); err != nil {
+			return nil, err
+		}
+		if resp, err = c.Do(c.req); err != nil {
+			return nil, err
+		}
+	}
 
 	//respi, _ := httputil.DumpResponse(resp, false)
 	//fmt.Print(string(respi))
@@ -309,11 +392,83 @@ func (c *Client) do() (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
-// Reconnect tries to reconnect to the caster.
+// Reconnect tries to reconnect to the caster. A changed GGA sentence is sent with the new request.
 func (c *Client) Reconnect() (io.ReadCloser, error) {
 	return c.do()
 }
 
+// PostStream uploads a GNSS stream to the mountpoint mp of the NtripCaster, acting as Ntrip 2.0 server.
+// The data is read from r and sent chunked until r returns EOF or an error.
+// With a Challenger as Auth, the request is sent with "Expect: 100-continue", so that it can be
+// repeated after the challenge of the caster without losing data.
+func (c *Client) PostStream(mp string, r io.Reader) error {
+	// The upload runs as long as there is data, so no overall timeout.
+	httpClient := *c.Client
+	httpClient.Timeout = 0
+
+	resp, err := c.post(&httpClient, mp, r)
+	if err != nil {
+		return err
+	}
+	if c.challenge(resp) {
+		resp.Body.Close()
+		if resp, err = c.post(&httpClient, mp, r); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST failed: %d (%s)", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+func (c *Client) post(httpClient *http.Client, mp string, r io.Reader) (*http.Response, error) {
+	streamURL := *c.URL
+	streamURL.Path = mp
+	req, err := http.NewRequest("POST", streamURL.String(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.Useragent)
+	req.Header.Add("Ntrip-Version", "Ntrip/2.0")
+	req.Header.Set("Content-Type", "gnss/data")
+	req.Header.Set("Connection", "close")
+	if _, ok := c.Auth.(Challenger); ok {
+		req.Header.Set("Expect", "100-continue")
+	}
+	req.ContentLength = -1 // chunked
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	return httpClient.Do(req)
+}
+
+// authorize adds the credentials to the request.
+func (c *Client) authorize(req *http.Request) error {
+	if c.Auth != nil {
+		return c.Auth.Authorize(req)
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	return nil
+}
+
+// challenge passes the challenges of an unauthorized response to the Authorizer and reports
+// whether the request should be repeated.
+func (c *Client) challenge(resp *http.Response) bool {
+	ch, ok := c.Auth.(Challenger)
+	if !ok || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		if ch.Challenge(header) {
+			return true
+		}
+	}
+	return false
+}
+
 // Caster specifies a sourcetable record for a caster.
 // See http://software.rtcm-ntrip.org/wiki/CAS.
 type Caster struct {
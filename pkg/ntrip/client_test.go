@@ -1,11 +1,21 @@
 package ntrip
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -120,6 +130,178 @@ func TestPullStream(t *testing.T) {
 	// dec.Decode(&m)
 }
 
+func TestPostStream(t *testing.T) {
+	var got, ctype, version string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.Method != "POST" || r.URL.Path != "/TEST00DEU0" || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ctype, version = r.Header.Get("Content-Type"), r.Header.Get("Ntrip-Version")
+		b, _ := ioutil.ReadAll(r.Body)
+		got = string(b)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, Options{Username: "user", Password: "pass"})
+	assert.NoError(t, err)
+	defer c.CloseIdleConnections()
+
+	err = c.PostStream("TEST00DEU0", strings.NewReader("some data"))
+	assert.NoError(t, err)
+	assert.Equal(t, "some data", got)
+	assert.Equal(t, "gnss/data", ctype)
+	assert.Equal(t, "Ntrip/2.0", version)
+	assert.Equal(t, "", c.URL.Path, "client URL unchanged")
+
+	err = c.PostStream("OTHER", strings.NewReader("some data"))
+	assert.Error(t, err)
+}
+
+func TestGetStream_GGA(t *testing.T) {
+	var ggas []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ggas = append(ggas, r.Header.Get("Ntrip-GGA"))
+		w.Header().Set("Content-Type", "gnss/data")
+		io.WriteString(w, "data")
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, Options{})
+	assert.NoError(t, err)
+	defer c.CloseIdleConnections()
+
+	c.GGA = "$GPGGA,120000.00,5007.0000,N,00840.0000,E,1,12,1.0,100.000,M,0.000,M,,*7C"
+	r, err := c.GetStream("TEST00DEU0")
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(b))
+	r.Close()
+
+	c.GGA = "$GPGGA,120100.00,5007.0000,N,00840.0000,E,1,12,1.0,100.000,M,0.000,M,,*7D"
+	r, err = c.Reconnect()
+	assert.NoError(t, err)
+	r.Close()
+	assert.Equal(t, []string{"$GPGGA,120000.00,5007.0000,N,00840.0000,E,1,12,1.0,100.000,M,0.000,M,,*7C",
+		"$GPGGA,120100.00,5007.0000,N,00840.0000,E,1,12,1.0,100.000,M,0.000,M,,*7D"}, ggas)
+}
+
+// sourcetableHandler answers with an empty sourcetable.
+var sourcetableHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "gnss/sourcetable")
+	io.WriteString(w, "ENDSOURCETABLE\r\n")
+})
+
+// writePEM writes the blocks to a file in dir.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		pem.Encode(&buf, b)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewClient_TLS(t *testing.T) {
+	assert := assert.New(t)
+	var clientCerts int
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+		sourcetableHandler(w, r)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	addr := strings.Replace(srv.URL, "https://", "ntrips://", 1)
+
+	c, err := NewClient(addr, Options{})
+	assert.NoError(err)
+	assert.Equal("https", c.URL.Scheme)
+	_, err = c.GetSourcetable()
+	assert.Error(err, "unknown authority")
+
+	c, err = NewClient(addr, Options{UnsafeSSL: true})
+	assert.NoError(err)
+	_, err = c.GetSourcetable()
+	assert.NoError(err)
+
+	dir, err := ioutil.TempDir("", "ntrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	caFile := writePEM(t, dir, "ca.pem", cert)
+	c, err = NewClient(addr, Options{CAFile: caFile})
+	assert.NoError(err)
+	_, err = c.GetSourcetable()
+	assert.NoError(err)
+	assert.Equal(0, clientCerts)
+
+	// the server certificate serves as client certificate
+	key, err := x509.MarshalPKCS8PrivateKey(srv.TLS.Certificates[0].PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := writePEM(t, dir, "key.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	c, err = NewClient(addr, Options{CAFile: caFile, CertFile: caFile, KeyFile: keyFile})
+	assert.NoError(err)
+	_, err = c.GetSourcetable()
+	assert.NoError(err)
+	assert.Equal(1, clientCerts)
+
+	_, err = NewClient(addr, Options{CAFile: keyFile})
+	assert.Error(err, "no certificates")
+	_, err = NewClient(addr, Options{CertFile: caFile})
+	assert.Error(err, "no key")
+	_, err = NewClient("ftp://localhost", Options{})
+	assert.Error(err)
+}
+
+func TestNewClient_proxy(t *testing.T) {
+	assert := assert.New(t)
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+		sourcetableHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	c, err := NewClient("ntrip://caster.invalid:2101", Options{Proxy: proxy.URL})
+	assert.NoError(err)
+	_, err = c.GetSourcetable()
+	assert.NoError(err)
+	assert.Equal("caster.invalid:2101", host)
+
+	_, err = NewClient("http://localhost:2101", Options{Proxy: "http://[::1"})
+	assert.Error(err)
+}
+
+func TestNewClient_timeouts(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		sourcetableHandler(w, r)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, Options{ResponseHeaderTimeout: 50 * time.Millisecond})
+	assert.NoError(err)
+	_, err = c.GetSourcetable()
+	assert.Error(err)
+
+	c, err = NewClient(srv.URL, Options{})
+	assert.NoError(err)
+	_, err = c.GetSourcetable()
+	assert.NoError(err)
+}
+
 /*
 func TestRawFil(t *testing.T) {
 	r, err := os.Open("testdata/YELL7_171207")
@@ -0,0 +1,287 @@
+// Package qc checks the quality of GNSS observation streams in real time, e.g. of the epochs decoded
+// from an NTRIP stream. The Engine keeps rolling statistics over a time window, like the number of
+// satellites, the mean signal strengths, the rate of cycle slips and the latency, and calls the alert
+// handler when a threshold is breached and when the value is back to normal.
+//
+//	eng := qc.New(qc.Options{Window: 10 * time.Minute, Thresholds: qc.Thresholds{MinSats: 8, MaxLatency: 2 * time.Second}},
+//		func(a qc.Alert) { log.Print(a) })
+//	for dec.NextEpoch() {
+//		eng.Add(dec.Epoch(), time.Now())
+//	}
+package qc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// DefaultWindow is the time window of the statistics if not set in the Options.
+const DefaultWindow = 10 * time.Minute
+
+// Thresholds trigger the alerts. A zero value disables the check.
+type Thresholds struct {
+	MinSats     int           // mean number of satellites per epoch
+	MinSNR      float64       // mean signal strength in dBHz, per system and frequency band
+	MaxSlipRate float64       // percentage of the satellites with a loss of lock of a phase
+	MaxLatency  time.Duration // mean latency of the epochs
+}
+
+// Options configures the Engine.
+type Options struct {
+	Window     time.Duration // the epochs of the window before the last epoch are used, default DefaultWindow
+	Thresholds Thresholds
+
+	// LeapSeconds is the number of GPS-UTC leap seconds, 0 means the value of the gnsstime leap second table.
+	// It is needed for the latency, as the epochs are in GPS time.
+	LeapSeconds int
+}
+
+// SignalSNR is the mean signal strength of a frequency band.
+type SignalSNR struct {
+	Sys   gnss.System `json:"-"`
+	Band  string      `json:"band"` // system abbreviation and band, e.g. G1 or E5
+	Mean  float64     `json:"mean"` // in dBHz
+	Count int         `json:"count"`
+}
+
+// Stats are the statistics of the epochs in the window.
+type Stats struct {
+	Start       time.Time   `json:"start"` // first epoch in the window
+	End         time.Time   `json:"end"`   // last epoch
+	Epochs      int         `json:"epochs"`
+	MeanSats    float64     `json:"meanSats"`
+	MinSats     int         `json:"minSats"`
+	SNR         []SignalSNR `json:"snr"`
+	SlipRate    float64     `json:"slipRate"`    // percentage of the satellites with a loss of lock of a phase
+	MeanLatency float64     `json:"meanLatency"` // in seconds, 0 if no reception times are known
+	MaxLatency  float64     `json:"maxLatency"`  // in seconds
+}
+
+// AlertType is the checked value of an alert.
+type AlertType int
+
+// The alert types.
+const (
+	AlertSats AlertType = iota
+	AlertSNR
+	AlertSlips
+	AlertLatency
+)
+
+func (t AlertType) String() string {
+	switch t {
+	case AlertSats:
+		return "satellites"
+	case AlertSNR:
+		return "SNR"
+	case AlertSlips:
+		return "slip rate"
+	case AlertLatency:
+		return "latency"
+	}
+	return fmt.Sprintf("AlertType(%d)", int(t))
+}
+
+// Alert reports a breached threshold, or with Resolved that the value is back within the threshold.
+type Alert struct {
+	Time      time.Time // epoch
+	Type      AlertType
+	Band      string // for SNR alerts, e.g. G2
+	Value     float64
+	Threshold float64
+	Resolved  bool
+}
+
+func (a Alert) String() string {
+	name := a.Type.String()
+	if a.Band != "" {
+		name += " " + a.Band
+	}
+	state := "breached"
+	if a.Resolved {
+		state = "resolved"
+	}
+	return fmt.Sprintf("%s: %s %s: %.2f, threshold %.2f", a.Time.Format(time.RFC3339), name, state, a.Value, a.Threshold)
+}
+
+// epochSummary holds the counts of an epoch in the window.
+type epochSummary struct {
+	time     time.Time
+	sats     int
+	slips    int
+	latency  float64
+	received bool
+	snr      map[string]snrSum
+}
+
+type snrSum struct {
+	sys   gnss.System
+	sum   float64
+	count int
+}
+
+// Engine collects the rolling statistics of a stream of epochs. It is safe for concurrent use.
+type Engine struct {
+	opts    Options
+	onAlert func(Alert)
+
+	mu       sync.Mutex
+	epochs   []epochSummary // in the window, in time order
+	breached map[string]bool
+}
+
+// New returns a new engine. onAlert, which may be nil, is called by Add for each breached and resolved threshold.
+func New(opts Options, onAlert func(Alert)) *Engine {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	return &Engine{opts: opts, onAlert: onAlert, breached: make(map[string]bool)}
+}
+
+// Add adds the epoch received at the given time, zero if unknown, checks the thresholds and calls the
+// alert handler. Event epochs and epochs older than the last one are skipped.
+func (e *Engine) Add(epo *rinex.Epoch, received time.Time) {
+	if epo.IsEvent() || epo.IsCycleSlip() {
+		return
+	}
+	sum := epochSummary{time: epo.Time, sats: len(epo.ObsList), snr: make(map[string]snrSum)}
+	if !received.IsZero() {
+		leap := e.opts.LeapSeconds
+		if leap == 0 {
+			leap = gnsstime.LeapSecondsGPS(received)
+		}
+		sum.received = true
+		sum.latency = received.Sub(epo.Time.Add(-time.Duration(leap) * time.Second)).Seconds()
+	}
+	for _, satObs := range epo.ObsList {
+		slip := false
+		bands := make(map[string]bool, 3)
+		for i, typ := range satObs.Types {
+			if len(typ) != 3 || i >= len(satObs.Obss) {
+				continue
+			}
+			if typ[0] == 'L' && satObs.Obss[i].LLI&1 != 0 {
+				slip = true
+			}
+			bands[typ[1:2]] = true
+		}
+		if slip {
+			sum.slips++
+		}
+		for band := range bands {
+			if snr, ok := satObs.SNRdBHz(band); ok && snr > 0 {
+				key := satObs.Prn.Sys.Abbr() + band
+				s := sum.snr[key]
+				s.sys, s.sum, s.count = satObs.Prn.Sys, s.sum+snr, s.count+1
+				sum.snr[key] = s
+			}
+		}
+	}
+
+	e.mu.Lock()
+	if n := len(e.epochs); n > 0 && !epo.Time.After(e.epochs[n-1].time) {
+		e.mu.Unlock()
+		return
+	}
+	e.epochs = append(e.epochs, sum)
+	start := epo.Time.Add(-e.opts.Window)
+	i := sort.Search(len(e.epochs), func(i int) bool { return e.epochs[i].time.After(start) })
+	e.epochs = append(e.epochs[:0], e.epochs[i:]...)
+	alerts := e.check(e.stats())
+	e.mu.Unlock()
+
+	if e.onAlert != nil {
+		for _, a := range alerts {
+			e.onAlert(a)
+		}
+	}
+}
+
+// Stats returns the statistics of the current window.
+func (e *Engine) Stats() Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats()
+}
+
+func (e *Engine) stats() Stats {
+	var st Stats
+	if len(e.epochs) == 0 {
+		return st
+	}
+	st.Start, st.End, st.Epochs = e.epochs[0].time, e.epochs[len(e.epochs)-1].time, len(e.epochs)
+	st.MinSats = e.epochs[0].sats
+	sats, slips, nLatency := 0, 0, 0
+	snr := make(map[string]snrSum)
+	for _, sum := range e.epochs {
+		sats += sum.sats
+		slips += sum.slips
+		if sum.sats < st.MinSats {
+			st.MinSats = sum.sats
+		}
+		if sum.received {
+			nLatency++
+			st.MeanLatency += sum.latency
+			if nLatency == 1 || sum.latency > st.MaxLatency {
+				st.MaxLatency = sum.latency
+			}
+		}
+		for key, s := range sum.snr {
+			tot := snr[key]
+			tot.sys, tot.sum, tot.count = s.sys, tot.sum+s.sum, tot.count+s.count
+			snr[key] = tot
+		}
+	}
+	st.MeanSats = float64(sats) / float64(st.Epochs)
+	if sats > 0 {
+		st.SlipRate = float64(slips) / float64(sats) * 100
+	}
+	if nLatency > 0 {
+		st.MeanLatency /= float64(nLatency)
+	}
+	for key, s := range snr {
+		st.SNR = append(st.SNR, SignalSNR{Sys: s.sys, Band: key, Mean: s.sum / float64(s.count), Count: s.count})
+	}
+	sort.Slice(st.SNR, func(i, j int) bool {
+		if st.SNR[i].Sys != st.SNR[j].Sys {
+			return st.SNR[i].Sys < st.SNR[j].Sys
+		}
+		return st.SNR[i].Band < st.SNR[j].Band
+	})
+	return st
+}
+
+// check returns the alerts for the thresholds whose state changed.
+func (e *Engine) check(st Stats) []Alert {
+	th := e.opts.Thresholds
+	var alerts []Alert
+	update := func(typ AlertType, band string, value, threshold float64, breached bool) {
+		key := typ.String() + band
+		if breached == e.breached[key] {
+			return
+		}
+		e.breached[key] = breached
+		alerts = append(alerts, Alert{Time: st.End, Type: typ, Band: band, Value: value, Threshold: threshold, Resolved: !breached})
+	}
+	if th.MinSats > 0 {
+		update(AlertSats, "", st.MeanSats, float64(th.MinSats), st.MeanSats < float64(th.MinSats))
+	}
+	if th.MinSNR > 0 {
+		for _, s := range st.SNR {
+			update(AlertSNR, s.Band, s.Mean, th.MinSNR, s.Mean < th.MinSNR)
+		}
+	}
+	if th.MaxSlipRate > 0 {
+		update(AlertSlips, "", st.SlipRate, th.MaxSlipRate, st.SlipRate > th.MaxSlipRate)
+	}
+	if th.MaxLatency > 0 && st.MeanLatency != 0 {
+		update(AlertLatency, "", st.MeanLatency, th.MaxLatency.Seconds(), st.MeanLatency > th.MaxLatency.Seconds())
+	}
+	return alerts
+}
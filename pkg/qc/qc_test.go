@@ -0,0 +1,85 @@
+package qc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+// epoch returns an epoch of numSats GPS satellites with the S1C value, the first numSlips with a loss of lock.
+func epoch(t time.Time, numSats, numSlips int, snr float64) *rinex.Epoch {
+	epo := &rinex.Epoch{Time: t}
+	for i := 0; i < numSats; i++ {
+		lli := int8(0)
+		if i < numSlips {
+			lli = 1
+		}
+		epo.ObsList = append(epo.ObsList, rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGPS, Num: int8(i + 1)},
+			map[string]rinex.Obs{"C1C": {Val: 2e7}, "L1C": {Val: 1e8, LLI: lli}, "S1C": {Val: snr}}))
+	}
+	return epo
+}
+
+func TestEngine(t *testing.T) {
+	assert := assert.New(t)
+	var alerts []Alert
+	eng := New(Options{Window: time.Minute, LeapSeconds: 18,
+		Thresholds: Thresholds{MinSats: 6, MinSNR: 35, MaxSlipRate: 10, MaxLatency: 2 * time.Second}},
+		func(a Alert) { alerts = append(alerts, a) })
+
+	start := time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC) // GPS time
+	recv := func(t time.Time, delay time.Duration) time.Time { return t.Add(-18*time.Second + delay) }
+	for i := 0; i < 6; i++ {
+		t := start.Add(time.Duration(i) * 10 * time.Second)
+		eng.Add(epoch(t, 8, 0, 45), recv(t, time.Second))
+	}
+	assert.Empty(alerts)
+	st := eng.Stats()
+	assert.Equal(6, st.Epochs)
+	assert.Equal(8.0, st.MeanSats)
+	assert.InDelta(1, st.MeanLatency, 1e-9)
+	assert.Equal([]SignalSNR{{Sys: gnss.SysGPS, Band: "G1", Mean: 45, Count: 48}}, st.SNR)
+
+	// degraded epochs push the old ones out of the window
+	for i := 6; i < 12; i++ {
+		t := start.Add(time.Duration(i) * 10 * time.Second)
+		eng.Add(epoch(t, 4, 1, 30), recv(t, 5*time.Second))
+	}
+	st = eng.Stats()
+	assert.Equal(6, st.Epochs, "one minute window")
+	assert.Equal(start.Add(60*time.Second), st.Start)
+	assert.Equal(4, st.MinSats)
+	assert.InDelta(25.0, st.SlipRate, 0.01)
+	types := make(map[AlertType]bool)
+	for _, a := range alerts {
+		assert.False(a.Resolved)
+		types[a.Type] = true
+	}
+	assert.Len(alerts, 4, "%v", alerts)
+	assert.Len(types, 4)
+
+	// back to normal, each alert is resolved once
+	alerts = nil
+	for i := 12; i < 20; i++ {
+		t := start.Add(time.Duration(i) * 10 * time.Second)
+		eng.Add(epoch(t, 8, 0, 45), recv(t, time.Second))
+	}
+	assert.Len(alerts, 4)
+	for _, a := range alerts {
+		assert.True(a.Resolved, a.String())
+	}
+
+	// old and event epochs are ignored
+	n := eng.Stats().Epochs
+	eng.Add(epoch(start, 8, 0, 45), time.Time{})
+	eng.Add(&rinex.Epoch{Time: start.Add(time.Hour), Flag: rinex.EpochFlagExternalEvent}, time.Time{})
+	assert.Equal(n, eng.Stats().Epochs)
+}
+
+func TestAlert_String(t *testing.T) {
+	a := Alert{Time: time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC), Type: AlertSNR, Band: "G2", Value: 30.5, Threshold: 35}
+	assert.Equal(t, "2020-06-18T12:00:00Z: SNR G2 breached: 30.50, threshold 35.00", a.String())
+}
@@ -0,0 +1,216 @@
+package rinex
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// AvailabilityCell is the number of observations of a signal in an hour.
+type AvailabilityCell struct {
+	Count    int     `json:"count"`
+	Expected int     `json:"expected"`
+	Percent  float64 `json:"percent"` // 0 if none expected
+}
+
+// AvailabilityRow contains the hourly observation counts of an observation type.
+type AvailabilityRow struct {
+	Sys     gnss.System        `json:"-"`
+	System  string             `json:"system"`  // abbreviation, e.g. G
+	ObsType string             `json:"obsType"` // e.g. L2W
+	Hours   []AvailabilityCell `json:"hours"`   // in the order of Availability.Hours
+}
+
+// Availability is the matrix of the observation counts per system and observation type and hour, e.g. to
+// spot partial outages and tracking problems of single signals in daily files.
+//
+// The expected count of an hour is the number of satellite epochs of the system in the hour, extrapolated
+// to the epochs expected in the hour according to the sampling interval. Hours without any epoch between
+// the first and the last epoch use the mean number of satellites per epoch of the file.
+type Availability struct {
+	Interval float64           `json:"interval"` // sampling interval in seconds
+	Hours    []time.Time       `json:"hours"`    // the start of the hours
+	Rows     []AvailabilityRow `json:"rows"`
+}
+
+// NewAvailability reads the epochs of the decoder and returns the hourly observation counts.
+// Event epochs are skipped, blank observations are not counted.
+func NewAvailability(dec *ObsDecoder) (*Availability, error) {
+	type signal struct {
+		sys gnss.System
+		typ string
+	}
+	type hourCount struct {
+		epochs    int
+		satEpochs map[gnss.System]int
+		obs       map[signal]int
+	}
+	hours := make(map[time.Time]*hourCount)
+	totalSatEpochs := make(map[gnss.System]int)
+	found := make(map[signal]bool)
+	var times []time.Time
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if epo.IsEvent() || epo.IsCycleSlip() {
+			continue
+		}
+		times = append(times, epo.Time)
+		h := epo.Time.Truncate(time.Hour)
+		hc, ok := hours[h]
+		if !ok {
+			hc = &hourCount{satEpochs: make(map[gnss.System]int), obs: make(map[signal]int)}
+			hours[h] = hc
+		}
+		hc.epochs++
+		for _, satObs := range epo.ObsList {
+			sys := satObs.Prn.Sys
+			hc.satEpochs[sys]++
+			totalSatEpochs[sys]++
+			for i, obs := range satObs.Obss {
+				if obs.Val == 0 || i >= len(satObs.Types) {
+					continue
+				}
+				sig := signal{sys: sys, typ: satObs.Types[i]}
+				hc.obs[sig]++
+				found[sig] = true
+			}
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read epochs: %w", err)
+	}
+	av := &Availability{Hours: []time.Time{}, Rows: []AvailabilityRow{}}
+	if len(times) == 0 {
+		return av, nil
+	}
+
+	gaps := Gaps(times, time.Duration(dec.Header.Interval*float64(time.Second)))
+	av.Interval = gaps.Interval.Seconds()
+	for h := gaps.Start.Truncate(time.Hour); !h.After(gaps.End); h = h.Add(time.Hour) {
+		av.Hours = append(av.Hours, h)
+	}
+
+	// the rows in the order of the header's observation types, other types found appended
+	systems := make([]gnss.System, 0, len(totalSatEpochs))
+	for sys := range totalSatEpochs {
+		systems = append(systems, sys)
+	}
+	sort.Slice(systems, func(i, j int) bool { return systems[i] < systems[j] })
+	var sigs []signal
+	for _, sys := range systems {
+		types := dec.Header.ObsTypes[sys]
+		var extra []string
+		for sig := range found {
+			if sig.sys == sys && indexOf(types, sig.typ) < 0 {
+				extra = append(extra, sig.typ)
+			}
+		}
+		sort.Strings(extra)
+		for _, typ := range append(append([]string(nil), types...), extra...) {
+			sigs = append(sigs, signal{sys: sys, typ: typ})
+		}
+	}
+
+	for _, sig := range sigs {
+		row := AvailabilityRow{Sys: sig.sys, System: sig.sys.Abbr(), ObsType: sig.typ, Hours: make([]AvailabilityCell, len(av.Hours))}
+		for i, h := range av.Hours {
+			expEpochs := expectedEpochs(h, gaps)
+			cell := &row.Hours[i]
+			if hc, ok := hours[h]; ok {
+				cell.Count = hc.obs[sig]
+				if expEpochs < hc.epochs {
+					expEpochs = hc.epochs
+				}
+				cell.Expected = int(math.Round(float64(hc.satEpochs[sig.sys]) * float64(expEpochs) / float64(hc.epochs)))
+			} else {
+				cell.Expected = int(math.Round(float64(totalSatEpochs[sig.sys]) * float64(expEpochs) / float64(gaps.NumEpochs)))
+			}
+			if cell.Expected > 0 {
+				cell.Percent = float64(cell.Count) / float64(cell.Expected) * 100
+			}
+		}
+		av.Rows = append(av.Rows, row)
+	}
+	return av, nil
+}
+
+// expectedEpochs returns the number of epochs expected in the hour starting at h, within the first and
+// last epoch of the gap report.
+func expectedEpochs(h time.Time, gaps GapReport) int {
+	if gaps.Interval <= 0 {
+		return 0
+	}
+	start, end := h, h.Add(time.Hour)
+	if start.Before(gaps.Start) {
+		start = gaps.Start
+	}
+	if last := gaps.End.Add(gaps.Interval); end.After(last) {
+		end = last
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int(math.Ceil(float64(end.Sub(start)) / float64(gaps.Interval)))
+}
+
+// Write writes the matrix in the given format. The text and HTML formats show the percentages of the
+// expected observations.
+func (av *Availability) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(av)
+	case ReportHTML:
+		return availabilityHTMLTmpl.Execute(w, av)
+	case ReportText:
+		bw := bufio.NewWriter(w)
+		fmt.Fprintf(bw, "%-8s", "Signal")
+		for _, h := range av.Hours {
+			fmt.Fprintf(bw, " %5s", h.Format("15h"))
+		}
+		fmt.Fprintln(bw)
+		for _, row := range av.Rows {
+			fmt.Fprintf(bw, "%-8s", row.System+" "+row.ObsType)
+			for _, cell := range row.Hours {
+				if cell.Expected == 0 {
+					fmt.Fprintf(bw, " %5s", "-")
+					continue
+				}
+				fmt.Fprintf(bw, " %5.1f", cell.Percent)
+			}
+			fmt.Fprintln(bw)
+		}
+		return bw.Flush()
+	}
+	return fmt.Errorf("invalid report format: %d", format)
+}
+
+var availabilityHTMLTmpl = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Hourly availability</title>
+<style>
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 2px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Hourly availability</h1>
+<table>
+<tr><th>Signal</th>{{range .Hours}}<th title="{{time .}}">{{.Format "15h"}}</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{.System}} {{.ObsType}}</td>{{range .Hours}}<td title="{{.Count}} of {{.Expected}}">{{if .Expected}}{{printf "%.1f" .Percent}}{{else}}-{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,63 @@
+package rinex
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAvailability(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+
+	// hour 1 missing, half of hour 2 missing, no L2W of G02 in hour 3
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, ObsHeader{RINEXVersion: 3.04, RINEXType: "O", SatSystem: gnss.SysGPS, MarkerName: "SIMU",
+		Interval: 30, ObsTypes: map[gnss.System][]string{gnss.SysGPS: {"C1C", "L1C", "L2W"}}})
+	for i := 0; i < 4*120; i++ {
+		epoTime := start.Add(time.Duration(i) * 30 * time.Second)
+		if h := i / 120; h == 1 || h == 2 && i%120 < 60 {
+			continue
+		}
+		epo := &Epoch{Time: epoTime}
+		for _, num := range []int8{1, 2} {
+			obs := map[string]Obs{"C1C": {Val: 2e7}, "L1C": {Val: 1e8}, "L2W": {Val: 8e7}}
+			if num == 2 && i >= 360 {
+				delete(obs, "L2W")
+			}
+			epo.ObsList = append(epo.ObsList, NewSatObs(PRN{Sys: gnss.SysGPS, Num: num}, obs))
+		}
+		assert.NoError(enc.Encode(epo))
+	}
+	assert.NoError(enc.Flush())
+
+	dec, err := NewObsDecoder(&buf)
+	assert.NoError(err)
+	av, err := NewAvailability(dec)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(30.0, av.Interval)
+	assert.Equal([]time.Time{start, start.Add(time.Hour), start.Add(2 * time.Hour), start.Add(3 * time.Hour)}, av.Hours)
+	if !assert.Len(av.Rows, 3) {
+		return
+	}
+	assert.Equal("C1C", av.Rows[0].ObsType)
+	assert.Equal([]AvailabilityCell{{240, 240, 100}, {0, 240, 0}, {120, 240, 50}, {240, 240, 100}}, av.Rows[0].Hours)
+	assert.Equal("L2W", av.Rows[2].ObsType)
+	assert.Equal(AvailabilityCell{120, 240, 50}, av.Rows[2].Hours[3])
+
+	buf.Reset()
+	assert.NoError(av.Write(&buf, ReportText))
+	assert.Equal(`Signal     00h   01h   02h   03h
+G C1C    100.0   0.0  50.0 100.0
+G L1C    100.0   0.0  50.0 100.0
+G L2W    100.0   0.0  50.0  50.0
+`, buf.String())
+	buf.Reset()
+	assert.NoError(av.Write(&buf, ReportHTML))
+	assert.Contains(buf.String(), `<td title="120 of 240">50.0</td>`)
+}
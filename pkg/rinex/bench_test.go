@@ -0,0 +1,216 @@
+package rinex
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// benchFiles are representative observation files, generated by repeating the first epoch of benchSource.
+var benchFiles = []struct {
+	name     string
+	interval time.Duration
+	epochs   int
+}{
+	{"30s_daily", 30 * time.Second, 2880},
+	{"1Hz_hourly", time.Second, 3600},
+	{"20Hz_15min", 50 * time.Millisecond, 18000},
+}
+
+const benchSource = "testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx"
+
+// genObsData returns an observation file with the given number of epochs and sampling interval.
+func genObsData(tb testing.TB, interval time.Duration, epochs int) []byte {
+	tb.Helper()
+	data, err := ioutil.ReadAll(newRepeatReader(tb, benchSource, interval, epochs))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+// genObsFile writes an observation file with the given number of epochs and sampling interval into dir.
+func genObsFile(tb testing.TB, dir, name string, interval time.Duration, epochs int) string {
+	tb.Helper()
+	path := filepath.Join(dir, name+".rnx")
+	if err := ioutil.WriteFile(path, genObsData(tb, interval, epochs), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestGenObsData(t *testing.T) {
+	assert := assert.New(t)
+	dec, err := NewObsDecoder(bytes.NewReader(genObsData(t, 50*time.Millisecond, 3)))
+	if !assert.NoError(err) {
+		return
+	}
+	var times []time.Time
+	for dec.NextEpoch() {
+		times = append(times, dec.Epoch().Time)
+		assert.NotEmpty(dec.Epoch().ObsList)
+	}
+	assert.NoError(dec.Err())
+	t0 := time.Date(2019, 9, 27, 0, 0, 0, 0, time.UTC)
+	assert.Equal([]time.Time{t0, t0.Add(50 * time.Millisecond), t0.Add(100 * time.Millisecond)}, times)
+}
+
+func BenchmarkObsDecoder_header(b *testing.B) {
+	data := genObsData(b, 30*time.Second, 1)
+	b.ReportAllocs()
+	b.SetBytes(int64(bytes.Index(data, []byte("END OF HEADER"))))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewObsDecoder(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkObsDecoder_decode(b *testing.B) {
+	for _, bf := range benchFiles {
+		bf := bf
+		b.Run(bf.name, func(b *testing.B) {
+			data := genObsData(b, bf.interval, bf.epochs)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				dec, err := NewObsDecoder(bytes.NewReader(data))
+				if err != nil {
+					b.Fatal(err)
+				}
+				n := 0
+				for dec.NextEpoch() {
+					n++
+				}
+				if err := dec.Err(); err != nil {
+					b.Fatal(err)
+				}
+				if n != bf.epochs {
+					b.Fatalf("got %d epochs", n)
+				}
+			}
+		})
+	}
+}
+
+// Decode a file from disk, read with bufio and memory-mapped.
+func BenchmarkObsDecoder_file(b *testing.B) {
+	path := genObsFile(b, b.TempDir(), "1Hz_hourly", time.Second, 3600)
+	for _, mapped := range []bool{false, true} {
+		name := "bufio"
+		if mapped {
+			name = "mmap"
+		}
+		mapped := mapped
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var f io.ReadCloser
+				var err error
+				if mapped {
+					f, err = OpenMapped(path)
+				} else {
+					f, err = OpenFile(path)
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				dec, err := NewObsDecoder(f)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for dec.NextEpoch() {
+				}
+				f.Close()
+				if err := dec.Err(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkObsFile_Stat(b *testing.B) {
+	dir := b.TempDir()
+	for _, bf := range benchFiles {
+		bf := bf
+		b.Run(bf.name, func(b *testing.B) {
+			obsFil := &ObsFile{RnxFil: &RnxFil{Path: genObsFile(b, dir, bf.name, bf.interval, bf.epochs)}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				stat, err := obsFil.Stat()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if stat.NumEpochs != bf.epochs {
+					b.Fatalf("got %d epochs", stat.NumEpochs)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkObsFile_Differences(b *testing.B) {
+	dir := b.TempDir()
+	for _, bf := range benchFiles[:2] {
+		bf := bf
+		b.Run(bf.name, func(b *testing.B) {
+			path := genObsFile(b, dir, bf.name, bf.interval, bf.epochs)
+			f1 := &ObsFile{RnxFil: &RnxFil{Path: path}}
+			f2 := &ObsFile{RnxFil: &RnxFil{Path: path}}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				diffs, err := f1.Differences(f2)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(diffs) > 0 {
+					b.Fatalf("got %d differences", len(diffs))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkObsEncoder(b *testing.B) {
+	for _, bf := range benchFiles {
+		bf := bf
+		b.Run(bf.name, func(b *testing.B) {
+			data := genObsData(b, bf.interval, bf.epochs)
+			dec, err := NewObsDecoder(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+			var epochs []*Epoch
+			for dec.NextEpoch() {
+				epochs = append(epochs, dec.Epoch())
+			}
+			if err := dec.Err(); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				enc := NewObsEncoder(ioutil.Discard, dec.Header)
+				for _, epo := range epochs {
+					if err := enc.Encode(epo); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := enc.Flush(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
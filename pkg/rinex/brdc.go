@@ -0,0 +1,158 @@
+package rinex
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// brdcLead is the time before the day that is searched for ephemerides of the day. They are transmitted
+// up to two hours before their clock reference epoch.
+const brdcLead = 2 * time.Hour
+
+// BRDCBuilder builds the daily broadcast ephemeris file of all satellite systems, like
+// BRDC00WRD_S_20201690000_01D_MN.rnx, from the navigation files of several stations or hours with MergeNav.
+// The file contains the ephemerides with a clock reference epoch on the day.
+//
+//	b := &rinex.BRDCBuilder{Name: "BRDC00WRD", DataSource: "S", RunBy: "BKG"}
+//	path, n, err := b.BuildDir("/data/nav/hourly", "/data/nav/daily", time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC))
+type BRDCBuilder struct {
+	Name       string // the nine char ID of the file, e.g. BRDC00WRD
+	DataSource string // R for receiver, S for stream or U for unknown, the default
+	RunBy      string // agency of the PGM / RUN BY / DATE record
+
+	// HealthyOnly and Best are passed to MergeNav.
+	HealthyOnly bool
+	Best        bool
+}
+
+// Filename returns the RINEX 3 filename of the daily file, e.g. BRDC00WRD_S_20201690000_01D_MN.rnx.
+func (b *BRDCBuilder) Filename(day time.Time) (string, error) {
+	if len(b.Name) != 9 {
+		return "", fmt.Errorf("invalid name %q: must have 9 chars", b.Name)
+	}
+	f := &NavFile{RnxFil: &RnxFil{DataSource: b.DataSource, StartTime: day.UTC().Truncate(24 * time.Hour),
+		FilePeriod: "01D", DataType: "MN"}}
+	if err := f.SetStationName(b.Name); err != nil {
+		return "", err
+	}
+	return f.Rnx3Filename()
+}
+
+// Build merges the ephemerides of the decoders with a clock reference epoch on the day and writes the
+// daily file to w. The header gets the program of the DefaultHistory and the RunBy agency, the records
+// describing a single station, like comments and the receiver, are removed.
+// It returns the number of written ephemerides.
+func (b *BRDCBuilder) Build(w io.Writer, day time.Time, decs ...*NavDecoder) (int, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	opts := NavMergeOptions{Start: start, End: start.Add(24 * time.Hour), HealthyOnly: b.HealthyOnly, Best: b.Best}
+	hdr, recs, err := mergeNav(opts, decs)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	hdr.Pgm, hdr.RunBy, hdr.Date = DefaultHistory.Program, b.RunBy, now.UTC().Format("20060102 150405 UTC")
+	hdr.ReceiverNumber, hdr.ReceiverType, hdr.ReceiverVersion = "", "", ""
+	hdr.DOI, hdr.Licenses, hdr.StationInfos = "", nil, nil
+	hdr.Comments = DefaultHistory.Comments("brdc", nil, now, "input files: "+strconv.Itoa(len(decs)))
+	return len(recs), writeNav(w, &hdr, recs)
+}
+
+// BuildDir builds the daily file of the navigation files in dir and writes it to outDir. All files with
+// a RINEX 3 name of a navigation data type and a period overlapping the day are read, other files are
+// ignored. The file is written to a temporary file first and renamed when complete.
+// It returns the path of the daily file and the number of its ephemerides.
+func (b *BRDCBuilder) BuildDir(dir, outDir string, day time.Time) (string, int, error) {
+	name, err := b.Filename(day)
+	if err != nil {
+		return "", 0, err
+	}
+	paths, err := dailyNavFiles(dir, day, name)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(paths) == 0 {
+		return "", 0, fmt.Errorf("no navigation files for %s in %s", day.Format("2006-01-02"), dir)
+	}
+
+	var decs []*NavDecoder
+	for _, path := range paths {
+		r, err := OpenFile(path)
+		if err != nil {
+			return "", 0, err
+		}
+		defer r.Close()
+		dec, err := NewNavDecoder(r)
+		if err != nil {
+			return "", 0, fmt.Errorf("%s: %w", path, err)
+		}
+		dec.Name = filepath.Base(path)
+		decs = append(decs, dec)
+	}
+
+	path := filepath.Join(outDir, name)
+	tmp, err := ioutil.TempFile(outDir, name+".*")
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := b.Build(tmp, day, decs...)
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, err
+	}
+	return path, n, nil
+}
+
+// dailyNavFiles returns the paths of the navigation files in dir that overlap the day, sorted by name.
+// The file skip is ignored, e.g. the daily file itself.
+func dailyNavFiles(dir string, day time.Time, skip string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	start := day.UTC().Truncate(24 * time.Hour)
+	from, to := start.Add(-brdcLead), start.Add(24*time.Hour)
+	var paths []string
+	for _, info := range infos {
+		if info.IsDir() || info.Name() == skip {
+			continue
+		}
+		fi, err := ParseFilename(info.Name())
+		if err != nil || fi.NamingVersion != 3 || !(&RnxFil{DataType: fi.DataType}).IsNavType() {
+			continue
+		}
+		period, err := parsePeriodCode(fi.FilePeriod)
+		if err != nil {
+			continue
+		}
+		if fi.StartTime.Add(period).After(from) && fi.StartTime.Before(to) {
+			paths = append(paths, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// parsePeriodCode parses the RINEX 3 filename code of the file period, e.g. 15M or 01D, see periodCode.
+func parsePeriodCode(code string) (time.Duration, error) {
+	units := map[byte]time.Duration{'M': time.Minute, 'H': time.Hour, 'D': 24 * time.Hour, 'Y': 365 * 24 * time.Hour}
+	if len(code) == 3 {
+		if unit, ok := units[code[2]]; ok {
+			if n, err := strconv.Atoi(code[:2]); err == nil && n > 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid file period: %q", code)
+}
@@ -0,0 +1,78 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBRDCBuilder_Filename(t *testing.T) {
+	assert := assert.New(t)
+	b := &BRDCBuilder{Name: "BRDC00WRD", DataSource: "S"}
+	name, err := b.Filename(time.Date(2020, 6, 17, 13, 0, 0, 0, time.UTC))
+	assert.NoError(err)
+	assert.Equal("BRDC00WRD_S_20201690000_01D_MN.rnx", name)
+
+	b = &BRDCBuilder{Name: "BRDC"}
+	_, err = b.Filename(time.Now())
+	assert.Error(err)
+}
+
+func TestBRDCBuilder_BuildDir(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "brdc")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	newIOD := strings.Replace(navDataG20, "     8.300000000000E+01 2.078125000000E+01", "     8.400000000000E+01 2.078125000000E+01", 1)
+	newIOD = strings.Replace(newIOD, "G20 2020 06 18 00 00 00", "G20 2020 06 18 02 00 00", 1)
+	files := map[string]string{
+		"WTZR00DEU_R_20201700000_01H_GN.rnx":     navDataG20 + newIOD[strings.Index(newIOD, "G20"):],
+		"BRUX00BEL_R_20201692300_01H_GN.rnx":     navDataG20, // the ephemerides of the day are transmitted before
+		"BRUX00BEL_R_20201692000_01H_GN.rnx":     "not read",
+		"BRUX00BEL_R_20201700000_01H_30S_MO.rnx": "not read",
+		"README":                                 "not read",
+	}
+	for name, data := range files {
+		assert.NoError(ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+
+	b := &BRDCBuilder{Name: "BRDC00WRD", DataSource: "S", RunBy: "BKG"}
+	path, n, err := b.BuildDir(dir, dir, time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.Equal(filepath.Join(dir, "BRDC00WRD_S_20201700000_01D_MN.rnx"), path)
+	assert.Equal(2, n)
+	infos, err := ioutil.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(infos, len(files)+1, "no temporary files")
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+	dec, err := NewNavDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	assert.Equal(gnss.SysGPS, dec.Header.SatSystem)
+	assert.Equal("gognss", dec.Header.Pgm)
+	assert.Equal("BKG", dec.Header.RunBy)
+	assert.Equal([]string{dec.Header.Comments[0], "input files: 2"}, dec.Header.Comments)
+	nEph := 0
+	for dec.NextEphemeris() {
+		nEph++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(2, nEph)
+
+	// the following day has no files
+	_, _, err = b.BuildDir(dir, dir, time.Date(2020, 6, 20, 0, 0, 0, 0, time.UTC))
+	assert.Error(err)
+}
@@ -0,0 +1,114 @@
+package rinex
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Checksums contains the hex encoded hashes of a file.
+type Checksums struct {
+	MD5    string // MD5 of the file as stored
+	SHA256 string // SHA256 of the file as stored
+
+	// The hashes of the decompressed content. They are equal to the ones above for uncompressed files.
+	// Hatanaka compression is not undone.
+	UncompressedMD5    string
+	UncompressedSHA256 string
+}
+
+// FileChecksums computes the MD5 and SHA256 hashes of the file path and of its decompressed content.
+// gzip and .Z files are hashed in a single pass, zip archives are read twice.
+func FileChecksums(path string) (Checksums, error) {
+	return fileChecksums(LocalFS{}, path)
+}
+
+func fileChecksums(fsys Filesystem, path string) (Checksums, error) {
+	var sums Checksums
+	f, err := fsys.Open(path)
+	if err != nil {
+		return sums, err
+	}
+	defer f.Close()
+
+	rawMD5, rawSHA := md5.New(), sha256.New()
+	raw := io.TeeReader(f, io.MultiWriter(rawMD5, rawSHA))
+	uncMD5, uncSHA := md5.New(), sha256.New()
+	unc := io.MultiWriter(uncMD5, uncSHA)
+
+	r, err := NewDecompressReader(raw)
+	if err != nil {
+		// zip archives can not be streamed
+		if _, err := io.Copy(ioutil.Discard, raw); err != nil {
+			return sums, err
+		}
+		r, err = OpenFileFS(fsys, path)
+		if err != nil {
+			return sums, err
+		}
+	}
+	defer r.Close()
+	if _, err := io.Copy(unc, r); err != nil {
+		return sums, fmt.Errorf("%s: %w", path, err)
+	}
+	// read trailing data that the decompressor did not consume
+	if _, err := io.Copy(ioutil.Discard, raw); err != nil {
+		return sums, err
+	}
+
+	sums.MD5, sums.SHA256 = hexSum(rawMD5), hexSum(rawSHA)
+	sums.UncompressedMD5, sums.UncompressedSHA256 = hexSum(uncMD5), hexSum(uncSHA)
+	return sums, nil
+}
+
+// Verify checks the file checksums against the expected hex encoded MD5 or SHA256 hash. The algorithm
+// is determined by the length of the hash.
+func (sums Checksums) Verify(expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	var got string
+	switch len(expected) {
+	case 2 * md5.Size:
+		got = sums.MD5
+	case 2 * sha256.Size:
+		got = sums.SHA256
+	default:
+		return fmt.Errorf("invalid hash length %d: %q", len(expected), expected)
+	}
+	if got != expected {
+		return fmt.Errorf("checksum mismatch: got %s, expected %s", got, expected)
+	}
+	return nil
+}
+
+// ComputeChecksums computes the hashes of the file and stores them in f.Checksums.
+func (f *ObsFile) ComputeChecksums() error {
+	sums, err := fileChecksums(f.fs(), f.Path)
+	if err != nil {
+		return err
+	}
+	f.Checksums = &sums
+	return nil
+}
+
+// VerifyChecksum checks the file against the expected hex encoded MD5 or SHA256 hash of the file as stored.
+// The checksums are computed if they are not yet set.
+func (f *ObsFile) VerifyChecksum(expected string) error {
+	if f.Checksums == nil {
+		if err := f.ComputeChecksums(); err != nil {
+			return err
+		}
+	}
+	if err := f.Checksums.Verify(expected); err != nil {
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return nil
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
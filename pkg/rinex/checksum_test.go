@@ -0,0 +1,79 @@
+package rinex
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsFile_Checksums(t *testing.T) {
+	assert := assert.New(t)
+	const (
+		md5Sum    = "7d33b4ec763c76c8fcac7282c3c30a09"
+		sha256Sum = "edfdd3acaadbf36014d80e42ad630601efcd8204a3e7bdd02e19b50c77835b19"
+	)
+
+	obs, err := NewObsFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(obs.VerifyChecksum(md5Sum))
+	if assert.NotNil(obs.Checksums) {
+		assert.Equal(sha256Sum, obs.Checksums.SHA256)
+		assert.Equal(md5Sum, obs.Checksums.UncompressedMD5)
+		assert.Equal(sha256Sum, obs.Checksums.UncompressedSHA256)
+	}
+	assert.NoError(obs.VerifyChecksum(" " + sha256Sum + "\n"))
+	assert.Error(obs.VerifyChecksum(md5Sum[1:] + "0"))
+	assert.Error(obs.VerifyChecksum("1234"))
+
+	// compressed
+	gzPath := filepath.Join(t.TempDir(), filepath.Base(obs.Path)+".gz")
+	assert.NoError(CompressFile(obs.Path, gzPath))
+	obs, err = NewObsFile(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(obs.ComputeChecksums())
+	assert.NotEqual(md5Sum, obs.Checksums.MD5)
+	assert.Equal(md5Sum, obs.Checksums.UncompressedMD5)
+	assert.Equal(sha256Sum, obs.Checksums.UncompressedSHA256)
+	assert.NoError(obs.VerifyChecksum(obs.Checksums.SHA256))
+	assert.Error(obs.VerifyChecksum(sha256Sum), "uncompressed hash")
+
+	// .Z
+	sums, err := FileChecksums("testdata/compress/DIEP00DEU_R_20202941900_01H_10S_MM.rnx.Z")
+	assert.NoError(err)
+	want, err := FileChecksums(diepFile)
+	assert.NoError(err)
+	assert.Equal(want.MD5, sums.UncompressedMD5)
+	assert.Equal(want.SHA256, sums.UncompressedSHA256)
+	assert.NotEqual(want.MD5, sums.MD5)
+
+	// zip
+	zipPath := filepath.Join(t.TempDir(), "diep.zip")
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(zf)
+	w, _ := zw.Create(filepath.Base(diepFile))
+	data, _ := ioutil.ReadFile(diepFile)
+	w.Write(data)
+	assert.NoError(zw.Close())
+	assert.NoError(zf.Close())
+	sums, err = FileChecksums(zipPath)
+	assert.NoError(err)
+	assert.Equal(want.SHA256, sums.UncompressedSHA256)
+	zipData, _ := ioutil.ReadFile(zipPath)
+	assert.Equal(fmt.Sprintf("%x", sha256.Sum256(zipData)), sums.SHA256)
+
+	_, err = FileChecksums("testdata/white/missing.rnx")
+	assert.Error(err)
+}
@@ -0,0 +1,476 @@
+package rinex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// Clock data types.
+const (
+	ClkTypeAR = "AR" // receiver clocks derived from a network
+	ClkTypeAS = "AS" // satellite clocks derived from a network
+	ClkTypeCR = "CR" // calibration measurements for a single GNSS receiver
+	ClkTypeDR = "DR" // discontinuity measurements for a single GNSS receiver
+	ClkTypeMS = "MS" // monitor measurements for the broadcast satellite clocks
+)
+
+// ClkRef is a reference clock used by the analysis center.
+type ClkRef struct {
+	Name       string  // station name or satellite
+	Number     string  // clock ID, e.g. DOMES number
+	Constraint float64 // a priori clock constraint in seconds, 0 if not given
+}
+
+// ClkStation is a station used in the clock solution.
+type ClkStation struct {
+	Name   string // 4-char or 9-char station name
+	Number string // unique station ID, e.g. DOMES number
+	Pos    Coord  // geocentric position [m]
+}
+
+// A ClkHeader provides the Clock RINEX Header information.
+type ClkHeader struct {
+	RINEXVersion float32     // RINEX Format version
+	RINEXType    string      // RINEX File type. C for Clock
+	SatSystem    gnss.System // Satellite System. System is "Mixed" if more than one.
+
+	Pgm   string // name of program creating this file
+	RunBy string // name of agency creating this file
+	Date  string // date and time of file creation
+
+	Comments []string // * comment lines
+
+	TimeSystem  string // time system used for time tags, e.g. GPS
+	LeapSeconds int    // number of leap seconds since 6-Jan-1980
+
+	DCBSApplied []CorrApplied // Differential code bias corrections applied
+	PCVSApplied []CorrApplied // Phase center variation corrections applied
+
+	DataTypes []string // clock data types, e.g. AR, AS
+
+	StationName   string // station name for calibration and discontinuity data, i.e. CR and DR
+	StationNumber string // station number for calibration and discontinuity data
+	StationClkRef string // identifier of the external reference clock for calibration data
+
+	AC     string // 3-char analysis center designator
+	ACName string // full name of the analysis center
+
+	ClkRefs    []ClkRef     // reference clocks of the analysis center
+	TRF        string       // terrestrial reference frame of the station coordinates
+	Stations   []ClkStation // stations used in the clock solution
+	Satellites []PRN        // satellites used in the clock solution
+
+	labels   []string // all Header Labels found
+	warnings []string
+}
+
+// nameWidth returns the width of the station names, that is 9 since version 3.04.
+func (hdr *ClkHeader) nameWidth() int {
+	if hdr.RINEXVersion >= 3.04 {
+		return 9
+	}
+	return 4
+}
+
+// labelCol returns the start column of the header labels, that were shifted in version 3.04.
+func (hdr *ClkHeader) labelCol() int {
+	if hdr.RINEXVersion >= 3.04 {
+		return 65
+	}
+	return 60
+}
+
+// ClkRecord is a clock data record.
+type ClkRecord struct {
+	Type   string    // clock data type, e.g. AS
+	Name   string    // station name or satellite, e.g. G01
+	Time   time.Time // epoch
+	Values []float64 // clock bias [s], bias sigma [s], rate [s/s], rate sigma, acceleration [1/s], acceleration sigma
+}
+
+// Bias returns the clock bias in seconds.
+func (rec *ClkRecord) Bias() float64 {
+	if len(rec.Values) < 1 {
+		return 0
+	}
+	return rec.Values[0]
+}
+
+// ClkDecoder reads and decodes header and data records from a Clock RINEX input stream.
+type ClkDecoder struct {
+	// The Header is valid after NewClkDecoder. The header must exist.
+	Header ClkHeader
+
+	sc      *lineReader
+	decOpts DecoderOptions
+	rec     *ClkRecord
+	lineNum int
+	err     error
+}
+
+// NewClkDecoder creates a new decoder for Clock RINEX data.
+// The RINEX header will be read implicitly. The header must exist.
+//
+// It is the caller's responsibility to call Close on the underlying reader when done!
+func NewClkDecoder(r io.Reader) (*ClkDecoder, error) {
+	return NewClkDecoderWithOptions(r, DecoderOptions{})
+}
+
+// NewClkDecoderWithOptions creates a new decoder for Clock RINEX data with the given
+// line length and header limits.
+func NewClkDecoderWithOptions(r io.Reader, opts DecoderOptions) (*ClkDecoder, error) {
+	dec := &ClkDecoder{sc: opts.lineReader(r), decOpts: opts}
+	dec.Header, dec.err = dec.readHeader()
+	return dec, dec.err
+}
+
+// Err returns the first non-EOF error that was encountered by the decoder.
+func (dec *ClkDecoder) Err() error {
+	if dec.err == io.EOF {
+		return nil
+	}
+	return dec.err
+}
+
+// setErr records the first error encountered.
+func (dec *ClkDecoder) setErr(err error) {
+	if dec.err == nil || dec.err == io.EOF {
+		dec.err = err
+	}
+}
+
+// readHeader reads a Clock RINEX header. If the Header does not exist,
+// a ErrNoHeader error will be returned.
+func (dec *ClkDecoder) readHeader() (hdr ClkHeader, err error) {
+	maxLines := dec.decOpts.maxHeaderLines(5000) // the list of stations might be long
+	labelCol := 60
+read:
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+
+		if dec.lineNum == 1 {
+			idx := strings.Index(line, "RINEX VERSION / TYPE")
+			if idx < 0 {
+				return hdr, ErrNoHeader
+			}
+			labelCol = idx
+		}
+		if dec.lineNum > maxLines {
+			return hdr, fmt.Errorf("Reading header failed: line %d reached without finding end of header", maxLines)
+		}
+		if len(line) < labelCol {
+			continue
+		}
+
+		val := line[:labelCol]
+		key := strings.TrimSpace(line[labelCol:])
+		nameWidth := hdr.nameWidth()
+
+		hdr.labels = append(hdr.labels, key)
+
+		switch key {
+		case "RINEX VERSION / TYPE":
+			f64, err := strconv.ParseFloat(strings.TrimSpace(field(val, 0, 20)), 32)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing RINEX VERSION: %w", err)
+			}
+			hdr.RINEXVersion = float32(f64)
+			hdr.RINEXType = strings.TrimSpace(field(val, 20, 21))
+			if hdr.RINEXType != "C" {
+				return hdr, fmt.Errorf("invalid RINEX type for clock files: %q", hdr.RINEXType)
+			}
+			if s := strings.TrimSpace(field(val, 40, 41)); s != "" {
+				sys, ok := gnss.SystemByAbbr(s)
+				if !ok {
+					return hdr, fmt.Errorf("read header: invalid satellite system in line %d: %s", dec.lineNum, line)
+				}
+				hdr.SatSystem = sys
+			}
+			if hdr.labelCol() != labelCol {
+				return hdr, fmt.Errorf("read header: header labels of version %.2f must start at column %d", hdr.RINEXVersion, hdr.labelCol()+1)
+			}
+		case "PGM / RUN BY / DATE":
+			hdr.Pgm = strings.TrimSpace(field(val, 0, 20))
+			hdr.RunBy = strings.TrimSpace(field(val, 20, 40))
+			hdr.Date = strings.TrimSpace(field(val, 40, len(val)))
+		case "COMMENT":
+			hdr.Comments = append(hdr.Comments, strings.TrimSpace(val))
+		case "TIME SYSTEM ID":
+			hdr.TimeSystem = strings.TrimSpace(field(val, 0, 6))
+		case "LEAP SECONDS":
+			i, err := strconv.Atoi(strings.TrimSpace(field(val, 0, 6)))
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %w", key, err)
+			}
+			hdr.LeapSeconds = i
+		case "SYS / DCBS APPLIED", "SYS / PCVS APPLIED":
+			sys, ok := gnss.SystemByAbbr(field(val, 0, 1))
+			if !ok {
+				return hdr, fmt.Errorf("invalid satellite system: %q: line %d", field(val, 0, 1), dec.lineNum)
+			}
+			corr := CorrApplied{Sys: sys, Program: strings.TrimSpace(field(val, 2, 19)), Source: strings.TrimSpace(field(val, 20, len(val)))}
+			if key == "SYS / DCBS APPLIED" {
+				hdr.DCBSApplied = append(hdr.DCBSApplied, corr)
+			} else {
+				hdr.PCVSApplied = append(hdr.PCVSApplied, corr)
+			}
+		case "# / TYPES OF DATA":
+			hdr.DataTypes = strings.Fields(field(val, 6, len(val)))
+		case "STATION NAME / NUM":
+			hdr.StationName = strings.TrimSpace(field(val, 0, nameWidth))
+			hdr.StationNumber = strings.TrimSpace(field(val, nameWidth+1, nameWidth+21))
+		case "STATION CLK REF":
+			hdr.StationClkRef = strings.TrimSpace(val)
+		case "ANALYSIS CENTER":
+			hdr.AC = strings.TrimSpace(field(val, 0, 3))
+			hdr.ACName = strings.TrimSpace(field(val, 5, len(val)))
+		case "ANALYSIS CLK REF":
+			ref := ClkRef{Name: strings.TrimSpace(field(val, 0, nameWidth)), Number: strings.TrimSpace(field(val, nameWidth+1, nameWidth+21))}
+			if s := strings.TrimSpace(field(val, nameWidth+21, len(val))); s != "" {
+				ref.Constraint, err = parseFloat(s)
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+			}
+			hdr.ClkRefs = append(hdr.ClkRefs, ref)
+		case "# OF SOLN STA / TRF":
+			hdr.TRF = strings.TrimSpace(field(val, 10, len(val)))
+		case "SOLN STA NAME / NUM":
+			sta := ClkStation{Name: strings.TrimSpace(field(val, 0, nameWidth)), Number: strings.TrimSpace(field(val, nameWidth+1, nameWidth+21))}
+			xyz := strings.Fields(field(val, nameWidth+21, len(val)))
+			if len(xyz) != 3 {
+				return hdr, fmt.Errorf("parsing %q: line %d: expected 3 coordinates", key, dec.lineNum)
+			}
+			var mm [3]int64
+			for i := range xyz {
+				mm[i], err = strconv.ParseInt(xyz[i], 10, 64)
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+			}
+			sta.Pos = Coord{X: float64(mm[0]) / 1000, Y: float64(mm[1]) / 1000, Z: float64(mm[2]) / 1000}
+			hdr.Stations = append(hdr.Stations, sta)
+		case "PRN LIST":
+			for col := 0; col+3 <= len(val); col += 4 {
+				s := val[col : col+3]
+				if strings.TrimSpace(s) == "" {
+					continue
+				}
+				prn, err := ParsePRN(strings.Replace(s, " ", "0", 1))
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+				hdr.Satellites = append(hdr.Satellites, prn)
+			}
+		case "# OF CLK REF", "# OF SOLN SATS", "SYS / # / OBS TYPES", "LEAP SECONDS GNSS":
+			// derived from other records or not needed
+		case "END OF HEADER":
+			break read
+		default:
+			hdr.warnings = append(hdr.warnings, fmt.Sprintf("header label not handled: %s", key))
+		}
+	}
+
+	err = dec.sc.Err()
+	return
+}
+
+// NextRecord reads the next clock data record.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (dec *ClkDecoder) NextRecord() bool {
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// AS G01  2020 06 18 00 00  0.000000  2   -1.234567890123E-04  1.234567890123E-11
+		fields := strings.Fields(line)
+		if len(fields) < 9 {
+			dec.setErr(fmt.Errorf("invalid clock data record in line %d: %q", dec.lineNum, line))
+			return false
+		}
+
+		epTime, err := parseClkEpoch(fields[2:8])
+		if err != nil {
+			dec.setErr(fmt.Errorf("parsing epoch in line %d: %q: %w", dec.lineNum, line, err))
+			return false
+		}
+
+		nVals, err := strconv.Atoi(fields[8])
+		if err != nil || nVals < 1 || nVals > 6 {
+			dec.setErr(fmt.Errorf("invalid number of data values in line %d: %q", dec.lineNum, line))
+			return false
+		}
+
+		vals := fields[9:]
+		for len(vals) < nVals { // continuation line
+			if !dec.sc.Scan() {
+				dec.setErr(fmt.Errorf("unexpected EOF after line %d", dec.lineNum))
+				return false
+			}
+			dec.lineNum++
+			vals = append(vals, strings.Fields(dec.sc.Text())...)
+		}
+		if len(vals) != nVals {
+			dec.setErr(fmt.Errorf("expected %d data values in line %d", nVals, dec.lineNum))
+			return false
+		}
+
+		rec := &ClkRecord{Type: fields[0], Name: fields[1], Time: epTime, Values: make([]float64, 0, nVals)}
+		for _, s := range vals {
+			f64, err := parseFloat(strings.Replace(s, "D", "E", 1)) // Fortran double precision
+			if err != nil {
+				dec.setErr(fmt.Errorf("parsing data value in line %d: %w", dec.lineNum, err))
+				return false
+			}
+			rec.Values = append(rec.Values, f64)
+		}
+		dec.rec = rec
+		return true
+	}
+
+	if err := dec.sc.Err(); err != nil {
+		dec.setErr(fmt.Errorf("read records scanner error: %w", err))
+	}
+	return false // EOF
+}
+
+// Record returns the most recent record generated by a call to NextRecord.
+func (dec *ClkDecoder) Record() *ClkRecord {
+	return dec.rec
+}
+
+// parseClkEpoch parses the epoch given as year, month, day, hour, min and seconds.
+func parseClkEpoch(fields []string) (time.Time, error) {
+	var ymdhm [5]int
+	for i := range ymdhm {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+		ymdhm[i] = n
+	}
+	sec, err := strconv.ParseFloat(fields[5], 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	whole, frac := math.Modf(sec)
+	return time.Date(ymdhm[0], time.Month(ymdhm[1]), ymdhm[2], ymdhm[3], ymdhm[4], int(whole),
+		int(math.Round(frac*1e9)), time.UTC), nil
+}
+
+// Write writes the header in Clock RINEX format to w.
+func (hdr *ClkHeader) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	hw := &headerWriter{w: bw, valWidth: hdr.labelCol()}
+	nameWidth := hdr.nameWidth()
+
+	hw.writeLine(fmt.Sprintf("%9.2f%11s%-20s%-20s", hdr.RINEXVersion, "", "C", hdr.SatSystem.Abbr()), "RINEX VERSION / TYPE")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.Pgm, hdr.RunBy, hdr.Date), "PGM / RUN BY / DATE")
+	for _, c := range hdr.Comments {
+		hw.writeLine(c, "COMMENT")
+	}
+	if hdr.TimeSystem != "" {
+		hw.writeLine(fmt.Sprintf("   %-3s", hdr.TimeSystem), "TIME SYSTEM ID")
+	}
+	if hdr.LeapSeconds != 0 {
+		hw.writeLine(fmt.Sprintf("%6d", hdr.LeapSeconds), "LEAP SECONDS")
+	}
+	for _, corr := range hdr.DCBSApplied {
+		hw.writeLine(fmt.Sprintf("%-1s %-17s %s", corr.Sys.Abbr(), corr.Program, corr.Source), "SYS / DCBS APPLIED")
+	}
+	for _, corr := range hdr.PCVSApplied {
+		hw.writeLine(fmt.Sprintf("%-1s %-17s %s", corr.Sys.Abbr(), corr.Program, corr.Source), "SYS / PCVS APPLIED")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%6d", len(hdr.DataTypes))
+	for _, typ := range hdr.DataTypes {
+		fmt.Fprintf(&sb, "    %-2s", typ)
+	}
+	hw.writeLine(sb.String(), "# / TYPES OF DATA")
+
+	if hdr.StationName != "" {
+		hw.writeLine(fmt.Sprintf("%-*s %-20s", nameWidth, hdr.StationName, hdr.StationNumber), "STATION NAME / NUM")
+	}
+	if hdr.StationClkRef != "" {
+		hw.writeLine(hdr.StationClkRef, "STATION CLK REF")
+	}
+	if hdr.AC != "" {
+		hw.writeLine(fmt.Sprintf("%-3s  %s", hdr.AC, hdr.ACName), "ANALYSIS CENTER")
+	}
+	if len(hdr.ClkRefs) > 0 {
+		hw.writeLine(fmt.Sprintf("%6d", len(hdr.ClkRefs)), "# OF CLK REF")
+		for _, ref := range hdr.ClkRefs {
+			s := fmt.Sprintf("%-*s %-20s", nameWidth, ref.Name, ref.Number)
+			if ref.Constraint != 0 {
+				s += fmt.Sprintf("%15s%19.12E", "", ref.Constraint)
+			}
+			hw.writeLine(s, "ANALYSIS CLK REF")
+		}
+	}
+	if len(hdr.Stations) > 0 {
+		hw.writeLine(fmt.Sprintf("%6d    %-50s", len(hdr.Stations), hdr.TRF), "# OF SOLN STA / TRF")
+		for _, sta := range hdr.Stations {
+			hw.writeLine(fmt.Sprintf("%-*s %-20s%11d %11d %11d", nameWidth, sta.Name, sta.Number,
+				int64(math.Round(sta.Pos.X*1000)), int64(math.Round(sta.Pos.Y*1000)), int64(math.Round(sta.Pos.Z*1000))), "SOLN STA NAME / NUM")
+		}
+	}
+	if len(hdr.Satellites) > 0 {
+		hw.writeLine(fmt.Sprintf("%6d", len(hdr.Satellites)), "# OF SOLN SATS")
+		prns := make([]string, 0, len(hdr.Satellites))
+		for _, prn := range hdr.Satellites {
+			prns = append(prns, prn.String())
+		}
+		for i := 0; i < len(prns); i += 15 {
+			end := i + 15
+			if end > len(prns) {
+				end = len(prns)
+			}
+			hw.writeLine(strings.Join(prns[i:end], " "), "PRN LIST")
+		}
+	}
+
+	hw.writeLine("", "END OF HEADER")
+	if hw.err != nil {
+		return hw.err
+	}
+	return bw.Flush()
+}
+
+// Write writes the clock data record in Clock RINEX format to w.
+// The station or satellite name is written with the given width, 4 or 9 since version 3.04.
+func (rec *ClkRecord) Write(w io.Writer, nameWidth int) error {
+	if len(rec.Values) < 1 || len(rec.Values) > 6 {
+		return fmt.Errorf("write clock record: invalid number of data values: %d", len(rec.Values))
+	}
+	t := rec.Time
+	sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	line := fmt.Sprintf("%-2s %-*s %4d %02d %02d %02d %02d %9.6f %2d  ", rec.Type, nameWidth, rec.Name,
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), sec, len(rec.Values))
+	lines := []string{line + formatClkValues(rec.Values)}
+	if len(rec.Values) > 2 { // continuation line
+		lines = []string{line + formatClkValues(rec.Values[:2]), formatClkValues(rec.Values[2:])}
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// formatClkValues formats the clock data values as E19.12 separated by a blank.
+func formatClkValues(vals []float64) string {
+	strs := make([]string, 0, len(vals))
+	for _, v := range vals {
+		strs = append(strs, fmt.Sprintf("%19.12E", v))
+	}
+	return strings.Join(strs, " ")
+}
@@ -0,0 +1,107 @@
+package rinex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+const clkData300 = `     3.00           C                   G                   RINEX VERSION / TYPE
+CCLOCK              IGSACC @ GA & MIT   20200625 14:44:57   PGM / RUN BY / DATE
+GPS week: 2110   Day: 4   MJD: 59018                        COMMENT
+     2    AR    AS                                          # / TYPES OF DATA
+IGS  IGSACC @ GA and MIT                                    ANALYSIS CENTER
+     1                                                      # OF CLK REF
+USN7 40451S010                          -1.000000000000E-09 ANALYSIS CLK REF
+     2    IGS14                                             # OF SOLN STA / TRF
+ALGO 40104M002            918129435 -4346071255  4562010325 SOLN STA NAME / NUM
+USN7 40451S010           1112162082 -4842853755  3985497029 SOLN STA NAME / NUM
+     2                                                      # OF SOLN SATS
+G01 G20                                                     PRN LIST
+    18                                                      LEAP SECONDS
+   GPS                                                      TIME SYSTEM ID
+G CODE              http://www.aiub.unibe.ch                SYS / DCBS APPLIED
+                                                            END OF HEADER
+AR ALGO 2020 06 18 00 00  0.000000  2   -1.066291216467E-06  5.097330883630E-11
+AS G01  2020 06 18 00 00  0.000000  1   -2.117838501064E-04
+AS G20  2020 06 18 00 00 30.000000  4    5.274894647300D-04  1.000000000000D-11
+    1.136868377216E-13  1.000000000000E-14
+`
+
+func TestClkDecoder(t *testing.T) {
+	assert := assert.New(t)
+	dec, err := NewClkDecoder(strings.NewReader(clkData300))
+	assert.NoError(err)
+	hdr := dec.Header
+	assert.Equal(float32(3.0), hdr.RINEXVersion)
+	assert.Equal(gnss.SysGPS, hdr.SatSystem)
+	assert.Equal([]string{ClkTypeAR, ClkTypeAS}, hdr.DataTypes)
+	assert.Equal("IGS", hdr.AC)
+	assert.Equal("IGSACC @ GA and MIT", hdr.ACName)
+	assert.Equal([]ClkRef{{Name: "USN7", Number: "40451S010", Constraint: -1e-9}}, hdr.ClkRefs)
+	assert.Equal("IGS14", hdr.TRF)
+	if assert.Len(hdr.Stations, 2) {
+		assert.Equal(ClkStation{Name: "ALGO", Number: "40104M002", Pos: Coord{X: 918129.435, Y: -4346071.255, Z: 4562010.325}}, hdr.Stations[0])
+	}
+	assert.Equal([]PRN{{Sys: gnss.SysGPS, Num: 1}, {Sys: gnss.SysGPS, Num: 20}}, hdr.Satellites)
+	assert.Equal(18, hdr.LeapSeconds)
+	assert.Equal("GPS", hdr.TimeSystem)
+	assert.Len(hdr.DCBSApplied, 1)
+	assert.Empty(hdr.warnings)
+
+	recs := []*ClkRecord{}
+	for dec.NextRecord() {
+		recs = append(recs, dec.Record())
+	}
+	assert.NoError(dec.Err())
+	if assert.Len(recs, 3) {
+		assert.Equal(ClkTypeAR, recs[0].Type)
+		assert.Equal("ALGO", recs[0].Name)
+		assert.Equal(-1.066291216467e-06, recs[0].Bias())
+		assert.Len(recs[1].Values, 1)
+		assert.Equal(time.Date(2020, 6, 18, 0, 0, 30, 0, time.UTC), recs[2].Time)
+		assert.Equal([]float64{5.274894647300e-04, 1e-11, 1.136868377216e-13, 1e-14}, recs[2].Values)
+	}
+
+	// round trip
+	var buf bytes.Buffer
+	assert.NoError(hdr.Write(&buf))
+	for _, rec := range recs {
+		assert.NoError(rec.Write(&buf, hdr.nameWidth()))
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		assert.LessOrEqual(len(line), 80, line)
+	}
+	dec2, err := NewClkDecoder(&buf)
+	assert.NoError(err)
+	assert.Equal(hdr.Stations, dec2.Header.Stations)
+	assert.Equal(hdr.ClkRefs, dec2.Header.ClkRefs)
+	assert.Equal(hdr.Satellites, dec2.Header.Satellites)
+	assert.Equal(hdr.DataTypes, dec2.Header.DataTypes)
+	recs2 := []*ClkRecord{}
+	for dec2.NextRecord() {
+		recs2 = append(recs2, dec2.Record())
+	}
+	assert.NoError(dec2.Err())
+	assert.Equal(recs, recs2)
+}
+
+func TestClkHeader_Write304(t *testing.T) {
+	assert := assert.New(t)
+	hdr := ClkHeader{RINEXVersion: 3.04, SatSystem: gnss.SysMIXED, Pgm: "gognss", DataTypes: []string{ClkTypeAS},
+		TRF: "IGS20", Stations: []ClkStation{{Name: "WTZR00DEU", Number: "14201M010", Pos: Coord{X: 4075580.385, Y: 931853.96, Z: 4801568.225}}}}
+	var buf bytes.Buffer
+	assert.NoError(hdr.Write(&buf))
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		assert.Len(line, 85, line)
+		assert.NotEqual(" ", line[65:66], line)
+	}
+	dec, err := NewClkDecoder(&buf)
+	assert.NoError(err)
+	assert.Equal(hdr.Stations, dec.Header.Stations)
+	assert.Equal(gnss.SysMIXED, dec.Header.SatSystem)
+}
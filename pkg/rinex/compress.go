@@ -0,0 +1,452 @@
+package rinex
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Magic numbers of the supported compression formats.
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicLZW  = []byte{0x1f, 0x9d} // Unix compress (.Z)
+	magicZip  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// compressedExts contains the file extensions of compressed files, in lower case.
+var compressedExts = map[string]bool{
+	".gz": true, ".z": true, ".zip": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true,
+}
+
+// CompressOptions configures the compression of RINEX files.
+type CompressOptions struct {
+	KeepSource bool   // keep the source file, by default it is removed after a successful compression
+	Dir        string // directory of the compressed file, defaults to the directory of the source file
+	NoVerify   bool   // do not decode the header of the compressed file before the source file is removed
+
+	// Progress receives the bytes read from the file to compress, see Progress.
+	Progress Progress
+}
+
+// CompressFile gzips the file src and writes it to dst. The data is streamed into a temporary file
+// that is renamed to dst at the end, so dst is never left incomplete.
+func CompressFile(src, dst string) error {
+	tmp, err := gzipToTemp(src, filepath.Dir(dst), nil)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// compressRnx gzips the RINEX file src into the directory opts.Dir and returns the path of the
+// compressed file. Unless disabled, the compressed file is checked with verify before it gets its
+// final name. The source file is not touched.
+func compressRnx(src string, opts CompressOptions, verify func(r io.Reader) error) (string, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = filepath.Dir(src)
+	}
+	dst := filepath.Join(dir, filepath.Base(src)+".gz")
+	tmp, err := gzipToTemp(src, dir, opts.Progress)
+	if err != nil {
+		return "", err
+	}
+	if !opts.NoVerify {
+		if err := verifyFile(tmp, verify); err != nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("verify %s: %w", dst, err)
+		}
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return dst, nil
+}
+
+// gzipToTemp gzips the file src into a temporary file in dir and returns its path.
+// The temporary file gets the permissions of src. The bytes read are reported to p if it is not nil.
+func gzipToTemp(src, dir string, p Progress) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	stat, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ioutil.TempFile(dir, "."+filepath.Base(src)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(src)
+	gz.ModTime = stat.ModTime()
+	if p != nil {
+		p.AddTotal(stat.Size())
+	}
+	_, err = io.Copy(gz, withProgress(in, p))
+	if err2 := gz.Close(); err == nil {
+		err = err2
+	}
+	if err2 := out.Chmod(stat.Mode().Perm()); err == nil {
+		err = err2
+	}
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("compress %s: %w", src, err)
+	}
+	return out.Name(), nil
+}
+
+// verifyFile opens the compressed file path and passes the decompressed data to verify.
+func verifyFile(path string, verify func(r io.Reader) error) error {
+	r, err := OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return verify(r)
+}
+
+// verifyHeader checks that r starts with a RINEX header of the given file type, e.g. 'M' for meteo files.
+func verifyHeader(r io.Reader, fileType byte) error {
+	sc := bufio.NewScanner(r)
+	for n := 0; sc.Scan(); n++ {
+		line := sc.Text()
+		if n == 0 {
+			if len(line) < 61 || !strings.HasPrefix(line[60:], "RINEX VERSION / TYPE") {
+				return fmt.Errorf("invalid first header line: %q", line)
+			}
+			if line[20] != fileType {
+				return fmt.Errorf("invalid file type: %q", line[20])
+			}
+		}
+		if len(line) >= 61 && strings.HasPrefix(line[60:], "END OF HEADER") {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return errors.New("no END OF HEADER")
+}
+
+// DecompressFile decompresses the gzip, Unix compress (.Z) or zip file src and writes it to dst.
+// dst is removed if the decompression fails.
+func DecompressFile(src, dst string) error {
+	r, err := OpenFile(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("decompress %s: %w", src, err)
+	}
+	return nil
+}
+
+// OpenFile opens the named file for reading. Compressed files are decompressed on the fly, without
+// writing the uncompressed data to disk. The format is detected from the content, supported are gzip,
+// Unix compress (.Z) and zip. A zip archive must contain exactly one file.
+func OpenFile(path string) (io.ReadCloser, error) {
+	return openFile(path, nil)
+}
+
+// openFile opens the file like OpenFile. If p is not nil, the size of the file and the bytes read from
+// it are reported to p, for zip archives only the uncompressed bytes.
+func openFile(path string, p Progress) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(magicZip))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	if bytes.Equal(magic[:n], magicZip) {
+		f.Close()
+		if p != nil {
+			rc, err := openZip(path)
+			if err != nil {
+				return nil, err
+			}
+			return &multiCloser{Reader: NewProgressReader(rc, p), closers: []io.Closer{rc}}, nil
+		}
+		return openZip(path)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	addFileSize(f, p)
+	r, err := NewDecompressReader(withProgress(f, p))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &multiCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+// NewDecompressReader returns a reader that decompresses the data read from r. The format is detected
+// from the content, supported are gzip and Unix compress (.Z). Uncompressed data is passed through.
+// Zip archives can not be read from a stream, use OpenFile for them.
+// It is the caller's responsibility to call Close on the returned reader when done, r is not closed.
+func NewDecompressReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(magicZip))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, magicLZW):
+		return newLZWReader(br)
+	case bytes.HasPrefix(magic, magicZip):
+		return nil, errors.New("zip archive can not be read from a stream")
+	}
+	return ioutil.NopCloser(br), nil
+}
+
+// IsCompressed returns true if the src is compressed, otherwise false.
+// The decision is based on the file extension.
+func IsCompressed(src string) bool {
+	return compressedExts[strings.ToLower(filepath.Ext(src))]
+}
+
+// openZip returns a reader for the only file in the zip archive.
+func openZip(path string) (io.ReadCloser, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []*zip.File
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, f)
+		}
+	}
+	if len(files) != 1 {
+		zr.Close()
+		return nil, fmt.Errorf("%s: zip archive contains %d files, expected 1", path, len(files))
+	}
+	r, err := files[0].Open()
+	if err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &multiCloser{Reader: r, closers: []io.Closer{r, zr}}, nil
+}
+
+// multiCloser closes all closers in order.
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var err error
+	for _, c := range m.closers {
+		if err2 := c.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// lzwReader decompresses data in the format of the Unix compress program (.Z files).
+// The package compress/lzw can not be used, because compress uses variable code widths up to 16 bits
+// and pads the codes to groups of 8 when the width changes.
+type lzwReader struct {
+	r       io.ByteReader
+	maxBits uint
+	block   bool // block mode, code 256 clears the table
+
+	nBits  uint   // current code width
+	nCodes int    // number of codes read with the current width
+	bits   uint32 // bit buffer
+	nBuf   uint   // number of bits in the buffer
+
+	prefix  []uint16
+	suffix  []byte
+	freeEnt int // next free table entry
+	prev    int // previous code, -1 at the start and after a clear
+	first   byte
+
+	stack []byte
+	out   []byte // decoded but not yet read data
+	err   error
+}
+
+const (
+	lzwClear    = 256
+	lzwInitBits = 9
+)
+
+func newLZWReader(r *bufio.Reader) (*lzwReader, error) {
+	hdr := make([]byte, 3)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("read .Z header: %w", err)
+	}
+	if !bytes.Equal(hdr[:2], magicLZW) {
+		return nil, errors.New("invalid .Z header")
+	}
+	maxBits := uint(hdr[2] & 0x1f)
+	if maxBits < lzwInitBits || maxBits > 16 {
+		return nil, fmt.Errorf("invalid .Z max bits: %d", maxBits)
+	}
+	z := &lzwReader{
+		r:       r,
+		maxBits: maxBits,
+		block:   hdr[2]&0x80 != 0,
+		nBits:   lzwInitBits,
+		prefix:  make([]uint16, 1<<maxBits),
+		suffix:  make([]byte, 1<<maxBits),
+		prev:    -1,
+	}
+	z.freeEnt = 256
+	if z.block {
+		z.freeEnt = 257
+	}
+	return z, nil
+}
+
+func (z *lzwReader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.decode()
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+func (z *lzwReader) Close() error {
+	return nil
+}
+
+// decode decodes the next code into z.out.
+func (z *lzwReader) decode() {
+	if z.freeEnt > 1<<z.nBits-1 && z.nBits < z.maxBits {
+		if z.err = z.skipGroup(); z.err != nil {
+			return
+		}
+		z.nBits++
+	}
+
+	code, err := z.readCode()
+	if err != nil {
+		z.err = err
+		return
+	}
+	if code == lzwClear && z.block {
+		if z.err = z.skipGroup(); z.err != nil {
+			return
+		}
+		z.nBits = lzwInitBits
+		z.freeEnt = 257
+		z.prev = -1
+		return
+	}
+	if z.prev == -1 {
+		if code > 255 {
+			z.err = fmt.Errorf("invalid .Z code: %d", code)
+			return
+		}
+		z.first = byte(code)
+		z.prev = code
+		z.stack = append(z.stack[:0], z.first)
+		z.out = z.stack
+		return
+	}
+
+	in := code
+	stack := z.stack[:0]
+	if code >= z.freeEnt {
+		if code > z.freeEnt {
+			z.err = fmt.Errorf("invalid .Z code: %d", code)
+			return
+		}
+		stack = append(stack, z.first)
+		code = z.prev
+	}
+	for code > 255 {
+		stack = append(stack, z.suffix[code])
+		code = int(z.prefix[code])
+	}
+	z.first = byte(code)
+	stack = append(stack, z.first)
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+
+	if z.freeEnt < 1<<z.maxBits {
+		z.prefix[z.freeEnt] = uint16(z.prev)
+		z.suffix[z.freeEnt] = z.first
+		z.freeEnt++
+	}
+	z.prev = in
+	z.stack = stack
+	z.out = stack
+}
+
+// readCode reads the next code with the current width. A trailing incomplete code is io.EOF.
+func (z *lzwReader) readCode() (int, error) {
+	for z.nBuf < z.nBits {
+		b, err := z.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		z.bits |= uint32(b) << z.nBuf
+		z.nBuf += 8
+	}
+	code := int(z.bits & (1<<z.nBits - 1))
+	z.bits >>= z.nBits
+	z.nBuf -= z.nBits
+	z.nCodes++
+	return code, nil
+}
+
+// skipGroup skips the padding up to the end of the current group of 8 codes.
+func (z *lzwReader) skipGroup() error {
+	for z.nCodes%8 != 0 {
+		if _, err := z.readCode(); err != nil {
+			return err
+		}
+	}
+	z.nCodes = 0
+	return nil
+}
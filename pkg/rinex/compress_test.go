@@ -0,0 +1,187 @@
+package rinex
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const diepFile = "testdata/white/DIEP00DEU_R_20202941900_01H_10S_MM.rnx"
+
+func readAll(t *testing.T, path string) []byte {
+	r, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return data
+}
+
+func TestCompressFile(t *testing.T) {
+	assert := assert.New(t)
+	want, err := ioutil.ReadFile(diepFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	gzPath := filepath.Join(dir, filepath.Base(diepFile)+".gz")
+	assert.NoError(CompressFile(diepFile, gzPath))
+	assert.Equal(want, readAll(t, gzPath))
+
+	dst := filepath.Join(dir, "decompressed.rnx")
+	assert.NoError(DecompressFile(gzPath, dst))
+	got, err := ioutil.ReadFile(dst)
+	assert.NoError(err)
+	assert.Equal(want, got)
+
+	// uncompressed files are passed through
+	assert.Equal(want, readAll(t, diepFile))
+
+	assert.Error(CompressFile(filepath.Join(dir, "missing.rnx"), filepath.Join(dir, "missing.rnx.gz")))
+	_, err = os.Stat(filepath.Join(dir, "missing.rnx.gz"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestOpenFile_lzw(t *testing.T) {
+	assert := assert.New(t)
+	want, err := ioutil.ReadFile(diepFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 bit codes, several table clears
+	assert.Equal(want, readAll(t, "testdata/compress/DIEP00DEU_R_20202941900_01H_10S_MM.rnx.Z"))
+
+	fi, err := ParseFilename("DIEP00DEU_R_20202941900_01H_10S_MM.rnx.Z")
+	assert.NoError(err)
+	assert.True(IsCompressed(fi.Name))
+	assert.Equal("Z", fi.Compression)
+
+	// truncated header
+	path := filepath.Join(t.TempDir(), "short.Z")
+	assert.NoError(ioutil.WriteFile(path, magicLZW, 0644))
+	_, err = OpenFile(path)
+	assert.Error(err)
+}
+
+func TestOpenFile_zip(t *testing.T) {
+	assert := assert.New(t)
+	want, err := ioutil.ReadFile(diepFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeZip := func(path string, names ...string) {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := zip.NewWriter(f)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			assert.NoError(err)
+			_, err = w.Write(want)
+			assert.NoError(err)
+		}
+		assert.NoError(zw.Close())
+		assert.NoError(f.Close())
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "diep.zip")
+	writeZip(path, filepath.Base(diepFile))
+	assert.Equal(want, readAll(t, path))
+
+	writeZip(path, "a.rnx", "b.rnx")
+	_, err = OpenFile(path)
+	assert.Error(err)
+}
+
+func TestIsCompressed(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(IsCompressed("brux3100.18o.Z"))
+	assert.True(IsCompressed("brux3100.18d.z"))
+	assert.True(IsCompressed("BRUX00BEL_R_20183101900_01H_30S_MO.crx.gz"))
+	assert.True(IsCompressed("BRUX00BEL_R_20183101900_01H_30S_MO.rnx.zip"))
+	assert.False(IsCompressed("BRUX00BEL_R_20183101900_01H_30S_MO.crx"))
+	assert.False(IsCompressed("brux3100.18o"))
+}
+
+func TestCompressWithOptions(t *testing.T) {
+	assert := assert.New(t)
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+
+	// keep the source, write to another directory
+	metPath, err := copyToTempDir(diepFile, srcDir)
+	if err != nil {
+		t.Fatalf("Could not copy to temp dir: %v", err)
+	}
+	met, err := NewMeteoFile(metPath)
+	assert.NoError(err)
+	assert.NoError(met.CompressWithOptions(CompressOptions{KeepSource: true, Dir: dstDir}))
+	assert.Equal(filepath.Join(dstDir, filepath.Base(diepFile)+".gz"), met.Path)
+	assert.Equal("gz", met.Compression)
+	assert.FileExists(metPath)
+	want, _ := ioutil.ReadFile(diepFile)
+	assert.Equal(want, readAll(t, met.Path))
+
+	// Hatanaka compressed obs file
+	crxPath, err := copyToTempDir("testdata/white/BRUX00BEL_R_20202302000_01H_30S_MO.crx", srcDir)
+	if err != nil {
+		t.Fatalf("Could not copy to temp dir: %v", err)
+	}
+	obs, err := NewObsFile(crxPath)
+	assert.NoError(err)
+	assert.NoError(obs.CompressWithOptions(CompressOptions{Dir: dstDir}))
+	assert.Equal(filepath.Join(dstDir, "BRUX00BEL_R_20202302000_01H_30S_MO.crx.gz"), obs.Path)
+	assert.NoFileExists(crxPath)
+
+	// navigation file, source removed
+	navPath, err := copyToTempDir("testdata/white/AREG00PER_R_20201690000_01D_MN.rnx", srcDir)
+	if err != nil {
+		t.Fatalf("Could not copy to temp dir: %v", err)
+	}
+	nav, err := NewNavFile(navPath)
+	assert.NoError(err)
+	assert.NoError(nav.Compress())
+	assert.Equal(navPath+".gz", nav.Path)
+	assert.NoFileExists(navPath)
+
+	// no temporary files left
+	for _, dir := range []string{srcDir, dstDir} {
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(err)
+		for _, f := range files {
+			assert.NotEqual(".tmp", filepath.Ext(f.Name()), f.Name())
+		}
+	}
+}
+
+func TestCompressWithOptions_verify(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "WTZR00DEU_R_20202941900_01H_10S_MM.rnx")
+	assert.NoError(ioutil.WriteFile(path, []byte("no RINEX\n"), 0644))
+
+	met, err := NewMeteoFile(path)
+	assert.NoError(err)
+	assert.Error(met.Compress())
+	assert.Equal(path, met.Path)
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(err)
+	if assert.Len(files, 1, "source kept, no output") {
+		assert.Equal(filepath.Base(path), files[0].Name())
+	}
+
+	assert.NoError(met.CompressWithOptions(CompressOptions{NoVerify: true}))
+	assert.Equal(path+".gz", met.Path)
+}
@@ -0,0 +1,259 @@
+package rinex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// DefaultConvertVersion is the RINEX version of the files converted from RINEX 2 by ConvertObs and ConvertNav.
+const DefaultConvertVersion = 3.04
+
+// DefaultDowngradeVersion is the RINEX version of the observation files converted from RINEX 3 by ConvertObs.
+const DefaultDowngradeVersion = 2.11
+
+// rinex2Bands are the frequency bands per system defined by RINEX 2.11.
+var rinex2Bands = map[gnss.System]string{
+	gnss.SysGPS:  "125",
+	gnss.SysGLO:  "12",
+	gnss.SysGAL:  "15678",
+	gnss.SysSBAS: "15",
+}
+
+// ConvertOptions configures ConvertObs and ConvertNav.
+type ConvertOptions struct {
+	// Version is the RINEX version of the output. If 0, RINEX 2 files are converted to DefaultConvertVersion
+	// and RINEX 3 files to DefaultDowngradeVersion.
+	Version float32
+
+	// Mapper maps the observation codes between RINEX 2 and RINEX 3, NewObsCodeMapper if nil.
+	// Its Priorities select the signals of RINEX 3 files written as RINEX 2.
+	Mapper *ObsCodeMapper
+
+	// GloSlots are the GLONASS frequency channels for the GLONASS SLOT / FRQ # record, which RINEX 2
+	// files do not have, e.g. NewGloSlots of the broadcast ephemerides.
+	GloSlots GloSlots
+}
+
+// version returns the RINEX version of the output for the input version in.
+func (opts ConvertOptions) version(in float32) float32 {
+	if opts.Version != 0 {
+		return opts.Version
+	}
+	if in >= 3 {
+		return DefaultDowngradeVersion
+	}
+	return DefaultConvertVersion
+}
+
+// ConvertObs converts the observation data of dec between RINEX 2 and RINEX 3 and writes it to w.
+// It returns the number of written epochs.
+//
+// RINEX 2 data is converted to RINEX 3 with the Mapper, observations without a RINEX 3 signal of the
+// system are dropped, e.g. P1 of Galileo. The header is migrated: the observation types are listed per
+// system with observations, the time system is set and the GLONASS slots are taken from the options.
+// The phase shifts and the GLONASS code phase biases of RINEX 2 data are unknown, so blank
+// SYS / PHASE SHIFT and GLONASS COD/PHS/BIS records are written.
+//
+// RINEX 3 data is converted to RINEX 2.11, see convertObsV2.
+//
+// The records PRN / # OF OBS, # OF SATELLITES and TIME OF LAST OBS are computed from the data.
+func ConvertObs(w io.Writer, dec *ObsDecoder, opts ConvertOptions) (int, error) {
+	in := dec.Header.RINEXVersion
+	out := opts.version(in)
+	mapper := opts.Mapper
+	if mapper == nil {
+		mapper = NewObsCodeMapper()
+	}
+	switch {
+	case in >= 3 && out >= 2 && out < 3:
+		return convertObsV2(w, dec, out, mapper)
+	case in >= 3 || out < 3 || out >= 4:
+		return 0, fmt.Errorf("convert: %w: RINEX %.2f to %.2f", ErrUnsupportedVersion, in, out)
+	}
+
+	hdr := editHeader(dec.Header)
+	hdr.RINEXVersion = out
+	// the RINEX 3 codes in the order of the RINEX 2 types, blank if not mapped
+	v3Types := make(map[gnss.System][]string, len(hdr.ObsTypes))
+	for sys, types := range hdr.ObsTypes {
+		aligned := make([]string, len(types))
+		var list []string
+		for i, typ := range types {
+			code, err := mapper.ToV3(sys, typ)
+			if err != nil || indexOf(list, string(code)) >= 0 {
+				continue
+			}
+			aligned[i] = string(code)
+			list = append(list, string(code))
+		}
+		v3Types[sys] = aligned
+		hdr.ObsTypes[sys] = list
+		if len(list) == 0 {
+			delete(hdr.ObsTypes, sys)
+		}
+	}
+	if hdr.TimeSystem == "" {
+		hdr.TimeSystem = "GPS"
+		switch hdr.SatSystem {
+		case gnss.SysGLO:
+			hdr.TimeSystem = "GLO"
+		case gnss.SysGAL:
+			hdr.TimeSystem = "GAL"
+		}
+	}
+	if _, ok := hdr.ObsTypes[gnss.SysGLO]; ok {
+		if len(opts.GloSlots) > 0 {
+			hdr.GloSlots = make(GloSlots, len(opts.GloSlots))
+			for prn, ch := range opts.GloSlots {
+				hdr.GloSlots[prn] = ch
+			}
+		}
+		if hdr.GloCodPhsBis == nil {
+			hdr.GloCodPhsBis = map[string]float64{}
+		}
+	}
+	hdr.addHistory("convert", []string{dec.Name}, fmt.Sprintf("RINEX %.2f to %.2f", in, out))
+
+	enc := NewObsEncoder(w, hdr)
+	n, observed, err := encodeConverted(enc, dec, v3Types)
+	if err != nil {
+		return n, err
+	}
+	if len(observed) > 0 && !observed[gnss.SysGLO] {
+		enc.Header.GloSlots, enc.Header.GloCodPhsBis = nil, nil
+	}
+	enc.Header.PhaseShifts = nil
+	for _, sys := range sortedSystems(enc.Header.ObsTypes) {
+		enc.Header.PhaseShifts = append(enc.Header.PhaseShifts, PhaseShift{Sys: sys})
+	}
+	return n, enc.Flush()
+}
+
+// convertObsV2 converts RINEX 3 observation data to RINEX 2. Systems not defined by RINEX 2.11, e.g. BeiDou,
+// are dropped. If several RINEX 3 codes map onto the same RINEX 2 code, e.g. "C1C" and "C1X" onto "C1",
+// the signal is selected by the mapper's priorities once for the file, so that the observations of a
+// satellite are not switched between signals. As RINEX 2 has one list of observation types for all
+// systems, the observations of a system are blank for the types of other systems.
+func convertObsV2(w io.Writer, dec *ObsDecoder, out float32, mapper *ObsCodeMapper) (int, error) {
+	in := dec.Header.RINEXVersion
+	hdr := editHeader(dec.Header)
+	hdr.RINEXVersion = out
+	hdr.PhaseShifts, hdr.ScaleFactors, hdr.GloSlots, hdr.GloCodPhsBis = nil, nil, nil, nil
+	hdr.DCBSApplied, hdr.PCVSApplied = nil, nil
+	if hdr.TimeSystem != "GLO" && hdr.TimeSystem != "GAL" {
+		hdr.TimeSystem = "GPS" // RINEX 2 has no other time systems
+	}
+
+	// the RINEX 2 codes in the order of the RINEX 3 types, blank if not selected
+	v2Types := make(map[gnss.System][]string, len(hdr.ObsTypes))
+	var list []string
+	for _, sys := range sortedSystems(hdr.ObsTypes) {
+		types := hdr.ObsTypes[sys]
+		delete(hdr.ObsTypes, sys)
+		bands, ok := rinex2Bands[sys]
+		if !ok {
+			continue
+		}
+		codes, selected := mapper.SelectV2(sys, types)
+		aligned := make([]string, len(types))
+		for _, v2 := range codes {
+			if strings.IndexByte(bands, v2[1]) < 0 {
+				continue
+			}
+			aligned[indexOf(types, selected[v2])] = v2
+			if indexOf(list, v2) < 0 {
+				list = append(list, v2)
+			}
+		}
+		v2Types[sys] = aligned
+	}
+	for sys := range v2Types {
+		hdr.ObsTypes[sys] = list // one list for all systems
+	}
+	hdr.addHistory("convert", []string{dec.Name}, fmt.Sprintf("RINEX %.2f to %.2f", in, out))
+
+	enc := NewObsEncoder(w, hdr)
+	n, _, err := encodeConverted(enc, dec, v2Types)
+	if err != nil {
+		return n, err
+	}
+	if len(enc.Header.ObsTypes) == 1 {
+		for sys := range enc.Header.ObsTypes {
+			enc.Header.SatSystem = sys
+		}
+	} else if len(enc.Header.ObsTypes) > 1 {
+		enc.Header.SatSystem = gnss.SysMIXED
+	}
+	return n, enc.Flush()
+}
+
+// encodeConverted encodes the epochs of dec, with the observation types of the satellites replaced by the
+// converted types of their system, aligned with the decoded types. The epochs are buffered until the
+// caller flushes the encoder, the systems without observations are removed from its header before.
+// It returns the number of epochs and the observed systems.
+func encodeConverted(enc *ObsEncoder, dec *ObsDecoder, types map[gnss.System][]string) (int, map[gnss.System]bool, error) {
+	enc.CountObs = true
+	observed := make(map[gnss.System]bool, len(types))
+	n := 0
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		for i := range epo.ObsList {
+			satObs := &epo.ObsList[i]
+			if aligned, ok := types[satObs.Prn.Sys]; ok && len(satObs.Types) == len(aligned) {
+				satObs.Types = aligned
+				observed[satObs.Prn.Sys] = true
+			}
+		}
+		if err := enc.Encode(epo); err != nil {
+			return n, observed, err
+		}
+		n++
+	}
+	if err := dec.Err(); err != nil {
+		return n, observed, fmt.Errorf("read epochs: %w", err)
+	}
+
+	for sys := range enc.Header.ObsTypes {
+		if !observed[sys] && len(observed) > 0 {
+			delete(enc.Header.ObsTypes, sys)
+		}
+	}
+	return n, observed, nil
+}
+
+// ConvertNav converts the RINEX 2 navigation data of dec to RINEX 3 and writes it to w. It returns the
+// number of written ephemerides. The ION ALPHA and ION BETA and the time system corrections of the
+// header are written as IONOSPHERIC CORR and TIME SYSTEM CORR records.
+func ConvertNav(w io.Writer, dec *NavDecoder, opts ConvertOptions) (int, error) {
+	in := dec.Header.RINEXVersion
+	out := opts.version(in)
+	if in >= 3 {
+		return 0, fmt.Errorf("convert: %w: input version %.2f", ErrUnsupportedVersion, in)
+	}
+	if out < 3 || out >= 4 {
+		return 0, fmt.Errorf("convert: %w: output version %.2f", ErrUnsupportedVersion, out)
+	}
+
+	hdr := dec.Header
+	hdr.RINEXVersion, hdr.RINEXType = out, "N"
+	hdr.addHistory("convert", []string{dec.Name}, fmt.Sprintf("RINEX %.2f to %.2f", in, out))
+	bw := bufio.NewWriter(w)
+	if err := hdr.Write(bw); err != nil {
+		return 0, err
+	}
+	n := 0
+	for dec.NextEphemeris() {
+		if _, err := bw.Write(dec.Record()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := dec.Err(); err != nil {
+		return n, fmt.Errorf("read ephemerides: %w", err)
+	}
+	return n, bw.Flush()
+}
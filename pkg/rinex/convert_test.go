@@ -0,0 +1,229 @@
+package rinex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+// rinex2Obs returns a RINEX 2.11 file with an epoch of 12 GPS satellites and R07, and an epoch of G01.
+func rinex2Obs() string {
+	var sb strings.Builder
+	sb.WriteString(`     2.11           OBSERVATION DATA    M (MIXED)           RINEX VERSION / TYPE
+teqc  2019Feb25     BKG                 20200603 08:03:25UTCPGM / RUN BY / DATE
+SIMU                                                        MARKER NAME
+  4231162.7880  -332746.9200  4745130.6890                  APPROX POSITION XYZ
+        0.0000        0.0000        0.0000                  ANTENNA: DELTA H/E/N
+     6    L1    L2    C1    P1    P2    S1                  # / TYPES OF OBSERV
+    30.0000                                                 INTERVAL
+  2020     6     3     7     0    0.0000000     GPS         TIME OF FIRST OBS
+                                                            END OF HEADER
+ 20  6  3  7  0  0.0000000  0 13G01G02G03G04G05G06G07G08G09G10G11G12
+                                R07
+`)
+	for i := 1; i <= 13; i++ {
+		fmt.Fprintf(&sb, "%14.3f 6%14.3f 5%14.3f  %14.3f  %14.3f  \n%14.3f\n", 1e8+float64(i), 8e7, 2e7, 2e7+1, 2e7+2, 45.0)
+	}
+	sb.WriteString(" 20  6  3  7  0 30.0000000  0  1 01\n")
+	fmt.Fprintf(&sb, "%14.3f1 %14.3f  %14.3f\n\n", 1e8, 8e7, 2e7)
+	return sb.String()
+}
+
+func TestConvertObs(t *testing.T) {
+	assert := assert.New(t)
+	dec, err := NewObsDecoder(strings.NewReader(rinex2Obs()))
+	if !assert.NoError(err) {
+		return
+	}
+	dec.Name = "simu1540.20o"
+	mapper := NewObsCodeMapper()
+	mapper.Attributes[gnss.SysGPS]["L2"] = 'L'
+	slots := GloSlots{PRN{Sys: gnss.SysGLO, Num: 7}: 5}
+
+	var buf bytes.Buffer
+	n, err := ConvertObs(&buf, dec, ConvertOptions{Mapper: mapper, GloSlots: slots})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(2, n)
+	out := buf.String()
+	assert.Contains(out, "     3.04           OBSERVATION DATA    M")
+	assert.Contains(out, "G    6 L1C L2L C1C C1W C2W S1C")
+	assert.Contains(out, "R    6 L1C L2P C1C C1P C2P S1C")
+	assert.NotContains(out, "\nE  ", "no Galileo observations")
+	assert.Contains(out, "G                                                           SYS / PHASE SHIFT")
+	assert.Contains(out, "  1 R07  5")
+	assert.Contains(out, "input: simu1540.20o")
+
+	dec, err = NewObsDecoder(&buf)
+	if !assert.NoError(err) {
+		return
+	}
+	hdr := dec.Header
+	assert.Equal(float32(3.04), hdr.RINEXVersion)
+	assert.Equal("GPS", hdr.TimeSystem)
+	assert.Equal(time.Date(2020, 6, 3, 7, 0, 30, 0, time.UTC), hdr.TimeOfLastObs)
+	assert.Equal(13, hdr.NSatellites)
+	assert.Len(hdr.PhaseShifts, 2)
+	assert.Equal(map[string]float64{}, hdr.GloCodPhsBis)
+
+	var epochs []*Epoch
+	for dec.NextEpoch() {
+		epochs = append(epochs, dec.Epoch())
+	}
+	assert.NoError(dec.Err())
+	if !assert.Len(epochs, 2) || !assert.Len(epochs[0].ObsList, 13) {
+		return
+	}
+	r07 := epochs[0].ObsList[12]
+	assert.Equal(PRN{Sys: gnss.SysGLO, Num: 7}, r07.Prn)
+	obs, _ := r07.Get("L1C")
+	assert.Equal(Obs{Val: 1e8 + 13, SNR: 6}, obs)
+	obs, _ = r07.Get("C2P")
+	assert.Equal(2e7+2, obs.Val)
+	obs, _ = r07.Get("S1C")
+	assert.Equal(45.0, obs.Val)
+	obs, _ = epochs[1].ObsList[0].Get("L1C")
+	assert.Equal(Obs{Val: 1e8, LLI: 1}, obs)
+
+	// RINEX 3 input
+	dec, err = NewObsDecoder(strings.NewReader(out))
+	assert.NoError(err)
+	_, err = ConvertObs(&buf, dec, ConvertOptions{Version: 3.05})
+	assert.True(errors.Is(err, ErrUnsupportedVersion), "%v", err)
+}
+
+func TestConvertObs_downgrade(t *testing.T) {
+	assert := assert.New(t)
+	var sb strings.Builder
+	sb.WriteString(`     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+gnss                BKG                 20200603 080325 UTC PGM / RUN BY / DATE
+SIMU                                                        MARKER NAME
+  4231162.7880  -332746.9200  4745130.6890                  APPROX POSITION XYZ
+        0.0000        0.0000        0.0000                  ANTENNA: DELTA H/E/N
+G    6 C1C L1C C2W L2W L2L S1C                              SYS / # / OBS TYPES
+E    3 C1X L1X C5Q                                          SYS / # / OBS TYPES
+C    2 C2I L2I                                              SYS / # / OBS TYPES
+    30.000                                                  INTERVAL
+  2020     6     3     7     0    0.0000000     GPS         TIME OF FIRST OBS
+G L1C  0.00000                                              SYS / PHASE SHIFT
+                                                            END OF HEADER
+> 2020 06 03 07 00  0.0000000  0 15       0.000000123456
+`)
+	for i := 1; i <= 13; i++ {
+		fmt.Fprintf(&sb, "G%02d%14.3f  %14.3f16%14.3f  %14.3f  %14.3f  %14.3f  \n", i, 2e7+float64(i), 1e8, 2e7+1, 8e7, 8e7+1, 45.0)
+	}
+	fmt.Fprintf(&sb, "E11%14.3f  %14.3f  %14.3f\n", 2e7+11, 1e8+11, 2e7+12)
+	fmt.Fprintf(&sb, "C06%14.3f  %14.3f\n", 2e7+6, 1e8+6)
+
+	dec, err := NewObsDecoder(strings.NewReader(sb.String()))
+	if !assert.NoError(err) {
+		return
+	}
+	mapper := NewObsCodeMapper()
+	mapper.Priorities[gnss.SysGPS]['2'] = "LW"
+	var buf bytes.Buffer
+	n, err := ConvertObs(&buf, dec, ConvertOptions{Mapper: mapper})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(1, n)
+	out := buf.String()
+	assert.Contains(out, "     2.11           OBSERVATION DATA    M")
+	assert.Contains(out, "     6    C1    L1    P2    L2    S1    C5                  # / TYPES OF OBSERV")
+	assert.Contains(out, "     1     1                                                WAVELENGTH FACT L1/2")
+	assert.NotContains(out, "PHASE SHIFT")
+	assert.Contains(out, " 20  6  3  7  0  0.0000000  0 14G01G02G03G04G05G06G07G08G09G10G11G12 0.000000123\n"+
+		"                                G13E11\n")
+
+	dec, err = NewObsDecoder(&buf)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(float32(2.11), dec.Header.RINEXVersion)
+	assert.Equal(14, dec.Header.NSatellites)
+	assert.Equal([]int{1, 1, 0, 0, 0, 1}, dec.Header.ObsPerSat[PRN{Sys: gnss.SysGAL, Num: 11}])
+	if !assert.True(dec.NextEpoch()) {
+		return
+	}
+	epo := dec.Epoch()
+	assert.InDelta(1.23e-7, epo.ClockOffset, 1e-12)
+	if !assert.Len(epo.ObsList, 14) {
+		return
+	}
+	g01 := epo.ObsList[0]
+	obs, _ := g01.Get("L1")
+	assert.Equal(Obs{Val: 1e8, LLI: 1, SNR: 6}, obs)
+	obs, _ = g01.Get("L2")
+	assert.Equal(8e7+1, obs.Val, "L2L preferred")
+	obs, _ = g01.Get("P2")
+	assert.Equal(2e7+1, obs.Val)
+	e11 := epo.ObsList[13]
+	obs, _ = e11.Get("C5")
+	assert.Equal(2e7+12, obs.Val)
+	assert.False(dec.NextEpoch())
+	assert.NoError(dec.Err())
+
+	// same version
+	dec, err = NewObsDecoder(strings.NewReader(sb.String()))
+	assert.NoError(err)
+	_, err = ConvertObs(&buf, dec, ConvertOptions{Version: 3.04})
+	assert.True(errors.Is(err, ErrUnsupportedVersion), "%v", err)
+}
+
+func TestConvertNav(t *testing.T) {
+	assert := assert.New(t)
+	// navDataG20 in RINEX 2
+	var sb strings.Builder
+	sb.WriteString(`     2.11           N: GPS NAV DATA                         RINEX VERSION / TYPE
+teqc  2019Feb25     BKG                 20200618 08:03:25UTCPGM / RUN BY / DATE
+    0.1118D-07  0.7451D-08 -0.5960D-07 -0.5960D-07          ION ALPHA
+    0.9011D+05  0.4915D+05 -0.1311D+06 -0.3277D+06          ION BETA
+    0.349245965481D-09-0.115463194561D-13   503808     2110 DELTA-UTC: A0,A1,T,W
+    18                                                      LEAP SECONDS
+                                                            END OF HEADER
+20 20  6 18  0  0  0.0 5.274894647300D-04-1.136868377216D-13 0.000000000000D+00
+`)
+	lines := strings.Split(navDataG20, "\n")
+	for _, line := range lines[5:] {
+		if line != "" {
+			sb.WriteString(strings.Replace(line[1:], "E", "D", -1) + "\n")
+		}
+	}
+
+	dec, err := NewNavDecoder(strings.NewReader(sb.String()))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(gnss.SysGPS, dec.Header.SatSystem)
+	var buf bytes.Buffer
+	n, err := ConvertNav(&buf, dec, ConvertOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(1, n)
+	assert.Contains(buf.String(), "GPUT  3.4924596548E-10-1.154631946E-14 503808 2110")
+	assert.Contains(buf.String(), navDataG20[strings.Index(navDataG20, "G20 "):])
+
+	dec, err = NewNavDecoder(&buf)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(float32(3.04), dec.Header.RINEXVersion)
+	assert.Equal(18, dec.Header.LeapSeconds)
+	assert.Equal([4]float64{0.9011e5, 0.4915e5, -0.1311e6, -0.3277e6}, dec.Header.IonoCorr["GPSB"])
+	assert.Equal([]TimeSysCorr{{Type: "GPUT", A0: 3.4924596548e-10, A1: -1.154631946e-14, RefTime: 503808, RefWeek: 2110}},
+		dec.Header.TimeSysCorrs)
+
+	want, err := NewNavDecoder(strings.NewReader(navDataG20))
+	assert.NoError(err)
+	if assert.True(dec.NextEphemeris()) && assert.True(want.NextEphemeris()) {
+		assert.Equal(want.Ephemeris(), dec.Ephemeris())
+	}
+}
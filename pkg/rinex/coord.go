@@ -0,0 +1,138 @@
+package rinex
+
+import (
+	"math"
+)
+
+// Ellipsoid defines a reference ellipsoid by its semi-major axis and flattening.
+type Ellipsoid struct {
+	A float64 // semi-major axis [m]
+	F float64 // flattening
+}
+
+// Reference ellipsoids.
+var (
+	GRS80 = Ellipsoid{A: 6378137.0, F: 1 / 298.257222101}
+	WGS84 = Ellipsoid{A: 6378137.0, F: 1 / 298.257223563}
+)
+
+// e2 returns the squared first eccentricity.
+func (ell Ellipsoid) e2() float64 {
+	return ell.F * (2 - ell.F)
+}
+
+// LatLonHeight defines a geodetic coordinate.
+type LatLonHeight struct {
+	Lat, Lon float64 // latitude and longitude in degrees
+	Height   float64 // ellipsoidal height in [m]
+}
+
+// LatLonHeight converts the XYZ coordinate into geodetic latitude, longitude and height on the given ellipsoid.
+func (c Coord) LatLonHeight(ell Ellipsoid) LatLonHeight {
+	e2 := ell.e2()
+	p := math.Hypot(c.X, c.Y)
+	lon := math.Atan2(c.Y, c.X)
+
+	if p < 1e-9 { // at the poles
+		lat := math.Copysign(math.Pi/2, c.Z)
+		b := ell.A * (1 - ell.F)
+		return LatLonHeight{Lat: rad2deg(lat), Lon: rad2deg(lon), Height: math.Abs(c.Z) - b}
+	}
+
+	lat := math.Atan2(c.Z, p*(1-e2))
+	var h float64
+	for i := 0; i < 10; i++ {
+		sinLat := math.Sin(lat)
+		n := ell.A / math.Sqrt(1-e2*sinLat*sinLat)
+		h = p/math.Cos(lat) - n
+		latNew := math.Atan2(c.Z, p*(1-e2*n/(n+h)))
+		if math.Abs(latNew-lat) < 1e-12 {
+			lat = latNew
+			break
+		}
+		lat = latNew
+	}
+	return LatLonHeight{Lat: rad2deg(lat), Lon: rad2deg(lon), Height: h}
+}
+
+// Coord converts the geodetic coordinate on the given ellipsoid into a XYZ coordinate.
+func (g LatLonHeight) Coord(ell Ellipsoid) Coord {
+	lat, lon := deg2rad(g.Lat), deg2rad(g.Lon)
+	e2 := ell.e2()
+	sinLat, cosLat := math.Sincos(lat)
+	n := ell.A / math.Sqrt(1-e2*sinLat*sinLat)
+	return Coord{
+		X: (n + g.Height) * cosLat * math.Cos(lon),
+		Y: (n + g.Height) * cosLat * math.Sin(lon),
+		Z: (n*(1-e2) + g.Height) * sinLat,
+	}
+}
+
+// SurfaceDistance returns the great circle distance in [m] between two points on a sphere
+// with the mean earth radius. The heights are ignored.
+func (g LatLonHeight) SurfaceDistance(g2 LatLonHeight) float64 {
+	const earthRadius = 6371000.0
+	lat1, lat2 := deg2rad(g.Lat), deg2rad(g2.Lat)
+	dLat := lat2 - lat1
+	dLon := deg2rad(g2.Lon - g.Lon)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Sub returns the vector c-c2.
+func (c Coord) Sub(c2 Coord) Coord {
+	return Coord{X: c.X - c2.X, Y: c.Y - c2.Y, Z: c.Z - c2.Z}
+}
+
+// Distance returns the euclidean distance in [m] between c and c2.
+func (c Coord) Distance(c2 Coord) float64 {
+	d := c.Sub(c2)
+	return math.Sqrt(d.X*d.X + d.Y*d.Y + d.Z*d.Z)
+}
+
+// NEU returns the topocentric North, East, Up coordinates of c relative to the reference point ref.
+// The local frame is defined by the geodetic latitude and longitude of ref on GRS80.
+func (c Coord) NEU(ref Coord) CoordNEU {
+	g := ref.LatLonHeight(GRS80)
+	sinLat, cosLat := math.Sincos(deg2rad(g.Lat))
+	sinLon, cosLon := math.Sincos(deg2rad(g.Lon))
+	d := c.Sub(ref)
+	return CoordNEU{
+		N:  -sinLat*cosLon*d.X - sinLat*sinLon*d.Y + cosLat*d.Z,
+		E:  -sinLon*d.X + cosLon*d.Y,
+		Up: cosLat*cosLon*d.X + cosLat*sinLon*d.Y + sinLat*d.Z,
+	}
+}
+
+// Coord converts the topocentric coordinates relative to the reference point ref into a XYZ coordinate.
+func (neu CoordNEU) Coord(ref Coord) Coord {
+	g := ref.LatLonHeight(GRS80)
+	sinLat, cosLat := math.Sincos(deg2rad(g.Lat))
+	sinLon, cosLon := math.Sincos(deg2rad(g.Lon))
+	return Coord{
+		X: ref.X - sinLat*cosLon*neu.N - sinLon*neu.E + cosLat*cosLon*neu.Up,
+		Y: ref.Y - sinLat*sinLon*neu.N + cosLon*neu.E + cosLat*sinLon*neu.Up,
+		Z: ref.Z + cosLat*neu.N + sinLat*neu.Up,
+	}
+}
+
+// AzEl returns the azimuth and elevation in degrees of the target, e.g. a satellite, seen from c.
+// The azimuth is counted clockwise from north in the range [0,360).
+func (c Coord) AzEl(target Coord) (az, el float64) {
+	neu := target.NEU(c)
+	hor := math.Hypot(neu.N, neu.E)
+	az = rad2deg(math.Atan2(neu.E, neu.N))
+	if az < 0 {
+		az += 360
+	}
+	el = rad2deg(math.Atan2(neu.Up, hor))
+	return
+}
+
+func deg2rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func rad2deg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,53 @@
+package rinex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoord_LatLonHeight(t *testing.T) {
+	assert := assert.New(t)
+	wtzr := Coord{X: 4075580.3849, Y: 931853.9604, Z: 4801568.2245}
+
+	g := wtzr.LatLonHeight(GRS80)
+	assert.InDelta(49.144199, g.Lat, 1e-5)
+	assert.InDelta(12.878907, g.Lon, 1e-5)
+	assert.InDelta(666.0, g.Height, 0.05)
+
+	c := g.Coord(GRS80)
+	assert.InDelta(wtzr.X, c.X, 1e-6)
+	assert.InDelta(wtzr.Y, c.Y, 1e-6)
+	assert.InDelta(wtzr.Z, c.Z, 1e-6)
+
+	pole := Coord{Z: 6356752.3141}.LatLonHeight(GRS80)
+	assert.InDelta(90.0, pole.Lat, 1e-9)
+	assert.InDelta(0.0, pole.Height, 1e-3)
+}
+
+func TestCoord_NEU(t *testing.T) {
+	assert := assert.New(t)
+	ref := Coord{X: 4075580.3849, Y: 931853.9604, Z: 4801568.2245}
+
+	neu := CoordNEU{N: 10, E: -5, Up: 2}
+	c := neu.Coord(ref)
+	assert.InDelta(ref.Distance(c), 11.3578, 1e-4)
+
+	neu2 := c.NEU(ref)
+	assert.InDelta(neu.N, neu2.N, 1e-9)
+	assert.InDelta(neu.E, neu2.E, 1e-9)
+	assert.InDelta(neu.Up, neu2.Up, 1e-9)
+
+	az, el := ref.AzEl(CoordNEU{N: 0, E: 100, Up: 100}.Coord(ref))
+	assert.InDelta(90.0, az, 1e-9)
+	assert.InDelta(45.0, el, 1e-9)
+
+	az, _ = ref.AzEl(CoordNEU{N: 1, E: -1}.Coord(ref))
+	assert.InDelta(315.0, az, 1e-6)
+}
+
+func TestLatLonHeight_SurfaceDistance(t *testing.T) {
+	frankfurt := LatLonHeight{Lat: 50.09, Lon: 8.66}
+	wettzell := LatLonHeight{Lat: 49.14, Lon: 12.88}
+	assert.InDelta(t, 321000, frankfurt.SurfaceDistance(wettzell), 2000)
+}
@@ -0,0 +1,97 @@
+package rinex
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// TypeInfo describes a RINEX file as detected from its content.
+type TypeInfo struct {
+	Version   float32     // RINEX version
+	Type      string      // file type: O, N, M or C
+	SatSystem gnss.System // satellite system, SysMIXED for mixed files, 0 if not given, e.g. for meteo files
+	Hatanaka  bool        // Hatanaka compressed (compact RINEX)
+}
+
+// rnx2NavSystems maps the RINEX 2 navigation file types to their satellite system.
+var rnx2NavSystems = map[string]gnss.System{"N": gnss.SysGPS, "G": gnss.SysGLO, "H": gnss.SysSBAS}
+
+// DetectType inspects the first header lines of a RINEX file and returns its version, file type,
+// satellite system and whether it is Hatanaka compressed. The filename is not needed.
+// gzip and .Z compressed data is decompressed on the fly.
+func DetectType(r io.Reader) (TypeInfo, error) {
+	var info TypeInfo
+	rd, err := NewDecompressReader(r)
+	if err != nil {
+		return info, err
+	}
+	defer rd.Close()
+
+	sc := newLineReader(rd, DefaultMaxLineLength)
+	line, err := nextLine(sc)
+	if err != nil {
+		return info, err
+	}
+	if strings.Contains(line, "CRINEX VERS   / TYPE") {
+		info.Hatanaka = true
+		if _, err := nextLine(sc); err != nil { // CRINEX PROG / DATE
+			return info, err
+		}
+		if line, err = nextLine(sc); err != nil {
+			return info, err
+		}
+	}
+
+	// clock files in version 3.04 have the label in column 65
+	idx := strings.Index(line, "RINEX VERSION / TYPE")
+	if idx < 41 {
+		return info, ErrNoHeader
+	}
+	val := line[:idx]
+	f64, err := strconv.ParseFloat(strings.TrimSpace(val[:20]), 32)
+	if err != nil {
+		return info, fmt.Errorf("parsing RINEX VERSION: %w", err)
+	}
+	info.Version = float32(f64)
+
+	typ, sys := strings.TrimSpace(val[20:21]), strings.TrimSpace(val[40:41])
+	switch {
+	case typ == "O":
+		if sys == "" && info.Version < 3 {
+			sys = "G" // blank means GPS
+		}
+	case info.Version < 3 && rnx2NavSystems[typ] != 0:
+		info.SatSystem = rnx2NavSystems[typ]
+		typ = "N"
+	case typ == "N" || typ == "M" || typ == "C":
+	default:
+		return info, fmt.Errorf("invalid RINEX type: %q", typ)
+	}
+	info.Type = typ
+	if sys != "" {
+		s, ok := gnss.SystemByAbbr(sys)
+		if !ok {
+			return info, fmt.Errorf("invalid satellite system: %q", sys)
+		}
+		info.SatSystem = s
+	}
+	if info.Hatanaka && info.Type != "O" {
+		return info, fmt.Errorf("invalid RINEX type for Hatanaka compressed files: %q", info.Type)
+	}
+	return info, nil
+}
+
+// nextLine returns the next line of sc. io.ErrUnexpectedEOF is returned at the end of the data.
+func nextLine(sc *lineReader) (string, error) {
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	return sc.Text(), nil
+}
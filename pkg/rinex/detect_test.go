@@ -0,0 +1,89 @@
+package rinex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		path string
+		want TypeInfo
+	}{
+		{"testdata/white/brst155h.20o", TypeInfo{Version: 2.11, Type: "O", SatSystem: gnss.SysMIXED}},
+		{"testdata/white/brst155h.20d", TypeInfo{Version: 2.11, Type: "O", SatSystem: gnss.SysMIXED, Hatanaka: true}},
+		{"testdata/white/BRUX00BEL_R_20202302000_01H_30S_MO.crx", TypeInfo{Version: 3.04, Type: "O", SatSystem: gnss.SysMIXED, Hatanaka: true}},
+		{"testdata/white/AREG00PER_R_20201690000_01D_MN.rnx", TypeInfo{Version: 3.04, Type: "N", SatSystem: gnss.SysMIXED}},
+		{"testdata/white/DIEP00DEU_R_20202941900_01H_10S_MM.rnx", TypeInfo{Version: 3.04, Type: "M"}},
+		{"testdata/compress/DIEP00DEU_R_20202941900_01H_10S_MM.rnx.Z", TypeInfo{Version: 3.04, Type: "M"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			f, err := os.Open(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := DetectType(f)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetectType_header(t *testing.T) {
+	assert := assert.New(t)
+	detect := func(line string) (TypeInfo, error) {
+		return DetectType(strings.NewReader(line + "\n"))
+	}
+
+	info, err := detect("     2.11           OBSERVATION DATA                        RINEX VERSION / TYPE")
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 2.11, Type: "O", SatSystem: gnss.SysGPS}, info, "blank system is GPS")
+
+	info, err = detect("     2.11           G: GLONASS NAV DATA                     RINEX VERSION / TYPE")
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 2.11, Type: "N", SatSystem: gnss.SysGLO}, info)
+
+	info, err = detect("     2.11           H: GEO NAV MSG DATA                     RINEX VERSION / TYPE")
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 2.11, Type: "N", SatSystem: gnss.SysSBAS}, info)
+
+	info, err = detect("     3.04           N: GNSS NAV DATA    E: GALILEO          RINEX VERSION / TYPE")
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 3.04, Type: "N", SatSystem: gnss.SysGAL}, info)
+
+	info, err = detect(clkData300[:strings.Index(clkData300, "\n")])
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 3, Type: "C", SatSystem: gnss.SysGPS}, info)
+
+	info, err = detect("     3.04           C                   M                        RINEX VERSION / TYPE")
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 3.04, Type: "C", SatSystem: gnss.SysMIXED}, info, "label in column 65")
+
+	// gzip
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE\n"))
+	gz.Close()
+	info, err = DetectType(&buf)
+	assert.NoError(err)
+	assert.Equal(TypeInfo{Version: 3.04, Type: "O", SatSystem: gnss.SysMIXED}, info)
+
+	_, err = detect("     3.04           X                   M                   RINEX VERSION / TYPE")
+	assert.Error(err)
+	_, err = detect("     3.04           O                   X                   RINEX VERSION / TYPE")
+	assert.Error(err)
+	_, err = detect("some text")
+	assert.Equal(ErrNoHeader, err)
+	_, err = DetectType(strings.NewReader(""))
+	assert.Error(err)
+	_, err = detect("1.0                 COMPACT RINEX FORMAT                    CRINEX VERS   / TYPE")
+	assert.Error(err)
+}
@@ -0,0 +1,125 @@
+package rinex
+
+import (
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// DoubleDiff is the between-satellite, between-receiver double difference of an observation type:
+// (rover(Sat) - base(Sat)) - (rover(Ref) - base(Ref)).
+type DoubleDiff struct {
+	Time time.Time
+	Type string  // observation type, e.g. L1C
+	Ref  PRN     // reference satellite
+	Sat  PRN     // satellite
+	Val  float64 // in the unit of the observation type, i.e. meters for codes and cycles for phases
+	Slip bool    // loss of lock indicated for one of the four observations
+}
+
+// DoubleDiffBuilder forms the double differences of the code and phase observations of two receivers.
+// The reference satellite of each system and observation type is the one with the highest elevation
+// as seen from the base, which requires the EphemerisStore and the Base position. Otherwise or for satellites
+// without ephemeris the lowest PRN is used. GLONASS FDMA phases are not differenced, their wavelengths
+// differ between the satellites.
+//
+//	ddb := &rinex.DoubleDiffBuilder{EphemerisStore: ephs, Base: dec.Header.Position}
+//	for dec.SyncWith(dec2) {
+//		dds := ddb.Build(dec.SyncEpoch())
+//		...
+//	}
+type DoubleDiffBuilder struct {
+	EphemerisStore *EphemerisStore
+	Base           Coord   // approximate position of the base
+	ElevationMask  float64 // satellites below the cutoff angle in degrees are skipped
+}
+
+// Build returns the double differences of the synchronized epochs of the base (Epo1) and rover (Epo2),
+// sorted by system, observation type and satellite.
+func (b *DoubleDiffBuilder) Build(epochs SyncEpochs) []DoubleDiff {
+	base, rover := epochs.Epo1, epochs.Epo2
+	if base == nil || rover == nil {
+		return nil
+	}
+	var azel map[PRN]AzEl
+	if b.EphemerisStore != nil && b.Base != (Coord{}) {
+		azel = b.EphemerisStore.AzEl(base, b.Base)
+	}
+	roverObs := make(map[PRN]SatObs, len(rover.ObsList))
+	for _, satObs := range rover.ObsList {
+		roverObs[satObs.Prn] = satObs
+	}
+
+	// single differences per system and observation type
+	type sysType struct {
+		sys gnss.System
+		typ string
+	}
+	type singleDiff struct {
+		prn  PRN
+		val  float64
+		slip bool
+	}
+	sds := make(map[sysType][]singleDiff)
+	for _, obs1 := range base.ObsList {
+		if ae, ok := azel[obs1.Prn]; ok && ae.El < b.ElevationMask {
+			continue
+		}
+		obs2, ok := roverObs[obs1.Prn]
+		if !ok {
+			continue
+		}
+		for i, typ := range obs1.Types {
+			code := ObsCode(typ)
+			if i >= len(obs1.Obss) || (code.Type() != 'C' && code.Type() != 'L') {
+				continue
+			}
+			if info, _ := code.Lookup(obs1.Prn.Sys); code.Type() == 'L' && info.FDMA {
+				continue
+			}
+			o2, ok := obs2.Get(typ)
+			o1 := obs1.Obss[i]
+			if !ok || o1.Val == 0 || o2.Val == 0 {
+				continue
+			}
+			key := sysType{obs1.Prn.Sys, typ}
+			sds[key] = append(sds[key], singleDiff{prn: obs1.Prn, val: o2.Val - o1.Val, slip: o1.LLI&1 != 0 || o2.LLI&1 != 0})
+		}
+	}
+
+	var dds []DoubleDiff
+	for key, sd := range sds {
+		if len(sd) < 2 {
+			continue
+		}
+		sort.Slice(sd, func(i, j int) bool { return sd[i].prn.Num < sd[j].prn.Num })
+		ref := 0
+		for i := range sd {
+			ae, ok := azel[sd[i].prn]
+			if !ok {
+				continue
+			}
+			if aeRef, okRef := azel[sd[ref].prn]; !okRef || ae.El > aeRef.El {
+				ref = i
+			}
+		}
+		for i := range sd {
+			if i == ref {
+				continue
+			}
+			dds = append(dds, DoubleDiff{Time: base.Time, Type: key.typ, Ref: sd[ref].prn, Sat: sd[i].prn,
+				Val: sd[i].val - sd[ref].val, Slip: sd[i].slip || sd[ref].slip})
+		}
+	}
+	sort.Slice(dds, func(i, j int) bool {
+		if dds[i].Sat.Sys != dds[j].Sat.Sys {
+			return dds[i].Sat.Sys < dds[j].Sat.Sys
+		}
+		if dds[i].Type != dds[j].Type {
+			return dds[i].Type < dds[j].Type
+		}
+		return dds[i].Sat.Num < dds[j].Sat.Num
+	})
+	return dds
+}
@@ -0,0 +1,59 @@
+package rinex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoubleDiffBuilder_Build(t *testing.T) {
+	assert := assert.New(t)
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	g05, g20, g25 := PRN{Sys: gnss.SysGPS, Num: 5}, PRN{Sys: gnss.SysGPS, Num: 20}, PRN{Sys: gnss.SysGPS, Num: 25}
+	r01, r02 := PRN{Sys: gnss.SysGLO, Num: 1}, PRN{Sys: gnss.SysGLO, Num: 2}
+	base := &Epoch{Time: toc, ObsList: []SatObs{
+		NewSatObs(g05, map[string]Obs{"C1C": {Val: 100}, "L1C": {Val: 1000}}),
+		NewSatObs(g20, map[string]Obs{"C1C": {Val: 200}, "L1C": {Val: 2000}}),
+		NewSatObs(g25, map[string]Obs{"C1C": {Val: 300}, "L1C": {Val: 3000}, "S1C": {Val: 45}}),
+		NewSatObs(r01, map[string]Obs{"C1C": {Val: 100}, "L1C": {Val: 1000}}),
+		NewSatObs(r02, map[string]Obs{"C1C": {Val: 200}, "L1C": {Val: 2000}}),
+	}}
+	rover := &Epoch{Time: toc, ObsList: []SatObs{
+		NewSatObs(g25, map[string]Obs{"C1C": {Val: 330}, "L1C": {Val: 3001, LLI: 1}, "S1C": {Val: 40}}),
+		NewSatObs(g20, map[string]Obs{"C1C": {Val: 205}, "L1C": {Val: 0}}),
+		NewSatObs(g05, map[string]Obs{"C1C": {Val: 110}, "L1C": {Val: 1003}}),
+		NewSatObs(r01, map[string]Obs{"C1C": {Val: 101}, "L1C": {Val: 1001}}),
+		NewSatObs(r02, map[string]Obs{"C1C": {Val: 203}, "L1C": {Val: 2003}}),
+	}}
+
+	// reference satellite with the lowest PRN
+	ddb := &DoubleDiffBuilder{}
+	dds := ddb.Build(SyncEpochs{base, rover})
+	assert.Equal([]DoubleDiff{
+		{Time: toc, Type: "C1C", Ref: g05, Sat: g20, Val: -5},
+		{Time: toc, Type: "C1C", Ref: g05, Sat: g25, Val: 20},
+		{Time: toc, Type: "L1C", Ref: g05, Sat: g25, Val: -2, Slip: true},
+		{Time: toc, Type: "C1C", Ref: r01, Sat: r02, Val: 2},
+	}, dds)
+
+	// reference satellite with the highest elevation, the base is below G20
+	navDec, err := NewNavDecoder(strings.NewReader(navDataG20))
+	assert.NoError(err)
+	ephs, err := NewEphemerides(navDec)
+	assert.NoError(err)
+	pos, err := ephs.SatPos(g20, toc)
+	assert.NoError(err)
+	g := pos.LatLonHeight(GRS80)
+	g.Height = 0
+	ddb = &DoubleDiffBuilder{EphemerisStore: ephs, Base: g.Coord(GRS80)}
+	dds = ddb.Build(SyncEpochs{base, rover})
+	if assert.Len(dds, 4) {
+		assert.Equal(DoubleDiff{Time: toc, Type: "C1C", Ref: g20, Sat: g05, Val: 5}, dds[0])
+		assert.Equal(DoubleDiff{Time: toc, Type: "C1C", Ref: g20, Sat: g25, Val: 25}, dds[1])
+	}
+
+	assert.Nil(ddb.Build(SyncEpochs{Epo1: base}))
+}
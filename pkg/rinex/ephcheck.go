@@ -0,0 +1,142 @@
+package rinex
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ephCheckInterval      = time.Minute // minimum time between the epochs checked for not observed satellites
+	ephCheckElevationMask = 10.0        // default cutoff angle in degrees for not observed satellites
+)
+
+// SatEpochs is the number of epochs of a satellite.
+type SatEpochs struct {
+	PRN    PRN       `json:"-"`
+	Sat    string    `json:"sat"` // e.g. G01
+	Epochs int       `json:"epochs"`
+	First  time.Time `json:"first"`
+	Last   time.Time `json:"last"`
+}
+
+// EphemerisCheck is the result of the cross-check of the observed satellites and the broadcast ephemerides.
+// Satellites that are observed without ephemeris point to an incomplete collection of the navigation data,
+// satellites that are above the elevation mask but not observed to receiver problems, like too few channels.
+type EphemerisCheck struct {
+	SatEpochs int         `json:"satEpochs"` // number of observed satellite epochs
+	Missing   []SatEpochs `json:"missing"`   // observed satellites without valid ephemeris, per satellite
+
+	// NotObserved are the GPS satellites with a healthy ephemeris that are above the elevation mask but not
+	// observed, checked at most once per minute. Only set if the header has a position, satellite positions
+	// are only computed for GPS.
+	NotObserved   []SatEpochs `json:"notObserved"`
+	ElevationMask float64     `json:"elevationMask"`
+
+	OK bool `json:"ok"` // no satellite missing or not observed
+}
+
+// CheckEphemerides cross-checks the satellites observed in the epochs of the decoder with the decoder's
+// EphemerisStore. It reports the satellites observed without a valid ephemeris and, if the header
+// has a position, the GPS satellites above the decoder's elevation mask, or 10 degrees if not set, that
+// have an ephemeris but are not observed.
+func CheckEphemerides(dec *ObsDecoder) (*EphemerisCheck, error) {
+	if dec.EphemerisStore == nil {
+		return nil, errors.New("ephemeris check needs ephemerides")
+	}
+	ec := newEphemerisChecker(dec)
+	for dec.NextEpoch() {
+		ec.add(dec.Epoch())
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read epochs: %w", err)
+	}
+	return ec.result(), nil
+}
+
+// ephemerisChecker collects the satellite epochs without ephemeris and the not observed ones.
+type ephemerisChecker struct {
+	ephs      *EphemerisStore
+	marker    Coord
+	mask      float64
+	last      time.Time
+	satEpochs int
+	missing   map[PRN]*SatEpochs
+	notObs    map[PRN]*SatEpochs
+}
+
+func newEphemerisChecker(dec *ObsDecoder) *ephemerisChecker {
+	mask := dec.Opts.ElevationMask
+	if mask <= 0 {
+		mask = ephCheckElevationMask
+	}
+	return &ephemerisChecker{ephs: dec.EphemerisStore, marker: dec.Header.Position, mask: mask,
+		missing: make(map[PRN]*SatEpochs), notObs: make(map[PRN]*SatEpochs)}
+}
+
+func (ec *ephemerisChecker) add(epo *Epoch) {
+	if epo.IsEvent() || epo.IsCycleSlip() {
+		return
+	}
+	observed := make(map[PRN]bool, len(epo.ObsList))
+	for _, satObs := range epo.ObsList {
+		observed[satObs.Prn] = true
+		ec.satEpochs++
+		if !ec.ephs.Has(satObs.Prn, epo.Time) {
+			countSatEpoch(ec.missing, satObs.Prn, epo.Time)
+		}
+	}
+
+	if ec.marker == (Coord{}) || !ec.last.IsZero() && epo.Time.Sub(ec.last) < ephCheckInterval {
+		return
+	}
+	ec.last = epo.Time
+	for _, prn := range ec.ephs.Satellites() {
+		if observed[prn] {
+			continue
+		}
+		pos, err := ec.ephs.SatPos(prn, epo.Time)
+		if err != nil {
+			continue
+		}
+		if _, el := ec.marker.AzEl(pos); el >= ec.mask {
+			countSatEpoch(ec.notObs, prn, epo.Time)
+		}
+	}
+}
+
+func (ec *ephemerisChecker) result() *EphemerisCheck {
+	chk := &EphemerisCheck{SatEpochs: ec.satEpochs, Missing: sortedSatEpochs(ec.missing), NotObserved: sortedSatEpochs(ec.notObs)}
+	if ec.marker != (Coord{}) {
+		chk.ElevationMask = ec.mask
+	}
+	chk.OK = len(chk.Missing) == 0 && len(chk.NotObserved) == 0
+	return chk
+}
+
+// countSatEpoch counts the epoch of the satellite.
+func countSatEpoch(counts map[PRN]*SatEpochs, prn PRN, t time.Time) {
+	c, ok := counts[prn]
+	if !ok {
+		c = &SatEpochs{PRN: prn, Sat: prn.String(), First: t}
+		counts[prn] = c
+	}
+	c.Epochs++
+	c.Last = t
+}
+
+// sortedSatEpochs returns the counts sorted by satellite, an empty slice for none.
+func sortedSatEpochs(counts map[PRN]*SatEpochs) []SatEpochs {
+	list := make([]SatEpochs, 0, len(counts))
+	for _, c := range counts {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].PRN.Sys != list[j].PRN.Sys {
+			return list[i].PRN.Sys < list[j].PRN.Sys
+		}
+		return list[i].PRN.Num < list[j].PRN.Num
+	})
+	return list
+}
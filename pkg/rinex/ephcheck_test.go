@@ -0,0 +1,113 @@
+package rinex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+// simulatedConstellation returns a store with satellites derived from navDataG20 by shifting the orbit,
+// and a marker on the ground below G20.
+func simulatedConstellation(t *testing.T) (*EphemerisStore, Coord) {
+	t.Helper()
+	dec, err := NewNavDecoder(strings.NewReader(navDataG20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dec.NextEphemeris() {
+		t.Fatal(dec.Err())
+	}
+	g20 := dec.Ephemeris().(*EphGPS)
+	ephs := NewEphemerisStore()
+	for i, shift := range [][2]float64{{0, 0}, {0.4, 0}, {-0.4, 0}, {0, 0.5}, {0, -0.5}, {0.3, 0.4}, {-0.3, -0.4}} {
+		eph := *g20
+		eph.PRN = PRN{Sys: gnss.SysGPS, Num: int8(i + 1)}
+		eph.M0 += shift[0]
+		eph.Omega0 += shift[1]
+		ephs.Add(&eph)
+	}
+	g := g20.Position(g20.TOC).LatLonHeight(GRS80)
+	g.Height = 100
+	return ephs, g.Coord(GRS80)
+}
+
+// simulateEpoch returns an epoch with the geometric C1C ranges of all satellites of the store, observed at marker.
+func simulateEpoch(ephs *EphemerisStore, marker Coord, t time.Time) *Epoch {
+	epo := &Epoch{Time: t}
+	for _, prn := range ephs.Satellites() {
+		pos, err := ephs.SatPos(prn, t)
+		if err != nil {
+			continue
+		}
+		epo.ObsList = append(epo.ObsList, NewSatObs(prn, map[string]Obs{"C1C": {Val: marker.Distance(pos)}}))
+	}
+	return epo
+}
+
+func TestCheckEphemerides(t *testing.T) {
+	assert := assert.New(t)
+	ephs, marker := simulatedConstellation(t)
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	g03, g09 := PRN{Sys: gnss.SysGPS, Num: 3}, PRN{Sys: gnss.SysGPS, Num: 9}
+
+	// G03 is not tracked, G09 is tracked without ephemeris
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, ObsHeader{RINEXVersion: 3.04, RINEXType: "O", SatSystem: gnss.SysGPS,
+		MarkerName: "SIMU", Interval: 30, Position: marker, ObsTypes: map[gnss.System][]string{gnss.SysGPS: {"C1C"}}})
+	for i := 0; i < 10; i++ {
+		epo := simulateEpoch(ephs, marker, toc.Add(time.Duration(i)*30*time.Second))
+		obsList := epo.ObsList[:0]
+		for _, satObs := range epo.ObsList {
+			if satObs.Prn != g03 {
+				obsList = append(obsList, satObs)
+			}
+		}
+		epo.ObsList = append(obsList, NewSatObs(g09, map[string]Obs{"C1C": {Val: 2.2e7}}))
+		assert.NoError(enc.Encode(epo))
+	}
+	assert.NoError(enc.Flush())
+	data := buf.Bytes()
+
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	_, err = CheckEphemerides(dec)
+	assert.Error(err, "no ephemerides")
+
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.EphemerisStore = ephs
+	chk, err := CheckEphemerides(dec)
+	if assert.NoError(err) {
+		assert.False(chk.OK)
+		assert.Equal(70, chk.SatEpochs)
+		assert.Equal(10.0, chk.ElevationMask)
+		assert.Equal([]SatEpochs{{PRN: g09, Sat: "G09", Epochs: 10, First: toc, Last: toc.Add(270 * time.Second)}}, chk.Missing)
+		assert.Equal([]SatEpochs{{PRN: g03, Sat: "G03", Epochs: 5, First: toc, Last: toc.Add(4 * time.Minute)}}, chk.NotObserved)
+	}
+
+	// without position only the missing ephemerides are checked
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.Header.Position = Coord{}
+	dec.EphemerisStore = ephs
+	chk, err = CheckEphemerides(dec)
+	if assert.NoError(err) {
+		assert.Len(chk.Missing, 1)
+		assert.Empty(chk.NotObserved)
+		assert.Zero(chk.ElevationMask)
+	}
+
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.EphemerisStore = ephs
+	rep, err := NewReport(dec)
+	assert.NoError(err)
+	var out bytes.Buffer
+	assert.NoError(rep.Write(&out, ReportText))
+	assert.Contains(out.String(), "Ephemerides:   CHECK, 1 satellites without ephemeris, 1 not observed\n")
+	assert.Contains(out.String(), "  no ephemeris: G09 10 epochs, 2020-06-18 00:00:00 - 2020-06-18 00:04:30\n")
+}
@@ -0,0 +1,272 @@
+package rinex
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// EphemerisStore collects the broadcast ephemerides of RINEX navigation files, RTCM or receiver streams
+// and serves the best ephemeris for an epoch, e.g. for satellite positions, azimuth and elevation or
+// single point positioning. The ephemerides are stored per satellite and issue of data. An ephemeris with
+// the IODE and clock reference epoch of a stored one is an update and replaces it.
+// Currently only GPS ephemerides are supported for positions, of the other systems only the clock reference
+// epochs are kept to tell whether a satellite has an ephemeris, see Has.
+//
+// It is safe for concurrent use, so that a stream can add ephemerides while others are computing positions.
+// Long running streams should call Prune regularly.
+type EphemerisStore struct {
+	mu   sync.RWMutex
+	ephs map[PRN][]*EphGPS   // sorted by TOC
+	tocs map[PRN][]time.Time // the clock reference epochs of the other systems, sorted
+}
+
+// ephValidity is the time an ephemeris of the systems other than GPS is valid before and after its clock
+// reference epoch, as in RTKLIB. GPS ephemerides have a fit interval, see EphGPS.Validity.
+var ephValidity = map[gnss.System]time.Duration{
+	gnss.SysGLO:   30 * time.Minute,
+	gnss.SysGAL:   4 * time.Hour,
+	gnss.SysQZSS:  2 * time.Hour,
+	gnss.SysBDS:   6 * time.Hour,
+	gnss.SysIRNSS: 2 * time.Hour,
+	gnss.SysSBAS:  6 * time.Minute,
+}
+
+// NewEphemerisStore returns an empty store.
+func NewEphemerisStore() *EphemerisStore {
+	return &EphemerisStore{ephs: make(map[PRN][]*EphGPS, 32)}
+}
+
+// NewEphemerides reads all ephemerides from the navigation decoder.
+func NewEphemerides(dec *NavDecoder) (*EphemerisStore, error) {
+	e := NewEphemerisStore()
+	if err := e.Load(dec); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Load adds all ephemerides of the navigation decoder.
+func (e *EphemerisStore) Load(dec *NavDecoder) error {
+	for dec.NextEphemeris() {
+		e.Add(dec.Ephemeris())
+	}
+	return dec.Err()
+}
+
+// Add adds the ephemeris and reports whether the store changed, i.e. the ephemeris is new or
+// an update of a stored one. Of the systems other than GPS only the clock reference epoch is added.
+func (e *EphemerisStore) Add(eph Eph) bool {
+	var prn PRN
+	var toc time.Time
+	switch eph := eph.(type) {
+	case *EphGPS:
+		if eph != nil {
+			return e.addGPS(eph)
+		}
+		return false
+	case *EphGLO:
+		prn, toc = eph.PRN, eph.TOC
+	case *EphGAL:
+		prn, toc = eph.PRN, eph.TOC
+	case *EphQZSS:
+		prn, toc = eph.PRN, eph.TOC
+	case *EphBDS:
+		prn, toc = eph.PRN, eph.TOC
+	case *EphIRNSS:
+		prn, toc = eph.PRN, eph.TOC
+	case *EphSBAS:
+		prn, toc = eph.PRN, eph.TOC
+	default:
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.tocs == nil {
+		e.tocs = make(map[PRN][]time.Time, 64)
+	}
+	list := e.tocs[prn]
+	i := sort.Search(len(list), func(i int) bool { return !list[i].Before(toc) })
+	if i < len(list) && list[i].Equal(toc) {
+		return false
+	}
+	list = append(list, time.Time{})
+	copy(list[i+1:], list[i:])
+	list[i] = toc
+	e.tocs[prn] = list
+	return true
+}
+
+func (e *EphemerisStore) addGPS(gps *EphGPS) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ephs == nil {
+		e.ephs = make(map[PRN][]*EphGPS, 32)
+	}
+
+	list := e.ephs[gps.PRN]
+	i := sort.Search(len(list), func(i int) bool { return list[i].TOC.After(gps.TOC) })
+	for j := i - 1; j >= 0 && list[j].TOC.Equal(gps.TOC); j-- {
+		if list[j].IODE != gps.IODE {
+			continue
+		}
+		if *list[j] == *gps {
+			return false
+		}
+		list[j] = gps
+		return true
+	}
+	list = append(list, nil)
+	copy(list[i+1:], list[i:])
+	list[i] = gps
+	e.ephs[gps.PRN] = list
+	return true
+}
+
+// Find returns the healthy ephemeris of the satellite with the clock reference epoch closest to t
+// that is valid at t. Of two equally close ephemerides the last added one wins.
+func (e *EphemerisStore) Find(prn PRN, t time.Time) (*EphGPS, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var best *EphGPS
+	var bestDiff time.Duration
+	for _, eph := range e.ephs[prn] {
+		if eph.Health != 0 {
+			continue
+		}
+		from, to := eph.Validity()
+		if t.Before(from) || t.After(to) {
+			continue
+		}
+		diff := t.Sub(eph.TOC)
+		if diff < 0 {
+			diff = -diff
+		}
+		if best == nil || diff <= bestDiff {
+			best, bestDiff = eph, diff
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no valid ephemeris for %s at %s", prn, t.Format(time.RFC3339))
+	}
+	return best, nil
+}
+
+// Has reports whether the store has an ephemeris of the satellite that is valid at t, regardless of
+// the health of the satellite.
+func (e *EphemerisStore) Has(prn PRN, t time.Time) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if prn.Sys == gnss.SysGPS {
+		for _, eph := range e.ephs[prn] {
+			if from, to := eph.Validity(); !t.Before(from) && !t.After(to) {
+				return true
+			}
+		}
+		return false
+	}
+	list, valid := e.tocs[prn], ephValidity[prn.Sys]
+	i := sort.Search(len(list), func(i int) bool { return !list[i].Before(t.Add(-valid)) })
+	return i < len(list) && !list[i].After(t.Add(valid))
+}
+
+// SatPos returns the position of the satellite in the earth-fixed frame at the GPS time t.
+func (e *EphemerisStore) SatPos(prn PRN, t time.Time) (Coord, error) {
+	eph, err := e.Find(prn, t)
+	if err != nil {
+		return Coord{}, err
+	}
+	return eph.Position(t), nil
+}
+
+// AzEl returns the azimuth and elevation of all satellites of the epoch as seen from the marker.
+// Satellites without a valid ephemeris are missing in the returned map.
+func (e *EphemerisStore) AzEl(epo *Epoch, marker Coord) map[PRN]AzEl {
+	azel := make(map[PRN]AzEl, len(epo.ObsList))
+	for _, satObs := range epo.ObsList {
+		pos, err := e.SatPos(satObs.Prn, epo.Time)
+		if err != nil {
+			continue
+		}
+		az, el := marker.AzEl(pos)
+		azel[satObs.Prn] = AzEl{Az: az, El: el}
+	}
+	return azel
+}
+
+// Prune removes the ephemerides whose validity ended before t and returns their number.
+func (e *EphemerisStore) Prune(t time.Time) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for prn, list := range e.ephs {
+		kept := list[:0]
+		for _, eph := range list {
+			if _, to := eph.Validity(); to.Before(t) {
+				n++
+				continue
+			}
+			kept = append(kept, eph)
+		}
+		if len(kept) == 0 {
+			delete(e.ephs, prn)
+			continue
+		}
+		e.ephs[prn] = kept
+	}
+	for prn, list := range e.tocs {
+		valid := ephValidity[prn.Sys]
+		i := sort.Search(len(list), func(i int) bool { return !list[i].Add(valid).Before(t) })
+		n += i
+		if i == len(list) {
+			delete(e.tocs, prn)
+			continue
+		}
+		e.tocs[prn] = list[i:]
+	}
+	return n
+}
+
+// Satellites returns the satellites with ephemerides, sorted by PRN.
+func (e *EphemerisStore) Satellites() []PRN {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	prns := make([]PRN, 0, len(e.ephs)+len(e.tocs))
+	for prn := range e.ephs {
+		prns = append(prns, prn)
+	}
+	for prn := range e.tocs {
+		prns = append(prns, prn)
+	}
+	sortPRNs(prns)
+	return prns
+}
+
+// Len returns the number of stored ephemerides.
+func (e *EphemerisStore) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	n := 0
+	for _, list := range e.ephs {
+		n += len(list)
+	}
+	for _, list := range e.tocs {
+		n += len(list)
+	}
+	return n
+}
+
+// Validity returns the interval in which the ephemeris is valid, the fit interval centered at the
+// clock reference epoch. If the fit interval is not set, it defaults to 4 hours.
+func (eph *EphGPS) Validity() (from, to time.Time) {
+	fit := eph.FitInterval
+	if fit <= 0 {
+		fit = defaultFitHr
+	}
+	half := time.Duration(fit / 2 * float64(time.Hour))
+	return eph.TOC.Add(-half), eph.TOC.Add(half)
+}
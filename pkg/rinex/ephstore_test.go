@@ -0,0 +1,126 @@
+package rinex
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEphemerisStore(t *testing.T) {
+	assert := assert.New(t)
+	g20 := PRN{Sys: gnss.SysGPS, Num: 20}
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	e := NewEphemerisStore()
+
+	eph1 := &EphGPS{PRN: g20, TOC: toc, IODE: 83, FitInterval: 4}
+	eph2 := &EphGPS{PRN: g20, TOC: toc.Add(2 * time.Hour), IODE: 84}
+	assert.True(e.Add(eph2))
+	assert.True(e.Add(eph1))
+	assert.False(e.Add(&EphGPS{PRN: g20, TOC: toc, IODE: 83, FitInterval: 4}), "duplicate")
+	assert.Equal(2, e.Len())
+
+	tests := []struct {
+		t    time.Time
+		want *EphGPS
+	}{
+		{toc.Add(-2 * time.Hour), eph1},
+		{toc.Add(59 * time.Minute), eph1},
+		{toc.Add(time.Hour), eph2}, // equally close, prefer the newer one
+		{toc.Add(4 * time.Hour), eph2},
+		{toc.Add(4*time.Hour + time.Second), nil},
+	}
+	for _, tt := range tests {
+		got, err := e.Find(g20, tt.t)
+		if tt.want == nil {
+			assert.Error(err, "%s", tt.t)
+			continue
+		}
+		if assert.NoError(err) {
+			assert.Same(tt.want, got, "%s", tt.t)
+		}
+	}
+
+	// an update with the same issue of data replaces the stored ephemeris
+	unhealthy := *eph2
+	unhealthy.Health = 1
+	assert.True(e.Add(&unhealthy))
+	assert.Equal(2, e.Len())
+	got, err := e.Find(g20, toc.Add(time.Hour))
+	assert.NoError(err)
+	assert.Same(eph1, got)
+
+	// a new issue of data is kept besides
+	eph3 := &EphGPS{PRN: g20, TOC: toc.Add(2 * time.Hour), IODE: 85}
+	assert.True(e.Add(eph3))
+	got, err = e.Find(g20, toc.Add(3*time.Hour))
+	assert.NoError(err)
+	assert.Same(eph3, got)
+
+	assert.True(e.Add(&EphGPS{PRN: PRN{Sys: gnss.SysGPS, Num: 3}, TOC: toc}))
+	assert.Equal([]PRN{{Sys: gnss.SysGPS, Num: 3}, g20}, e.Satellites())
+	assert.Equal(2, e.Prune(toc.Add(2*time.Hour+time.Second)))
+	assert.Equal([]PRN{g20}, e.Satellites())
+	assert.Equal(2, e.Len())
+}
+
+func TestEphemerisStore_Has(t *testing.T) {
+	assert := assert.New(t)
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	g20, r01 := PRN{Sys: gnss.SysGPS, Num: 20}, PRN{Sys: gnss.SysGLO, Num: 1}
+	e := NewEphemerisStore()
+	assert.True(e.Add(&EphGPS{PRN: g20, TOC: toc, Health: 1}))
+	assert.True(e.Add(&EphGLO{PRN: r01, TOC: toc}))
+	assert.True(e.Add(&EphGLO{PRN: r01, TOC: toc.Add(time.Hour)}))
+	assert.False(e.Add(&EphGLO{PRN: r01, TOC: toc}), "duplicate")
+	assert.Equal(3, e.Len())
+
+	assert.True(e.Has(g20, toc.Add(time.Hour)), "unhealthy")
+	assert.False(e.Has(g20, toc.Add(3*time.Hour)))
+	assert.True(e.Has(r01, toc.Add(-30*time.Minute)))
+	assert.True(e.Has(r01, toc.Add(90*time.Minute)))
+	assert.False(e.Has(r01, toc.Add(91*time.Minute)))
+	assert.False(e.Has(PRN{Sys: gnss.SysGAL, Num: 1}, toc))
+	_, err := e.Find(r01, toc)
+	assert.Error(err, "no positions of GLONASS")
+
+	assert.Equal(1, e.Prune(toc.Add(31*time.Minute)))
+	assert.False(e.Has(r01, toc))
+	assert.True(e.Has(r01, toc.Add(time.Hour)))
+}
+
+func TestEphemerisStore_concurrent(t *testing.T) {
+	g20 := PRN{Sys: gnss.SysGPS, Num: 20}
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	e := &EphemerisStore{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			e.Add(&EphGPS{PRN: g20, TOC: toc.Add(time.Duration(i) * time.Hour), IODE: float64(i)})
+			e.Prune(toc.Add(time.Duration(i-10) * time.Hour))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			e.Find(g20, toc.Add(time.Duration(i)*time.Hour))
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, 13, e.Len())
+}
+
+func TestEphGPS_Validity(t *testing.T) {
+	assert := assert.New(t)
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	from, to := (&EphGPS{TOC: toc}).Validity()
+	assert.Equal(toc.Add(-2*time.Hour), from)
+	assert.Equal(toc.Add(2*time.Hour), to)
+	from, to = (&EphGPS{TOC: toc, FitInterval: 6}).Validity()
+	assert.Equal(toc.Add(-3*time.Hour), from)
+	assert.Equal(toc.Add(3*time.Hour), to)
+}
@@ -0,0 +1,108 @@
+package rinex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CountryLookup returns the ISO 3166 alpha-3 country code for the four character station ID, e.g. BEL for BRUX.
+// It may also return the complete nine character ID, e.g. BRUX00BEL, to set the monument and receiver numbers.
+type CountryLookup func(fourCharID string) (string, error)
+
+// CountryMap maps four character station IDs to country codes or nine character IDs.
+// Its Lookup method can be used as CountryLookup.
+type CountryMap map[string]string
+
+// Lookup returns the country code for the station.
+func (m CountryMap) Lookup(fourCharID string) (string, error) {
+	if cc, ok := m[strings.ToUpper(fourCharID)]; ok {
+		return cc, nil
+	}
+	return "", fmt.Errorf("no country code for station %s", fourCharID)
+}
+
+// FilenameConverter converts filenames between the RINEX 2 and the RINEX 3 convention,
+// e.g. brux3100.18o to BRUX00BEL_R_20183100000_01D_30S_MO.rnx and vice versa.
+// The compression extension, e.g. .gz, is not part of the converted names.
+type FilenameConverter struct {
+	// Country returns the country code of the station, it is required for the conversion to RINEX 3.
+	Country CountryLookup
+
+	// DataSource is the data source of the RINEX 3 name: R for receiver, S for stream or U for unknown.
+	// It defaults to R.
+	DataSource string
+}
+
+// ToRnx3 returns the RINEX 3 filename for the RINEX 2 file. RINEX 3 names are returned without the
+// compression extension. The directory of name is ignored.
+func (c FilenameConverter) ToRnx3(name string) (string, error) {
+	fi, err := ParseFilename(name)
+	if err != nil {
+		return "", err
+	}
+	rnx := fi.rnxFil(name)
+	if fi.NamingVersion == 2 {
+		if c.Country == nil {
+			return "", fmt.Errorf("no country lookup")
+		}
+		id, err := c.Country(rnx.FourCharID)
+		if err != nil {
+			return "", err
+		}
+		switch len(id) {
+		case 3:
+			rnx.CountryCode = strings.ToUpper(id)
+		case 9:
+			if !strings.EqualFold(id[:4], rnx.FourCharID) {
+				return "", fmt.Errorf("station %s does not match %s", id, rnx.FourCharID)
+			}
+			if err := rnx.SetStationName(id); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("invalid country code %q for station %s", id, rnx.FourCharID)
+		}
+		rnx.DataSource = c.DataSource
+		if rnx.DataSource == "" {
+			rnx.DataSource = "R"
+		}
+	}
+
+	switch {
+	case rnx.IsObsType():
+		return (&ObsFile{RnxFil: rnx}).Rnx3Filename()
+	case rnx.IsNavType():
+		return (&NavFile{RnxFil: rnx}).Rnx3Filename()
+	case rnx.IsMeteoType():
+		return (&MeteoFile{RnxFil: rnx}).Rnx3Filename()
+	}
+	return "", fmt.Errorf("no valid RINEX filename: %s", name)
+}
+
+// ToRnx2 returns the RINEX 2 filename for the RINEX 3 file. RINEX 2 names are returned without the
+// compression extension. The directory of name is ignored.
+func (c FilenameConverter) ToRnx2(name string) (string, error) {
+	fi, err := ParseFilename(name)
+	if err != nil {
+		return "", err
+	}
+	return fi.rnxFil(name).Rnx2Filename()
+}
+
+// rnxFil returns a RnxFil with the fields of the file info.
+func (fi FileInfo) rnxFil(path string) *RnxFil {
+	return &RnxFil{
+		Path:           path,
+		FourCharID:     fi.FourCharID,
+		MonumentNumber: fi.MonumentNumber,
+		ReceiverNumber: fi.ReceiverNumber,
+		CountryCode:    fi.CountryCode,
+		StartTime:      fi.StartTime,
+		DataSource:     fi.DataSource,
+		FilePeriod:     fi.FilePeriod,
+		DataFreq:       fi.DataFreq,
+		DataType:       fi.DataType,
+		Format:         fi.Format,
+		Compression:    fi.Compression,
+	}
+}
@@ -0,0 +1,60 @@
+package rinex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilenameConverter(t *testing.T) {
+	assert := assert.New(t)
+	conv := FilenameConverter{Country: CountryMap{"BRUX": "BEL", "WTZR": "WTZR00DEU", "ADIS": "ETH", "BAD1": "XY"}.Lookup}
+	tests := []struct {
+		rnx2, rnx3 string
+	}{
+		{"brux3100.18o", "BRUX00BEL_R_20183100000_01D_30S_MO.rnx"},
+		{"brux310t.18d", "BRUX00BEL_R_20183101900_01H_30S_MO.crx"},
+		{"adis240e15.19d", "ADIS00ETH_R_20192400415_15M_01S_MO.crx"},
+		{"wtzr1550.20n", "WTZR00DEU_R_20201550000_01D_GN.rnx"},
+		{"wtzr1550.20m", "WTZR00DEU_R_20201550000_01D_30S_MM.rnx"},
+	}
+	for _, tt := range tests {
+		got, err := conv.ToRnx3("/archive/" + tt.rnx2 + ".Z")
+		assert.NoError(err, tt.rnx2)
+		assert.Equal(tt.rnx3, got, tt.rnx2)
+
+		got, err = conv.ToRnx2(tt.rnx3 + ".gz")
+		assert.NoError(err, tt.rnx3)
+		assert.Equal(tt.rnx2, got, tt.rnx3)
+	}
+
+	_, err := conv.ToRnx3("zzzz3100.18o")
+	assert.Error(err, "unknown station")
+	_, err = conv.ToRnx3("bad13100.18o")
+	assert.Error(err, "invalid country code")
+	_, err = FilenameConverter{}.ToRnx3("brux3100.18o")
+	assert.Error(err, "no lookup")
+
+	conv.DataSource = "S"
+	got, err := conv.ToRnx3("brux3100.18o")
+	assert.NoError(err)
+	assert.Equal("BRUX00BEL_S_20183100000_01D_30S_MO.rnx", got)
+}
+
+// Convert archive filenames with a station list.
+func ExampleFilenameConverter() {
+	countries := CountryMap{"BRUX": "BEL", "WTZR": "DEU"}
+	conv := FilenameConverter{Country: countries.Lookup}
+	for _, name := range []string{"brux3100.18o.Z", "wtzr310a.18d.Z"} {
+		rnx3, err := conv.ToRnx3(name)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(rnx3)
+	}
+	// Output:
+	// BRUX00BEL_R_20183100000_01D_30S_MO.rnx
+	// WTZR00DEU_R_20183100000_01H_30S_MO.crx
+}
@@ -0,0 +1,115 @@
+package rinex
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/antex"
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// timeSysPerSys is the default time system of the TIME OF FIRST OBS per satellite system.
+var timeSysPerSys = map[gnss.System]string{
+	gnss.SysGPS:   "GPS",
+	gnss.SysGLO:   "GLO",
+	gnss.SysGAL:   "GAL",
+	gnss.SysBDS:   "BDT",
+	gnss.SysQZSS:  "QZS",
+	gnss.SysIRNSS: "IRN",
+	gnss.SysSBAS:  "GPS",
+	gnss.SysMIXED: "GPS",
+}
+
+// Normalize cleans up the header as data centers do before archiving the files: the text fields are
+// trimmed, the receiver and antenna types are upper case and the antenna type is in the IGS notation
+// with the radome, see antex.NormalizeType. 4-char and 9-char marker names are upper case.
+// The satellite system is set from the observation types and a missing time system from the satellite system.
+func (hdr *ObsHeader) Normalize() {
+	for _, s := range []*string{&hdr.Pgm, &hdr.RunBy, &hdr.MarkerName, &hdr.MarkerNumber, &hdr.MarkerType,
+		&hdr.Observer, &hdr.Agency, &hdr.ReceiverNumber, &hdr.ReceiverType, &hdr.ReceiverVersion,
+		&hdr.AntennaNumber, &hdr.AntennaType} {
+		*s = strings.TrimSpace(*s)
+	}
+	if n := len(hdr.MarkerName); (n == 4 || n == 9) && !strings.Contains(hdr.MarkerName, " ") {
+		hdr.MarkerName = strings.ToUpper(hdr.MarkerName)
+	}
+	hdr.ReceiverType = strings.ToUpper(hdr.ReceiverType)
+	hdr.AntennaType = antex.NormalizeType(strings.ToUpper(hdr.AntennaType))
+	for i, c := range hdr.Comments {
+		hdr.Comments[i] = strings.TrimRight(c, " ")
+	}
+
+	switch len(hdr.ObsTypes) {
+	case 0:
+	case 1:
+		for sys := range hdr.ObsTypes {
+			hdr.SatSystem = sys
+		}
+	default:
+		hdr.SatSystem = gnss.SysMIXED
+	}
+	if hdr.TimeSystem == "" {
+		hdr.TimeSystem = timeSysPerSys[hdr.SatSystem]
+	}
+}
+
+// FixHeader rewrites the file with the header hdr, e.g. to correct the marker name, the receiver and antenna
+// or the approximate position. The data records are copied unchanged, so the observation types must not change.
+// The History comments are added to the header. Only uncompressed files on the local disk are supported.
+func (f *ObsFile) FixHeader(hdr ObsHeader) error {
+	if f.FS != nil {
+		if _, ok := f.FS.(LocalFS); !ok {
+			return fmt.Errorf("fix header: %s: file not on the local disk", f.Path)
+		}
+	}
+	if IsCompressed(f.Path) || f.IsHatanakaCompressed() {
+		return fmt.Errorf("fix header: %s: compressed files are not supported", f.Path)
+	}
+
+	r, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	dec, err := NewObsDecoder(r)
+	if err != nil {
+		return fmt.Errorf("fix header: %s: %w", f.Path, err)
+	}
+	if !reflect.DeepEqual(dec.Header.ObsTypes, hdr.ObsTypes) {
+		return fmt.Errorf("fix header: %s: the observation types must not change", f.Path)
+	}
+	if _, err := r.Seek(dec.dataStart, io.SeekStart); err != nil {
+		return err
+	}
+
+	hdr.addHistory("fix header", []string{filepath.Base(f.Path)})
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), ".fixheader-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = hdr.Write(tmp)
+	if err == nil {
+		_, err = io.Copy(tmp, r)
+	}
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("fix header: %s: %w", f.Path, err)
+	}
+	if fi, err := os.Stat(f.Path); err == nil {
+		os.Chmod(tmp.Name(), fi.Mode())
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return err
+	}
+	f.Header = hdr
+	return nil
+}
@@ -0,0 +1,75 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsHeader_Normalize(t *testing.T) {
+	assert := assert.New(t)
+	hdr := ObsHeader{
+		MarkerName:   " reyk00isl ",
+		ReceiverType: "septentrio polarx5",
+		AntennaType:  "lear25.r4 leit",
+		Comments:     []string{"comment   "},
+		ObsTypes:     map[gnss.System][]string{gnss.SysGPS: {"C1C"}, gnss.SysGAL: {"C1C"}},
+	}
+	hdr.Normalize()
+	assert.Equal("REYK00ISL", hdr.MarkerName)
+	assert.Equal("SEPTENTRIO POLARX5", hdr.ReceiverType)
+	assert.Equal("LEAR25.R4       LEIT", hdr.AntennaType)
+	assert.Equal([]string{"comment"}, hdr.Comments)
+	assert.Equal(gnss.SysMIXED, hdr.SatSystem)
+	assert.Equal("GPS", hdr.TimeSystem)
+
+	hdr = ObsHeader{MarkerName: "Wettzell 1", AntennaType: "TRM59800.00", ObsTypes: map[gnss.System][]string{gnss.SysGAL: {"C1C"}}}
+	hdr.Normalize()
+	assert.Equal("Wettzell 1", hdr.MarkerName)
+	assert.Equal("TRM59800.00     NONE", hdr.AntennaType)
+	assert.Equal(gnss.SysGAL, hdr.SatSystem)
+	assert.Equal("GAL", hdr.TimeSystem)
+}
+
+func TestObsFile_FixHeader(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	path := filepath.Join(t.TempDir(), filepath.Base(reykFile))
+	assert.NoError(ioutil.WriteFile(path, data, 0644))
+
+	obsFil, err := NewObsFile(path)
+	assert.NoError(err)
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	hdr := dec.Header
+	hdr.MarkerNumber = "10202M001"
+	hdr.Position = Coord{X: 2587384.0, Y: -1043033.5, Z: 5716564.0}
+	hdr.AntennaType = "LEIAR25.R4"
+	hdr.Normalize()
+	assert.NoError(obsFil.FixHeader(hdr))
+
+	fixed, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+	dec, err = NewObsDecoder(bytes.NewReader(fixed))
+	assert.NoError(err)
+	assert.Equal("10202M001", dec.Header.MarkerNumber)
+	assert.Equal("LEIAR25.R4      NONE", dec.Header.AntennaType)
+	assert.Equal(hdr.Position, dec.Header.Position)
+	assert.Equal(epochStrings(t, data), epochStrings(t, fixed))
+	body := func(b []byte) []byte {
+		b = b[bytes.Index(b, []byte("END OF HEADER")):]
+		return b[bytes.IndexByte(b, '\n'):]
+	}
+	assert.Equal(body(data), body(fixed))
+
+	hdr.ObsTypes = map[gnss.System][]string{gnss.SysGPS: {"C1C"}}
+	assert.Error(obsFil.FixHeader(hdr), "observation types changed")
+	gz, err := NewObsFile(path + ".gz")
+	assert.NoError(err)
+	assert.Error(gz.FixHeader(hdr), "compressed file")
+}
@@ -0,0 +1,63 @@
+package rinex
+
+import (
+	"io"
+	"time"
+)
+
+// FollowOptions configures the follow mode of the decoders, where the decoding waits for data appended
+// to a growing file, like tail -f, instead of stopping at the end of the input.
+// Real-time converters often write their RINEX files incrementally.
+type FollowOptions struct {
+	// PollInterval is the time between the checks for new data. Zero means 1 second.
+	PollInterval time.Duration
+
+	// IdleTimeout stops the decoding with EOF if no new data arrived for this duration.
+	// Zero means to wait forever.
+	IdleTimeout time.Duration
+
+	// Done stops the waiting with EOF when closed, e.g. set it to ctx.Done().
+	Done <-chan struct{}
+}
+
+// followReader is a reader that waits for new data at EOF.
+type followReader struct {
+	r    io.Reader
+	opts FollowOptions
+}
+
+// NewFollowReader returns a reader that does not return EOF at the end of r, but polls r for
+// new data until the IdleTimeout expires or Done is closed.
+func NewFollowReader(r io.Reader, opts FollowOptions) io.Reader {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	return &followReader{r: r, opts: opts}
+}
+
+func (fr *followReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	var idle time.Duration
+	for {
+		n, err := fr.r.Read(p)
+		if n > 0 || (err != nil && err != io.EOF) {
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+		if fr.opts.IdleTimeout > 0 && idle >= fr.opts.IdleTimeout {
+			return 0, io.EOF
+		}
+		t := time.NewTimer(fr.opts.PollInterval)
+		select {
+		case <-t.C:
+		case <-fr.opts.Done:
+			t.Stop()
+			return 0, io.EOF
+		}
+		idle += fr.opts.PollInterval
+	}
+}
@@ -0,0 +1,68 @@
+package rinex
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsDecoder_Follow(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := ioutil.TempFile("", "follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// the converter writes the file in chunks that split the lines
+	chunk := len(data) / 7
+	go func() {
+		for i := 0; i < len(data); i += chunk {
+			end := i + chunk
+			if end > len(data) {
+				end = len(data)
+			}
+			tmp.Write(data[i:end])
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	r, err := os.Open(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dec, err := NewObsDecoderWithOptions(r, DecoderOptions{
+		Follow: &FollowOptions{PollInterval: 5 * time.Millisecond, IdleTimeout: 200 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("REYK", dec.Header.MarkerName[:4])
+	n := 0
+	for dec.NextEpoch() {
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(120, n)
+}
+
+func TestFollowReader_Done(t *testing.T) {
+	assert := assert.New(t)
+	done := make(chan struct{})
+	r := NewFollowReader(strings.NewReader(""), FollowOptions{PollInterval: time.Millisecond, Done: done})
+	time.AfterFunc(20*time.Millisecond, func() { close(done) })
+	start := time.Now()
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(err)
+	assert.Len(b, 0)
+	assert.True(time.Since(start) >= 20*time.Millisecond)
+}
@@ -0,0 +1,72 @@
+package rinex
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Filesystem is the storage of RINEX files, e.g. the local disk or an object store like S3.
+// Set RnxFil.FS to read the files of other storages than the local disk.
+type Filesystem interface {
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+
+	// Create creates or truncates the named file. The data may not be stored before Close returns.
+	Create(name string) (io.WriteCloser, error)
+
+	// Stat returns the FileInfo of the named file.
+	Stat(name string) (os.FileInfo, error)
+
+	// Remove removes the named file.
+	Remove(name string) error
+}
+
+// LocalFS is the Filesystem of the local disk.
+type LocalFS struct{}
+
+// Open implements Filesystem.
+func (LocalFS) Open(name string) (io.ReadCloser, error) { return os.Open(name) }
+
+// Create implements Filesystem.
+func (LocalFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+
+// Stat implements Filesystem.
+func (LocalFS) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+// Remove implements Filesystem.
+func (LocalFS) Remove(name string) error { return os.Remove(name) }
+
+// OpenFileFS opens the named file of fsys for reading and decompresses it on the fly like OpenFile.
+// Zip archives are only supported on the local disk. A nil fsys is the local disk.
+func OpenFileFS(fsys Filesystem, name string) (io.ReadCloser, error) {
+	if fsys == nil {
+		fsys = LocalFS{}
+	}
+	if _, ok := fsys.(LocalFS); ok {
+		return OpenFile(name)
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewDecompressReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &multiCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+// fs returns the filesystem of the file.
+func (f *RnxFil) fs() Filesystem {
+	if f.FS == nil {
+		return LocalFS{}
+	}
+	return f.FS
+}
+
+// open opens the file for reading, compressed files are decompressed.
+func (f *RnxFil) open() (io.ReadCloser, error) {
+	return OpenFileFS(f.FS, f.Path)
+}
@@ -0,0 +1,83 @@
+package rinex
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+)
+
+// fuzzSeed returns the first n lines of the file as seed for the fuzz corpus.
+func fuzzSeed(f *testing.F, path string, n int) []byte {
+	f.Helper()
+	r, err := os.Open(path)
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	sc := bufio.NewScanner(r)
+	for i := 0; i < n && sc.Scan(); i++ {
+		buf.Write(sc.Bytes())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func FuzzObsDecoder(f *testing.F) {
+	f.Add(fuzzSeed(f, reykFile, 120))
+	f.Add(fuzzSeed(f, "testdata/white/BRUX00BEL_R_20183101900_01H_30S_MO.rnx", 120))
+	f.Add([]byte(`     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2020 11 14 00 00  0.0000000  4  2
+                                                            COMMENT
+  2020    11    14     0     0    0.0000000     GPS         TIME OF FIRST OBS
+> 2020 11 14 00 00  0.0000000  0  1
+G05  22783244.880   119729271.83308
+`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, lenient := range []bool{false, true} {
+			dec, err := NewObsDecoder(bytes.NewReader(data), WithDecoderOptions(DecoderOptions{MaxLineLength: 4096}))
+			if err != nil {
+				return
+			}
+			dec.Lenient = lenient
+			for dec.NextEpoch() {
+			}
+		}
+	})
+}
+
+func FuzzNavDecoder(f *testing.F) {
+	f.Add(fuzzSeed(f, "testdata/white/AREG00PER_R_20201690000_01D_MN.rnx", 120))
+	f.Add(fuzzSeed(f, "testdata/white/DIEP00DEU_R_20202941900_01H_10S_MM.rnx", 60))
+	f.Add([]byte(`     4.00           N: GNSS NAV DATA    M: MIXED            RINEX VERSION / TYPE
+                                                            END OF HEADER
+> EPH M01 LNAV
+M01 2020 06 18 00 00 00 5.274894647300E-04-1.136868377216E-13 0.000000000000E+00
+> EPH G20 LNAV
+G20 2020 06 18 00 00 00 5.274894647300E-04-1.136868377216E-13 0.000000000000E+00
+     8.300000000000E+01 2.078125000000E+01 5.373438110980E-09-2.252452975616E+00
+`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dec, err := NewNavDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxLineLength: 4096})
+		if err != nil {
+			return
+		}
+		for dec.NextEphemeris() {
+		}
+	})
+}
+
+func FuzzClkDecoder(f *testing.F) {
+	f.Add([]byte(clkData300))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dec, err := NewClkDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxLineLength: 4096})
+		if err != nil {
+			return
+		}
+		for dec.NextRecord() {
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package rinex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Gap is a data gap, i.e. a period with missing epochs.
+type Gap struct {
+	Start         time.Time // last epoch before the gap
+	End           time.Time // first epoch after the gap
+	MissingEpochs int
+}
+
+// GapReport is the result of the gap analysis of observation epochs.
+type GapReport struct {
+	Interval       time.Duration // sampling interval used for the analysis
+	Start, End     time.Time     // first and last epoch
+	NumEpochs      int           // number of epochs found
+	ExpectedEpochs int           // number of epochs expected between Start and End
+	Gaps           []Gap
+	Availability   float64 // percentage of the expected epochs found
+}
+
+// MissingEpochs returns the total number of missing epochs.
+func (r GapReport) MissingEpochs() int {
+	n := 0
+	for _, gap := range r.Gaps {
+		n += gap.MissingEpochs
+	}
+	return n
+}
+
+// Gaps returns the data gaps of the epoch times. If the sampling interval is zero, it is detected as the most
+// frequent difference between consecutive epochs. The times must be in ascending order, duplicates are ignored.
+func Gaps(times []time.Time, interval time.Duration) GapReport {
+	var r GapReport
+	if len(times) == 0 {
+		return r
+	}
+	if interval <= 0 {
+		interval = detectInterval(times)
+	}
+	r.Interval = interval
+	r.Start, r.End = times[0], times[len(times)-1]
+	r.NumEpochs = 1
+	for i := 1; i < len(times); i++ {
+		d := times[i].Sub(times[i-1])
+		if d <= 0 {
+			continue
+		}
+		r.NumEpochs++
+		if interval <= 0 || d < interval*3/2 {
+			continue
+		}
+		r.Gaps = append(r.Gaps, Gap{Start: times[i-1], End: times[i], MissingEpochs: int(math.Round(float64(d)/float64(interval))) - 1})
+	}
+
+	if interval > 0 {
+		r.ExpectedEpochs = int(math.Round(float64(r.End.Sub(r.Start))/float64(interval))) + 1
+	}
+	if r.ExpectedEpochs < r.NumEpochs { // higher rate than the interval
+		r.ExpectedEpochs = r.NumEpochs
+	}
+	r.Availability = float64(r.NumEpochs) / float64(r.ExpectedEpochs) * 100
+	return r
+}
+
+// detectInterval returns the most frequent difference between consecutive epochs,
+// the shorter one if two are equally frequent.
+func detectInterval(times []time.Time) time.Duration {
+	counts := make(map[time.Duration]int)
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d > 0 {
+			counts[d]++
+		}
+	}
+	intervals := make([]time.Duration, 0, len(counts))
+	for d := range counts {
+		intervals = append(intervals, d)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		if counts[intervals[i]] != counts[intervals[j]] {
+			return counts[intervals[i]] > counts[intervals[j]]
+		}
+		return intervals[i] < intervals[j]
+	})
+	if len(intervals) == 0 {
+		return 0
+	}
+	return intervals[0]
+}
+
+// Gaps reads the file and returns its data gaps. The sampling interval is taken from the header,
+// or detected from the epochs if the header does not specify it. Event epochs are skipped.
+func (f *ObsFile) Gaps() (GapReport, error) {
+	r, err := f.open()
+	if err != nil {
+		return GapReport{}, err
+	}
+	defer r.Close()
+	dec, err := NewObsDecoder(r)
+	if err != nil {
+		return GapReport{}, err
+	}
+
+	var times []time.Time
+	for dec.NextEpoch() {
+		if epo := dec.Epoch(); !epo.IsEvent() && !epo.IsCycleSlip() {
+			times = append(times, epo.Time)
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return GapReport{}, fmt.Errorf("read epochs: %w", err)
+	}
+	interval := time.Duration(dec.Header.Interval * float64(time.Second))
+	return Gaps(times, interval), nil
+}
@@ -0,0 +1,49 @@
+package rinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGaps(t *testing.T) {
+	assert := assert.New(t)
+	t0 := time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC)
+	var times []time.Time
+	for _, sec := range []int{0, 30, 60, 60, 90, 210, 240, 270, 330} {
+		times = append(times, t0.Add(time.Duration(sec)*time.Second))
+	}
+
+	r := Gaps(times, 0)
+	assert.Equal(30*time.Second, r.Interval)
+	assert.Equal(8, r.NumEpochs)
+	assert.Equal(12, r.ExpectedEpochs)
+	assert.Equal([]Gap{
+		{Start: t0.Add(90 * time.Second), End: t0.Add(210 * time.Second), MissingEpochs: 3},
+		{Start: t0.Add(270 * time.Second), End: t0.Add(330 * time.Second), MissingEpochs: 1},
+	}, r.Gaps)
+	assert.Equal(4, r.MissingEpochs())
+	assert.InDelta(66.667, r.Availability, 1e-3)
+
+	r = Gaps(times, 15*time.Second)
+	assert.Equal(23, r.ExpectedEpochs)
+	assert.Len(r.Gaps, 7)
+
+	assert.Equal(GapReport{}, Gaps(nil, 0))
+	r = Gaps(times[:1], 0)
+	assert.Equal(100.0, r.Availability)
+}
+
+func TestObsFile_Gaps(t *testing.T) {
+	assert := assert.New(t)
+	obsFil, err := NewObsFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	r, err := obsFil.Gaps()
+	assert.NoError(err)
+	assert.Equal(30*time.Second, r.Interval)
+	assert.Equal(r.ExpectedEpochs, r.NumEpochs+r.MissingEpochs())
+	t.Logf("%+v", r)
+}
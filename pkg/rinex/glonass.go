@@ -0,0 +1,55 @@
+package rinex
+
+import (
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// GloSlots maps the GLONASS satellites to their frequency channel numbers, -7 to +6, as given by the header
+// record GLONASS SLOT / FRQ # or the broadcast ephemerides. The channel is needed for the frequencies and
+// wavelengths of the FDMA signals on the bands 1 and 2.
+type GloSlots map[PRN]int
+
+// NewGloSlots returns the frequency channels of the GLONASS ephemerides, other ephemerides are ignored.
+func NewGloSlots(ephs []Eph) GloSlots {
+	slots := make(GloSlots, 24)
+	for _, eph := range ephs {
+		if glo, ok := eph.(*EphGLO); ok {
+			slots[glo.PRN] = glo.FreqNum
+		}
+	}
+	return slots
+}
+
+// Channel returns the frequency channel of the GLONASS satellite. It returns false if it is not known.
+func (slots GloSlots) Channel(prn PRN) (int, bool) {
+	ch, ok := slots[prn]
+	return ch, ok && prn.Sys == gnss.SysGLO
+}
+
+// Frequency returns the carrier frequency in Hz of the observation type, e.g. L1C, of the satellite of any system.
+// It returns 0 if the signal is not defined or if the frequency channel of a GLONASS FDMA signal is not known.
+func (slots GloSlots) Frequency(prn PRN, typ string) float64 {
+	code := ObsCode(typ)
+	info, ok := code.Lookup(prn.Sys)
+	if !ok {
+		return 0
+	}
+	if !info.FDMA {
+		return info.Frequency
+	}
+	ch, ok := slots.Channel(prn)
+	if !ok {
+		return 0
+	}
+	return GLOFrequency(code.Band(), ch)
+}
+
+// Wavelength returns the wavelength in meters of the observation type of the satellite, or 0 if unknown,
+// see Frequency.
+func (slots GloSlots) Wavelength(prn PRN, typ string) float64 {
+	f := slots.Frequency(prn, typ)
+	if f == 0 {
+		return 0
+	}
+	return gnss.SpeedOfLight / f
+}
@@ -0,0 +1,51 @@
+package rinex
+
+import (
+	"os"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGloSlots(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("testdata/white/AREG00PER_R_20201690000_01D_MN.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dec, err := NewNavDecoder(r)
+	assert.NoError(err)
+	var ephs []Eph
+	for dec.NextEphemeris() {
+		ephs = append(ephs, dec.Ephemeris())
+	}
+
+	slots := NewGloSlots(ephs)
+	ch, ok := slots.Channel(PRN{Sys: gnss.SysGLO, Num: 2})
+	assert.True(ok)
+	assert.Equal(-4, ch)
+	ch, ok = slots.Channel(PRN{Sys: gnss.SysGLO, Num: 3})
+	assert.True(ok)
+	assert.Equal(5, ch)
+	_, ok = slots.Channel(PRN{Sys: gnss.SysGPS, Num: 3})
+	assert.False(ok)
+}
+
+func TestGloSlots_Wavelength(t *testing.T) {
+	assert := assert.New(t)
+	r02 := PRN{Sys: gnss.SysGLO, Num: 2}
+	slots := GloSlots{r02: -4}
+
+	assert.Equal(1602e6-4*0.5625e6, slots.Frequency(r02, "L1C"))
+	assert.Equal(1246e6-4*0.4375e6, slots.Frequency(r02, "C2P"))
+	assert.InDelta(gnss.SpeedOfLight/(1602e6-4*0.5625e6), slots.Wavelength(r02, "L1C"), 1e-12)
+	assert.Equal(1202.025e6, slots.Frequency(r02, "L3Q"), "CDMA signal")
+
+	r05 := PRN{Sys: gnss.SysGLO, Num: 5}
+	assert.Equal(0.0, slots.Frequency(r05, "L1C"), "unknown channel")
+	assert.Equal(1202.025e6, slots.Frequency(r05, "L3Q"))
+	assert.InDelta(0.1903, slots.Wavelength(PRN{Sys: gnss.SysGPS, Num: 1}, "L1C"), 1e-4)
+	assert.Equal(0.0, slots.Wavelength(PRN{Sys: gnss.SysGPS, Num: 1}, "L9X"))
+}
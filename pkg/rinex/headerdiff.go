@@ -0,0 +1,158 @@
+package rinex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/antex"
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// DefaultMaxPositionJump is the change of the approximate position in meters above which it is a
+// critical difference.
+const DefaultMaxPositionJump = 5.0
+
+// Severity classifies a header difference.
+type Severity int
+
+// Severities of header differences.
+const (
+	SeverityCosmetic Severity = iota // no effect on the processing, e.g. comments or the observer
+	SeverityWarning                  // worth a look, e.g. a new receiver firmware or other observation types
+	SeverityCritical                 // the station changed, e.g. the antenna, receiver or position
+)
+
+var severityNames = []string{"cosmetic", "warning", "critical"}
+
+// String is a Severity Stringer.
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// HeaderFieldDiff is a difference of a header field.
+type HeaderFieldDiff struct {
+	Field    string   `json:"field"` // the header field, e.g. "antenna type"
+	Old      string   `json:"old"`   // the value of the first header
+	New      string   `json:"new"`   // the value of the second header
+	Severity Severity `json:"severity"`
+}
+
+// String is a HeaderFieldDiff Stringer.
+func (d HeaderFieldDiff) String() string {
+	return fmt.Sprintf("%s: %s: %q -> %q", d.Severity, d.Field, d.Old, d.New)
+}
+
+// HeaderDiffOptions sets options for the header comparison.
+type HeaderDiffOptions struct {
+	MaxPositionJump float64 // in meters, default DefaultMaxPositionJump
+}
+
+// HeaderDiff compares the headers h1 and h2, e.g. of consecutive files of a station, and returns the
+// differences of their fields, ordered by descending severity. Fields that differ between files
+// anyway, like the time of the first observation and the number of satellites, are not compared.
+func HeaderDiff(h1, h2 *ObsHeader) []HeaderFieldDiff {
+	return HeaderDiffWithOptions(h1, h2, HeaderDiffOptions{})
+}
+
+// HeaderDiffWithOptions compares the headers like HeaderDiff, with options.
+func HeaderDiffWithOptions(h1, h2 *ObsHeader, opts HeaderDiffOptions) []HeaderFieldDiff {
+	maxJump := opts.MaxPositionJump
+	if maxJump <= 0 {
+		maxJump = DefaultMaxPositionJump
+	}
+	var diffs []HeaderFieldDiff
+	add := func(sev Severity, field, v1, v2 string) {
+		if v1 != v2 {
+			diffs = append(diffs, HeaderFieldDiff{Field: field, Old: v1, New: v2, Severity: sev})
+		}
+	}
+	addFloat := func(sev Severity, field string, v1, v2 float64) {
+		if math.Abs(v1-v2) > eccTolerance {
+			add(sev, field, fmt.Sprintf("%.4f", v1), fmt.Sprintf("%.4f", v2))
+		}
+	}
+
+	// critical: the station's equipment and position
+	if !equalMarkerName(h1.MarkerName, h2.MarkerName) {
+		add(SeverityCritical, "marker name", h1.MarkerName, h2.MarkerName)
+	}
+	add(SeverityCritical, "receiver type", h1.ReceiverType, h2.ReceiverType)
+	add(SeverityCritical, "receiver serial number", h1.ReceiverNumber, h2.ReceiverNumber)
+	add(SeverityCritical, "antenna type", antex.NormalizeType(h1.AntennaType), antex.NormalizeType(h2.AntennaType))
+	add(SeverityCritical, "antenna serial number", h1.AntennaNumber, h2.AntennaNumber)
+	addFloat(SeverityCritical, "antenna height", h1.AntennaDelta.Up, h2.AntennaDelta.Up)
+	addFloat(SeverityCritical, "antenna north eccentricity", h1.AntennaDelta.N, h2.AntennaDelta.N)
+	addFloat(SeverityCritical, "antenna east eccentricity", h1.AntennaDelta.E, h2.AntennaDelta.E)
+	if d := h1.Position.Distance(h2.Position); d > eccTolerance {
+		sev := SeverityWarning
+		if d > maxJump {
+			sev = SeverityCritical
+		}
+		xyz := func(c Coord) string { return fmt.Sprintf("%.4f %.4f %.4f", c.X, c.Y, c.Z) }
+		diffs = append(diffs, HeaderFieldDiff{Field: "approx position", Old: xyz(h1.Position), New: xyz(h2.Position), Severity: sev})
+	}
+
+	// warning: the data may be processed differently
+	add(SeverityWarning, "marker number", h1.MarkerNumber, h2.MarkerNumber)
+	add(SeverityWarning, "marker type", h1.MarkerType, h2.MarkerType)
+	add(SeverityWarning, "receiver firmware", h1.ReceiverVersion, h2.ReceiverVersion)
+	add(SeverityWarning, "RINEX version", fmt.Sprintf("%.2f", h1.RINEXVersion), fmt.Sprintf("%.2f", h2.RINEXVersion))
+	add(SeverityWarning, "satellite system", h1.SatSystem.Abbr(), h2.SatSystem.Abbr())
+	add(SeverityWarning, "interval", fmt.Sprintf("%g", h1.Interval), fmt.Sprintf("%g", h2.Interval))
+	add(SeverityWarning, "time system", h1.TimeSystem, h2.TimeSystem)
+	add(SeverityWarning, "signal strength unit", h1.SignalStrengthUnit, h2.SignalStrengthUnit)
+	for _, sys := range headerSystems(h1, h2) {
+		add(SeverityWarning, sys.Abbr()+" observation types", strings.Join(h1.ObsTypes[sys], " "),
+			strings.Join(h2.ObsTypes[sys], " "))
+	}
+
+	// cosmetic
+	add(SeverityCosmetic, "observer", h1.Observer, h2.Observer)
+	add(SeverityCosmetic, "agency", h1.Agency, h2.Agency)
+	add(SeverityCosmetic, "program", h1.Pgm, h2.Pgm)
+	add(SeverityCosmetic, "run by", h1.RunBy, h2.RunBy)
+	add(SeverityCosmetic, "comments", strings.Join(h1.Comments, "\n"), strings.Join(h2.Comments, "\n"))
+
+	sort.SliceStable(diffs, func(i, j int) bool { return diffs[i].Severity > diffs[j].Severity })
+	return diffs
+}
+
+// MaxSeverity returns the highest severity of the differences and false if there are none.
+func MaxSeverity(diffs []HeaderFieldDiff) (Severity, bool) {
+	if len(diffs) == 0 {
+		return SeverityCosmetic, false
+	}
+	max := diffs[0].Severity
+	for _, d := range diffs[1:] {
+		if d.Severity > max {
+			max = d.Severity
+		}
+	}
+	return max, true
+}
+
+// headerSystems returns the satellite systems with observation types in any of the headers, in order.
+func headerSystems(h1, h2 *ObsHeader) []gnss.System {
+	seen := make(map[gnss.System]bool)
+	var systems []gnss.System
+	for _, hdr := range []*ObsHeader{h1, h2} {
+		for sys := range hdr.ObsTypes {
+			if !seen[sys] {
+				seen[sys] = true
+				systems = append(systems, sys)
+			}
+		}
+	}
+	sort.Slice(systems, func(i, j int) bool { return systems[i] < systems[j] })
+	return systems
+}
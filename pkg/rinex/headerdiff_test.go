@@ -0,0 +1,65 @@
+package rinex
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderDiff(t *testing.T) {
+	assert := assert.New(t)
+	h1 := &ObsHeader{
+		RINEXVersion: 3.04,
+		MarkerName:   "WTZR",
+		ReceiverType: "SEPT POLARX5",
+		AntennaType:  "LEIAR25.R3      LEIT",
+		Position:     Coord{X: 4075580.3, Y: 931854.0, Z: 4801568.2},
+		AntennaDelta: CoordNEU{Up: 0.071},
+		ObsTypes:     map[gnss.System][]string{gnss.SysGPS: {"C1C", "L1C"}},
+		Observer:     "BKG",
+		Comments:     []string{"first"},
+	}
+	h2 := *h1
+	assert.Empty(HeaderDiff(h1, &h2))
+
+	h2.MarkerName = "WTZR00DEU"
+	h2.ReceiverVersion = "5.4.0"
+	h2.Observer = "IfE"
+	h2.Position.X += 1
+	assert.Equal([]HeaderFieldDiff{
+		{Field: "approx position", Old: "4075580.3000 931854.0000 4801568.2000", New: "4075581.3000 931854.0000 4801568.2000", Severity: SeverityWarning},
+		{Field: "receiver firmware", Old: "", New: "5.4.0", Severity: SeverityWarning},
+		{Field: "observer", Old: "BKG", New: "IfE", Severity: SeverityCosmetic},
+	}, HeaderDiff(h1, &h2))
+
+	h2 = *h1
+	h2.AntennaType = "LEIAR25.R4      LEIT"
+	h2.AntennaDelta.Up = 0.0714 // within the tolerance
+	h2.Position.Z += 10
+	h2.ObsTypes = map[gnss.System][]string{gnss.SysGPS: {"C1C", "L1C"}, gnss.SysGAL: {"C1X"}}
+	h2.Comments = nil
+	diffs := HeaderDiff(h1, &h2)
+	if !assert.Len(diffs, 4) {
+		return
+	}
+	assert.Equal("antenna type", diffs[0].Field)
+	assert.Equal(SeverityCritical, diffs[0].Severity)
+	assert.Equal("approx position", diffs[1].Field)
+	assert.Equal(SeverityCritical, diffs[1].Severity)
+	assert.Equal(HeaderFieldDiff{Field: "E observation types", Old: "", New: "C1X", Severity: SeverityWarning}, diffs[2])
+	assert.Equal(`cosmetic: comments: "first" -> ""`, diffs[3].String())
+	sev, ok := MaxSeverity(diffs)
+	assert.True(ok)
+	assert.Equal(SeverityCritical, sev)
+
+	diffs = HeaderDiffWithOptions(h1, &h2, HeaderDiffOptions{MaxPositionJump: 20})
+	assert.Equal(SeverityWarning, diffs[1].Severity)
+
+	b, err := json.Marshal(diffs[0])
+	assert.NoError(err)
+	assert.Equal(`{"field":"antenna type","old":"LEIAR25.R3      LEIT","new":"LEIAR25.R4      LEIT","severity":"critical"}`, string(b))
+	_, ok = MaxSeverity(nil)
+	assert.False(ok)
+}
@@ -0,0 +1,86 @@
+package rinex
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// History configures the COMMENT lines that document the modifications of files. They are added to the
+// header by the functions that rewrite files, like CropObs, MergeObs, SplitObs, MergeNav, ObsFile.FixHeader and
+// ObsFile.PatchHeader:
+//
+//	gnss v0.0.1         crop                20201016 120000 UTC COMMENT
+//	input: REYK00ISL_R_20192701000_01H_30S_MO.rnx               COMMENT
+//	from 2019-09-27 10:15:00 to 2019-09-27 10:30:00             COMMENT
+type History struct {
+	Program  string // program name and version, e.g. "gnss v0.0.1"
+	RunBy    string // agency, written after the operation if set
+	Disabled bool   // do not add history comments
+}
+
+// DefaultHistory is the History used by the functions of this package. Commands should set their name
+// and version.
+var DefaultHistory = &History{Program: "gognss"}
+
+// maxCommentLen is the length of a COMMENT record.
+const maxCommentLen = 60
+
+// Comments returns the COMMENT lines for the operation op, e.g. "crop", applied to the inputs at time t.
+// The details describe the operation, e.g. its parameters. Lines longer than a COMMENT record are wrapped.
+func (h *History) Comments(op string, inputs []string, t time.Time, details ...string) []string {
+	if h == nil || h.Disabled {
+		return nil
+	}
+	opBy := op
+	if h.RunBy != "" {
+		opBy = op + " " + h.RunBy
+	}
+	lines := []string{fmt.Sprintf("%-20.20s%-20.20s%s", h.Program, opBy, t.UTC().Format("20060102 150405 UTC"))}
+	for _, in := range inputs {
+		if in != "" {
+			lines = append(lines, wrapComment("input: "+in)...)
+		}
+	}
+	for _, d := range details {
+		if d != "" {
+			lines = append(lines, wrapComment(d)...)
+		}
+	}
+	return lines
+}
+
+// addHistory appends the history comments of the operation to the header.
+func (hdr *ObsHeader) addHistory(op string, inputs []string, details ...string) {
+	lines := DefaultHistory.Comments(op, inputs, time.Now(), details...)
+	if len(lines) == 0 {
+		return
+	}
+	hdr.Comments = append(hdr.Comments[:len(hdr.Comments):len(hdr.Comments)], lines...)
+}
+
+// addHistory appends the history comments of the operation to the header.
+func (hdr *NavHeader) addHistory(op string, inputs []string, details ...string) {
+	lines := DefaultHistory.Comments(op, inputs, time.Now(), details...)
+	if len(lines) == 0 {
+		return
+	}
+	hdr.Comments = append(hdr.Comments[:len(hdr.Comments):len(hdr.Comments)], lines...)
+}
+
+// wrapComment splits s into lines that fit into COMMENT records.
+func wrapComment(s string) []string {
+	var lines []string
+	for len(s) > maxCommentLen {
+		i := strings.LastIndexByte(s[:maxCommentLen], ' ')
+		if i <= 0 {
+			i = maxCommentLen
+		}
+		lines = append(lines, strings.TrimRight(s[:i], " "))
+		s = strings.TrimLeft(s[i:], " ")
+	}
+	if s = strings.TrimRight(s, " "); s != "" {
+		lines = append(lines, s)
+	}
+	return lines
+}
@@ -0,0 +1,52 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_Comments(t *testing.T) {
+	assert := assert.New(t)
+	h := &History{Program: "gnss v0.0.1", RunBy: "BKG"}
+	lines := h.Comments("crop", []string{"REYK00ISL_R_20192701000_01H_30S_MO.rnx", ""},
+		time.Date(2020, 10, 16, 12, 0, 0, 0, time.UTC), strings.Repeat("detail ", 10), "")
+	assert.Equal([]string{
+		"gnss v0.0.1         crop BKG            20201016 120000 UTC",
+		"input: REYK00ISL_R_20192701000_01H_30S_MO.rnx",
+		"detail detail detail detail detail detail detail detail",
+		"detail detail",
+	}, lines)
+	for _, l := range lines {
+		assert.True(len(l) <= 60)
+	}
+
+	h.Disabled = true
+	assert.Nil(h.Comments("crop", nil, time.Now()))
+}
+
+func TestCropObs_History(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.Name = "REYK00ISL_R_20192701000_01H_30S_MO.rnx"
+	nComments := len(dec.Header.Comments)
+	var buf bytes.Buffer
+	_, err = CropObs(&buf, dec, time.Date(2019, 9, 27, 10, 15, 0, 0, time.UTC), time.Time{})
+	assert.NoError(err)
+
+	dec, err = NewObsDecoder(&buf)
+	assert.NoError(err)
+	comments := dec.Header.Comments[nComments:]
+	if assert.Len(comments, 3) {
+		assert.True(strings.HasPrefix(comments[0], "gognss              crop"))
+		assert.Equal("input: REYK00ISL_R_20192701000_01H_30S_MO.rnx", comments[1])
+		assert.Equal("from 2019-09-27 10:15:00", comments[2])
+	}
+}
@@ -0,0 +1,108 @@
+package rinex
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileLatency is the latency of a file, the time between the nominal end of its data and its arrival,
+// e.g. in the archive of a data center.
+type FileLatency struct {
+	Name    string    `json:"name"`
+	Station string    `json:"station"` // 4 char ID in upper case
+	End     time.Time `json:"end"`     // nominal end of the data, the start time plus the file period
+	Arrival time.Time `json:"arrival"`
+	Latency float64   `json:"latency"` // in seconds, negative for files arriving before their end
+}
+
+// Latency returns the latency of the file, with its modification time as arrival. The nominal end of
+// the data is derived from the file name. The times of the file names are not corrected for the
+// leap seconds of GPS time.
+func Latency(path string) (FileLatency, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileLatency{}, err
+	}
+	return LatencyAt(path, info.ModTime())
+}
+
+// LatencyAt returns the latency of the file named name arrived at the given time, e.g. the receipt time
+// logged by an upload server.
+func LatencyAt(name string, arrival time.Time) (FileLatency, error) {
+	fi, err := ParseFilename(name)
+	if err != nil {
+		return FileLatency{}, err
+	}
+	period, err := parsePeriodCode(fi.FilePeriod)
+	if err != nil {
+		return FileLatency{}, fmt.Errorf("%s: %w", fi.Name, err)
+	}
+	end := fi.StartTime.Add(period)
+	arrival = arrival.UTC()
+	return FileLatency{
+		Name:    fi.Name,
+		Station: strings.ToUpper(fi.FourCharID),
+		End:     end,
+		Arrival: arrival,
+		Latency: arrival.Sub(end).Seconds(),
+	}, nil
+}
+
+// LatencyStats are the latency statistics of the files of a station, in seconds.
+type LatencyStats struct {
+	Station string    `json:"station"`
+	Files   int       `json:"files"`
+	Late    int       `json:"late"`   // number of files above the latency limit
+	OnTime  float64   `json:"onTime"` // percentage of the files within the limit
+	Min     float64   `json:"min"`
+	Max     float64   `json:"max"`
+	Mean    float64   `json:"mean"`
+	Median  float64   `json:"median"`
+	P95     float64   `json:"p95"`     // 95th percentile
+	LastEnd time.Time `json:"lastEnd"` // nominal end of the latest file
+}
+
+// LatencyStatsByStation aggregates the latencies per station, sorted by station. Files with a latency
+// above limit are counted as late, a zero limit counts none.
+func LatencyStatsByStation(lats []FileLatency, limit time.Duration) []LatencyStats {
+	byStation := make(map[string][]FileLatency)
+	for _, l := range lats {
+		byStation[l.Station] = append(byStation[l.Station], l)
+	}
+	stations := make([]string, 0, len(byStation))
+	for sta := range byStation {
+		stations = append(stations, sta)
+	}
+	sort.Strings(stations)
+
+	stats := make([]LatencyStats, 0, len(stations))
+	for _, sta := range stations {
+		files := byStation[sta]
+		vals := make([]float64, len(files))
+		st := LatencyStats{Station: sta, Files: len(files)}
+		var sum float64
+		var lastEnd time.Time
+		for i, l := range files {
+			vals[i] = l.Latency
+			sum += l.Latency
+			if limit > 0 && l.Latency > limit.Seconds() {
+				st.Late++
+			}
+			if l.End.After(lastEnd) {
+				lastEnd = l.End
+			}
+		}
+		st.OnTime = 100 * float64(st.Files-st.Late) / float64(st.Files)
+		st.Mean = sum / float64(len(vals))
+		st.Median = median(vals) // sorts vals
+		st.Min, st.Max = vals[0], vals[len(vals)-1]
+		st.P95 = vals[int(math.Ceil(0.95*float64(len(vals))))-1]
+		st.LastEnd = lastEnd
+		stats = append(stats, st)
+	}
+	return stats
+}
@@ -0,0 +1,66 @@
+package rinex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatency(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "latency-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "WTZR00DEU_R_20201691300_01H_30S_MO.crx.gz")
+	assert.NoError(ioutil.WriteFile(path, nil, 0644))
+	arrival := time.Date(2020, 6, 17, 14, 2, 30, 0, time.UTC)
+	assert.NoError(os.Chtimes(path, arrival, arrival))
+
+	l, err := Latency(path)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("WTZR", l.Station)
+	assert.Equal(time.Date(2020, 6, 17, 14, 0, 0, 0, time.UTC), l.End)
+	assert.Equal(150.0, l.Latency)
+
+	l, err = LatencyAt("wtzr169.20o", time.Date(2020, 6, 18, 0, 0, 10, 0, time.UTC))
+	assert.Error(err)
+	l, err = LatencyAt("wtzr1690.20o", time.Date(2020, 6, 18, 0, 0, 10, 0, time.UTC))
+	assert.NoError(err)
+	assert.Equal(10.0, l.Latency)
+}
+
+func TestLatencyStatsByStation(t *testing.T) {
+	assert := assert.New(t)
+	var lats []FileLatency
+	start := time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		end := start.Add(time.Duration(i+1) * time.Hour)
+		lats = append(lats, FileLatency{Station: "WTZR", End: end, Latency: float64(60 * (i + 1))})
+	}
+	lats = append(lats, FileLatency{Station: "BRST", End: start, Latency: -5})
+
+	stats := LatencyStatsByStation(lats, 10*time.Minute)
+	if !assert.Len(stats, 2) {
+		return
+	}
+	assert.Equal(LatencyStats{Station: "BRST", Files: 1, OnTime: 100, Min: -5, Max: -5, Mean: -5, Median: -5, P95: -5, LastEnd: start}, stats[0])
+	st := stats[1]
+	assert.Equal("WTZR", st.Station)
+	assert.Equal(20, st.Files)
+	assert.Equal(10, st.Late)
+	assert.Equal(50.0, st.OnTime)
+	assert.Equal(60.0, st.Min)
+	assert.Equal(1200.0, st.Max)
+	assert.Equal(630.0, st.Mean)
+	assert.Equal(630.0, st.Median)
+	assert.Equal(1140.0, st.P95)
+	assert.Equal(start.Add(20*time.Hour), st.LastEnd)
+}
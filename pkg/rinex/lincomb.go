@@ -0,0 +1,150 @@
+package rinex
+
+import (
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// attrPriority is the order in which the attributes of a frequency band are used for linear combinations.
+const attrPriority = "PWYCSLXIQDABZEMN"
+
+// defaultBands are the frequency bands used per system for the linear combinations of an epoch.
+var defaultBands = map[gnss.System][2]byte{
+	gnss.SysGPS:   {'1', '2'},
+	gnss.SysGLO:   {'1', '2'},
+	gnss.SysGAL:   {'1', '5'},
+	gnss.SysQZSS:  {'1', '2'},
+	gnss.SysBDS:   {'2', '6'},
+	gnss.SysIRNSS: {'5', '9'},
+	gnss.SysSBAS:  {'1', '5'},
+}
+
+// DefaultBands returns the two frequency bands commonly combined for the system, e.g. '1' and '5' for Galileo.
+func DefaultBands(sys gnss.System) (band1, band2 byte, ok bool) {
+	b, ok := defaultBands[sys]
+	return b[0], b[1], ok
+}
+
+// LinComb holds the linear combinations of the observations of two frequency bands of a satellite.
+// All values are in meters. The code combinations are only valid if HasCode is set,
+// the phase combinations if HasPhase is set, and MW if both are set.
+type LinComb struct {
+	Code1, Code2   string // code observation types used, e.g. "C1W" and "C2W"
+	Phase1, Phase2 string // phase observation types used, e.g. "L1W" and "L2W"
+	HasCode        bool
+	HasPhase       bool
+
+	IFCode  float64 // ionosphere-free code
+	IFPhase float64 // ionosphere-free phase
+	GFCode  float64 // geometry-free code, P2-P1
+	GFPhase float64 // geometry-free phase, L1-L2
+	WLPhase float64 // wide-lane phase
+	NLCode  float64 // narrow-lane code
+	MW      float64 // Melbourne-Wübbena, wide-lane phase minus narrow-lane code
+
+	WLWavelength float64 // wavelength of the wide-lane in meters
+}
+
+// LinComb computes the linear combinations of the observations of the frequency bands band1 and band2.
+// For each band the code and phase observation types are chosen by the attribute, preferring attributes
+// with both code and phase, e.g. C1C/L1C, in the order of attrPriority. The GLONASS frequency channel
+// is needed for the FDMA signals. It returns false if neither the codes nor the phases are available.
+func (satObs SatObs) LinComb(band1, band2 byte, gloChannel int) (LinComb, bool) {
+	var lc LinComb
+	sys := satObs.Prn.Sys
+	f1, f2 := bandFrequency(sys, band1, gloChannel), bandFrequency(sys, band2, gloChannel)
+	if f1 == 0 || f2 == 0 || f1 == f2 {
+		return lc, false
+	}
+
+	var p1, p2, l1, l2 float64
+	lc.Code1, lc.Phase1 = satObs.pairObs(band1)
+	lc.Code2, lc.Phase2 = satObs.pairObs(band2)
+	if lc.Code1 != "" && lc.Code2 != "" {
+		lc.HasCode = true
+		o1, _ := satObs.Get(lc.Code1)
+		o2, _ := satObs.Get(lc.Code2)
+		p1, p2 = o1.Val, o2.Val
+		lc.IFCode = (f1*f1*p1 - f2*f2*p2) / (f1*f1 - f2*f2)
+		lc.GFCode = p2 - p1
+		lc.NLCode = (f1*p1 + f2*p2) / (f1 + f2)
+	}
+	if lc.Phase1 != "" && lc.Phase2 != "" {
+		lc.HasPhase = true
+		o1, _ := satObs.Get(lc.Phase1)
+		o2, _ := satObs.Get(lc.Phase2)
+		l1 = o1.Val * gnss.SpeedOfLight / f1
+		l2 = o2.Val * gnss.SpeedOfLight / f2
+		lc.IFPhase = (f1*f1*l1 - f2*f2*l2) / (f1*f1 - f2*f2)
+		lc.GFPhase = l1 - l2
+		lc.WLPhase = (f1*l1 - f2*l2) / (f1 - f2)
+	}
+	if lc.HasCode && lc.HasPhase {
+		lc.MW = lc.WLPhase - lc.NLCode
+	}
+	if f1 > f2 {
+		lc.WLWavelength = gnss.SpeedOfLight / (f1 - f2)
+	} else {
+		lc.WLWavelength = gnss.SpeedOfLight / (f2 - f1)
+	}
+	return lc, lc.HasCode || lc.HasPhase
+}
+
+// pairObs returns the code and phase observation types of the band to be used for linear combinations.
+// An empty string is returned if there is no such observation.
+func (satObs SatObs) pairObs(band byte) (code, phase string) {
+	has := func(typ string) bool {
+		obs, ok := satObs.Get(typ)
+		return ok && obs.Val != 0
+	}
+	for i := 0; i < len(attrPriority); i++ {
+		sig := string([]byte{band, attrPriority[i]})
+		if has("C"+sig) && has("L"+sig) {
+			return "C" + sig, "L" + sig
+		}
+	}
+	for i := 0; i < len(attrPriority); i++ {
+		sig := string([]byte{band, attrPriority[i]})
+		if code == "" && has("C"+sig) {
+			code = "C" + sig
+		}
+		if phase == "" && has("L"+sig) {
+			phase = "L" + sig
+		}
+	}
+	return code, phase
+}
+
+// bandFrequency returns the carrier frequency in Hz of the frequency band, or 0 if unknown.
+func bandFrequency(sys gnss.System, band byte, gloChannel int) float64 {
+	for sig, info := range signals[sys] {
+		if sig[0] != band {
+			continue
+		}
+		if info.FDMA {
+			return GLOFrequency(band, gloChannel)
+		}
+		return info.Frequency
+	}
+	return 0
+}
+
+// LinCombs computes the linear combinations of the default bands of each satellite's system, see DefaultBands.
+// The GLONASS frequency channels are taken from gloSlots, as given in the header.
+// Satellites without GLONASS frequency channel or without observations of the bands are skipped.
+func (epo *Epoch) LinCombs(gloSlots map[PRN]int) map[PRN]LinComb {
+	combs := make(map[PRN]LinComb, len(epo.ObsList))
+	for _, satObs := range epo.ObsList {
+		band1, band2, ok := DefaultBands(satObs.Prn.Sys)
+		if !ok {
+			continue
+		}
+		channel, ok := gloSlots[satObs.Prn]
+		if satObs.Prn.Sys == gnss.SysGLO && !ok {
+			continue
+		}
+		if lc, ok := satObs.LinComb(band1, band2, channel); ok {
+			combs[satObs.Prn] = lc
+		}
+	}
+	return combs
+}
@@ -0,0 +1,69 @@
+package rinex
+
+import (
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSatObs_LinComb(t *testing.T) {
+	assert := assert.New(t)
+	const (
+		rho  = 22000000.0
+		iono = 5.0 // on L1 in meters
+		n1   = 12
+		n2   = 3
+		f1   = 1575.42e6
+		f2   = 1227.60e6
+	)
+	gamma := f1 * f1 / (f2 * f2)
+	lambda1, lambda2 := gnss.SpeedOfLight/f1, gnss.SpeedOfLight/f2
+	satObs := NewSatObs(PRN{Sys: gnss.SysGPS, Num: 5}, map[string]Obs{
+		"C1C": {Val: rho + iono + 1},
+		"L1C": {Val: (rho-iono)/lambda1 + n1},
+		"C1W": {Val: rho + iono},
+		"L1W": {Val: (rho-iono)/lambda1 + n1},
+		"C2W": {Val: rho + gamma*iono},
+		"L2W": {Val: (rho-gamma*iono)/lambda2 + n2},
+		"C2L": {Val: rho + gamma*iono + 1},
+	})
+
+	lc, ok := satObs.LinComb('1', '2', 0)
+	assert.True(ok)
+	assert.Equal("C1W", lc.Code1)
+	assert.Equal("L1W", lc.Phase1)
+	assert.Equal("C2W", lc.Code2)
+	assert.True(lc.HasCode)
+	assert.True(lc.HasPhase)
+	assert.InDelta(rho, lc.IFCode, 1e-6)
+	assert.InDelta((gamma-1)*iono, lc.GFCode, 1e-6)
+	assert.InDelta((gamma-1)*iono+n1*lambda1-n2*lambda2, lc.GFPhase, 1e-6)
+	assert.InDelta(0.861918, lc.WLWavelength, 1e-6)
+	assert.InDelta((n1-n2)*lc.WLWavelength, lc.MW, 1e-6)
+
+	// phase only on band 2 with another attribute
+	satObs.Set("C2W", Obs{})
+	satObs.Set("C2L", Obs{})
+	lc, ok = satObs.LinComb('1', '2', 0)
+	assert.True(ok)
+	assert.False(lc.HasCode)
+	assert.Equal("L2W", lc.Phase2)
+
+	_, ok = satObs.LinComb('1', '5', 0)
+	assert.False(ok)
+	_, ok = satObs.LinComb('1', '1', 0)
+	assert.False(ok)
+}
+
+func TestEpoch_LinCombs(t *testing.T) {
+	assert := assert.New(t)
+	g01, r01, r02 := PRN{Sys: gnss.SysGPS, Num: 1}, PRN{Sys: gnss.SysGLO, Num: 1}, PRN{Sys: gnss.SysGLO, Num: 2}
+	obs := map[string]Obs{"C1C": {Val: 2e7}, "C2P": {Val: 2e7 + 3}}
+	epo := &Epoch{ObsList: []SatObs{NewSatObs(g01, map[string]Obs{"C1C": {Val: 2e7}, "C2W": {Val: 2e7 + 3}}),
+		NewSatObs(r01, obs), NewSatObs(r02, obs)}}
+	combs := epo.LinCombs(map[PRN]int{r01: 1})
+	assert.Len(combs, 2)
+	assert.InDelta(3, combs[g01].GFCode, 1e-9)
+	assert.InDelta(gnss.SpeedOfLight/(1602.5625e6-1246.4375e6), combs[r01].WLWavelength, 1e-9)
+}
@@ -0,0 +1,193 @@
+package rinex
+
+import (
+	"bufio"
+	"io"
+)
+
+// DefaultMaxLineLength is the default maximum length of a line in bytes.
+const DefaultMaxLineLength = 1 << 20
+
+// DecoderOptions configures the reading of the input stream by the decoders.
+type DecoderOptions struct {
+	// MaxLineLength is the maximum length of a line in bytes, longer lines stop the decoding with
+	// ErrLineTooLong. Zero means DefaultMaxLineLength.
+	MaxLineLength int
+
+	// MaxHeaderLines is the maximum number of lines to read for the header.
+	// Zero means the default of the decoder, which is 800 for observation, 300 for navigation
+	// and 5000 for clock files.
+	MaxHeaderLines int
+
+	// BufferSize is the size of the read buffer in bytes. Zero means the default of 4096 bytes.
+	// Larger buffers reduce the number of reads for big files, lines may be longer than the buffer.
+	BufferSize int
+
+	// Follow enables the follow mode, where the decoder waits for data appended to the input at its end,
+	// instead of stopping. The input cannot be seeked in this mode.
+	Follow *FollowOptions
+
+	// Progress receives the bytes read and the decoded epochs, see Progress. The size of a file input
+	// is added as total, unless in follow mode.
+	Progress Progress
+}
+
+// input returns the reader the decoder reads from.
+func (opts DecoderOptions) input(r io.Reader) io.Reader {
+	if opts.Follow != nil {
+		return withProgress(NewFollowReader(r, *opts.Follow), opts.Progress)
+	}
+	addFileSize(r, opts.Progress)
+	return withProgress(r, opts.Progress)
+}
+
+// lineReader returns the lineReader of the decoder for r. The lines of a memory-mapped file are sliced
+// from the mapping, see OpenMapped.
+func (opts DecoderOptions) lineReader(r io.Reader) *lineReader {
+	if m, ok := r.(*MappedFile); ok && m.Mapped() && opts.Follow == nil {
+		lr := newMappedLineReader(m, opts.MaxLineLength)
+		if opts.Progress != nil {
+			opts.Progress.AddTotal(int64(len(m.data)) - m.off)
+			lr.progress = opts.Progress
+		}
+		return lr
+	}
+	return newLineReaderSize(opts.input(r), opts.MaxLineLength, opts.BufferSize)
+}
+
+// maxHeaderLines returns the maximum number of header lines or def if it is not set.
+func (opts DecoderOptions) maxHeaderLines(def int) int {
+	if opts.MaxHeaderLines > 0 {
+		return opts.MaxHeaderLines
+	}
+	return def
+}
+
+// lineReader reads lines from a bufio.Reader. Other than bufio.Scanner its lines are not limited
+// by the buffer size, only by the given maximum length. The methods follow the ones of bufio.Scanner.
+// With data set it slices the lines from a memory-mapped file instead, see OpenMapped.
+type lineReader struct {
+	r      *bufio.Reader
+	maxLen int
+	line   []byte
+	buf    []byte // assembles lines longer than the buffer of r
+	err    error
+	pos    int64 // offset of the next line in the input
+	start  int64 // offset of the current line
+
+	data     []byte      // the memory-mapped input
+	file     *MappedFile // the file of data
+	progress Progress    // receives the bytes of data
+}
+
+// newLineReader returns a lineReader for r. A maxLen of 0 means DefaultMaxLineLength.
+func newLineReader(r io.Reader, maxLen int) *lineReader {
+	return newLineReaderSize(r, maxLen, 0)
+}
+
+// newLineReaderSize returns a lineReader for r with a read buffer of the given size.
+// A size of 0 means the default size of bufio.
+func newLineReaderSize(r io.Reader, maxLen, size int) *lineReader {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLineLength
+	}
+	if size <= 0 {
+		return &lineReader{r: bufio.NewReader(r), maxLen: maxLen}
+	}
+	return &lineReader{r: bufio.NewReaderSize(r, size), maxLen: maxLen}
+}
+
+// Scan advances to the next line, which will then be available through Bytes or Text.
+// It returns false when the input ends or an error occurred.
+func (lr *lineReader) Scan() bool {
+	if lr.err != nil {
+		return false
+	}
+	if lr.data != nil {
+		return lr.scanMapped()
+	}
+	lr.buf = lr.buf[:0]
+	lr.start = lr.pos
+	for {
+		frag, err := lr.r.ReadSlice('\n')
+		lr.pos += int64(len(frag))
+		if len(lr.buf)+len(frag) > lr.maxLen+2 { // allow for CRLF
+			lr.err = ErrLineTooLong
+			return false
+		}
+		if err == bufio.ErrBufferFull {
+			lr.buf = append(lr.buf, frag...)
+			continue
+		}
+
+		line := frag
+		if len(lr.buf) > 0 {
+			lr.buf = append(lr.buf, frag...)
+			line = lr.buf
+		}
+		if err != nil {
+			lr.err = err
+			if len(line) == 0 {
+				return false
+			}
+		}
+		line = dropEOL(line)
+		if len(line) > lr.maxLen {
+			lr.err = ErrLineTooLong
+			return false
+		}
+		lr.line = line
+		return true
+	}
+}
+
+// Bytes returns the most recent line without the line ending.
+// The underlying array may be overwritten by the next call to Scan.
+func (lr *lineReader) Bytes() []byte {
+	return lr.line
+}
+
+// Text returns the most recent line as string.
+func (lr *lineReader) Text() string {
+	return string(lr.line)
+}
+
+// Err returns the first non-EOF error.
+func (lr *lineReader) Err() error {
+	if lr.err == io.EOF {
+		return nil
+	}
+	return lr.err
+}
+
+// reset makes the lineReader read from r, which is positioned at the offset pos.
+func (lr *lineReader) reset(r io.Reader, pos int64) {
+	if lr.data == nil {
+		lr.r.Reset(r)
+	}
+	lr.line, lr.err = nil, nil
+	lr.pos, lr.start = pos, pos
+}
+
+// dropEOL drops a trailing \n or \r\n.
+func dropEOL(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line
+}
+
+// field returns the columns [from, to) of the line. Other than slicing it does not panic on short lines,
+// the missing columns are omitted, so a field beyond the end of the line is empty.
+func field(line string, from, to int) string {
+	if to > len(line) {
+		to = len(line)
+	}
+	if from >= to {
+		return ""
+	}
+	return line[from:to]
+}
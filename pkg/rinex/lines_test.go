@@ -0,0 +1,62 @@
+package rinex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineReader(t *testing.T) {
+	assert := assert.New(t)
+	long := strings.Repeat("x", 100000)
+	lr := newLineReader(strings.NewReader("a\r\n\n"+long+"\nlast"), 0)
+	var lines []string
+	for lr.Scan() {
+		lines = append(lines, lr.Text())
+	}
+	assert.NoError(lr.Err())
+	assert.Equal([]string{"a", "", long, "last"}, lines)
+
+	lr = newLineReader(strings.NewReader("short\n"+long+"\n"), 1000)
+	assert.True(lr.Scan())
+	assert.False(lr.Scan())
+	assert.Equal(ErrLineTooLong, lr.Err())
+}
+
+func TestObsDecoder_options(t *testing.T) {
+	assert := assert.New(t)
+	const header = `     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+G    4 C1C L1C C2W L2W                                      SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2020 11 18 00 00  0.0000000  0  1
+G01  20000000.123   105000000.123 6  20000001.456    81818181.818 5
+`
+	comment := strings.Repeat(" ", 60) + "COMMENT\n"
+	data := header[:81] + strings.Repeat(comment, 1000) + header[81:]
+
+	// long header
+	_, err := NewObsDecoder(strings.NewReader(data))
+	assert.Error(err)
+	dec, err := NewObsDecoderWithOptions(strings.NewReader(data), DecoderOptions{MaxHeaderLines: 2000})
+	assert.NoError(err)
+	assert.True(dec.NextEpoch())
+	assert.Len(dec.Epoch().ObsList, 1)
+
+	// long line
+	data = header[:81] + strings.Repeat("x", 70000) + "COMMENT\n" + header[81:]
+	dec, err = NewObsDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	assert.True(dec.NextEpoch())
+	_, err = NewObsDecoderWithOptions(strings.NewReader(data), DecoderOptions{MaxLineLength: 1000})
+	assert.Equal(ErrLineTooLong, err)
+}
+
+func TestField(t *testing.T) {
+	assert := assert.New(t)
+	line := "> 2020 11 14"
+	assert.Equal("2020", field(line, 2, 6))
+	assert.Equal("11 14", field(line, 7, 20))
+	assert.Equal("", field(line, 31, 32))
+	assert.Equal("", field(line, 5, 2))
+}
@@ -2,11 +2,11 @@ package rinex
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
-
-	"github.com/mholt/archiver/v3"
+	"time"
 
 	"github.com/de-bkg/gognss/pkg/gnss"
 )
@@ -31,11 +31,225 @@ type MeteoHeader struct {
 
 	Observer, Agency string
 
+	ObsTypes  []string           // observation types, e.g. PR, TD, HR
+	SensorPos map[string]Coord   // sensor position per observation type
+	SensorH   map[string]float64 // sensor height per observation type, ellipsoidal in [m]
+
 	Comments []string
 
 	warnings []string
 }
 
+// MeteoRecord contains the meteorological observations of an epoch.
+type MeteoRecord struct {
+	Time   time.Time
+	Types  []string  // observation types, shared by the records, do not modify
+	Values []float64 // observations in the order of Types
+}
+
+// Get returns the observation of the given type, e.g. PR for the pressure in hPa.
+// It returns false if the type is not included.
+func (rec *MeteoRecord) Get(typ string) (float64, bool) {
+	for i, t := range rec.Types {
+		if t == typ && i < len(rec.Values) {
+			return rec.Values[i], true
+		}
+	}
+	return 0, false
+}
+
+// MeteoDecoder reads and decodes header and data records from a Meteo RINEX input stream.
+type MeteoDecoder struct {
+	// The Header is valid after NewMeteoDecoder. The header must exist.
+	Header MeteoHeader
+
+	sc      *lineReader
+	decOpts DecoderOptions
+	rec     *MeteoRecord
+	lineNum int
+	err     error
+}
+
+// NewMeteoDecoder creates a new decoder for Meteo RINEX data, version 2 or 3.
+// The RINEX header will be read implicitly. The header must exist.
+//
+// It is the caller's responsibility to call Close on the underlying reader when done!
+func NewMeteoDecoder(r io.Reader) (*MeteoDecoder, error) {
+	dec := &MeteoDecoder{sc: DecoderOptions{}.lineReader(r)}
+	dec.Header, dec.err = dec.readHeader()
+	return dec, dec.err
+}
+
+// Err returns the first non-EOF error that was encountered by the decoder.
+func (dec *MeteoDecoder) Err() error {
+	if dec.err == io.EOF {
+		return nil
+	}
+	return dec.err
+}
+
+// readHeader reads a Meteo RINEX header. If the Header does not exist,
+// a ErrNoHeader error will be returned.
+func (dec *MeteoDecoder) readHeader() (hdr MeteoHeader, err error) {
+	maxLines := dec.decOpts.maxHeaderLines(500)
+	nTypes := 0
+read:
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+
+		if dec.lineNum == 1 && !strings.Contains(line, "RINEX VERSION / TYPE") {
+			return hdr, ErrNoHeader
+		}
+		if dec.lineNum > maxLines {
+			return hdr, fmt.Errorf("Reading header failed: line %d reached without finding end of header", maxLines)
+		}
+		if len(line) < 60 {
+			continue
+		}
+
+		val := line[:60]
+		key := strings.TrimSpace(line[60:])
+
+		switch key {
+		case "RINEX VERSION / TYPE":
+			f64, err := strconv.ParseFloat(strings.TrimSpace(field(val, 0, 20)), 32)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing RINEX VERSION: %w", err)
+			}
+			hdr.RINEXVersion = float32(f64)
+			hdr.RINEXType = strings.TrimSpace(field(val, 20, 21))
+			if hdr.RINEXType != "M" {
+				return hdr, fmt.Errorf("invalid RINEX type for meteo files: %q", hdr.RINEXType)
+			}
+		case "PGM / RUN BY / DATE":
+			hdr.Pgm = strings.TrimSpace(field(val, 0, 20))
+			hdr.RunBy = strings.TrimSpace(field(val, 20, 40))
+			hdr.Date = strings.TrimSpace(field(val, 40, len(val)))
+		case "COMMENT":
+			hdr.Comments = append(hdr.Comments, strings.TrimSpace(val))
+		case "MARKER NAME":
+			hdr.MarkerName = strings.TrimSpace(val)
+		case "MARKER NUMBER":
+			hdr.MarkerNumber = strings.TrimSpace(field(val, 0, 20))
+		case "MARKER TYPE":
+			hdr.MarkerType = strings.TrimSpace(field(val, 0, 20))
+		case "OBSERVER / AGENCY":
+			hdr.Observer = strings.TrimSpace(field(val, 0, 20))
+			hdr.Agency = strings.TrimSpace(field(val, 20, 60))
+		case "# / TYPES OF OBSERV":
+			if n := strings.TrimSpace(field(val, 0, 6)); n != "" {
+				if nTypes, err = strconv.Atoi(n); err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+			}
+			hdr.ObsTypes = append(hdr.ObsTypes, strings.Fields(field(val, 6, len(val)))...)
+		case "SENSOR POS XYZ/H":
+			vals := strings.Fields(field(val, 0, 56))
+			typ := strings.TrimSpace(field(val, 57, 59))
+			if len(vals) != 4 {
+				return hdr, fmt.Errorf("parsing %q: line %d: expected 4 values", key, dec.lineNum)
+			}
+			var xyzh [4]float64
+			for i := range vals {
+				if xyzh[i], err = parseFloat(vals[i]); err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+			}
+			if hdr.SensorPos == nil {
+				hdr.SensorPos, hdr.SensorH = make(map[string]Coord, 3), make(map[string]float64, 3)
+			}
+			hdr.SensorPos[typ] = Coord{X: xyzh[0], Y: xyzh[1], Z: xyzh[2]}
+			hdr.SensorH[typ] = xyzh[3]
+		case "SENSOR MOD/TYPE/ACC":
+			// not needed
+		case "END OF HEADER":
+			break read
+		default:
+			hdr.warnings = append(hdr.warnings, fmt.Sprintf("header label not handled: %s", key))
+		}
+	}
+
+	if err = dec.sc.Err(); err != nil {
+		return
+	}
+	if nTypes != len(hdr.ObsTypes) {
+		err = fmt.Errorf("read header: %d observation types expected, found %d", nTypes, len(hdr.ObsTypes))
+	}
+	return
+}
+
+// NextRecord reads the next meteo data record.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (dec *MeteoDecoder) NextRecord() bool {
+	// 2020 10 20 19  0  2 1004.7   12.8   88.1  216.0    3.0    0.0
+	epoWidth, perLine := 20, 8 // RINEX 3: 1X,I4,5(1X,I2),8F7.1
+	if dec.Header.RINEXVersion < 3 {
+		epoWidth = 18 // 6(1X,I2)
+	}
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		epoFields := strings.Fields(field(line, 0, epoWidth))
+		if len(epoFields) != 6 {
+			dec.err = fmt.Errorf("invalid meteo data record in line %d: %q", dec.lineNum, line)
+			return false
+		}
+		if epoWidth == 18 {
+			yy, err := strconv.Atoi(epoFields[0])
+			if err != nil {
+				dec.err = fmt.Errorf("parsing epoch in line %d: %q: %w", dec.lineNum, line, err)
+				return false
+			}
+			epoFields[0] = strconv.Itoa(yy + 1900)
+			if yy < 80 {
+				epoFields[0] = strconv.Itoa(yy + 2000)
+			}
+		}
+		epTime, err := parseClkEpoch(epoFields)
+		if err != nil {
+			dec.err = fmt.Errorf("parsing epoch in line %d: %q: %w", dec.lineNum, line, err)
+			return false
+		}
+
+		rec := &MeteoRecord{Time: epTime, Types: dec.Header.ObsTypes, Values: make([]float64, len(dec.Header.ObsTypes))}
+		col := epoWidth
+		for i := range rec.Values {
+			if i >= perLine && (i-perLine)%10 == 0 { // continuation line: 4X,10F7.1
+				if !dec.sc.Scan() {
+					dec.err = fmt.Errorf("unexpected EOF after line %d", dec.lineNum)
+					return false
+				}
+				dec.lineNum++
+				line, col = dec.sc.Text(), 4
+			}
+			if s := strings.TrimSpace(field(line, col, col+7)); s != "" {
+				if rec.Values[i], err = parseFloat(s); err != nil {
+					dec.err = fmt.Errorf("parsing data value in line %d: %w", dec.lineNum, err)
+					return false
+				}
+			}
+			col += 7
+		}
+		dec.rec = rec
+		return true
+	}
+
+	if err := dec.sc.Err(); err != nil {
+		dec.err = fmt.Errorf("read records scanner error: %w", err)
+	}
+	return false // EOF
+}
+
+// Record returns the most recent record generated by a call to NextRecord.
+func (dec *MeteoDecoder) Record() *MeteoRecord {
+	return dec.rec
+}
+
 // NewMeteoFile returns a new RINEX Meteo file.
 func NewMeteoFile(filepath string) (*MeteoFile, error) {
 	met := &MeteoFile{RnxFil: &RnxFil{Path: filepath}}
@@ -44,24 +258,33 @@ func NewMeteoFile(filepath string) (*MeteoFile, error) {
 }
 
 // Compress a meteo file using the gzip format.
-// The source file will be removed if the compression finishes without errors.
+// The source file will be removed if the compression finishes without errors, Path is set to the compressed file.
 func (f *MeteoFile) Compress() error {
+	return f.CompressWithOptions(CompressOptions{})
+}
+
+// CompressWithOptions compresses a meteo file using the gzip format and the given options.
+// Path is set to the compressed file.
+func (f *MeteoFile) CompressWithOptions(opts CompressOptions) error {
 	if IsCompressed(f.Path) {
 		return nil
 	}
 
-	err := archiver.CompressFile(f.Path, f.Path+".gz")
+	src := f.Path
+	dst, err := compressRnx(src, opts, func(r io.Reader) error { return verifyHeader(r, 'M') })
 	if err != nil {
 		return err
 	}
-	os.Remove(f.Path)
-	f.Path = f.Path + ".gz"
+	f.Path = dst
 	f.Compression = "gz"
+	if !opts.KeepSource {
+		return os.Remove(src)
+	}
 	return nil
 }
 
 // Rnx3Filename returns the filename following the RINEX3 convention.
-// TODO !!!
+// The country code must come from an external source.
 func (f *MeteoFile) Rnx3Filename() (string, error) {
 	// Station Identifier
 	if len(f.FourCharID) != 4 {
@@ -97,6 +320,9 @@ func (f *MeteoFile) Rnx3Filename() (string, error) {
 	fn.WriteString(f.FilePeriod)
 	fn.WriteString("_")
 
+	fn.WriteString(f.DataFreq)
+	fn.WriteString("_")
+
 	fn.WriteString(f.DataType)
 	fn.WriteString(".rnx")
 
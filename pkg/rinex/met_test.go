@@ -1,8 +1,11 @@
 package rinex
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,3 +27,65 @@ func TestMeteoFile_Compress(t *testing.T) {
 	}
 	assert.Equal(filepath.Join(tempDir, "DIEP00DEU_R_20202941900_01H_10S_MM.rnx.gz"), rnx3Fil.Path, "crx.gz file")
 }
+
+func TestMeteoDecoder(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("testdata/white/DIEP00DEU_R_20202941900_01H_10S_MM.rnx")
+	assert.NoError(err)
+	defer r.Close()
+
+	dec, err := NewMeteoDecoder(r)
+	if !assert.NoError(err) {
+		return
+	}
+	hdr := dec.Header
+	assert.Equal(float32(3.04), hdr.RINEXVersion)
+	assert.Equal("DIEP", hdr.MarkerName)
+	assert.Equal("14287M001", hdr.MarkerNumber)
+	assert.Equal([]string{"PR", "TD", "HR", "WD", "WS", "RI"}, hdr.ObsTypes)
+	assert.Equal(Coord{X: 3842153.364, Y: 563401.649, Z: 5042888.216}, hdr.SensorPos["PR"])
+	assert.Equal(81.6782, hdr.SensorH["PR"])
+
+	var recs []*MeteoRecord
+	for dec.NextRecord() {
+		recs = append(recs, dec.Record())
+	}
+	assert.NoError(dec.Err())
+	assert.Len(recs, 360)
+	assert.Equal(time.Date(2020, 10, 20, 19, 0, 2, 0, time.UTC), recs[0].Time)
+	pr, ok := recs[0].Get("PR")
+	assert.True(ok)
+	assert.Equal(1004.7, pr)
+	hr, _ := recs[0].Get("HR")
+	assert.Equal(88.1, hr)
+	_, ok = recs[0].Get("ZW")
+	assert.False(ok)
+	wd, _ := recs[359].Get("WD")
+	assert.Equal(0.0, wd, "blank")
+}
+
+func TestMeteoDecoder_v2(t *testing.T) {
+	assert := assert.New(t)
+	data := `     2.11           METEOROLOGICAL DATA                     RINEX VERSION / TYPE
+WTZR                                                        MARKER NAME
+    10    PR    TD    HR    WD    WS    RI    HI    ZW    ZD# / TYPES OF OBSERV
+          ZT                                                # / TYPES OF OBSERV
+                                                            END OF HEADER
+ 20  6 18  0  0  0  946.4    9.8   78.1  120.0    1.1    0.0    0.0    0.1
+      2.3    4.5
+`
+	dec, err := NewMeteoDecoder(strings.NewReader(data))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(dec.Header.ObsTypes, 10)
+	assert.True(dec.NextRecord())
+	rec := dec.Record()
+	assert.Equal(time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC), rec.Time)
+	assert.Equal([]float64{946.4, 9.8, 78.1, 120, 1.1, 0, 0, 0.1, 2.3, 4.5}, rec.Values)
+	assert.False(dec.NextRecord())
+	assert.NoError(dec.Err())
+
+	_, err = NewMeteoDecoder(strings.NewReader("     2.11           OBSERVATION DATA    G                   RINEX VERSION / TYPE\n"))
+	assert.Error(err, "no meteo file")
+}
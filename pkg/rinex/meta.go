@@ -0,0 +1,150 @@
+package rinex
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/antex"
+	"github.com/de-bkg/gognss/pkg/site"
+)
+
+// eccTolerance is the tolerance in meters for comparing antenna eccentricities.
+const eccTolerance = 0.0005
+
+// StationMeta holds the station metadata to check RINEX headers against, e.g. from a sitelog.
+type StationMeta struct {
+	MarkerName   string // 4-char or 9-char station ID
+	MarkerNumber string // DOMES number
+	Receivers    []*site.Receiver
+	Antennas     []*site.Antenna
+}
+
+// NewStationMeta returns the station metadata of the site.
+func NewStationMeta(s *site.Site) StationMeta {
+	name := s.Ident.NineCharacterID
+	if name == "" {
+		name = s.Ident.FourCharacterID
+	}
+	return StationMeta{MarkerName: name, MarkerNumber: s.Ident.DOMESNumber, Receivers: s.Receivers, Antennas: s.Antennas}
+}
+
+// MetaMismatch is a difference between the RINEX header and the station metadata.
+type MetaMismatch struct {
+	Field  string // the header field, e.g. "receiver type"
+	Header string // the value in the RINEX header
+	Meta   string // the value expected from the station metadata
+}
+
+// String is a MetaMismatch Stringer.
+func (m MetaMismatch) String() string {
+	return fmt.Sprintf("%s: header %q, metadata %q", m.Field, m.Header, m.Meta)
+}
+
+// Validate compares the header with the station metadata, that is the marker name and number, the receiver
+// type, serial number and firmware, and the antenna type incl. radome, serial number and eccentricities.
+// The receiver and antenna are taken from the metadata valid for the time span given by TIME OF FIRST OBS
+// and TIME OF LAST OBS. It returns the mismatches found, if any.
+func (hdr *ObsHeader) Validate(meta StationMeta) []MetaMismatch {
+	var mm []MetaMismatch
+	add := func(field, hdrVal, metaVal string) {
+		mm = append(mm, MetaMismatch{Field: field, Header: hdrVal, Meta: metaVal})
+	}
+
+	if meta.MarkerName != "" && !equalMarkerName(hdr.MarkerName, meta.MarkerName) {
+		add("marker name", hdr.MarkerName, meta.MarkerName)
+	}
+	if meta.MarkerNumber != "" && hdr.MarkerNumber != meta.MarkerNumber {
+		add("marker number", hdr.MarkerNumber, meta.MarkerNumber)
+	}
+
+	from, until := hdr.TimeOfFirstObs, hdr.TimeOfLastObs
+	if until.IsZero() {
+		until = from
+	}
+
+	recvs := make([]*site.Receiver, 0, 1)
+	for _, recv := range meta.Receivers {
+		if overlaps(recv.DateInstalled, recv.DateRemoved, from, until) {
+			recvs = append(recvs, recv)
+		}
+	}
+	switch len(recvs) {
+	case 0:
+		add("receiver", hdr.ReceiverType, "no receiver installed")
+	case 1:
+		recv := recvs[0]
+		if hdr.ReceiverType != recv.Type {
+			add("receiver type", hdr.ReceiverType, recv.Type)
+		}
+		if hdr.ReceiverNumber != recv.SerialNum {
+			add("receiver serial number", hdr.ReceiverNumber, recv.SerialNum)
+		}
+		if hdr.ReceiverVersion != recv.Firmware {
+			add("receiver firmware", hdr.ReceiverVersion, recv.Firmware)
+		}
+	default:
+		add("receiver", hdr.ReceiverType, "receiver changed within the observation time span")
+	}
+
+	ants := make([]*site.Antenna, 0, 1)
+	for _, ant := range meta.Antennas {
+		if overlaps(ant.DateInstalled, ant.DateRemoved, from, until) {
+			ants = append(ants, ant)
+		}
+	}
+	switch len(ants) {
+	case 0:
+		add("antenna", hdr.AntennaType, "no antenna installed")
+	case 1:
+		ant := ants[0]
+		metaType := ant.Type
+		if len(strings.Fields(metaType)) < 2 && ant.Radome != "" {
+			metaType = fmt.Sprintf("%-16s%4s", ant.Type, ant.Radome)
+		}
+		if antex.NormalizeType(hdr.AntennaType) != antex.NormalizeType(metaType) {
+			add("antenna type", hdr.AntennaType, antex.NormalizeType(metaType))
+		}
+		if hdr.AntennaNumber != ant.SerialNum {
+			add("antenna serial number", hdr.AntennaNumber, ant.SerialNum)
+		}
+		if math.Abs(hdr.AntennaDelta.Up-ant.EccUp) > eccTolerance {
+			add("antenna height", fmt.Sprintf("%.4f", hdr.AntennaDelta.Up), fmt.Sprintf("%.4f", ant.EccUp))
+		}
+		if math.Abs(hdr.AntennaDelta.N-ant.EccNorth) > eccTolerance {
+			add("antenna north eccentricity", fmt.Sprintf("%.4f", hdr.AntennaDelta.N), fmt.Sprintf("%.4f", ant.EccNorth))
+		}
+		if math.Abs(hdr.AntennaDelta.E-ant.EccEast) > eccTolerance {
+			add("antenna east eccentricity", fmt.Sprintf("%.4f", hdr.AntennaDelta.E), fmt.Sprintf("%.4f", ant.EccEast))
+		}
+	default:
+		add("antenna", hdr.AntennaType, "antenna changed within the observation time span")
+	}
+
+	return mm
+}
+
+// equalMarkerName compares the marker names case-insensitive. A 4-char name matches a 9-char name
+// with the same 4-char prefix.
+func equalMarkerName(name, name2 string) bool {
+	if strings.EqualFold(name, name2) {
+		return true
+	}
+	if (len(name) == 4 && len(name2) == 9) || (len(name) == 9 && len(name2) == 4) {
+		return strings.EqualFold(name[:4], name2[:4])
+	}
+	return false
+}
+
+// overlaps reports whether the equipment installed at the time span [installed, removed] was in use during
+// [from, until]. A zero removed time means the equipment is still installed.
+func overlaps(installed, removed, from, until time.Time) bool {
+	if until.Before(installed) {
+		return false
+	}
+	if !removed.IsZero() && !from.Before(removed) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package rinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/site"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsHeader_Validate(t *testing.T) {
+	assert := assert.New(t)
+	meta := StationMeta{
+		MarkerName:   "WTZR00DEU",
+		MarkerNumber: "14201M010",
+		Receivers: []*site.Receiver{
+			{Type: "JAVAD TRE_G3TH DELTA", SerialNum: "00411", Firmware: "3.6.7",
+				DateInstalled: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), DateRemoved: time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC)},
+			{Type: "SEPT POLARX5", SerialNum: "3047935", Firmware: "5.3.2",
+				DateInstalled: time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC)},
+		},
+		Antennas: []*site.Antenna{
+			{Type: "LEIAR25.R3", Radome: "LEIT", SerialNum: "10180007", EccUp: 0.071,
+				DateInstalled: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	hdr := ObsHeader{
+		MarkerName: "WTZR", MarkerNumber: "14201M010",
+		ReceiverType: "SEPT POLARX5", ReceiverNumber: "3047935", ReceiverVersion: "5.3.2",
+		AntennaType: "LEIAR25.R3      LEIT", AntennaNumber: "10180007", AntennaDelta: CoordNEU{Up: 0.0710},
+		TimeOfFirstObs: time.Date(2020, 6, 19, 0, 0, 0, 0, time.UTC),
+		TimeOfLastObs:  time.Date(2020, 6, 19, 23, 59, 30, 0, time.UTC),
+	}
+	assert.Empty(hdr.Validate(meta))
+
+	hdr.MarkerName = "WTZZ"
+	hdr.ReceiverVersion = "5.3.0"
+	hdr.AntennaType = "LEIAR25.R3      NONE"
+	hdr.AntennaDelta.N = 0.002
+	mm := hdr.Validate(meta)
+	if assert.Len(mm, 4) {
+		assert.Equal(MetaMismatch{Field: "marker name", Header: "WTZZ", Meta: "WTZR00DEU"}, mm[0])
+		assert.Equal("receiver firmware", mm[1].Field)
+		assert.Equal(MetaMismatch{Field: "antenna type", Header: "LEIAR25.R3      NONE", Meta: "LEIAR25.R3      LEIT"}, mm[2])
+		assert.Equal("antenna north eccentricity", mm[3].Field)
+	}
+
+	// receiver change within the time span
+	hdr = ObsHeader{MarkerName: "WTZR00DEU", TimeOfFirstObs: time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC),
+		TimeOfLastObs: time.Date(2020, 6, 18, 23, 59, 30, 0, time.UTC), AntennaType: "LEIAR25.R3      LEIT",
+		AntennaNumber: "10180007", AntennaDelta: CoordNEU{Up: 0.0710}}
+	mm = hdr.Validate(meta)
+	if assert.Len(mm, 2) {
+		assert.Equal("marker number", mm[0].Field)
+		assert.Equal("receiver", mm[1].Field)
+	}
+}
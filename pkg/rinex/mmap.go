@@ -0,0 +1,171 @@
+package rinex
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// errMmapUnsupported is returned by mmapFile on platforms without memory mapping.
+var errMmapUnsupported = errors.New("memory mapping not supported")
+
+// ErrFileInUse is returned by MappedFile.Close while a parallel decoder still reads the mapping.
+var ErrFileInUse = errors.New("close: mapped file in use by a parallel decoder, call Stop first")
+
+// MappedFile is a file opened by OpenMapped. The decoders slice the lines of a memory-mapped file
+// directly from the mapping, without copying them into a read buffer, which speeds up the decoding
+// of large files. If the file could not be mapped, it is read like a file opened by OpenFile.
+type MappedFile struct {
+	data []byte        // the mapped file, nil if not mapped
+	off  int64         // read offset in data
+	rc   io.ReadCloser // the file if not mapped
+
+	mu    sync.Mutex
+	users int // running parallel decoders reading data, see acquire
+}
+
+// OpenMapped opens the named file for reading and maps it into memory, on the platforms that support it.
+// Compressed and empty files and files that can not be mapped are read as by OpenFile instead.
+// The lines returned by the decoders are only valid until Close is called and the file must not be
+// truncated while it is mapped. A decoder with Workers must be stopped before the file is closed.
+func OpenMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(magicZip))
+	n, _ := io.ReadFull(f, magic)
+	compressed := bytes.HasPrefix(magic[:n], magicGzip) || bytes.HasPrefix(magic[:n], magicLZW) ||
+		bytes.HasPrefix(magic[:n], magicZip)
+	if !compressed {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && fi.Size() > 0 && int64(int(fi.Size())) == fi.Size() {
+			data, err := mmapFile(f, int(fi.Size()))
+			if err == nil {
+				f.Close() // the mapping stays valid
+				return &MappedFile{data: data}, nil
+			}
+		}
+	}
+	f.Close()
+
+	rc, err := OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MappedFile{rc: rc}, nil
+}
+
+// Mapped returns true if the file is memory-mapped.
+func (f *MappedFile) Mapped() bool {
+	return f.data != nil
+}
+
+// Read implements io.Reader.
+func (f *MappedFile) Read(p []byte) (int, error) {
+	if f.data == nil {
+		return f.rc.Read(p)
+	}
+	if f.off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker. Files that are not mapped can only be seeked if they are not compressed.
+func (f *MappedFile) Seek(offset int64, whence int) (int64, error) {
+	if f.data == nil {
+		if s, ok := f.rc.(io.Seeker); ok {
+			return s.Seek(offset, whence)
+		}
+		return 0, errors.New("seek: file not seekable")
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("seek: negative position")
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// Close unmaps or closes the file. It returns ErrFileInUse without unmapping the file while
+// a parallel decoder reads it, see ObsDecoder.Stop.
+func (f *MappedFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.users > 0 {
+		return ErrFileInUse
+	}
+	if f.data == nil {
+		return f.rc.Close()
+	}
+	data := f.data
+	f.data, f.rc = nil, eofCloser{}
+	return munmapFile(data)
+}
+
+// acquire keeps the mapping valid until release is called, Close refuses to unmap it meanwhile.
+func (f *MappedFile) acquire() {
+	f.mu.Lock()
+	f.users++
+	f.mu.Unlock()
+}
+
+// release releases the mapping acquired by acquire.
+func (f *MappedFile) release() {
+	f.mu.Lock()
+	f.users--
+	f.mu.Unlock()
+}
+
+// eofCloser is the reader of a closed MappedFile.
+type eofCloser struct{}
+
+func (eofCloser) Read([]byte) (int, error) { return 0, os.ErrClosed }
+func (eofCloser) Close() error             { return os.ErrClosed }
+
+// newMappedLineReader returns a lineReader that slices the lines from the mapping of the file, starting at
+// its read offset. A maxLen of 0 means DefaultMaxLineLength.
+func newMappedLineReader(f *MappedFile, maxLen int) *lineReader {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLineLength
+	}
+	return &lineReader{data: f.data, file: f, maxLen: maxLen, pos: f.off, start: f.off}
+}
+
+// scanMapped advances to the next line of the mapped data, see Scan.
+func (lr *lineReader) scanMapped() bool {
+	lr.start = lr.pos
+	if !lr.file.Mapped() { // unmapped by Close
+		lr.line, lr.err = nil, os.ErrClosed
+		return false
+	}
+	if lr.pos >= int64(len(lr.data)) {
+		lr.err = io.EOF
+		return false
+	}
+	rest := lr.data[lr.pos:]
+	line := rest
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		line = rest[:i+1]
+	}
+	lr.pos += int64(len(line))
+	if lr.progress != nil {
+		lr.progress.AddBytes(int64(len(line)))
+	}
+	line = dropEOL(line)
+	if len(line) > lr.maxLen {
+		lr.err = ErrLineTooLong
+		return false
+	}
+	lr.line = line
+	return true
+}
@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package rinex
+
+import "os"
+
+// mmapSupported is true if files can be memory-mapped on this platform.
+const mmapSupported = false
+
+// mmapFile is not supported on this platform, OpenMapped reads the file instead.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
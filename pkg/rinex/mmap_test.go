@@ -0,0 +1,150 @@
+package rinex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenMapped(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+
+	f, err := OpenMapped(reykFile)
+	if !assert.NoError(err) {
+		return
+	}
+	defer f.Close()
+	assert.Equal(mmapSupported, f.Mapped())
+	tr := NewProgressTracker()
+	dec, err := NewObsDecoder(f, WithProgress(tr))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("REYK", dec.Header.MarkerName)
+	var epochs []string
+	for dec.NextEpoch() {
+		epochs = append(epochs, fmt.Sprintf("%v", *dec.Epoch()))
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(epochStrings(t, data), epochs)
+	assert.Equal(int64(len(data)), tr.Status().Bytes)
+	assert.Equal(int64(len(data)), tr.Status().Total)
+
+	// random access
+	epo := time.Date(2019, 9, 27, 10, 30, 0, 0, time.UTC)
+	if assert.NoError(dec.SeekEpoch(epo)) && assert.True(dec.NextEpoch()) {
+		assert.Equal(epo, dec.Epoch().Time)
+	}
+	assert.NoError(f.Close())
+	_, err = f.Read(make([]byte, 1))
+	assert.Error(err)
+}
+
+func TestOpenMapped_fallback(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	gzPath := filepath.Join(dir, filepath.Base(reykFile)+".gz")
+	assert.NoError(CompressFile(reykFile, gzPath))
+	f, err := OpenMapped(gzPath)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.False(f.Mapped())
+	dec, err := NewObsDecoder(f)
+	assert.NoError(err)
+	n := 0
+	for dec.NextEpoch() {
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(120, n)
+	_, err = f.Seek(0, 0)
+	assert.Error(err, "compressed")
+	assert.NoError(f.Close())
+
+	empty := filepath.Join(dir, "empty.rnx")
+	assert.NoError(ioutil.WriteFile(empty, nil, 0644))
+	f, err = OpenMapped(empty)
+	if assert.NoError(err) {
+		assert.False(f.Mapped())
+		f.Close()
+	}
+	_, err = OpenMapped(filepath.Join(dir, "missing.rnx"))
+	assert.Error(err)
+}
+
+func TestOpenMapped_workersStop(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	i := bytes.Index(data, []byte("END OF HEADER"))
+	i += bytes.IndexByte(data[i:], '\n') + 1
+	big := append([]byte{}, data...)
+	for len(big) < 20<<20 { // the splitter is still scanning when the decoding stops
+		big = append(big, data[i:]...)
+	}
+	path := filepath.Join(t.TempDir(), filepath.Base(reykFile))
+	assert.NoError(ioutil.WriteFile(path, big, 0644))
+
+	window := WithTimeWindow(time.Time{}, time.Date(2019, 9, 27, 10, 5, 0, 0, time.UTC))
+	for _, opts := range [][]Option{nil, {window}} {
+		f, err := OpenMapped(path)
+		if !assert.NoError(err) {
+			return
+		}
+		dec, err := NewObsDecoder(f, opts...)
+		assert.NoError(err)
+		dec.Workers = 4
+		n := 0
+		for dec.NextEpoch() && n < 10 {
+			n++
+		}
+		if f.Mapped() && opts == nil {
+			assert.Equal(ErrFileInUse, f.Close(), "pipeline running")
+		}
+		dec.Stop()
+		assert.NoError(f.Close())
+		assert.False(dec.NextEpoch())
+		assert.Equal(10, n)
+	}
+}
+
+func TestLineReader_mapped(t *testing.T) {
+	assert := assert.New(t)
+	lr := newMappedLineReader(&MappedFile{data: []byte("a\r\nbb\n\nend")}, 3)
+	var lines []string
+	for lr.Scan() {
+		lines = append(lines, lr.Text())
+	}
+	assert.NoError(lr.Err())
+	assert.Equal([]string{"a", "bb", "", "end"}, lines)
+
+	lr = newMappedLineReader(&MappedFile{data: []byte("a\n" + strings.Repeat("x", 4) + "\n")}, 3)
+	assert.True(lr.Scan())
+	assert.False(lr.Scan())
+	assert.Equal(ErrLineTooLong, lr.Err())
+}
+
+func TestScanDir_memoryMap(t *testing.T) {
+	assert := assert.New(t)
+	n := 0
+	for res := range ScanDir(context.Background(), "testdata/white", ScanOptions{MemoryMap: true}) {
+		if res.File.Format == "crx" {
+			continue
+		}
+		n++
+		if assert.NoError(res.Err, res.Path) {
+			assert.NotNil(res.Stat)
+		}
+	}
+	assert.True(n > 0)
+}
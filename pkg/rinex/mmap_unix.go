@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package rinex
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapSupported is true if files can be memory-mapped on this platform.
+const mmapSupported = true
+
+// mmapFile maps the first size bytes of the file read-only into memory.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile unmaps data mapped by mmapFile.
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
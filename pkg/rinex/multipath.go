@@ -0,0 +1,245 @@
+package rinex
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// Defaults of the MultipathOptions.
+const (
+	DefaultMultipathMaxGap       = 5 * time.Minute
+	DefaultMultipathMaxJump      = 5.0 // meters
+	DefaultMultipathMinArcEpochs = 10
+)
+
+// MultipathOptions configures Multipath. Zero values are replaced by the defaults.
+type MultipathOptions struct {
+	MaxGap       time.Duration // a longer data gap starts a new arc
+	MaxJump      float64       // a larger change of the combination between two epochs in meters, e.g. a cycle slip, starts a new arc
+	MinArcEpochs int           // shorter arcs are dropped, as their mean is not reliable
+}
+
+// MultipathPoint is a value of the multipath combination.
+type MultipathPoint struct {
+	Time      time.Time `json:"time"`
+	Value     float64   `json:"value"`               // in meters, the mean of the arc removed
+	Elevation float64   `json:"elevation,omitempty"` // in degrees, 0 if unknown
+}
+
+// MultipathSeries is the time series of the multipath combination of a satellite and code signal.
+type MultipathSeries struct {
+	PRN    PRN              `json:"-"`
+	Sat    string           `json:"sat"`  // e.g. G01
+	Name   string           `json:"name"` // MP and the frequency band, e.g. MP1
+	Code   string           `json:"code"` // code observation type, e.g. C1C
+	RMS    float64          `json:"rms"`  // in meters
+	Points []MultipathPoint `json:"points"`
+}
+
+// Multipath computes the multipath combinations MP1 and MP2, as known from teqc, of the default bands of
+// each satellite's system, see DefaultBands, for the epochs of the decoder:
+//
+//	MP1 = P1 - (1 + 2/(a-1)) L1 + 2/(a-1) L2
+//	MP2 = P2 - 2a/(a-1) L1 + (2a/(a-1) - 1) L2,  a = (f1/f2)²
+//
+// The combinations contain the code multipath and noise and the phase ambiguities, which are removed
+// by subtracting the mean of each arc. An arc ends at a loss of lock of a phase, a data gap or a jump,
+// see MultipathOptions. The series are named by the band, e.g. MP1 and MP5 for Galileo.
+//
+// If the decoder has an EphemerisStore and the header a position, the elevations are added.
+func Multipath(dec *ObsDecoder, opts MultipathOptions) ([]MultipathSeries, error) {
+	mc := newMultipathComputer(opts, dec.Header.GloSlots)
+	var marker Coord
+	if dec.EphemerisStore != nil {
+		marker = dec.Header.Position
+	}
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if epo.IsEvent() || epo.IsCycleSlip() {
+			continue
+		}
+		var azel map[PRN]AzEl
+		if marker != (Coord{}) {
+			azel = dec.EphemerisStore.AzEl(epo, marker)
+		}
+		mc.add(epo, azel)
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read epochs: %w", err)
+	}
+	return mc.result(), nil
+}
+
+// multipathKey identifies a series.
+type multipathKey struct {
+	prn  PRN
+	code string
+}
+
+// multipathArc collects the values of the current arc of a series.
+type multipathArc struct {
+	name           string
+	phase1, phase2 string
+	points         []MultipathPoint // the values including the ambiguities
+}
+
+type multipathComputer struct {
+	opts     MultipathOptions
+	gloSlots GloSlots
+	arcs     map[multipathKey]*multipathArc
+	series   map[multipathKey]*MultipathSeries
+}
+
+func newMultipathComputer(opts MultipathOptions, gloSlots GloSlots) *multipathComputer {
+	if opts.MaxGap <= 0 {
+		opts.MaxGap = DefaultMultipathMaxGap
+	}
+	if opts.MaxJump <= 0 {
+		opts.MaxJump = DefaultMultipathMaxJump
+	}
+	if opts.MinArcEpochs <= 0 {
+		opts.MinArcEpochs = DefaultMultipathMinArcEpochs
+	}
+	return &multipathComputer{opts: opts, gloSlots: gloSlots,
+		arcs: make(map[multipathKey]*multipathArc), series: make(map[multipathKey]*MultipathSeries)}
+}
+
+func (mc *multipathComputer) add(epo *Epoch, azel map[PRN]AzEl) {
+	for _, satObs := range epo.ObsList {
+		sys := satObs.Prn.Sys
+		band1, band2, ok := DefaultBands(sys)
+		if !ok {
+			continue
+		}
+		channel, ok := mc.gloSlots[satObs.Prn]
+		if sys == gnss.SysGLO && !ok {
+			continue
+		}
+		f1, f2 := bandFrequency(sys, band1, channel), bandFrequency(sys, band2, channel)
+		if f1 == 0 || f2 == 0 || f1 == f2 {
+			continue
+		}
+		code1, phase1 := satObs.pairObs(band1)
+		code2, phase2 := satObs.pairObs(band2)
+		if phase1 == "" || phase2 == "" {
+			continue
+		}
+		o1, _ := satObs.Get(phase1)
+		o2, _ := satObs.Get(phase2)
+		l1, l2 := o1.Val*gnss.SpeedOfLight/f1, o2.Val*gnss.SpeedOfLight/f2
+		slip := o1.LLI&1 != 0 || o2.LLI&1 != 0
+
+		a := f1 * f1 / (f2 * f2)
+		pt := MultipathPoint{Time: epo.Time, Elevation: azel[satObs.Prn].El}
+		if code1 != "" {
+			p, _ := satObs.Get(code1)
+			pt.Value = p.Val - (1+2/(a-1))*l1 + 2/(a-1)*l2
+			mc.addPoint(satObs.Prn, "MP"+string(band1), code1, phase1, phase2, pt, slip)
+		}
+		if code2 != "" {
+			p, _ := satObs.Get(code2)
+			pt.Value = p.Val - 2*a/(a-1)*l1 + (2*a/(a-1)-1)*l2
+			mc.addPoint(satObs.Prn, "MP"+string(band2), code2, phase1, phase2, pt, slip)
+		}
+	}
+}
+
+// addPoint adds the value to the arc of the series, a new arc is started if the phases changed, at a
+// loss of lock, a gap or a jump.
+func (mc *multipathComputer) addPoint(prn PRN, name, code, phase1, phase2 string, pt MultipathPoint, slip bool) {
+	k := multipathKey{prn: prn, code: code}
+	arc, ok := mc.arcs[k]
+	if !ok {
+		arc = &multipathArc{name: name}
+		mc.arcs[k] = arc
+	}
+	if n := len(arc.points); n > 0 {
+		last := arc.points[n-1]
+		if slip || arc.phase1 != phase1 || arc.phase2 != phase2 || pt.Time.Sub(last.Time) > mc.opts.MaxGap ||
+			math.Abs(pt.Value-last.Value) > mc.opts.MaxJump {
+			mc.closeArc(k, arc)
+		}
+	}
+	arc.phase1, arc.phase2 = phase1, phase2
+	arc.points = append(arc.points, pt)
+}
+
+// closeArc removes the mean of the arc and adds its values to the series.
+func (mc *multipathComputer) closeArc(k multipathKey, arc *multipathArc) {
+	if len(arc.points) >= mc.opts.MinArcEpochs {
+		mean := 0.0
+		for _, pt := range arc.points {
+			mean += pt.Value
+		}
+		mean /= float64(len(arc.points))
+
+		s, ok := mc.series[k]
+		if !ok {
+			s = &MultipathSeries{PRN: k.prn, Sat: k.prn.String(), Name: arc.name, Code: k.code}
+			mc.series[k] = s
+		}
+		for _, pt := range arc.points {
+			pt.Value -= mean
+			s.Points = append(s.Points, pt)
+		}
+	}
+	arc.points = arc.points[:0]
+}
+
+// result returns the series sorted by satellite and code type.
+func (mc *multipathComputer) result() []MultipathSeries {
+	for k, arc := range mc.arcs {
+		mc.closeArc(k, arc)
+	}
+	list := make([]MultipathSeries, 0, len(mc.series))
+	for _, s := range mc.series {
+		sum := 0.0
+		for _, pt := range s.Points {
+			sum += pt.Value * pt.Value
+		}
+		s.RMS = math.Sqrt(sum / float64(len(s.Points)))
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.PRN != b.PRN {
+			if a.PRN.Sys != b.PRN.Sys {
+				return a.PRN.Sys < b.PRN.Sys
+			}
+			return a.PRN.Num < b.PRN.Num
+		}
+		return a.Code < b.Code
+	})
+	return list
+}
+
+// WriteMultipathCSV writes the series as CSV with a header line and the columns time, sat, name, code,
+// value and elevation, one line per value, e.g. for plotting.
+func WriteMultipathCSV(w io.Writer, series []MultipathSeries) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "sat", "name", "code", "value", "elevation"})
+	for _, s := range series {
+		for _, pt := range s.Points {
+			cw.Write([]string{pt.Time.Format(time.RFC3339Nano), s.Sat, s.Name, s.Code,
+				strconv.FormatFloat(pt.Value, 'f', 4, 64), strconv.FormatFloat(pt.Elevation, 'f', 2, 64)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMultipathJSON writes the series as JSON array.
+func WriteMultipathJSON(w io.Writer, series []MultipathSeries) error {
+	if series == nil {
+		series = []MultipathSeries{}
+	}
+	return json.NewEncoder(w).Encode(series)
+}
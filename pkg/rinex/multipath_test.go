@@ -0,0 +1,97 @@
+package rinex
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipath(t *testing.T) {
+	assert := assert.New(t)
+	const (
+		f1 = 1575.42e6
+		f2 = 1227.60e6
+	)
+	lambda1, lambda2 := gnss.SpeedOfLight/f1, gnss.SpeedOfLight/f2
+	gamma := f1 * f1 / (f2 * f2)
+	g05 := PRN{Sys: gnss.SysGPS, Num: 5}
+	start := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	mp := func(i int) float64 { return 0.5 * math.Sin(float64(i)/3) }
+
+	// a cycle slip with loss of lock after 25 epochs
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, ObsHeader{RINEXVersion: 3.04, RINEXType: "O", SatSystem: gnss.SysGPS, MarkerName: "SIMU",
+		Interval: 30, ObsTypes: map[gnss.System][]string{gnss.SysGPS: {"C1W", "L1W", "C2W", "L2W"}}})
+	for i := 0; i < 30; i++ {
+		rho, iono := 22e6+float64(i)*300, 5+float64(i)*0.01
+		n1, lli := 12.0, int8(0)
+		if i >= 25 {
+			n1 = 20
+		}
+		if i == 25 {
+			lli = 1
+		}
+		epo := &Epoch{Time: start.Add(time.Duration(i) * 30 * time.Second)}
+		epo.ObsList = append(epo.ObsList, NewSatObs(g05, map[string]Obs{
+			"C1W": {Val: rho + iono + mp(i)},
+			"L1W": {Val: (rho-iono)/lambda1 + n1, LLI: lli},
+			"C2W": {Val: rho + gamma*iono},
+			"L2W": {Val: (rho-gamma*iono)/lambda2 + 3},
+		}))
+		assert.NoError(enc.Encode(epo))
+	}
+	assert.NoError(enc.Flush())
+	data := buf.Bytes()
+
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	series, err := Multipath(dec, MultipathOptions{})
+	assert.NoError(err)
+	if !assert.Len(series, 2) {
+		return
+	}
+	mp1 := series[0]
+	assert.Equal("G05", mp1.Sat)
+	assert.Equal("MP1", mp1.Name)
+	assert.Equal("C1W", mp1.Code)
+	assert.Len(mp1.Points, 25, "short arc dropped")
+	mean := 0.0
+	for i := 0; i < 25; i++ {
+		mean += mp(i) / 25
+	}
+	for i, pt := range mp1.Points {
+		assert.Equal(start.Add(time.Duration(i)*30*time.Second), pt.Time)
+		assert.InDelta(mp(i)-mean, pt.Value, 0.01)
+		assert.Zero(pt.Elevation)
+	}
+	assert.InDelta(0.35, mp1.RMS, 0.05)
+	assert.Equal("MP2", series[1].Name)
+	assert.Equal("C2W", series[1].Code)
+	assert.InDelta(0, series[1].RMS, 0.01)
+
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	series, err = Multipath(dec, MultipathOptions{MinArcEpochs: 5})
+	assert.NoError(err)
+	assert.Len(series[0].Points, 30)
+
+	buf.Reset()
+	assert.NoError(WriteMultipathCSV(&buf, series))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 61)
+	assert.Equal("time,sat,name,code,value,elevation", lines[0])
+	assert.True(strings.HasPrefix(lines[1], "2020-06-18T00:00:00Z,G05,MP1,C1W,"), lines[1])
+
+	buf.Reset()
+	assert.NoError(WriteMultipathJSON(&buf, series))
+	var got []MultipathSeries
+	assert.NoError(json.Unmarshal(buf.Bytes(), &got))
+	assert.Len(got, 2)
+	assert.Equal("MP2", got[1].Name)
+}
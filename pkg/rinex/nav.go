@@ -10,8 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/mholt/archiver/v3"
-
 	"github.com/de-bkg/gognss/pkg/gnss"
 )
 
@@ -28,7 +26,7 @@ type Eph interface {
 	unmarshal(data []byte) error
 }
 
-// NewEph returns a new ephemeris having the concrete type.
+// NewEph returns a new ephemeris having the concrete type, or nil for an unknown satellite system.
 func NewEph(sys gnss.System) Eph {
 	var eph Eph
 	switch sys {
@@ -47,8 +45,7 @@ func NewEph(sys gnss.System) Eph {
 	case gnss.SysSBAS:
 		eph = &EphSBAS{}
 	default:
-		fmt.Printf("unknown satellite system: %v", sys)
-		os.Exit(1)
+		return nil
 	}
 
 	return eph
@@ -109,8 +106,9 @@ type EphGPS struct {
 
 // EphGLO describes a GLONASS ephemeris.
 type EphGLO struct {
-	PRN PRN
-	TOC time.Time
+	PRN     PRN
+	TOC     time.Time
+	FreqNum int // frequency channel number
 }
 
 // EphGAL describes a Galileo ephemeris.
@@ -163,31 +161,31 @@ func (eph *EphGPS) unmarshal(data []byte) (err error) {
 		return
 	}
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysGPS, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysGPS, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
 	}
 
-	eph.ClockBias, err = parseFloat(line[23 : 23+19])
+	eph.ClockBias, err = parseFloat(field(line, 23, 23+19))
 	if err != nil {
 		return
 	}
 
-	eph.ClockDrift, err = parseFloat(line[42 : 42+19])
+	eph.ClockDrift, err = parseFloat(field(line, 42, 42+19))
 	if err != nil {
 		return
 	}
 
-	eph.ClockDriftRate, err = parseFloat(line[61 : 61+19])
+	eph.ClockDriftRate, err = parseFloat(field(line, 61, 61+19))
 	if err != nil {
 		return
 	}
@@ -264,18 +262,34 @@ func (eph *EphGLO) unmarshal(data []byte) error {
 	r := bufio.NewReader(bytes.NewReader(data))
 	line, err := r.ReadString('\n')
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysGLO, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysGLO, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
+	}
+
+	// the frequency number is the last field of broadcast orbit 2
+	var orbit2 string
+	for i := 0; i < 2; i++ {
+		if orbit2, err = r.ReadString('\n'); err != nil && orbit2 == "" {
+			return fmt.Errorf("%s: missing broadcast orbit lines", eph.PRN)
+		}
+	}
+	orbit2 = strings.TrimRight(orbit2, "\r\n")
+	if len(orbit2) >= 80 {
+		f64, err := strconv.ParseFloat(strings.TrimSpace(strings.Replace(orbit2[61:80], "D", "E", 1)), 64)
+		if err != nil {
+			return fmt.Errorf("%s: could not parse frequency number: %q: %w", eph.PRN, orbit2, err)
+		}
+		eph.FreqNum = int(f64)
 	}
 
 	return nil
@@ -286,18 +300,18 @@ func (eph *EphGAL) unmarshal(data []byte) error {
 	r := bufio.NewReader(bytes.NewReader(data))
 	line, err := r.ReadString('\n')
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysGAL, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysGAL, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
 	}
 
 	return nil
@@ -308,18 +322,18 @@ func (eph *EphQZSS) unmarshal(data []byte) error {
 	r := bufio.NewReader(bytes.NewReader(data))
 	line, err := r.ReadString('\n')
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysQZSS, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysQZSS, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
 	}
 
 	return nil
@@ -330,18 +344,18 @@ func (eph *EphBDS) unmarshal(data []byte) error {
 	r := bufio.NewReader(bytes.NewReader(data))
 	line, err := r.ReadString('\n')
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysBDS, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysBDS, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
 	}
 
 	return nil
@@ -352,18 +366,18 @@ func (eph *EphIRNSS) unmarshal(data []byte) error {
 	r := bufio.NewReader(bytes.NewReader(data))
 	line, err := r.ReadString('\n')
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysIRNSS, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysIRNSS, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
 	}
 
 	return nil
@@ -374,18 +388,18 @@ func (eph *EphSBAS) unmarshal(data []byte) error {
 	r := bufio.NewReader(bytes.NewReader(data))
 	line, err := r.ReadString('\n')
 
-	snum, err := strconv.Atoi(line[1:3])
+	snum, err := strconv.Atoi(field(line, 1, 3))
 	if err != nil {
-		return fmt.Errorf("Could not parse sat num: %q: %v", line, err)
+		return fmt.Errorf("Could not parse sat num: %q: %w", line, err)
 	}
-	eph.PRN, err = newPRN(gnss.SysSBAS, int8(snum))
+	eph.PRN, err = NewPRN(gnss.SysSBAS, snum)
 	if err != nil {
 		return err
 	}
 
-	eph.TOC, err = time.Parse(TimeOfClockFormat, line[4:23])
+	eph.TOC, err = time.Parse(TimeOfClockFormat, field(line, 4, 23))
 	if err != nil {
-		return fmt.Errorf("Could not parse TOC: '%s': %v", line, err)
+		return fmt.Errorf("Could not parse TOC: '%s': %w", line, err)
 	}
 
 	return nil
@@ -405,10 +419,38 @@ type NavHeader struct {
 
 	Comments []string // * comment lines
 
+	// IonoCorr contains the ionospheric correction parameters per correction type, e.g. GPSA and GPSB
+	// for the Klobuchar alpha and beta or GAL for the Galileo ai0, ai1, ai2.
+	// The RINEX 2 ION ALPHA and ION BETA are stored as GPSA and GPSB.
+	IonoCorr map[string][4]float64
+
+	// TimeSysCorrs are the corrections of the system times to UTC or to other system times. The RINEX 2
+	// records DELTA-UTC: A0,A1,T,W, CORR TO SYSTEM TIME and D-UTC A0,A1,T,W,S,U are stored as GPUT, GLUT
+	// and SBUT.
+	TimeSysCorrs []TimeSysCorr
+	LeapSeconds  int // The current number of leap seconds
+
+	ReceiverNumber, ReceiverType, ReceiverVersion string // receiver, RINEX 3.05+ for single station files
+
+	MergedFiles  int      // Number of files merged, RINEX 3.05+
+	DOI          string   // Digital Object Identifier of the data, RINEX 3.05+
+	Licenses     []string // License of use, RINEX 3.05+
+	StationInfos []string // Links to further station information, RINEX 3.05+
+
 	labels   []string // all Header Labels found
 	warnings []string
 }
 
+// TimeSysCorr is the correction of a system time to UTC or to another system time, a0 + a1*(t - tref).
+type TimeSysCorr struct {
+	Type    string  // correction type, e.g. GPUT for GPS to UTC or GAGP for Galileo to GPS
+	A0, A1  float64 // in s and s/s
+	RefTime int     // reference time of the polynomial in seconds of the week
+	RefWeek int     // reference week number
+	Source  string  // e.g. the satellite number for SBAS, optional
+	UTCID   int     // UTC identifier, optional
+}
+
 // A headerLabel is a RINEX Header Label.
 type headerLabel struct {
 	label    string
@@ -422,13 +464,21 @@ type NavDecoder struct {
 	// e.g. if you want to read from a stream. Then ErrNoHeader will be returned.
 	Header NavHeader
 
+	// Name of the input, e.g. the file name, used in the History comments of the written files.
+	Name string
+
 	//b       *bufio.Reader
-	sc  *bufio.Scanner
-	eph Eph
+	sc      *lineReader
+	decOpts DecoderOptions
+	eph     Eph
 	//ephLines []string
 	buf     bytes.Buffer
 	lineNum int
 	err     error
+
+	// RINEX 4 only: the record header line read ahead, e.g. "> EPH G01 LNAV"
+	nextRecHdr []byte
+	recHdr     []byte // of the current record
 }
 
 // NewNavDecoder creates a new decoder for RINEX Navigation data.
@@ -437,7 +487,6 @@ type NavDecoder struct {
 //
 // It is the caller's responsibility to call Close on the underlying reader when done!
 func NewNavDecoder(r io.Reader) (*NavDecoder, error) {
-	var err error
 	//br := bufio.NewReader(r)
 	/* 	rc, ok := r.(io.ReadCloser)
 	   	if !ok && r != nil {
@@ -445,7 +494,14 @@ func NewNavDecoder(r io.Reader) (*NavDecoder, error) {
 	   		rc = ioutil.NopCloser(r)
 	   	}
 	   	dec := &NavDecoder{r: rc} */
-	dec := &NavDecoder{sc: bufio.NewScanner(r)}
+	return NewNavDecoderWithOptions(r, DecoderOptions{})
+}
+
+// NewNavDecoderWithOptions creates a new decoder for RINEX Navigation data with the given
+// line length and header limits.
+func NewNavDecoderWithOptions(r io.Reader, opts DecoderOptions) (*NavDecoder, error) {
+	var err error
+	dec := &NavDecoder{sc: opts.lineReader(r), decOpts: opts}
 	// TODO: reset reader?
 	// if err := dec.Reset(r); err != nil {
 	// 	return nil, err
@@ -466,6 +522,11 @@ func (dec *NavDecoder) Err() error {
 
 func (dec *NavDecoder) unmarshal(sys gnss.System) error {
 	eph := NewEph(sys)
+	if eph == nil {
+		err := fmt.Errorf("invalid satellite system in line %d: %v", dec.lineNum, sys)
+		dec.setErr(err)
+		return err
+	}
 	err := eph.unmarshal(dec.buf.Bytes())
 	if err != nil {
 		dec.setErr(err)
@@ -514,7 +575,7 @@ func (dec *NavDecoder) readHeader() (hdr NavHeader, err error) {
 	   	} */
 
 	// Now we can read the header
-	maxLines := 300
+	maxLines := dec.decOpts.maxHeaderLines(300)
 read:
 	for dec.sc.Scan() {
 		dec.lineNum++
@@ -546,7 +607,7 @@ read:
 			if f64, err := strconv.ParseFloat(strings.TrimSpace(val[:20]), 32); err == nil {
 				hdr.RINEXVersion = float32(f64)
 			} else {
-				return hdr, fmt.Errorf("Could not parse RINEX VERSION: %v", err)
+				return hdr, fmt.Errorf("Could not parse RINEX VERSION: %w", err)
 			}
 			hdr.RINEXType = strings.TrimSpace(val[20:21])
 
@@ -569,7 +630,9 @@ read:
 			   				}
 			   				else { $ok = 0 } */
 
-			if sys, ok := sysPerAbbr[s]; ok {
+			if sys, ok := rnx2NavSystems[hdr.RINEXType]; ok && s == "" && hdr.RINEXVersion < 3 {
+				hdr.SatSystem = sys
+			} else if sys, ok := gnss.SystemByAbbr(s); ok {
 				hdr.SatSystem = sys
 			} else {
 				err = fmt.Errorf("read header: invalid satellite system in line %d: %s", dec.lineNum, line)
@@ -581,14 +644,47 @@ read:
 			hdr.Date = strings.TrimSpace(val[40:])
 		case "COMMENT":
 			hdr.Comments = append(hdr.Comments, strings.TrimSpace(val))
-		case "IONOSPHERIC CORR":
-			// TODO
-		case "TIME SYSTEM CORR":
-			// TODO
+		case "IONOSPHERIC CORR", "ION ALPHA", "ION BETA":
+			typ, pos := strings.TrimSpace(field(val, 0, 4)), 5
+			if key == "ION ALPHA" {
+				typ, pos = "GPSA", 2
+			} else if key == "ION BETA" {
+				typ, pos = "GPSB", 2
+			}
+			params, perr := parseIonoCorr(val, pos)
+			if perr != nil {
+				err = fmt.Errorf("read header: %s in line %d: %w", key, dec.lineNum, perr)
+				return
+			}
+			if hdr.IonoCorr == nil {
+				hdr.IonoCorr = make(map[string][4]float64, 3)
+			}
+			hdr.IonoCorr[typ] = params
+		case "TIME SYSTEM CORR", "DELTA-UTC: A0,A1,T,W", "CORR TO SYSTEM TIME", "D-UTC A0,A1,T,W,S,U":
+			corr, perr := parseTimeSysCorr(key, val)
+			if perr != nil {
+				err = fmt.Errorf("read header: %s in line %d: %w", key, dec.lineNum, perr)
+				return
+			}
+			hdr.TimeSysCorrs = append(hdr.TimeSysCorrs, corr)
 		case "LEAP SECONDS":
-			// TODO
-			// my @lsecs = split ( " ", trim($val) );
-			// $self->leapSecs( $lsecs[0] );    # ab Vers. 3 hier mehrere Werte moeglich!
+			if i, err := strconv.Atoi(strings.TrimSpace(val[:6])); err == nil {
+				hdr.LeapSeconds = i
+			}
+		case "REC # / TYPE / VERS":
+			hdr.ReceiverNumber = strings.TrimSpace(val[:20])
+			hdr.ReceiverType = strings.TrimSpace(val[20:40])
+			hdr.ReceiverVersion = strings.TrimSpace(val[40:])
+		case "MERGED FILE":
+			if i, err := strconv.Atoi(strings.TrimSpace(val[:9])); err == nil {
+				hdr.MergedFiles = i
+			}
+		case "DOI":
+			hdr.DOI = strings.TrimSpace(val)
+		case "LICENSE OF USE":
+			hdr.Licenses = append(hdr.Licenses, strings.TrimSpace(val))
+		case "STATION INFORMATION":
+			hdr.StationInfos = append(hdr.StationInfos, strings.TrimSpace(val))
 		case "END OF HEADER":
 			break read
 		default:
@@ -597,6 +693,9 @@ read:
 	}
 
 	err = dec.sc.Err()
+	if err == nil && hdr.RINEXVersion >= 5 {
+		err = fmt.Errorf("%w: %.2f", ErrUnsupportedVersion, hdr.RINEXVersion)
+	}
 	return
 }
 
@@ -606,10 +705,17 @@ read:
 // If there is no header we suppose the format is RINEX3.
 // TODO: read all values
 func (dec *NavDecoder) NextEphemeris() bool {
+	if dec.Header.RINEXVersion >= 4 {
+		return dec.nextEphemerisV4()
+	}
+
 	for dec.sc.Scan() {
 		dec.lineNum++
 		//line := dec.sc.Text()
 		line := dec.sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
 
 		// RINEX 3
 		if dec.Header.RINEXVersion == 0 || dec.Header.RINEXVersion >= 3 {
@@ -619,7 +725,7 @@ func (dec *NavDecoder) NextEphemeris() bool {
 				continue
 			}
 
-			sys, ok := sysPerAbbr[string(line[:1])]
+			sys, ok := gnss.SystemByAbbr(string(line[:1]))
 			if !ok {
 				dec.setErr(fmt.Errorf("invalid satellite system: %q: line %d", line[:1], dec.lineNum))
 				return false
@@ -641,7 +747,7 @@ func (dec *NavDecoder) NextEphemeris() bool {
 				dec.sc.Scan()
 				dec.lineNum++
 				if err := dec.sc.Err(); err != nil {
-					dec.setErr(fmt.Errorf("read eph lines scanner error: %v", err))
+					dec.setErr(fmt.Errorf("read eph lines scanner error: %w", err))
 					return false
 				}
 				//dec.ephLines = append(dec.ephLines, dec.sc.Text())
@@ -658,23 +764,223 @@ func (dec *NavDecoder) NextEphemeris() bool {
 			return true
 		}
 
-		// RINEX 2
-		dec.setErr(fmt.Errorf("RINEX 2 not supported so far"))
-		return false
+		// RINEX 2, the record is converted to the RINEX 3 layout
+		sys := dec.Header.SatSystem
+		nLines := 8
+		switch sys {
+		case gnss.SysGLO, gnss.SysSBAS:
+			nLines = 4
+		case gnss.SysGPS:
+		default:
+			dec.setErr(fmt.Errorf("%w: RINEX 2 navigation data of system %v", ErrUnsupportedVersion, sys))
+			return false
+		}
+		lines := make([]string, 1, nLines)
+		lines[0] = string(line)
+		for ii := 1; ii < nLines; ii++ {
+			if !dec.sc.Scan() {
+				if err := dec.sc.Err(); err != nil {
+					dec.setErr(fmt.Errorf("read eph lines scanner error: %w", err))
+				} else {
+					dec.setErr(fmt.Errorf("unexpected EOF: ephemeris of line %d", dec.lineNum-ii+1))
+				}
+				return false
+			}
+			dec.lineNum++
+			lines = append(lines, dec.sc.Text())
+		}
+		rec, err := navRecordV3(sys, lines)
+		if err != nil {
+			dec.setErr(fmt.Errorf("line %d: %w", dec.lineNum-nLines+1, err))
+			return false
+		}
+		dec.buf.Reset()
+		dec.buf.WriteString(rec)
+		if err := dec.unmarshal(sys); err != nil {
+			return false
+		}
+		return true
 	}
 
 	if err := dec.sc.Err(); err != nil {
-		dec.setErr(fmt.Errorf("read eph scanner error: %v", err))
+		dec.setErr(fmt.Errorf("read eph scanner error: %w", err))
 	}
 
 	return false // EOF
 }
 
+// navMessagesV4 are the message types of the RINEX 4 ephemeris records per system that are decoded.
+// These have the orbit layout of RINEX 3, the other types like CNAV, CNV1, CNV2 and CNV3 are skipped.
+var navMessagesV4 = map[gnss.System][]string{
+	gnss.SysGPS:   {"LNAV"},
+	gnss.SysQZSS:  {"LNAV"},
+	gnss.SysGAL:   {"INAV", "FNAV"},
+	gnss.SysBDS:   {"D1", "D2"},
+	gnss.SysGLO:   {"FDMA"},
+	gnss.SysSBAS:  {"SBAS"},
+	gnss.SysIRNSS: {"LNAV"},
+}
+
+// decodedNavMessageV4 returns true if the ephemeris records of the system and message type are decoded.
+func decodedNavMessageV4(sys gnss.System, msg string) bool {
+	for _, m := range navMessagesV4[sys] {
+		if m == msg {
+			return true
+		}
+	}
+	return false
+}
+
+// nextEphemerisV4 reads the next ephemeris from a RINEX 4 input stream.
+// In RINEX 4 each record starts with a record header line like "> EPH G01 LNAV". Besides ephemerides
+// there are system time offset (STO), earth orientation (EOP) and ionosphere (ION) records, which
+// are skipped so far. Only the ephemerides of the message types in navMessagesV4 are returned.
+func (dec *NavDecoder) nextEphemerisV4() bool {
+	for {
+		recHdr, data, ok := dec.readRecordV4()
+		if !ok {
+			return false
+		}
+
+		// > EPH G01 LNAV
+		fields := strings.Fields(string(recHdr))
+		if len(fields) < 3 {
+			dec.setErr(fmt.Errorf("invalid record header in line %d: %q", dec.lineNum, recHdr))
+			return false
+		}
+		if fields[1] != "EPH" {
+			continue
+		}
+
+		sys, ok := gnss.SystemByAbbr(fields[2][:1])
+		if !ok {
+			dec.setErr(fmt.Errorf("invalid satellite system: %q: line %d", fields[2], dec.lineNum))
+			return false
+		}
+		if len(fields) < 4 || !decodedNavMessageV4(sys, fields[3]) {
+			continue
+		}
+
+		dec.recHdr = recHdr
+		dec.buf.Reset()
+		dec.buf.Write(data)
+		if err := dec.unmarshal(sys); err != nil {
+			return false
+		}
+		return true
+	}
+}
+
+// readRecordV4 reads a RINEX 4 nav record and returns its record header line and the data lines.
+func (dec *NavDecoder) readRecordV4() (recHdr []byte, data []byte, ok bool) {
+	// find the record header
+	recHdr = dec.nextRecHdr
+	dec.nextRecHdr = nil
+	for recHdr == nil {
+		if !dec.sc.Scan() {
+			if err := dec.sc.Err(); err != nil {
+				dec.setErr(fmt.Errorf("read record scanner error: %w", err))
+			}
+			return nil, nil, false
+		}
+		dec.lineNum++
+		line := dec.sc.Bytes()
+		if bytes.HasPrefix(line, []byte("> ")) {
+			recHdr = append([]byte(nil), line...)
+		} else if len(bytes.TrimSpace(line)) > 0 {
+			dec.setErr(fmt.Errorf("missing record header before line %d: %q", dec.lineNum, line))
+			return nil, nil, false
+		}
+	}
+
+	// read the data lines up to the next record header
+	var buf bytes.Buffer
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Bytes()
+		if bytes.HasPrefix(line, []byte("> ")) {
+			dec.nextRecHdr = append([]byte(nil), line...)
+			break
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := dec.sc.Err(); err != nil {
+		dec.setErr(fmt.Errorf("read record scanner error: %w", err))
+		return nil, nil, false
+	}
+
+	return recHdr, buf.Bytes(), true
+}
+
 // Ephemeris returns the most recent ephemeris generated by a call to NextEphemeris.
 func (dec *NavDecoder) Ephemeris() Eph {
 	return dec.eph
 }
 
+// Record returns the lines of the most recent ephemeris as read by NextEphemeris, for RINEX 4
+// including the record header line. RINEX 2 records are returned in the RINEX 3 layout.
+// The data is only valid until the next call to NextEphemeris.
+func (dec *NavDecoder) Record() []byte {
+	if dec.Header.RINEXVersion < 4 {
+		return dec.buf.Bytes()
+	}
+	rec := make([]byte, 0, len(dec.recHdr)+1+dec.buf.Len())
+	rec = append(rec, dec.recHdr...)
+	rec = append(rec, '\n')
+	return append(rec, dec.buf.Bytes()...)
+}
+
+// navRecordV3 converts the lines of a RINEX 2 ephemeris of the system to the RINEX 3 layout, with the
+// satellite system in the first line, a four-digit year and the data lines indented by 4 blanks.
+func navRecordV3(sys gnss.System, lines []string) (string, error) {
+	// 12 20  6 17  2  0  0.0 1.051961444318D-04-4.433786671143D-12 0.000000000000D+00
+	first := lines[0]
+	num, err := strconv.Atoi(strings.TrimSpace(field(first, 0, 2)))
+	if err != nil {
+		return "", fmt.Errorf("parsing sat num: %q: %w", first, err)
+	}
+	toc, err := parseTimeV2(field(first, 2, 22))
+	if err != nil {
+		return "", fmt.Errorf("parsing TOC: %q: %w", first, err)
+	}
+	toc = toc.Round(time.Second)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s%02d %s", sys.Abbr(), num, toc.Format("2006 01 02 15 04 05"))
+	if err := appendNavValues(&sb, first, 22, 3); err != nil {
+		return "", err
+	}
+	for _, line := range lines[1:] {
+		sb.WriteString("    ")
+		if err := appendNavValues(&sb, line, 3, 4); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+// appendNavValues appends the n values D19.12 of the line beginning at col in the format E19.12 and a
+// newline. Blank values are 0, the values missing at the end of the line are omitted.
+func appendNavValues(sb *strings.Builder, line string, col, n int) error {
+	for i := 0; i < n; i++ {
+		s := field(line, col+i*19, col+(i+1)*19)
+		if s == "" {
+			break
+		}
+		var f64 float64
+		if s = strings.TrimSpace(s); s != "" {
+			var err error
+			if f64, err = parseFloat(strings.NewReplacer("D", "E", "d", "E").Replace(s)); err != nil {
+				return fmt.Errorf("parsing nav value: %q: %w", line, err)
+			}
+		}
+		fmt.Fprintf(sb, "%19.12E", f64)
+	}
+	sb.WriteByte('\n')
+	return nil
+}
+
 // setErr records the first error encountered.
 func (dec *NavDecoder) setErr(err error) {
 	if dec.err == nil || dec.err == io.EOF {
@@ -699,27 +1005,36 @@ func NewNavFile(filepath string) (*NavFile, error) {
 }
 
 // Compress a navigation file using the gzip format.
-// The source file will be removed if the compression finishes without errors.
+// The source file will be removed if the compression finishes without errors, Path is set to the compressed file.
 func (f *NavFile) Compress() error {
+	return f.CompressWithOptions(CompressOptions{})
+}
+
+// CompressWithOptions compresses a navigation file using the gzip format and the given options.
+// Path is set to the compressed file.
+func (f *NavFile) CompressWithOptions(opts CompressOptions) error {
 	if IsCompressed(f.Path) {
 		return nil
 	}
-	err := archiver.CompressFile(f.Path, f.Path+".gz")
+
+	src := f.Path
+	dst, err := compressRnx(src, opts, func(r io.Reader) error {
+		_, err := NewNavDecoder(r)
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	os.Remove(f.Path)
-	f.Path = f.Path + ".gz"
+	f.Path = dst
 	f.Compression = "gz"
+	if !opts.KeepSource {
+		return os.Remove(src)
+	}
 	return nil
 }
 
 // Rnx3Filename returns the filename following the RINEX3 convention.
-// In most cases we must read the read the header. The countrycode must come from an external source.
-// DO NOT USE! Must parse header first!
-
-// Rnx3Filename returns the filename following the RINEX3 convention.
-// TODO !!!
+// The country code must come from an external source.
 func (f *NavFile) Rnx3Filename() (string, error) {
 	// Station Identifier
 	if len(f.FourCharID) != 4 {
@@ -770,9 +1085,9 @@ func (f *NavFile) Rnx3Filename() (string, error) {
 
 // Validate validates the RINEX Nav file. It is valid if no error is returned.
 func (f *NavFile) Validate() error {
-	r, err := os.Open(f.Path)
+	r, err := f.open()
 	if err != nil {
-		return fmt.Errorf("open nav file: %v", err)
+		return fmt.Errorf("open nav file: %w", err)
 	}
 	defer r.Close()
 
@@ -862,17 +1177,29 @@ var navHeaderLables = map[float32][]headerLabel{
 	3.02: rnx3HeaderLables,
 	3.03: rnx3HeaderLables,
 	3.04: rnx3HeaderLables,
+	3.05: append(rnx3HeaderLables,
+		headerLabel{label: "REC # / TYPE / VERS", official: true, optional: true},
+		headerLabel{label: "MERGED FILE", official: true, optional: true},
+		headerLabel{label: "DOI", official: true, optional: true},
+		headerLabel{label: "LICENSE OF USE", official: true, optional: true},
+		headerLabel{label: "STATION INFORMATION", official: true, optional: true},
+	),
 	4: {
-		// unofficial CNAV files
 		// mandatory
-		headerLabel{label: "RINEX VERSION / TYPE", optional: false},
-		headerLabel{label: "PGM / RUN BY / DATE", optional: false},
-		headerLabel{label: "END OF HEADER", optional: false},
+		headerLabel{label: "RINEX VERSION / TYPE", official: true, optional: false},
+		headerLabel{label: "PGM / RUN BY / DATE", official: true, optional: false},
+		headerLabel{label: "END OF HEADER", official: true, optional: false},
 		// optional
-		headerLabel{label: "COMMENT", optional: true},
+		headerLabel{label: "COMMENT", official: true, optional: true},
+		headerLabel{label: "REC # / TYPE / VERS", official: true, optional: true},
+		headerLabel{label: "MERGED FILE", official: true, optional: true},
+		headerLabel{label: "DOI", official: true, optional: true},
+		headerLabel{label: "LICENSE OF USE", official: true, optional: true},
+		headerLabel{label: "STATION INFORMATION", official: true, optional: true},
+		headerLabel{label: "LEAP SECONDS", official: true, optional: true},
+		// unofficial CNAV files
 		headerLabel{label: "IONOSPHERIC CORR", optional: true},
 		headerLabel{label: "TIME SYSTEM CORR", optional: true},
-		headerLabel{label: "LEAP SECONDS", optional: true},
 	},
 }
 
@@ -926,14 +1253,68 @@ func (hdr NavHeader) Validate() error {
 	return nil
 }
 
+// parseTimeSysCorr parses the time system correction header record key, the RINEX 3 TIME SYSTEM CORR
+// or one of the RINEX 2 records.
+func parseTimeSysCorr(key, val string) (corr TimeSysCorr, err error) {
+	float := func(from, to int) float64 {
+		if err != nil {
+			return 0
+		}
+		var f64 float64
+		if s := strings.TrimSpace(field(val, from, to)); s != "" {
+			f64, err = parseFloat(strings.Replace(s, "D", "E", 1))
+		}
+		return f64
+	}
+	integer := func(from, to int) int {
+		if err != nil {
+			return 0
+		}
+		var i int
+		if s := strings.TrimSpace(field(val, from, to)); s != "" {
+			i, err = strconv.Atoi(s)
+		}
+		return i
+	}
+
+	switch key {
+	case "TIME SYSTEM CORR": // A4,1X,D17.10,D16.9,1X,I6,1X,I4,1X,A5,1X,I2
+		corr = TimeSysCorr{Type: strings.TrimSpace(field(val, 0, 4)), A0: float(5, 22), A1: float(22, 38),
+			RefTime: integer(38, 45), RefWeek: integer(45, 50), Source: strings.TrimSpace(field(val, 51, 56)), UTCID: integer(56, 59)}
+	case "DELTA-UTC: A0,A1,T,W": // 3X,2D19.12,2I9
+		corr = TimeSysCorr{Type: "GPUT", A0: float(3, 22), A1: float(22, 41), RefTime: integer(41, 50), RefWeek: integer(50, 59)}
+	case "D-UTC A0,A1,T,W,S,U": // 3X,2D19.12,2I7,1X,A5,1X,I2
+		corr = TimeSysCorr{Type: "SBUT", A0: float(3, 22), A1: float(22, 41), RefTime: integer(41, 48), RefWeek: integer(48, 55),
+			Source: strings.TrimSpace(field(val, 56, 61)), UTCID: integer(61, 64)}
+	case "CORR TO SYSTEM TIME": // 3I6,3X,D19.12, the correction -TauC
+		corr = TimeSysCorr{Type: "GLUT", A0: float(21, 40)}
+	}
+	return
+}
+
+// parseIonoCorr parses the four parameters of an ionospheric correction header line, format 4D12.4 beginning at pos.
+// Blank parameters are 0.
+func parseIonoCorr(val string, pos int) (params [4]float64, err error) {
+	for i := range params {
+		s := strings.TrimSpace(field(val, pos+i*12, pos+(i+1)*12))
+		if s == "" {
+			continue
+		}
+		if params[i], err = parseFloat(strings.Replace(s, "D", "E", 1)); err != nil {
+			return
+		}
+	}
+	return
+}
+
 // parseFloatsNavLine parses a common data line of a nav file, having four floats 4X,4D19.12.
 func parseFloatsNavLine(s string) (f1, f2, f3, f4 float64, err error) {
-	f1, err = parseFloat(s[4 : 4+19])
+	f1, err = parseFloat(field(s, 4, 4+19))
 	if err != nil {
 		return
 	}
 
-	f2, err = parseFloat(s[23 : 23+19])
+	f2, err = parseFloat(field(s, 23, 23+19))
 	if err != nil {
 		return
 	}
@@ -941,7 +1322,7 @@ func parseFloatsNavLine(s string) (f1, f2, f3, f4 float64, err error) {
 	if len(s) < 45 {
 		return
 	}
-	f3, err = parseFloat(s[42 : 42+19])
+	f3, err = parseFloat(field(s, 42, 42+19))
 	if err != nil {
 		return
 	}
@@ -949,6 +1330,6 @@ func parseFloatsNavLine(s string) (f1, f2, f3, f4 float64, err error) {
 	if len(s) < 64 {
 		return
 	}
-	f4, err = parseFloat(s[61 : 61+19])
+	f4, err = parseFloat(field(s, 61, 61+19))
 	return
 }
@@ -0,0 +1,332 @@
+package rinex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// Write writes the header in RINEX format to w. Optional records are only written if they are set.
+// The records REC # / TYPE / VERS, MERGED FILE, DOI, LICENSE OF USE and STATION INFORMATION are only
+// written for version 3.05 and later, the IONOSPHERIC CORR and TIME SYSTEM CORR records only before version 4.
+func (hdr *NavHeader) Write(w io.Writer) error {
+	if hdr.RINEXVersion < 3 {
+		return fmt.Errorf("write header: %w: %.2f", ErrUnsupportedVersion, hdr.RINEXVersion)
+	}
+
+	bw := bufio.NewWriter(w)
+	hw := &headerWriter{w: bw}
+
+	sys := hdr.SatSystem.Abbr() + ": " + hdr.SatSystem.String()
+	hw.writeLine(fmt.Sprintf("%9.2f%11s%-20s%-20s", hdr.RINEXVersion, "", "N: GNSS NAV DATA", sys), "RINEX VERSION / TYPE")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.Pgm, hdr.RunBy, hdr.Date), "PGM / RUN BY / DATE")
+	for _, c := range hdr.Comments {
+		hw.writeLine(c, "COMMENT")
+	}
+	if hdr.RINEXVersion >= 3.05 {
+		if hdr.ReceiverNumber != "" || hdr.ReceiverType != "" || hdr.ReceiverVersion != "" {
+			hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.ReceiverNumber, hdr.ReceiverType, hdr.ReceiverVersion), "REC # / TYPE / VERS")
+		}
+		if hdr.MergedFiles > 0 {
+			hw.writeLine(fmt.Sprintf("%9d", hdr.MergedFiles), "MERGED FILE")
+		}
+		if hdr.DOI != "" {
+			hw.writeLine(hdr.DOI, "DOI")
+		}
+		for _, l := range hdr.Licenses {
+			hw.writeLine(l, "LICENSE OF USE")
+		}
+		for _, si := range hdr.StationInfos {
+			hw.writeLine(si, "STATION INFORMATION")
+		}
+	}
+	if hdr.RINEXVersion < 4 {
+		types := make([]string, 0, len(hdr.IonoCorr))
+		for typ := range hdr.IonoCorr {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		for _, typ := range types {
+			p := hdr.IonoCorr[typ]
+			hw.writeLine(fmt.Sprintf("%-4s %12.4E%12.4E%12.4E%12.4E", typ, p[0], p[1], p[2], p[3]), "IONOSPHERIC CORR")
+		}
+		for _, corr := range hdr.TimeSysCorrs {
+			val := fmt.Sprintf("%-4s %17.10E%16.9E %6d %4d", corr.Type, corr.A0, corr.A1, corr.RefTime, corr.RefWeek)
+			if corr.Source != "" || corr.UTCID != 0 {
+				val += fmt.Sprintf(" %-5s %2d", corr.Source, corr.UTCID)
+			}
+			hw.writeLine(val, "TIME SYSTEM CORR")
+		}
+	}
+	if hdr.LeapSeconds != 0 {
+		hw.writeLine(fmt.Sprintf("%6d", hdr.LeapSeconds), "LEAP SECONDS")
+	}
+	hw.writeLine("", "END OF HEADER")
+	if hw.err != nil {
+		return hw.err
+	}
+	return bw.Flush()
+}
+
+// NavMergeOptions configures MergeNav.
+type NavMergeOptions struct {
+	// Start and End restrict the ephemerides to clock reference epochs in [Start, End), e.g. to a day.
+	// A zero time means no limit.
+	Start, End time.Time
+
+	// HealthyOnly drops the ephemerides of unhealthy satellites.
+	HealthyOnly bool
+
+	// Best keeps only one ephemeris per satellite and clock reference epoch, for Galileo per data source:
+	// a healthy one if any, of these the first transmitted one.
+	Best bool
+}
+
+// navRecord is an ephemeris record with the fields to identify it.
+type navRecord struct {
+	prn    PRN
+	toc    time.Time
+	iod    float64 // issue of data, not for GLONASS and SBAS
+	toe    float64 // time of ephemeris, not for GLONASS and SBAS
+	source float64 // Galileo data source, I/NAV or F/NAV
+	health float64
+	tom    float64 // transmission time of message
+	data   []byte  // the lines of the record
+}
+
+// navRecordKey identifies the duplicates of an ephemeris.
+type navRecordKey struct {
+	prn              PRN
+	toc              time.Time
+	iod, toe, source float64
+}
+
+func (rec *navRecord) key() navRecordKey {
+	return navRecordKey{prn: rec.prn, toc: rec.toc, iod: rec.iod, toe: rec.toe, source: rec.source}
+}
+
+// newNavRecord returns the record of the most recent ephemeris of the decoder.
+func newNavRecord(dec *NavDecoder) (*navRecord, error) {
+	data := append([]byte(nil), dec.Record()...)
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if dec.Header.RINEXVersion >= 4 {
+		lines = lines[1:] // record header
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("invalid ephemeris record: %q", data)
+	}
+	sys, ok := gnss.SystemByAbbr(field(lines[0], 0, 1))
+	if !ok {
+		return nil, fmt.Errorf("invalid satellite system: %q", lines[0])
+	}
+	num, err := strconv.Atoi(strings.TrimSpace(field(lines[0], 1, 3)))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sat num: %q: %v", lines[0], err)
+	}
+	rec := &navRecord{data: data}
+	if rec.prn, err = NewPRN(sys, num); err != nil {
+		return nil, err
+	}
+	if rec.toc, err = time.Parse(TimeOfClockFormat, field(lines[0], 4, 23)); err != nil {
+		return nil, fmt.Errorf("could not parse TOC: %q: %v", lines[0], err)
+	}
+
+	// value returns the i-th value of the broadcast orbit n, n=0 is the line with the clock parameters
+	value := func(n, i int) float64 {
+		if n >= len(lines) {
+			return 0
+		}
+		pos := 4 + i*19
+		if n == 0 {
+			pos = 23 + (i-1)*19
+		}
+		s := strings.Replace(strings.TrimSpace(field(lines[n], pos, pos+19)), "D", "E", 1)
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	switch sys {
+	case gnss.SysGLO, gnss.SysSBAS:
+		rec.tom, rec.health = value(0, 3), value(1, 3)
+	default:
+		rec.iod, rec.toe = value(1, 0), value(3, 0)
+		rec.health, rec.tom = value(6, 1), value(7, 0)
+		if sys == gnss.SysGAL {
+			rec.source = value(5, 1)
+		}
+	}
+	return rec, nil
+}
+
+// MergeNav merges the ephemerides of the decoders, e.g. of several stations or hourly files, and writes
+// them to w, sorted by satellite and clock reference epoch. Duplicate ephemerides, having the same
+// satellite, clock reference epoch, issue of data and time of ephemeris, are written once, the first
+// transmitted one. The header is taken from the first decoder, ionospheric corrections only found in the
+// others are added. It returns the number of written ephemerides.
+//
+// All inputs must have the same major RINEX version, the output has the highest one. RINEX 2 is not supported.
+// RINEX 4 records other than the ephemerides read by NavDecoder, like STO, EOP and ION, are dropped.
+func MergeNav(w io.Writer, opts NavMergeOptions, decs ...*NavDecoder) (int, error) {
+	hdr, recs, err := mergeNav(opts, decs)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(decs))
+	for _, dec := range decs {
+		names = append(names, dec.Name)
+	}
+	hdr.addHistory("merge", names)
+	return len(recs), writeNav(w, &hdr, recs)
+}
+
+// mergeNav returns the header and the records of the merged decoders, see MergeNav.
+func mergeNav(opts NavMergeOptions, decs []*NavDecoder) (NavHeader, []*navRecord, error) {
+	if len(decs) == 0 {
+		return NavHeader{}, nil, fmt.Errorf("merge: no input")
+	}
+	hdr := decs[0].Header
+	hdr.IonoCorr = make(map[string][4]float64, len(decs[0].Header.IonoCorr))
+	for _, dec := range decs {
+		v := dec.Header.RINEXVersion
+		if v < 3 || int(v) != int(hdr.RINEXVersion) {
+			return hdr, nil, fmt.Errorf("merge: %w: %.2f", ErrUnsupportedVersion, v)
+		}
+		if v > hdr.RINEXVersion {
+			hdr.RINEXVersion = v
+		}
+		for typ, params := range dec.Header.IonoCorr {
+			if _, ok := hdr.IonoCorr[typ]; !ok {
+				hdr.IonoCorr[typ] = params
+			}
+		}
+	}
+
+	// read all records, keep the first transmitted of the duplicates
+	var recs []*navRecord
+	seen := make(map[navRecordKey]int)
+	for i, dec := range decs {
+		for dec.NextEphemeris() {
+			rec, err := newNavRecord(dec)
+			if err != nil {
+				return hdr, nil, fmt.Errorf("read ephemerides of input %d: %v", i+1, err)
+			}
+			if rec.toc.Before(opts.Start) || !opts.End.IsZero() && !rec.toc.Before(opts.End) {
+				continue
+			}
+			if opts.HealthyOnly && rec.health != 0 {
+				continue
+			}
+			if j, ok := seen[rec.key()]; ok {
+				if rec.tom < recs[j].tom {
+					recs[j] = rec
+				}
+				continue
+			}
+			seen[rec.key()] = len(recs)
+			recs = append(recs, rec)
+		}
+		if err := dec.Err(); err != nil {
+			return hdr, nil, fmt.Errorf("read ephemerides of input %d: %w", i+1, err)
+		}
+	}
+	if opts.Best {
+		recs = bestNavRecords(recs)
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		a, b := recs[i], recs[j]
+		if a.prn != b.prn {
+			if a.prn.Sys != b.prn.Sys {
+				return a.prn.Sys < b.prn.Sys
+			}
+			return a.prn.Num < b.prn.Num
+		}
+		return a.toc.Before(b.toc)
+	})
+
+	syss := gnss.SystemSet(0)
+	for _, rec := range recs {
+		syss = syss.Add(rec.prn.Sys)
+	}
+	if all := syss.Systems(); len(all) == 1 {
+		hdr.SatSystem = all[0]
+	} else if len(all) > 1 {
+		hdr.SatSystem = gnss.SysMIXED
+	}
+	if hdr.RINEXVersion >= 3.05 {
+		hdr.MergedFiles = len(decs)
+	}
+	return hdr, recs, nil
+}
+
+// writeNav writes the header and the records to w.
+func writeNav(w io.Writer, hdr *NavHeader, recs []*navRecord) error {
+	bw := bufio.NewWriter(w)
+	if err := hdr.Write(bw); err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if _, err := bw.Write(rec.data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// bestNavRecords returns one record per satellite, clock reference epoch and data source, a healthy one
+// if any, of these the first transmitted one.
+func bestNavRecords(recs []*navRecord) []*navRecord {
+	type epoch struct {
+		prn    PRN
+		toc    time.Time
+		source float64
+	}
+	best := make(map[epoch]int)
+	var out []*navRecord
+	for _, rec := range recs {
+		k := epoch{prn: rec.prn, toc: rec.toc, source: rec.source}
+		j, ok := best[k]
+		if !ok {
+			best[k] = len(out)
+			out = append(out, rec)
+			continue
+		}
+		cur := out[j]
+		if (rec.health == 0) != (cur.health == 0) {
+			if rec.health == 0 {
+				out[j] = rec
+			}
+			continue
+		}
+		if rec.tom < cur.tom {
+			out[j] = rec
+		}
+	}
+	return out
+}
+
+// Merge merges the ephemerides of the navigation file and the others with MergeNav and writes them to w,
+// e.g. to build a daily broadcast file from the files of several stations.
+func (f *NavFile) Merge(w io.Writer, opts NavMergeOptions, others ...*NavFile) (int, error) {
+	files := append([]*NavFile{f}, others...)
+	decs := make([]*NavDecoder, 0, len(files))
+	for _, fil := range files {
+		r, err := fil.open()
+		if err != nil {
+			return 0, fmt.Errorf("open nav file: %w", err)
+		}
+		defer r.Close()
+		dec, err := NewNavDecoder(r)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", fil.Path, err)
+		}
+		dec.Name = filepath.Base(fil.Path)
+		decs = append(decs, dec)
+	}
+	return MergeNav(w, opts, decs...)
+}
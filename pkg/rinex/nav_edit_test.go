@@ -0,0 +1,134 @@
+package rinex
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNavHeader_Write(t *testing.T) {
+	assert := assert.New(t)
+	hdr := NavHeader{RINEXVersion: 3.05, RINEXType: "N", SatSystem: gnss.SysMIXED, Pgm: "gognss", RunBy: "BKG",
+		Date: "20200619 003025 GMT", Comments: []string{"a comment"}, MergedFiles: 3,
+		IonoCorr: map[string][4]float64{"GPSA": {1.1176e-08, 7.4506e-09, -5.9605e-08, -5.9605e-08}, "GAL": {28.25, 0.2344}}}
+	var buf bytes.Buffer
+	assert.NoError(hdr.Write(&buf))
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			assert.Len(line, 81)
+		}
+	}
+	assert.Equal(`     3.05           N: GNSS NAV DATA    M: MIXED            RINEX VERSION / TYPE
+gognss              BKG                 20200619 003025 GMT PGM / RUN BY / DATE
+a comment                                                   COMMENT
+        3                                                   MERGED FILE
+GAL    2.8250E+01  2.3440E-01  0.0000E+00  0.0000E+00       IONOSPHERIC CORR
+GPSA   1.1176E-08  7.4506E-09 -5.9605E-08 -5.9605E-08       IONOSPHERIC CORR
+                                                            END OF HEADER
+`, regexp.MustCompile(" +\n").ReplaceAllString(buf.String(), "\n"))
+
+	dec, err := NewNavDecoder(&buf)
+	assert.NoError(err)
+	assert.Equal(hdr.SatSystem, dec.Header.SatSystem)
+	assert.Equal(hdr.MergedFiles, dec.Header.MergedFiles)
+	assert.Equal(hdr.IonoCorr, dec.Header.IonoCorr)
+}
+
+func TestMergeNav(t *testing.T) {
+	assert := assert.New(t)
+	// the same ephemeris received later and a new issue of data
+	later := strings.Replace(navDataG20, "3.393480000000E+05 4.000000000000E+00", "3.394080000000E+05 4.000000000000E+00", 1)
+	newIOD := strings.Replace(navDataG20, "     8.300000000000E+01 2.078125000000E+01", "     8.400000000000E+01 2.078125000000E+01", 1)
+	newIOD = strings.Replace(newIOD, "G20 2020 06 18 00 00 00", "G20 2020 06 18 02 00 00", 1)
+	unhealthy := strings.Replace(newIOD, "2.000000000000E+00 0.000000000000E+00-8.8", "2.000000000000E+00 1.000000000000E+00-8.8", 1)
+
+	decoders := func(inputs ...string) []*NavDecoder {
+		var decs []*NavDecoder
+		for _, in := range inputs {
+			dec, err := NewNavDecoder(strings.NewReader(in))
+			if !assert.NoError(err) {
+				t.FailNow()
+			}
+			decs = append(decs, dec)
+		}
+		return decs
+	}
+
+	var buf bytes.Buffer
+	n, err := MergeNav(&buf, NavMergeOptions{}, decoders(later, navDataG20, newIOD)...)
+	assert.NoError(err)
+	assert.Equal(2, n)
+	assert.Contains(buf.String(), "3.393480000000E+05", "first transmitted")
+	assert.NotContains(buf.String(), "3.394080000000E+05")
+	dec, err := NewNavDecoder(&buf)
+	assert.NoError(err)
+	assert.Equal(gnss.SysGPS, dec.Header.SatSystem)
+	var tocs []time.Time
+	for dec.NextEphemeris() {
+		tocs = append(tocs, dec.Ephemeris().(*EphGPS).TOC)
+	}
+	assert.NoError(dec.Err())
+	assert.Equal([]time.Time{time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC), time.Date(2020, 6, 18, 2, 0, 0, 0, time.UTC)}, tocs)
+
+	buf.Reset()
+	n, err = MergeNav(&buf, NavMergeOptions{HealthyOnly: true}, decoders(navDataG20, unhealthy)...)
+	assert.NoError(err)
+	assert.Equal(1, n)
+
+	buf.Reset()
+	n, err = MergeNav(&buf, NavMergeOptions{Start: time.Date(2020, 6, 18, 1, 0, 0, 0, time.UTC)}, decoders(navDataG20, newIOD)...)
+	assert.NoError(err)
+	assert.Equal(1, n)
+
+	// a new issue of data for the same epoch
+	sameEpoch := strings.Replace(navDataG20, "     8.300000000000E+01 2.078125000000E+01", "     8.400000000000E+01 2.078125000000E+01", 1)
+	buf.Reset()
+	n, err = MergeNav(&buf, NavMergeOptions{}, decoders(navDataG20, sameEpoch)...)
+	assert.NoError(err)
+	assert.Equal(2, n)
+	buf.Reset()
+	n, err = MergeNav(&buf, NavMergeOptions{Best: true}, decoders(sameEpoch, navDataG20)...)
+	assert.NoError(err)
+	assert.Equal(1, n)
+
+	_, err = MergeNav(&buf, NavMergeOptions{})
+	assert.Error(err)
+}
+
+func TestNavFile_Merge(t *testing.T) {
+	assert := assert.New(t)
+	f, err := NewNavFile("testdata/white/AREG00PER_R_20201690000_01D_MN.rnx")
+	assert.NoError(err)
+	f2, err := NewNavFile("testdata/white/AREG00PER_R_20201690000_01D_MN.rnx")
+	assert.NoError(err)
+
+	var buf bytes.Buffer
+	n, err := f.Merge(&buf, NavMergeOptions{}, f2)
+	assert.NoError(err)
+	assert.Equal(3612, n)
+	assert.Contains(buf.String(), "input: AREG00PER_R_20201690000_01D_MN.rnx")
+
+	dec, err := NewNavDecoder(&buf)
+	assert.NoError(err)
+	assert.Equal(gnss.SysMIXED, dec.Header.SatSystem)
+	nEph := 0
+	var prev PRN
+	for dec.NextEphemeris() {
+		nEph++
+		switch eph := dec.Ephemeris().(type) {
+		case *EphGPS:
+			assert.True(prev.Sys <= eph.PRN.Sys, "sorted by system")
+			prev = eph.PRN
+		case *EphGAL:
+			assert.True(prev.Sys <= eph.PRN.Sys, "sorted by system")
+			prev = eph.PRN
+		}
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(n, nEph)
+}
@@ -28,10 +28,27 @@ func TestNavDecoder_readHeader(t *testing.T) {
 	assert.Equal(float32(3.04), dec.Header.RINEXVersion, "RINEX Version")
 	assert.Equal("N", dec.Header.RINEXType, "RINEX Type")
 	assert.Equal(gnss.SysMIXED, dec.Header.SatSystem, "Sat System")
+	assert.Equal([4]float64{5.5879e-09, 1.4901e-08, -5.9605e-08, -1.1921e-07}, dec.Header.IonoCorr["GPSA"], "Klobuchar alpha")
+	assert.Equal([4]float64{8.3968e+04, 9.8304e+04, -6.5536e+04, -5.2429e+05}, dec.Header.IonoCorr["GPSB"], "Klobuchar beta")
+	assert.Equal([4]float64{2.3750e+01, 1.5625e-02, 1.2329e-02, 0}, dec.Header.IonoCorr["GAL"], "Galileo ai")
 
 	t.Logf("RINEX Header: %+v\n", dec)
 }
 
+func TestParseIonoCorr(t *testing.T) {
+	assert := assert.New(t)
+	params, err := parseIonoCorr("    0.1118D-07  0.7451D-08 -0.5960D-07 -0.5960D-07          ", 2)
+	assert.NoError(err)
+	assert.Equal([4]float64{0.1118e-07, 0.7451e-08, -0.5960e-07, -0.5960e-07}, params, "RINEX 2 ION ALPHA")
+
+	params, err = parseIonoCorr("GAL    2.3750E+01  1.5625E-02", 5)
+	assert.NoError(err)
+	assert.Equal([4]float64{23.75, 1.5625e-02, 0, 0}, params, "short line")
+
+	_, err = parseIonoCorr("GPSA   5.5879E-09  x.4901E-08", 5)
+	assert.Error(err)
+}
+
 func BenchmarkNavDecoder_Ephemerides(b *testing.B) {
 	b.ReportAllocs()
 	filepath := "testdata/white/AREG00PER_R_20201690000_01D_MN.rnx"
@@ -343,3 +360,135 @@ func TestUnmarshalEph(t *testing.T) {
 		})
 	}
 }
+
+func TestNavDecoder_RINEX4(t *testing.T) {
+	const data = `     4.00           N: GNSS NAV DATA    M: MIXED            RINEX VERSION / TYPE
+BCEmerge            congo               20220102 003025 GMT PGM / RUN BY / DATE
+    18                                                      LEAP SECONDS
+https://doi.org/10.1000/xyz123                              DOI
+                                                            END OF HEADER
+> EPH G20 LNAV
+G20 2020 06 18 00 00 00 5.274894647300E-04-1.136868377216E-13 0.000000000000E+00
+     8.300000000000E+01 2.078125000000E+01 5.373438110980E-09-2.252452975616E+00
+     1.156702637672E-06 5.203154985793E-03 7.405877113342E-06 5.153647661209E+03
+     3.456000000000E+05-1.247972249985E-07-2.679776962713E+00 2.048909664154E-08
+     9.344138223835E-01 2.252500000000E+02 2.669542608731E+00-8.333918569731E-09
+     4.632335812523E-10 1.000000000000E+00 2.110000000000E+03 0.000000000000E+00
+     2.000000000000E+00 0.000000000000E+00-8.847564458847E-09 8.300000000000E+01
+     3.393480000000E+05 4.000000000000E+00
+> STO G01 LNAV
+    2020 06 18 00 00 00 GPUT          UTC(USNO)
+     3.393480000000E+05-1.862645149231E-09-8.881784197001E-16 0.000000000000E+00
+> ION G01 LNAV
+    2020 06 18 00 00 00 1.024454832077E-08 2.235174179077E-08-5.960464477539E-08
+    -1.192092895508E-07 9.625600000000E+04 1.310720000000E+05-6.553600000000E+04
+    -5.242880000000E+05
+> EPH G20 CNAV
+G20 2020 06 18 00 00 00 5.274894647300E-04-1.136868377216E-13 0.000000000000E+00
+     8.300000000000E+01 2.078125000000E+01 5.373438110980E-09-2.252452975616E+00
+     1.156702637672E-06 5.203154985793E-03 7.405877113342E-06 5.153647661209E+03
+     3.456000000000E+05-1.247972249985E-07-2.679776962713E+00 2.048909664154E-08
+     9.344138223835E-01 2.252500000000E+02 2.669542608731E+00-8.333918569731E-09
+     4.632335812523E-10 1.000000000000E+00 2.110000000000E+03 0.000000000000E+00
+     2.000000000000E+00 0.000000000000E+00-8.847564458847E-09 8.300000000000E+01
+     3.393480000000E+05 4.000000000000E+00 0.000000000000E+00 0.000000000000E+00
+> EPH R21 FDMA
+R21 2020 06 17 09 45 00-1.319693401456E-04-2.728484105319E-12 2.937000000000E+05
+    -1.042075537109E+04 2.813003540039E+00-2.793967723846E-09 0.000000000000E+00
+    -6.330877929688E+03-1.233654975891E+00 0.000000000000E+00 4.000000000000E+00
+    -2.240664208984E+04-9.621353149414E-01 9.313225746155E-10 0.000000000000E+00
+     1.790000000000E+02 9.999999999999E+08 1.500000000000E+01 0.000000000000E+00
+`
+	assert := assert.New(t)
+	dec, err := NewNavDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	assert.Equal(float32(4), dec.Header.RINEXVersion, "RINEX Version")
+	assert.Equal("N", dec.Header.RINEXType, "RINEX Type")
+	assert.Equal(gnss.SysMIXED, dec.Header.SatSystem, "Sat System")
+	assert.Equal("https://doi.org/10.1000/xyz123", dec.Header.DOI, "DOI")
+	assert.NoError(dec.Header.Validate())
+
+	ephs := []Eph{}
+	for dec.NextEphemeris() {
+		ephs = append(ephs, dec.Ephemeris())
+	}
+	assert.NoError(dec.Err())
+	assert.Len(ephs, 2, "number of ephemerides")
+	if assert.IsType(&EphGPS{}, ephs[0]) {
+		eph := ephs[0].(*EphGPS)
+		assert.Equal(PRN{gnss.SysGPS, 20}, eph.PRN)
+		assert.Equal(83.0, eph.IODE)
+		assert.Equal(4.0, eph.FitInterval)
+	}
+	if assert.IsType(&EphGLO{}, ephs[1]) {
+		assert.Equal(PRN{gnss.SysGLO, 21}, ephs[1].(*EphGLO).PRN)
+	}
+}
+
+func TestNavDecoder_RINEX4MessageTypes(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("testdata/white/BRD400DLR_S_20201690000_01D_MN.rnx")
+	if !assert.NoError(err) {
+		return
+	}
+	defer r.Close()
+	dec, err := NewNavDecoder(r)
+	assert.NoError(err)
+
+	type navEph struct {
+		prn PRN
+		toc time.Time
+	}
+	var ephs []navEph
+	for dec.NextEphemeris() {
+		switch eph := dec.Ephemeris().(type) {
+		case *EphGPS:
+			ephs = append(ephs, navEph{eph.PRN, eph.TOC})
+		case *EphGLO:
+			ephs = append(ephs, navEph{eph.PRN, eph.TOC})
+		case *EphGAL:
+			ephs = append(ephs, navEph{eph.PRN, eph.TOC})
+		case *EphBDS:
+			ephs = append(ephs, navEph{eph.PRN, eph.TOC})
+		case *EphSBAS:
+			ephs = append(ephs, navEph{eph.PRN, eph.TOC})
+		default:
+			t.Errorf("unexpected ephemeris type %T", eph)
+		}
+	}
+	assert.NoError(dec.Err())
+	assert.Equal([]navEph{
+		{PRN{gnss.SysGPS, 2}, time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC)},      // LNAV
+		{PRN{gnss.SysGLO, 2}, time.Date(2020, 6, 16, 23, 45, 0, 0, time.UTC)},    // FDMA
+		{PRN{gnss.SysGAL, 1}, time.Date(2020, 6, 16, 23, 30, 0, 0, time.UTC)},    // INAV
+		{PRN{gnss.SysGAL, 1}, time.Date(2020, 6, 16, 23, 0, 0, 0, time.UTC)},     // FNAV
+		{PRN{gnss.SysBDS, 19}, time.Date(2020, 6, 16, 21, 0, 0, 0, time.UTC)},    // D1
+		{PRN{gnss.SysSBAS, 31}, time.Date(2020, 6, 16, 23, 58, 56, 0, time.UTC)}, // SBAS
+	}, ephs, "CNAV, CNV2, CNV1, STO, EOP and ION records are skipped")
+
+	for _, tt := range []struct {
+		sys  gnss.System
+		msg  string
+		want bool
+	}{
+		{gnss.SysGPS, "LNAV", true}, {gnss.SysGPS, "CNAV", false}, {gnss.SysGPS, "CNV2", false},
+		{gnss.SysQZSS, "LNAV", true}, {gnss.SysQZSS, "CNAV", false}, {gnss.SysQZSS, "CNV2", false},
+		{gnss.SysGAL, "INAV", true}, {gnss.SysGAL, "FNAV", true},
+		{gnss.SysBDS, "D1", true}, {gnss.SysBDS, "D2", true}, {gnss.SysBDS, "CNV1", false}, {gnss.SysBDS, "CNV3", false},
+		{gnss.SysGLO, "FDMA", true}, {gnss.SysSBAS, "SBAS", true}, {gnss.SysIRNSS, "LNAV", true}, {gnss.SysIRNSS, "L1NV", false},
+	} {
+		assert.Equal(tt.want, decodedNavMessageV4(tt.sys, tt.msg), "%s %s", tt.sys, tt.msg)
+	}
+}
+
+func TestNavDecoder_RINEX4MissingRecordHeader(t *testing.T) {
+	const data = `     4.00           N: GNSS NAV DATA    M: MIXED            RINEX VERSION / TYPE
+                                                            END OF HEADER
+G02 2020 06 17 00 00 00-4.732492379844E-04-5.911715561524E-12 0.000000000000E+00
+`
+	assert := assert.New(t)
+	dec, err := NewNavDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	assert.False(dec.NextEphemeris())
+	assert.EqualError(dec.Err(), `missing record header before line 3: "G02 2020 06 17 00 00 00-4.732492379844E-04-5.911715561524E-12 0.000000000000E+00"`)
+}
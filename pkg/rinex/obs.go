@@ -3,31 +3,39 @@ package rinex
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math"
 	"math/big"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/mholt/archiver/v3"
-
 	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
 )
 
 // Options for global settings.
 type Options struct {
-	SatSys string // satellite systems GRE...
+	SatSys        gnss.SystemSet // satellite systems, e.g. gnss.ParseSystemSet("GRE"), the empty set means all systems
+	ElevationMask float64        // elevation cutoff angle in degrees, observations below are dropped, 0 means no mask
+}
+
+// useSys reports whether the satellite system is selected by SatSys.
+func (opts Options) useSys(sys gnss.System) bool {
+	return opts.SatSys == 0 || opts.SatSys.Contains(sys)
 }
 
 // DiffOptions sets options for file comparison.
 type DiffOptions struct {
-	SatSys      string // satellite systems GRE...
-	CheckHeader bool   // also compare the RINEX header
+	SatSys      gnss.SystemSet // satellite systems to compare, the empty set means all systems
+	CheckHeader bool           // also compare the RINEX header
 }
 
 // Coord defines a XYZ coordinate.
@@ -54,14 +62,73 @@ type PRN struct {
 	// flags
 }
 
-func newPRN(sys gnss.System, num int8) (PRN, error) {
-	/* 	if !(sys == 'G' || sys == 'R' || sys == 'E' || sys == 'J' || sys == 'C' || sys == 'I' || sys == 'S') {
-		return PRN{}, fmt.Errorf("Invalid satellite system: '%v'", sys)
-	} */
-	if num < 1 || num > 60 {
-		return PRN{}, fmt.Errorf("Check satellite number '%v%d'", sys, num)
+// satNumRange gives the valid satellite numbers of the RINEX identifiers per system.
+var satNumRange = map[gnss.System][2]int{
+	gnss.SysGPS:   {1, 32},
+	gnss.SysGLO:   {1, 32},
+	gnss.SysGAL:   {1, 36},
+	gnss.SysQZSS:  {1, 10},
+	gnss.SysBDS:   {1, 63},
+	gnss.SysIRNSS: {1, 14},
+	gnss.SysSBAS:  {20, 58},
+}
+
+// prnOffset is the difference between the PRN code of the system's ICD and the number of the RINEX identifier.
+var prnOffset = map[gnss.System]int{gnss.SysSBAS: 100, gnss.SysQZSS: 192}
+
+// NewPRN returns the satellite of the system with the given number. The number is either the one of the
+// RINEX identifier, e.g. 23 for "S23", or the PRN code, e.g. 123 for "S23" or 193 for "J01".
+func NewPRN(sys gnss.System, num int) (PRN, error) {
+	r, ok := satNumRange[sys]
+	if !ok {
+		return PRN{}, fmt.Errorf("invalid satellite system: %v", sys)
+	}
+	if off := prnOffset[sys]; off > 0 && num > off {
+		num -= off
+	}
+	if num < r[0] || num > r[1] {
+		return PRN{}, fmt.Errorf("invalid satellite number for %v: %d", sys, num)
+	}
+	return PRN{Sys: sys, Num: int8(num)}, nil
+}
+
+// ParsePRN parses a 3-char RINEX satellite identifier like "G05", "S23" or "J02".
+// A blank system identifier means GPS, as in RINEX 2.
+func ParsePRN(s string) (PRN, error) {
+	if len(s) != 3 {
+		return PRN{}, fmt.Errorf("invalid satellite identifier: %q", s)
+	}
+	sys, ok := gnss.SystemByAbbr(s[:1])
+	if s[0] == ' ' {
+		sys, ok = gnss.SysGPS, true
+	}
+	if !ok || sys == gnss.SysMIXED {
+		return PRN{}, fmt.Errorf("invalid satellite system: %q", s)
+	}
+	snum, err := strconv.Atoi(strings.TrimSpace(s[1:3]))
+	if err != nil {
+		return PRN{}, fmt.Errorf("parsing sat num: %q: %w", s, err)
+	}
+	return NewPRN(sys, snum)
+}
+
+// Number returns the PRN code of the satellite as defined in the system's ICD,
+// that is 120-158 for SBAS and 193-202 for QZSS. For the other systems it is the RINEX number.
+func (prn PRN) Number() int {
+	return int(prn.Num) + prnOffset[prn.Sys]
+}
+
+// parsePRNList parses a list of satellite identifiers, each preceded by a blank, e.g. " G01 G05".
+func parsePRNList(s string) ([]PRN, error) {
+	var prns []PRN
+	for _, f := range strings.Fields(s) {
+		prn, err := ParsePRN(f)
+		if err != nil {
+			return nil, err
+		}
+		prns = append(prns, prn)
 	}
-	return PRN{Sys: sys, Num: num}, nil
+	return prns, nil
 }
 
 // String is a PRN Stringer.
@@ -70,9 +137,93 @@ func (prn PRN) String() string {
 }
 
 // SatObs conatins all observations for a satellite for a epoch.
+// The observations are stored in the order of their types. For decoded epochs the types are the header's
+// observation types of the satellite system and missing observations have the value 0.
 type SatObs struct {
-	Prn  PRN
-	Obss map[string]Obs // L1C: obs
+	Prn   PRN
+	Types []string // observation types, e.g. L1C, shared by the satellites of a system, do not modify
+	Obss  []Obs    // observations in the order of Types
+}
+
+// NewSatObs returns the observations of the satellite, sorted by type.
+func NewSatObs(prn PRN, obs map[string]Obs) SatObs {
+	satObs := SatObs{Prn: prn, Types: make([]string, 0, len(obs)), Obss: make([]Obs, 0, len(obs))}
+	for typ := range obs {
+		satObs.Types = append(satObs.Types, typ)
+	}
+	sort.Strings(satObs.Types)
+	for _, typ := range satObs.Types {
+		satObs.Obss = append(satObs.Obss, obs[typ])
+	}
+	return satObs
+}
+
+// Index returns the index of the observation type in Types, or -1 if it is not included.
+func (satObs SatObs) Index(typ string) int {
+	for i, t := range satObs.Types {
+		if t == typ {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the observation of the given type, e.g. L1C.
+// It returns false if the type is not included.
+func (satObs SatObs) Get(typ string) (Obs, bool) {
+	if i := satObs.Index(typ); i >= 0 && i < len(satObs.Obss) {
+		return satObs.Obss[i], true
+	}
+	return Obs{}, false
+}
+
+// Set sets the observation of the given type. Types not yet included are appended,
+// without modifying the shared Types.
+func (satObs *SatObs) Set(typ string, obs Obs) {
+	if i := satObs.Index(typ); i >= 0 && i < len(satObs.Obss) {
+		satObs.Obss[i] = obs
+		return
+	}
+	satObs.Types = append(satObs.Types[:len(satObs.Types):len(satObs.Types)], typ)
+	satObs.Obss = append(satObs.Obss[:len(satObs.Obss):len(satObs.Obss)], obs)
+}
+
+// SNRdBHz returns the carrier-to-noise density in dBHz for the given frequency band, e.g. "1" or "5".
+// The value of a signal strength observation S<band><attr> is preferred, its unit is given by
+// the header's SIGNAL STRENGTH UNIT, which is usually DBHZ. Otherwise the signal strength indicator
+// of the phase or code observation of the band is mapped to the lower bound of its dBHz range.
+// It returns false if there is no signal strength information for the band.
+func (satObs SatObs) SNRdBHz(band string) (float64, bool) {
+	idx := make([]int, 0, len(satObs.Types))
+	for i, typ := range satObs.Types {
+		if len(typ) == 3 && typ[1:2] == band && i < len(satObs.Obss) {
+			idx = append(idx, i)
+		}
+	}
+	sort.Slice(idx, func(i, j int) bool { return satObs.Types[idx[i]] < satObs.Types[idx[j]] })
+
+	for _, i := range idx {
+		if obs := satObs.Obss[i]; satObs.Types[i][0] == 'S' && obs.Val != 0 {
+			return obs.Val, true
+		}
+	}
+	for _, prefix := range []byte{'L', 'C'} {
+		for _, i := range idx {
+			if obs := satObs.Obss[i]; satObs.Types[i][0] == prefix && obs.SNR > 0 {
+				return snrIndicatorToDBHz(obs.SNR), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// snrIndicatorToDBHz maps a signal strength indicator 1-9 to the lower bound of the related dBHz range,
+// see the RINEX 3 specification, i.e. 1: < 12 dBHz, 2: 12-17 dBHz, ..., 9: >= 54 dBHz.
+func snrIndicatorToDBHz(snr int8) float64 {
+	if snr <= 1 {
+		return 0
+	}
+	return float64(snr) * 6
 }
 
 // SyncEpochs contains two epochs from different files with the same timestamp.
@@ -83,46 +234,70 @@ type SyncEpochs struct {
 
 // Epoch contains a RINEX data epoch.
 type Epoch struct {
-	Time    time.Time // epoch time
-	Flag    int8
-	NumSat  uint8
-	ObsList []SatObs
+	Time        time.Time // epoch time
+	Flag        EpochFlag
+	NumSat      uint8
+	ClockOffset float64 // receiver clock offset in seconds (optional)
+	ObsList     []SatObs
+	Event       *Event // the event records for epoch flags 2-5, otherwise nil
 	//Error   error // e.g. parsing error
 }
 
-// Print pretty prints the epoch.
-func (epo *Epoch) Print() {
-	//fmt.Printf("%+v\n", epo)
-	fmt.Printf("%s Flag: %d #prn: %d\n", epo.Time.Format(time.RFC3339Nano), epo.Flag, epo.NumSat)
-	for _, satObs := range epo.ObsList {
-		fmt.Printf("%v -------------------------------------\n", satObs.Prn)
-		for typ, obs := range satObs.Obss {
-			fmt.Printf("%s: %+v\n", typ, obs)
-		}
+// EpochFlag is the flag of an epoch line, it tells what the records after the epoch line contain.
+type EpochFlag int8
+
+// Epoch flags.
+const (
+	EpochFlagOK                EpochFlag = iota // OK
+	EpochFlagPowerFailure                       // power failure between previous and current epoch
+	EpochFlagMovingAntenna                      // start moving antenna
+	EpochFlagNewSiteOccupation                  // new site occupation (end of kinematic data)
+	EpochFlagHeaderInfo                         // header information follows
+	EpochFlagExternalEvent                      // external event (epoch is significant)
+	EpochFlagCycleSlip                          // cycle slip records follow
+)
+
+func (f EpochFlag) String() string {
+	names := [...]string{"OK", "power failure", "moving antenna", "new site occupation", "header info",
+		"external event", "cycle slip"}
+	if f < 0 || int(f) >= len(names) {
+		return fmt.Sprintf("EpochFlag(%d)", int8(f))
 	}
+	return names[f]
 }
 
-// PrintTab prints the epoch in a tabular format.
-func (epo *Epoch) PrintTab(opts Options) {
-	for _, obsPerSat := range epo.ObsList {
-		printSys := false
-		for _, useSys := range opts.SatSys {
-			if obsPerSat.Prn.Sys.Abbr() == string(useSys) {
-				printSys = true
-				break
-			}
-		}
+// Event contains the records of a special event epoch, i.e. epoch flags 2-5.
+// The records following the epoch line are header records, e.g. a new
+// ANT # / TYPE and ANTENNA: DELTA H/E/N after an antenna change.
+type Event struct {
+	Header  ObsHeader // the parsed header records
+	Records []string  // the raw records
+}
 
-		if !printSys {
-			continue
-		}
+// IsEvent reports whether the epoch is a special event epoch and not an observation epoch.
+func (epo *Epoch) IsEvent() bool {
+	return epo.Flag >= EpochFlagMovingAntenna && epo.Flag <= EpochFlagExternalEvent
+}
 
-		fmt.Printf("%s %v ", epo.Time.Format(time.RFC3339Nano), obsPerSat.Prn)
-		for _, obs := range obsPerSat.Obss {
-			fmt.Printf("%14.03f ", obs.Val)
+// IsCycleSlip reports whether the epoch contains cycle slip records, flag 6. These records repeat
+// observations of the preceding epoch with the same time, they are no new observations.
+func (epo *Epoch) IsCycleSlip() bool {
+	return epo.Flag == EpochFlagCycleSlip
+}
+
+// ApplyElevationMask removes the satellites with an elevation below mask degrees, as seen from
+// the marker position. Satellites without a valid ephemeris are kept.
+func (epo *Epoch) ApplyElevationMask(ephs *EphemerisStore, marker Coord, mask float64) {
+	azel := ephs.AzEl(epo, marker)
+	obsList := epo.ObsList[:0]
+	for _, satObs := range epo.ObsList {
+		if ae, ok := azel[satObs.Prn]; ok && ae.El < mask {
+			continue
 		}
-		fmt.Printf("\n")
+		obsList = append(obsList, satObs)
 	}
+	epo.ObsList = obsList
+	epo.NumSat = uint8(len(obsList))
 }
 
 // ObsStat stores observation statistics.
@@ -133,6 +308,30 @@ type ObsStat struct {
 	TimeOfLastObs  time.Time `json:"timeOfLastObs"`
 }
 
+// PhaseShift is a phase shift correction applied to a carrier phase observation type
+// to rotate it into phase with the signal of the frequency band.
+type PhaseShift struct {
+	Sys        gnss.System // satellite system
+	ObsType    string      // carrier phase observation code, e.g. L2S, blank if the phase shifts are unknown
+	Correction float64     // correction applied in cycles
+	Sats       []PRN       // satellites involved, empty if valid for all satellites of the system
+}
+
+// ScaleFactor is a factor used to divide the observations of the given types to retrieve the original values.
+type ScaleFactor struct {
+	Sys      gnss.System // satellite system
+	Factor   int         // factor to divide the stored observations with, 1, 10, 100 or 1000
+	ObsTypes []string    // observation types involved, empty if valid for all types of the system
+}
+
+// CorrApplied describes corrections applied to the observations of a satellite system,
+// like differential code biases (DCBs) or phase center variations (PCVs).
+type CorrApplied struct {
+	Sys     gnss.System // satellite system
+	Program string      // program name used to apply the corrections
+	Source  string      // source of corrections (URL)
+}
+
 // A ObsHeader provides the RINEX Observation Header information.
 type ObsHeader struct {
 	RINEXVersion float32     // RINEX Format version
@@ -152,8 +351,11 @@ type ObsHeader struct {
 	ReceiverNumber, ReceiverType, ReceiverVersion string
 	AntennaNumber, AntennaType                    string
 
-	Position     Coord    // Geocentric approximate marker position [m]
-	AntennaDelta CoordNEU // North,East,Up deltas in [m]
+	Position        Coord    // Geocentric approximate marker position [m]
+	AntennaDelta    CoordNEU // North,East,Up deltas in [m]
+	AntennaDeltaXYZ Coord    // Position of antenna reference point for antenna on vehicle [m]
+	AntennaBSight   Coord    // Direction of the vertical antenna axis towards the GNSS satellites
+	CenterOfMass    Coord    // Current center of mass of vehicle in body-fixed coordinate system [m]
 
 	ObsTypes map[gnss.System][]string
 
@@ -161,36 +363,137 @@ type ObsHeader struct {
 	Interval           float64 // Observation interval in seconds
 	TimeOfFirstObs     time.Time
 	TimeOfLastObs      time.Time
-	LeapSeconds        int // The current number of leap seconds
-	NSatellites        int // Number of satellites, for which observations are stored in the file
+	TimeSystem         string // Time system of TIME OF FIRST/LAST OBS, e.g. GPS
+	LeapSeconds        int    // The current number of leap seconds
+	LeapSecondsFuture  int    // Future or past leap seconds
+	LeapSecondsWeek    int    // Week number of the future or past leap seconds
+	LeapSecondsDay     int    // Day number of the future or past leap seconds
+	LeapSecondsSys     string // Time system identifier of the leap seconds, GPS or BDS
+	NSatellites        int    // Number of satellites, for which observations are stored in the file
+	RcvClockOffsAppl   bool   // Epoch, code, and phase are corrected by applying the realtime-derived receiver clock offset
+
+	DCBSApplied []CorrApplied // Differential code bias corrections applied
+	PCVSApplied []CorrApplied // Phase center variation corrections applied
+
+	ScaleFactors []ScaleFactor      // Factors the observations were multiplied with
+	PhaseShifts  []PhaseShift       // Phase shift corrections applied to carrier phase observations
+	GloSlots     GloSlots           // GLONASS slot and frequency numbers
+	GloCodPhsBis map[string]float64 // GLONASS code phase bias corrections per observation type in meters
+	ObsPerSat    map[PRN][]int      // Number of observations per satellite in the order of ObsTypes
+
+	DOI          string   // Digital Object Identifier of the data, RINEX 3.05+
+	Licenses     []string // License of use, RINEX 3.05+
+	StationInfos []string // Links to further station information, RINEX 3.05+
 
 	labels   []string // all Header Labels found
 	warnings []string
 }
 
+// ParseWarning describes a recoverable problem that was skipped while decoding in lenient mode.
+type ParseWarning struct {
+	Line   int    // line number
+	Reason string // description of the problem
+}
+
+// String is a ParseWarning Stringer.
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Reason)
+}
+
 // ObsDecoder reads and decodes header and data records from a RINEX Obs input stream.
 type ObsDecoder struct {
 	// The Header is valid after NewObsDecoder or Reader.Reset. The header must exist,
 	// otherwise ErrNoHeader will be returned.
 	Header ObsHeader
 	//b       *bufio.Reader // remove!!!
-	sc      *bufio.Scanner
+	// Lenient enables the lenient mode where malformed epochs and observation lines
+	// are skipped and recorded in ParseWarnings instead of stopping the decoding.
+	Lenient bool
+	// ParseWarnings holds the problems encountered in lenient mode.
+	ParseWarnings []ParseWarning
+	// Logger logs the parse warnings and other problems of the input, if set.
+	Logger *log.Logger
+	// ReuseEpoch makes NextEpoch reuse the same Epoch, its ObsList and the observation buffers
+	// for every epoch. It avoids most allocations for callers that fully process each epoch
+	// before advancing, the epoch and its observations must not be retained. Set it before
+	// the first call to NextEpoch.
+	ReuseEpoch bool
+	// Workers sets the number of goroutines parsing the epochs concurrently. One goroutine splits
+	// the input into blocks of epochs, the workers parse them and NextEpoch returns the epochs in
+	// input order. Values less than 2 mean sequential parsing. ReuseEpoch and SeekEpoch are not
+	// supported in this mode. Call Stop if the epochs are not read until the end. RINEX 2 input is
+	// always parsed sequentially.
+	Workers int
+
+	// Name of the input, e.g. the file name, used in the History comments of the written files.
+	Name string
+
+	// OnEvent is called for the event epochs, flags 2-5, and the cycle slip epochs, flag 6, which are then
+	// not returned by NextEpoch, so that NextEpoch only returns observation epochs. The epoch must not be
+	// retained after the call in ReuseEpoch mode. If OnEvent is nil, NextEpoch returns all epochs.
+	OnEvent func(epo *Epoch)
+
+	// Opts.SatSys drops the satellites of the other systems from the epochs.
+	// Opts.ElevationMask drops satellites below the cutoff angle, as seen from the header's
+	// approximate position. This requires the EphemerisStore to be set.
+	Opts           Options
+	EphemerisStore *EphemerisStore
+
+	sc      *lineReader
+	decOpts DecoderOptions
+	obsSlab []Obs  // the current buffer of preallocated observations
+	obsBuf  []Obs  // the unused part of obsSlab, carved up for the satellites
 	epo     *Epoch // the current epoch
+	reuse   *Epoch // the epoch reused in ReuseEpoch mode
 	syncEpo *Epoch // the snchronized epoch from a second decoder
 	lineNum int
 	err     error
+
+	// random access, see SeekEpoch
+	rs        io.ReadSeeker
+	dataStart int64 // offset of the first line after the header
+	dataLine  int   // number of the last header line
+	index     *EpochIndex
+	start     time.Time // skip the epochs before, see WithTimeWindow
+	end       time.Time // stop decoding at this epoch
+
+	pipe *pipeline // the parallel parsing, see Workers
 }
 
-// NewObsDecoder creates a new decoder for RINEX Observation data.
+// NewObsDecoder creates a new decoder for RINEX Observation data, configured by the options, e.g.
+//
+//	dec, err := NewObsDecoder(r, WithSatSys(gnss.NewSystemSet(gnss.SysGPS, gnss.SysGAL)), WithLenientParsing())
+//
 // The RINEX header will be read implicitly. The header must exist.
 //
 // It is the caller's responsibility to call Close on the underlying reader when done!
-func NewObsDecoder(r io.Reader) (*ObsDecoder, error) {
-	dec := &ObsDecoder{sc: bufio.NewScanner(r)}
+func NewObsDecoder(r io.Reader, opts ...Option) (*ObsDecoder, error) {
+	dec := &ObsDecoder{}
+	for _, opt := range opts {
+		opt(dec)
+	}
+	dec.sc = dec.decOpts.lineReader(r)
+	if rs, ok := r.(io.ReadSeeker); ok && dec.decOpts.Follow == nil {
+		if pos, err := rs.Seek(0, io.SeekCurrent); err == nil { // fails e.g. for pipes
+			dec.rs = rs
+			dec.sc.pos = pos
+		}
+	}
 	dec.Header, dec.err = dec.readHeader()
+	if dec.err == nil && dec.lineNum > 0 && (len(dec.Header.labels) == 0 || dec.Header.labels[0] != "RINEX VERSION / TYPE") {
+		dec.err = ErrNoHeader // empty input is not an error
+	}
+	dec.dataStart, dec.dataLine = dec.sc.pos, dec.lineNum
 	return dec, dec.err
 }
 
+// NewObsDecoderWithOptions creates a new decoder for RINEX Observation data with the given
+// line length and header limits. With opts.Follow the decoder waits for epochs appended to a growing file.
+// It is the same as NewObsDecoder with WithDecoderOptions.
+func NewObsDecoderWithOptions(r io.Reader, opts DecoderOptions) (*ObsDecoder, error) {
+	return NewObsDecoder(r, WithDecoderOptions(opts))
+}
+
 // Err returns the first non-EOF error that was encountered by the decoder.
 func (dec *ObsDecoder) Err() error {
 	if dec.err == io.EOF {
@@ -200,12 +503,15 @@ func (dec *ObsDecoder) Err() error {
 	return dec.err
 }
 
-// readHeader reads a RINEX Navigation header. If the Header does not exist,
-// a ErrNoHeader error will be returned.
+// readHeader reads a RINEX Observation header. NewObsDecoder returns ErrNoHeader
+// if a non-empty input does not begin with the RINEX VERSION / TYPE record.
 func (dec *ObsDecoder) readHeader() (hdr ObsHeader, err error) {
 	hdr.ObsTypes = map[gnss.System][]string{}
-	maxLines := 800
+	maxLines := dec.decOpts.maxHeaderLines(800)
 	rememberMe := ""
+	var lastPRN PRN
+	var lastPhaseShift *PhaseShift
+	var lastScaleFactor *ScaleFactor
 read:
 	for dec.sc.Scan() {
 		dec.lineNum++
@@ -231,10 +537,14 @@ read:
 			if f64, err := strconv.ParseFloat(strings.TrimSpace(val[:20]), 32); err == nil {
 				hdr.RINEXVersion = float32(f64)
 			} else {
-				return hdr, fmt.Errorf("parsing RINEX VERSION: %v", err)
+				return hdr, fmt.Errorf("parsing RINEX VERSION: %w", err)
 			}
 			hdr.RINEXType = strings.TrimSpace(val[20:21])
-			if sys, ok := sysPerAbbr[strings.TrimSpace(val[40:41])]; ok {
+			sysStr := strings.TrimSpace(val[40:41])
+			if sysStr == "" && hdr.RINEXVersion < 3 {
+				sysStr = "G" // blank means GPS
+			}
+			if sys, ok := gnss.SystemByAbbr(sysStr); ok {
 				hdr.SatSystem = sys
 			} else {
 				err = fmt.Errorf("read header: invalid satellite system in line %d: %s", dec.lineNum, line)
@@ -251,7 +561,7 @@ read:
 		case "MARKER NUMBER":
 			hdr.MarkerNumber = strings.TrimSpace(val[:20])
 		case "MARKER TYPE":
-			hdr.MarkerType = strings.TrimSpace(val[20:40])
+			hdr.MarkerType = strings.TrimSpace(val[:20])
 		case "OBSERVER / AGENCY":
 			hdr.Observer = strings.TrimSpace(val[:20])
 			hdr.Agency = strings.TrimSpace(val[20:])
@@ -290,6 +600,21 @@ read:
 			if f64, err := strconv.ParseFloat(ecc[2], 64); err == nil {
 				hdr.AntennaDelta.N = f64
 			}
+		case "ANTENNA: DELTA X/Y/Z":
+			hdr.AntennaDeltaXYZ, err = parseCoord(val)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+			}
+		case "ANTENNA: B.SIGHT XYZ":
+			hdr.AntennaBSight, err = parseCoord(val)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+			}
+		case "CENTER OF MASS: XYZ":
+			hdr.CenterOfMass, err = parseCoord(val)
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+			}
 		case "SYS / # / OBS TYPES":
 			sysStr := val[:1]
 			if sysStr == " " { // line continued
@@ -298,7 +623,7 @@ read:
 				rememberMe = sysStr
 			}
 
-			sys, ok := sysPerAbbr[sysStr]
+			sys, ok := gnss.SystemByAbbr(sysStr)
 			if !ok {
 				err = fmt.Errorf("invalid satellite system: %q: line %d", val[:1], dec.lineNum)
 				return
@@ -309,6 +634,20 @@ read:
 			} else {
 				hdr.ObsTypes[sys] = append(hdr.ObsTypes[sys], strings.Fields(val[7:])...)
 			}
+		case "# / TYPES OF OBSERV": // RINEX 2, the types of all systems
+			var types []string
+			if strings.TrimSpace(val[:6]) == "" { // line continued
+				types = hdr.ObsTypes[hdr.SatSystem]
+				if hdr.SatSystem == gnss.SysMIXED {
+					types = hdr.ObsTypes[gnss.SysGPS]
+				}
+			}
+			types = append(types[:len(types):len(types)], strings.Fields(val[6:])...)
+			for _, sys := range rinex2Systems(hdr.SatSystem) {
+				hdr.ObsTypes[sys] = types
+			}
+		case "WAVELENGTH FACT L1/2":
+			// RINEX 2, full cycle ambiguities of modern receivers
 		case "SIGNAL STRENGTH UNIT":
 			hdr.SignalStrengthUnit = strings.TrimSpace(val[:20])
 		case "INTERVAL":
@@ -318,42 +657,258 @@ read:
 		case "TIME OF FIRST OBS":
 			t, err := time.Parse(epochTimeFormat, strings.TrimSpace(val[:43]))
 			if err != nil {
-				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+				return hdr, fmt.Errorf("parsing %q: %w", key, err)
 			}
 			hdr.TimeOfFirstObs = t
+			hdr.TimeSystem = strings.TrimSpace(val[48:51])
 		case "TIME OF LAST OBS":
 			t, err := time.Parse(epochTimeFormat, strings.TrimSpace(val[:43]))
 			if err != nil {
-				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+				return hdr, fmt.Errorf("parsing %q: %w", key, err)
 			}
 			hdr.TimeOfLastObs = t
-		case "LEAP SECONDS": // not complete! TODO: extend
+		case "LEAP SECONDS":
 			i, err := strconv.Atoi(strings.TrimSpace(val[:6]))
 			if err != nil {
-				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+				return hdr, fmt.Errorf("parsing %q: %w", key, err)
 			}
 			hdr.LeapSeconds = i
+			hdr.LeapSecondsFuture, _ = strconv.Atoi(strings.TrimSpace(val[6:12]))
+			hdr.LeapSecondsWeek, _ = strconv.Atoi(strings.TrimSpace(val[12:18]))
+			hdr.LeapSecondsDay, _ = strconv.Atoi(strings.TrimSpace(val[18:24]))
+			hdr.LeapSecondsSys = strings.TrimSpace(val[24:27])
 		case "# OF SATELLITES":
 			i, err := strconv.Atoi(strings.TrimSpace(val[:6]))
 			if err != nil {
-				return hdr, fmt.Errorf("parsing %q: %v", key, err)
+				return hdr, fmt.Errorf("parsing %q: %w", key, err)
 			}
 			hdr.NSatellites = i
+		case "RCV CLOCK OFFS APPL":
+			if s := strings.TrimSpace(val[:6]); s != "" {
+				i, err := strconv.Atoi(s)
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: %w", key, err)
+				}
+				hdr.RcvClockOffsAppl = i == 1
+			}
+		case "SYS / DCBS APPLIED", "SYS / PCVS APPLIED":
+			sys, ok := gnss.SystemByAbbr(val[:1])
+			if !ok {
+				return hdr, fmt.Errorf("invalid satellite system: %q: line %d", val[:1], dec.lineNum)
+			}
+			corr := CorrApplied{Sys: sys, Program: strings.TrimSpace(val[2:19]), Source: strings.TrimSpace(val[20:])}
+			if key == "SYS / DCBS APPLIED" {
+				hdr.DCBSApplied = append(hdr.DCBSApplied, corr)
+			} else {
+				hdr.PCVSApplied = append(hdr.PCVSApplied, corr)
+			}
+		case "SYS / SCALE FACTOR":
+			if val[:1] == " " { // line continued
+				if lastScaleFactor == nil {
+					return hdr, fmt.Errorf("parsing %q: continuation line without preceding record: line %d", key, dec.lineNum)
+				}
+				lastScaleFactor.ObsTypes = append(lastScaleFactor.ObsTypes, strings.Fields(val[10:])...)
+				continue
+			}
+			sys, ok := gnss.SystemByAbbr(val[:1])
+			if !ok {
+				return hdr, fmt.Errorf("invalid satellite system: %q: line %d", val[:1], dec.lineNum)
+			}
+			factor, err := strconv.Atoi(strings.TrimSpace(val[2:6]))
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: %w", key, err)
+			}
+			hdr.ScaleFactors = append(hdr.ScaleFactors, ScaleFactor{Sys: sys, Factor: factor, ObsTypes: strings.Fields(val[10:])})
+			lastScaleFactor = &hdr.ScaleFactors[len(hdr.ScaleFactors)-1]
+		case "SYS / PHASE SHIFT":
+			if val[:1] == " " { // line continued
+				if lastPhaseShift == nil {
+					return hdr, fmt.Errorf("parsing %q: continuation line without preceding record: line %d", key, dec.lineNum)
+				}
+				sats, err := parsePRNList(val[18:])
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+				lastPhaseShift.Sats = append(lastPhaseShift.Sats, sats...)
+				continue
+			}
+			sys, ok := gnss.SystemByAbbr(val[:1])
+			if !ok {
+				return hdr, fmt.Errorf("invalid satellite system: %q: line %d", val[:1], dec.lineNum)
+			}
+			shift := PhaseShift{Sys: sys, ObsType: strings.TrimSpace(val[2:5])}
+			if s := strings.TrimSpace(val[6:14]); s != "" {
+				f64, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: %w", key, err)
+				}
+				shift.Correction = f64
+			}
+			shift.Sats, err = parsePRNList(val[18:])
+			if err != nil {
+				return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+			}
+			hdr.PhaseShifts = append(hdr.PhaseShifts, shift)
+			lastPhaseShift = &hdr.PhaseShifts[len(hdr.PhaseShifts)-1]
+		case "GLONASS SLOT / FRQ #":
+			if hdr.GloSlots == nil {
+				hdr.GloSlots = make(GloSlots, 24)
+			}
+			for col := 4; col+7 <= 60; col += 7 {
+				s := val[col : col+7]
+				if strings.TrimSpace(s) == "" {
+					continue
+				}
+				prn, err := ParsePRN(s[:3])
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+				frq, err := strconv.Atoi(strings.TrimSpace(s[4:6]))
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+				hdr.GloSlots[prn] = frq
+			}
+		case "GLONASS COD/PHS/BIS":
+			if hdr.GloCodPhsBis == nil {
+				hdr.GloCodPhsBis = make(map[string]float64, 4)
+			}
+			for col := 0; col+13 <= 52; col += 13 {
+				typ := strings.TrimSpace(val[col+1 : col+4])
+				s := strings.TrimSpace(val[col+5 : col+13])
+				if typ == "" || s == "" { // bias unknown
+					continue
+				}
+				bias, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: %w", key, err)
+				}
+				hdr.GloCodPhsBis[typ] = bias
+			}
+		case "PRN / # OF OBS":
+			if hdr.ObsPerSat == nil {
+				hdr.ObsPerSat = make(map[PRN][]int, 60)
+			}
+			if s := val[3:6]; s != "   " {
+				lastPRN, err = ParsePRN(s)
+				if err != nil {
+					return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+				}
+			}
+			for col := 6; col+6 <= 60; col += 6 {
+				s := strings.TrimSpace(val[col : col+6])
+				nObs := 0
+				if s != "" {
+					nObs, err = strconv.Atoi(s)
+					if err != nil {
+						return hdr, fmt.Errorf("parsing %q: line %d: %w", key, dec.lineNum, err)
+					}
+				}
+				if len(hdr.ObsPerSat[lastPRN]) < len(hdr.ObsTypes[lastPRN.Sys]) {
+					hdr.ObsPerSat[lastPRN] = append(hdr.ObsPerSat[lastPRN], nObs)
+				}
+			}
+		case "DOI":
+			hdr.DOI = strings.TrimSpace(val)
+		case "LICENSE OF USE":
+			hdr.Licenses = append(hdr.Licenses, strings.TrimSpace(val))
+		case "STATION INFORMATION":
+			hdr.StationInfos = append(hdr.StationInfos, strings.TrimSpace(val))
 		case "END OF HEADER":
 			break read
 		default:
-			fmt.Printf("Header field %q not handled yet\n", key)
+			hdr.warnings = append(hdr.warnings, fmt.Sprintf("header label not handled: %s", key))
 		}
 	}
 
 	err = dec.sc.Err()
+	if err == nil && hdr.RINEXVersion >= 5 {
+		err = fmt.Errorf("%w: %.2f", ErrUnsupportedVersion, hdr.RINEXVersion)
+	}
 	return
 }
 
+// RegisterLeapSeconds adds the leap seconds of the header to the leap second table of gnsstime with
+// gnsstime.UpdateLeapSeconds, e.g. to convert the epochs after a leap second that is not yet in the
+// built-in table. The table is shared by the whole process and wrong LEAP SECONDS are common, so only
+// a single leap second after the last entry of the table is added. It returns an error and leaves the
+// table unchanged if the header contradicts the table.
+func (hdr *ObsHeader) RegisterLeapSeconds() error {
+	if hdr.LeapSeconds == 0 {
+		return nil
+	}
+	epoch, offset := gnsstime.GPSEpoch, 0
+	if hdr.LeapSecondsSys == "BDS" { // BDT-UTC
+		epoch, offset = gnsstime.BDSEpoch, int(gnsstime.BDSOffset/time.Second)
+	}
+	var leaps []gnsstime.LeapSecond // GPS-UTC from the UTC time Start on
+	if !hdr.TimeOfFirstObs.IsZero() {
+		leap := hdr.LeapSeconds + offset
+		leaps = append(leaps, gnsstime.LeapSecond{Start: headerTimeToUTC(hdr.TimeOfFirstObs, hdr.TimeSystem, leap), Offset: leap})
+	}
+	if hdr.LeapSecondsWeek > 0 && hdr.LeapSecondsDay > 0 {
+		start := epoch.AddDate(0, 0, hdr.LeapSecondsWeek*7+hdr.LeapSecondsDay)
+		leaps = append(leaps, gnsstime.LeapSecond{Start: start, Offset: hdr.LeapSecondsFuture + offset})
+	}
+	if err := gnsstime.UpdateLeapSeconds(leaps...); err != nil {
+		return fmt.Errorf("register header leap seconds: %w", err)
+	}
+	return nil
+}
+
+// headerTimeToUTC converts the header time t in the time system sys to UTC, with leap the GPS-UTC seconds.
+func headerTimeToUTC(t time.Time, sys string, leap int) time.Time {
+	switch sys {
+	case "GLO", "UTC":
+		return t
+	case "BDT":
+		return t.Add(gnsstime.BDSOffset - time.Duration(leap)*time.Second)
+	}
+	return t.Add(-time.Duration(leap) * time.Second)
+}
+
+// parseCoord parses three floats in the format 3F14.4, as used for XYZ coordinates in the header.
+func parseCoord(val string) (Coord, error) {
+	f := strings.Fields(val)
+	if len(f) != 3 {
+		return Coord{}, fmt.Errorf("expected 3 values: %q", val)
+	}
+	var xyz [3]float64
+	for i := range xyz {
+		f64, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return Coord{}, err
+		}
+		xyz[i] = f64
+	}
+	return Coord{X: xyz[0], Y: xyz[1], Z: xyz[2]}, nil
+}
+
 // NextEpoch reads the observations for the next epoch.
 // It returns false when the scan stops, either by reaching the end of the input or an error.
+// In lenient mode malformed epoch lines and observation lines are skipped and recorded in ParseWarnings.
 // TODO: add phase shifts
 func (dec *ObsDecoder) NextEpoch() bool {
+	if !dec.nextEpoch() {
+		return false
+	}
+	if dec.decOpts.Progress != nil {
+		dec.decOpts.Progress.AddEpochs(1)
+	}
+	return true
+}
+
+// nextEpoch reads the next epoch, see NextEpoch.
+func (dec *ObsDecoder) nextEpoch() bool {
+	if dec.err != nil {
+		return false
+	}
+	if dec.Header.RINEXVersion > 0 && dec.Header.RINEXVersion < 3 {
+		return dec.nextEpochV2()
+	}
+	if dec.Workers > 1 {
+		return dec.nextParallel()
+	}
 	for dec.sc.Scan() {
 		dec.lineNum++
 		line := dec.sc.Text()
@@ -363,128 +918,317 @@ func (dec *ObsDecoder) NextEpoch() bool {
 		}
 
 		if !strings.HasPrefix(line, "> ") {
-			fmt.Printf("stream does not start with epoch line: %q\n", line) // must not be an error
+			if !dec.Lenient {
+				dec.logf("stream does not start with epoch line: %q", line) // must not be an error
+			}
 			continue
 		}
 
-		//> 2018 11 06 19 00  0.0000000  0 31
-		epTime, err := time.Parse(epochTimeFormat, line[2:29])
+		e, numSat, err := dec.parseEpochLine(line)
 		if err != nil {
-			dec.setErr(fmt.Errorf("error in line %d: %v", dec.lineNum, err))
+			if dec.Lenient {
+				dec.warn(err)
+				continue
+			}
+			dec.setErr(err)
 			return false
 		}
-
-		epochFlag, err := strconv.Atoi(line[31:32])
-		if err != nil {
-			dec.setErr(fmt.Errorf("parsing epoch flag in line %d: %q", dec.lineNum, line))
+		if !dec.end.IsZero() && !e.Time.IsZero() && !e.Time.Before(dec.end) {
+			dec.setErr(io.EOF)
 			return false
 		}
-
-		numSat, err := strconv.Atoi(strings.TrimSpace(line[32:35]))
-		if err != nil {
-			dec.setErr(fmt.Errorf("error in line %d: %v", dec.lineNum, err))
-			return false
+		if !dec.start.IsZero() && !e.Time.IsZero() && e.Time.Before(dec.start) {
+			if !dec.skipLines(numSat) {
+				return false
+			}
+			continue
 		}
-
-		//fmt.Printf("epoch: %s\n", epTime.Format(time.RFC3339Nano))
-		// TODO wrap errors Go 1.13
-		dec.epo = &Epoch{Time: epTime, Flag: int8(epochFlag), NumSat: uint8(numSat),
-			ObsList: make([]SatObs, 0, numSat)}
-
-		for ii := 1; ii <= numSat; ii++ {
-			dec.sc.Scan()
-			dec.lineNum++
-			if err := dec.sc.Err(); err != nil {
-				dec.setErr(fmt.Errorf("error in line %d: %v", dec.lineNum, err))
+		var epo *Epoch
+		if dec.ReuseEpoch {
+			if dec.reuse == nil {
+				dec.reuse = &Epoch{}
+			}
+			e.ObsList = dec.reuse.ObsList[:0]
+			*dec.reuse = e
+			epo = dec.reuse
+			dec.obsBuf = dec.obsSlab
+		} else {
+			ep := e
+			epo = &ep
+		}
+		dec.epo = epo
+
+		if epo.IsEvent() {
+			// numSat is the number of special records to follow
+			if err := dec.readEvent(numSat); err != nil {
+				if dec.Lenient {
+					dec.warn(err)
+					continue
+				}
+				dec.setErr(err)
 				return false
 			}
-			line = dec.sc.Text()
-
-			// Parse obs line
-			// fmt.Sscanf(" 1234567 ", "%5s%d", &s, &i)
-			// fmt.Scanf is pretty slow in Go!? https://github.com/golang/go/issues/12275#issuecomment-133796990
+			if dec.OnEvent != nil {
+				dec.OnEvent(epo)
+				continue
+			}
+			return true
+		}
 
-			// sysHlp := []rune(line[:1])
-			// sys := sysHlp[0]
-			sys, ok := sysPerAbbr[line[:1]]
-			if !ok {
-				dec.setErr(fmt.Errorf("invalid satellite system: %q: line %d", line[:1], dec.lineNum))
+		if !dec.ReuseEpoch || cap(epo.ObsList) < numSat {
+			epo.ObsList = make([]SatObs, 0, numSat)
+		}
+		for ii := 1; ii <= numSat; ii++ {
+			if !dec.sc.Scan() {
+				if err := dec.sc.Err(); err != nil {
+					dec.setErr(fmt.Errorf("error in line %d: %w", dec.lineNum, err))
+					return false
+				}
+				err := fmt.Errorf("unexpected EOF: epoch %s has %d of %d satellites", epo.Time, ii-1, numSat)
+				if dec.Lenient {
+					dec.warn(err)
+					return true
+				}
+				dec.setErr(err)
 				return false
 			}
+			dec.lineNum++
 
-			snum, err := strconv.Atoi(line[1:3])
+			satObs, err := dec.parseObsLine(dec.sc.Text())
 			if err != nil {
-				dec.setErr(fmt.Errorf("parsing sat num in line %d: %q: %v", dec.lineNum, line, err))
+				if dec.Lenient {
+					dec.warn(err)
+					continue
+				}
+				dec.setErr(err)
 				return false
 			}
-			prn, err := newPRN(sys, int8(snum))
-			if err != nil {
-				dec.setErr(fmt.Errorf("parsing sat num in line %d: %q: %v", dec.lineNum, line, err))
-				return false
+			if satObs.Obss == nil || !dec.Opts.useSys(satObs.Prn.Sys) { // no observations or not selected
+				continue
 			}
+			epo.ObsList = append(epo.ObsList, satObs)
+		}
 
-			if strings.TrimSpace(line[3:]) == "" { // ??
-				continue
+		if epo.IsCycleSlip() && dec.OnEvent != nil {
+			dec.OnEvent(epo)
+			continue
+		}
+		if dec.Opts.ElevationMask > 0 && dec.EphemerisStore != nil && dec.Header.Position != (Coord{}) {
+			epo.ApplyElevationMask(dec.EphemerisStore, dec.Header.Position, dec.Opts.ElevationMask)
+		}
+		return true
+	}
+
+	if err := dec.sc.Err(); err != nil {
+		dec.setErr(fmt.Errorf("read epoch scanner error: %w", err))
+	}
+
+	return false // EOF
+}
+
+// parseEpochLine parses an epoch line and returns the epoch and the number of satellites or special records to follow.
+// Errors are of type *ErrBadEpochLine.
+func (dec *ObsDecoder) parseEpochLine(line string) (Epoch, int, error) {
+	epo, numSat, err := parseEpochLine(line)
+	if err != nil {
+		return Epoch{}, 0, &ErrBadEpochLine{Line: line, Num: dec.lineNum, Err: err}
+	}
+	return epo, numSat, nil
+}
+
+// parseEpochLine parses a RINEX 3 epoch line.
+func parseEpochLine(line string) (Epoch, int, error) {
+	//> 2018 11 06 19 00  0.0000000  0 31       -0.000123456789
+	if len(line) < 35 {
+		return Epoch{}, 0, errors.New("line too short")
+	}
+
+	epochFlag, err := strconv.Atoi(field(line, 31, 32))
+	if err != nil {
+		return Epoch{}, 0, fmt.Errorf("parsing epoch flag: %w", err)
+	}
+
+	// The epoch time is optional for event flags 2-5.
+	var epTime time.Time
+	if epochFlag < 2 || epochFlag > 5 || strings.TrimSpace(field(line, 2, 29)) != "" {
+		epTime, err = time.Parse(epochTimeFormat, field(line, 2, 29))
+		if err != nil {
+			return Epoch{}, 0, err
+		}
+	}
+
+	numSat, err := strconv.Atoi(strings.TrimSpace(field(line, 32, 35)))
+	if err != nil {
+		return Epoch{}, 0, fmt.Errorf("parsing number of satellites: %w", err)
+	}
+	if numSat < 0 {
+		return Epoch{}, 0, fmt.Errorf("invalid number of satellites: %d", numSat)
+	}
+
+	var clkOff float64
+	if s := strings.TrimSpace(field(line, 41, len(line))); s != "" {
+		clkOff, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Epoch{}, 0, fmt.Errorf("parsing receiver clock offset: %w", err)
+		}
+	}
+
+	return Epoch{Time: epTime, Flag: EpochFlag(epochFlag), NumSat: uint8(numSat), ClockOffset: clkOff}, numSat, nil
+}
+
+// obsBufSize is the number of observations allocated at once by the decoder.
+const obsBufSize = 4096
+
+// allocObs returns a slice of n zeroed observations from the decoder's buffer.
+func (dec *ObsDecoder) allocObs(n int) []Obs {
+	if n > len(dec.obsBuf) {
+		size := obsBufSize
+		if dec.ReuseEpoch { // the buffer is reused for every epoch, so it should hold a complete one
+			if used := len(dec.obsSlab) - len(dec.obsBuf); 2*(used+n) > size {
+				size = 2 * (used + n)
 			}
+		}
+		if n > size {
+			size = n
+		}
+		dec.obsSlab = make([]Obs, size)
+		dec.obsBuf = dec.obsSlab
+	}
+	obs := dec.obsBuf[:n:n]
+	dec.obsBuf = dec.obsBuf[n:]
+	if dec.ReuseEpoch {
+		for i := range obs {
+			obs[i] = Obs{}
+		}
+	}
+	return obs
+}
 
-			obsPerTyp := make(map[string]Obs, 30) // cap
-			col := 3                              // line column
-			for _, typ := range dec.Header.ObsTypes[sys] {
-				var val float64
-				if col+14 > len(line) {
-					// error ??
-					dec.setErr(fmt.Errorf("obstype %s out of range in line %d: %q", typ, dec.lineNum, line))
-					//break
-					return false
-				}
+// parseObsLine parses the observations of a satellite. If the line contains no observations,
+// the returned SatObs has a nil Obss slice.
+func (dec *ObsDecoder) parseObsLine(line string) (SatObs, error) {
+	// Parse obs line
+	// fmt.Sscanf(" 1234567 ", "%5s%d", &s, &i)
+	// fmt.Scanf is pretty slow in Go!? https://github.com/golang/go/issues/12275#issuecomment-133796990
+	if len(line) < 3 {
+		return SatObs{}, fmt.Errorf("observation line too short: line %d: %q", dec.lineNum, line)
+	}
 
-				//fmt.Printf("%q\n", line[col:col+14])
-				obsStr := strings.TrimSpace(line[col : col+14])
-				if obsStr != "" {
-					val, err = strconv.ParseFloat(obsStr, 64)
-					if err != nil {
-						dec.setErr(fmt.Errorf("parsing the %s observation in line %d: %q", typ, dec.lineNum, line))
-						return false
-					}
-				}
-				col += 14
+	sys, ok := gnss.SystemByAbbr(line[:1])
+	if !ok {
+		return SatObs{}, fmt.Errorf("invalid satellite system: %q: line %d", line[:1], dec.lineNum)
+	}
 
-				// LLI
-				if col+1 > len(line) {
-					obsPerTyp[typ] = Obs{Val: val}
-					break
-				}
-				col++
-				lli, err := parseFlag(line[col-1 : col])
-				if err != nil {
-					dec.setErr(fmt.Errorf("parsing the %s LLI in line %d: %q: %v", typ, dec.lineNum, line, err))
-					return false
-				}
+	snum, err := strconv.Atoi(line[1:3])
+	if err != nil {
+		return SatObs{}, fmt.Errorf("parsing sat num in line %d: %q: %w", dec.lineNum, line, err)
+	}
+	prn, err := NewPRN(sys, snum)
+	if err != nil {
+		return SatObs{}, fmt.Errorf("parsing sat num in line %d: %q: %w", dec.lineNum, line, err)
+	}
 
-				// SNR
-				if col+1 > len(line) {
-					obsPerTyp[typ] = Obs{Val: val, LLI: int8(lli)}
-					break
-				}
-				col++
-				snr, err := parseFlag(line[col-1 : col])
-				if err != nil {
-					dec.setErr(fmt.Errorf("parsing the %s SNR in line %d: %q: %v", typ, dec.lineNum, line, err))
-					return false
-				}
+	if strings.TrimSpace(line[3:]) == "" { // ??
+		return SatObs{Prn: prn}, nil
+	}
+
+	types := dec.Header.ObsTypes[sys]
+	obss := dec.allocObs(len(types))
+	col := 3 // line column
+	for i, typ := range types {
+		if col >= len(line) { // trailing blank observations may be omitted
+			break
+		}
+		if col+14 > len(line) {
+			return SatObs{}, fmt.Errorf("obstype %s out of range in line %d: %q", typ, dec.lineNum, line)
+		}
+
+		val, err := parseFixedFloat(line[col : col+14])
+		if err != nil {
+			return SatObs{}, fmt.Errorf("parsing the %s observation in line %d: %q", typ, dec.lineNum, line)
+		}
+		col += 14
+
+		// LLI
+		if col+1 > len(line) {
+			obss[i] = Obs{Val: val}
+			break
+		}
+		col++
+		lli, err := parseFlag(line[col-1 : col])
+		if err != nil {
+			return SatObs{}, fmt.Errorf("parsing the %s LLI in line %d: %q: %w", typ, dec.lineNum, line, err)
+		}
+
+		// SNR
+		if col+1 > len(line) {
+			obss[i] = Obs{Val: val, LLI: int8(lli)}
+			break
+		}
+		col++
+		snr, err := parseFlag(line[col-1 : col])
+		if err != nil {
+			return SatObs{}, fmt.Errorf("parsing the %s SNR in line %d: %q: %w", typ, dec.lineNum, line, err)
+		}
+
+		obss[i] = Obs{Val: val, LLI: int8(lli), SNR: int8(snr)}
+	}
+	return SatObs{Prn: prn, Types: types[:len(types):len(types)], Obss: obss}, nil
+}
+
+// warn records a parse warning for the current line.
+func (dec *ObsDecoder) warn(err error) {
+	dec.ParseWarnings = append(dec.ParseWarnings, ParseWarning{Line: dec.lineNum, Reason: err.Error()})
+	if dec.Logger != nil {
+		dec.Logger.Printf("line %d: %v", dec.lineNum, err)
+	}
+}
+
+// logf logs a problem of the input to the Logger, or prints it to stdout if no Logger is set.
+func (dec *ObsDecoder) logf(format string, args ...interface{}) {
+	if dec.Logger != nil {
+		dec.Logger.Printf(format, args...)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
 
-				obsPerTyp[typ] = Obs{Val: val, LLI: int8(lli), SNR: int8(snr)}
+// skipLines skips the n records of an epoch. It returns false if the input ends before.
+func (dec *ObsDecoder) skipLines(n int) bool {
+	for i := 0; i < n; i++ {
+		if !dec.sc.Scan() {
+			if err := dec.sc.Err(); err != nil {
+				dec.setErr(fmt.Errorf("error in line %d: %w", dec.lineNum, err))
 			}
-			dec.epo.ObsList = append(dec.epo.ObsList, SatObs{Prn: prn, Obss: obsPerTyp})
+			return false
 		}
-		return true
+		dec.lineNum++
 	}
+	return true
+}
 
-	if err := dec.sc.Err(); err != nil {
-		dec.setErr(fmt.Errorf("read epoch scanner error: %v", err))
+// readEvent reads the n header records that follow an event epoch line.
+func (dec *ObsDecoder) readEvent(n int) error {
+	ev := &Event{Records: make([]string, 0, n)}
+	for ii := 1; ii <= n; ii++ {
+		if !dec.sc.Scan() {
+			if err := dec.sc.Err(); err != nil {
+				return fmt.Errorf("error in line %d: %w", dec.lineNum, err)
+			}
+			return fmt.Errorf("unexpected EOF reading event records: line %d", dec.lineNum)
+		}
+		dec.lineNum++
+		ev.Records = append(ev.Records, dec.sc.Text())
 	}
 
-	return false // EOF
+	evDec := &ObsDecoder{sc: newLineReader(strings.NewReader(strings.Join(ev.Records, "\n")), dec.decOpts.MaxLineLength)}
+	hdr, err := evDec.readHeader()
+	if err != nil {
+		return fmt.Errorf("parsing event records after line %d: %w", dec.lineNum-n, err)
+	}
+	ev.Header = hdr
+	dec.epo.Event = ev
+	return nil
 }
 
 // Epoch returns the most recent epoch generated by a call to NextEpoch.
@@ -492,7 +1236,7 @@ func (dec *ObsDecoder) Epoch() *Epoch {
 	return dec.epo
 }
 
-// SyncEpoch returns the current pair of time-synchronized epochs from two RINEX Obs input streams.
+// SyncEpoch returns the current pair of time-synchronized epochs from two RINEX Obs input streams, see SyncWith.
 func (dec *ObsDecoder) SyncEpoch() SyncEpochs {
 	return SyncEpochs{dec.epo, dec.syncEpo}
 }
@@ -504,8 +1248,16 @@ func (dec *ObsDecoder) setErr(err error) {
 	}
 }
 
-// sync returns a stream of time-synchronized epochs from two RINEX Obs input streams.
-func (dec *ObsDecoder) sync(dec2 *ObsDecoder) bool {
+// SyncWith advances both decoders to the next epoch with the same timestamp, epochs found in only one
+// of the streams are skipped. The pair of epochs is available by SyncEpoch. It returns false at the end
+// of one of the streams or on an error, which is reported by dec.Err.
+// The epochs must be in time order. For more than two streams see EpochSynchronizer.
+//
+//	for dec.SyncWith(dec2) {
+//		epochs := dec.SyncEpoch()
+//		...
+//	}
+func (dec *ObsDecoder) SyncWith(dec2 *ObsDecoder) bool {
 	var epoF1, epoF2 *Epoch
 	for dec.NextEpoch() {
 		epoF1 = dec.Epoch()
@@ -534,7 +1286,7 @@ func (dec *ObsDecoder) sync(dec2 *ObsDecoder) bool {
 	}
 
 	if err := dec2.Err(); err != nil {
-		dec.setErr(fmt.Errorf("stream2 decoder error: %v", err))
+		dec.setErr(fmt.Errorf("stream2 decoder error: %w", err))
 	}
 	return false
 }
@@ -543,8 +1295,9 @@ func (dec *ObsDecoder) sync(dec2 *ObsDecoder) bool {
 // Use NewObsFil() to instantiate a new ObsFile.
 type ObsFile struct {
 	*RnxFil
-	Header ObsHeader
-	Opts   Options
+	Header    ObsHeader
+	Opts      Options
+	Checksums *Checksums // nil until computed with ComputeChecksums
 }
 
 // NewObsFile returns a new ObsFile.
@@ -555,50 +1308,58 @@ func NewObsFile(filepath string) (*ObsFile, error) {
 	return obsFil, err
 }
 
-// Diff compares two RINEX obs files.
+// Diff compares two RINEX obs files and prints the differences.
 func (f *ObsFile) Diff(obsFil2 *ObsFile) error {
+	diffs, err := f.Differences(obsFil2)
+	for _, diff := range diffs {
+		fmt.Printf("diff: %s\n", diff)
+	}
+	return err
+}
+
+// Differences compares two RINEX obs files and returns the differences of their common epochs.
+func (f *ObsFile) Differences(obsFil2 *ObsFile) ([]string, error) {
 	// file 1
-	r, err := os.Open(f.Path)
+	r, err := f.open()
 	if err != nil {
-		return fmt.Errorf("open obs file: %v", err)
+		return nil, fmt.Errorf("open obs file: %w", err)
 	}
 	defer r.Close()
 	dec, err := NewObsDecoder(r)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// file 2
-	r2, err := os.Open(obsFil2.Path)
+	r2, err := obsFil2.open()
 	if err != nil {
-		return fmt.Errorf("open obs file: %v", err)
+		return nil, fmt.Errorf("open obs file: %w", err)
 	}
 	defer r2.Close()
 	dec2, err := NewObsDecoder(r2)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nSyncEpochs := 0
-	for dec.sync(dec2) {
-		nSyncEpochs++
+	var diffs []string
+	for dec.SyncWith(dec2) {
 		syncEpo := dec.SyncEpoch()
 
 		diff := diffEpo(syncEpo, f.Opts)
 		if diff != "" {
-			fmt.Printf("diff: %s\n", diff)
+			diffs = append(diffs, diff)
 		}
 	}
 	if err := dec.Err(); err != nil {
-		return fmt.Errorf("read epochs error: %v", err)
+		return diffs, fmt.Errorf("read epochs error: %w", err)
 	}
 
-	return nil
+	return diffs, nil
 }
 
 // Stat gathers some observation statistics.
 func (f *ObsFile) Stat() (stat ObsStat, err error) {
-	r, err := os.Open(f.Path)
+	r, err := f.open()
 	if err != nil {
 		return
 	}
@@ -607,7 +1368,11 @@ func (f *ObsFile) Stat() (stat ObsStat, err error) {
 	if err != nil {
 		return
 	}
+	return obsStat(dec)
+}
 
+// obsStat reads the epochs of the decoder and gathers the statistics.
+func obsStat(dec *ObsDecoder) (stat ObsStat, err error) {
 	numOfEpochs := 0
 	intervals := make([]time.Duration, 0, 10)
 	var epo, epoPrev *Epoch
@@ -629,7 +1394,9 @@ func (f *ObsFile) Stat() (stat ObsStat, err error) {
 		return
 	}
 
-	stat.TimeOfLastObs = epoPrev.Time
+	if epoPrev != nil {
+		stat.TimeOfLastObs = epoPrev.Time
+	}
 	stat.NumEpochs = numOfEpochs
 
 	// check sampling rate
@@ -644,8 +1411,14 @@ func (f *ObsFile) Stat() (stat ObsStat, err error) {
 }
 
 // Compress an observation file using Hatanaka first and then gzip.
-// The source file will be removed if the compression finishes without errors.
+// The source file will be removed if the compression finishes without errors, Path is set to the compressed file.
 func (f *ObsFile) Compress() error {
+	return f.CompressWithOptions(CompressOptions{})
+}
+
+// CompressWithOptions compresses an observation file using Hatanaka first and then gzip with the
+// given options. The intermediate Hatanaka file is removed. Path is set to the compressed file.
+func (f *ObsFile) CompressWithOptions(opts CompressOptions) error {
 	if f.Format == "crx" && f.Compression == "gz" {
 		return nil
 	}
@@ -653,22 +1426,47 @@ func (f *ObsFile) Compress() error {
 		return fmt.Errorf("compressed file is not Hatanaka compressed: %s", f.Path)
 	}
 
-	err := f.Rnx2crx()
-	if err != nil {
-		return err
+	src, crxPath := f.Path, f.Path
+	if !f.IsHatanakaCompressed() {
+		var err error
+		crxPath, err = rnx2crx(src, false)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(crxPath)
 	}
 
-	err = archiver.CompressFile(f.Path, f.Path+".gz")
+	dst, err := compressRnx(crxPath, opts, verifyCrxHeader)
 	if err != nil {
 		return err
 	}
-	os.Remove(f.Path)
-	f.Path = f.Path + ".gz"
+	f.Path = dst
+	f.Format = "crx"
 	f.Compression = "gz"
-
+	f.Checksums = nil
+	if !opts.KeepSource {
+		return os.Remove(src)
+	}
 	return nil
 }
 
+// verifyCrxHeader checks that r starts with a compact RINEX header.
+func verifyCrxHeader(r io.Reader) error {
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if len(line) < 61 || !strings.HasPrefix(line[60:], "CRINEX VERS   / TYPE") {
+		return fmt.Errorf("invalid first header line: %q", strings.TrimRight(line, "\r\n"))
+	}
+	if _, err := br.ReadString('\n'); err != nil { // CRINEX PROG / DATE
+		return err
+	}
+	_, err = NewObsDecoder(br)
+	return err
+}
+
 // IsHatanakaCompressed returns true if the obs file is Hatanaka compressed, otherwise false.
 func (f *ObsFile) IsHatanakaCompressed() bool {
 	if f.Format == "crx" {
@@ -682,16 +1480,28 @@ func (f *ObsFile) IsHatanakaCompressed() bool {
 // Rnx2crx returns the filepath of the compressed file.
 // see http://terras.gsi.go.jp/ja/crx2rnx.html
 func (f *ObsFile) Rnx2crx() error {
-	rnxFilePath := f.Path
-
 	if f.IsHatanakaCompressed() {
 		return nil
 	}
 
-	tool, err := exec.LookPath("RNX2CRX")
+	crxFilePath, err := rnx2crx(f.Path, true)
 	if err != nil {
 		return err
 	}
+	f.Path = crxFilePath
+	f.Format = "crx"
+	f.Checksums = nil
+
+	return nil
+}
+
+// rnx2crx Hatanaka-compresses the file rnxFilePath and returns the path of the compressed file.
+// The source file is removed by the tool if del is true.
+func rnx2crx(rnxFilePath string, del bool) (string, error) {
+	tool, err := exec.LookPath("RNX2CRX")
+	if err != nil {
+		return "", err
+	}
 
 	dir, rnxFil := filepath.Split(rnxFilePath)
 
@@ -702,35 +1512,34 @@ func (f *ObsFile) Rnx2crx() error {
 	} else if Rnx3FileNamePattern.MatchString(rnxFil) {
 		crxFil = Rnx3FileNamePattern.ReplaceAllString(rnxFil, "${2}.crx")
 	} else {
-		return fmt.Errorf("file %s with no standard RINEX extension", rnxFil)
+		return "", fmt.Errorf("file %s with no standard RINEX extension", rnxFil)
 	}
 
-	//fmt.Printf("rnxFil: %s - crxFil: %s\n", rnxFil, crxFil)
-
 	if crxFil == "" || rnxFil == crxFil {
-		return fmt.Errorf("Could not build compressed filename for %s", rnxFil)
+		return "", fmt.Errorf("Could not build compressed filename for %s", rnxFil)
 	}
 
 	// Run compression tool
-	cmd := exec.Command(tool, rnxFilePath, "-d", "-f")
+	args := []string{rnxFilePath, "-f"}
+	if del {
+		args = append(args, "-d")
+	}
+	cmd := exec.Command(tool, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	err = cmd.Run()
 	if err != nil {
-		return fmt.Errorf("cmd %s failed: %v: %s", tool, err, stderr.Bytes())
+		return "", fmt.Errorf("cmd %s failed: %v: %s", tool, err, stderr.Bytes())
 	}
 
 	// Return filepath
 	crxFilePath := filepath.Join(dir, crxFil)
 	if _, err := os.Stat(crxFilePath); os.IsNotExist(err) {
-		return fmt.Errorf("compressed file does not exist: %s", crxFilePath)
+		return "", fmt.Errorf("compressed file does not exist: %s", crxFilePath)
 	}
 
-	f.Path = crxFilePath
-	f.Format = "crx"
-
-	return nil
+	return crxFilePath, nil
 }
 
 // Crx2rnx decompresses a Hatanaka-compressed file. Crx2rnx returns the filepath of the decompressed file.
@@ -781,29 +1590,76 @@ func (f *ObsFile) Crx2rnx() error {
 	}
 
 	f.Path = rnxFilePath
+	f.Checksums = nil
 	return nil
 }
 
-// Rnx3Filename returns the filename following the RINEX3 convention.
-// In most cases we must read the read the header. The countrycode must come from an external source.
-// DO NOT USE! Must parse header first!
-func (f *ObsFile) Rnx3Filename() (string, error) {
-	if f.DataFreq == "" || f.FilePeriod == "" {
-		r, err := os.Open(f.Path)
-		if err != nil {
-			return "", err
-		}
-		defer r.Close()
-		dec, err := NewObsDecoder(r)
-		if err != nil {
-			return "", err
+// SetFromContent reads the header and the epochs of the file and sets the start time, the file period,
+// the data frequency and the data type from them. The station name is taken from the MARKER NAME,
+// if it is not yet set. Fields that can not be determined keep their values, e.g. the period of
+// files without epochs and TIME OF LAST OBS. Hatanaka compressed files are not supported.
+func (f *ObsFile) SetFromContent() error {
+	if f.Format == "crx" {
+		return fmt.Errorf("compression not supported: %s", f.Path)
+	}
+	rd, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	dec, err := NewObsDecoder(rd)
+	if err != nil {
+		return err
+	}
+	dec.Lenient = true
+	var times []time.Time
+	for dec.NextEpoch() {
+		if epo := dec.Epoch(); !epo.IsEvent() && !epo.IsCycleSlip() {
+			times = append(times, epo.Time)
 		}
+	}
+	if err := dec.Err(); err != nil {
+		return fmt.Errorf("read epochs: %w", err)
+	}
 
-		if dec.Header.Interval != 0 {
-			f.DataFreq = fmt.Sprintf("%02d%s", int(dec.Header.Interval), "S")
+	hdr := &dec.Header
+	first, last := hdr.TimeOfFirstObs, hdr.TimeOfLastObs
+	if len(times) > 0 {
+		first, last = times[0], times[len(times)-1]
+	}
+	interval := time.Duration(hdr.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = detectInterval(times)
+	}
+	if interval > 0 {
+		f.DataFreq = freqCode(interval)
+	}
+	if !first.IsZero() && !last.IsZero() && interval > 0 {
+		period := nominalPeriod(last.Sub(first) + interval)
+		f.FilePeriod = periodCode(period)
+		f.StartTime = first.Truncate(period)
+	} else if !first.IsZero() {
+		f.StartTime = first.Truncate(time.Minute)
+	}
+	if hdr.SatSystem != 0 {
+		f.DataType = hdr.SatSystem.Abbr() + "O"
+	}
+	if f.FourCharID == "" {
+		if name := strings.ToUpper(hdr.MarkerName); len(name) == 4 || len(name) == 9 {
+			f.SetStationName(name)
 		}
+	}
+	return nil
+}
 
-		f.DataType = fmt.Sprintf("%s%s", dec.Header.SatSystem.Abbr(), "O")
+// Rnx3Filename returns the filename following the RINEX3 convention.
+// The start time, period, data frequency and type are read from the file with SetFromContent, if
+// they are not known from the filename. The country code must come from an external source.
+func (f *ObsFile) Rnx3Filename() (string, error) {
+	if f.DataFreq == "" || f.FilePeriod == "" || f.DataType == "" || f.StartTime.IsZero() {
+		if err := f.SetFromContent(); err != nil {
+			return "", err
+		}
 	}
 
 	// Station Identifier
@@ -891,15 +1747,7 @@ func diffEpo(epochs SyncEpochs, opts Options) string {
 	// }
 
 	for _, obs := range epo1.ObsList {
-		printSys := false
-		for _, useSys := range opts.SatSys {
-			if obs.Prn.Sys.Abbr() == string(useSys) {
-				printSys = true
-				break
-			}
-		}
-
-		if !printSys {
+		if !opts.useSys(obs.Prn.Sys) {
 			continue
 		}
 
@@ -928,8 +1776,9 @@ func getObsByPRN(obslist []SatObs, prn PRN) (SatObs, error) {
 func diffObs(obs1, obs2 SatObs, epoTime time.Time, prn PRN) string {
 	deltaPhase := 0.005
 	checkSNR := false
-	for k, o1 := range obs1.Obss {
-		if o2, ok := obs2.Obss[k]; ok {
+	for i, o1 := range obs1.Obss {
+		k := obs1.Types[i]
+		if o2, ok := obs2.Get(k); ok {
 			val1, val2 := o1.Val, o2.Val
 			if strings.HasPrefix(k, "L") { // phase observations
 				val1 = getDecimal(val1)
@@ -0,0 +1,194 @@
+package rinex
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// editHeader returns a copy of the header for writing a subset of its epochs. The time of the first
+// observation is set by the encoder, the records that depend on the epochs are removed.
+// See newEditEncoder for recomputing them.
+func editHeader(hdr ObsHeader) ObsHeader {
+	obsTypes := make(map[gnss.System][]string, len(hdr.ObsTypes))
+	for sys, types := range hdr.ObsTypes {
+		obsTypes[sys] = append([]string(nil), types...)
+	}
+	hdr.ObsTypes = obsTypes
+	hdr.TimeOfFirstObs, hdr.TimeOfLastObs = time.Time{}, time.Time{}
+	hdr.NSatellites = 0
+	hdr.ObsPerSat = nil
+	return hdr
+}
+
+// newEditEncoder returns the encoder for the edited header hdr of the input header in. The records
+// PRN / # OF OBS and # OF SATELLITES are computed from the data if the input has them.
+func newEditEncoder(w io.Writer, hdr ObsHeader, in ObsHeader) *ObsEncoder {
+	enc := NewObsEncoder(w, hdr)
+	enc.CountObs = len(in.ObsPerSat) > 0
+	return enc
+}
+
+// CropObs writes the header and the epochs of dec within [from, to) to w. A zero time means no limit.
+// The epochs must be in time order. It returns the number of written epochs.
+func CropObs(w io.Writer, dec *ObsDecoder, from, to time.Time) (int, error) {
+	hdr := editHeader(dec.Header)
+	var window []string
+	if !from.IsZero() {
+		window = append(window, "from "+from.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !to.IsZero() {
+		window = append(window, "to "+to.UTC().Format("2006-01-02 15:04:05"))
+	}
+	hdr.addHistory("crop", []string{dec.Name}, strings.Join(window, " "))
+	enc := newEditEncoder(w, hdr, dec.Header)
+	n := 0
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if !to.IsZero() && !epo.Time.Before(to) {
+			break
+		}
+		if epo.Time.Before(from) {
+			continue
+		}
+		if err := enc.Encode(epo); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := dec.Err(); err != nil {
+		return n, fmt.Errorf("read epochs: %w", err)
+	}
+	return n, enc.Flush()
+}
+
+// MergeObs merges the epochs of the decoders in time order and writes them to w. The header is taken
+// from the first decoder, observation types only found in the others are appended. An epoch found in
+// more than one decoder is taken from the first one. It returns the number of written epochs.
+func MergeObs(w io.Writer, decs ...*ObsDecoder) (int, error) {
+	if len(decs) == 0 {
+		return 0, fmt.Errorf("merge: no input")
+	}
+	hdr := editHeader(decs[0].Header)
+	for _, dec := range decs[1:] {
+		for sys, types := range dec.Header.ObsTypes {
+			for _, typ := range types {
+				if indexOf(hdr.ObsTypes[sys], typ) < 0 {
+					hdr.ObsTypes[sys] = append(hdr.ObsTypes[sys], typ)
+				}
+			}
+		}
+		if dec.Header.Interval != hdr.Interval {
+			hdr.Interval = 0
+		}
+	}
+	if len(hdr.ObsTypes) > 1 {
+		hdr.SatSystem = gnss.SysMIXED
+	}
+	names := make([]string, 0, len(decs))
+	for _, dec := range decs {
+		names = append(names, dec.Name)
+	}
+	hdr.addHistory("merge", names)
+
+	enc := newEditEncoder(w, hdr, decs[0].Header)
+	cur := make([]*Epoch, len(decs))
+	next := func(i int) {
+		cur[i] = nil
+		if decs[i].NextEpoch() {
+			cur[i] = decs[i].Epoch()
+		}
+	}
+	for i := range decs {
+		next(i)
+	}
+
+	n := 0
+	for {
+		first := -1
+		for i, epo := range cur {
+			if epo != nil && (first < 0 || epo.Time.Before(cur[first].Time)) {
+				first = i
+			}
+		}
+		if first < 0 {
+			break
+		}
+		epo := cur[first]
+		if err := enc.Encode(epo); err != nil {
+			return n, err
+		}
+		n++
+		t, flag := epo.Time, epo.Flag
+		for i, e := range cur {
+			if e != nil && e.Time.Equal(t) && e.Flag == flag {
+				next(i)
+			}
+		}
+	}
+	for i, dec := range decs {
+		if err := dec.Err(); err != nil {
+			return n, fmt.Errorf("read epochs of input %d: %w", i+1, err)
+		}
+	}
+	return n, enc.Flush()
+}
+
+// SplitObs splits the epochs of dec into pieces of the given period, e.g. hourly files. The pieces are aligned
+// to multiples of the period, i.e. an hourly piece starts at the full hour. create is called with the start time
+// of each piece and returns its writer, which is closed when the piece is complete.
+// It returns the number of pieces.
+func SplitObs(dec *ObsDecoder, period time.Duration, create func(start time.Time) (io.WriteCloser, error)) (int, error) {
+	if period <= 0 {
+		return 0, fmt.Errorf("split: invalid period %s", period)
+	}
+	var (
+		wc    io.WriteCloser
+		enc   *ObsEncoder
+		start time.Time
+		n     int
+	)
+	closePiece := func() error {
+		if wc == nil {
+			return nil
+		}
+		err := enc.Flush()
+		if err2 := wc.Close(); err == nil {
+			err = err2
+		}
+		wc = nil
+		return err
+	}
+
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if s := epo.Time.Truncate(period); wc == nil || !s.Equal(start) {
+			if err := closePiece(); err != nil {
+				return n, err
+			}
+			start = s
+			var err error
+			if wc, err = create(start); err != nil {
+				return n, err
+			}
+			hdr := editHeader(dec.Header)
+			hdr.addHistory("split", []string{dec.Name}, "period "+periodCode(period))
+			enc = newEditEncoder(wc, hdr, dec.Header)
+			n++
+		}
+		if err := enc.Encode(epo); err != nil {
+			closePiece()
+			return n, err
+		}
+	}
+	if err := closePiece(); err != nil {
+		return n, err
+	}
+	if err := dec.Err(); err != nil {
+		return n, fmt.Errorf("read epochs: %w", err)
+	}
+	return n, nil
+}
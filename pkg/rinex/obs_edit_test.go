@@ -0,0 +1,111 @@
+package rinex
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+const reykFile = "testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx"
+
+func TestCropObs(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	all := epochStrings(t, data)
+
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	from := time.Date(2019, 9, 27, 10, 15, 0, 0, time.UTC)
+	to := time.Date(2019, 9, 27, 10, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	n, err := CropObs(&buf, dec, from, to)
+	assert.NoError(err)
+	assert.Equal(30, n)
+
+	dec, err = NewObsDecoder(bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	assert.Equal(from, dec.Header.TimeOfFirstObs)
+	assert.Equal(all[30:60], epochStrings(t, buf.Bytes()))
+}
+
+func TestMergeObs(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	all := epochStrings(t, data)
+
+	// split at 10:20 with an overlap of one epoch
+	var first, second bytes.Buffer
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	_, err = CropObs(&first, dec, time.Time{}, time.Date(2019, 9, 27, 10, 20, 30, 0, time.UTC))
+	assert.NoError(err)
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	_, err = CropObs(&second, dec, time.Date(2019, 9, 27, 10, 20, 0, 0, time.UTC), time.Time{})
+	assert.NoError(err)
+
+	dec1, err := NewObsDecoder(&second)
+	assert.NoError(err)
+	dec2, err := NewObsDecoder(&first)
+	assert.NoError(err)
+	var buf bytes.Buffer
+	n, err := MergeObs(&buf, dec1, dec2)
+	assert.NoError(err)
+	assert.Equal(len(all), n)
+	assert.Equal(all, epochStrings(t, buf.Bytes()))
+
+	dec, err = NewObsDecoder(&buf)
+	assert.NoError(err)
+	assert.Equal(gnss.SysMIXED, dec.Header.SatSystem)
+	assert.Equal(time.Date(2019, 9, 27, 10, 0, 0, 0, time.UTC), dec.Header.TimeOfFirstObs)
+}
+
+type bufCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestSplitObs(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	all := epochStrings(t, data)
+
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	var (
+		starts []time.Time
+		pieces []*bufCloser
+	)
+	n, err := SplitObs(dec, 15*time.Minute, func(start time.Time) (io.WriteCloser, error) {
+		starts = append(starts, start)
+		b := &bufCloser{}
+		pieces = append(pieces, b)
+		return b, nil
+	})
+	assert.NoError(err)
+	assert.Equal(4, n)
+	assert.Equal(time.Date(2019, 9, 27, 10, 45, 0, 0, time.UTC), starts[3])
+
+	var got []string
+	for _, b := range pieces {
+		assert.True(b.closed)
+		got = append(got, epochStrings(t, b.Bytes())...)
+	}
+	assert.Equal(all, got)
+
+	_, err = SplitObs(dec, 0, nil)
+	assert.Error(err)
+}
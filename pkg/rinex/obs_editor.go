@@ -0,0 +1,162 @@
+package rinex
+
+import (
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// clockJumpMeters is a receiver clock jump of one millisecond in meters.
+const clockJumpMeters = gnss.SpeedOfLight * 1e-3
+
+// ClockJump is a millisecond jump of the receiver clock.
+type ClockJump struct {
+	Time   time.Time // first epoch after the jump
+	Millis int       // size of the jump in milliseconds
+}
+
+// ObsEditor is an editing stage between the decoder and the processing of the epochs. It detects the
+// millisecond jumps of the receiver clock and applies phase shift corrections. The editor is used like
+// the decoder:
+//
+//	ed := rinex.NewObsEditor(dec)
+//	ed.RepairClockJumps = true
+//	for ed.NextEpoch() {
+//		epo := ed.Epoch()
+//		...
+//	}
+//
+// The clock jumps are detected by the code minus phase differences of the first frequency band, which jump
+// by the same multiple of 1 ms for all satellites. Most receivers keep the phases continuous and steer the
+// codes and the epoch times, so the jumps are repaired by applying them to the phases.
+type ObsEditor struct {
+	// Header is the header of the decoder with the applied phase shifts added.
+	Header ObsHeader
+
+	// RepairClockJumps applies the detected clock jumps to the phase observations.
+	RepairClockJumps bool
+
+	// PhaseShifts are applied to the phase observations. Set them before the first call to NextEpoch.
+	PhaseShifts []PhaseShift
+
+	// ClockJumps are the detected clock jumps.
+	ClockJumps []ClockJump
+
+	dec     *ObsDecoder
+	started bool
+	prev    map[PRN]float64 // the code minus phase in meters of the previous epoch
+	jump    int             // the sum of the jumps in milliseconds
+}
+
+// NewObsEditor returns an editor for the epochs of the decoder.
+func NewObsEditor(dec *ObsDecoder) *ObsEditor {
+	return &ObsEditor{Header: dec.Header, dec: dec, prev: make(map[PRN]float64)}
+}
+
+// NextEpoch reads and edits the next epoch. It returns false at the end of the input or on an error.
+func (ed *ObsEditor) NextEpoch() bool {
+	if !ed.started {
+		ed.started = true
+		ed.Header.PhaseShifts = append(ed.Header.PhaseShifts[:len(ed.Header.PhaseShifts):len(ed.Header.PhaseShifts)],
+			ed.PhaseShifts...)
+	}
+	if !ed.dec.NextEpoch() {
+		return false
+	}
+	epo := ed.dec.Epoch()
+	if epo.IsEvent() {
+		return true
+	}
+	ed.detectClockJump(epo)
+	for i := range epo.ObsList {
+		satObs := &epo.ObsList[i]
+		for j, typ := range satObs.Types {
+			if j >= len(satObs.Obss) || typ[0] != 'L' || satObs.Obss[j].Val == 0 {
+				continue
+			}
+			obs := &satObs.Obss[j]
+			for _, shift := range ed.PhaseShifts {
+				if shift.Sys == satObs.Prn.Sys && shift.ObsType == typ && (len(shift.Sats) == 0 || containsPRN(shift.Sats, satObs.Prn)) {
+					obs.Val += shift.Correction
+				}
+			}
+			if ed.RepairClockJumps && ed.jump != 0 {
+				if wl := ed.wavelength(satObs.Prn, typ); wl > 0 {
+					obs.Val += float64(ed.jump) * clockJumpMeters / wl
+				}
+			}
+		}
+	}
+	return true
+}
+
+// detectClockJump compares the code minus phase differences of the satellites with the previous epoch.
+func (ed *ObsEditor) detectClockJump(epo *Epoch) {
+	cmp := make(map[PRN]float64, len(epo.ObsList))
+	var jumps []int // per satellite
+	for _, satObs := range epo.ObsList {
+		band, _, ok := DefaultBands(satObs.Prn.Sys)
+		if !ok {
+			continue
+		}
+		code, phase := satObs.pairObs(band)
+		if code == "" || phase == "" {
+			continue
+		}
+		wl := ed.wavelength(satObs.Prn, phase)
+		if wl == 0 {
+			continue
+		}
+		c, _ := satObs.Get(code)
+		l, _ := satObs.Get(phase)
+		cmp[satObs.Prn] = c.Val - l.Val*wl
+		prev, ok := ed.prev[satObs.Prn]
+		if !ok {
+			continue
+		}
+		// the differences are continuous apart from the jump, cycle slips and the ionosphere
+		delta := cmp[satObs.Prn] - prev
+		k := int(math.Round(delta / clockJumpMeters))
+		if math.Abs(delta-float64(k)*clockJumpMeters) > 0.01*clockJumpMeters {
+			k = 0
+		}
+		jumps = append(jumps, k)
+	}
+	ed.prev = cmp
+	if len(jumps) < 2 || jumps[0] == 0 {
+		return
+	}
+	for _, k := range jumps[1:] {
+		if k != jumps[0] {
+			return
+		}
+	}
+	jump := jumps[0]
+	ed.ClockJumps = append(ed.ClockJumps, ClockJump{Time: epo.Time, Millis: jump})
+	ed.jump += jump
+}
+
+// wavelength returns the wavelength in meters of the phase observation type of the satellite, or 0 if unknown.
+func (ed *ObsEditor) wavelength(prn PRN, typ string) float64 {
+	return ed.Header.GloSlots.Wavelength(prn, typ)
+}
+
+// Epoch returns the most recent epoch generated by a call to NextEpoch.
+func (ed *ObsEditor) Epoch() *Epoch {
+	return ed.dec.Epoch()
+}
+
+// Err returns the first non-EOF error of the decoder.
+func (ed *ObsEditor) Err() error {
+	return ed.dec.Err()
+}
+
+func containsPRN(prns []PRN, prn PRN) bool {
+	for _, p := range prns {
+		if p == prn {
+			return true
+		}
+	}
+	return false
+}
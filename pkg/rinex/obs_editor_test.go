@@ -0,0 +1,80 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsEditor(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+
+	// the receiver clock jumps by 1 ms at 10:30, the codes and the epoch times are steered
+	jumpTime := time.Date(2019, 9, 27, 10, 30, 0, 0, time.UTC)
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, editHeader(dec.Header))
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if !epo.Time.Before(jumpTime) {
+			for _, satObs := range epo.ObsList {
+				for i, typ := range satObs.Types {
+					if typ[0] == 'C' && satObs.Obss[i].Val != 0 {
+						satObs.Obss[i].Val += clockJumpMeters
+					}
+				}
+			}
+		}
+		assert.NoError(enc.Encode(epo))
+	}
+	assert.NoError(enc.Flush())
+
+	dec, err = NewObsDecoder(&buf)
+	assert.NoError(err)
+	g08 := PRN{Sys: gnss.SysGPS, Num: 8}
+	shift := PhaseShift{Sys: gnss.SysGPS, ObsType: "L2W", Correction: 0.25, Sats: []PRN{g08}}
+	ed := NewObsEditor(dec)
+	ed.RepairClockJumps = true
+	ed.PhaseShifts = []PhaseShift{shift}
+	orig, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+
+	prev := make(map[PRN]float64)
+	for ed.NextEpoch() {
+		assert.True(orig.NextEpoch())
+		epo, origEpo := ed.Epoch(), orig.Epoch()
+		for i, satObs := range epo.ObsList {
+			origObs := origEpo.ObsList[i]
+			if l2, ok := satObs.Get("L2W"); ok && l2.Val != 0 && !epo.Time.Before(jumpTime) {
+				want, _ := origObs.Get("L2W")
+				want.Val += clockJumpMeters / ObsCode("L2W").Wavelength(gnss.SysGPS)
+				if satObs.Prn == g08 {
+					want.Val += 0.25
+				}
+				assert.InDelta(want.Val, l2.Val, 1e-3)
+			}
+
+			c1, _ := satObs.Get("C1C")
+			l1, _ := satObs.Get("L1C")
+			if satObs.Prn.Sys != gnss.SysGPS || c1.Val == 0 || l1.Val == 0 {
+				continue
+			}
+			cmp := c1.Val - l1.Val*ObsCode("L1C").Wavelength(gnss.SysGPS)
+			if p, ok := prev[satObs.Prn]; ok {
+				assert.True(math.Abs(cmp-p) < 100, "code minus phase is continuous")
+			}
+			prev[satObs.Prn] = cmp
+		}
+	}
+	assert.NoError(ed.Err())
+	assert.Equal([]ClockJump{{Time: jumpTime, Millis: 1}}, ed.ClockJumps)
+	assert.Equal(append(dec.Header.PhaseShifts, shift), ed.Header.PhaseShifts)
+}
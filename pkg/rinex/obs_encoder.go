@@ -0,0 +1,475 @@
+package rinex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// gloCodPhsBisTypes are the observation types of the GLONASS COD/PHS/BIS record in the order of the spec.
+var gloCodPhsBisTypes = []string{"C1C", "C1P", "C2C", "C2P"}
+
+// Marshal returns the RINEX encoding of the header.
+func (hdr *ObsHeader) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := hdr.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write writes the header in RINEX format to w. The records are written in the order
+// recommended by the RINEX 3.04 specification. Optional records are only written if they are set.
+// The records DOI, LICENSE OF USE and STATION INFORMATION are only written for version 3.05 and later.
+// For RINEX 2 only the records defined by RINEX 2.11 are written.
+func (hdr *ObsHeader) Write(w io.Writer) error {
+	if hdr.RINEXVersion < 2 {
+		return fmt.Errorf("write header: %w: %.2f", ErrUnsupportedVersion, hdr.RINEXVersion)
+	}
+	if hdr.RINEXVersion < 3 {
+		return hdr.writeV2(w)
+	}
+
+	bw := bufio.NewWriter(w)
+	hw := &headerWriter{w: bw}
+
+	hw.writeLine(fmt.Sprintf("%9.2f%11s%-20s%-20s", hdr.RINEXVersion, "", "OBSERVATION DATA", hdr.SatSystem.Abbr()), "RINEX VERSION / TYPE")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.Pgm, hdr.RunBy, hdr.Date), "PGM / RUN BY / DATE")
+	for _, c := range hdr.Comments {
+		hw.writeLine(c, "COMMENT")
+	}
+	hw.writeLine(hdr.MarkerName, "MARKER NAME")
+	if hdr.MarkerNumber != "" {
+		hw.writeLine(hdr.MarkerNumber, "MARKER NUMBER")
+	}
+	if hdr.MarkerType != "" {
+		hw.writeLine(hdr.MarkerType, "MARKER TYPE")
+	}
+	hw.writeLine(fmt.Sprintf("%-20s%-40s", hdr.Observer, hdr.Agency), "OBSERVER / AGENCY")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.ReceiverNumber, hdr.ReceiverType, hdr.ReceiverVersion), "REC # / TYPE / VERS")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s", hdr.AntennaNumber, hdr.AntennaType), "ANT # / TYPE")
+	hw.writeLine(formatCoord(hdr.Position), "APPROX POSITION XYZ")
+	hw.writeLine(fmt.Sprintf("%14.4f%14.4f%14.4f", hdr.AntennaDelta.Up, hdr.AntennaDelta.E, hdr.AntennaDelta.N), "ANTENNA: DELTA H/E/N")
+	if hdr.AntennaDeltaXYZ != (Coord{}) {
+		hw.writeLine(formatCoord(hdr.AntennaDeltaXYZ), "ANTENNA: DELTA X/Y/Z")
+	}
+	if hdr.AntennaBSight != (Coord{}) {
+		hw.writeLine(formatCoord(hdr.AntennaBSight), "ANTENNA: B.SIGHT XYZ")
+	}
+	if hdr.CenterOfMass != (Coord{}) {
+		hw.writeLine(formatCoord(hdr.CenterOfMass), "CENTER OF MASS: XYZ")
+	}
+
+	for _, sys := range sortedSystems(hdr.ObsTypes) {
+		types := hdr.ObsTypes[sys]
+		first := fmt.Sprintf("%-1s  %3d", sys.Abbr(), len(types))
+		hw.writeList(first, strings.Repeat(" ", 6), types, 13, "SYS / # / OBS TYPES")
+	}
+
+	if hdr.SignalStrengthUnit != "" {
+		hw.writeLine(hdr.SignalStrengthUnit, "SIGNAL STRENGTH UNIT")
+	}
+	if hdr.Interval != 0 {
+		hw.writeLine(fmt.Sprintf("%10.3f", hdr.Interval), "INTERVAL")
+	}
+	timeSys := hdr.TimeSystem
+	if timeSys == "" {
+		timeSys = "GPS"
+	}
+	hw.writeLine(formatHeaderTime(hdr.TimeOfFirstObs, timeSys), "TIME OF FIRST OBS")
+	if !hdr.TimeOfLastObs.IsZero() {
+		hw.writeLine(formatHeaderTime(hdr.TimeOfLastObs, timeSys), "TIME OF LAST OBS")
+	}
+
+	if hdr.RcvClockOffsAppl {
+		hw.writeLine(fmt.Sprintf("%6d", 1), "RCV CLOCK OFFS APPL")
+	}
+	for _, corr := range hdr.DCBSApplied {
+		hw.writeLine(fmt.Sprintf("%-1s %-17s %s", corr.Sys.Abbr(), corr.Program, corr.Source), "SYS / DCBS APPLIED")
+	}
+	for _, corr := range hdr.PCVSApplied {
+		hw.writeLine(fmt.Sprintf("%-1s %-17s %s", corr.Sys.Abbr(), corr.Program, corr.Source), "SYS / PCVS APPLIED")
+	}
+
+	for _, sf := range hdr.ScaleFactors {
+		first := fmt.Sprintf("%-1s %4d  %2d", sf.Sys.Abbr(), sf.Factor, len(sf.ObsTypes))
+		if len(sf.ObsTypes) == 0 {
+			first = fmt.Sprintf("%-1s %4d", sf.Sys.Abbr(), sf.Factor)
+		}
+		hw.writeList(first, strings.Repeat(" ", 10), sf.ObsTypes, 12, "SYS / SCALE FACTOR")
+	}
+
+	for _, ps := range hdr.PhaseShifts {
+		first := fmt.Sprintf("%-1s %-3s %8.5f", ps.Sys.Abbr(), ps.ObsType, ps.Correction)
+		if ps.ObsType == "" { // phase shifts unknown, e.g. for converted RINEX 2 data
+			first = ps.Sys.Abbr()
+		}
+		if len(ps.Sats) > 0 {
+			first += fmt.Sprintf("  %02d", len(ps.Sats))
+		}
+		sats := make([]string, 0, len(ps.Sats))
+		for _, prn := range ps.Sats {
+			sats = append(sats, prn.String())
+		}
+		hw.writeList(first, strings.Repeat(" ", 18), sats, 10, "SYS / PHASE SHIFT")
+	}
+
+	if len(hdr.GloSlots) > 0 {
+		prns := make([]PRN, 0, len(hdr.GloSlots))
+		for prn := range hdr.GloSlots {
+			prns = append(prns, prn)
+		}
+		sortPRNs(prns)
+		slots := make([]string, 0, len(prns))
+		for _, prn := range prns {
+			slots = append(slots, fmt.Sprintf("%s %2d", prn, hdr.GloSlots[prn]))
+		}
+		hw.writeList(fmt.Sprintf("%3d", len(prns)), strings.Repeat(" ", 3), slots, 8, "GLONASS SLOT / FRQ #")
+	}
+
+	if hdr.GloCodPhsBis != nil { // empty if the biases are unknown
+		var sb strings.Builder
+		for _, typ := range gloCodPhsBisTypes {
+			if bias, ok := hdr.GloCodPhsBis[typ]; ok {
+				fmt.Fprintf(&sb, " %-3s %8.3f", typ, bias)
+			} else {
+				fmt.Fprintf(&sb, " %-3s %8s", typ, "")
+			}
+		}
+		hw.writeLine(sb.String(), "GLONASS COD/PHS/BIS")
+	}
+
+	if hdr.LeapSeconds != 0 {
+		ls := fmt.Sprintf("%6d", hdr.LeapSeconds)
+		if hdr.LeapSecondsFuture != 0 || hdr.LeapSecondsWeek != 0 || hdr.LeapSecondsDay != 0 || hdr.LeapSecondsSys != "" {
+			ls += fmt.Sprintf("%6d%6d%6d%-3s", hdr.LeapSecondsFuture, hdr.LeapSecondsWeek, hdr.LeapSecondsDay, hdr.LeapSecondsSys)
+		}
+		hw.writeLine(ls, "LEAP SECONDS")
+	}
+
+	if hdr.NSatellites != 0 {
+		hw.writeLine(fmt.Sprintf("%6d", hdr.NSatellites), "# OF SATELLITES")
+	}
+
+	hw.writeObsPerSat(hdr.ObsPerSat)
+
+	if hdr.RINEXVersion >= 3.05 {
+		if hdr.DOI != "" {
+			hw.writeLine(hdr.DOI, "DOI")
+		}
+		for _, l := range hdr.Licenses {
+			hw.writeLine(l, "LICENSE OF USE")
+		}
+		for _, s := range hdr.StationInfos {
+			hw.writeLine(s, "STATION INFORMATION")
+		}
+	}
+
+	hw.writeLine("", "END OF HEADER")
+	if hw.err != nil {
+		return hw.err
+	}
+	return bw.Flush()
+}
+
+// ObsEncoder writes RINEX observation files, RINEX 2 if the header's version is below 3.
+type ObsEncoder struct {
+	// Header is written with the first epoch or by Flush, so it can be changed before.
+	// If TimeOfFirstObs is zero, it is set to the time of the first epoch.
+	Header ObsHeader
+
+	// CountObs writes the records PRN / # OF OBS and # OF SATELLITES computed from the encoded epochs,
+	// and TIME OF LAST OBS if not set. The epochs are buffered in a temporary file until Flush writes
+	// the header, so Flush must only be called at the end. Set it before the first epoch.
+	CountObs bool
+
+	w          *bufio.Writer
+	hdrWritten bool
+	line, num  []byte
+	v2Types    []string // the observation types of all systems for RINEX 2
+
+	// CountObs mode
+	out       *bufio.Writer // the output, w writes to tmp
+	tmp       *os.File
+	obsPerSat map[PRN][]int
+	lastTime  time.Time
+}
+
+// NewObsEncoder returns an encoder that writes to w.
+func NewObsEncoder(w io.Writer, hdr ObsHeader) *ObsEncoder {
+	return &ObsEncoder{Header: hdr, w: bufio.NewWriter(w)}
+}
+
+// Encode writes the epoch. The observations are written in the order of the header's observation types,
+// observations of other types are dropped. Satellites of systems without observation types are skipped.
+// The epoch's NumSat is ignored.
+func (enc *ObsEncoder) Encode(epo *Epoch) error {
+	if !enc.hdrWritten && enc.tmp == nil {
+		if enc.Header.TimeOfFirstObs.IsZero() {
+			enc.Header.TimeOfFirstObs = epo.Time
+		}
+		if enc.CountObs {
+			tmp, err := ioutil.TempFile("", "rnxenc-")
+			if err != nil {
+				return err
+			}
+			enc.tmp, enc.out, enc.w = tmp, enc.w, bufio.NewWriter(tmp)
+			enc.obsPerSat = make(map[PRN][]int)
+		} else if err := enc.writeHeader(); err != nil {
+			return err
+		}
+	}
+	if enc.tmp != nil && !epo.IsEvent() {
+		enc.lastTime = epo.Time
+	}
+	if enc.Header.RINEXVersion < 3 {
+		return enc.encodeV2(epo)
+	}
+
+	t := epo.Time
+	sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	epoLine := fmt.Sprintf("> %4d %02d %02d %02d %02d%11.7f  %d", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), sec, epo.Flag)
+	if epo.IsEvent() {
+		var records []string
+		if epo.Event != nil {
+			records = epo.Event.Records
+		}
+		fmt.Fprintf(enc.w, "%s%3d\n", epoLine, len(records))
+		for _, rec := range records {
+			enc.w.WriteString(rec)
+			enc.w.WriteByte('\n')
+		}
+		return nil
+	}
+
+	sats := make([]*SatObs, 0, len(epo.ObsList))
+	for i := range epo.ObsList {
+		if len(enc.Header.ObsTypes[epo.ObsList[i].Prn.Sys]) > 0 {
+			sats = append(sats, &epo.ObsList[i])
+		}
+	}
+	fmt.Fprintf(enc.w, "%s%3d", epoLine, len(sats))
+	if epo.ClockOffset != 0 {
+		fmt.Fprintf(enc.w, "%6s%15.12f", "", epo.ClockOffset)
+	}
+	enc.w.WriteByte('\n')
+
+	for _, satObs := range sats {
+		line := append(enc.line[:0], satObs.Prn.String()...)
+		types := enc.Header.ObsTypes[satObs.Prn.Sys]
+		var counts []int
+		if enc.obsPerSat != nil {
+			if counts = enc.obsPerSat[satObs.Prn]; counts == nil {
+				counts = make([]int, len(types))
+				enc.obsPerSat[satObs.Prn] = counts
+			}
+		}
+		for i, typ := range types {
+			obs, _ := satObs.Get(typ)
+			if counts != nil && obs.Val != 0 {
+				counts[i]++
+			}
+			line = enc.appendObs(line, obs)
+		}
+		line = bytes.TrimRight(line, " ")
+		enc.w.Write(line)
+		enc.w.WriteByte('\n')
+		enc.line = line
+	}
+	return nil
+}
+
+// Flush writes the header, if no epoch was encoded yet, and any buffered data to the underlying writer.
+func (enc *ObsEncoder) Flush() error {
+	if enc.tmp != nil {
+		return enc.flushCounted()
+	}
+	if !enc.hdrWritten {
+		if err := enc.writeHeader(); err != nil {
+			return err
+		}
+	}
+	return enc.w.Flush()
+}
+
+// flushCounted writes the header with the observation counts and the buffered epochs.
+func (enc *ObsEncoder) flushCounted() error {
+	tmp := enc.tmp
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	enc.tmp = nil
+	if err := enc.w.Flush(); err != nil {
+		return err
+	}
+	enc.w = enc.out
+
+	// satellites without observations, e.g. with only blank fields, are not counted
+	for prn, counts := range enc.obsPerSat {
+		n := 0
+		for _, c := range counts {
+			n += c
+		}
+		if n == 0 {
+			delete(enc.obsPerSat, prn)
+		}
+	}
+	enc.Header.ObsPerSat = enc.obsPerSat
+	enc.Header.NSatellites = len(enc.obsPerSat)
+	if enc.Header.TimeOfLastObs.IsZero() {
+		enc.Header.TimeOfLastObs = enc.lastTime
+	}
+	if err := enc.writeHeader(); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(enc.w, tmp); err != nil {
+		return err
+	}
+	return enc.w.Flush()
+}
+
+func (enc *ObsEncoder) writeHeader() error {
+	enc.hdrWritten = true
+	return enc.Header.Write(enc.w)
+}
+
+// appendObs appends the observation in the format F14.3,I1,I1, blank if zero.
+func (enc *ObsEncoder) appendObs(line []byte, obs Obs) []byte {
+	if obs == (Obs{}) {
+		return append(line, "                "...)
+	}
+	num := strconv.AppendFloat(enc.num[:0], obs.Val, 'f', 3, 64)
+	for i := len(num); i < 14; i++ {
+		line = append(line, ' ')
+	}
+	line = append(line, num...)
+	enc.num = num
+	line = appendFlag(line, obs.LLI)
+	return appendFlag(line, obs.SNR)
+}
+
+// appendFlag appends a LLI or SNR flag, blank if zero.
+func appendFlag(b []byte, flag int8) []byte {
+	if flag <= 0 || flag > 9 {
+		return append(b, ' ')
+	}
+	return append(b, byte('0'+flag))
+}
+
+// headerWriter writes RINEX header lines and records the first error.
+type headerWriter struct {
+	w        *bufio.Writer
+	valWidth int // width of the value columns, 60 if not set
+	err      error
+}
+
+// writeLine writes a header line consisting of the value in columns 1-60 and the label in columns 61-80.
+func (hw *headerWriter) writeLine(val, label string) {
+	if hw.err != nil {
+		return
+	}
+	width := hw.valWidth
+	if width == 0 {
+		width = 60
+	}
+	if len(val) > width {
+		val = val[:width]
+	}
+	_, hw.err = fmt.Fprintf(hw.w, "%-*s%-20s\n", width, val, label)
+}
+
+// writeList writes a header record with a list of items, each preceded by a blank. If the items do
+// not fit into one line, continuation lines are written starting with the given indent.
+func (hw *headerWriter) writeList(first, indent string, items []string, perLine int, label string) {
+	for i := 0; i < len(items) || i == 0; i += perLine {
+		var sb strings.Builder
+		if i == 0 {
+			sb.WriteString(first)
+		} else {
+			sb.WriteString(indent)
+		}
+		for j := i; j < i+perLine && j < len(items); j++ {
+			sb.WriteString(" ")
+			sb.WriteString(items[j])
+		}
+		hw.writeLine(sb.String(), label)
+	}
+}
+
+// writeFixedList is like writeList but the items are written without separating blanks.
+func (hw *headerWriter) writeFixedList(first, indent string, items []string, perLine int, label string) {
+	for i := 0; i < len(items) || i == 0; i += perLine {
+		var sb strings.Builder
+		if i == 0 {
+			sb.WriteString(first)
+		} else {
+			sb.WriteString(indent)
+		}
+		for j := i; j < i+perLine && j < len(items); j++ {
+			sb.WriteString(items[j])
+		}
+		hw.writeLine(sb.String(), label)
+	}
+}
+
+// writeObsPerSat writes the PRN / # OF OBS records, sorted by satellite.
+func (hw *headerWriter) writeObsPerSat(obsPerSat map[PRN][]int) {
+	prns := make([]PRN, 0, len(obsPerSat))
+	for prn := range obsPerSat {
+		prns = append(prns, prn)
+	}
+	sortPRNs(prns)
+	for _, prn := range prns {
+		nums := make([]string, 0, len(obsPerSat[prn]))
+		for _, n := range obsPerSat[prn] {
+			if n == 0 {
+				nums = append(nums, strings.Repeat(" ", 6))
+			} else {
+				nums = append(nums, fmt.Sprintf("%6d", n))
+			}
+		}
+		hw.writeFixedList(fmt.Sprintf("   %s", prn), strings.Repeat(" ", 6), nums, 9, "PRN / # OF OBS")
+	}
+}
+
+// formatCoord formats a XYZ coordinate in the format 3F14.4.
+func formatCoord(c Coord) string {
+	return fmt.Sprintf("%14.4f%14.4f%14.4f", c.X, c.Y, c.Z)
+}
+
+// formatHeaderTime formats t as used in the TIME OF FIRST/LAST OBS records.
+func formatHeaderTime(t time.Time, timeSys string) string {
+	sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	return fmt.Sprintf("%6d%6d%6d%6d%6d%13.7f%5s%-3s", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), sec, "", timeSys)
+}
+
+// sortedSystems returns the systems of the obs types map in the order G,R,E,J,C,I,S.
+func sortedSystems(obsTypes map[gnss.System][]string) []gnss.System {
+	syss := make([]gnss.System, 0, len(obsTypes))
+	for sys := range obsTypes {
+		syss = append(syss, sys)
+	}
+	sort.Slice(syss, func(i, j int) bool { return syss[i] < syss[j] })
+	return syss
+}
+
+// sortPRNs sorts the satellites by system and number.
+func sortPRNs(prns []PRN) {
+	sort.Slice(prns, func(i, j int) bool {
+		if prns[i].Sys != prns[j].Sys {
+			return prns[i].Sys < prns[j].Sys
+		}
+		return prns[i].Num < prns[j].Num
+	})
+}
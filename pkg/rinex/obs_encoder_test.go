@@ -0,0 +1,159 @@
+package rinex
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsHeader_Write(t *testing.T) {
+	assert := assert.New(t)
+	for _, filepath := range []string{
+		"testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx",
+		"testdata/white/BRUX00BEL_R_20183101900_01H_30S_MO.rnx",
+	} {
+		r, err := os.Open(filepath)
+		assert.NoError(err)
+		defer r.Close()
+
+		dec, err := NewObsDecoder(r)
+		assert.NoError(err)
+		hdr := dec.Header
+
+		var buf bytes.Buffer
+		err = hdr.Write(&buf)
+		assert.NoError(err)
+		for _, line := range bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n")) {
+			assert.Len(line, 80, "line length: %q", line)
+		}
+
+		// Round trip
+		dec2, err := NewObsDecoder(&buf)
+		assert.NoError(err)
+		hdr2 := dec2.Header
+		hdr.labels, hdr2.labels = nil, nil
+		assert.Equal(hdr, hdr2, filepath)
+	}
+}
+
+func TestObsHeader_WriteRecords(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	assert.NoError(err)
+	defer r.Close()
+
+	dec, err := NewObsDecoder(r)
+	assert.NoError(err)
+	hdr := dec.Header
+
+	assert.Len(hdr.PhaseShifts, 3)
+	assert.Equal(PhaseShift{Sys: gnss.SysGPS, ObsType: "L2S", Correction: -0.25}, hdr.PhaseShifts[0])
+	assert.Len(hdr.GloSlots, 24)
+	assert.Equal(-4, hdr.GloSlots[PRN{Sys: gnss.SysGLO, Num: 2}])
+	assert.Equal(-71.940, hdr.GloCodPhsBis["C1P"])
+	assert.Equal(18, hdr.LeapSeconds)
+	assert.Equal(1929, hdr.LeapSecondsWeek)
+	assert.Len(hdr.ObsPerSat, 49)
+	assert.Equal([]int{120, 0, 120, 0, 120, 0, 120, 0}, hdr.ObsPerSat[PRN{Sys: gnss.SysBDS, Num: 21}])
+
+	data, err := hdr.Marshal()
+	assert.NoError(err)
+	assert.Contains(string(data), "G L2S -0.25000                                              SYS / PHASE SHIFT   \n")
+	assert.Contains(string(data), " 24 R01  1 R02 -4 R03  5 R04  6 R05  1 R06 -4 R07  5 R08  6 GLONASS SLOT / FRQ #\n")
+	assert.Contains(string(data), " C1C  -71.940 C1P  -71.940 C2C  -71.940 C2P  -71.940        GLONASS COD/PHS/BIS \n")
+	assert.Contains(string(data), "   C21   120         120         120         120            PRN / # OF OBS      \n")
+	assert.NotContains(string(data), "DOI")
+
+	hdr.RINEXVersion = 3.05
+	hdr.DOI = "https://doi.org/10.5880/GFZ.1.1.2020.001"
+	hdr.Licenses = []string{"CC BY 4.0"}
+	data, err = hdr.Marshal()
+	assert.NoError(err)
+	assert.Contains(string(data), "https://doi.org/10.5880/GFZ.1.1.2020.001                    DOI                 \n")
+	assert.Contains(string(data), "CC BY 4.0                                                   LICENSE OF USE      \n")
+}
+
+func TestObsEncoder_Encode(t *testing.T) {
+	assert := assert.New(t)
+	for _, filepath := range []string{
+		"testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx",
+		"testdata/white/BRUX00BEL_R_20183101900_01H_30S_MO.rnx",
+	} {
+		data, err := ioutil.ReadFile(filepath)
+		assert.NoError(err)
+		want := epochStrings(t, data)
+
+		dec, err := NewObsDecoder(bytes.NewReader(data))
+		assert.NoError(err)
+		var buf bytes.Buffer
+		enc := NewObsEncoder(&buf, dec.Header)
+		for dec.NextEpoch() {
+			assert.NoError(enc.Encode(dec.Epoch()))
+		}
+		assert.NoError(dec.Err())
+		assert.NoError(enc.Flush())
+
+		// Round trip
+		assert.Equal(want, epochStrings(t, buf.Bytes()), filepath)
+	}
+}
+
+func TestObsEncoder_CountObs(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	want := dec.Header
+
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, editHeader(dec.Header))
+	enc.CountObs = true
+	for dec.NextEpoch() {
+		assert.NoError(enc.Encode(dec.Epoch()))
+	}
+	assert.NoError(enc.Flush())
+
+	dec, err = NewObsDecoder(bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	assert.Equal(49, dec.Header.NSatellites)
+	assert.Equal(want.NSatellites, dec.Header.NSatellites)
+	assert.Equal(want.ObsPerSat, dec.Header.ObsPerSat)
+	assert.Equal(want.TimeOfLastObs, dec.Header.TimeOfLastObs)
+	assert.Equal(epochStrings(t, data), epochStrings(t, buf.Bytes()))
+
+	// the cropped file gets its own counts
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	buf.Reset()
+	_, err = CropObs(&buf, dec, time.Time{}, time.Date(2019, 9, 27, 10, 1, 0, 0, time.UTC))
+	assert.NoError(err)
+	dec, err = NewObsDecoder(bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	g01 := PRN{Sys: gnss.SysGPS, Num: 1}
+	assert.Equal(2, dec.Header.ObsPerSat[g01][0], "C1C of G01 in 2 epochs")
+	assert.Equal(time.Date(2019, 9, 27, 10, 0, 30, 0, time.UTC), dec.Header.TimeOfLastObs)
+}
+
+// epochStrings decodes the RINEX observation data and returns its epochs as strings.
+func epochStrings(t *testing.T, data []byte) []string {
+	t.Helper()
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var epochs []string
+	for dec.NextEpoch() {
+		epochs = append(epochs, fmt.Sprintf("%v", *dec.Epoch()))
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return epochs
+}
@@ -0,0 +1,167 @@
+package rinex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// indexMagic is the first line of a persisted epoch index.
+const indexMagic = "# RINEX OBS EPOCH INDEX 1"
+
+// IndexEntry is the position of an epoch in a RINEX observation file.
+type IndexEntry struct {
+	Time   time.Time
+	Offset int64 // byte offset of the epoch line
+	Line   int   // line number of the epoch line
+}
+
+// EpochIndex contains the positions of the epochs in a RINEX observation file, sorted by time.
+// It allows random access by time, see ObsDecoder.SeekEpoch.
+type EpochIndex struct {
+	Entries []IndexEntry
+}
+
+// Search returns the index of the first entry at or after t. It returns len(Entries) if there is none.
+func (idx *EpochIndex) Search(t time.Time) int {
+	return sort.Search(len(idx.Entries), func(i int) bool { return !idx.Entries[i].Time.Before(t) })
+}
+
+// WriteTo writes the index in a simple text format, e.g. to persist it as sidecar file next to the RINEX file.
+func (idx *EpochIndex) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	nn, _ := fmt.Fprintln(bw, indexMagic)
+	n += int64(nn)
+	for _, e := range idx.Entries {
+		nn, _ = fmt.Fprintf(bw, "%s %d %d\n", e.Time.Format(time.RFC3339Nano), e.Offset, e.Line)
+		n += int64(nn)
+	}
+	return n, bw.Flush()
+}
+
+// ReadEpochIndex reads an index written by EpochIndex.WriteTo.
+func ReadEpochIndex(r io.Reader) (*EpochIndex, error) {
+	sc := bufio.NewScanner(r)
+	if !sc.Scan() || sc.Text() != indexMagic {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no RINEX epoch index")
+	}
+	idx := &EpochIndex{}
+	for lineNum := 2; sc.Scan(); lineNum++ {
+		f := strings.Fields(sc.Text())
+		if len(f) != 3 {
+			return nil, fmt.Errorf("invalid epoch index line %d: %q", lineNum, sc.Text())
+		}
+		t, err := time.Parse(time.RFC3339Nano, f[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid epoch index line %d: %w", lineNum, err)
+		}
+		off, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid epoch index line %d: %w", lineNum, err)
+		}
+		line, err := strconv.Atoi(f[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid epoch index line %d: %w", lineNum, err)
+		}
+		idx.Entries = append(idx.Entries, IndexEntry{Time: t, Offset: off, Line: line})
+	}
+	return idx, sc.Err()
+}
+
+// SetIndex sets the epoch index, e.g. one read from a sidecar file with ReadEpochIndex.
+// The index must have been built for the same file.
+func (dec *ObsDecoder) SetIndex(idx *EpochIndex) {
+	dec.index = idx
+}
+
+// Index returns the epoch index of the file. It is built on the first call by reading all epoch lines
+// of the file, the current position of the decoder is preserved. The decoder must read from an io.ReadSeeker.
+func (dec *ObsDecoder) Index() (*EpochIndex, error) {
+	if dec.index != nil {
+		return dec.index, nil
+	}
+	if dec.rs == nil {
+		return nil, fmt.Errorf("epoch index requires an io.ReadSeeker")
+	}
+	pos, lineNum := dec.sc.pos, dec.lineNum
+	if err := dec.seek(dec.dataStart, dec.dataLine); err != nil {
+		return nil, err
+	}
+
+	idx := &EpochIndex{}
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Bytes()
+		if !bytes.HasPrefix(line, []byte("> ")) {
+			continue
+		}
+		epo, _, err := dec.parseEpochLine(string(line))
+		if err != nil || epo.Time.IsZero() {
+			continue // an event without time or a malformed line, that is not indexed
+		}
+		idx.Entries = append(idx.Entries, IndexEntry{Time: epo.Time, Offset: dec.sc.start, Line: dec.lineNum})
+	}
+	if err := dec.sc.Err(); err != nil {
+		return nil, fmt.Errorf("build epoch index: %w", err)
+	}
+	sort.SliceStable(idx.Entries, func(i, j int) bool { return idx.Entries[i].Time.Before(idx.Entries[j].Time) })
+
+	if err := dec.seek(pos, lineNum); err != nil {
+		return nil, err
+	}
+	dec.index = idx
+	return idx, nil
+}
+
+// SeekEpoch positions the decoder so that the next call to NextEpoch returns the first epoch at or after t.
+// If there is no such epoch, NextEpoch returns false. The decoder must read from an io.ReadSeeker,
+// the epoch index is built if needed, see Index.
+func (dec *ObsDecoder) SeekEpoch(t time.Time) error {
+	return dec.SeekRange(t, time.Time{})
+}
+
+// SeekRange positions the decoder like SeekEpoch and lets NextEpoch return false at the first epoch
+// at or after end, so that only the epochs in [start, end) are decoded. A zero end means no limit.
+func (dec *ObsDecoder) SeekRange(start, end time.Time) error {
+	idx, err := dec.Index()
+	if err != nil {
+		return err
+	}
+	off, lineNum := dec.dataStart, dec.dataLine
+	if i := idx.Search(start); i < len(idx.Entries) {
+		off, lineNum = idx.Entries[i].Offset, idx.Entries[i].Line-1
+	} else if len(idx.Entries) > 0 {
+		off, err = dec.rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+	}
+	if err := dec.seek(off, lineNum); err != nil {
+		return err
+	}
+	dec.end = end
+	return nil
+}
+
+// seek continues the decoding at the offset, lineNum is the number of lines before.
+func (dec *ObsDecoder) seek(off int64, lineNum int) error {
+	if _, err := dec.rs.Seek(off, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+	dec.sc.reset(withProgress(dec.rs, dec.decOpts.Progress), off)
+	dec.lineNum = lineNum
+	dec.epo = nil
+	if dec.err == io.EOF {
+		dec.err = nil
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package rinex
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsDecoder_SeekEpoch(t *testing.T) {
+	assert := assert.New(t)
+	f, err := os.Open("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	dec, err := NewObsDecoder(f)
+	assert.NoError(err)
+	assert.True(dec.NextEpoch())
+	first := dec.Epoch().Time
+
+	idx, err := dec.Index()
+	assert.NoError(err)
+	assert.Len(idx.Entries, 120)
+	assert.Equal(first, idx.Entries[0].Time)
+
+	// the position is preserved
+	assert.True(dec.NextEpoch())
+	assert.Equal(first.Add(30*time.Second), dec.Epoch().Time)
+
+	t0 := first.Add(30 * time.Minute)
+	assert.NoError(dec.SeekEpoch(t0.Add(-10 * time.Second)))
+	assert.True(dec.NextEpoch())
+	assert.Equal(t0, dec.Epoch().Time)
+	assert.Len(dec.Epoch().ObsList, int(dec.Epoch().NumSat))
+
+	// time slice
+	assert.NoError(dec.SeekRange(t0, t0.Add(5*time.Minute)))
+	n := 0
+	for dec.NextEpoch() {
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(10, n)
+
+	// after the last epoch
+	assert.NoError(dec.SeekEpoch(first.Add(2 * time.Hour)))
+	assert.False(dec.NextEpoch())
+	assert.NoError(dec.Err())
+
+	// sidecar
+	var buf bytes.Buffer
+	_, err = idx.WriteTo(&buf)
+	assert.NoError(err)
+	idx2, err := ReadEpochIndex(&buf)
+	assert.NoError(err)
+	assert.Equal(idx.Entries[119].Offset, idx2.Entries[119].Offset)
+	assert.True(idx.Entries[119].Time.Equal(idx2.Entries[119].Time))
+
+	_, err = f.Seek(0, 0)
+	assert.NoError(err)
+	dec2, err := NewObsDecoder(f)
+	assert.NoError(err)
+	dec2.SetIndex(idx2)
+	assert.NoError(dec2.SeekEpoch(t0))
+	assert.True(dec2.NextEpoch())
+	assert.Equal(t0, dec2.Epoch().Time)
+
+	// no seeker
+	dec3, err := NewObsDecoder(bytes.NewBufferString(""))
+	assert.NoError(err)
+	assert.Error(dec3.SeekEpoch(t0))
+}
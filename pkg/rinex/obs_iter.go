@@ -0,0 +1,30 @@
+//go:build go1.23
+
+package rinex
+
+import "iter"
+
+// Epochs returns an iterator over the epochs of the stream, so that the epochs can be read
+// with a range-over-func loop:
+//
+//	for epo, err := range dec.Epochs() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// A decoding error is yielded together with a nil epoch and ends the iteration.
+// It is an alternative to the NextEpoch, Epoch and Err pattern, both must not be mixed.
+func (dec *ObsDecoder) Epochs() iter.Seq2[*Epoch, error] {
+	return func(yield func(*Epoch, error) bool) {
+		for dec.NextEpoch() {
+			if !yield(dec.Epoch(), nil) {
+				return
+			}
+		}
+		if err := dec.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
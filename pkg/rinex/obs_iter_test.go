@@ -0,0 +1,60 @@
+//go:build go1.23
+
+package rinex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsDecoder_Epochs(t *testing.T) {
+	const header = `     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+  2020    11    14     0     0    0.0000000     GPS         TIME OF FIRST OBS
+                                                            END OF HEADER
+`
+	const epochs = `> 2020 11 14 00 00  0.0000000  0  1
+G05  22783244.880   119729271.83308
+> 2020 11 14 00 00 30.0000000  0  1
+G05  22783244.880   119729271.83308
+`
+	assert := assert.New(t)
+
+	dec, err := NewObsDecoder(strings.NewReader(header + epochs))
+	assert.NoError(err)
+	n := 0
+	for epo, err := range dec.Epochs() {
+		assert.NoError(err)
+		assert.Len(epo.ObsList, 1)
+		n++
+	}
+	assert.Equal(2, n)
+
+	// break early
+	dec, err = NewObsDecoder(strings.NewReader(header + epochs))
+	assert.NoError(err)
+	n = 0
+	for range dec.Epochs() {
+		n++
+		break
+	}
+	assert.Equal(1, n)
+
+	// error
+	dec, err = NewObsDecoder(strings.NewReader(header + epochs + "> 2020 11 14 00 01  0.0000000  0  1\nX05  22783244.880\n"))
+	assert.NoError(err)
+	n = 0
+	var lastErr error
+	for epo, err := range dec.Epochs() {
+		if err != nil {
+			assert.Nil(epo)
+			lastErr = err
+			continue
+		}
+		n++
+	}
+	assert.Equal(2, n)
+	assert.Error(lastErr)
+}
@@ -0,0 +1,71 @@
+package rinex
+
+import (
+	"log"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// Option configures an ObsDecoder, see NewObsDecoder.
+type Option func(*ObsDecoder)
+
+// WithDecoderOptions sets the options for reading the input stream.
+func WithDecoderOptions(opts DecoderOptions) Option {
+	return func(dec *ObsDecoder) {
+		dec.decOpts = opts
+	}
+}
+
+// WithSatSys decodes only the satellites of the given systems, the others are dropped from the epochs.
+// The empty set means all systems.
+func WithSatSys(set gnss.SystemSet) Option {
+	return func(dec *ObsDecoder) {
+		dec.Opts.SatSys = set
+	}
+}
+
+// WithLenientParsing enables the lenient mode, see ObsDecoder.Lenient.
+func WithLenientParsing() Option {
+	return func(dec *ObsDecoder) {
+		dec.Lenient = true
+	}
+}
+
+// WithLogger sets the logger for the problems encountered while decoding, see ObsDecoder.Logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(dec *ObsDecoder) {
+		dec.Logger = logger
+	}
+}
+
+// WithBufferSize sets the size of the read buffer in bytes, see DecoderOptions.BufferSize.
+func WithBufferSize(size int) Option {
+	return func(dec *ObsDecoder) {
+		dec.decOpts.BufferSize = size
+	}
+}
+
+// WithProgress reports the bytes read and the decoded epochs to p, see DecoderOptions.Progress.
+func WithProgress(p Progress) Option {
+	return func(dec *ObsDecoder) {
+		dec.decOpts.Progress = p
+	}
+}
+
+// WithTimeWindow decodes only the epochs in [from, to), the epochs before are skipped and the
+// decoding stops at the first epoch at or after to. A zero time means no limit.
+// Events without epoch time are always returned.
+func WithTimeWindow(from, to time.Time) Option {
+	return func(dec *ObsDecoder) {
+		dec.start, dec.end = from, to
+	}
+}
+
+// WithEventHandler passes the event and cycle slip epochs to fn instead of returning them by NextEpoch,
+// see ObsDecoder.OnEvent.
+func WithEventHandler(fn func(epo *Epoch)) Option {
+	return func(dec *ObsDecoder) {
+		dec.OnEvent = fn
+	}
+}
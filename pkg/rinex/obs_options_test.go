@@ -0,0 +1,104 @@
+package rinex
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewObsDecoder_Options(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+
+	// satellite systems
+	ge := gnss.NewSystemSet(gnss.SysGPS, gnss.SysGAL)
+	dec, err := NewObsDecoder(bytes.NewReader(data), WithSatSys(ge), WithBufferSize(64))
+	assert.NoError(err)
+	n := 0
+	for dec.NextEpoch() {
+		for _, obs := range dec.Epoch().ObsList {
+			assert.True(ge.Contains(obs.Prn.Sys), obs.Prn.String())
+		}
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(120, n)
+
+	// time window
+	from := time.Date(2019, 9, 27, 10, 15, 0, 0, time.UTC)
+	dec, err = NewObsDecoder(bytes.NewReader(data), WithTimeWindow(from, from.Add(15*time.Minute)))
+	assert.NoError(err)
+	var epochs []time.Time
+	for dec.NextEpoch() {
+		epochs = append(epochs, dec.Epoch().Time)
+	}
+	assert.NoError(dec.Err())
+	if assert.Len(epochs, 30) {
+		assert.Equal(from, epochs[0])
+		assert.Equal(from.Add(14*time.Minute+30*time.Second), epochs[29])
+	}
+
+	// the same with parallel parsing
+	dec, err = NewObsDecoder(bytes.NewReader(data), WithTimeWindow(from, time.Time{}))
+	assert.NoError(err)
+	dec.Workers = 4
+	n = 0
+	for dec.NextEpoch() {
+		if n == 0 {
+			assert.Equal(from, dec.Epoch().Time)
+		}
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(90, n)
+
+	// buffer size
+	dec, err = NewObsDecoder(bytes.NewReader(data), WithBufferSize(16))
+	assert.NoError(err)
+	var got []string
+	for dec.NextEpoch() {
+		got = append(got, fmt.Sprintf("%v", *dec.Epoch()))
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(epochStrings(t, data), got)
+}
+
+func TestNewObsDecoder_LenientLogger(t *testing.T) {
+	const data = `     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2020 11 14 00 00  0.0000000  0  2
+G05  22783244.880   119729271.83308
+G09  2278324x.880   119729271.83308
+`
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	dec, err := NewObsDecoder(strings.NewReader(data), WithLenientParsing(), WithLogger(log.New(&buf, "", 0)))
+	assert.NoError(err)
+	n := 0
+	for dec.NextEpoch() {
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(1, n)
+	assert.Len(dec.ParseWarnings, 1)
+	assert.True(strings.HasPrefix(buf.String(), "line 6: "), buf.String())
+}
+
+func TestNewObsDecoderWithOptions(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open(reykFile)
+	assert.NoError(err)
+	defer r.Close()
+	_, err = NewObsDecoderWithOptions(r, DecoderOptions{MaxHeaderLines: 10})
+	assert.Error(err)
+}
@@ -0,0 +1,187 @@
+package rinex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// epochsPerBlock is the number of epochs parsed at once by a worker of the parallel pipeline.
+const epochsPerBlock = 64
+
+// epochBlock is a chunk of the input starting with an epoch line.
+type epochBlock struct {
+	data    []byte
+	lineNum int // number of lines before the block
+	res     chan parsedBlock
+}
+
+// parsedBlock is the result of parsing an epochBlock.
+type parsedBlock struct {
+	epochs   []*Epoch
+	warnings []ParseWarning
+	err      error
+}
+
+// pipeline is the state of the parallel parsing, see ObsDecoder.Workers.
+type pipeline struct {
+	futures chan chan parsedBlock // the results in input order
+	done    chan struct{}
+	exited  chan struct{} // closed when the splitting goroutine has returned
+	epochs  []*Epoch      // parsed epochs not yet returned
+	err     error
+}
+
+// Stop stops the goroutines of the parallel parsing. It must be called if the caller stops reading
+// before NextEpoch returned false. It returns when the input is no longer read, so that a MappedFile
+// can be closed. NextEpoch returns false afterwards. It is a no-op if Workers is not set.
+func (dec *ObsDecoder) Stop() {
+	if dec.pipe != nil && dec.pipe.done != nil {
+		close(dec.pipe.done)
+		dec.pipe.done = nil
+		<-dec.pipe.exited
+		dec.pipe.epochs = nil
+		dec.setErr(io.EOF)
+	}
+}
+
+// nextParallel returns the next epoch from the parallel pipeline, which is started on the first call.
+func (dec *ObsDecoder) nextParallel() bool {
+	if dec.pipe == nil {
+		dec.pipe = dec.startPipeline()
+	}
+	p := dec.pipe
+	for {
+		for len(p.epochs) == 0 {
+			if p.err != nil {
+				dec.setErr(p.err)
+				dec.Stop()
+				return false
+			}
+			fut, ok := <-p.futures
+			if !ok {
+				dec.setErr(io.EOF)
+				dec.Stop()
+				return false
+			}
+			res := <-fut
+			p.epochs, p.err = res.epochs, res.err
+			dec.ParseWarnings = append(dec.ParseWarnings, res.warnings...)
+		}
+
+		epo := p.epochs[0]
+		p.epochs = p.epochs[1:]
+		if !dec.end.IsZero() && !epo.Time.IsZero() && !epo.Time.Before(dec.end) {
+			p.epochs = nil
+			dec.setErr(io.EOF)
+			dec.Stop()
+			return false
+		}
+		if dec.OnEvent != nil && (epo.IsEvent() || epo.IsCycleSlip()) {
+			dec.OnEvent(epo) // called here to keep the input order
+			continue
+		}
+		dec.epo = epo
+		return true
+	}
+}
+
+// startPipeline starts a goroutine that splits the input into blocks of epochs and the workers
+// that parse the blocks.
+func (dec *ObsDecoder) startPipeline() *pipeline {
+	p := &pipeline{futures: make(chan chan parsedBlock, 2*dec.Workers), done: make(chan struct{}), exited: make(chan struct{})}
+	jobs := make(chan epochBlock, dec.Workers)
+	for i := 0; i < dec.Workers; i++ {
+		go func() {
+			for blk := range jobs {
+				blk.res <- dec.parseBlock(blk)
+			}
+		}()
+	}
+
+	done := p.done
+	if f := dec.sc.file; f != nil {
+		f.acquire() // the lines are sliced from the mapping
+	}
+	go func() {
+		defer close(p.exited)
+		if f := dec.sc.file; f != nil {
+			defer f.release()
+		}
+		defer close(p.futures)
+		defer close(jobs)
+
+		var data []byte
+		lineNum, n := dec.lineNum, 0
+		send := func() bool {
+			res := make(chan parsedBlock, 1)
+			select {
+			case p.futures <- res:
+			case <-done:
+				return false
+			}
+			select {
+			case jobs <- epochBlock{data: data, lineNum: lineNum, res: res}:
+			case <-done:
+				return false
+			}
+			data, n = make([]byte, 0, len(data)+len(data)/8), 0 // blocks have similar sizes
+			return true
+		}
+
+		for dec.sc.Scan() {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			line := dec.sc.Bytes()
+			if bytes.HasPrefix(line, []byte("> ")) {
+				if n == epochsPerBlock && !send() {
+					return
+				}
+				n++
+			}
+			if len(data) == 0 {
+				lineNum = dec.lineNum
+			}
+			dec.lineNum++
+			data = append(data, line...)
+			data = append(data, '\n')
+		}
+		if len(data) > 0 && !send() {
+			return
+		}
+		if err := dec.sc.Err(); err != nil {
+			res := make(chan parsedBlock, 1)
+			res <- parsedBlock{err: fmt.Errorf("read epoch scanner error: %w", err)}
+			select {
+			case p.futures <- res:
+			case <-done:
+			}
+		}
+	}()
+	return p
+}
+
+// parseBlock parses the epochs of the block with a sequential decoder.
+func (dec *ObsDecoder) parseBlock(blk epochBlock) parsedBlock {
+	sub := &ObsDecoder{
+		Header:         dec.Header,
+		Lenient:        dec.Lenient,
+		Logger:         dec.Logger,
+		Opts:           dec.Opts,
+		EphemerisStore: dec.EphemerisStore,
+		sc:             newLineReader(bytes.NewReader(blk.data), 0),
+		decOpts:        dec.decOpts,
+		lineNum:        blk.lineNum,
+		start:          dec.start,
+	}
+	sub.decOpts.Progress = nil // the epochs are counted when returned by NextEpoch
+	var res parsedBlock
+	for sub.NextEpoch() {
+		res.epochs = append(res.epochs, sub.epo)
+	}
+	res.warnings, res.err = sub.ParseWarnings, sub.Err()
+	return res
+}
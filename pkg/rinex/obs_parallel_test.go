@@ -0,0 +1,108 @@
+package rinex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsDecoder_Workers(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	assert.NoError(err)
+
+	decode := func(data []byte, workers int, lenient bool) ([]string, *ObsDecoder) {
+		dec, err := NewObsDecoder(bytes.NewReader(data))
+		assert.NoError(err)
+		dec.Workers = workers
+		dec.Lenient = lenient
+		var epochs []string
+		for dec.NextEpoch() {
+			epochs = append(epochs, fmt.Sprintf("%v", *dec.Epoch()))
+		}
+		return epochs, dec
+	}
+
+	want, _ := decode(data, 0, false)
+	got, dec := decode(data, 4, false)
+	assert.NoError(dec.Err())
+	assert.Equal(want, got)
+
+	// errors and warnings
+	broken := strings.Replace(string(data), "> 2019 09 27 10 30 00.0000000  0", "> 2019 09 27 10 30 0x.0000000  0", 1)
+	assert.NotEqual(string(data), broken)
+	want, seq := decode([]byte(broken), 0, true)
+	got, dec = decode([]byte(broken), 3, true)
+	assert.NoError(dec.Err())
+	assert.Equal(want, got)
+	assert.Equal(seq.ParseWarnings, dec.ParseWarnings)
+
+	want, seq = decode([]byte(broken), 0, false)
+	got, dec = decode([]byte(broken), 3, false)
+	assert.Equal(want, got)
+	assert.Error(dec.Err())
+	assert.Equal(seq.Err(), dec.Err())
+
+	// stop early
+	goroutines := runtime.NumGoroutine()
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.Workers = 2
+	assert.True(dec.NextEpoch())
+	dec.Stop()
+	for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(runtime.NumGoroutine() <= goroutines, "goroutines stopped")
+}
+
+// countingReader counts the reads without synchronization, so that reads after Stop returned are
+// reported by the race detector.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	cr.reads++
+	return cr.r.Read(p)
+}
+
+// TestObsDecoder_StopMidStream should be run with -race.
+func TestObsDecoder_StopMidStream(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	i := bytes.Index(data, []byte("END OF HEADER"))
+	i += bytes.IndexByte(data[i:], '\n') + 1
+
+	for _, stopAfter := range []int{1, 100, 500} {
+		parts := []io.Reader{bytes.NewReader(data)}
+		for j := 0; j < 50; j++ { // the splitter is still reading when the decoding stops
+			parts = append(parts, bytes.NewReader(data[i:]))
+		}
+		in := &countingReader{r: io.MultiReader(parts...)}
+		dec, err := NewObsDecoder(in)
+		assert.NoError(err)
+		dec.Workers = 4
+		n := 0
+		for n < stopAfter && dec.NextEpoch() {
+			n++
+		}
+		assert.Equal(stopAfter, n)
+		dec.Stop()
+		reads := in.reads
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(reads, in.reads, "input read after Stop")
+		assert.False(dec.NextEpoch())
+		assert.NoError(dec.Err())
+		dec.Stop() // no-op
+	}
+}
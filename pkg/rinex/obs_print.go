@@ -0,0 +1,161 @@
+package rinex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// EpochFormat configures the tabular output of Epoch.FprintTab, one line per satellite with the epoch
+// time, the satellite and its observations.
+type EpochFormat struct {
+	SatSys gnss.SystemSet // satellite systems, the empty set means all systems
+
+	// Columns are the observation types to print, in this order, e.g. C1C and L1C. Missing observations
+	// are printed as 0. Empty means all observations of the satellite in the order of its types.
+	Columns []string
+
+	TimeFormat string // layout of the epoch time, see time.Time.Format, default time.RFC3339Nano
+
+	// Delimiter separates the columns, e.g. "," for CSV. The default empty delimiter aligns the values
+	// in columns of width 14, separated by a space.
+	Delimiter string
+
+	Flags bool // add the loss of lock indicator and the signal strength indicator after each value
+}
+
+// FprintHeader writes the names of the columns, e.g. as first line of a CSV file. Without Columns
+// the names of the observation columns are not known and only the time and the satellite are written.
+func (f EpochFormat) FprintHeader(w io.Writer) error {
+	fields := []string{"time", "prn"}
+	for _, typ := range f.Columns {
+		fields = append(fields, typ)
+		if f.Flags {
+			fields = append(fields, typ+"_lli", typ+"_snr")
+		}
+	}
+	bw := bufio.NewWriter(w)
+	for i, field := range fields {
+		if i > 0 {
+			bw.WriteString(f.delimiter())
+		}
+		bw.WriteString(field)
+	}
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+func (f EpochFormat) delimiter() string {
+	if f.Delimiter == "" {
+		return " "
+	}
+	return f.Delimiter
+}
+
+// String returns the epoch time, the flag and the number of satellites, or of the event records, e.g.
+// "2019-09-27T10:00:00Z OK: 22 satellites".
+func (epo *Epoch) String() string {
+	if epo.IsEvent() {
+		n := 0
+		if epo.Event != nil {
+			n = len(epo.Event.Records)
+		}
+		return fmt.Sprintf("%s %s: %d records", epo.Time.Format(time.RFC3339Nano), epo.Flag, n)
+	}
+	return fmt.Sprintf("%s %s: %d satellites", epo.Time.Format(time.RFC3339Nano), epo.Flag, len(epo.ObsList))
+}
+
+// Fprint pretty prints the epoch to w, all observations of a satellite below it.
+func (epo *Epoch) Fprint(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s Flag: %d #prn: %d\n", epo.Time.Format(time.RFC3339Nano), epo.Flag, epo.NumSat)
+	for _, satObs := range epo.ObsList {
+		fmt.Fprintf(bw, "%v -------------------------------------\n", satObs.Prn)
+		for i, obs := range satObs.Obss {
+			fmt.Fprintf(bw, "%s: %+v\n", satObs.Types[i], obs)
+		}
+	}
+	return bw.Flush()
+}
+
+// FprintTab prints the epoch in a tabular format to w, one line per satellite.
+func (epo *Epoch) FprintTab(w io.Writer, f EpochFormat) error {
+	layout := f.TimeFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	ts := epo.Time.Format(layout)
+	delim := f.delimiter()
+	bw := bufio.NewWriter(w)
+	var buf []byte
+	for _, satObs := range epo.ObsList {
+		if f.SatSys != 0 && !f.SatSys.Contains(satObs.Prn.Sys) {
+			continue
+		}
+		buf = append(buf[:0], ts...)
+		buf = append(buf, delim...)
+		buf = append(buf, satObs.Prn.String()...)
+		n := len(satObs.Obss)
+		if len(f.Columns) > 0 {
+			n = len(f.Columns)
+		}
+		for i := 0; i < n; i++ {
+			var obs Obs
+			if len(f.Columns) > 0 {
+				obs, _ = satObs.Get(f.Columns[i])
+			} else {
+				obs = satObs.Obss[i]
+			}
+			buf = append(buf, delim...)
+			buf = f.appendObs(buf, obs)
+		}
+		if f.Delimiter == "" {
+			buf = append(buf, ' ') // as written by the former PrintTab
+		}
+		buf = append(buf, '\n')
+		bw.Write(buf)
+	}
+	return bw.Flush()
+}
+
+// appendObs appends the value of the observation with 3 decimals and the flags if requested.
+func (f EpochFormat) appendObs(buf []byte, obs Obs) []byte {
+	if f.Delimiter == "" {
+		buf = append(buf, fmt.Sprintf("%14.03f", obs.Val)...)
+		if f.Flags {
+			buf = append(buf, fmt.Sprintf(" %d %d", obs.LLI, obs.SNR)...)
+		}
+		return buf
+	}
+	buf = strconv.AppendFloat(buf, obs.Val, 'f', 3, 64)
+	if f.Flags {
+		buf = append(buf, f.Delimiter...)
+		buf = strconv.AppendInt(buf, int64(obs.LLI), 10)
+		buf = append(buf, f.Delimiter...)
+		buf = strconv.AppendInt(buf, int64(obs.SNR), 10)
+	}
+	return buf
+}
+
+// Print pretty prints the epoch to stdout.
+//
+// Deprecated: use Fprint.
+func (epo *Epoch) Print() {
+	epo.Fprint(os.Stdout)
+}
+
+// PrintTab prints the epoch in a tabular format to stdout. Only the satellites of opts.SatSys are printed,
+// none if it is empty.
+//
+// Deprecated: use FprintTab, which prints all systems for an empty set.
+func (epo *Epoch) PrintTab(opts Options) {
+	if opts.SatSys == 0 {
+		return
+	}
+	epo.FprintTab(os.Stdout, EpochFormat{SatSys: opts.SatSys})
+}
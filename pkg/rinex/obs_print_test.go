@@ -0,0 +1,113 @@
+package rinex
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPrintEpoch() *Epoch {
+	gpsTypes := []string{"C1C", "L1C", "S1C"}
+	gloTypes := []string{"C1C", "L1C"}
+	return &Epoch{
+		Time:   time.Date(2019, 9, 27, 10, 0, 0, 0, time.UTC),
+		Flag:   EpochFlagOK,
+		NumSat: 2,
+		ObsList: []SatObs{
+			{Prn: PRN{Sys: gnss.SysGPS, Num: 1}, Types: gpsTypes,
+				Obss: []Obs{{Val: 20000000.123}, {Val: 105000000.456, LLI: 1, SNR: 7}, {Val: 45.25}}},
+			{Prn: PRN{Sys: gnss.SysGLO, Num: 3}, Types: gloTypes,
+				Obss: []Obs{{Val: 21000000.5}, {Val: 112000000.25, SNR: 6}}},
+		},
+	}
+}
+
+func TestEpoch_String(t *testing.T) {
+	assert := assert.New(t)
+	epo := testPrintEpoch()
+	assert.Equal("2019-09-27T10:00:00Z OK: 2 satellites", epo.String())
+	assert.Equal("2019-09-27T10:00:00Z OK: 2 satellites", fmt.Sprint(epo))
+
+	ev := &Epoch{Time: epo.Time, Flag: EpochFlagHeaderInfo, Event: &Event{Records: []string{"a", "b"}}}
+	assert.Equal("2019-09-27T10:00:00Z header info: 2 records", ev.String())
+}
+
+func TestEpoch_Fprint(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(testPrintEpoch().Fprint(&buf))
+	want := `2019-09-27T10:00:00Z Flag: 0 #prn: 2
+G01 -------------------------------------
+C1C: {Val:2.0000000123e+07 LLI:0 SNR:0}
+L1C: {Val:1.05000000456e+08 LLI:1 SNR:7}
+S1C: {Val:45.25 LLI:0 SNR:0}
+R03 -------------------------------------
+C1C: {Val:2.10000005e+07 LLI:0 SNR:0}
+L1C: {Val:1.1200000025e+08 LLI:0 SNR:6}
+`
+	assert.Equal(want, buf.String())
+}
+
+func TestEpoch_FprintTab(t *testing.T) {
+	tests := []struct {
+		name string
+		f    EpochFormat
+		want string
+	}{
+		{"default", EpochFormat{},
+			"2019-09-27T10:00:00Z G01   20000000.123  105000000.456         45.250 \n" +
+				"2019-09-27T10:00:00Z R03   21000000.500  112000000.250 \n"},
+		{"satsys", EpochFormat{SatSys: gnss.NewSystemSet(gnss.SysGLO)},
+			"2019-09-27T10:00:00Z R03   21000000.500  112000000.250 \n"},
+		{"csv", EpochFormat{Columns: []string{"L1C", "S1C"}, TimeFormat: "2006-01-02 15:04:05", Delimiter: ",", Flags: true},
+			"2019-09-27 10:00:00,G01,105000000.456,1,7,45.250,0,0\n" +
+				"2019-09-27 10:00:00,R03,112000000.250,0,6,0.000,0,0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, testPrintEpoch().FprintTab(&buf, tt.f))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestEpoch_PrintTab(t *testing.T) {
+	assert := assert.New(t)
+	stdout := func(print func()) string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := os.Stdout
+		os.Stdout = w
+		print()
+		os.Stdout = orig
+		w.Close()
+		out, err := ioutil.ReadAll(r)
+		assert.NoError(err)
+		return string(out)
+	}
+	epo := testPrintEpoch()
+	assert.Empty(stdout(func() { epo.PrintTab(Options{}) }), "no system selected")
+	assert.Equal("2019-09-27T10:00:00Z R03   21000000.500  112000000.250 \n",
+		stdout(func() { epo.PrintTab(Options{SatSys: gnss.NewSystemSet(gnss.SysGLO)}) }))
+}
+
+func TestEpochFormat_FprintHeader(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	f := EpochFormat{Columns: []string{"C1C", "L1C"}, Delimiter: ";", Flags: true}
+	assert.NoError(f.FprintHeader(&buf))
+	assert.Equal("time;prn;C1C;C1C_lli;C1C_snr;L1C;L1C_lli;L1C_snr\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(EpochFormat{}.FprintHeader(&buf))
+	assert.Equal("time prn\n", buf.String())
+}
@@ -0,0 +1,123 @@
+package rinex
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EpochSynchronizer aligns the epochs of several RINEX Obs input streams by time, e.g. of the stations
+// of a baseline or network. Next advances to the next common epoch, which is available by Epochs and Time.
+// Event epochs are skipped. The decoders must not use ReuseEpoch or Workers.
+//
+//	syn := rinex.NewEpochSynchronizer(map[string]*rinex.ObsDecoder{"WTZR": dec1, "WTZZ": dec2})
+//	syn.Tolerance = 100 * time.Millisecond
+//	for syn.Next() {
+//		epochs := syn.Epochs()
+//		...
+//	}
+//	if err := syn.Err(); err != nil {
+//		...
+//	}
+type EpochSynchronizer struct {
+	// Tolerance is the maximum time difference of epochs that are treated as simultaneous.
+	Tolerance time.Duration
+
+	// MinStreams is the number of streams that must have an epoch, zero means all streams.
+	MinStreams int
+
+	names  []string
+	decs   []*ObsDecoder
+	heads  []*Epoch // the next unused epoch of each decoder, nil at the end
+	epochs map[string]*Epoch
+	t      time.Time
+	err    error
+}
+
+// NewEpochSynchronizer returns a synchronizer for the decoders, which are keyed by a name, e.g. the station.
+func NewEpochSynchronizer(decs map[string]*ObsDecoder) *EpochSynchronizer {
+	syn := &EpochSynchronizer{}
+	for name := range decs {
+		syn.names = append(syn.names, name)
+	}
+	sort.Strings(syn.names)
+	for _, name := range syn.names {
+		syn.decs = append(syn.decs, decs[name])
+	}
+	syn.heads = make([]*Epoch, len(syn.decs))
+	for i := range syn.decs {
+		syn.advance(i)
+	}
+	return syn
+}
+
+// advance reads the next observation epoch of decoder i.
+func (syn *EpochSynchronizer) advance(i int) {
+	syn.heads[i] = nil
+	dec := syn.decs[i]
+	for dec.NextEpoch() {
+		if epo := dec.Epoch(); !epo.IsEvent() && !epo.IsCycleSlip() {
+			syn.heads[i] = epo
+			return
+		}
+	}
+	if err := dec.Err(); err != nil && syn.err == nil {
+		syn.err = fmt.Errorf("stream %s: %w", syn.names[i], err)
+	}
+}
+
+// Next advances to the next epoch that is found in at least MinStreams streams within the Tolerance.
+// It returns false at the end of the streams or on an error.
+func (syn *EpochSynchronizer) Next() bool {
+	minStreams := syn.MinStreams
+	if minStreams <= 0 || minStreams > len(syn.decs) {
+		minStreams = len(syn.decs)
+	}
+	for syn.err == nil {
+		// the earliest epoch and the streams within the tolerance of it
+		first := -1
+		for i, epo := range syn.heads {
+			if epo != nil && (first < 0 || epo.Time.Before(syn.heads[first].Time)) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return false
+		}
+		t := syn.heads[first].Time
+		var group []int
+		for i, epo := range syn.heads {
+			if epo != nil && epo.Time.Sub(t) <= syn.Tolerance {
+				group = append(group, i)
+			}
+		}
+		if len(group) < minStreams {
+			syn.advance(first)
+			continue
+		}
+
+		syn.t = t
+		syn.epochs = make(map[string]*Epoch, len(group))
+		for _, i := range group {
+			syn.epochs[syn.names[i]] = syn.heads[i]
+			syn.advance(i)
+		}
+		return true
+	}
+	return false
+}
+
+// Epochs returns the current epochs keyed by the names of the streams. Streams without the epoch are missing.
+func (syn *EpochSynchronizer) Epochs() map[string]*Epoch {
+	return syn.epochs
+}
+
+// Time returns the time of the current epochs, i.e. the earliest one within the tolerance.
+func (syn *EpochSynchronizer) Time() time.Time {
+	return syn.t
+}
+
+// Err returns the first error of the decoders.
+func (syn *EpochSynchronizer) Err() error {
+	return syn.err
+}
@@ -0,0 +1,133 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cropDecoder returns a decoder for the epochs of data within [from, to).
+func cropDecoder(t *testing.T, data []byte, from, to time.Time) *ObsDecoder {
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err = CropObs(&buf, dec, from, to); err != nil {
+		t.Fatal(err)
+	}
+	dec, err = NewObsDecoder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dec
+}
+
+func TestObsDecoder_SyncWith(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	t1020 := time.Date(2019, 9, 27, 10, 20, 0, 0, time.UTC)
+	dec := cropDecoder(t, data, time.Date(2019, 9, 27, 10, 15, 0, 0, time.UTC), time.Date(2019, 9, 27, 10, 30, 0, 0, time.UTC))
+	dec2 := cropDecoder(t, data, t1020, time.Time{})
+
+	n := 0
+	for dec.SyncWith(dec2) {
+		epochs := dec.SyncEpoch()
+		assert.Equal(t1020.Add(time.Duration(n)*30*time.Second), epochs.Epo1.Time)
+		assert.Equal(epochs.Epo1.Time, epochs.Epo2.Time)
+		assert.Equal(epochs.Epo1.ObsList, epochs.Epo2.ObsList)
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(20, n)
+}
+
+func TestEpochSynchronizer(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+
+	crop := func(from, to time.Time) *ObsDecoder { return cropDecoder(t, data, from, to) }
+	t1015 := time.Date(2019, 9, 27, 10, 15, 0, 0, time.UTC)
+	t1020 := time.Date(2019, 9, 27, 10, 20, 0, 0, time.UTC)
+	t1030 := time.Date(2019, 9, 27, 10, 30, 0, 0, time.UTC)
+	decs := func() map[string]*ObsDecoder {
+		return map[string]*ObsDecoder{
+			"ALL": crop(time.Time{}, time.Time{}),
+			"A":   crop(t1015, t1030),
+			"B":   crop(t1020, time.Time{}),
+		}
+	}
+
+	// all streams
+	syn := NewEpochSynchronizer(decs())
+	n := 0
+	for syn.Next() {
+		epochs := syn.Epochs()
+		assert.Len(epochs, 3)
+		assert.Equal(epochs["ALL"].Time, syn.Time())
+		assert.Equal(epochs["ALL"].ObsList, epochs["B"].ObsList)
+		if n == 0 {
+			assert.Equal(t1020, syn.Time())
+		}
+		n++
+	}
+	assert.NoError(syn.Err())
+	assert.Equal(20, n)
+
+	// at least two streams
+	syn = NewEpochSynchronizer(decs())
+	syn.MinStreams = 2
+	n = 0
+	for syn.Next() {
+		if n == 0 {
+			assert.Equal(t1015, syn.Time())
+			assert.Len(syn.Epochs(), 2)
+		}
+		n++
+	}
+	assert.NoError(syn.Err())
+	assert.Equal(90, n)
+}
+
+func TestEpochSynchronizer_Tolerance(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+
+	// a receiver with a clock offset of 0.2 s
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, editHeader(dec.Header))
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		epo.Time = epo.Time.Add(200 * time.Millisecond)
+		assert.NoError(enc.Encode(epo))
+	}
+	assert.NoError(enc.Flush())
+
+	newSyn := func() *EpochSynchronizer {
+		dec1, err := NewObsDecoder(bytes.NewReader(data))
+		assert.NoError(err)
+		dec2, err := NewObsDecoder(bytes.NewReader(buf.Bytes()))
+		assert.NoError(err)
+		return NewEpochSynchronizer(map[string]*ObsDecoder{"REYK": dec1, "SHIFTED": dec2})
+	}
+	syn := newSyn()
+	assert.False(syn.Next())
+
+	syn = newSyn()
+	syn.Tolerance = 500 * time.Millisecond
+	n := 0
+	for syn.Next() {
+		assert.Equal(syn.Epochs()["REYK"].Time, syn.Time())
+		n++
+	}
+	assert.NoError(syn.Err())
+	assert.Equal(120, n)
+}
@@ -1,15 +1,21 @@
 package rinex
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -79,6 +85,362 @@ DBHZ                                                        SIGNAL STRENGTH UNIT
 	t.Logf("RINEX Header: %+v\n", dec)
 }
 
+func TestObsDecoder_readHeaderRecords(t *testing.T) {
+	const header = `     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+sbf2rin-13.4.5                          20201119 001256 UTC PGM / RUN BY / DATE
+VESSEL                                                      MARKER NAME
+        0.0000        0.0000        0.0000                  ANTENNA: DELTA H/E/N
+        1.2000       -0.5000        2.3000                  ANTENNA: DELTA X/Y/Z
+        0.0000        0.0000        1.0000                  ANTENNA: B.SIGHT XYZ
+       10.1000        0.2000       -1.3000                  CENTER OF MASS: XYZ
+G    4 C1C L1C C2W L2W                                      SYS / # / OBS TYPES
+  2020    11    18     0     0    0.0000000     GPS         TIME OF FIRST OBS
+     1                                                      RCV CLOCK OFFS APPL
+G CC2NONCC          http://www.igs.org/dcb/p1c1.dcb         SYS / DCBS APPLIED
+G PAGES             igs20.atx                               SYS / PCVS APPLIED
+                                                            END OF HEADER
+`
+
+	assert := assert.New(t)
+	dec, err := NewObsDecoder(strings.NewReader(header))
+	assert.NoError(err)
+	hdr := dec.Header
+	assert.Equal(Coord{X: 1.2, Y: -0.5, Z: 2.3}, hdr.AntennaDeltaXYZ, "ANTENNA: DELTA X/Y/Z")
+	assert.Equal(Coord{X: 0, Y: 0, Z: 1}, hdr.AntennaBSight, "ANTENNA: B.SIGHT XYZ")
+	assert.Equal(Coord{X: 10.1, Y: 0.2, Z: -1.3}, hdr.CenterOfMass, "CENTER OF MASS: XYZ")
+	assert.True(hdr.RcvClockOffsAppl, "RCV CLOCK OFFS APPL")
+	assert.Equal([]CorrApplied{{Sys: gnss.SysGPS, Program: "CC2NONCC", Source: "http://www.igs.org/dcb/p1c1.dcb"}}, hdr.DCBSApplied)
+	assert.Equal([]CorrApplied{{Sys: gnss.SysGPS, Program: "PAGES", Source: "igs20.atx"}}, hdr.PCVSApplied)
+	assert.Empty(hdr.warnings)
+
+	// Round trip
+	data, err := hdr.Marshal()
+	assert.NoError(err)
+	dec2, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	hdr2 := dec2.Header
+	hdr.labels, hdr2.labels = nil, nil
+	assert.Equal(hdr, hdr2)
+}
+
+func TestObsDecoder_leapSeconds(t *testing.T) {
+	const header = `     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+G    4 C1C L1C C2W L2W                                      SYS / # / OBS TYPES
+  2040    11    18     0     0    0.0000000     GPS         TIME OF FIRST OBS
+    19    20  3200     1                                    LEAP SECONDS
+                                                            END OF HEADER
+`
+	assert := assert.New(t)
+	tab := gnsstime.LeapSecondTable()
+	t.Cleanup(func() { gnsstime.SetLeapSecondTable(tab) })
+
+	dec, err := NewObsDecoder(strings.NewReader(header))
+	assert.NoError(err)
+	assert.Equal(tab, gnsstime.LeapSecondTable(), "decoding does not change the table")
+	assert.Equal(19, dec.Header.LeapSeconds)
+	assert.NoError(dec.Header.RegisterLeapSeconds())
+	assert.Equal(19, gnsstime.LeapSeconds(time.Date(2040, 11, 18, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(20, gnsstime.LeapSeconds(time.Date(2041, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(18, gnsstime.LeapSeconds(time.Date(2020, 11, 18, 0, 0, 0, 0, time.UTC)))
+
+	// wrong leap seconds
+	gnsstime.SetLeapSecondTable(tab)
+	for _, hdr := range []ObsHeader{
+		{TimeOfFirstObs: time.Date(2020, 11, 18, 0, 0, 0, 0, time.UTC), LeapSeconds: 17},
+		{TimeOfFirstObs: time.Date(2040, 11, 18, 0, 0, 0, 0, time.UTC), LeapSeconds: 37},
+		{TimeOfFirstObs: time.Date(2040, 11, 18, 0, 0, 0, 0, time.UTC), LeapSeconds: 19, LeapSecondsFuture: 21, LeapSecondsWeek: 3200, LeapSecondsDay: 1},
+	} {
+		assert.Error(hdr.RegisterLeapSeconds(), "%+v", hdr)
+		assert.Equal(tab, gnsstime.LeapSecondTable())
+	}
+	hdr := ObsHeader{TimeOfFirstObs: time.Date(2020, 11, 18, 0, 0, 0, 0, time.UTC), LeapSeconds: 18}
+	assert.NoError(hdr.RegisterLeapSeconds())
+	hdr = ObsHeader{TimeOfFirstObs: time.Date(2020, 11, 18, 0, 0, 0, 0, time.UTC), LeapSeconds: 4, LeapSecondsSys: "BDS"}
+	assert.NoError(hdr.RegisterLeapSeconds())
+	assert.Equal(tab, gnsstime.LeapSecondTable())
+}
+
+func TestObsDecoder_events(t *testing.T) {
+	const data = `     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+sbf2rin-13.4.3                          20201115 000000 UTC PGM / RUN BY / DATE
+WTZR                                                        MARKER NAME
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+  2020    11    14     0     0    0.0000000     GPS         TIME OF FIRST OBS
+                                                            END OF HEADER
+> 2020 11 14 00 00  0.0000000  0  1      -0.000123456789
+G05  22783244.880   119729271.83308
+>                              4  3
+ANTENNA CHANGED                                             COMMENT
+                    LEIAR25.R3      LEIT                    ANT # / TYPE
+        0.0710        0.0000        0.0000                  ANTENNA: DELTA H/E/N
+> 2020 11 14 00 00 30.0000000  0  1
+G05  22783244.880   119729271.83308
+`
+	assert := assert.New(t)
+	dec, err := NewObsDecoder(strings.NewReader(data))
+	assert.NoError(err)
+
+	epochs := []*Epoch{}
+	for dec.NextEpoch() {
+		epochs = append(epochs, dec.Epoch())
+	}
+	assert.NoError(dec.Err())
+	if !assert.Len(epochs, 3) {
+		return
+	}
+
+	assert.Equal(-0.000123456789, epochs[0].ClockOffset)
+	assert.False(epochs[0].IsEvent())
+	assert.Nil(epochs[0].Event)
+
+	ev := epochs[1]
+	assert.True(ev.IsEvent())
+	assert.Equal(EpochFlagHeaderInfo, ev.Flag)
+	assert.True(ev.Time.IsZero())
+	if assert.NotNil(ev.Event) {
+		assert.Len(ev.Event.Records, 3)
+		assert.Equal([]string{"ANTENNA CHANGED"}, ev.Event.Header.Comments)
+		assert.Equal("LEIAR25.R3      LEIT", ev.Event.Header.AntennaType)
+		assert.Equal(0.071, ev.Event.Header.AntennaDelta.Up)
+	}
+
+	assert.Equal(0.0, epochs[2].ClockOffset)
+	assert.Len(epochs[2].ObsList, 1)
+}
+
+func TestObsDecoder_OnEvent(t *testing.T) {
+	const data = `     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2020 11 14 00 00  0.0000000  0  1
+G05  22783244.880   119729271.83308
+> 2020 11 14 00 00  0.0000000  6  1
+G05                 119729270.83318
+>                              4  1
+ANTENNA CHANGED                                             COMMENT
+> 2020 11 14 00 00 30.0000000  0  1
+G05  22783244.880   119729271.83308
+`
+	assert := assert.New(t)
+	for _, workers := range []int{0, 2} {
+		var events []EpochFlag
+		dec, err := NewObsDecoder(strings.NewReader(data), WithEventHandler(func(epo *Epoch) {
+			events = append(events, epo.Flag)
+		}))
+		assert.NoError(err)
+		dec.Workers = workers
+		n := 0
+		for dec.NextEpoch() {
+			assert.Equal(EpochFlagOK, dec.Epoch().Flag)
+			n++
+		}
+		assert.NoError(dec.Err())
+		assert.Equal(2, n)
+		assert.Equal([]EpochFlag{EpochFlagCycleSlip, EpochFlagHeaderInfo}, events)
+	}
+
+	assert.Equal("cycle slip", EpochFlagCycleSlip.String())
+	assert.Equal("EpochFlag(9)", EpochFlag(9).String())
+}
+
+func TestObsDecoder_Lenient(t *testing.T) {
+	const data = `     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+  2020    11    14     0     0    0.0000000     GPS         TIME OF FIRST OBS
+                                                            END OF HEADER
+> 2020 11 14 00 00  0.0000000  0  3
+G05  22783244.880   119729271.83308
+X07  22783244.880   119729271.83308
+G09  2278324x.880   119729271.83308
+> 2020 13 14 00 00 30.0000000  0  1
+G05  22783244.880   119729271.83308
+> 2020 11 14 00 01  0.0000000  0  1
+G05  22783244.880   119729271.83308
+`
+	assert := assert.New(t)
+
+	// strict
+	dec, err := NewObsDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	n := 0
+	for dec.NextEpoch() {
+		n++
+	}
+	assert.Error(dec.Err())
+	assert.Equal(0, n)
+
+	// lenient
+	dec, err = NewObsDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	dec.Lenient = true
+	epochs := []*Epoch{}
+	for dec.NextEpoch() {
+		epochs = append(epochs, dec.Epoch())
+	}
+	assert.NoError(dec.Err())
+	if assert.Len(epochs, 2) {
+		assert.Len(epochs[0].ObsList, 1)
+		assert.Equal(time.Date(2020, 11, 14, 0, 1, 0, 0, time.UTC), epochs[1].Time)
+	}
+	if assert.Len(dec.ParseWarnings, 3) {
+		assert.Equal(7, dec.ParseWarnings[0].Line)
+		assert.Equal(8, dec.ParseWarnings[1].Line)
+		assert.Equal(9, dec.ParseWarnings[2].Line)
+	}
+}
+
+func TestObsDecoder_RINEX4(t *testing.T) {
+	const data = `     4.00           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+gfzrnx-2.0.1                            20221215 100512 UTC PGM / RUN BY / DATE
+POTS                                                        MARKER NAME
+G    4 C1C L1C C2W L2W                                      SYS / # / OBS TYPES
+  2022    12    14     0     0    0.0000000     GPS         TIME OF FIRST OBS
+https://doi.org/10.5880/GFZ.1.1.2020.001                    DOI
+CC BY 4.0                                                   LICENSE OF USE
+https://gnss.gfz-potsdam.de/stations/POTS                   STATION INFORMATION
+                                                            END OF HEADER
+> 2022 12 14 00 00  0.0000000  0  1
+G05  22783244.880   119729271.83308  22783247.960    93295551.13907
+`
+	assert := assert.New(t)
+	dec, err := NewObsDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	assert.Equal(float32(4), dec.Header.RINEXVersion)
+	assert.Equal("https://doi.org/10.5880/GFZ.1.1.2020.001", dec.Header.DOI)
+	assert.Equal([]string{"CC BY 4.0"}, dec.Header.Licenses)
+	assert.Equal([]string{"https://gnss.gfz-potsdam.de/stations/POTS"}, dec.Header.StationInfos)
+	assert.Empty(dec.Header.warnings)
+
+	nEpochs := 0
+	for dec.NextEpoch() {
+		nEpochs++
+		epo := dec.Epoch()
+		assert.Len(epo.ObsList, 1)
+		assert.Equal(119729271.833, epo.ObsList[0].Obss[epo.ObsList[0].Index("L1C")].Val)
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(1, nEpochs)
+
+	data5 := strings.Replace(data, "     4.00", "     5.00", 1)
+	_, err = NewObsDecoder(strings.NewReader(data5))
+	assert.Error(err, "unsupported version")
+	assert.True(errors.Is(err, ErrUnsupportedVersion))
+}
+
+func TestObsDecoder_errors(t *testing.T) {
+	const data = `     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2020 11 14 00 00  0.0000000  0  1
+G05  22783244.880   119729271.83308
+> 2020 11 14 00 00 30.0000000  x  1
+G05  22783244.880   119729271.83308
+`
+	assert := assert.New(t)
+	_, err := NewObsDecoder(strings.NewReader("G05  22783244.880   119729271.83308\n"))
+	assert.True(errors.Is(err, ErrNoHeader))
+
+	dec, err := NewObsDecoder(strings.NewReader(data))
+	assert.NoError(err)
+	for dec.NextEpoch() {
+	}
+	var badEpo *ErrBadEpochLine
+	if assert.True(errors.As(dec.Err(), &badEpo)) {
+		assert.Equal(6, badEpo.Num)
+		assert.True(strings.HasPrefix(badEpo.Line, "> 2020 11 14 00 00 30.0"))
+		assert.Error(errors.Unwrap(badEpo))
+	}
+	assert.False(errors.Is(dec.Err(), ErrNoHeader))
+}
+
+func TestSatObs_SNRdBHz(t *testing.T) {
+	assert := assert.New(t)
+	satObs := NewSatObs(PRN{Sys: gnss.SysGPS, Num: 5}, map[string]Obs{
+		"C1C": {Val: 22783244.880, SNR: 7},
+		"L1C": {Val: 119729271.833, SNR: 8},
+		"S1C": {Val: 49.25},
+		"C2W": {Val: 22783247.960, SNR: 5},
+		"L2W": {Val: 93295551.139},
+	})
+
+	snr, ok := satObs.SNRdBHz("1")
+	assert.True(ok)
+	assert.Equal(49.25, snr, "S observation")
+
+	snr, ok = satObs.SNRdBHz("2")
+	assert.True(ok)
+	assert.Equal(30.0, snr, "indicator")
+
+	_, ok = satObs.SNRdBHz("5")
+	assert.False(ok)
+}
+
+func TestSatObs_GetSet(t *testing.T) {
+	assert := assert.New(t)
+	types := []string{"C1C", "L1C", "S1C"}
+	satObs := SatObs{Prn: PRN{Sys: gnss.SysGPS, Num: 5}, Types: types[:3:3], Obss: make([]Obs, 3)}
+
+	assert.Equal(1, satObs.Index("L1C"))
+	assert.Equal(-1, satObs.Index("L2W"))
+
+	satObs.Set("L1C", Obs{Val: 119729271.833, LLI: 1})
+	obs, ok := satObs.Get("L1C")
+	assert.True(ok)
+	assert.Equal(Obs{Val: 119729271.833, LLI: 1}, obs)
+
+	satObs.Set("L2W", Obs{Val: 93295551.139})
+	obs, ok = satObs.Get("L2W")
+	assert.True(ok)
+	assert.Equal(93295551.139, obs.Val)
+	assert.Len(satObs.Types, 4)
+	assert.Equal([]string{"C1C", "L1C", "S1C"}, types, "shared types unchanged")
+
+	_, ok = satObs.Get("C5Q")
+	assert.False(ok)
+}
+
+func TestParsePRN(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		s   string
+		prn PRN
+		num int
+	}{
+		{"G05", PRN{Sys: gnss.SysGPS, Num: 5}, 5},
+		{" 7", PRN{}, 0},
+		{" 07", PRN{Sys: gnss.SysGPS, Num: 7}, 7},
+		{"R 3", PRN{Sys: gnss.SysGLO, Num: 3}, 3},
+		{"S23", PRN{Sys: gnss.SysSBAS, Num: 23}, 123},
+		{"J02", PRN{Sys: gnss.SysQZSS, Num: 2}, 194},
+		{"C60", PRN{Sys: gnss.SysBDS, Num: 60}, 60},
+		{"I09", PRN{Sys: gnss.SysIRNSS, Num: 9}, 9},
+		{"I15", PRN{}, 0},
+		{"G33", PRN{}, 0},
+		{"S05", PRN{}, 0},
+		{"J11", PRN{}, 0},
+		{"M01", PRN{}, 0},
+		{"X01", PRN{}, 0},
+	}
+	for _, tt := range tests {
+		prn, err := ParsePRN(tt.s)
+		if tt.num == 0 {
+			assert.Error(err, tt.s)
+			continue
+		}
+		if assert.NoError(err, tt.s) {
+			assert.Equal(tt.prn, prn, tt.s)
+			assert.Equal(tt.num, prn.Number(), tt.s)
+		}
+	}
+
+	prn, err := NewPRN(gnss.SysSBAS, 158)
+	assert.NoError(err)
+	assert.Equal("S58", prn.String())
+	prn, err = NewPRN(gnss.SysQZSS, 193)
+	assert.NoError(err)
+	assert.Equal("J01", prn.String())
+}
+
 func TestObsFile_parseFilename(t *testing.T) {
 	assert := assert.New(t)
 	rnx, err := NewObsFile("ALGO01CAN_R_20121601000_15M_01S_GO.rnx.gz")
@@ -215,6 +577,96 @@ func BenchmarkReadEpochs(b *testing.B) {
 	}
 }
 
+// highrateReader generates an observation stream by repeating the first epoch of a RINEX file.
+type highrateReader struct {
+	hdr      []byte
+	obs      []byte // observation lines of the first epoch
+	numSat   string
+	t        time.Time
+	interval time.Duration
+	n        int // number of epochs left
+	buf      bytes.Buffer
+}
+
+// newHighrateReader returns a 1 Hz stream of the given number of epochs.
+func newHighrateReader(tb testing.TB, path string, epochs int) *highrateReader {
+	return newRepeatReader(tb, path, time.Second, epochs)
+}
+
+// newRepeatReader returns a stream of the given number of epochs and sampling interval.
+func newRepeatReader(tb testing.TB, path string, interval time.Duration, epochs int) *highrateReader {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	end := bytes.Index(data, []byte("END OF HEADER"))
+	if end < 0 {
+		tb.Fatalf("no header end in %s", path)
+	}
+	end += bytes.IndexByte(data[end:], '\n') + 1
+	epoLine := data[end : end+bytes.IndexByte(data[end:], '\n')+1]
+	obs := data[end+len(epoLine):]
+	if i := bytes.Index(obs, []byte("\n>")); i >= 0 {
+		obs = obs[:i+1]
+	}
+	r := &highrateReader{hdr: data[:end], obs: obs, numSat: string(epoLine[32:35]), n: epochs,
+		t: time.Date(2019, 9, 27, 0, 0, 0, 0, time.UTC), interval: interval}
+	r.buf.Write(r.hdr)
+	return r
+}
+
+func (r *highrateReader) Read(p []byte) (int, error) {
+	if r.buf.Len() == 0 {
+		if r.n == 0 {
+			return 0, io.EOF
+		}
+		fmt.Fprintf(&r.buf, "> %s  0%s\n", r.t.Format("2006 01 02 15 04 05.0000000"), r.numSat)
+		r.buf.Write(r.obs)
+		r.t = r.t.Add(r.interval)
+		r.n--
+	}
+	return r.buf.Read(p)
+}
+
+// Decode a 24 h 1 Hz observation stream.
+func BenchmarkObsDecoder_highrate(b *testing.B) {
+	const epochs = 86400
+	tests := []struct {
+		name    string
+		reuse   bool
+		workers int
+	}{
+		{"sequential", false, 0},
+		{"reuse", true, 0},
+		{"workers=4", false, 4},
+		{fmt.Sprintf("workers=%d", runtime.NumCPU()), false, runtime.NumCPU()},
+	}
+	for _, tt := range tests {
+		tt := tt
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				dec, err := NewObsDecoder(newHighrateReader(b, "testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx", epochs))
+				if err != nil {
+					b.Fatal(err)
+				}
+				dec.ReuseEpoch = tt.reuse
+				dec.Workers = tt.workers
+				n := 0
+				for dec.NextEpoch() {
+					n++
+				}
+				if err := dec.Err(); err != nil {
+					b.Fatal(err)
+				}
+				if n != epochs {
+					b.Fatalf("got %d epochs", n)
+				}
+			}
+		})
+	}
+}
+
 // Loop over the epochs of a observation data input stream.
 func ExampleObsDecoder_loop() {
 	filepath := "testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx"
@@ -271,6 +723,39 @@ func TestReadEpochs(t *testing.T) {
 	t.Logf("got all epochs: %d", numOfEpochs)
 }
 
+func TestObsDecoder_ReuseEpoch(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	assert.NoError(err)
+
+	var want []string
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	for dec.NextEpoch() {
+		want = append(want, fmt.Sprintf("%v", *dec.Epoch()))
+	}
+	assert.NoError(dec.Err())
+
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.ReuseEpoch = true
+	var first *Epoch
+	n := 0
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if first == nil {
+			first = epo
+		}
+		assert.True(first == epo, "same epoch")
+		if n < len(want) {
+			assert.Equal(want[n], fmt.Sprintf("%v", *epo), "epoch %d", n)
+		}
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(len(want), n)
+}
+
 func TestPrintEpochs(t *testing.T) {
 	assert := assert.New(t)
 	filepath := "testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx"
@@ -289,7 +774,7 @@ func TestPrintEpochs(t *testing.T) {
 	for dec.NextEpoch() {
 		numOfEpochs++
 		epo := dec.Epoch()
-		epo.PrintTab(Options{SatSys: "GR"})
+		epo.FprintTab(os.Stdout, EpochFormat{SatSys: gnss.NewSystemSet(gnss.SysGPS, gnss.SysGLO)})
 	}
 	if err := dec.Err(); err != nil {
 		fmt.Fprintln(os.Stderr, "reading standard input:", err)
@@ -373,11 +858,40 @@ func TestDiff(t *testing.T) {
 	assert.NotNil(obs2)
 	assert.NoError(err)
 
-	obs1.Opts.SatSys = "GR"
+	obs1.Opts.SatSys = gnss.NewSystemSet(gnss.SysGPS, gnss.SysGLO)
 	err = obs1.Diff(obs2)
 	assert.NoError(err)
 }
 
+func TestObsDecoder_IRNSSAndSBAS(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open(reykFile)
+	assert.NoError(err)
+	defer r.Close()
+	dec, err := NewObsDecoder(r)
+	assert.NoError(err)
+	assert.Equal([]string{"C5A", "D5A", "L5A", "S5A"}, dec.Header.ObsTypes[gnss.SysIRNSS])
+	assert.Equal([]string{"C1C", "D1C", "L1C", "S1C"}, dec.Header.ObsTypes[gnss.SysSBAS])
+
+	var seen gnss.SystemSet
+	for dec.NextEpoch() {
+		for _, obs := range dec.Epoch().ObsList {
+			seen = seen.Add(obs.Prn.Sys)
+			if obs.Prn.Sys == gnss.SysIRNSS || obs.Prn.Sys == gnss.SysSBAS {
+				assert.Equal(dec.Header.ObsTypes[obs.Prn.Sys], obs.Types, obs.Prn.String())
+			}
+		}
+	}
+	assert.NoError(dec.Err())
+	assert.True(seen.Contains(gnss.SysIRNSS))
+	assert.True(seen.Contains(gnss.SysSBAS))
+
+	opts := Options{SatSys: gnss.NewSystemSet(gnss.SysIRNSS, gnss.SysSBAS)}
+	assert.True(opts.useSys(gnss.SysIRNSS))
+	assert.False(opts.useSys(gnss.SysGPS))
+	assert.True(Options{}.useSys(gnss.SysSBAS), "empty set means all systems")
+}
+
 func TestSyncEpochs(t *testing.T) {
 	assert := assert.New(t)
 	//filePath1 := filepath.Join(homeDir, "IGS000USA_R_20192180344_02H_01S_MO.rnx")
@@ -399,7 +913,7 @@ func TestSyncEpochs(t *testing.T) {
 	assert.NoError(err)
 
 	numOfSyncEpochs := 0
-	for dec.sync(dec2) {
+	for dec.SyncWith(dec2) {
 		numOfSyncEpochs++
 		syncEpo := dec.SyncEpoch()
 
@@ -535,3 +1049,46 @@ func copyFile(src, dst string) (int64, error) {
 	}
 	return homeDir
 } */
+
+func TestObsFile_SetFromContent(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	data, err := ioutil.ReadFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	assert.NoError(err)
+	path := filepath.Join(dir, "reyk.obs")
+	assert.NoError(ioutil.WriteFile(path, data, 0644))
+
+	f := &ObsFile{RnxFil: &RnxFil{Path: path, CountryCode: "ISL", DataSource: "R"}}
+	assert.NoError(f.SetFromContent())
+	assert.Equal("REYK", f.FourCharID)
+	assert.Equal(time.Date(2019, 9, 27, 10, 0, 0, 0, time.UTC), f.StartTime)
+	assert.Equal("01H", f.FilePeriod)
+	assert.Equal("30S", f.DataFreq)
+	assert.Equal("MO", f.DataType)
+
+	f = &ObsFile{RnxFil: &RnxFil{Path: path, CountryCode: "ISL", DataSource: "R"}}
+	name, err := f.Rnx3Filename()
+	assert.NoError(err)
+	assert.Equal("REYK00ISL_R_20192701000_01H_30S_MO.rnx", name)
+	name, err = f.Rnx2Filename()
+	assert.NoError(err)
+	assert.Equal("reyk270k.19o", name)
+}
+
+func TestNominalPeriod(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		span time.Duration
+		code string
+	}{
+		{15 * time.Minute, "15M"}, {14 * time.Minute, "15M"}, {5 * time.Minute, "05M"},
+		{time.Hour, "01H"}, {55 * time.Minute, "01H"}, {2*time.Hour + time.Minute, "03H"},
+		{24 * time.Hour, "01D"}, {23*time.Hour + 59*time.Minute, "01D"}, {72 * time.Hour, "03D"},
+	}
+	for _, tt := range tests {
+		assert.Equal(tt.code, periodCode(nominalPeriod(tt.span)), "%s", tt.span)
+	}
+	assert.Equal("30S", freqCode(30*time.Second))
+	assert.Equal("05Z", freqCode(200*time.Millisecond))
+	assert.Equal("15M", freqCode(15*time.Minute))
+}
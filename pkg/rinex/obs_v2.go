@@ -0,0 +1,392 @@
+package rinex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// rinex2Systems returns the satellite systems of a RINEX 2 observation file of the system sys. The
+// observation types of the header apply to all of them.
+func rinex2Systems(sys gnss.System) []gnss.System {
+	if sys == gnss.SysMIXED {
+		return []gnss.System{gnss.SysGPS, gnss.SysGLO, gnss.SysGAL, gnss.SysSBAS}
+	}
+	return []gnss.System{sys}
+}
+
+// nextEpochV2 reads the next epoch of a RINEX 2 observation file, see NextEpoch.
+// The satellites are listed in the epoch line and its continuation lines, followed by the
+// observation records of the satellites with 5 observations per line.
+func (dec *ObsDecoder) nextEpochV2() bool {
+	for dec.sc.Scan() {
+		dec.lineNum++
+		line := dec.sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		e, numSat, err := parseEpochLineV2(line)
+		if err != nil {
+			err = &ErrBadEpochLine{Line: line, Num: dec.lineNum, Err: err}
+			if dec.Lenient {
+				dec.warn(err)
+				continue
+			}
+			dec.setErr(err)
+			return false
+		}
+		var epo *Epoch
+		if dec.ReuseEpoch {
+			if dec.reuse == nil {
+				dec.reuse = &Epoch{}
+			}
+			e.ObsList = dec.reuse.ObsList[:0]
+			*dec.reuse = e
+			epo = dec.reuse
+			dec.obsBuf = dec.obsSlab
+		} else {
+			ep := e
+			epo = &ep
+		}
+		dec.epo = epo
+
+		if epo.IsEvent() {
+			// numSat is the number of special records to follow
+			if err := dec.readEvent(numSat); err != nil {
+				if dec.Lenient {
+					dec.warn(err)
+					continue
+				}
+				dec.setErr(err)
+				return false
+			}
+			if dec.OnEvent != nil {
+				dec.OnEvent(epo)
+				continue
+			}
+			return true
+		}
+
+		prns, err := dec.readSatListV2(line, numSat)
+		if err != nil {
+			dec.setErr(err)
+			return false
+		}
+		skip := !dec.end.IsZero() && !epo.Time.Before(dec.end) || !dec.start.IsZero() && epo.Time.Before(dec.start)
+		if !dec.ReuseEpoch || cap(epo.ObsList) < numSat {
+			epo.ObsList = make([]SatObs, 0, numSat)
+		}
+		for _, prn := range prns {
+			nLines := (len(dec.Header.ObsTypes[prn.Sys]) + 4) / 5
+			if nLines == 0 {
+				nLines = (len(dec.Header.ObsTypes[gnss.SysGPS]) + 4) / 5
+			}
+			var sb strings.Builder
+			sb.WriteString(prn.String())
+			for i := 0; i < nLines; i++ {
+				if !dec.sc.Scan() {
+					if err := dec.sc.Err(); err != nil {
+						dec.setErr(fmt.Errorf("error in line %d: %w", dec.lineNum, err))
+						return false
+					}
+					dec.setErr(fmt.Errorf("unexpected EOF: epoch %s: observations of %s", epo.Time, prn))
+					return false
+				}
+				dec.lineNum++
+				obsLine := dec.sc.Text()
+				if i < nLines-1 {
+					obsLine = fmt.Sprintf("%-80s", obsLine)
+				}
+				sb.WriteString(obsLine)
+			}
+			if skip || len(dec.Header.ObsTypes[prn.Sys]) == 0 {
+				continue
+			}
+
+			satObs, err := dec.parseObsLine(strings.TrimRight(sb.String(), " "))
+			if err != nil {
+				if dec.Lenient {
+					dec.warn(err)
+					continue
+				}
+				dec.setErr(err)
+				return false
+			}
+			if satObs.Obss == nil || !dec.Opts.useSys(satObs.Prn.Sys) { // no observations or not selected
+				continue
+			}
+			epo.ObsList = append(epo.ObsList, satObs)
+		}
+		if !dec.end.IsZero() && !epo.Time.Before(dec.end) {
+			dec.setErr(io.EOF)
+			return false
+		}
+		if skip {
+			continue
+		}
+
+		if epo.IsCycleSlip() && dec.OnEvent != nil {
+			dec.OnEvent(epo)
+			continue
+		}
+		if dec.Opts.ElevationMask > 0 && dec.EphemerisStore != nil && dec.Header.Position != (Coord{}) {
+			epo.ApplyElevationMask(dec.EphemerisStore, dec.Header.Position, dec.Opts.ElevationMask)
+		}
+		return true
+	}
+
+	if err := dec.sc.Err(); err != nil {
+		dec.setErr(fmt.Errorf("read epoch scanner error: %w", err))
+	}
+	return false // EOF
+}
+
+// readSatListV2 returns the satellites of the RINEX 2 epoch line, reading the continuation lines
+// of epochs with more than 12 satellites.
+func (dec *ObsDecoder) readSatListV2(line string, numSat int) ([]PRN, error) {
+	prns := make([]PRN, 0, numSat)
+	for len(prns) < numSat {
+		if len(prns) > 0 && len(prns)%12 == 0 {
+			if !dec.sc.Scan() {
+				if err := dec.sc.Err(); err != nil {
+					return nil, fmt.Errorf("error in line %d: %w", dec.lineNum, err)
+				}
+				return nil, fmt.Errorf("unexpected EOF: satellite list continued after line %d", dec.lineNum)
+			}
+			dec.lineNum++
+			line = dec.sc.Text()
+		}
+		col := 32 + 3*(len(prns)%12)
+		s := field(line, col, col+3)
+		if len(s) < 3 {
+			return nil, fmt.Errorf("satellite list too short in line %d: %q", dec.lineNum, line)
+		}
+		if s[0] == ' ' {
+			s = "G" + s[1:] // blank means GPS
+		}
+		prn, err := ParsePRN(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the satellite list in line %d: %w", dec.lineNum, err)
+		}
+		prns = append(prns, prn)
+	}
+	return prns, nil
+}
+
+// parseEpochLineV2 parses a RINEX 2 epoch line and returns the epoch and the number of satellites
+// or special records to follow. The satellites are read by readSatListV2.
+func parseEpochLineV2(line string) (Epoch, int, error) {
+	// 20  6  3  7  0  0.0000000  0 32S25G14R07G29G24R06R24G15G02G12G19G10      -0.123456789
+	if len(line) < 32 {
+		return Epoch{}, 0, errors.New("line too short")
+	}
+	epochFlag, err := strconv.Atoi(strings.TrimSpace(field(line, 26, 29)))
+	if err != nil {
+		return Epoch{}, 0, fmt.Errorf("parsing epoch flag: %w", err)
+	}
+
+	// The epoch time is optional for event flags 2-5.
+	var epTime time.Time
+	if epochFlag < 2 || epochFlag > 5 || strings.TrimSpace(field(line, 0, 26)) != "" {
+		epTime, err = parseTimeV2(field(line, 0, 26))
+		if err != nil {
+			return Epoch{}, 0, err
+		}
+	}
+
+	numSat, err := strconv.Atoi(strings.TrimSpace(field(line, 29, 32)))
+	if err != nil {
+		return Epoch{}, 0, fmt.Errorf("parsing number of satellites: %w", err)
+	}
+	if numSat < 0 {
+		return Epoch{}, 0, fmt.Errorf("invalid number of satellites: %d", numSat)
+	}
+
+	var clkOff float64
+	if s := strings.TrimSpace(field(line, 68, len(line))); s != "" {
+		clkOff, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Epoch{}, 0, fmt.Errorf("parsing receiver clock offset: %w", err)
+		}
+	}
+	return Epoch{Time: epTime, Flag: EpochFlag(epochFlag), NumSat: uint8(numSat), ClockOffset: clkOff}, numSat, nil
+}
+
+// parseTimeV2 parses a RINEX 2 epoch like " 20  6  3  7  0  0.0000000" with a two-digit year, 80-99
+// meaning 1980-1999.
+func parseTimeV2(s string) (time.Time, error) {
+	f := strings.Fields(s)
+	if len(f) != 6 {
+		return time.Time{}, fmt.Errorf("invalid epoch: %q", s)
+	}
+	var ymdhm [5]int
+	for i := range ymdhm {
+		n, err := strconv.Atoi(f[i])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch: %q", s)
+		}
+		ymdhm[i] = n
+	}
+	sec, err := strconv.ParseFloat(f[5], 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch: %q", s)
+	}
+	year := ymdhm[0] + 1900
+	if ymdhm[0] < 80 {
+		year = ymdhm[0] + 2000
+	}
+	nsec := time.Duration(math.Round(sec*1e7)) * 100 // 0.1 microseconds
+	return time.Date(year, time.Month(ymdhm[1]), ymdhm[2], ymdhm[3], ymdhm[4], 0, 0, time.UTC).Add(nsec), nil
+}
+
+// v2ObsTypes returns the observation types of all systems for the RINEX 2 # / TYPES OF OBSERV record,
+// in the order of the systems.
+func (hdr *ObsHeader) v2ObsTypes() []string {
+	var types []string
+	for _, sys := range sortedSystems(hdr.ObsTypes) {
+		for _, typ := range hdr.ObsTypes[sys] {
+			if indexOf(types, typ) < 0 {
+				types = append(types, typ)
+			}
+		}
+	}
+	return types
+}
+
+// writeV2 writes the header in RINEX 2.11 format. The observation types of all systems are written as one list.
+func (hdr *ObsHeader) writeV2(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	hw := &headerWriter{w: bw}
+
+	hw.writeLine(fmt.Sprintf("%9.2f%11s%-20s%-20s", hdr.RINEXVersion, "", "OBSERVATION DATA", hdr.SatSystem.Abbr()), "RINEX VERSION / TYPE")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.Pgm, hdr.RunBy, hdr.Date), "PGM / RUN BY / DATE")
+	for _, c := range hdr.Comments {
+		hw.writeLine(c, "COMMENT")
+	}
+	hw.writeLine(hdr.MarkerName, "MARKER NAME")
+	if hdr.MarkerNumber != "" {
+		hw.writeLine(hdr.MarkerNumber, "MARKER NUMBER")
+	}
+	hw.writeLine(fmt.Sprintf("%-20s%-40s", hdr.Observer, hdr.Agency), "OBSERVER / AGENCY")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s%-20s", hdr.ReceiverNumber, hdr.ReceiverType, hdr.ReceiverVersion), "REC # / TYPE / VERS")
+	hw.writeLine(fmt.Sprintf("%-20s%-20s", hdr.AntennaNumber, hdr.AntennaType), "ANT # / TYPE")
+	hw.writeLine(formatCoord(hdr.Position), "APPROX POSITION XYZ")
+	hw.writeLine(fmt.Sprintf("%14.4f%14.4f%14.4f", hdr.AntennaDelta.Up, hdr.AntennaDelta.E, hdr.AntennaDelta.N), "ANTENNA: DELTA H/E/N")
+	hw.writeLine(fmt.Sprintf("%6d%6d", 1, 1), "WAVELENGTH FACT L1/2")
+
+	types := hdr.v2ObsTypes()
+	items := make([]string, 0, len(types))
+	for _, typ := range types {
+		items = append(items, fmt.Sprintf("%5s", typ))
+	}
+	hw.writeList(fmt.Sprintf("%6d", len(types)), strings.Repeat(" ", 6), items, 9, "# / TYPES OF OBSERV")
+
+	if hdr.Interval != 0 {
+		hw.writeLine(fmt.Sprintf("%10.3f", hdr.Interval), "INTERVAL")
+	}
+	timeSys := hdr.TimeSystem
+	if timeSys == "" {
+		timeSys = "GPS"
+	}
+	hw.writeLine(formatHeaderTime(hdr.TimeOfFirstObs, timeSys), "TIME OF FIRST OBS")
+	if !hdr.TimeOfLastObs.IsZero() {
+		hw.writeLine(formatHeaderTime(hdr.TimeOfLastObs, timeSys), "TIME OF LAST OBS")
+	}
+	if hdr.RcvClockOffsAppl {
+		hw.writeLine(fmt.Sprintf("%6d", 1), "RCV CLOCK OFFS APPL")
+	}
+	if hdr.LeapSeconds != 0 {
+		hw.writeLine(fmt.Sprintf("%6d", hdr.LeapSeconds), "LEAP SECONDS")
+	}
+	if hdr.NSatellites != 0 {
+		hw.writeLine(fmt.Sprintf("%6d", hdr.NSatellites), "# OF SATELLITES")
+	}
+	hw.writeObsPerSat(hdr.ObsPerSat)
+
+	hw.writeLine("", "END OF HEADER")
+	if hw.err != nil {
+		return hw.err
+	}
+	return bw.Flush()
+}
+
+// encodeV2 writes the epoch in RINEX 2 format, see Encode. The satellites are listed in the epoch line,
+// 12 per line, and the observations of all systems are written in the order of the header's
+// # / TYPES OF OBSERV record, 5 per line.
+func (enc *ObsEncoder) encodeV2(epo *Epoch) error {
+	if enc.v2Types == nil {
+		enc.v2Types = enc.Header.v2ObsTypes()
+	}
+	t := epo.Time
+	sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	epoLine := fmt.Sprintf(" %02d %2d %2d %2d %2d%11.7f  %d", t.Year()%100, t.Month(), t.Day(), t.Hour(), t.Minute(), sec, epo.Flag)
+	if epo.IsEvent() {
+		var records []string
+		if epo.Event != nil {
+			records = epo.Event.Records
+		}
+		fmt.Fprintf(enc.w, "%s%3d\n", epoLine, len(records))
+		for _, rec := range records {
+			enc.w.WriteString(rec)
+			enc.w.WriteByte('\n')
+		}
+		return nil
+	}
+
+	sats := make([]*SatObs, 0, len(epo.ObsList))
+	for i := range epo.ObsList {
+		if len(enc.Header.ObsTypes[epo.ObsList[i].Prn.Sys]) > 0 {
+			sats = append(sats, &epo.ObsList[i])
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s%3d", epoLine, len(sats))
+	for i, satObs := range sats {
+		if i > 0 && i%12 == 0 {
+			if i == 12 && epo.ClockOffset != 0 {
+				fmt.Fprintf(&sb, "%12.9f", epo.ClockOffset)
+			}
+			sb.WriteString("\n" + strings.Repeat(" ", 32))
+		}
+		sb.WriteString(satObs.Prn.String())
+	}
+	if len(sats) <= 12 && epo.ClockOffset != 0 {
+		fmt.Fprintf(&sb, "%*s%12.9f", 3*(12-len(sats)), "", epo.ClockOffset)
+	}
+	enc.w.WriteString(sb.String())
+	enc.w.WriteByte('\n')
+
+	for _, satObs := range sats {
+		var counts []int
+		if enc.obsPerSat != nil {
+			if counts = enc.obsPerSat[satObs.Prn]; counts == nil {
+				counts = make([]int, len(enc.v2Types))
+				enc.obsPerSat[satObs.Prn] = counts
+			}
+		}
+		line := enc.line[:0]
+		for i, typ := range enc.v2Types {
+			obs, _ := satObs.Get(typ)
+			if counts != nil && obs.Val != 0 {
+				counts[i]++
+			}
+			line = enc.appendObs(line, obs)
+			if (i+1)%5 == 0 || i == len(enc.v2Types)-1 {
+				enc.w.Write(bytes.TrimRight(line, " "))
+				enc.w.WriteByte('\n')
+				line = line[:0]
+			}
+		}
+		enc.line = line
+	}
+	return nil
+}
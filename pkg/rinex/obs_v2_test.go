@@ -0,0 +1,68 @@
+package rinex
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsDecoder_RINEX2(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("testdata/white/brst155h.20o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dec, err := NewObsDecoder(r)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(gnss.SysMIXED, dec.Header.SatSystem)
+	assert.Len(dec.Header.ObsTypes, 4)
+	types := dec.Header.ObsTypes[gnss.SysGAL]
+	assert.Len(types, 22)
+	assert.Equal("S8", types[21])
+
+	n := 0
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if n == 0 {
+			assert.Equal(time.Date(2020, 6, 3, 7, 0, 0, 0, time.UTC), epo.Time)
+			if assert.Len(epo.ObsList, 32) {
+				first, last := epo.ObsList[0], epo.ObsList[31]
+				assert.Equal(PRN{Sys: gnss.SysSBAS, Num: 25}, first.Prn)
+				assert.Equal(Obs{Val: 204258192.226, SNR: 6}, first.Obss[0])
+				obs, _ := first.Get("S1")
+				assert.Equal(41.7, obs.Val)
+				assert.Equal(PRN{Sys: gnss.SysSBAS, Num: 36}, last.Prn)
+				obs, _ = last.Get("C5")
+				assert.Equal(38279208.305, obs.Val)
+			}
+		}
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(120, n)
+}
+
+func TestParseEpochLineV2(t *testing.T) {
+	assert := assert.New(t)
+	epo, n, err := parseEpochLineV2(" 98 12 31 23 59 59.5000000  1  2G01 12                                  -0.123456789")
+	assert.NoError(err)
+	assert.Equal(2, n)
+	assert.Equal(time.Date(1998, 12, 31, 23, 59, 59, 5e8, time.UTC), epo.Time)
+	assert.Equal(EpochFlag(1), epo.Flag)
+	assert.Equal(-0.123456789, epo.ClockOffset)
+
+	epo, n, err = parseEpochLineV2("                            4  1")
+	assert.NoError(err)
+	assert.True(epo.IsEvent())
+	assert.True(epo.Time.IsZero())
+	assert.Equal(1, n)
+
+	_, _, err = parseEpochLineV2(" 20  6  3  7  0  0.0000000  0")
+	assert.Error(err)
+}
@@ -0,0 +1,168 @@
+package rinex
+
+import (
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// ObsCode is a RINEX 3 observation code like "L1C": the observation type, the frequency band
+// and the attribute, i.e. the tracking mode or channel.
+type ObsCode string
+
+// Type returns the observation type: 'C' code, 'L' phase, 'D' Doppler, 'S' signal strength or 'X' channel number.
+func (c ObsCode) Type() byte { return c.char(0) }
+
+// Band returns the frequency band, e.g. '1'.
+func (c ObsCode) Band() byte { return c.char(1) }
+
+// Attribute returns the tracking mode or channel, e.g. 'C'.
+func (c ObsCode) Attribute() byte { return c.char(2) }
+
+// Signal returns the frequency band and attribute, e.g. "1C".
+func (c ObsCode) Signal() string {
+	if len(c) != 3 {
+		return ""
+	}
+	return string(c[1:])
+}
+
+func (c ObsCode) char(i int) byte {
+	if len(c) != 3 {
+		return 0
+	}
+	return c[i]
+}
+
+// Lookup returns the description of the signal of the observation code for the system.
+// It returns false if the signal is not defined for the system.
+func (c ObsCode) Lookup(sys gnss.System) (SignalInfo, bool) {
+	info, ok := signals[sys][c.Signal()]
+	return info, ok
+}
+
+// Frequency returns the carrier frequency in Hz of the observation code for the system, or 0 if the
+// signal is not defined. For the GLONASS FDMA signals it is the frequency of channel 0, see GLOFrequency.
+func (c ObsCode) Frequency(sys gnss.System) float64 {
+	info, _ := c.Lookup(sys)
+	return info.Frequency
+}
+
+// Wavelength returns the wavelength in meters of the observation code for the system, or 0 if the
+// signal is not defined. For the GLONASS FDMA signals it is the wavelength of channel 0.
+func (c ObsCode) Wavelength(sys gnss.System) float64 {
+	info, _ := c.Lookup(sys)
+	return info.Wavelength()
+}
+
+// GLOFrequency returns the carrier frequency in Hz of the GLONASS FDMA band '1' or '2' for the
+// frequency channel, or 0 for other bands.
+func GLOFrequency(band byte, channel int) float64 {
+	switch band {
+	case '1':
+		return freqG1 + float64(channel)*0.5625e6
+	case '2':
+		return freqG2 + float64(channel)*0.4375e6
+	}
+	return 0
+}
+
+// SignalInfo describes a signal of a satellite system.
+type SignalInfo struct {
+	Band      byte    // frequency band, e.g. '1'
+	Attribute byte    // tracking mode or channel, e.g. 'C'
+	Name      string  // name of the frequency, e.g. "L1" or "E5a"
+	Frequency float64 // carrier frequency in Hz
+	FDMA      bool    // GLONASS FDMA signal, the frequency depends on the channel
+}
+
+// Wavelength returns the wavelength in meters.
+func (info SignalInfo) Wavelength() float64 {
+	if info.Frequency == 0 {
+		return 0
+	}
+	return gnss.SpeedOfLight / info.Frequency
+}
+
+// Carrier frequencies in Hz.
+const (
+	freqL1  = 1575.42e6
+	freqL2  = 1227.60e6
+	freqL5  = 1176.45e6
+	freqE6  = 1278.75e6
+	freqE5b = 1207.14e6
+	freqE5  = 1191.795e6
+	freqG1  = 1602e6
+	freqG2  = 1246e6
+	freqG1a = 1600.995e6
+	freqG2a = 1248.06e6
+	freqG3  = 1202.025e6
+	freqB1I = 1561.098e6
+	freqB3  = 1268.52e6
+	freqS   = 2492.028e6
+)
+
+// signals is the registry of the RINEX 3 signals per system and frequency band and attribute, see RINEX 3.05 table 4 ff.
+var signals = func() map[gnss.System]map[string]SignalInfo {
+	type band struct {
+		band  byte
+		name  string
+		freq  float64
+		attrs string
+	}
+	defs := map[gnss.System][]band{
+		gnss.SysGPS: {
+			{'1', "L1", freqL1, "CSLXPWYMN"},
+			{'2', "L2", freqL2, "CDSLXPWYMN"},
+			{'5', "L5", freqL5, "IQX"},
+		},
+		gnss.SysGLO: {
+			{'1', "G1", freqG1, "CP"},
+			{'4', "G1a", freqG1a, "ABX"},
+			{'2', "G2", freqG2, "CP"},
+			{'6', "G2a", freqG2a, "ABX"},
+			{'3', "G3", freqG3, "IQX"},
+		},
+		gnss.SysGAL: {
+			{'1', "E1", freqL1, "ABCXZ"},
+			{'5', "E5a", freqL5, "IQX"},
+			{'7', "E5b", freqE5b, "IQX"},
+			{'8', "E5", freqE5, "IQX"},
+			{'6', "E6", freqE6, "ABCXZ"},
+		},
+		gnss.SysSBAS: {
+			{'1', "L1", freqL1, "C"},
+			{'5', "L5", freqL5, "IQX"},
+		},
+		gnss.SysQZSS: {
+			{'1', "L1", freqL1, "CESLXZB"},
+			{'2', "L2", freqL2, "SLX"},
+			{'5', "L5", freqL5, "IQXDPZ"},
+			{'6', "L6", freqE6, "SLXEZ"},
+		},
+		gnss.SysBDS: {
+			{'2', "B1I", freqB1I, "IQX"},
+			{'1', "B1C", freqL1, "DPXAN"},
+			{'5', "B2a", freqL5, "DPX"},
+			{'7', "B2b", freqE5b, "IQXDPZ"},
+			{'8', "B2", freqE5, "DPX"},
+			{'6', "B3", freqB3, "IQXA"},
+		},
+		gnss.SysIRNSS: {
+			{'1', "L1", freqL1, "DPX"},
+			{'5', "L5", freqL5, "ABCX"},
+			{'9', "S", freqS, "ABCX"},
+		},
+	}
+
+	reg := make(map[gnss.System]map[string]SignalInfo, len(defs))
+	for sys, bands := range defs {
+		reg[sys] = make(map[string]SignalInfo)
+		for _, b := range bands {
+			for i := 0; i < len(b.attrs); i++ {
+				fdma := sys == gnss.SysGLO && (b.band == '1' || b.band == '2')
+				reg[sys][string([]byte{b.band, b.attrs[i]})] = SignalInfo{Band: b.band, Attribute: b.attrs[i],
+					Name: b.name, Frequency: b.freq, FDMA: fdma}
+			}
+		}
+	}
+	return reg
+}()
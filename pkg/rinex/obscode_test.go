@@ -0,0 +1,45 @@
+package rinex
+
+import (
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsCode(t *testing.T) {
+	assert := assert.New(t)
+	c := ObsCode("L1C")
+	assert.Equal(byte('L'), c.Type())
+	assert.Equal(byte('1'), c.Band())
+	assert.Equal(byte('C'), c.Attribute())
+	assert.Equal("1C", c.Signal())
+	assert.Equal(1575.42e6, c.Frequency(gnss.SysGPS))
+	assert.InDelta(0.19029367, c.Wavelength(gnss.SysGPS), 1e-8)
+
+	info, ok := ObsCode("C2I").Lookup(gnss.SysBDS)
+	assert.True(ok)
+	assert.Equal("B1I", info.Name)
+	assert.Equal(1561.098e6, info.Frequency)
+	assert.Equal(1176.45e6, ObsCode("C5Q").Frequency(gnss.SysGAL))
+	assert.Equal(1207.14e6, ObsCode("L7Q").Frequency(gnss.SysGAL))
+	assert.Equal(2492.028e6, ObsCode("L9A").Frequency(gnss.SysIRNSS))
+
+	// not defined for the system
+	_, ok = ObsCode("L2W").Lookup(gnss.SysGAL)
+	assert.False(ok)
+	assert.Equal(0.0, ObsCode("L2I").Frequency(gnss.SysGPS))
+	assert.Equal(0.0, ObsCode("L1").Frequency(gnss.SysGPS))
+	assert.Equal(byte(0), ObsCode("").Type())
+
+	// GLONASS FDMA
+	info, ok = ObsCode("L1C").Lookup(gnss.SysGLO)
+	assert.True(ok)
+	assert.True(info.FDMA)
+	assert.Equal(1602e6, info.Frequency)
+	assert.Equal(1598.0625e6, GLOFrequency('1', -7))
+	assert.Equal(1248.625e6, GLOFrequency('2', 6))
+	assert.Equal(0.0, GLOFrequency('3', 1))
+	info, _ = ObsCode("L3I").Lookup(gnss.SysGLO)
+	assert.False(info.FDMA)
+}
@@ -0,0 +1,186 @@
+package rinex
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// DefaultV2Attributes are the RINEX 3 attributes assigned to the RINEX 2 observation codes per system,
+// see RINEX 3.04 section 5.1. Bands not listed get the attribute 'X' if defined, else the first
+// attribute of the band in alphabetical order.
+var DefaultV2Attributes = map[gnss.System]map[string]byte{
+	gnss.SysGPS: {
+		"C1": 'C', "P1": 'W', "L1": 'C', "D1": 'C', "S1": 'C',
+		"C2": 'X', "P2": 'W', "L2": 'W', "D2": 'W', "S2": 'W',
+	},
+	gnss.SysGLO: {
+		"C1": 'C', "P1": 'P', "L1": 'C', "D1": 'C', "S1": 'C',
+		"C2": 'C', "P2": 'P', "L2": 'P', "D2": 'P', "S2": 'P',
+	},
+	gnss.SysQZSS: {"C1": 'C', "L1": 'C', "D1": 'C', "S1": 'C'},
+	gnss.SysSBAS: {"C1": 'C', "L1": 'C', "D1": 'C', "S1": 'C'},
+	gnss.SysBDS: {
+		"C2": 'I', "L2": 'I', "D2": 'I', "S2": 'I',
+		"C6": 'I', "L6": 'I', "D6": 'I', "S6": 'I',
+		"C7": 'I', "L7": 'I', "D7": 'I', "S7": 'I',
+	},
+}
+
+// DefaultSignalPriorities are the RINEX 3 attributes per system and band in order of preference, used to
+// select the signal if several RINEX 3 codes map onto the same RINEX 2 code, e.g. "L2W" before "L2L" for
+// the GPS "L2". Attributes not listed come last in alphabetical order.
+var DefaultSignalPriorities = map[gnss.System]map[byte]string{
+	gnss.SysGPS:  {'1': "CSLXPWYM", '2': "PWYMDCLSX", '5': "QXI"},
+	gnss.SysGLO:  {'1': "CP", '2': "PC"},
+	gnss.SysGAL:  {'1': "CXBAZ", '5': "QXI", '6': "CXBAZ", '7': "QXI", '8': "QXI"},
+	gnss.SysSBAS: {'1': "C", '5': "QXI"},
+}
+
+// pCodeAttributes are the RINEX 3 attributes of the code observations stored as P1/P2 in RINEX 2.
+var pCodeAttributes = map[gnss.System]string{
+	gnss.SysGPS: "PWYMD",
+	gnss.SysGLO: "P",
+}
+
+// ObsCodeMapper maps the observation codes between RINEX 2, e.g. "P2", and RINEX 3, e.g. "C2W".
+// RINEX 2 codes do not tell the tracking mode, so the mapping to RINEX 3 depends on the receiver
+// and can be configured with the Attributes.
+type ObsCodeMapper struct {
+	// Attributes are the RINEX 3 attributes of the RINEX 2 codes per system, e.g. 'L' for a GPS "L2"
+	// tracked on L2C. Codes not listed get the defaults, see DefaultV2Attributes.
+	Attributes map[gnss.System]map[string]byte
+
+	// Priorities are the RINEX 3 attributes per system and band in order of preference for SelectV2,
+	// e.g. "LW" for the GPS band '2' to prefer L2C. Bands not listed get the DefaultSignalPriorities.
+	Priorities map[gnss.System]map[byte]string
+}
+
+// NewObsCodeMapper returns a mapper with a copy of the DefaultV2Attributes and DefaultSignalPriorities.
+func NewObsCodeMapper() *ObsCodeMapper {
+	attrs := make(map[gnss.System]map[string]byte, len(DefaultV2Attributes))
+	for sys, codes := range DefaultV2Attributes {
+		attrs[sys] = make(map[string]byte, len(codes))
+		for code, attr := range codes {
+			attrs[sys][code] = attr
+		}
+	}
+	prios := make(map[gnss.System]map[byte]string, len(DefaultSignalPriorities))
+	for sys, bands := range DefaultSignalPriorities {
+		prios[sys] = make(map[byte]string, len(bands))
+		for band, attrs := range bands {
+			prios[sys][band] = attrs
+		}
+	}
+	return &ObsCodeMapper{Attributes: attrs, Priorities: prios}
+}
+
+// ToV3 returns the RINEX 3 code of the RINEX 2 observation code for the system, e.g. "C2W" for the GPS "P2".
+func (m *ObsCodeMapper) ToV3(sys gnss.System, code string) (ObsCode, error) {
+	if len(code) != 2 {
+		return "", fmt.Errorf("invalid RINEX 2 observation code: %q", code)
+	}
+	typ, band := code[0], code[1]
+	switch typ {
+	case 'C', 'L', 'D', 'S':
+	case 'P':
+		if pCodeAttributes[sys] == "" {
+			return "", fmt.Errorf("no P-code observations for %v: %q", sys, code)
+		}
+		typ = 'C'
+	default:
+		return "", fmt.Errorf("invalid RINEX 2 observation code: %q", code)
+	}
+
+	attr, ok := m.Attributes[sys][code]
+	if !ok {
+		attr, ok = DefaultV2Attributes[sys][code]
+	}
+	if !ok {
+		if _, ok := signals[sys][string([]byte{band, 'X'})]; ok {
+			attr = 'X'
+		} else {
+			for sig, info := range signals[sys] {
+				if sig[0] == band && (attr == 0 || info.Attribute < attr) {
+					attr = info.Attribute
+				}
+			}
+		}
+	}
+	v3 := ObsCode([]byte{typ, band, attr})
+	if _, ok := v3.Lookup(sys); !ok {
+		return "", fmt.Errorf("no RINEX 3 signal for %v %q", sys, code)
+	}
+	return v3, nil
+}
+
+// ToV2 returns the RINEX 2 code of the RINEX 3 observation code for the system, e.g. "P2" for the GPS
+// "C2W". Several RINEX 3 codes map to the same RINEX 2 code, e.g. "L2W" and "L2L" to "L2".
+func (m *ObsCodeMapper) ToV2(sys gnss.System, code ObsCode) (string, error) {
+	if _, ok := code.Lookup(sys); !ok {
+		return "", fmt.Errorf("invalid observation code for %v: %q", sys, code)
+	}
+	typ := code.Type()
+	switch typ {
+	case 'C':
+		if strings.IndexByte(pCodeAttributes[sys], code.Attribute()) >= 0 {
+			typ = 'P'
+		}
+	case 'L', 'D', 'S':
+	default:
+		return "", fmt.Errorf("no RINEX 2 observation code for %q", code)
+	}
+	return string([]byte{typ, code.Band()}), nil
+}
+
+// SelectV2 selects the RINEX 3 observation codes of the system to be written as RINEX 2 codes. If several
+// codes map onto the same RINEX 2 code, e.g. "L2W" and "L2L" onto "L2", the one with the preferred
+// attribute is taken, see Priorities. It returns the RINEX 2 codes in the order of the RINEX 3 codes and
+// the selected RINEX 3 code for each of them. Codes without a RINEX 2 code are skipped.
+func (m *ObsCodeMapper) SelectV2(sys gnss.System, codes []string) ([]string, map[string]string) {
+	var v2Codes []string
+	selected := make(map[string]string)
+	for _, code := range codes {
+		v2, err := m.ToV2(sys, ObsCode(code))
+		if err != nil {
+			continue
+		}
+		cur, ok := selected[v2]
+		if !ok {
+			v2Codes = append(v2Codes, v2)
+			selected[v2] = code
+			continue
+		}
+		if m.rank(sys, ObsCode(code)) < m.rank(sys, ObsCode(cur)) {
+			selected[v2] = code
+		}
+	}
+	return v2Codes, selected
+}
+
+// rank returns the position of the code's attribute in the priorities of its band, the lower the better.
+func (m *ObsCodeMapper) rank(sys gnss.System, code ObsCode) int {
+	prio, ok := m.Priorities[sys][code.Band()]
+	if !ok {
+		prio = DefaultSignalPriorities[sys][code.Band()]
+	}
+	if i := strings.IndexByte(prio, code.Attribute()); i >= 0 {
+		return i
+	}
+	return len(prio) + int(code.Attribute()) // not listed: alphabetical
+}
+
+// Equal reports whether the observation codes a and b of the system denote the same observable. RINEX 2
+// and RINEX 3 codes can be mixed, e.g. to compare files of different RINEX versions. A RINEX 2 code
+// equals all RINEX 3 codes mapped onto it, e.g. "L2" equals "L2W" and "L2L".
+func (m *ObsCodeMapper) Equal(sys gnss.System, a, b string) bool {
+	if len(a) == len(b) {
+		return a == b
+	}
+	if len(a) == 3 {
+		a, b = b, a
+	}
+	v2, err := m.ToV2(sys, ObsCode(b))
+	return err == nil && v2 == a
+}
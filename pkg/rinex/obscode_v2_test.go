@@ -0,0 +1,91 @@
+package rinex
+
+import (
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsCodeMapper(t *testing.T) {
+	assert := assert.New(t)
+	m := NewObsCodeMapper()
+
+	tests := []struct {
+		sys gnss.System
+		v2  string
+		v3  ObsCode
+	}{
+		{gnss.SysGPS, "C1", "C1C"},
+		{gnss.SysGPS, "P1", "C1W"},
+		{gnss.SysGPS, "L1", "L1C"},
+		{gnss.SysGPS, "P2", "C2W"},
+		{gnss.SysGPS, "C2", "C2X"},
+		{gnss.SysGPS, "L2", "L2W"},
+		{gnss.SysGPS, "S2", "S2W"},
+		{gnss.SysGPS, "C5", "C5X"},
+		{gnss.SysGLO, "P2", "C2P"},
+		{gnss.SysGLO, "L1", "L1C"},
+		{gnss.SysGAL, "L7", "L7X"},
+		{gnss.SysGAL, "C1", "C1X"},
+		{gnss.SysBDS, "L2", "L2I"},
+	}
+	for _, tt := range tests {
+		v3, err := m.ToV3(tt.sys, tt.v2)
+		assert.NoError(err)
+		assert.Equal(tt.v3, v3, "%v %s", tt.sys, tt.v2)
+
+		v2, err := m.ToV2(tt.sys, tt.v3)
+		assert.NoError(err)
+		assert.Equal(tt.v2, v2, "%v %s", tt.sys, tt.v3)
+	}
+
+	// many to one
+	v2, err := m.ToV2(gnss.SysGPS, "L2L")
+	assert.NoError(err)
+	assert.Equal("L2", v2)
+	v2, err = m.ToV2(gnss.SysGPS, "C1P")
+	assert.NoError(err)
+	assert.Equal("P1", v2)
+
+	// configured attribute
+	m.Attributes[gnss.SysGPS]["L2"] = 'L'
+	v3, err := m.ToV3(gnss.SysGPS, "L2")
+	assert.NoError(err)
+	assert.Equal(ObsCode("L2L"), v3)
+	assert.Equal(byte('W'), DefaultV2Attributes[gnss.SysGPS]["L2"], "defaults unchanged")
+	v3, err = (&ObsCodeMapper{}).ToV3(gnss.SysGPS, "L2")
+	assert.NoError(err)
+	assert.Equal(ObsCode("L2W"), v3)
+
+	for _, code := range []string{"P1", "X1", "L", "C3"} {
+		_, err = m.ToV3(gnss.SysGAL, code)
+		assert.Error(err, code)
+	}
+	_, err = m.ToV2(gnss.SysGPS, "X1C")
+	assert.Error(err)
+	_, err = m.ToV2(gnss.SysGAL, "L2W")
+	assert.Error(err)
+
+	assert.True(m.Equal(gnss.SysGPS, "L2", "L2W"))
+	assert.True(m.Equal(gnss.SysGPS, "C2W", "P2"))
+	assert.True(m.Equal(gnss.SysGPS, "L2W", "L2W"))
+	assert.False(m.Equal(gnss.SysGPS, "C2X", "P2"))
+	assert.False(m.Equal(gnss.SysGPS, "L2W", "L2L"))
+}
+
+func TestObsCodeMapper_SelectV2(t *testing.T) {
+	assert := assert.New(t)
+	m := NewObsCodeMapper()
+
+	codes, selected := m.SelectV2(gnss.SysGPS, []string{"C1C", "L1C", "C2L", "C2W", "L2L", "L2W", "C1X", "C6X"})
+	assert.Equal([]string{"C1", "L1", "C2", "P2", "L2"}, codes)
+	assert.Equal(map[string]string{"C1": "C1C", "L1": "L1C", "C2": "C2L", "P2": "C2W", "L2": "L2W"}, selected)
+
+	m.Priorities[gnss.SysGPS]['2'] = "LW"
+	m.Priorities[gnss.SysGPS]['1'] = ""
+	_, selected = m.SelectV2(gnss.SysGPS, []string{"C1X", "C1C", "L2W", "L2L"})
+	assert.Equal("L2L", selected["L2"])
+	assert.Equal("C1C", selected["C1"], "alphabetical without priorities")
+	assert.Equal("PWYMDCLSX", DefaultSignalPriorities[gnss.SysGPS]['2'], "defaults unchanged")
+}
@@ -0,0 +1,241 @@
+package rinex
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fixedHeaderLabels are the header records that can not be patched, because the data records depend on them.
+var fixedHeaderLabels = map[string]bool{
+	"RINEX VERSION / TYPE": true,
+	"CRINEX VERS   / TYPE": true,
+	"CRINEX PROG / DATE":   true,
+	"SYS / # / OBS TYPES":  true,
+	"# / TYPES OF OBSERV":  true,
+	"SYS / SCALE FACTOR":   true,
+	"OBS SCALE FACTOR":     true,
+	"END OF HEADER":        true,
+}
+
+// PatchHeader replaces the header records of the labels in changes, e.g. "MARKER NAME" or "ANT # / TYPE",
+// with the given values of columns 1-60. A value with several lines, separated by "\n", replaces all
+// records of the label, e.g. the COMMENTs, an empty value removes them. Records that are not in the header
+// are added before END OF HEADER. The records the data depends on, like the observation types, can not be
+// patched.
+//
+// In contrast to FixHeader, the other header records are not touched and the file is streamed, so the
+// data records of even large files are copied unchanged with little memory. Gzip and Hatanaka compressed
+// files stay compressed. The History comments are added to the header.
+//
+// Note that Unix compress (.Z) can only be read, so a .Z file is replaced by a gzip file, with the
+// extension .Z changed to .gz, and f.Path is updated. Zip archives are not supported.
+func (f *ObsFile) PatchHeader(changes map[string]string) error {
+	if f.FS != nil {
+		if _, ok := f.FS.(LocalFS); !ok {
+			return fmt.Errorf("patch header: %s: file not on the local disk", f.Path)
+		}
+	}
+	records, labels, err := headerPatches(changes)
+	if err != nil {
+		return fmt.Errorf("patch header: %s: %w", f.Path, err)
+	}
+
+	in, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	br := bufio.NewReader(in)
+	magic, err := br.Peek(len(magicZip))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	r, gzipped, path := br, false, f.Path
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("patch header: %s: %w", f.Path, err)
+		}
+		defer zr.Close()
+		r, gzipped = bufio.NewReader(zr), true
+	case bytes.HasPrefix(magic, magicLZW):
+		zr, err := newLZWReader(br)
+		if err != nil {
+			return fmt.Errorf("patch header: %s: %w", f.Path, err)
+		}
+		defer zr.Close()
+		r, gzipped = bufio.NewReader(zr), true
+		if ext := filepath.Ext(path); strings.EqualFold(ext, ".Z") {
+			path = strings.TrimSuffix(path, ext) + ".gz"
+		}
+	case bytes.HasPrefix(magic, magicZip):
+		return fmt.Errorf("patch header: %s: zip archives are not supported", f.Path)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), ".patchheader-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	var w io.Writer = tmp
+	var zw *gzip.Writer
+	if gzipped {
+		zw = gzip.NewWriter(tmp)
+		w = zw
+	}
+	bw := bufio.NewWriter(w)
+	history := DefaultHistory.Comments("patch header", []string{filepath.Base(f.Path)}, time.Now(),
+		"records: "+strings.Join(labels, ", "))
+	hdr, err := patchHeader(r, bw, records, labels, history)
+	if err == nil {
+		_, err = io.Copy(bw, r)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if zw != nil {
+		if err2 := zw.Close(); err == nil {
+			err = err2
+		}
+	}
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("patch header: %s: %w", f.Path, err)
+	}
+
+	dec, err := NewObsDecoder(bytes.NewReader(hdr))
+	if err != nil {
+		return fmt.Errorf("patch header: %s: invalid header: %w", f.Path, err)
+	}
+	if fi, err := os.Stat(f.Path); err == nil {
+		os.Chmod(tmp.Name(), fi.Mode())
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if path != f.Path {
+		if err := os.Remove(f.Path); err != nil {
+			return err
+		}
+		f.Path, f.Compression = path, "gz"
+	}
+	f.Header = dec.Header
+	return nil
+}
+
+// headerPatches checks the changes and returns the values per label and the sorted labels.
+func headerPatches(changes map[string]string) (map[string][]string, []string, error) {
+	if len(changes) == 0 {
+		return nil, nil, fmt.Errorf("no header records to patch")
+	}
+	records := make(map[string][]string, len(changes))
+	labels := make([]string, 0, len(changes))
+	for label, val := range changes {
+		label = strings.TrimSpace(label)
+		if label == "" || len(label) > 20 {
+			return nil, nil, fmt.Errorf("invalid header label: %q", label)
+		}
+		if fixedHeaderLabels[label] {
+			return nil, nil, fmt.Errorf("header record %q can not be patched", label)
+		}
+		var vals []string
+		if val != "" {
+			vals = strings.Split(strings.TrimRight(val, "\r\n"), "\n")
+		}
+		for i, v := range vals {
+			v = strings.TrimRight(v, "\r")
+			if len(v) > 60 {
+				return nil, nil, fmt.Errorf("%s: value longer than 60 characters: %q", label, v)
+			}
+			vals[i] = v
+		}
+		records[label] = vals
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return records, labels, nil
+}
+
+// patchHeader copies the header from r to w with the records replaced, up to and including END OF HEADER,
+// and returns the RINEX header that was written, i.e. without the CRINEX records. The history comments
+// are added before END OF HEADER. The line endings of the file are kept.
+func patchHeader(r *bufio.Reader, w io.Writer, records map[string][]string, labels, history []string) ([]byte, error) {
+	var hdr bytes.Buffer
+	eol := "\n"
+	done := make(map[string]bool, len(records))
+	write := func(line, label string) error {
+		if !strings.HasPrefix(label, "CRINEX") {
+			hdr.WriteString(line)
+		}
+		_, err := io.WriteString(w, line)
+		return err
+	}
+	writeRecord := func(val, label string) error { return write(fmt.Sprintf("%-60s%-20s%s", val, label, eol), label) }
+
+	for n := 0; ; n++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("no END OF HEADER")
+			}
+			return nil, err
+		}
+		if n == 0 && strings.HasSuffix(line, "\r\n") {
+			eol = "\r\n"
+		}
+		label := ""
+		if content := strings.TrimRight(line, "\r\n"); len(content) > 60 {
+			label = strings.TrimSpace(content[60:])
+		}
+
+		if vals, ok := records[label]; ok {
+			if !done[label] {
+				done[label] = true
+				for _, v := range vals {
+					if err := writeRecord(v, label); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		}
+		if label != "END OF HEADER" {
+			if err := write(line, label); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, label := range labels {
+			if done[label] {
+				continue
+			}
+			for _, v := range records[label] {
+				if err := writeRecord(v, label); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, c := range history {
+			if err := writeRecord(c, "COMMENT"); err != nil {
+				return nil, err
+			}
+		}
+		if err := write(line, label); err != nil {
+			return nil, err
+		}
+		return hdr.Bytes(), nil
+	}
+}
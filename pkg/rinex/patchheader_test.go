@@ -0,0 +1,121 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsFile_PatchHeader(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	body := func(b []byte) []byte {
+		b = b[bytes.Index(b, []byte("END OF HEADER")):]
+		return b[bytes.IndexByte(b, '\n'):]
+	}
+	changes := map[string]string{
+		"MARKER NAME":  "REYK00ISL",
+		"ANT # / TYPE": "725281              LEIAR25.R4      NONE",
+		"MARKER TYPE":  "GEODETIC",
+		"COMMENT":      "",
+	}
+
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	path := filepath.Join(dir, filepath.Base(reykFile))
+	assert.NoError(ioutil.WriteFile(path, data, 0644))
+	obsFil, err := NewObsFile(path)
+	assert.NoError(err)
+	if !assert.NoError(obsFil.PatchHeader(changes)) {
+		return
+	}
+	assert.Equal("REYK00ISL", obsFil.Header.MarkerName)
+	assert.Equal("LEIAR25.R4      NONE", obsFil.Header.AntennaType)
+	assert.Equal("GEODETIC", obsFil.Header.MarkerType)
+	assert.Equal("LEICA GR50", obsFil.Header.ReceiverType)
+	if assert.NotEmpty(obsFil.Header.Comments) {
+		assert.True(strings.Contains(obsFil.Header.Comments[0], "patch header"), "only the history comments")
+	}
+	patched, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+	assert.Contains(string(patched), "GEODETIC                                                    MARKER TYPE         \n"+
+		"gognss              patch header")
+	assert.Equal(body(data), body(patched))
+
+	// gzip stays gzip
+	gzPath := filepath.Join(dir, filepath.Base(reykFile)+".gz")
+	assert.NoError(CompressFile(reykFile, gzPath))
+	gz, err := NewObsFile(gzPath)
+	assert.NoError(err)
+	assert.NoError(gz.PatchHeader(map[string]string{"MARKER NAME": "REYK00ISL"}))
+	assert.Equal("REYK00ISL", gz.Header.MarkerName)
+	r, err := OpenFile(gzPath)
+	if assert.NoError(err) {
+		unzipped, err := ioutil.ReadAll(r)
+		r.Close()
+		assert.NoError(err)
+		assert.Equal(body(data), body(unzipped))
+	}
+	assert.True(isGzip(t, gzPath))
+
+	// .Z is replaced by gzip
+	data, err = ioutil.ReadFile("testdata/compress/brst155h.20o.Z")
+	assert.NoError(err)
+	zPath := filepath.Join(dir, "brst155h.20o.Z")
+	assert.NoError(ioutil.WriteFile(zPath, data, 0644))
+	z, err := NewObsFile(zPath)
+	assert.NoError(err)
+	assert.NoError(z.PatchHeader(map[string]string{"MARKER NAME": "BRST"}))
+	assert.Equal(filepath.Join(dir, "brst155h.20o.gz"), z.Path)
+	assert.Equal("gz", z.Compression)
+	assert.Equal("BRST", z.Header.MarkerName)
+	assert.True(isGzip(t, z.Path))
+	assert.NoFileExists(zPath)
+	want, err := ioutil.ReadFile("testdata/white/brst155h.20o")
+	assert.NoError(err)
+	r, err = OpenFile(z.Path)
+	if assert.NoError(err) {
+		unzipped, err := ioutil.ReadAll(r)
+		r.Close()
+		assert.NoError(err)
+		assert.True(bytes.HasPrefix(body(want), body(unzipped)), "data records")
+	}
+
+	// Hatanaka compressed
+	crxFile := "testdata/white/BRUX00BEL_R_20202302000_01H_30S_MO.crx"
+	data, err = ioutil.ReadFile(crxFile)
+	assert.NoError(err)
+	crxPath := filepath.Join(dir, filepath.Base(crxFile))
+	assert.NoError(ioutil.WriteFile(crxPath, data, 0644))
+	crx, err := NewObsFile(crxPath)
+	assert.NoError(err)
+	assert.NoError(crx.PatchHeader(map[string]string{"OBSERVER / AGENCY": "Automatic           BKG"}))
+	assert.Equal("BKG", crx.Header.Agency)
+	patched, err = ioutil.ReadFile(crxPath)
+	assert.NoError(err)
+	assert.True(bytes.HasPrefix(patched, data[:bytes.Index(data, []byte("RINEX VERSION"))]), "CRINEX records")
+	assert.Equal(body(data), body(patched))
+
+	for _, changes := range []map[string]string{
+		nil,
+		{"SYS / # / OBS TYPES": "G    1 C1C"},
+		{"END OF HEADER": ""},
+		{"MARKER NAME": strings.Repeat("X", 61)},
+		{"APPROX POSITION XYZ": "invalid"},
+	} {
+		assert.Error(obsFil.PatchHeader(changes), "%v", changes)
+	}
+}
+
+// isGzip returns true if the file starts with the gzip magic number.
+func isGzip(t *testing.T, path string) bool {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.HasPrefix(b, magicGzip)
+}
@@ -0,0 +1,85 @@
+package rinex
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// DefaultMaxPositionDiff is the default threshold in meters to flag the header position, see WithPositionCheck.
+// A single point position without atmospheric corrections is accurate to some 10 m.
+const DefaultMaxPositionDiff = 100.0
+
+// positionCheckInterval is the minimum time between the epochs used for the position check.
+const positionCheckInterval = time.Minute
+
+// Positioner computes the receiver position of an epoch, e.g. the single point position of spp.Solver.
+type Positioner interface {
+	Position(epo *Epoch) (Coord, error)
+}
+
+// PositionCheck is the result of the plausibility check of the header's APPROX POSITION XYZ.
+type PositionCheck struct {
+	Approx    Coord   `json:"approx"`    // header position
+	Estimated Coord   `json:"estimated"` // median of the epoch positions
+	Distance  float64 `json:"distance"`  // distance between the header and the estimated position in meters
+	NumEpochs int     `json:"numEpochs"` // number of epochs with a position
+	MaxDiff   float64 `json:"maxDiff"`   // threshold in meters
+	OK        bool    `json:"ok"`        // the header position is set and within the threshold
+}
+
+// PositionChecker collects the positions of the epochs, at most one per minute, to compare their median
+// with the header's APPROX POSITION XYZ, see spp.Solver.CheckPosition. Differences beyond the threshold,
+// e.g. for wrongly labeled files, are flagged by PositionCheck.OK being false.
+type PositionChecker struct {
+	pos     Positioner
+	last    time.Time
+	x, y, z []float64
+}
+
+// NewPositionChecker returns a PositionChecker computing the positions with pos.
+func NewPositionChecker(pos Positioner) *PositionChecker {
+	return &PositionChecker{pos: pos}
+}
+
+// Add adds the position of the epoch. Event epochs and epochs without a position are skipped.
+func (pc *PositionChecker) Add(epo *Epoch) {
+	if epo.IsEvent() || epo.IsCycleSlip() {
+		return
+	}
+	if !pc.last.IsZero() && epo.Time.Sub(pc.last) < positionCheckInterval {
+		return
+	}
+	pos, err := pc.pos.Position(epo)
+	if err != nil {
+		return
+	}
+	pc.last = epo.Time
+	pc.x, pc.y, pc.z = append(pc.x, pos.X), append(pc.y, pos.Y), append(pc.z, pos.Z)
+}
+
+// Result compares the median of the positions with the header position approx.
+func (pc *PositionChecker) Result(approx Coord, maxDiff float64) (*PositionCheck, error) {
+	if len(pc.x) == 0 {
+		return nil, errors.New("position check: no epoch with a position")
+	}
+	chk := &PositionCheck{
+		Approx:    approx,
+		Estimated: Coord{X: median(pc.x), Y: median(pc.y), Z: median(pc.z)},
+		NumEpochs: len(pc.x),
+		MaxDiff:   maxDiff,
+	}
+	chk.Distance = chk.Estimated.Distance(approx)
+	chk.OK = approx != (Coord{}) && chk.Distance <= maxDiff
+	return chk, nil
+}
+
+// median returns the median of the values. The slice is sorted in place.
+func median(vals []float64) float64 {
+	sort.Float64s(vals)
+	n := len(vals)
+	if n%2 == 1 {
+		return vals[n/2]
+	}
+	return (vals[n/2-1] + vals[n/2]) / 2
+}
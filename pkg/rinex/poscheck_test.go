@@ -0,0 +1,96 @@
+package rinex
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedPositioner returns the same position for every epoch with observations.
+type fixedPositioner struct {
+	pos   Coord
+	calls int
+}
+
+func (p *fixedPositioner) Position(epo *Epoch) (Coord, error) {
+	p.calls++
+	if len(epo.ObsList) == 0 {
+		return Coord{}, errors.New("no observations")
+	}
+	return p.pos, nil
+}
+
+func TestPositionChecker(t *testing.T) {
+	assert := assert.New(t)
+	approx := Coord{X: 4075580.3, Y: 931853.9, Z: 4801568.2}
+	pos := &fixedPositioner{pos: Coord{X: approx.X + 30, Y: approx.Y, Z: approx.Z + 40}}
+	pc := NewPositionChecker(pos)
+	_, err := pc.Result(approx, DefaultMaxPositionDiff)
+	assert.Error(err, "no epochs")
+
+	obs := []SatObs{NewSatObs(PRN{Sys: gnss.SysGPS, Num: 1}, map[string]Obs{"C1C": {Val: 2e7}})}
+	t0 := time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC)
+	pc.Add(&Epoch{Time: t0})                                                                   // no position
+	pc.Add(&Epoch{Time: t0.Add(30 * time.Second), ObsList: obs})                               // used
+	pc.Add(&Epoch{Time: t0.Add(60 * time.Second), ObsList: obs})                               // within a minute
+	pc.Add(&Epoch{Time: t0.Add(90 * time.Second), ObsList: obs, Flag: EpochFlagExternalEvent}) // event
+	pc.Add(&Epoch{Time: t0.Add(90 * time.Second), ObsList: obs})                               // used
+	assert.Equal(3, pos.calls)
+
+	chk, err := pc.Result(approx, DefaultMaxPositionDiff)
+	if assert.NoError(err) {
+		assert.Equal(2, chk.NumEpochs)
+		assert.Equal(pos.pos, chk.Estimated)
+		assert.InDelta(50.0, chk.Distance, 1e-6)
+		assert.True(chk.OK)
+	}
+	chk, err = pc.Result(approx, 10)
+	if assert.NoError(err) {
+		assert.False(chk.OK, "beyond the threshold")
+	}
+	chk, err = pc.Result(Coord{}, DefaultMaxPositionDiff)
+	if assert.NoError(err) {
+		assert.False(chk.OK, "no header position")
+	}
+}
+
+func TestNewReport_positionCheck(t *testing.T) {
+	assert := assert.New(t)
+	obs, err := NewObsFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := obs.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(rep.Position, "no check without WithPositionCheck")
+
+	r, err := obs.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dec, err := NewObsDecoder(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := dec.Header.Position
+	wrong.X += 2000
+	rep, err = NewReport(dec, WithPositionCheck(&fixedPositioner{pos: wrong}, DefaultMaxPositionDiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(rep.Position) {
+		assert.Equal(60, rep.Position.NumEpochs)
+		assert.False(rep.Position.OK)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(rep.Write(&buf, ReportText))
+	assert.Contains(buf.String(), "Position:      CHECK, 2000.0 m from the header position")
+}
@@ -0,0 +1,160 @@
+package rinex
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Progress receives the progress of long running operations, e.g. to render a progress bar or to detect
+// stalled jobs. The decoders report the bytes read and the processed epochs if DecoderOptions.Progress is
+// set, see WithProgress, and with them ConvertObs, MergeObs, CropObs, SplitObs and MergeNav. The
+// compression reports the bytes read if CompressOptions.Progress is set.
+//
+// The methods are called from the goroutines of the operations, so implementations must be safe for
+// concurrent use, e.g. if several decoders share a Progress. ProgressTracker is such an implementation.
+type Progress interface {
+	AddTotal(n int64) // adds the expected number of bytes of an input, e.g. the size of a file
+	AddBytes(n int64) // adds the number of bytes read
+	AddEpochs(n int)  // adds the number of processed epochs
+}
+
+// ProgressStatus is a snapshot of the progress.
+type ProgressStatus struct {
+	Bytes   int64         // bytes read
+	Total   int64         // expected bytes, 0 if unknown
+	Epochs  int64         // processed epochs
+	Elapsed time.Duration // since the tracker was created
+	ETA     time.Duration // estimated remaining time based on the bytes, 0 if unknown
+	Updated time.Time     // time of the last progress, the creation time if none
+}
+
+// Percent returns the percentage of the bytes read, or -1 if the total is unknown.
+func (s ProgressStatus) Percent() float64 {
+	if s.Total <= 0 {
+		return -1
+	}
+	if s.Bytes >= s.Total {
+		return 100
+	}
+	return 100 * float64(s.Bytes) / float64(s.Total)
+}
+
+// ProgressTracker is a Progress that counts the bytes and epochs. It is safe for concurrent use, the
+// operations update it while e.g. a goroutine rendering a progress bar reads its Status.
+type ProgressTracker struct {
+	// the 64-bit words are accessed atomically and must stay 64-bit aligned
+	bytes   int64
+	total   int64
+	epochs  int64
+	updated int64 // unix nanoseconds of the last progress
+
+	start time.Time
+	now   func() time.Time
+}
+
+// NewProgressTracker returns a ProgressTracker that starts now.
+func NewProgressTracker() *ProgressTracker {
+	return newProgressTracker(time.Now)
+}
+
+func newProgressTracker(now func() time.Time) *ProgressTracker {
+	start := now()
+	return &ProgressTracker{start: start, updated: start.UnixNano(), now: now}
+}
+
+// AddTotal implements Progress.
+func (t *ProgressTracker) AddTotal(n int64) {
+	atomic.AddInt64(&t.total, n)
+}
+
+// AddBytes implements Progress.
+func (t *ProgressTracker) AddBytes(n int64) {
+	atomic.AddInt64(&t.bytes, n)
+	t.touch()
+}
+
+// AddEpochs implements Progress.
+func (t *ProgressTracker) AddEpochs(n int) {
+	atomic.AddInt64(&t.epochs, int64(n))
+	t.touch()
+}
+
+func (t *ProgressTracker) touch() {
+	atomic.StoreInt64(&t.updated, t.now().UnixNano())
+}
+
+// Status returns the current progress.
+func (t *ProgressTracker) Status() ProgressStatus {
+	s := ProgressStatus{
+		Bytes:   atomic.LoadInt64(&t.bytes),
+		Total:   atomic.LoadInt64(&t.total),
+		Epochs:  atomic.LoadInt64(&t.epochs),
+		Elapsed: t.now().Sub(t.start),
+		Updated: time.Unix(0, atomic.LoadInt64(&t.updated)),
+	}
+	if s.Total > 0 && s.Bytes > 0 && s.Bytes < s.Total {
+		s.ETA = time.Duration(float64(s.Elapsed) * float64(s.Total-s.Bytes) / float64(s.Bytes))
+	}
+	return s
+}
+
+// Stalled returns true if there was no progress for longer than d.
+func (t *ProgressTracker) Stalled(d time.Duration) bool {
+	return t.now().Sub(time.Unix(0, atomic.LoadInt64(&t.updated))) > d
+}
+
+// NewProgressReader returns a reader that reports the bytes read from r to p, e.g. to track the bytes of
+// a compressed file before decompressing it. The size of the file has to be added with AddTotal.
+func NewProgressReader(r io.Reader, p Progress) io.Reader {
+	return &progressReader{r: r, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p Progress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if n > 0 {
+		pr.p.AddBytes(int64(n))
+	}
+	return n, err
+}
+
+// OpenFileWithProgress opens the file like OpenFile and reports its size and the bytes read from the
+// file to p, so that the progress of compressed files refers to the compressed bytes. Decoders reading
+// from the returned reader with p, see WithProgress, only add the epochs.
+func OpenFileWithProgress(path string, p Progress) (io.ReadCloser, error) {
+	rc, err := openFile(path, p)
+	if err != nil {
+		return nil, err
+	}
+	return &progressFile{rc}, nil
+}
+
+// progressFile is a file that reports the bytes read itself, see OpenFileWithProgress.
+type progressFile struct {
+	io.ReadCloser
+}
+
+// withProgress returns r, reporting the bytes read to p if it is not nil and r does not report them itself.
+func withProgress(r io.Reader, p Progress) io.Reader {
+	if _, ok := r.(*progressFile); p == nil || ok {
+		return r
+	}
+	return NewProgressReader(r, p)
+}
+
+// addFileSize adds the size of r to p if it is a regular file.
+func addFileSize(r io.Reader, p Progress) {
+	f, ok := r.(interface{ Stat() (os.FileInfo, error) }) // not for a progressFile
+	if p == nil || !ok {
+		return
+	}
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		p.AddTotal(fi.Size())
+	}
+}
@@ -0,0 +1,116 @@
+package rinex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressTracker(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2020, 6, 3, 12, 0, 0, 0, time.UTC)
+	tr := newProgressTracker(func() time.Time { return now })
+	assert.Equal(-1.0, tr.Status().Percent())
+
+	tr.AddTotal(1000)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				tr.AddBytes(1)
+				tr.AddEpochs(1)
+			}
+		}()
+	}
+	wg.Wait()
+	now = now.Add(10 * time.Second)
+	st := tr.Status()
+	assert.Equal(int64(250), st.Bytes)
+	assert.Equal(int64(250), st.Epochs)
+	assert.Equal(25.0, st.Percent())
+	assert.Equal(10*time.Second, st.Elapsed)
+	assert.Equal(30*time.Second, st.ETA)
+	assert.True(tr.Stalled(5 * time.Second))
+	assert.False(tr.Stalled(time.Minute))
+
+	tr.AddBytes(750)
+	st = tr.Status()
+	assert.Equal(100.0, st.Percent())
+	assert.Equal(time.Duration(0), st.ETA)
+	assert.Equal(now, st.Updated.UTC())
+	assert.False(tr.Stalled(time.Second))
+}
+
+func TestObsDecoder_progress(t *testing.T) {
+	assert := assert.New(t)
+	fi, err := os.Stat(reykFile)
+	if !assert.NoError(err) {
+		return
+	}
+	for _, workers := range []int{0, 4} {
+		f, err := os.Open(reykFile)
+		if !assert.NoError(err) {
+			return
+		}
+		tr := NewProgressTracker()
+		dec, err := NewObsDecoder(f, WithProgress(tr))
+		assert.NoError(err)
+		dec.Workers = workers
+		n := 0
+		for dec.NextEpoch() {
+			n++
+		}
+		f.Close()
+		assert.NoError(dec.Err())
+		st := tr.Status()
+		assert.Equal(int64(n), st.Epochs, "workers %d", workers)
+		assert.Equal(fi.Size(), st.Total)
+		assert.Equal(fi.Size(), st.Bytes)
+		assert.True(n > 0)
+	}
+
+	// compressed bytes
+	gzPath := filepath.Join(t.TempDir(), filepath.Base(reykFile)+".gz")
+	assert.NoError(CompressFile(reykFile, gzPath))
+	fi, err = os.Stat(gzPath)
+	assert.NoError(err)
+	tr := NewProgressTracker()
+	r, err := OpenFileWithProgress(gzPath, tr)
+	if !assert.NoError(err) {
+		return
+	}
+	defer r.Close()
+	dec, err := NewObsDecoder(r, WithProgress(tr))
+	assert.NoError(err)
+	for dec.NextEpoch() {
+	}
+	st := tr.Status()
+	assert.Equal(fi.Size(), st.Total)
+	assert.Equal(fi.Size(), st.Bytes)
+	assert.Equal(int64(120), st.Epochs)
+}
+
+func TestCompress_progress(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ioutil.ReadFile("testdata/white/AREG00PER_R_20201690000_01D_MN.rnx")
+	assert.NoError(err)
+	path := filepath.Join(t.TempDir(), "AREG00PER_R_20201690000_01D_MN.rnx")
+	assert.NoError(ioutil.WriteFile(path, data, 0644))
+
+	tr := NewProgressTracker()
+	navFil, err := NewNavFile(path)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(navFil.CompressWithOptions(CompressOptions{Progress: tr}))
+	st := tr.Status()
+	assert.Equal(int64(len(data)), st.Total)
+	assert.Equal(int64(len(data)), st.Bytes)
+}
@@ -0,0 +1,314 @@
+package rinex
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// ReportFormat is the output format of a Report.
+type ReportFormat int
+
+// Available report formats.
+const (
+	ReportText ReportFormat = iota
+	ReportJSON
+	ReportHTML
+)
+
+// ParseReportFormat returns the report format for its name: text, json or html.
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch strings.ToLower(s) {
+	case "text", "txt", "":
+		return ReportText, nil
+	case "json":
+		return ReportJSON, nil
+	case "html":
+		return ReportHTML, nil
+	}
+	return ReportText, fmt.Errorf("invalid report format: %q", s)
+}
+
+// Report is a summary of an observation file for station monitoring. It can be written as plain text,
+// JSON or HTML.
+type Report struct {
+	File         string          `json:"file,omitempty"`
+	MarkerName   string          `json:"markerName"`
+	ReceiverType string          `json:"receiverType"`
+	AntennaType  string          `json:"antennaType"`
+	RINEXVersion float32         `json:"rinexVersion"`
+	Stat         ObsStat         `json:"stat"`
+	Expected     int             `json:"expectedEpochs"` // number of epochs expected between the first and last epoch
+	Availability float64         `json:"availability"`   // percentage of the expected epochs found
+	NumGaps      int             `json:"numGaps"`
+	Systems      []SystemReport  `json:"systems"`
+	Position     *PositionCheck  `json:"position,omitempty"`    // only with WithPositionCheck
+	Ephemerides  *EphemerisCheck `json:"ephemerides,omitempty"` // only if the decoder has an EphemerisStore
+	Created      time.Time       `json:"created"`
+}
+
+// SystemReport contains the statistics of a satellite system.
+type SystemReport struct {
+	Sys       gnss.System    `json:"-"`
+	System    string         `json:"system"`    // abbreviation, e.g. G
+	NumSats   int            `json:"numSats"`   // number of different satellites
+	SatEpochs int            `json:"satEpochs"` // number of satellite epochs
+	Signals   []SignalReport `json:"signals"`
+}
+
+// SignalReport contains the statistics of an observation type.
+type SignalReport struct {
+	ObsType      string  `json:"obsType"`
+	NumObs       int     `json:"numObs"`            // number of observations, blank ones are not counted
+	Completeness float64 `json:"completeness"`      // percentage of the satellite epochs with an observation
+	NumLLI       int     `json:"numLLI"`            // number of observations with loss of lock
+	MeanSNR      float64 `json:"meanSNR,omitempty"` // mean value of signal strength types (S)
+}
+
+// ReportOption configures NewReport.
+type ReportOption func(*reportConfig)
+
+type reportConfig struct {
+	positioner Positioner
+	maxDiff    float64
+}
+
+// WithPositionCheck checks the header position with the positions of pos and the threshold maxDiff
+// in meters, see PositionChecker.
+func WithPositionCheck(pos Positioner, maxDiff float64) ReportOption {
+	return func(cfg *reportConfig) {
+		cfg.positioner, cfg.maxDiff = pos, maxDiff
+	}
+}
+
+// NewReport reads the epochs of the decoder and returns the report. Event epochs are skipped.
+// If the decoder has an EphemerisStore, the observed satellites are checked with CheckEphemerides.
+// The header position is only checked with the option WithPositionCheck.
+func NewReport(dec *ObsDecoder, opts ...ReportOption) (*Report, error) {
+	var cfg reportConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	hdr := &dec.Header
+	rep := &Report{
+		MarkerName:   hdr.MarkerName,
+		ReceiverType: hdr.ReceiverType,
+		AntennaType:  hdr.AntennaType,
+		RINEXVersion: hdr.RINEXVersion,
+		Created:      time.Now().UTC(),
+	}
+
+	type sigCount struct {
+		n, lli int
+		snr    float64
+	}
+	type sysCount struct {
+		sats      map[PRN]bool
+		satEpochs int
+		sigs      map[string]*sigCount
+	}
+	counts := make(map[gnss.System]*sysCount)
+	var times []time.Time
+	var pc *PositionChecker
+	if cfg.positioner != nil {
+		pc = NewPositionChecker(cfg.positioner)
+	}
+	var ec *ephemerisChecker
+	if dec.EphemerisStore != nil {
+		ec = newEphemerisChecker(dec)
+	}
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if epo.IsEvent() || epo.IsCycleSlip() {
+			continue
+		}
+		times = append(times, epo.Time)
+		if pc != nil {
+			pc.Add(epo)
+		}
+		if ec != nil {
+			ec.add(epo)
+		}
+		for _, satObs := range epo.ObsList {
+			sc, ok := counts[satObs.Prn.Sys]
+			if !ok {
+				sc = &sysCount{sats: make(map[PRN]bool), sigs: make(map[string]*sigCount)}
+				counts[satObs.Prn.Sys] = sc
+			}
+			sc.sats[satObs.Prn] = true
+			sc.satEpochs++
+			for i, obs := range satObs.Obss {
+				if obs.Val == 0 {
+					continue
+				}
+				typ := satObs.Types[i]
+				c, ok := sc.sigs[typ]
+				if !ok {
+					c = &sigCount{}
+					sc.sigs[typ] = c
+				}
+				c.n++
+				if obs.LLI&1 != 0 {
+					c.lli++
+				}
+				c.snr += obs.Val
+			}
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read epochs: %w", err)
+	}
+
+	gaps := Gaps(times, time.Duration(hdr.Interval*float64(time.Second)))
+	rep.Stat = ObsStat{NumEpochs: gaps.NumEpochs, Sampling: int(gaps.Interval / time.Second),
+		TimeOfFirstObs: gaps.Start, TimeOfLastObs: gaps.End}
+	rep.Expected, rep.Availability, rep.NumGaps = gaps.ExpectedEpochs, gaps.Availability, len(gaps.Gaps)
+	if pc != nil {
+		// no position without GPS pseudoranges, which is not an error of the report
+		rep.Position, _ = pc.Result(hdr.Position, cfg.maxDiff)
+	}
+	if ec != nil {
+		rep.Ephemerides = ec.result()
+	}
+
+	for sys, sc := range counts {
+		sr := SystemReport{Sys: sys, System: sys.Abbr(), NumSats: len(sc.sats), SatEpochs: sc.satEpochs}
+		types := hdr.ObsTypes[sys]
+		for typ := range sc.sigs {
+			if indexOf(types, typ) < 0 {
+				types = append(types, typ)
+			}
+		}
+		for _, typ := range types {
+			sig := SignalReport{ObsType: typ}
+			if c, ok := sc.sigs[typ]; ok {
+				sig.NumObs, sig.NumLLI = c.n, c.lli
+				if strings.HasPrefix(typ, "S") {
+					sig.MeanSNR = c.snr / float64(c.n)
+				}
+			}
+			if sc.satEpochs > 0 {
+				sig.Completeness = float64(sig.NumObs) / float64(sc.satEpochs) * 100
+			}
+			sr.Signals = append(sr.Signals, sig)
+		}
+		rep.Systems = append(rep.Systems, sr)
+	}
+	sort.Slice(rep.Systems, func(i, j int) bool { return rep.Systems[i].Sys < rep.Systems[j].Sys })
+	return rep, nil
+}
+
+// Report reads the file and returns its report.
+func (f *ObsFile) Report() (*Report, error) {
+	r, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	dec, err := NewObsDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := NewReport(dec)
+	if err != nil {
+		return nil, err
+	}
+	rep.File = f.Path
+	return rep, nil
+}
+
+// Write writes the report in the given format.
+func (rep *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	case ReportHTML:
+		return reportHTMLTmpl.Execute(w, rep)
+	case ReportText:
+		return reportTextTmpl.Execute(w, rep)
+	}
+	return fmt.Errorf("invalid report format: %d", format)
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+var reportFuncs = map[string]interface{}{
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+}
+
+var reportTextTmpl = template.Must(template.New("text").Funcs(reportFuncs).Parse(
+	`{{if .File}}File:          {{.File}}
+{{end}}Marker:        {{.MarkerName}}
+Receiver:      {{.ReceiverType}}
+Antenna:       {{.AntennaType}}
+RINEX version: {{printf "%.2f" .RINEXVersion}}
+First epoch:   {{time .Stat.TimeOfFirstObs}}
+Last epoch:    {{time .Stat.TimeOfLastObs}}
+Sampling:      {{.Stat.Sampling}} s
+Epochs:        {{.Stat.NumEpochs}} of {{.Expected}} ({{printf "%.2f" .Availability}} %), {{.NumGaps}} gaps
+{{with .Position}}Position:      {{if .OK}}ok{{else}}CHECK{{end}}, {{printf "%.1f" .Distance}} m from the header position ({{.NumEpochs}} epochs)
+{{end}}{{with .Ephemerides}}Ephemerides:   {{if .OK}}ok{{else}}CHECK{{end}}, {{len .Missing}} satellites without ephemeris, {{len .NotObserved}} not observed
+{{range .Missing}}  no ephemeris: {{.Sat}} {{.Epochs}} epochs, {{time .First}} - {{time .Last}}
+{{end}}{{range .NotObserved}}  not observed: {{.Sat}} {{.Epochs}} epochs, {{time .First}} - {{time .Last}}
+{{end}}{{end}}{{range .Systems}}
+System {{.System}}: {{.NumSats}} satellites, {{.SatEpochs}} satellite epochs
+Type     #Obs  Compl. %   #LLI   Mean SNR
+{{range .Signals}}{{printf "%-4s %8d %9.2f %6d" .ObsType .NumObs .Completeness .NumLLI}}{{if .MeanSNR}}{{printf " %10.2f" .MeanSNR}}{{end}}
+{{end}}{{end}}`))
+
+var reportHTMLTmpl = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>RINEX report {{.MarkerName}}</title>
+<style>
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 2px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>RINEX report {{.MarkerName}}</h1>
+<table>
+{{if .File}}<tr><td>File</td><td>{{.File}}</td></tr>
+{{end}}<tr><td>Receiver</td><td>{{.ReceiverType}}</td></tr>
+<tr><td>Antenna</td><td>{{.AntennaType}}</td></tr>
+<tr><td>RINEX version</td><td>{{printf "%.2f" .RINEXVersion}}</td></tr>
+<tr><td>First epoch</td><td>{{time .Stat.TimeOfFirstObs}}</td></tr>
+<tr><td>Last epoch</td><td>{{time .Stat.TimeOfLastObs}}</td></tr>
+<tr><td>Sampling</td><td>{{.Stat.Sampling}} s</td></tr>
+<tr><td>Epochs</td><td>{{.Stat.NumEpochs}} of {{.Expected}} ({{printf "%.2f" .Availability}} %)</td></tr>
+<tr><td>Gaps</td><td>{{.NumGaps}}</td></tr>
+{{with .Position}}<tr><td>Position</td><td>{{if .OK}}ok{{else}}CHECK{{end}}, {{printf "%.1f" .Distance}} m from the header position</td></tr>
+{{end}}{{with .Ephemerides}}<tr><td>Ephemerides</td><td>{{if .OK}}ok{{else}}CHECK{{end}}, {{len .Missing}} satellites without ephemeris, {{len .NotObserved}} not observed</td></tr>
+{{range .Missing}}<tr><td>No ephemeris</td><td>{{.Sat}}: {{.Epochs}} epochs, {{time .First}} - {{time .Last}}</td></tr>
+{{end}}{{range .NotObserved}}<tr><td>Not observed</td><td>{{.Sat}}: {{.Epochs}} epochs, {{time .First}} - {{time .Last}}</td></tr>
+{{end}}{{end}}</table>
+{{range .Systems}}
+<h2>System {{.System}}</h2>
+<p>{{.NumSats}} satellites, {{.SatEpochs}} satellite epochs</p>
+<table>
+<tr><th>Type</th><th>#Obs</th><th>Completeness %</th><th>#LLI</th><th>Mean SNR</th></tr>
+{{range .Signals}}<tr><td>{{.ObsType}}</td><td>{{.NumObs}}</td><td>{{printf "%.2f" .Completeness}}</td><td>{{.NumLLI}}</td><td>{{if .MeanSNR}}{{printf "%.2f" .MeanSNR}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
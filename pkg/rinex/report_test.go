@@ -0,0 +1,52 @@
+package rinex
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObsFile_Report(t *testing.T) {
+	assert := assert.New(t)
+	obs, err := NewObsFile("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := obs.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("REYK", rep.MarkerName[:4])
+	assert.Equal(120, rep.Stat.NumEpochs)
+	assert.Equal(30, rep.Stat.Sampling)
+	assert.Equal(100.0, rep.Availability)
+	if assert.True(len(rep.Systems) > 1) {
+		gps := rep.Systems[0]
+		assert.Equal("G", gps.System)
+		assert.True(gps.NumSats > 0)
+		assert.Equal(gps.Signals[0].ObsType, "C1C")
+		assert.True(gps.Signals[0].Completeness > 90)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(rep.Write(&buf, ReportJSON))
+	var decoded Report
+	assert.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(rep.Stat, decoded.Stat)
+	assert.Equal(len(rep.Systems), len(decoded.Systems))
+
+	for _, name := range []string{"text", "html"} {
+		format, err := ParseReportFormat(name)
+		assert.NoError(err)
+		buf.Reset()
+		assert.NoError(rep.Write(&buf, format))
+		assert.Contains(buf.String(), rep.MarkerName, name)
+		assert.Contains(buf.String(), "C1C", name)
+	}
+	assert.Contains(buf.String(), "<table>")
+
+	_, err = ParseReportFormat("pdf")
+	assert.Error(err)
+}
@@ -4,14 +4,12 @@ package rinex
 import (
 	"errors"
 	"fmt"
+	"math"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/de-bkg/gognss/pkg/gnss"
-	"github.com/mholt/archiver/v3"
 )
 
 const (
@@ -26,25 +24,42 @@ const (
 var (
 	// ErrNoHeader is returned when reading RINEX data that does not begin with a RINEX Header.
 	ErrNoHeader = errors.New("RINEX: no header")
+
+	// ErrLineTooLong is returned when a line exceeds the maximum line length of the decoder.
+	ErrLineTooLong = errors.New("RINEX: line too long")
+
+	// ErrUnsupportedVersion is returned for RINEX versions that can not be read or written.
+	ErrUnsupportedVersion = errors.New("RINEX: version not supported")
 )
 
+// ErrBadEpochLine is returned by the observation decoder for an epoch line that can not be parsed,
+// which means corrupt data, other than ErrNoHeader. Use errors.As to get the line:
+//
+//	var badEpo *ErrBadEpochLine
+//	if errors.As(err, &badEpo) {
+//		log.Printf("skip line %d", badEpo.Num)
+//	}
+type ErrBadEpochLine struct {
+	Line string // the epoch line
+	Num  int    // the line number
+	Err  error  // the cause
+}
+
+func (e *ErrBadEpochLine) Error() string {
+	return fmt.Sprintf("bad epoch line %d: %q: %v", e.Num, e.Line, e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *ErrBadEpochLine) Unwrap() error {
+	return e.Err
+}
+
 var (
 	// Rnx2FileNamePattern is the regex for RINEX2 filenames.
 	Rnx2FileNamePattern = regexp.MustCompile(`(([a-z0-9]{4})(\d{3})([a-x0])(\d{2})?\.(\d{2})([domnglqfph]))\.?([a-zA-Z0-9]+)?`)
 
 	// Rnx3FileNamePattern is the regex for RINEX3 filenames.
-	Rnx3FileNamePattern = regexp.MustCompile(`((([A-Z0-9]{4})(\d)(\d)([A-Z]{3})_([RSU])_((\d{4})(\d{3})(\d{2})(\d{2}))_(\d{2}[A-Z])_?(\d{2}[CZSMHDU])?_([GREJCSM][MNO]))\.(rnx|crx))\.?([a-zA-Z0-9]+)?`)
-
-	sysPerAbbr = map[string]gnss.System{
-		"G": gnss.SysGPS,
-		"R": gnss.SysGLO,
-		"E": gnss.SysGAL,
-		"J": gnss.SysQZSS,
-		"C": gnss.SysBDS,
-		"I": gnss.SysIRNSS,
-		"S": gnss.SysSBAS,
-		"M": gnss.SysMIXED,
-	}
+	Rnx3FileNamePattern = regexp.MustCompile(`((([A-Z0-9]{4})(\d)(\d)([A-Z]{3})_([RSU])_((\d{4})(\d{3})(\d{2})(\d{2}))_(\d{2}[A-Z])_?(\d{2}[CZSMHDU])?_([GREJCISM][MNO]))\.(rnx|crx))\.?([a-zA-Z0-9]+)?`)
 
 	// rnxTypMap maps RINEX3 data-types to RINEX2 types.
 	rnxTypMap = map[string]string{"GO": "o", "RO": "o", "EO": "o", "JO": "o", "CO": "o", "IO": "o", "SO": "o", "MO": "o",
@@ -56,8 +71,14 @@ type FilerHandler interface {
 	// Compress compresses the RINEX file dependend of its file type.
 	Compress() error
 
+	// CompressWithOptions compresses the RINEX file using the given options.
+	CompressWithOptions(opts CompressOptions) error
+
 	// Rnx3Filename returns the filename following the RINEX3 convention.
 	Rnx3Filename() (string, error)
+
+	// Rnx2Filename returns the filename following the RINEX2 convention.
+	Rnx2Filename() (string, error)
 }
 
 /* // DataFrequency is a measurement of cycle per second, stored as an int64 micro Hertz.
@@ -74,6 +95,7 @@ func (f DataFrequency) String() string {
 // Both ObsFil and NavFile embed RnxFil.
 type RnxFil struct {
 	Path string
+	FS   Filesystem // storage of the file, nil for the local disk. Compression needs the local disk.
 
 	FourCharID     string
 	MonumentNumber int
@@ -153,118 +175,133 @@ func (f *RnxFil) IsMeteoType() bool {
 	return false
 }
 
+// FileInfo contains the information encoded in a RINEX filename.
+type FileInfo struct {
+	Name           string // the base name of the file
+	NamingVersion  int    // the naming convention, 2 for short names like brux3100.18o, 3 for long names
+	FourCharID     string
+	MonumentNumber int
+	ReceiverNumber int
+	CountryCode    string    // ISO 3char, RINEX 3 names only
+	DataSource     string    // [RSU], RINEX 3 names only
+	StartTime      time.Time // nominal start time
+	FilePeriod     string    // 15M, 01H, 01D
+	DataFreq       string    // 30S, not for nav files
+	DataType       string    // the data type abbreviations GO, RO, MN, MM, ...
+	Format         string    // rnx or crx
+	Compression    string    // gz, Z, ... or empty if not compressed
+}
+
+// Hatanaka returns true if the file is Hatanaka compressed.
+func (fi FileInfo) Hatanaka() bool {
+	return fi.Format == "crx"
+}
+
+// rnx2DataTypes maps the RINEX 2 file type to the RINEX 3 data type.
+var rnx2DataTypes = map[string]string{"o": "MO", "d": "MO", "n": "GN", "g": "RN", "l": "EN", "f": "CN", "q": "JN",
+	"h": "SN", "p": "MN", "m": "MM"}
+
+var (
+	rnx2NameRe = regexp.MustCompile(`^` + Rnx2FileNamePattern.String() + `$`)
+	rnx3NameRe = regexp.MustCompile(`^` + Rnx3FileNamePattern.String() + `$`)
+)
+
+// ParseFilename decodes a RINEX 2 or RINEX 3 filename, like brux3100.18o.Z or BRUX00BEL_R_20183101900_01H_30S_MO.crx.gz.
+// The directory of name is ignored. RINEX 2 names do not contain the country code, the data source
+// and the data frequency, for those the usual period and frequency are set: 30S for daily and hourly files
+// and 01S for 15 minutes highrate files.
+func ParseFilename(name string) (FileInfo, error) {
+	fn := strings.TrimSpace(filepath.Base(name))
+	fi := FileInfo{Name: fn}
+	if res := rnx3NameRe.FindStringSubmatch(fn); res != nil {
+		fi.NamingVersion = 3
+		fi.FourCharID = res[3]
+		fi.MonumentNumber, _ = strconv.Atoi(res[4])
+		fi.ReceiverNumber, _ = strconv.Atoi(res[5])
+		fi.CountryCode = res[6]
+		fi.DataSource = res[7]
+		t, err := time.Parse(rnx3StartTimeFormat, res[8])
+		if err != nil {
+			return fi, fmt.Errorf("could not parse start time: %s: %w", res[8], err)
+		}
+		fi.StartTime = t
+		fi.FilePeriod = res[13]
+		fi.DataFreq = res[14]
+		fi.DataType = res[15]
+		fi.Format = res[16]
+		fi.Compression = res[17]
+		return fi, nil
+	}
+
+	lower := strings.ToLower(fn)
+	if i := strings.LastIndex(fn, "."); i > 0 && strings.Count(fn, ".") > 1 {
+		lower = strings.ToLower(fn[:i]) + fn[i:] // keep the case of the compression extension, e.g. Z
+	}
+	res := rnx2NameRe.FindStringSubmatch(lower)
+	if res == nil {
+		return fi, fmt.Errorf("no valid RINEX filename: %q", fn)
+	}
+	fi.NamingVersion = 2
+	fi.FourCharID = strings.ToUpper(res[2])
+	doy, err := time.Parse("06002", res[6]+res[3])
+	if err != nil {
+		return fi, fmt.Errorf("could not parse DoY: %w", err)
+	}
+	fi.StartTime = doy
+	switch {
+	case res[4] == "0":
+		fi.FilePeriod, fi.DataFreq = "01D", "30S"
+	case res[5] != "": // highrate minutes
+		hr, _ := getHourAsDigit(rune(res[4][0]))
+		min, _ := strconv.Atoi(res[5])
+		fi.StartTime = doy.Add(time.Duration(hr)*time.Hour + time.Duration(min)*time.Minute)
+		fi.FilePeriod, fi.DataFreq = "15M", "01S"
+	default:
+		hr, _ := getHourAsDigit(rune(res[4][0]))
+		fi.StartTime = doy.Add(time.Duration(hr) * time.Hour)
+		fi.FilePeriod, fi.DataFreq = "01H", "30S"
+	}
+
+	typ, ok := rnx2DataTypes[res[7]]
+	if !ok {
+		return fi, fmt.Errorf("could not determine the DATA TYPE: %q", fn)
+	}
+	fi.DataType = typ
+	fi.Format = "rnx"
+	if res[7] == "d" {
+		fi.Format = "crx"
+	}
+	fi.Compression = res[8]
+	if fi.DataType != "MO" && fi.DataType != "MM" {
+		fi.DataFreq = "" // nav files
+	}
+	return fi, nil
+}
+
 // parseFilename parses the specified filename, which must be a valid RINEX filename,
-// and fills its fields.
+// and fills its fields. The country code and data source are kept for RINEX 2 names.
 func (f *RnxFil) parseFilename() error {
 	if f.Path == "" {
 		return fmt.Errorf("could not parse filename: Path is empty")
 	}
 
-	fn := filepath.Base(f.Path)
-	if len(fn) > 20 { // Rnx3
-		res := Rnx3FileNamePattern.FindStringSubmatch(fn)
-		for k, v := range res {
-			//fmt.Printf("%d. %s\n", k, v)
-			switch k {
-			case 3:
-				f.FourCharID = strings.ToUpper(v)
-			case 4:
-				i, err := strconv.Atoi(v)
-				f.MonumentNumber = i
-				if err != nil {
-					return fmt.Errorf("could not parse MonumentNumber: %s", v)
-				}
-			case 5:
-				i, err := strconv.Atoi(v)
-				f.ReceiverNumber = i
-				if err != nil {
-					return fmt.Errorf("could not parse ReceiverNumber: %s", v)
-				}
-			case 6:
-				f.CountryCode = strings.ToUpper(v)
-			case 7:
-				f.DataSource = strings.ToUpper(v)
-			case 8:
-				t, err := time.Parse(rnx3StartTimeFormat, v)
-				if err != nil {
-					return fmt.Errorf("could not parse start time: %s: %v", v, err)
-				}
-				f.StartTime = t
-			case 13:
-				f.FilePeriod = strings.ToUpper(v)
-			case 14:
-				f.DataFreq = strings.ToUpper(v)
-			case 15:
-				f.DataType = strings.ToUpper(v)
-			case 16:
-				f.Format = strings.ToLower(v)
-			case 17:
-				f.Compression = v
-			}
-		}
-	} else { // Rnx2
-		res := Rnx2FileNamePattern.FindStringSubmatch(fn)
-		for k, v := range res {
-			//fmt.Printf("%d. %s\n", k, v)
-			switch k {
-			case 2:
-				f.FourCharID = strings.ToUpper(v)
-			case 5: // highrate minutes
-				if res[4] == "0" {
-					f.FilePeriod = "01D"
-					f.DataFreq = "30S"
-				} else {
-					if v != "" {
-						f.FilePeriod = "15M"
-						f.DataFreq = "01S"
-					} else {
-						f.FilePeriod = "01H"
-						f.DataFreq = "30S"
-					}
-				}
-			case 6: // yr
-				doy, err := time.Parse("06002", v+res[3])
-				if err != nil {
-					return fmt.Errorf("could not parse DoY: %v", err)
-				}
-				hr, _ := getHourAsDigit(rune((res[4])[0]))
-				min := 0
-				if res[5] != "" && res[5] != "00" { // highrate minutes
-					min, _ = strconv.Atoi(res[5])
-				}
-				f.StartTime = doy.Add(time.Duration(hr)*time.Hour + time.Duration(min)*time.Minute)
-			case 7:
-				switch strings.ToLower(v) {
-				case "o":
-					f.DataType = "MO"
-					f.Format = "rnx"
-				case "d":
-					f.DataType = "MO"
-					f.Format = "crx"
-				case "n":
-					f.DataType = "GN"
-					f.Format = "rnx"
-				case "g":
-					f.DataType = "RN"
-					f.Format = "rnx"
-				case "m":
-					f.DataType = "MM"
-					f.Format = "rnx"
-				case "f":
-					f.DataType = "CN"
-					f.Format = "rnx"
-				case "l":
-					f.DataType = "EN"
-					f.Format = "rnx"
-				default:
-					return fmt.Errorf("could not determine the DATA TYPE")
-				}
-			case 8:
-				f.Compression = v
-			}
-		}
+	fi, err := ParseFilename(f.Path)
+	if err != nil {
+		return err
 	}
-
+	f.FourCharID = fi.FourCharID
+	f.MonumentNumber = fi.MonumentNumber
+	f.ReceiverNumber = fi.ReceiverNumber
+	if fi.NamingVersion == 3 {
+		f.CountryCode = fi.CountryCode
+		f.DataSource = fi.DataSource
+	}
+	f.StartTime = fi.StartTime
+	f.FilePeriod = fi.FilePeriod
+	f.DataFreq = fi.DataFreq
+	f.DataType = fi.DataType
+	f.Format = fi.Format
+	f.Compression = fi.Compression
 	return nil
 }
 
@@ -275,23 +312,8 @@ func Rnx3Filename(rnx2filepath string, countryCode string) (string, error) {
 	if len(countryCode) != 3 {
 		return "", fmt.Errorf("invalid countryCode %q", countryCode)
 	}
-	rnx := &RnxFil{Path: rnx2filepath, CountryCode: countryCode, DataSource: "R"}
-	err := rnx.parseFilename()
-	if err != nil {
-		return "", err
-	}
-
-	if rnx.IsObsType() {
-		f := &ObsFile{RnxFil: rnx}
-		return f.Rnx3Filename()
-	} else if rnx.IsNavType() {
-		f := &NavFile{RnxFil: rnx}
-		return f.Rnx3Filename()
-	} else if rnx.IsMeteoType() {
-		return "", fmt.Errorf("Meteo files not implemented yet")
-	}
-
-	return "", fmt.Errorf("No valid RINEX filename: %s", rnx2filepath)
+	conv := FilenameConverter{Country: func(string) (string, error) { return countryCode, nil }}
+	return conv.ToRnx3(rnx2filepath)
 }
 
 // Rnx2Filename returns the filename following the RINEX2 convention.
@@ -301,7 +323,12 @@ func Rnx2Filename(rnx3filepath string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return rnx.Rnx2Filename()
+}
 
+// Rnx2Filename returns the filename following the legacy RINEX2 convention, e.g. brux310t.18o
+// for an hourly observation file. 15 minutes files get the minutes, e.g. brux310t15.18d.
+func (rnx *RnxFil) Rnx2Filename() (string, error) {
 	// Station Identifier
 	if len(rnx.FourCharID) != 4 {
 		return "", fmt.Errorf("FourCharID: %s", rnx.FourCharID)
@@ -348,23 +375,48 @@ func Rnx2Filename(rnx3filepath string) (string, error) {
 	return fn.String(), nil
 }
 
-// IsCompressed returns true if the src is compressed, otherwise false.
-func IsCompressed(src string) bool {
-	ext := filepath.Ext(src)
-	if ext == "" {
-		return false
+// freqCode returns the RINEX 3 filename code of the data frequency for the sampling interval, e.g. 30S or 05Z.
+func freqCode(interval time.Duration) string {
+	switch {
+	case interval < time.Second:
+		return fmt.Sprintf("%02dZ", int(math.Round(float64(time.Second)/float64(interval))))
+	case interval < time.Minute:
+		return fmt.Sprintf("%02dS", int(interval/time.Second))
+	case interval < time.Hour:
+		return fmt.Sprintf("%02dM", int(interval/time.Minute))
+	case interval < 24*time.Hour:
+		return fmt.Sprintf("%02dH", int(interval/time.Hour))
 	}
+	return fmt.Sprintf("%02dD", int(interval/(24*time.Hour)))
+}
 
-	if ext == ".z" || ext == ".Z" {
-		return true
+// periodCode returns the RINEX 3 filename code of the file period, e.g. 15M or 01D.
+func periodCode(period time.Duration) string {
+	switch {
+	case period%(24*time.Hour) == 0:
+		return fmt.Sprintf("%02dD", int(period/(24*time.Hour)))
+	case period%time.Hour == 0:
+		return fmt.Sprintf("%02dH", int(period/time.Hour))
 	}
+	return fmt.Sprintf("%02dM", int(period/time.Minute))
+}
 
-	_, err := archiver.ByExtension(src)
-	if err == nil {
-		return true
+// nominalPeriod returns the nominal file period for the time span covered by the data.
+// Spans of more than the half of a standard period of 15 minutes, one hour or one day are assigned
+// to that period, so that files with missing epochs at the end get their nominal period.
+// Other spans are rounded up to full minutes, hours or days.
+func nominalPeriod(span time.Duration) time.Duration {
+	for _, p := range []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour} {
+		if span <= p && span > p/2 {
+			return p
+		}
 	}
-
-	return false
+	for _, unit := range []time.Duration{time.Minute, time.Hour, 24 * time.Hour} {
+		if span < 60*unit || unit == 24*time.Hour {
+			return (span + unit - 1) / unit * unit
+		}
+	}
+	return span
 }
 
 // ParseDoy returns the UTC-Time corresponding to the given year and day of year.
@@ -384,6 +436,58 @@ func parseFloat(s string) (float64, error) {
 	return strconv.ParseFloat(strings.TrimSpace(s), 64)
 }
 
+// pow10 contains the powers of ten that are exactly representable as float64.
+var pow10 = [...]float64{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15}
+
+// parseFixedFloat parses a fixed-width float field like F14.3 without allocations. A blank field is 0.
+// Fields with exponents or more than 15 digits are passed to strconv.ParseFloat, so the result is
+// always the same as the one of strconv.
+func parseFixedFloat(s string) (float64, error) {
+	i, n := 0, len(s)
+	for i < n && s[i] == ' ' {
+		i++
+	}
+	for n > i && s[n-1] == ' ' {
+		n--
+	}
+	if i == n {
+		return 0, nil
+	}
+
+	neg := false
+	if s[i] == '-' || s[i] == '+' {
+		neg = s[i] == '-'
+		i++
+	}
+	var mant uint64
+	digits, frac, dot := 0, 0, false
+	for ; i < n; i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			mant = mant*10 + uint64(c-'0')
+			digits++
+			if dot {
+				frac++
+			}
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return strconv.ParseFloat(strings.TrimSpace(s), 64)
+		}
+	}
+	if digits == 0 || digits >= len(pow10) {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+
+	// mant and the power of ten are exact, so the division is correctly rounded.
+	v := float64(mant) / pow10[frac]
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
 func getHourAsChar(hr int) string {
 	return string(rune(hr + 97))
 }
@@ -416,5 +520,5 @@ func getHourAsDigit(char rune) (int, error) {
 		return epTime, nil
 	}
 
-	return time.Time{}, fmt.Errorf("Could not parse date from string: '%s': %v", str, err)
+	return time.Time{}, fmt.Errorf("Could not parse date from string: '%s': %w", str, err)
 } */
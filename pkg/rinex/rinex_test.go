@@ -3,6 +3,8 @@ package rinex
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -182,3 +184,90 @@ func TestParseDoy(t *testing.T) {
 		fmt.Printf("epoch: %s\n", t)
 	}
 }
+
+func TestParseFixedFloat(t *testing.T) {
+	assert := assert.New(t)
+	for _, s := range []string{
+		"  22331467.258", " 117350011.123", "      -630.505", "        45.250", "             0",
+		"-0.000123456789", "  +12.5", "12.", ".5", "  1.5E+03", "  0.123456789012345678",
+		"99999999999.999", "-0.0",
+	} {
+		want, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		assert.NoError(err, s)
+		got, err := parseFixedFloat(s)
+		assert.NoError(err, s)
+		assert.Equal(want, got, "%q", s)
+	}
+
+	f, err := parseFixedFloat("              ")
+	assert.NoError(err)
+	assert.Equal(0.0, f)
+
+	for _, s := range []string{"  12.3.4", "   -", " 1x.5", "1 2"} {
+		_, err := parseFixedFloat(s)
+		assert.Error(err, "%q", s)
+	}
+}
+
+func BenchmarkParseFixedFloat(b *testing.B) {
+	fields := []string{"  22331467.258", " 117350011.123", "      -630.505", "        45.250", "              "}
+	b.Run("strconv", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, s := range fields {
+				if s = strings.TrimSpace(s); s != "" {
+					if _, err := strconv.ParseFloat(s, 64); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		}
+	})
+	b.Run("fixed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, s := range fields {
+				if _, err := parseFixedFloat(s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestParseFilename(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name string
+		want FileInfo
+	}{
+		{"/data/BRUX00BEL_R_20183101900_01H_30S_MO.crx.gz", FileInfo{Name: "BRUX00BEL_R_20183101900_01H_30S_MO.crx.gz", NamingVersion: 3,
+			FourCharID: "BRUX", CountryCode: "BEL", DataSource: "R", StartTime: time.Date(2018, 11, 6, 19, 0, 0, 0, time.UTC),
+			FilePeriod: "01H", DataFreq: "30S", DataType: "MO", Format: "crx", Compression: "gz"}},
+		{"WTZR12DEU_S_20201550000_01D_MN.rnx", FileInfo{Name: "WTZR12DEU_S_20201550000_01D_MN.rnx", NamingVersion: 3,
+			FourCharID: "WTZR", MonumentNumber: 1, ReceiverNumber: 2, CountryCode: "DEU", DataSource: "S",
+			StartTime: time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC), FilePeriod: "01D", DataType: "MN", Format: "rnx"}},
+		{"brux3100.18o", FileInfo{Name: "brux3100.18o", NamingVersion: 2, FourCharID: "BRUX",
+			StartTime: time.Date(2018, 11, 6, 0, 0, 0, 0, time.UTC), FilePeriod: "01D", DataFreq: "30S", DataType: "MO", Format: "rnx"}},
+		{"BRUX310T.18D.Z", FileInfo{Name: "BRUX310T.18D.Z", NamingVersion: 2, FourCharID: "BRUX",
+			StartTime: time.Date(2018, 11, 6, 19, 0, 0, 0, time.UTC), FilePeriod: "01H", DataFreq: "30S", DataType: "MO", Format: "crx", Compression: "Z"}},
+		{"adis240e15.19d.gz", FileInfo{Name: "adis240e15.19d.gz", NamingVersion: 2, FourCharID: "ADIS",
+			StartTime: time.Date(2019, 8, 28, 4, 15, 0, 0, time.UTC), FilePeriod: "15M", DataFreq: "01S", DataType: "MO", Format: "crx", Compression: "gz"}},
+		{"brdc1550.20p", FileInfo{Name: "brdc1550.20p", NamingVersion: 2, FourCharID: "BRDC",
+			StartTime: time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC), FilePeriod: "01D", DataType: "MN", Format: "rnx"}},
+	}
+	for _, tt := range tests {
+		fi, err := ParseFilename(tt.name)
+		if assert.NoError(err, tt.name) {
+			assert.Equal(tt.want, fi, tt.name)
+		}
+	}
+	fi, _ := ParseFilename("brux310t.18d")
+	assert.True(fi.Hatanaka())
+
+	for _, name := range []string{"", "brux.18o", "brux3100.18x", "brux3100.18o.tar.gz", "BRUX00BEL_R_20183101900_01H_30S_MO.txt",
+		"BRUX00BEL_X_20183101900_01H_30S_MO.rnx", "xBRUX00BEL_R_20183101900_01H_30S_MO.rnx"} {
+		_, err := ParseFilename(name)
+		assert.Error(err, "%q", name)
+	}
+}
@@ -0,0 +1,205 @@
+package rinex
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+)
+
+// RotateOptions configures a RotatingWriter.
+type RotateOptions struct {
+	Period     time.Duration // file period, defaults to one hour, 24 hours gives daily files
+	DataSource string        // data source in the filename: R, S or U, defaults to S (stream)
+	DataFreq   string        // data frequency in the filename, e.g. 01S, omitted if empty
+	DataType   string        // data type in the filename, defaults to MO
+	Format     string        // file extension, defaults to rnx, e.g. rtcm3 for raw RTCM data
+	Header     *ObsHeader    // header of the RINEX files written by WriteEpoch
+	Compress   bool          // gzip the closed files, an existing compressed file is replaced
+	ArchiveDir string        // move the closed files into this directory, must be on the same file system
+
+	// OnClose is called for every closed file with its final path and the error of the compression or move.
+	// It is called from another goroutine.
+	OnClose func(path string, err error)
+}
+
+// RotatingWriter writes streamed data into files of a fixed period, e.g. hourly files. The files start at
+// multiples of the period in GPS time and are named after the RINEX 3 convention, like
+// WTZR00DEU_S_20201690100_01H_01S_MO.rnx.
+//
+// Raw data, like RTCM, is written with Write and goes to the file of the current time. RINEX observation
+// epochs are written with WriteEpoch and go to the file of their epoch time. Both should not be mixed.
+// Existing files are appended. A file that is opened again, e.g. after the clock was set back, is opened
+// after its compression and move have finished.
+type RotatingWriter struct {
+	dir      string
+	station  string
+	opts     RotateOptions
+	now      func() time.Time
+	compress func(src, dst string) error
+
+	f     *os.File
+	enc   *ObsEncoder
+	start time.Time
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	pending map[string]chan struct{} // closed files by path, the channel is closed when they are finished
+}
+
+// NewRotatingWriter returns a writer for files in dir. station is the 9 char station name, e.g. WTZR00DEU.
+func NewRotatingWriter(dir, station string, opts RotateOptions) (*RotatingWriter, error) {
+	if len(station) != 9 {
+		return nil, fmt.Errorf("invalid station name: %q", station)
+	}
+	if opts.Period == 0 {
+		opts.Period = time.Hour
+	}
+	if opts.Period < time.Minute {
+		return nil, fmt.Errorf("invalid file period: %s", opts.Period)
+	}
+	if opts.DataSource == "" {
+		opts.DataSource = "S"
+	}
+	if opts.DataType == "" {
+		opts.DataType = "MO"
+	}
+	if opts.Format == "" {
+		opts.Format = "rnx"
+	}
+	return &RotatingWriter{dir: dir, station: station, opts: opts, now: time.Now, compress: CompressFile,
+		pending: make(map[string]chan struct{})}, nil
+}
+
+// Filename returns the name of the file starting at start.
+func (w *RotatingWriter) Filename(start time.Time) string {
+	freq := ""
+	if w.opts.DataFreq != "" {
+		freq = "_" + w.opts.DataFreq
+	}
+	return fmt.Sprintf("%s_%s_%s%03d%s_%s%s_%s.%s", w.station, w.opts.DataSource, start.Format("2006"), start.YearDay(),
+		start.Format("1504"), periodCode(w.opts.Period), freq, w.opts.DataType, w.opts.Format)
+}
+
+// Write writes raw data to the file of the current time.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	if err := w.rotate(gnsstime.UTCToGPS(w.now().UTC())); err != nil {
+		return 0, err
+	}
+	return w.f.Write(p)
+}
+
+// WriteEpoch encodes the epoch to the file of its epoch time. The header of each file is taken from
+// the options, with the time of the first observation of the file.
+func (w *RotatingWriter) WriteEpoch(epo *Epoch) error {
+	if w.opts.Header == nil {
+		return fmt.Errorf("rotating writer: no RINEX header")
+	}
+	if err := w.rotate(epo.Time); err != nil {
+		return err
+	}
+	return w.enc.Encode(epo)
+}
+
+// rotate opens the file for the GPS time t, if it is not yet open.
+func (w *RotatingWriter) rotate(t time.Time) error {
+	start := t.Truncate(w.opts.Period)
+	if w.f != nil && start.Equal(w.start) {
+		return nil
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, w.Filename(start))
+	w.waitFinished(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.f, w.start = f, start
+	if w.opts.Header != nil {
+		w.enc = NewObsEncoder(f, editHeader(*w.opts.Header))
+		if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+			w.enc.hdrWritten = true
+		}
+	}
+	return nil
+}
+
+// closeFile closes the current file and starts its compression and move.
+func (w *RotatingWriter) closeFile() error {
+	if w.f == nil {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		err = w.enc.Flush()
+	}
+	if err2 := w.f.Close(); err == nil {
+		err = err2
+	}
+	path := w.f.Name()
+	w.f, w.enc = nil, nil
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	w.mu.Lock()
+	w.pending[path] = done
+	w.mu.Unlock()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		final, err := w.finish(path)
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+		close(done)
+		if w.opts.OnClose != nil {
+			w.opts.OnClose(final, err)
+		}
+	}()
+	return nil
+}
+
+// waitFinished waits until the compression and move of the closed file path have finished.
+func (w *RotatingWriter) waitFinished(path string) {
+	w.mu.Lock()
+	done, ok := w.pending[path]
+	w.mu.Unlock()
+	if ok {
+		<-done
+	}
+}
+
+// finish compresses and moves the closed file and returns its final path.
+func (w *RotatingWriter) finish(path string) (string, error) {
+	if w.opts.Compress {
+		if err := w.compress(path, path+".gz"); err != nil {
+			return path, err
+		}
+		if err := os.Remove(path); err != nil {
+			return path, err
+		}
+		path += ".gz"
+	}
+	if w.opts.ArchiveDir != "" {
+		dst := filepath.Join(w.opts.ArchiveDir, filepath.Base(path))
+		if err := os.Rename(path, dst); err != nil {
+			return path, err
+		}
+		path = dst
+	}
+	return path, nil
+}
+
+// Close closes the current file and waits for the compression and move of the closed files.
+func (w *RotatingWriter) Close() error {
+	err := w.closeFile()
+	w.wg.Wait()
+	return err
+}
@@ -0,0 +1,129 @@
+package rinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingWriter_Write(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotatingWriter(dir, "WTZR00DEU", RotateOptions{Format: "rtcm3"})
+	assert.NoError(err)
+	now := time.Date(2020, 6, 17, 0, 59, 30, 0, time.UTC) // GPS time is 18 s ahead
+	w.now = func() time.Time { return now }
+	w.Write([]byte("a"))
+	now = now.Add(20 * time.Second)
+	w.Write([]byte("b"))
+	now = now.Add(20 * time.Second)
+	w.Write([]byte("c"))
+	assert.NoError(w.Close())
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "WTZR00DEU_S_20201690000_01H_MO.rtcm3"))
+	assert.NoError(err)
+	assert.Equal("a", string(b))
+	b, err = ioutil.ReadFile(filepath.Join(dir, "WTZR00DEU_S_20201690100_01H_MO.rtcm3"))
+	assert.NoError(err)
+	assert.Equal("bc", string(b))
+
+	_, err = NewRotatingWriter(dir, "WTZR", RotateOptions{})
+	assert.Error(err)
+}
+
+func TestRotatingWriter_WriteEpoch(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+	assert.NoError(os.Mkdir(archive, 0755))
+
+	data, err := ioutil.ReadFile(reykFile)
+	assert.NoError(err)
+	all := epochStrings(t, data)
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	w, err := NewRotatingWriter(dir, "REYK00ISL", RotateOptions{Period: 30 * time.Minute, DataFreq: "30S",
+		Header: &dec.Header, Compress: true, ArchiveDir: archive, OnClose: func(path string, err error) {
+			assert.NoError(err)
+			mu.Lock()
+			closed = append(closed, path)
+			mu.Unlock()
+		}})
+	assert.NoError(err)
+	for dec.NextEpoch() {
+		assert.NoError(w.WriteEpoch(dec.Epoch()))
+	}
+	assert.NoError(dec.Err())
+	assert.NoError(w.Close())
+
+	sort.Strings(closed)
+	assert.Equal([]string{filepath.Join(archive, "REYK00ISL_S_20192701000_30M_30S_MO.rnx.gz"),
+		filepath.Join(archive, "REYK00ISL_S_20192701030_30M_30S_MO.rnx.gz")}, closed)
+	var got []string
+	for _, path := range closed {
+		got = append(got, epochStrings(t, readAll(t, path))...)
+	}
+	assert.Equal(all, got)
+}
+
+func TestRotatingWriter_reopen(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	w, err := NewRotatingWriter(dir, "WTZR00DEU", RotateOptions{Format: "rtcm3", Compress: true,
+		OnClose: func(path string, err error) {
+			assert.NoError(err)
+			mu.Lock()
+			closed = append(closed, path)
+			mu.Unlock()
+		}})
+	assert.NoError(err)
+	w.compress = func(src, dst string) error {
+		time.Sleep(50 * time.Millisecond) // the file is opened again meanwhile
+		return CompressFile(src, dst)
+	}
+	now := time.Date(2020, 6, 17, 0, 30, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	assert.NoError(writeString(w, "a"))
+	now = now.Add(time.Hour)
+	assert.NoError(writeString(w, "b"))
+	now = now.Add(-time.Hour) // the clock is set back
+	assert.NoError(writeString(w, "c"))
+	assert.NoError(w.Close())
+
+	name := filepath.Join(dir, "WTZR00DEU_S_20201690000_01H_MO.rtcm3")
+	sort.Strings(closed)
+	assert.Equal([]string{name + ".gz", name + ".gz", filepath.Join(dir, "WTZR00DEU_S_20201690100_01H_MO.rtcm3.gz")}, closed)
+	assert.Equal("c", string(readAll(t, name+".gz")), "the compressed file is replaced")
+	_, err = os.Stat(name)
+	assert.True(os.IsNotExist(err))
+	assert.Empty(w.pending)
+}
+
+func writeString(w *RotatingWriter, s string) error {
+	_, err := w.Write([]byte(s))
+	return err
+}
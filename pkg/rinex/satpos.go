@@ -0,0 +1,94 @@
+package rinex
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	gmGPS        = 3.986005e14     // earth's gravitational constant for GPS [m^3/s^2]
+	omegaEarth   = 7.2921151467e-5 // earth's rotation rate [rad/s]
+	secondsWeek  = 604800.0
+	relativityF  = -4.442807633e-10 // relativistic clock correction constant [s/m^(1/2)]
+	speedOfLight = 299792458.0      // [m/s]
+	defaultFitHr = 4.0              // default fit interval in hours
+)
+
+// gpsEpoch is the start of the GPS time.
+var gpsEpoch = time.Date(1980, 1, 6, 0, 0, 0, 0, time.UTC)
+
+// AzEl specifies the azimuth and elevation of a satellite in degrees.
+type AzEl struct {
+	Az, El float64
+}
+
+// Position computes the satellite position in the earth-fixed frame at the GPS time t,
+// according to the GPS Interface Specification IS-GPS-200.
+func (eph *EphGPS) Position(t time.Time) Coord {
+	a := eph.SqrtA * eph.SqrtA
+	n := math.Sqrt(gmGPS/(a*a*a)) + eph.DeltaN
+
+	tk := eph.sinceToe(t)
+	ecc := eph.eccentricAnomaly(n, tk)
+	sinE, cosE := math.Sincos(ecc)
+
+	v := math.Atan2(math.Sqrt(1-eph.Ecc*eph.Ecc)*sinE, cosE-eph.Ecc)
+	phi := v + eph.Omega
+	sin2Phi, cos2Phi := math.Sincos(2 * phi)
+
+	u := phi + eph.Cus*sin2Phi + eph.Cuc*cos2Phi
+	r := a*(1-eph.Ecc*cosE) + eph.Crs*sin2Phi + eph.Crc*cos2Phi
+	i := eph.I0 + eph.IDOT*tk + eph.Cis*sin2Phi + eph.Cic*cos2Phi
+
+	x, y := r*math.Cos(u), r*math.Sin(u)
+	omega := eph.Omega0 + (eph.OmegaDot-omegaEarth)*tk - omegaEarth*eph.Toe
+	sinO, cosO := math.Sincos(omega)
+	sinI, cosI := math.Sincos(i)
+
+	return Coord{
+		X: x*cosO - y*cosI*sinO,
+		Y: x*sinO + y*cosI*cosO,
+		Z: y * sinI,
+	}
+}
+
+// ClockOffset returns the satellite clock offset in seconds at the GPS time t, including the relativistic
+// correction. The group delay TGD has to be subtracted for single-frequency L1 users.
+func (eph *EphGPS) ClockOffset(t time.Time) float64 {
+	dt := t.Sub(eph.TOC).Seconds()
+	a := eph.SqrtA * eph.SqrtA
+	n := math.Sqrt(gmGPS/(a*a*a)) + eph.DeltaN
+	ecc := eph.eccentricAnomaly(n, eph.sinceToe(t))
+	rel := relativityF * eph.Ecc * eph.SqrtA * math.Sin(ecc)
+	return eph.ClockBias + eph.ClockDrift*dt + eph.ClockDriftRate*dt*dt + rel
+}
+
+// sinceToe returns the time in seconds from the ephemeris reference epoch, accounting for the week crossover.
+func (eph *EphGPS) sinceToe(t time.Time) float64 {
+	tk := secondsOfWeek(t) - eph.Toe
+	if tk > secondsWeek/2 {
+		tk -= secondsWeek
+	} else if tk < -secondsWeek/2 {
+		tk += secondsWeek
+	}
+	return tk
+}
+
+// eccentricAnomaly solves Kepler's equation for the mean motion n at tk seconds from the reference epoch.
+func (eph *EphGPS) eccentricAnomaly(n, tk float64) float64 {
+	m := eph.M0 + n*tk
+	ecc := m
+	for i := 0; i < 20; i++ {
+		eNew := m + eph.Ecc*math.Sin(ecc)
+		if math.Abs(eNew-ecc) < 1e-13 {
+			return eNew
+		}
+		ecc = eNew
+	}
+	return ecc
+}
+
+// secondsOfWeek returns the seconds of the GPS week for the GPS time t.
+func secondsOfWeek(t time.Time) float64 {
+	return math.Mod(t.Sub(gpsEpoch).Seconds(), secondsWeek)
+}
@@ -0,0 +1,100 @@
+package rinex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+// navDataG20 is a GPS navigation file with one ephemeris.
+const navDataG20 = `     3.04           N: GNSS NAV DATA    G: GPS              RINEX VERSION / TYPE
+BCEmerge            congo               20200619 003025 GMT PGM / RUN BY / DATE
+    18                                                      LEAP SECONDS
+                                                            END OF HEADER
+G20 2020 06 18 00 00 00 5.274894647300E-04-1.136868377216E-13 0.000000000000E+00
+     8.300000000000E+01 2.078125000000E+01 5.373438110980E-09-2.252452975616E+00
+     1.156702637672E-06 5.203154985793E-03 7.405877113342E-06 5.153647661209E+03
+     3.456000000000E+05-1.247972249985E-07-2.679776962713E+00 2.048909664154E-08
+     9.344138223835E-01 2.252500000000E+02 2.669542608731E+00-8.333918569731E-09
+     4.632335812523E-10 1.000000000000E+00 2.110000000000E+03 0.000000000000E+00
+     2.000000000000E+00 0.000000000000E+00-8.847564458847E-09 8.300000000000E+01
+     3.393480000000E+05 4.000000000000E+00
+`
+
+func TestEphemerides_SatPos(t *testing.T) {
+	assert := assert.New(t)
+	dec, err := NewNavDecoder(strings.NewReader(navDataG20))
+	assert.NoError(err)
+	ephs, err := NewEphemerides(dec)
+	assert.NoError(err)
+
+	prn := PRN{Sys: gnss.SysGPS, Num: 20}
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	pos, err := ephs.SatPos(prn, toc)
+	assert.NoError(err)
+	assert.InDelta(26.56e6, pos.Distance(Coord{}), 0.15e6, "orbit radius")
+
+	pos2, err := ephs.SatPos(prn, toc.Add(time.Second))
+	assert.NoError(err)
+	assert.InDelta(3.2e3, pos.Distance(pos2), 0.8e3, "velocity")
+
+	_, err = ephs.SatPos(prn, toc.Add(3*time.Hour))
+	assert.Error(err, "ephemeris too old")
+	_, err = ephs.SatPos(PRN{Sys: gnss.SysGPS, Num: 1}, toc)
+	assert.Error(err, "no ephemeris")
+
+	// marker below the satellite
+	g := pos.LatLonHeight(GRS80)
+	g.Height = 0
+	marker := g.Coord(GRS80)
+	epo := &Epoch{Time: toc, ObsList: []SatObs{{Prn: prn}, {Prn: PRN{Sys: gnss.SysGPS, Num: 1}}}}
+	azel := ephs.AzEl(epo, marker)
+	assert.Len(azel, 1)
+	assert.InDelta(90.0, azel[prn].El, 0.5)
+}
+
+func TestObsDecoder_ElevationMask(t *testing.T) {
+	assert := assert.New(t)
+	navDec, err := NewNavDecoder(strings.NewReader(navDataG20))
+	assert.NoError(err)
+	ephs, err := NewEphemerides(navDec)
+	assert.NoError(err)
+
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	pos, err := ephs.SatPos(PRN{Sys: gnss.SysGPS, Num: 20}, toc)
+	assert.NoError(err)
+	g := pos.LatLonHeight(GRS80)
+	g.Height = 0
+	below := g.Coord(GRS80)
+	antipode := Coord{X: -below.X, Y: -below.Y, Z: -below.Z}
+
+	tests := []struct {
+		marker Coord
+		nSats  int
+	}{
+		{below, 2},
+		{antipode, 1},
+	}
+	for _, tt := range tests {
+		data := fmt.Sprintf(`     3.04           OBSERVATION DATA    G                   RINEX VERSION / TYPE
+%-60sAPPROX POSITION XYZ
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+  2020     6    18     0     0    0.0000000     GPS         TIME OF FIRST OBS
+                                                            END OF HEADER
+> 2020 06 18 00 00  0.0000000  0  2
+G01  22783244.880   119729271.83308
+G20  22783244.880   119729271.83308
+`, formatCoord(tt.marker))
+		dec, err := NewObsDecoder(strings.NewReader(data))
+		assert.NoError(err)
+		dec.Opts.ElevationMask = 10
+		dec.EphemerisStore = ephs
+		assert.True(dec.NextEpoch())
+		assert.Len(dec.Epoch().ObsList, tt.nSats)
+		assert.Equal(uint8(tt.nSats), dec.Epoch().NumSat)
+	}
+}
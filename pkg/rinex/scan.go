@@ -0,0 +1,127 @@
+package rinex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// ScanOptions configures ScanDir.
+type ScanOptions struct {
+	Workers    int  // number of files decoded concurrently, defaults to the number of CPUs
+	HeaderOnly bool // decode the headers only, no statistics
+	MemoryMap  bool // read the uncompressed files memory-mapped, see OpenMapped
+}
+
+// ScanResult is the result of scanning a RINEX observation file.
+type ScanResult struct {
+	Path   string
+	File   RnxFil     // information from the filename
+	Header *ObsHeader // the decoded header
+	Stat   *ObsStat   // the statistics, nil if ScanOptions.HeaderOnly is set
+	Err    error      // error decoding the file, Header and Stat are nil then
+}
+
+// ScanDir walks the directory tree rooted at dir and decodes the RINEX observation files concurrently.
+// Files are recognized by their RINEX 2 or 3 filename, other files are skipped. Plain, gzip, .Z and zip files are
+// supported, Hatanaka compressed files are reported with an error. The results are sent in arbitrary order
+// over the returned channel, which is closed when all files are scanned or the context is canceled.
+func ScanDir(ctx context.Context, dir string, opts ScanOptions) <-chan ScanResult {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	paths := make(chan string)
+	results := make(chan ScanResult)
+
+	go func() {
+		defer close(paths)
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				select {
+				case results <- ScanResult{Path: path, Err: err}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				res, ok := scanFile(path, opts)
+				if !ok {
+					continue
+				}
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// scanFile decodes the observation file. It returns false if the file is no RINEX observation file.
+func scanFile(path string, opts ScanOptions) (ScanResult, bool) {
+	res := ScanResult{Path: path, File: RnxFil{Path: path}}
+	if err := res.File.parseFilename(); err != nil || !res.File.IsObsType() {
+		return res, false
+	}
+	if res.File.Format == "crx" {
+		res.Err = fmt.Errorf("compression not supported: %s", path)
+		return res, true
+	}
+
+	var r io.ReadCloser
+	var err error
+	if opts.MemoryMap {
+		r, err = OpenMapped(path)
+	} else {
+		r, err = OpenFile(path)
+	}
+	if err != nil {
+		res.Err = err
+		return res, true
+	}
+	defer r.Close()
+
+	dec, err := NewObsDecoder(r)
+	if err != nil {
+		res.Err = fmt.Errorf("%s: %w", path, err)
+		return res, true
+	}
+	if !opts.HeaderOnly {
+		stat, err := obsStat(dec)
+		if err != nil {
+			res.Err = fmt.Errorf("%s: %w", path, err)
+			return res, true
+		}
+		res.Stat = &stat
+	}
+	res.Header = &dec.Header
+	return res, true
+}
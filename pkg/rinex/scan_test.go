@@ -0,0 +1,66 @@
+package rinex
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanDir(t *testing.T) {
+	assert := assert.New(t)
+	var ok, failed int
+	for res := range ScanDir(context.Background(), "testdata/white", ScanOptions{Workers: 3}) {
+		if res.Err != nil {
+			assert.Equal("crx", res.File.Format, res.Path)
+			failed++
+			continue
+		}
+		ok++
+		assert.NotNil(res.Header, res.Path)
+		if assert.NotNil(res.Stat, res.Path) && res.Header.RINEXVersion >= 3 {
+			assert.True(res.Stat.NumEpochs > 0, res.Path)
+		}
+	}
+	assert.Equal(4, ok)
+	assert.Equal(2, failed)
+}
+
+func TestScanDir_gzip(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	src, err := os.Open("testdata/white/REYK00ISL_R_20192701000_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(dir, "REYK00ISL_R_20192701000_01H_30S_MO.rnx.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	assert.NoError(err)
+	assert.NoError(gz.Close())
+	assert.NoError(dst.Close())
+
+	var results []ScanResult
+	for res := range ScanDir(context.Background(), dir, ScanOptions{HeaderOnly: true}) {
+		results = append(results, res)
+	}
+	if assert.Len(results, 1) {
+		assert.NoError(results[0].Err)
+		assert.Equal("REYK", results[0].Header.MarkerName[:4])
+		assert.Nil(results[0].Stat)
+	}
+
+	// canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range ScanDir(ctx, "testdata/white", ScanOptions{}) {
+	}
+}
@@ -0,0 +1,176 @@
+package rinex
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// Default bin sizes of the SkyOptions in degrees.
+const (
+	DefaultSkyAzStep = 10.0
+	DefaultSkyElStep = 5.0
+)
+
+// SkyOptions configures SkyPlot. Zero values are replaced by the defaults.
+type SkyOptions struct {
+	AzStep float64 // azimuth bin size in degrees
+	ElStep float64 // elevation bin size in degrees, also used for the SNR curves
+}
+
+// SkyGrid is the skyplot of a signal strength type. The cells are indexed by the elevation bin and the
+// azimuth bin, i.e. Count[i][j] holds the observations with an elevation in [i*ElStep, (i+1)*ElStep) and
+// an azimuth in [j*AzStep, (j+1)*AzStep).
+type SkyGrid struct {
+	Sys     gnss.System `json:"-"`
+	System  string      `json:"system"`  // abbreviation, e.g. G
+	ObsType string      `json:"obsType"` // e.g. S1C
+	Count   [][]int     `json:"count"`
+	MeanSNR [][]float64 `json:"meanSNR"` // 0 for empty cells
+}
+
+// SNRBin is the signal strength of an elevation bin.
+type SNRBin struct {
+	Elevation float64 `json:"elevation"` // center of the bin in degrees
+	Count     int     `json:"count"`
+	Mean      float64 `json:"mean"`
+	StdDev    float64 `json:"stdDev"`
+}
+
+// SNRCurve is the signal strength of a signal strength type versus the elevation, only bins with
+// observations are included.
+type SNRCurve struct {
+	Sys     gnss.System `json:"-"`
+	System  string      `json:"system"`
+	ObsType string      `json:"obsType"`
+	Bins    []SNRBin    `json:"bins"`
+}
+
+// SkyStats are the skyplot grids and the SNR curves of the signal strength types of an observation file,
+// e.g. as JSON for web visualizations of the station performance.
+type SkyStats struct {
+	AzStep float64    `json:"azStep"`
+	ElStep float64    `json:"elStep"`
+	Grids  []SkyGrid  `json:"grids"`
+	Curves []SNRCurve `json:"snrCurves"`
+}
+
+// SkyPlot bins the signal strength observations (S) of the decoder's epochs by the azimuth and elevation
+// of the satellites and returns the skyplot grids and the SNR-versus-elevation curves per system and
+// observation type. The decoder needs an EphemerisStore and the header a position. Satellites without a valid
+// ephemeris, currently all but GPS, and below the horizon are skipped.
+func SkyPlot(dec *ObsDecoder, opts SkyOptions) (*SkyStats, error) {
+	if dec.EphemerisStore == nil {
+		return nil, errors.New("skyplot needs ephemerides")
+	}
+	marker := dec.Header.Position
+	if marker == (Coord{}) {
+		return nil, errors.New("skyplot needs the header position")
+	}
+	if opts.AzStep <= 0 {
+		opts.AzStep = DefaultSkyAzStep
+	}
+	if opts.ElStep <= 0 {
+		opts.ElStep = DefaultSkyElStep
+	}
+	nAz, nEl := int(math.Ceil(360/opts.AzStep)), int(math.Ceil(90/opts.ElStep))
+
+	type signal struct {
+		sys gnss.System
+		typ string
+	}
+	type acc struct {
+		count [][]int
+		sum   [][]float64
+		elN   []int
+		elSum []float64
+		elSq  []float64
+	}
+	accs := make(map[signal]*acc)
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if epo.IsEvent() || epo.IsCycleSlip() {
+			continue
+		}
+		azel := dec.EphemerisStore.AzEl(epo, marker)
+		for _, satObs := range epo.ObsList {
+			ae, ok := azel[satObs.Prn]
+			if !ok || ae.El < 0 {
+				continue
+			}
+			az := math.Mod(ae.Az+360, 360)
+			i, j := int(ae.El/opts.ElStep), int(az/opts.AzStep)
+			if i >= nEl {
+				i = nEl - 1
+			}
+			if j >= nAz {
+				j = nAz - 1
+			}
+			for k, typ := range satObs.Types {
+				if len(typ) != 3 || typ[0] != 'S' || k >= len(satObs.Obss) || satObs.Obss[k].Val == 0 {
+					continue
+				}
+				snr := satObs.Obss[k].Val
+				sig := signal{sys: satObs.Prn.Sys, typ: typ}
+				a, ok := accs[sig]
+				if !ok {
+					a = &acc{count: make([][]int, nEl), sum: make([][]float64, nEl),
+						elN: make([]int, nEl), elSum: make([]float64, nEl), elSq: make([]float64, nEl)}
+					for r := range a.count {
+						a.count[r], a.sum[r] = make([]int, nAz), make([]float64, nAz)
+					}
+					accs[sig] = a
+				}
+				a.count[i][j]++
+				a.sum[i][j] += snr
+				a.elN[i]++
+				a.elSum[i] += snr
+				a.elSq[i] += snr * snr
+			}
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read epochs: %w", err)
+	}
+
+	sigs := make([]signal, 0, len(accs))
+	for sig := range accs {
+		sigs = append(sigs, sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		if sigs[i].sys != sigs[j].sys {
+			return sigs[i].sys < sigs[j].sys
+		}
+		return sigs[i].typ < sigs[j].typ
+	})
+	st := &SkyStats{AzStep: opts.AzStep, ElStep: opts.ElStep, Grids: []SkyGrid{}, Curves: []SNRCurve{}}
+	for _, sig := range sigs {
+		a := accs[sig]
+		grid := SkyGrid{Sys: sig.sys, System: sig.sys.Abbr(), ObsType: sig.typ, Count: a.count, MeanSNR: a.sum}
+		for i := range a.sum {
+			for j := range a.sum[i] {
+				if n := a.count[i][j]; n > 0 {
+					a.sum[i][j] /= float64(n)
+				}
+			}
+		}
+		curve := SNRCurve{Sys: sig.sys, System: sig.sys.Abbr(), ObsType: sig.typ, Bins: []SNRBin{}}
+		for i, n := range a.elN {
+			if n == 0 {
+				continue
+			}
+			mean := a.elSum[i] / float64(n)
+			bin := SNRBin{Elevation: (float64(i) + 0.5) * opts.ElStep, Count: n, Mean: mean}
+			if v := a.elSq[i]/float64(n) - mean*mean; v > 0 {
+				bin.StdDev = math.Sqrt(v)
+			}
+			curve.Bins = append(curve.Bins, bin)
+		}
+		st.Grids = append(st.Grids, grid)
+		st.Curves = append(st.Curves, curve)
+	}
+	return st, nil
+}
@@ -0,0 +1,91 @@
+package rinex
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkyPlot(t *testing.T) {
+	assert := assert.New(t)
+	ephs, marker := simulatedConstellation(t)
+	toc := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+
+	// S1C grows with the elevation, S2W only for G01
+	var buf bytes.Buffer
+	enc := NewObsEncoder(&buf, ObsHeader{RINEXVersion: 3.04, RINEXType: "O", SatSystem: gnss.SysGPS, MarkerName: "SIMU",
+		Interval: 30, Position: marker, ObsTypes: map[gnss.System][]string{gnss.SysGPS: {"C1C", "S1C", "S2W"}}})
+	for i := 0; i < 4; i++ {
+		epo := simulateEpoch(ephs, marker, toc.Add(time.Duration(i)*30*time.Second))
+		for k := range epo.ObsList {
+			satObs := &epo.ObsList[k]
+			pos, _ := ephs.SatPos(satObs.Prn, epo.Time)
+			_, el := marker.AzEl(pos)
+			satObs.Set("S1C", Obs{Val: 30 + el/5 + float64(i%2)})
+			if satObs.Prn.Num == 1 {
+				satObs.Set("S2W", Obs{Val: 40})
+			}
+		}
+		assert.NoError(enc.Encode(epo))
+	}
+	assert.NoError(enc.Flush())
+	data := buf.Bytes()
+
+	dec, err := NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	_, err = SkyPlot(dec, SkyOptions{})
+	assert.Error(err, "no ephemerides")
+
+	dec, err = NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	dec.EphemerisStore = ephs
+	st, err := SkyPlot(dec, SkyOptions{})
+	if !assert.NoError(err) || !assert.Len(st.Grids, 2) || !assert.Len(st.Curves, 2) {
+		return
+	}
+	assert.Equal(DefaultSkyAzStep, st.AzStep)
+	assert.Equal(DefaultSkyElStep, st.ElStep)
+
+	grid := st.Grids[0]
+	assert.Equal("G", grid.System)
+	assert.Equal("S1C", grid.ObsType)
+	assert.Len(grid.Count, 18)
+	assert.Len(grid.Count[0], 36)
+	total := 0
+	for i, row := range grid.Count {
+		for j, n := range row {
+			total += n
+			if n > 0 {
+				el := (float64(i) + 0.5) * st.ElStep
+				assert.InDelta(30+el/5+0.5, grid.MeanSNR[i][j], st.ElStep/5)
+			} else {
+				assert.Zero(grid.MeanSNR[i][j])
+			}
+		}
+	}
+	assert.Equal(28, total)
+	g01 := st.Grids[1]
+	assert.Equal("S2W", g01.ObsType)
+	top := 0
+	for _, n := range g01.Count[17] {
+		top += n
+	}
+	assert.Equal(4, top, "G01 near the zenith")
+
+	curve := st.Curves[0]
+	n := 0
+	for _, bin := range curve.Bins {
+		n += bin.Count
+		assert.InDelta(0.5, bin.StdDev, 0.3)
+	}
+	assert.Equal(28, n)
+	assert.Equal([]SNRBin{{Elevation: 87.5, Count: 4, Mean: 40}}, st.Curves[1].Bins)
+
+	out, err := json.Marshal(st)
+	assert.NoError(err)
+	assert.Contains(string(out), `"snrCurves":[{"system":"G","obsType":"S1C","bins":[`)
+}
@@ -0,0 +1,201 @@
+package rtcm3
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+)
+
+// frameOverhead is the length of the frame header and the CRC.
+const frameOverhead = 6
+
+// Analyzer collects the statistics of a RTCM 3 stream, like the "RTCM scan" of BNC: the message types with
+// their counts and rates, the MSM signals per satellite system and the station and antenna information of
+// the messages 1005/1006 and 1033. Use NewAnalyzer to create one.
+type Analyzer struct {
+	start, end time.Time
+	frames     int
+	bytes      int64
+	msgs       map[int]*MessageStats
+	signals    map[gnss.System]map[string]bool
+	station    *StationARP
+	descriptor *Descriptor
+	crcErrors  int
+}
+
+// MessageStats are the statistics of a message type.
+type MessageStats struct {
+	Number int
+	Count  int
+	Bytes  int64   // incl. the frames
+	Rate   float64 // messages per second
+	First  time.Time
+	Last   time.Time
+}
+
+// Report is the result of an Analyzer.
+type Report struct {
+	Start, End time.Time
+	Frames     int
+	Bytes      int64   // incl. the frames
+	MsgRate    float64 // messages per second
+	ByteRate   float64 // bytes per second
+	CRCErrors  int
+
+	// Messages are the message types in ascending order.
+	Messages []MessageStats
+
+	// Signals are the RINEX 3 frequency bands and attributes of the MSM signals per satellite system, e.g. "1C".
+	Signals map[gnss.System][]string
+
+	// Station and Descriptor are the last received messages 1005/1006 and 1033, nil if none.
+	Station    *StationARP
+	Descriptor *Descriptor
+}
+
+// NewAnalyzer returns a new Analyzer.
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{
+		msgs:    make(map[int]*MessageStats),
+		signals: make(map[gnss.System]map[string]bool),
+	}
+}
+
+// Run analyzes the stream read from r until EOF, the frames are timestamped with their arrival time.
+func (a *Analyzer) Run(r io.Reader) error {
+	dec := NewDecoder(r)
+	defer func() { a.crcErrors += dec.NumCRCErrors }()
+	for dec.NextFrame() {
+		a.Add(dec.Payload(), time.Now())
+	}
+	return dec.Err()
+}
+
+// Add analyzes the message payload received at t.
+func (a *Analyzer) Add(payload []byte, t time.Time) {
+	if len(payload) < 2 {
+		return
+	}
+	if a.frames == 0 || t.Before(a.start) {
+		a.start = t
+	}
+	if t.After(a.end) {
+		a.end = t
+	}
+	a.frames++
+	size := int64(len(payload) + frameOverhead)
+	a.bytes += size
+
+	num := msgNum(payload)
+	ms, ok := a.msgs[num]
+	if !ok {
+		ms = &MessageStats{Number: num, First: t}
+		a.msgs[num] = ms
+	}
+	ms.Count++
+	ms.Bytes += size
+	ms.Last = t
+
+	switch num {
+	case 1005, 1006:
+		m := &StationARP{}
+		if err := m.UnmarshalBinary(payload); err == nil {
+			a.station = m
+		}
+	case 1033:
+		m := &Descriptor{}
+		if err := m.UnmarshalBinary(payload); err == nil {
+			a.descriptor = m
+		}
+	default:
+		if sys, codes, ok := MSMSignals(payload); ok {
+			if a.signals[sys] == nil {
+				a.signals[sys] = make(map[string]bool)
+			}
+			for _, code := range codes {
+				a.signals[sys][code] = true
+			}
+		}
+	}
+}
+
+// Report returns the statistics of the messages analyzed so far.
+func (a *Analyzer) Report() *Report {
+	rep := &Report{
+		Start:      a.start,
+		End:        a.end,
+		Frames:     a.frames,
+		Bytes:      a.bytes,
+		CRCErrors:  a.crcErrors,
+		Signals:    make(map[gnss.System][]string, len(a.signals)),
+		Station:    a.station,
+		Descriptor: a.descriptor,
+	}
+	secs := a.end.Sub(a.start).Seconds()
+	if secs > 0 {
+		rep.MsgRate = float64(a.frames) / secs
+		rep.ByteRate = float64(a.bytes) / secs
+	}
+	for _, ms := range a.msgs {
+		stats := *ms
+		if secs > 0 {
+			stats.Rate = float64(ms.Count) / secs
+		}
+		rep.Messages = append(rep.Messages, stats)
+	}
+	sort.Slice(rep.Messages, func(i, j int) bool { return rep.Messages[i].Number < rep.Messages[j].Number })
+	for sys, codes := range a.signals {
+		for code := range codes {
+			rep.Signals[sys] = append(rep.Signals[sys], code)
+		}
+		sort.Strings(rep.Signals[sys])
+	}
+	return rep
+}
+
+// Write writes the report as text.
+func (rep *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Period:\t%s - %s (%s)\n", rep.Start.Format(time.RFC3339), rep.End.Format(time.RFC3339),
+		rep.End.Sub(rep.Start).Round(time.Millisecond))
+	fmt.Fprintf(tw, "Messages:\t%d (%.2f/s), %d bytes (%.1f/s), %d CRC errors\n",
+		rep.Frames, rep.MsgRate, rep.Bytes, rep.ByteRate, rep.CRCErrors)
+	tw.Flush()
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Type\tCount\tBytes\tRate/s\t")
+	for _, ms := range rep.Messages {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.2f\t\n", ms.Number, ms.Count, ms.Bytes, ms.Rate)
+	}
+	tw.Flush()
+
+	if len(rep.Signals) > 0 {
+		fmt.Fprintln(tw)
+		for sys := gnss.SysGPS; sys <= gnss.SysSBAS; sys++ {
+			if codes, ok := rep.Signals[sys]; ok {
+				fmt.Fprintf(tw, "%s:\t%s\n", sys, strings.Join(codes, " "))
+			}
+		}
+	}
+	if m := rep.Station; m != nil {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "Station ID:\t%d\n", m.StationID)
+		fmt.Fprintf(tw, "ARP XYZ:\t%.4f %.4f %.4f\n", m.Position.X, m.Position.Y, m.Position.Z)
+		if m.MsgNum == 1006 {
+			fmt.Fprintf(tw, "Antenna height:\t%.4f\n", m.AntennaHeight)
+		}
+	}
+	if m := rep.Descriptor; m != nil {
+		if rep.Station == nil {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "Antenna:\t%s (serial %q, setup ID %d)\n", m.AntennaType, m.AntennaSerial, m.AntennaSetupID)
+		fmt.Fprintf(tw, "Receiver:\t%s (firmware %q, serial %q)\n", m.ReceiverType, m.ReceiverFirmware, m.ReceiverSerial)
+	}
+	return tw.Flush()
+}
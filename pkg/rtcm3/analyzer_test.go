@@ -0,0 +1,101 @@
+package rtcm3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzer(t *testing.T) {
+	assert := assert.New(t)
+	hdr := &rinex.ObsHeader{AntennaType: "LEIAR25.R3      LEIT", AntennaNumber: "09370013",
+		ReceiverType: "SEPT POLARX5", ReceiverVersion: "5.3.2", ReceiverNumber: "3047937",
+		Position: rinex.Coord{X: 4075580.3515, Y: 931854.0113, Z: 4801568.1924}}
+	hdr.AntennaDelta.Up = 0.0712
+	arp, err := NewStationARP(42, hdr).MarshalBinary()
+	assert.NoError(err)
+	desc, err := NewDescriptor(42, hdr).MarshalBinary()
+	assert.NoError(err)
+
+	a := NewAnalyzer()
+	enc := NewMSMEncoder(42)
+	t0 := time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i <= 10; i++ {
+		t := t0.Add(time.Duration(i) * time.Second)
+		epo := &rinex.Epoch{Time: t, ObsList: []rinex.SatObs{
+			rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGPS, Num: 5}, map[string]rinex.Obs{
+				"C1C": {Val: 22331467.258}, "L1C": {Val: 117350011.123}, "C2W": {Val: 22331470.012}, "L2W": {Val: 91441539.456}}),
+			rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGAL, Num: 11}, map[string]rinex.Obs{
+				"C1C": {Val: 25765119.381}, "C5Q": {Val: 25765121.745}}),
+		}}
+		for _, msg := range enc.Messages(epo) {
+			payload, err := msg.MarshalBinary()
+			assert.NoError(err)
+			a.Add(payload, t)
+		}
+		if i%5 == 0 {
+			a.Add(arp, t)
+			a.Add(desc, t)
+		}
+	}
+	a.Add([]byte{0x3F}, t0) // too short
+
+	rep := a.Report()
+	assert.Equal(t0, rep.Start)
+	assert.Equal(10*time.Second, rep.End.Sub(rep.Start))
+	assert.Equal(28, rep.Frames)
+	assert.InDelta(2.8, rep.MsgRate, 1e-9)
+	assert.InDelta(float64(rep.Bytes)/10, rep.ByteRate, 1e-9)
+	if assert.Len(rep.Messages, 4) {
+		assert.Equal(1006, rep.Messages[0].Number)
+		assert.Equal(3, rep.Messages[0].Count)
+		assert.Equal(int64(3*(len(arp)+6)), rep.Messages[0].Bytes)
+		assert.Equal(1033, rep.Messages[1].Number)
+		assert.Equal(1077, rep.Messages[2].Number)
+		assert.Equal(11, rep.Messages[2].Count)
+		assert.InDelta(1.1, rep.Messages[2].Rate, 1e-9)
+		assert.Equal(1097, rep.Messages[3].Number)
+	}
+	assert.Equal(map[gnss.System][]string{gnss.SysGPS: {"1C", "2W"}, gnss.SysGAL: {"1C", "5Q"}}, rep.Signals)
+	if assert.NotNil(rep.Station) {
+		assert.Equal(uint16(42), rep.Station.StationID)
+		assert.InDelta(0.0712, rep.Station.AntennaHeight, 1e-6)
+	}
+	if assert.NotNil(rep.Descriptor) {
+		assert.Equal("SEPT POLARX5", rep.Descriptor.ReceiverType)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(rep.Write(&buf))
+	out := buf.String()
+	assert.Contains(out, "28 (2.80/s)")
+	assert.Contains(out, "GPS:  1C 2W")
+	assert.Contains(out, "GAL:  1C 5Q")
+	assert.Contains(out, "Receiver:")
+	assert.Contains(out, "SEPT POLARX5")
+}
+
+func TestAnalyzer_Run(t *testing.T) {
+	assert := assert.New(t)
+	corrupt := append([]byte{}, frame1005...)
+	corrupt[10]++
+	var buf bytes.Buffer
+	buf.Write(frame1005)
+	buf.Write(corrupt)
+	buf.Write(frame1005)
+
+	a := NewAnalyzer()
+	assert.NoError(a.Run(&buf))
+	rep := a.Report()
+	assert.Equal(2, rep.Frames)
+	assert.Equal(1, rep.CRCErrors)
+	assert.Equal(int64(2*len(frame1005)), rep.Bytes)
+	if assert.NotNil(rep.Station) {
+		assert.Equal(uint16(2003), rep.Station.StationID)
+	}
+	assert.Equal(rep.Start, rep.Messages[0].First)
+}
@@ -0,0 +1,143 @@
+package rtcm3
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// gpsPi is the value of pi used in the GPS interface specification.
+const gpsPi = 3.1415926535898
+
+// uraMeters maps the GPS URA index to the accuracy in meters.
+var uraMeters = [16]float64{2.4, 3.4, 4.85, 6.85, 9.65, 13.65, 24, 48, 96, 192, 384, 768, 1536, 3072, 6144, 0}
+
+// uraIndex returns the URA index for the accuracy in meters.
+func uraIndex(ura float64) int {
+	for i, v := range uraMeters[:15] {
+		if ura <= v {
+			return i
+		}
+	}
+	return 15
+}
+
+// GPSEphemeris is the GPS ephemeris message 1019.
+type GPSEphemeris struct {
+	rinex.EphGPS
+}
+
+// Number returns the message number.
+func (m *GPSEphemeris) Number() int { return 1019 }
+
+// MarshalBinary returns the message payload.
+func (m *GPSEphemeris) MarshalBinary() ([]byte, error) {
+	eph := &m.EphGPS
+	if eph.PRN.Sys != gnss.SysGPS || eph.PRN.Num < 1 || eph.PRN.Num > 63 {
+		return nil, fmt.Errorf("invalid satellite %v", eph.PRN)
+	}
+	toc := math.Mod(eph.TOC.Sub(gnsstime.GPSEpoch).Seconds(), 604800)
+
+	w := &bitWriter{}
+	w.writeUint(12, 1019)
+	w.writeUint(6, uint64(eph.PRN.Num))
+	w.writeUint(10, uint64(eph.ToeWeek)%1024)
+	w.writeUint(4, uint64(uraIndex(eph.URA)))
+	w.writeUint(2, uint64(eph.L2Codes))
+	w.writeInt(14, scale(eph.IDOT/gpsPi, -43))
+	w.writeUint(8, uint64(eph.IODE))
+	w.writeUint(16, uint64(scale(toc, 4)))
+	w.writeInt(8, scale(eph.ClockDriftRate, -55))
+	w.writeInt(16, scale(eph.ClockDrift, -43))
+	w.writeInt(22, scale(eph.ClockBias, -31))
+	w.writeUint(10, uint64(eph.IODC))
+	w.writeInt(16, scale(eph.Crs, -5))
+	w.writeInt(16, scale(eph.DeltaN/gpsPi, -43))
+	w.writeInt(32, scale(eph.M0/gpsPi, -31))
+	w.writeInt(16, scale(eph.Cuc, -29))
+	w.writeUint(32, uint64(scale(eph.Ecc, -33)))
+	w.writeInt(16, scale(eph.Cus, -29))
+	w.writeUint(32, uint64(scale(eph.SqrtA, -19)))
+	w.writeUint(16, uint64(scale(eph.Toe, 4)))
+	w.writeInt(16, scale(eph.Cic, -29))
+	w.writeInt(32, scale(eph.Omega0/gpsPi, -31))
+	w.writeInt(16, scale(eph.Cis, -29))
+	w.writeInt(32, scale(eph.I0/gpsPi, -31))
+	w.writeInt(16, scale(eph.Crc, -5))
+	w.writeInt(32, scale(eph.Omega/gpsPi, -31))
+	w.writeInt(24, scale(eph.OmegaDot/gpsPi, -43))
+	w.writeInt(8, scale(eph.TGD, -31))
+	w.writeUint(6, uint64(eph.Health))
+	w.writeUint(1, uint64(eph.L2PFlag))
+	if eph.FitInterval > 4 {
+		w.writeUint(1, 1)
+	} else {
+		w.writeUint(1, 0)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload. The 10-bit week number is resolved assuming
+// a date after the week rollover in April 2019.
+func (m *GPSEphemeris) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1019 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	eph := &m.EphGPS
+	eph.PRN = rinex.PRN{Sys: gnss.SysGPS, Num: int8(r.readUint(6))}
+	wn := int(r.readUint(10)) + 2048
+	eph.URA = uraMeters[r.readUint(4)]
+	eph.L2Codes = float64(r.readUint(2))
+	eph.IDOT = float64(r.readInt(14)) * math.Pow(2, -43) * gpsPi
+	eph.IODE = float64(r.readUint(8))
+	toc := float64(r.readUint(16)) * 16
+	eph.ClockDriftRate = float64(r.readInt(8)) * math.Pow(2, -55)
+	eph.ClockDrift = float64(r.readInt(16)) * math.Pow(2, -43)
+	eph.ClockBias = float64(r.readInt(22)) * math.Pow(2, -31)
+	eph.IODC = float64(r.readUint(10))
+	eph.Crs = float64(r.readInt(16)) * math.Pow(2, -5)
+	eph.DeltaN = float64(r.readInt(16)) * math.Pow(2, -43) * gpsPi
+	eph.M0 = float64(r.readInt(32)) * math.Pow(2, -31) * gpsPi
+	eph.Cuc = float64(r.readInt(16)) * math.Pow(2, -29)
+	eph.Ecc = float64(r.readUint(32)) * math.Pow(2, -33)
+	eph.Cus = float64(r.readInt(16)) * math.Pow(2, -29)
+	eph.SqrtA = float64(r.readUint(32)) * math.Pow(2, -19)
+	eph.Toe = float64(r.readUint(16)) * 16
+	eph.Cic = float64(r.readInt(16)) * math.Pow(2, -29)
+	eph.Omega0 = float64(r.readInt(32)) * math.Pow(2, -31) * gpsPi
+	eph.Cis = float64(r.readInt(16)) * math.Pow(2, -29)
+	eph.I0 = float64(r.readInt(32)) * math.Pow(2, -31) * gpsPi
+	eph.Crc = float64(r.readInt(16)) * math.Pow(2, -5)
+	eph.Omega = float64(r.readInt(32)) * math.Pow(2, -31) * gpsPi
+	eph.OmegaDot = float64(r.readInt(24)) * math.Pow(2, -43) * gpsPi
+	eph.TGD = float64(r.readInt(8)) * math.Pow(2, -31)
+	eph.Health = float64(r.readUint(6))
+	eph.L2PFlag = float64(r.readUint(1))
+	eph.FitInterval = 4
+	if r.readUint(1) == 1 {
+		eph.FitInterval = 6
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	eph.ToeWeek = float64(wn)
+	tocWeek := wn
+	if toc-eph.Toe > 302400 {
+		tocWeek--
+	} else if toc-eph.Toe < -302400 {
+		tocWeek++
+	}
+	eph.TOC = gnsstime.GPSEpoch.Add(time.Duration(tocWeek)*gnsstime.Week + time.Duration(toc)*time.Second)
+	return nil
+}
+
+// scale returns v divided by 2^exp, rounded to an integer.
+func scale(v float64, exp int) int64 {
+	return int64(math.Round(v / math.Pow(2, float64(exp))))
+}
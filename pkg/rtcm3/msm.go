@@ -0,0 +1,689 @@
+package rtcm3
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Invalid values of the MSM7 fields.
+const (
+	invalidRoughRange      = 255
+	invalidRoughRate       = -8192
+	invalidFinePseudorange = -1 << 19
+	invalidFinePhaseRange  = -1 << 23
+	invalidFineRate        = -1 << 14
+
+	extInfoUnknown = 15
+)
+
+// Resolutions of the MSM7 fields in milliseconds.
+var (
+	resRoughRangeMod   = math.Pow(2, -10)
+	resFinePseudorange = math.Pow(2, -29)
+	resFinePhaseRange  = math.Pow(2, -31)
+)
+
+// msm7Numbers are the MSM7 message numbers per satellite system.
+var msm7Numbers = map[gnss.System]int{
+	gnss.SysGPS:   1077,
+	gnss.SysGLO:   1087,
+	gnss.SysGAL:   1097,
+	gnss.SysSBAS:  1107,
+	gnss.SysQZSS:  1117,
+	gnss.SysBDS:   1127,
+	gnss.SysIRNSS: 1137,
+}
+
+func isMSM7(num int) bool {
+	for _, n := range msm7Numbers {
+		if n == num {
+			return true
+		}
+	}
+	return false
+}
+
+// MSM7 is the Multiple Signal Message with full resolution observations. The fields hold the raw values
+// as transmitted, use the methods of MSMSat to get the observations in physical units.
+type MSM7 struct {
+	MsgNum            int    // 1077, 1087, ...
+	StationID         uint16 // reference station ID
+	Epoch             uint32 // epoch time, for GLONASS day of week and time of day
+	MultipleMessage   bool   // more messages follow for the same epoch
+	IODS              uint8  // issue of data station
+	ClockSteering     uint8
+	ExtClock          uint8
+	Smoothing         bool
+	SmoothingInterval uint8
+	Sats              []MSMSat // sorted by satellite ID
+}
+
+// MSMSat is the satellite data of a MSM7.
+type MSMSat struct {
+	ID            int // satellite ID 1-64
+	RoughRange    uint8
+	ExtInfo       uint8 // extended satellite info, for GLONASS the frequency channel + 7
+	RoughRangeMod uint16
+	RoughRate     int16
+	Signals       []MSMSignal // sorted by signal ID
+}
+
+// MSMSignal is the signal data of a MSM7.
+type MSMSignal struct {
+	ID              int // signal ID 1-32
+	FinePseudorange int32
+	FinePhaseRange  int32
+	LockTime        uint16 // lock time indicator
+	HalfCycle       bool   // half-cycle ambiguity
+	CNR             uint16
+	FineRate        int16
+}
+
+// Number returns the message number.
+func (m *MSM7) Number() int { return m.MsgNum }
+
+// System returns the satellite system of the message.
+func (m *MSM7) System() gnss.System {
+	for sys, n := range msm7Numbers {
+		if n == m.MsgNum {
+			return sys
+		}
+	}
+	return 0
+}
+
+// PRN returns the satellite of the satellite ID.
+func (m *MSM7) PRN(satID int) rinex.PRN {
+	sys := m.System()
+	if sys == gnss.SysSBAS {
+		return rinex.PRN{Sys: sys, Num: int8(satID + 19)}
+	}
+	return rinex.PRN{Sys: sys, Num: int8(satID)}
+}
+
+// satID returns the MSM satellite ID of the satellite.
+func satID(prn rinex.PRN) int {
+	if prn.Sys == gnss.SysSBAS {
+		return int(prn.Num) - 19
+	}
+	return int(prn.Num)
+}
+
+// Time returns the epoch time in GPS time. The week, or the day for GLONASS, is resolved using
+// the reference time ref, which must be within half a week of the epoch. The number of leap seconds
+// is needed to convert the GLONASS time, 0 means the value of the gnsstime leap second table.
+func (m *MSM7) Time(ref time.Time, leapSeconds int) time.Time {
+	if leapSeconds == 0 {
+		leapSeconds = gnsstime.LeapSecondsGPS(ref)
+	}
+	leap := time.Duration(leapSeconds) * time.Second
+
+	var t time.Time
+	switch m.System() {
+	case gnss.SysGLO:
+		dow := int(m.Epoch >> 27)
+		tod := time.Duration(m.Epoch&0x7FFFFFF) * time.Millisecond
+		refGlo := ref.Add(-leap + 3*time.Hour)
+		day := time.Date(refGlo.Year(), refGlo.Month(), refGlo.Day(), 0, 0, 0, 0, time.UTC)
+		if dow < 7 {
+			day = day.AddDate(0, 0, dow-int(refGlo.Weekday()))
+		}
+		t = day.Add(tod).Add(leap - 3*time.Hour)
+		if dow == 7 { // day of week unknown, resolve the day
+			if t.Sub(ref) > 12*time.Hour {
+				t = t.AddDate(0, 0, -1)
+			} else if t.Sub(ref) < -12*time.Hour {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t
+		}
+	default:
+		tow := time.Duration(m.Epoch) * time.Millisecond
+		if m.System() == gnss.SysBDS {
+			tow += gnsstime.BDSOffset
+		}
+		weekStart := gnsstime.GPSEpoch.Add(ref.Sub(gnsstime.GPSEpoch) / gnsstime.Week * gnsstime.Week)
+		t = weekStart.Add(tow)
+	}
+	if t.Sub(ref) > gnsstime.Week/2 {
+		t = t.Add(-gnsstime.Week)
+	} else if t.Sub(ref) < -gnsstime.Week/2 {
+		t = t.Add(gnsstime.Week)
+	}
+	return t
+}
+
+// EpochTime returns the epoch time in GPS time of an observation message, that is any MSM1 to MSM7 and
+// the legacy GPS and GLONASS RTK messages 1001-1004 and 1009-1012. The reference time ref and
+// leapSeconds are used as by MSM7.Time. It returns false for other messages.
+func EpochTime(payload []byte, ref time.Time, leapSeconds int) (time.Time, bool) {
+	num := msgNum(payload)
+	r := &bitReader{buf: payload}
+	r.readUint(24) // message number, station ID
+	m := &MSM7{}
+	switch {
+	case num >= 1001 && num <= 1004:
+		m.MsgNum = msm7Numbers[gnss.SysGPS]
+		m.Epoch = uint32(r.readUint(30))
+	case num >= 1009 && num <= 1012:
+		m.MsgNum = msm7Numbers[gnss.SysGLO]
+		m.Epoch = 7<<27 | uint32(r.readUint(27)) // time of day only
+	case msmSystem(num) != 0:
+		m.MsgNum = msm7Numbers[msmSystem(num)]
+		m.Epoch = uint32(r.readUint(30))
+	default:
+		return time.Time{}, false
+	}
+	if r.err != nil {
+		return time.Time{}, false
+	}
+	return m.Time(ref, leapSeconds), true
+}
+
+// MSMSignals returns the satellite system and the RINEX 3 frequency bands and attributes, e.g. "1C",
+// of the signals of any MSM1 to MSM7 without decoding the observations. Unknown signals are skipped.
+// It returns false for other messages.
+func MSMSignals(payload []byte) (gnss.System, []string, bool) {
+	sys := msmSystem(msgNum(payload))
+	if sys == 0 {
+		return 0, nil, false
+	}
+	r := &bitReader{buf: payload}
+	r.pos = 73 + 64 // header and satellite mask
+	sigMask := r.readUint(32)
+	if r.err != nil {
+		return 0, nil, false
+	}
+	var codes []string
+	for id := 1; id <= 32; id++ {
+		if sigMask&(1<<uint(32-id)) != 0 {
+			if code := ObsCode(sys, id); code != "" {
+				codes = append(codes, code)
+			}
+		}
+	}
+	return sys, codes, true
+}
+
+// msmSystem returns the satellite system of the MSM1 to MSM7 message number, 0 for other messages.
+func msmSystem(num int) gnss.System {
+	if num%10 < 1 || num%10 > 7 {
+		return 0
+	}
+	for sys, n := range msm7Numbers {
+		if n == num-num%10+7 {
+			return sys
+		}
+	}
+	return 0
+}
+
+// msmEpoch returns the MSM epoch time field for the GPS time t.
+func msmEpoch(sys gnss.System, t time.Time, leapSeconds int) uint32 {
+	switch sys {
+	case gnss.SysGLO:
+		glo := t.Add(time.Duration(-leapSeconds)*time.Second + 3*time.Hour)
+		tod := glo.Sub(time.Date(glo.Year(), glo.Month(), glo.Day(), 0, 0, 0, 0, time.UTC))
+		return uint32(glo.Weekday())<<27 | uint32(tod/time.Millisecond)
+	case gnss.SysBDS:
+		t = t.Add(-gnsstime.BDSOffset)
+	}
+	return uint32(t.Sub(gnsstime.GPSEpoch) % gnsstime.Week / time.Millisecond)
+}
+
+// roughRangeMs returns the rough range in milliseconds.
+func (sat *MSMSat) roughRangeMs() float64 {
+	return float64(sat.RoughRange) + float64(sat.RoughRangeMod)*resRoughRangeMod
+}
+
+// Pseudorange returns the pseudorange of the signal in meters.
+func (sat *MSMSat) Pseudorange(sig *MSMSignal) (float64, bool) {
+	if sat.RoughRange == invalidRoughRange || sig.FinePseudorange == invalidFinePseudorange {
+		return 0, false
+	}
+	return (sat.roughRangeMs() + float64(sig.FinePseudorange)*resFinePseudorange) * rangeMs, true
+}
+
+// PhaseRange returns the carrier phase of the signal in meters.
+func (sat *MSMSat) PhaseRange(sig *MSMSignal) (float64, bool) {
+	if sat.RoughRange == invalidRoughRange || sig.FinePhaseRange == invalidFinePhaseRange {
+		return 0, false
+	}
+	return (sat.roughRangeMs() + float64(sig.FinePhaseRange)*resFinePhaseRange) * rangeMs, true
+}
+
+// PhaseRangeRate returns the phase range rate of the signal in m/s.
+func (sat *MSMSat) PhaseRangeRate(sig *MSMSignal) (float64, bool) {
+	if sat.RoughRate == invalidRoughRate || sig.FineRate == invalidFineRate {
+		return 0, false
+	}
+	return float64(sat.RoughRate) + float64(sig.FineRate)*1e-4, true
+}
+
+// CNRdBHz returns the carrier-to-noise ratio in dBHz, 0 if not available.
+func (sig *MSMSignal) CNRdBHz() float64 {
+	return float64(sig.CNR) / 16
+}
+
+// MinLockTime returns the minimum lock time given by the lock time indicator.
+func (sig *MSMSignal) MinLockTime() time.Duration {
+	i := int(sig.LockTime)
+	if i < 64 {
+		return time.Duration(i) * time.Millisecond
+	}
+	k := uint(i/32 - 1)
+	return time.Duration(i<<k-32*int(k)<<k) * time.Millisecond
+}
+
+// lockTimeIndicator returns the extended lock time indicator DF407 for the lock time.
+func lockTimeIndicator(d time.Duration) uint16 {
+	ms := int(d / time.Millisecond)
+	if ms < 64 {
+		if ms < 0 {
+			return 0
+		}
+		return uint16(ms)
+	}
+	for k := uint(1); k <= 21; k++ {
+		if ms < 64<<k {
+			return uint16((ms + 32*int(k)<<k) >> k)
+		}
+	}
+	return 704
+}
+
+// MarshalBinary returns the message payload.
+func (m *MSM7) MarshalBinary() ([]byte, error) {
+	if !isMSM7(m.MsgNum) {
+		return nil, fmt.Errorf("invalid message number %d", m.MsgNum)
+	}
+
+	var satMask uint64
+	var sigMask uint32
+	prevSat := 0
+	for _, sat := range m.Sats {
+		if sat.ID <= prevSat || sat.ID > 64 {
+			return nil, fmt.Errorf("invalid or unsorted satellite ID %d", sat.ID)
+		}
+		prevSat = sat.ID
+		satMask |= 1 << uint(64-sat.ID)
+		prevSig := 0
+		for _, sig := range sat.Signals {
+			if sig.ID <= prevSig || sig.ID > 32 {
+				return nil, fmt.Errorf("invalid or unsorted signal ID %d of satellite %d", sig.ID, sat.ID)
+			}
+			prevSig = sig.ID
+			sigMask |= 1 << uint(32-sig.ID)
+		}
+	}
+	sigIDs := make([]int, 0, 32)
+	for id := 1; id <= 32; id++ {
+		if sigMask&(1<<uint(32-id)) != 0 {
+			sigIDs = append(sigIDs, id)
+		}
+	}
+	if len(m.Sats)*len(sigIDs) > 64 {
+		return nil, fmt.Errorf("too many cells: %d satellites, %d signals", len(m.Sats), len(sigIDs))
+	}
+
+	w := &bitWriter{}
+	w.writeUint(12, uint64(m.MsgNum))
+	w.writeUint(12, uint64(m.StationID))
+	w.writeUint(30, uint64(m.Epoch))
+	w.writeBool(m.MultipleMessage)
+	w.writeUint(3, uint64(m.IODS))
+	w.writeUint(7, 0) // reserved
+	w.writeUint(2, uint64(m.ClockSteering))
+	w.writeUint(2, uint64(m.ExtClock))
+	w.writeBool(m.Smoothing)
+	w.writeUint(3, uint64(m.SmoothingInterval))
+	w.writeUint(64, satMask)
+	w.writeUint(32, uint64(sigMask))
+
+	// cell mask
+	var cells []*MSMSignal
+	for i := range m.Sats {
+		sat := &m.Sats[i]
+		j := 0
+		for _, id := range sigIDs {
+			if j < len(sat.Signals) && sat.Signals[j].ID == id {
+				w.writeBool(true)
+				cells = append(cells, &sat.Signals[j])
+				j++
+			} else {
+				w.writeBool(false)
+			}
+		}
+	}
+
+	// satellite data
+	for _, sat := range m.Sats {
+		w.writeUint(8, uint64(sat.RoughRange))
+	}
+	for _, sat := range m.Sats {
+		w.writeUint(4, uint64(sat.ExtInfo))
+	}
+	for _, sat := range m.Sats {
+		w.writeUint(10, uint64(sat.RoughRangeMod))
+	}
+	for _, sat := range m.Sats {
+		w.writeInt(14, int64(sat.RoughRate))
+	}
+
+	// signal data
+	for _, sig := range cells {
+		w.writeInt(20, int64(sig.FinePseudorange))
+	}
+	for _, sig := range cells {
+		w.writeInt(24, int64(sig.FinePhaseRange))
+	}
+	for _, sig := range cells {
+		w.writeUint(10, uint64(sig.LockTime))
+	}
+	for _, sig := range cells {
+		w.writeBool(sig.HalfCycle)
+	}
+	for _, sig := range cells {
+		w.writeUint(10, uint64(sig.CNR))
+	}
+	for _, sig := range cells {
+		w.writeInt(15, int64(sig.FineRate))
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *MSM7) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	m.MsgNum = int(r.readUint(12))
+	if !isMSM7(m.MsgNum) && r.err == nil {
+		return fmt.Errorf("invalid message number %d", m.MsgNum)
+	}
+	m.StationID = uint16(r.readUint(12))
+	m.Epoch = uint32(r.readUint(30))
+	m.MultipleMessage = r.readBool()
+	m.IODS = uint8(r.readUint(3))
+	r.readUint(7)
+	m.ClockSteering = uint8(r.readUint(2))
+	m.ExtClock = uint8(r.readUint(2))
+	m.Smoothing = r.readBool()
+	m.SmoothingInterval = uint8(r.readUint(3))
+	satMask := r.readUint(64)
+	sigMask := r.readUint(32)
+	if r.err != nil {
+		return r.err
+	}
+
+	m.Sats = m.Sats[:0]
+	for id := 1; id <= 64; id++ {
+		if satMask&(1<<uint(64-id)) != 0 {
+			m.Sats = append(m.Sats, MSMSat{ID: id})
+		}
+	}
+	sigIDs := make([]int, 0, 32)
+	for id := 1; id <= 32; id++ {
+		if sigMask&(1<<uint(32-id)) != 0 {
+			sigIDs = append(sigIDs, id)
+		}
+	}
+	if len(m.Sats)*len(sigIDs) > 64 {
+		return fmt.Errorf("too many cells: %d satellites, %d signals", len(m.Sats), len(sigIDs))
+	}
+
+	for i := range m.Sats {
+		for _, id := range sigIDs {
+			if r.readBool() {
+				m.Sats[i].Signals = append(m.Sats[i].Signals, MSMSignal{ID: id})
+			}
+		}
+	}
+	var cells []*MSMSignal
+	for i := range m.Sats {
+		for j := range m.Sats[i].Signals {
+			cells = append(cells, &m.Sats[i].Signals[j])
+		}
+	}
+
+	for i := range m.Sats {
+		m.Sats[i].RoughRange = uint8(r.readUint(8))
+	}
+	for i := range m.Sats {
+		m.Sats[i].ExtInfo = uint8(r.readUint(4))
+	}
+	for i := range m.Sats {
+		m.Sats[i].RoughRangeMod = uint16(r.readUint(10))
+	}
+	for i := range m.Sats {
+		m.Sats[i].RoughRate = int16(r.readInt(14))
+	}
+
+	for _, sig := range cells {
+		sig.FinePseudorange = int32(r.readInt(20))
+	}
+	for _, sig := range cells {
+		sig.FinePhaseRange = int32(r.readInt(24))
+	}
+	for _, sig := range cells {
+		sig.LockTime = uint16(r.readUint(10))
+	}
+	for _, sig := range cells {
+		sig.HalfCycle = r.readBool()
+	}
+	for _, sig := range cells {
+		sig.CNR = uint16(r.readUint(10))
+	}
+	for _, sig := range cells {
+		sig.FineRate = int16(r.readInt(15))
+	}
+	return r.err
+}
+
+// MSMEncoder builds MSM7 messages from RINEX epochs. It keeps track of the lock times and aligns
+// the carrier phases to the pseudoranges, therefore the epochs must be passed in chronological order.
+type MSMEncoder struct {
+	StationID   uint16
+	IODS        uint8
+	LeapSeconds int               // GPS-UTC for the GLONASS time, 0 means the value of the gnsstime leap second table
+	GloChannels map[rinex.PRN]int // GLONASS frequency channels, e.g. the header's GloSlots
+
+	locks     map[sigKey]*lockState
+	lastEpoch time.Time
+}
+
+type sigKey struct {
+	prn  rinex.PRN
+	code string
+}
+
+// lockState holds the continuous tracking of a carrier phase.
+type lockState struct {
+	start time.Time // start of lock
+	last  time.Time // last epoch
+	amb   float64   // integer cycles subtracted to align the phase to the pseudorange
+}
+
+// NewMSMEncoder returns a new MSMEncoder.
+func NewMSMEncoder(stationID uint16) *MSMEncoder {
+	return &MSMEncoder{StationID: stationID, locks: make(map[sigKey]*lockState)}
+}
+
+// Messages returns the MSM7 messages for the epoch, one per satellite system, or more if a system does not
+// fit into one message. All but the last message have the multiple message bit set. Signals not defined for
+// MSM, event and cycle slip epochs are skipped, as well as satellites without pseudorange.
+func (enc *MSMEncoder) Messages(epo *rinex.Epoch) []*MSM7 {
+	if epo.IsEvent() || epo.IsCycleSlip() {
+		return nil
+	}
+	leap := enc.LeapSeconds
+	if leap == 0 {
+		leap = gnsstime.LeapSecondsGPS(epo.Time)
+	}
+
+	bySys := make(map[gnss.System][]MSMSat)
+	for _, satObs := range epo.ObsList {
+		if _, ok := msm7Numbers[satObs.Prn.Sys]; !ok {
+			continue
+		}
+		if sat, ok := enc.satData(satObs, epo.Time); ok {
+			bySys[satObs.Prn.Sys] = append(bySys[satObs.Prn.Sys], sat)
+		}
+	}
+	systems := make([]gnss.System, 0, len(bySys))
+	for sys := range bySys {
+		systems = append(systems, sys)
+	}
+	sort.Slice(systems, func(i, j int) bool { return systems[i] < systems[j] })
+
+	var msgs []*MSM7
+	for _, sys := range systems {
+		sats := bySys[sys]
+		sort.Slice(sats, func(i, j int) bool { return sats[i].ID < sats[j].ID })
+		for len(sats) > 0 {
+			n := cellLimit(sats)
+			msgs = append(msgs, &MSM7{
+				MsgNum:          msm7Numbers[sys],
+				StationID:       enc.StationID,
+				Epoch:           msmEpoch(sys, epo.Time, leap),
+				MultipleMessage: true,
+				IODS:            enc.IODS,
+				Sats:            sats[:n],
+			})
+			sats = sats[n:]
+		}
+	}
+	if len(msgs) > 0 {
+		msgs[len(msgs)-1].MultipleMessage = false
+	}
+
+	for key, st := range enc.locks {
+		if !st.last.Equal(epo.Time) {
+			delete(enc.locks, key)
+		}
+	}
+	enc.lastEpoch = epo.Time
+	return msgs
+}
+
+// cellLimit returns the number of satellites that fit into a message with at most 64 cells.
+func cellLimit(sats []MSMSat) int {
+	var sigs [33]bool
+	nsig := 0
+	for i, sat := range sats {
+		for _, sig := range sat.Signals {
+			if !sigs[sig.ID] {
+				sigs[sig.ID] = true
+				nsig++
+			}
+		}
+		if (i+1)*nsig > 64 {
+			return i
+		}
+	}
+	return len(sats)
+}
+
+// satData returns the MSM7 satellite data.
+func (enc *MSMEncoder) satData(satObs rinex.SatObs, t time.Time) (MSMSat, bool) {
+	prn := satObs.Prn
+	sat := MSMSat{ID: satID(prn), RoughRate: invalidRoughRate}
+	if sat.ID < 1 || sat.ID > 64 {
+		return sat, false
+	}
+	gloChannel, hasChannel := enc.GloChannels[prn]
+	if prn.Sys == gnss.SysGLO {
+		sat.ExtInfo = extInfoUnknown
+		if hasChannel {
+			sat.ExtInfo = uint8(gloChannel + 7)
+		}
+	}
+
+	codes := make(map[int]string)
+	for _, typ := range satObs.Types {
+		if len(typ) != 3 || (typ[0] != 'C' && typ[0] != 'L' && typ[0] != 'D' && typ[0] != 'S') {
+			continue
+		}
+		if id := SignalID(prn.Sys, typ[1:]); id > 0 {
+			codes[id] = typ[1:]
+		}
+	}
+	sigIDs := make([]int, 0, len(codes))
+	for id := range codes {
+		sigIDs = append(sigIDs, id)
+	}
+	sort.Ints(sigIDs)
+
+	// rough range and rate from the first signal
+	roughMs := -1.0
+	for _, id := range sigIDs {
+		code := codes[id]
+		if obs, ok := satObs.Get("C" + code); ok && obs.Val != 0 && roughMs < 0 {
+			roughMs = math.Round(obs.Val/rangeMs/resRoughRangeMod) * resRoughRangeMod
+		}
+		lambda := wavelength(prn.Sys, code, gloChannel, hasChannel)
+		if obs, ok := satObs.Get("D" + code); ok && lambda > 0 && sat.RoughRate == invalidRoughRate {
+			if rate := math.Round(-obs.Val * lambda); math.Abs(rate) <= 8191 {
+				sat.RoughRate = int16(rate)
+			}
+		}
+	}
+	if roughMs < 0 || roughMs >= invalidRoughRange {
+		return sat, false
+	}
+	sat.RoughRange = uint8(math.Floor(roughMs))
+	sat.RoughRangeMod = uint16(math.Round((roughMs - math.Floor(roughMs)) / resRoughRangeMod))
+
+	for _, id := range sigIDs {
+		code := codes[id]
+		lambda := wavelength(prn.Sys, code, gloChannel, hasChannel)
+		sig := MSMSignal{ID: id, FinePseudorange: invalidFinePseudorange, FinePhaseRange: invalidFinePhaseRange, FineRate: invalidFineRate}
+
+		if obs, ok := satObs.Get("C" + code); ok && obs.Val != 0 {
+			if fine := math.Round((obs.Val/rangeMs - roughMs) / resFinePseudorange); math.Abs(fine) < 1<<19 {
+				sig.FinePseudorange = int32(fine)
+			}
+		}
+		if obs, ok := satObs.Get("L" + code); ok && obs.Val != 0 && lambda > 0 {
+			enc.phase(&sig, sigKey{prn, code}, obs, lambda, roughMs, t)
+		}
+		if obs, ok := satObs.Get("D" + code); ok && lambda > 0 && sat.RoughRate != invalidRoughRate {
+			if fine := math.Round((-obs.Val*lambda - float64(sat.RoughRate)) / 1e-4); math.Abs(fine) < 1<<14 {
+				sig.FineRate = int16(fine)
+			}
+		}
+		if obs, ok := satObs.Get("S" + code); ok && obs.Val > 0 {
+			sig.CNR = uint16(math.Min(math.Round(obs.Val*16), 1023))
+		}
+		sat.Signals = append(sat.Signals, sig)
+	}
+	return sat, true
+}
+
+// phase sets the fine phase range, the lock time and the half-cycle ambiguity of the signal.
+func (enc *MSMEncoder) phase(sig *MSMSignal, key sigKey, obs rinex.Obs, lambda, roughMs float64, t time.Time) {
+	newLock := func() *lockState {
+		st := &lockState{start: t, amb: math.Round(obs.Val - roughMs*rangeMs/lambda)}
+		enc.locks[key] = st
+		return st
+	}
+
+	st, ok := enc.locks[key]
+	if !ok || !st.last.Equal(enc.lastEpoch) || obs.LLI&0x01 != 0 {
+		st = newLock()
+	}
+	fine := math.Round(((obs.Val-st.amb)*lambda/rangeMs - roughMs) / resFinePhaseRange)
+	if math.Abs(fine) >= 1<<23 { // phase diverged from the pseudorange
+		st = newLock()
+		fine = math.Round(((obs.Val-st.amb)*lambda/rangeMs - roughMs) / resFinePhaseRange)
+	}
+	st.last = t
+
+	sig.FinePhaseRange = int32(fine)
+	sig.LockTime = lockTimeIndicator(t.Sub(st.start))
+	sig.HalfCycle = obs.LLI&0x02 != 0
+}
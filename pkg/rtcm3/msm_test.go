@@ -0,0 +1,225 @@
+package rtcm3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockTimeIndicator(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		d   time.Duration
+		ind uint16
+	}{
+		{0, 0}, {63 * time.Millisecond, 63}, {64 * time.Millisecond, 64}, {time.Second, 190},
+		{10 * time.Minute, 484}, {100 * 24 * time.Hour, 704},
+	}
+	for _, tt := range tests {
+		ind := lockTimeIndicator(tt.d)
+		assert.Equal(tt.ind, ind, "%s", tt.d)
+		sig := &MSMSignal{LockTime: ind}
+		if tt.ind < 704 {
+			assert.True(sig.MinLockTime() <= tt.d, "%s", tt.d)
+		}
+	}
+}
+
+func TestMSMEncoder(t *testing.T) {
+	assert := assert.New(t)
+	g05 := rinex.PRN{Sys: gnss.SysGPS, Num: 5}
+	r10 := rinex.PRN{Sys: gnss.SysGLO, Num: 10}
+	e11 := rinex.PRN{Sys: gnss.SysGAL, Num: 11}
+	c20 := rinex.PRN{Sys: gnss.SysBDS, Num: 20}
+
+	t0 := time.Date(2020, 6, 18, 23, 59, 59, 0, time.UTC)
+	newEpoch := func(t time.Time, dt float64, lli int8) *rinex.Epoch {
+		return &rinex.Epoch{Time: t, ObsList: []rinex.SatObs{
+			rinex.NewSatObs(g05, map[string]rinex.Obs{
+				"C1C": {Val: 22331467.258 + dt*120}, "L1C": {Val: 117350011.123 + dt*630.5, LLI: lli},
+				"D1C": {Val: -630.5}, "S1C": {Val: 45.25},
+				"C2W": {Val: 22331470.012 + dt*120}, "L2W": {Val: 91441539.456 + dt*491.3, LLI: 2},
+			}),
+			rinex.NewSatObs(r10, map[string]rinex.Obs{"C1C": {Val: 20118936.104}, "L1C": {Val: 107384755.876}, "S1C": {Val: 40}}),
+			rinex.NewSatObs(e11, map[string]rinex.Obs{
+				"C1C": {Val: 25765119.381}, "L1C": {Val: 135397040.715}, "C5Q": {Val: 25765121.745}, "L5Q": {Val: 101110541.562},
+				"X5Q": {Val: 1}, // unknown type
+			}),
+			rinex.NewSatObs(c20, map[string]rinex.Obs{"C2I": {Val: 21870256.017}, "L2I": {Val: 113886612.109}}),
+		}}
+	}
+
+	enc := NewMSMEncoder(42)
+	enc.GloChannels = map[rinex.PRN]int{r10: -7}
+	msgs := enc.Messages(newEpoch(t0, 0, 0))
+	if !assert.Len(msgs, 4) {
+		t.FailNow()
+	}
+	assert.Equal(1077, msgs[0].Number())
+	assert.Equal(1087, msgs[1].Number())
+	assert.Equal(1097, msgs[2].Number())
+	assert.Equal(1127, msgs[3].Number())
+	assert.True(msgs[0].MultipleMessage)
+	assert.False(msgs[3].MultipleMessage)
+
+	// round trip
+	var buf bytes.Buffer
+	wr := NewEncoder(&buf)
+	for _, msg := range msgs {
+		assert.NoError(wr.Encode(msg))
+	}
+	t1 := t0.Add(time.Second)
+	for _, msg := range enc.Messages(newEpoch(t1, 1, 0)) {
+		assert.NoError(wr.Encode(msg))
+	}
+
+	var decoded []*MSM7
+	dec := NewDecoder(&buf)
+	for dec.Next() {
+		decoded = append(decoded, dec.Message().(*MSM7))
+	}
+	assert.NoError(dec.Err())
+	if !assert.Len(decoded, 8) {
+		t.FailNow()
+	}
+	assert.Equal(msgs, decoded[:4])
+
+	for _, msg := range decoded[:4] {
+		assert.Equal(t0, msg.Time(t0.Add(-2*time.Hour), 0), "%d", msg.MsgNum)
+		assert.Equal(t0, msg.Time(t0.Add(3*24*time.Hour), 18), "%d", msg.MsgNum)
+	}
+
+	gps := decoded[0]
+	assert.Equal(g05, gps.PRN(gps.Sats[0].ID))
+	sat := &gps.Sats[0]
+	if !assert.Len(sat.Signals, 2) {
+		t.FailNow()
+	}
+	assert.Equal("1C", ObsCode(gnss.SysGPS, sat.Signals[0].ID))
+	assert.Equal("2W", ObsCode(gnss.SysGPS, sat.Signals[1].ID))
+	pr, ok := sat.Pseudorange(&sat.Signals[0])
+	assert.True(ok)
+	assert.InDelta(22331467.258, pr, 1e-3)
+	pr, _ = sat.Pseudorange(&sat.Signals[1])
+	assert.InDelta(22331470.012, pr, 1e-3)
+	rate, ok := sat.PhaseRangeRate(&sat.Signals[0])
+	assert.True(ok)
+	lambda1 := speedOfLight / 1575.42e6
+	assert.InDelta(630.5*lambda1, rate, 1e-4)
+	_, ok = sat.PhaseRangeRate(&sat.Signals[1])
+	assert.False(ok, "no Doppler")
+	assert.Equal(45.25, sat.Signals[0].CNRdBHz())
+	assert.False(sat.Signals[0].HalfCycle)
+	assert.True(sat.Signals[1].HalfCycle)
+	ph0, ok := sat.PhaseRange(&sat.Signals[0])
+	assert.True(ok)
+	assert.InDelta(pr, ph0, 1000, "phase aligned to pseudorange")
+
+	// continuous phase in the next epoch
+	sat1 := &decoded[4].Sats[0]
+	ph1, _ := sat1.PhaseRange(&sat1.Signals[0])
+	assert.InDelta(630.5*lambda1, ph1-ph0, 1e-3)
+	assert.Equal(uint16(190), sat1.Signals[0].LockTime)
+
+	// GLONASS frequency channel
+	glo := decoded[1]
+	assert.Equal(uint8(0), glo.Sats[0].ExtInfo)
+	_, ok = glo.Sats[0].PhaseRange(&glo.Sats[0].Signals[0])
+	assert.True(ok)
+
+	// Galileo: unknown types skipped
+	assert.Len(decoded[2].Sats[0].Signals, 2)
+
+	// loss of lock resets the lock time
+	msgs = enc.Messages(newEpoch(t1.Add(time.Second), 2, 1))
+	assert.Equal(uint16(0), msgs[0].Sats[0].Signals[0].LockTime)
+	assert.Equal(uint16(222), msgs[0].Sats[0].Signals[1].LockTime, "L2W still locked")
+}
+
+func TestMSM7_cellLimit(t *testing.T) {
+	assert := assert.New(t)
+	enc := NewMSMEncoder(1)
+	epo := &rinex.Epoch{Time: time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)}
+	for i := 1; i <= 20; i++ {
+		epo.ObsList = append(epo.ObsList, rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGPS, Num: int8(i)}, map[string]rinex.Obs{
+			"C1C": {Val: 2e7 + float64(i)}, "C2W": {Val: 2e7 + float64(i)}, "C5Q": {Val: 2e7 + float64(i)}, "C1L": {Val: 2e7 + float64(i)},
+		}))
+	}
+	msgs := enc.Messages(epo)
+	if !assert.Len(msgs, 2) {
+		t.FailNow()
+	}
+	assert.Len(msgs[0].Sats, 16)
+	assert.Len(msgs[1].Sats, 4)
+	assert.True(msgs[0].MultipleMessage)
+	for _, msg := range msgs {
+		_, err := msg.MarshalBinary()
+		assert.NoError(err)
+	}
+}
+
+func TestEpochTime(t *testing.T) {
+	assert := assert.New(t)
+	t0 := time.Date(2020, 6, 18, 23, 59, 59, 0, time.UTC)
+	ref := t0.Add(90 * time.Minute)
+
+	header := func(num int, nbits int, epoch uint32) []byte {
+		w := &bitWriter{}
+		w.writeUint(12, uint64(num))
+		w.writeUint(12, 42)
+		w.writeUint(nbits, uint64(epoch))
+		w.writeUint(32, 0)
+		return w.buf
+	}
+	tod := msmEpoch(gnss.SysGLO, t0, 18) & 0x7FFFFFF
+	tests := []struct {
+		payload []byte
+		comment string
+	}{
+		{header(1077, 30, msmEpoch(gnss.SysGPS, t0, 18)), "GPS MSM7"},
+		{header(1074, 30, msmEpoch(gnss.SysGPS, t0, 18)), "GPS MSM4"},
+		{header(1085, 30, msmEpoch(gnss.SysGLO, t0, 18)), "GLONASS MSM5"},
+		{header(1121, 30, msmEpoch(gnss.SysBDS, t0, 18)), "BDS MSM1"},
+		{header(1004, 30, msmEpoch(gnss.SysGPS, t0, 18)), "GPS legacy"},
+		{header(1012, 27, tod), "GLONASS legacy"},
+	}
+	for _, tt := range tests {
+		got, ok := EpochTime(tt.payload, ref, 18)
+		assert.True(ok, tt.comment)
+		assert.Equal(t0, got, tt.comment)
+	}
+
+	for _, num := range []int{1005, 1019, 1078, 1147} {
+		_, ok := EpochTime(header(num, 30, 0), ref, 18)
+		assert.False(ok, "%d", num)
+	}
+	_, ok := EpochTime([]byte{0x43, 0x50}, ref, 18)
+	assert.False(ok, "too short")
+}
+
+func TestMSMSignals(t *testing.T) {
+	assert := assert.New(t)
+	epo := &rinex.Epoch{Time: time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC), ObsList: []rinex.SatObs{
+		rinex.NewSatObs(rinex.PRN{Sys: gnss.SysGAL, Num: 11}, map[string]rinex.Obs{"C1C": {Val: 25765119.381}, "C5Q": {Val: 25765121.745}}),
+	}}
+	payload, err := NewMSMEncoder(1).Messages(epo)[0].MarshalBinary()
+	assert.NoError(err)
+	sys, codes, ok := MSMSignals(payload)
+	assert.True(ok)
+	assert.Equal(gnss.SysGAL, sys)
+	assert.Equal([]string{"1C", "5Q"}, codes)
+
+	payload[1] = payload[1]&0x0F | 0x40 // 1094
+	sys, _, ok = MSMSignals(payload)
+	assert.True(ok, "MSM4")
+	assert.Equal(gnss.SysGAL, sys)
+
+	_, _, ok = MSMSignals(frame1005[3 : len(frame1005)-3])
+	assert.False(ok)
+	_, _, ok = MSMSignals(payload[:10])
+	assert.False(ok, "too short")
+}
@@ -0,0 +1,113 @@
+package rtcm3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// ReplayOptions configures the replay of a RINEX file.
+type ReplayOptions struct {
+	StationID uint16
+
+	// Speed is the replay speed factor, e.g. 1 for real time or 10 for ten times faster.
+	// Zero means no pacing, the messages are written as fast as possible.
+	Speed float64
+
+	// StationInterval is the interval of the station messages 1006 and 1033, defaults to 10 seconds.
+	StationInterval time.Duration
+
+	// Nav is an optional navigation file decoder. The GPS ephemerides are sent as message 1019
+	// as soon as they become valid.
+	Nav *rinex.NavDecoder
+}
+
+// Replay reads the epochs of the RINEX observation file and writes them as MSM7 messages to w, together
+// with the station messages 1006 and 1033 built from the header. The messages of an epoch are written
+// at once. Replay returns when all epochs are written, the context is canceled or an error occurs.
+func Replay(ctx context.Context, w io.Writer, dec *rinex.ObsDecoder, opts ReplayOptions) error {
+	if opts.StationInterval <= 0 {
+		opts.StationInterval = 10 * time.Second
+	}
+	hdr := &dec.Header
+	msmEnc := NewMSMEncoder(opts.StationID)
+	msmEnc.LeapSeconds = hdr.LeapSeconds
+	msmEnc.GloChannels = hdr.GloSlots
+	arp := NewStationARP(opts.StationID, hdr)
+	desc := NewDescriptor(opts.StationID, hdr)
+
+	var ephs []*rinex.EphGPS
+	if opts.Nav != nil {
+		for opts.Nav.NextEphemeris() {
+			if eph, ok := opts.Nav.Ephemeris().(*rinex.EphGPS); ok {
+				ephs = append(ephs, eph)
+			}
+		}
+		if err := opts.Nav.Err(); err != nil {
+			return fmt.Errorf("read navigation file: %v", err)
+		}
+		sort.Slice(ephs, func(i, j int) bool { return ephs[i].TOC.Before(ephs[j].TOC) })
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	var first, lastStation time.Time
+	start := time.Now()
+	for dec.NextEpoch() {
+		epo := dec.Epoch()
+		if epo.IsEvent() || epo.IsCycleSlip() {
+			continue
+		}
+		if first.IsZero() {
+			first = epo.Time
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if opts.Speed > 0 {
+			due := start.Add(time.Duration(float64(epo.Time.Sub(first)) / opts.Speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		buf.Reset()
+		if lastStation.IsZero() || epo.Time.Sub(lastStation) >= opts.StationInterval {
+			if err := enc.Encode(arp); err != nil {
+				return err
+			}
+			if err := enc.Encode(desc); err != nil {
+				return err
+			}
+			lastStation = epo.Time
+		}
+		for len(ephs) > 0 {
+			if from, _ := ephs[0].Validity(); from.After(epo.Time) {
+				break
+			}
+			if err := enc.Encode(&GPSEphemeris{*ephs[0]}); err != nil {
+				return err
+			}
+			ephs = ephs[1:]
+		}
+		for _, msg := range msmEnc.Messages(epo) {
+			if err := enc.Encode(msg); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return dec.Err()
+}
@@ -0,0 +1,92 @@
+package rtcm3
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGPSEphemeris(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("../rinex/testdata/white/AREG00PER_R_20201690000_01D_MN.rnx")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer r.Close()
+	nav, err := rinex.NewNavDecoder(r)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	var eph *rinex.EphGPS
+	for nav.NextEphemeris() {
+		if e, ok := nav.Ephemeris().(*rinex.EphGPS); ok {
+			eph = e
+			break
+		}
+	}
+	if !assert.NotNil(eph) {
+		t.FailNow()
+	}
+
+	msg := &GPSEphemeris{*eph}
+	data, err := msg.MarshalBinary()
+	assert.NoError(err)
+	assert.Len(data, 61)
+	decoded, err := Decode(data)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	eph2 := &decoded.(*GPSEphemeris).EphGPS
+	assert.Equal(eph.PRN, eph2.PRN)
+	assert.Equal(eph.TOC, eph2.TOC)
+	assert.Equal(eph.ToeWeek, eph2.ToeWeek)
+	assert.Equal(eph.IODE, eph2.IODE)
+	assert.Equal(eph.Toe, eph2.Toe)
+	assert.InDelta(eph.ClockBias, eph2.ClockBias, 1e-12)
+	assert.InDelta(eph.SqrtA, eph2.SqrtA, 1e-6)
+	assert.InDelta(eph.Ecc, eph2.Ecc, 1e-10)
+	assert.InDelta(eph.M0, eph2.M0, 1e-8)
+	assert.InDelta(eph.OmegaDot, eph2.OmegaDot, 1e-15)
+}
+
+func TestReplay(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("../rinex/testdata/white/BRUX00BEL_R_20183101900_01H_30S_MO.rnx")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer r.Close()
+	obs, err := rinex.NewObsDecoder(r)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	err = Replay(context.Background(), &buf, obs, ReplayOptions{StationID: 42, StationInterval: 5 * time.Minute})
+	assert.NoError(err)
+
+	counts := map[int]int{}
+	dec := NewDecoder(&buf)
+	for dec.Next() {
+		counts[dec.Message().Number()]++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(12, counts[1006])
+	assert.Equal(12, counts[1033])
+	assert.Equal(120, counts[1077])
+	assert.Equal(120, counts[1087])
+	assert.Equal(0, dec.NumCRCErrors)
+
+	// canceled context
+	r.Seek(0, 0)
+	obs, _ = rinex.NewObsDecoder(r)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Replay(ctx, &buf, obs, ReplayOptions{Speed: 1})
+	assert.Equal(context.Canceled, err)
+}
@@ -0,0 +1,316 @@
+// Package rtcm3 provides functions for decoding and encoding RTCM 3 messages.
+//
+// Supported are the frame level, the station messages 1005/1006 and 1033, the GPS ephemeris 1019,
+// the Multiple Signal Messages MSM7 and the State Space Representation (SSR) messages with orbit, clock,
+// code and phase bias, URA and high rate clock corrections for GPS, GLONASS, Galileo, QZSS, SBAS and BDS.
+// The IGS-SSR format is not supported. The transformation messages 1021-1027 deliver the Helmert
+// parameters, residual grids and projections of datum transformations. Other messages are returned
+// as Unknown. The MSMEncoder builds MSM7 messages from RINEX epochs, so that RINEX files can be replayed
+// as RTCM stream, see Replay.
+// EpochTime returns the epoch time of any MSM or legacy RTK observation message without decoding it.
+// The Analyzer reports the message types, rates, MSM signals and station information of a stream.
+package rtcm3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/de-bkg/gognss/pkg/crc"
+)
+
+const (
+	preamble = 0xD3
+
+	// MaxPayload is the maximum length of a message.
+	MaxPayload = 1023
+)
+
+// Message is a RTCM 3 message.
+type Message interface {
+	// Number returns the message number, e.g. 1005.
+	Number() int
+
+	// MarshalBinary returns the message payload without the frame.
+	MarshalBinary() ([]byte, error)
+}
+
+// Unknown is a message not decoded by this package.
+type Unknown struct {
+	Payload []byte
+}
+
+// Number returns the message number.
+func (m *Unknown) Number() int { return msgNum(m.Payload) }
+
+// MarshalBinary returns the payload.
+func (m *Unknown) MarshalBinary() ([]byte, error) { return m.Payload, nil }
+
+// msgNum returns the message number from the first 12 bits of the payload.
+func msgNum(payload []byte) int {
+	if len(payload) < 2 {
+		return 0
+	}
+	return int(payload[0])<<4 | int(payload[1]>>4)
+}
+
+// Decode decodes the message payload.
+func Decode(payload []byte) (Message, error) {
+	num := msgNum(payload)
+	var msg interface {
+		Message
+		UnmarshalBinary(data []byte) error
+	}
+	switch {
+	case num == 1005 || num == 1006:
+		msg = &StationARP{}
+	case num == 1019:
+		msg = &GPSEphemeris{}
+	case num == 1021 || num == 1022:
+		msg = &Helmert{}
+	case num == 1023:
+		msg = &EllipsoidalGrid{}
+	case num == 1024:
+		msg = &PlaneGrid{}
+	case num == 1025:
+		msg = &Projection{}
+	case num == 1026:
+		msg = &ProjectionLCC2SP{}
+	case num == 1027:
+		msg = &ProjectionOM{}
+	case num == 1033:
+		msg = &Descriptor{}
+	case isMSM7(num):
+		msg = &MSM7{}
+	case newSSR(num) != nil:
+		msg = newSSR(num)
+	default:
+		return &Unknown{Payload: payload}, nil
+	}
+	if err := msg.UnmarshalBinary(payload); err != nil {
+		return nil, fmt.Errorf("decode message %d: %v", num, err)
+	}
+	return msg, nil
+}
+
+// Decoder reads and decodes RTCM 3 messages from an input stream. Data between the frames and frames
+// with invalid CRC are skipped.
+type Decoder struct {
+	r       *bufio.Reader
+	msg     Message
+	payload []byte
+	err     error
+
+	// NumCRCErrors counts the frames skipped due to invalid checksums.
+	NumCRCErrors int
+}
+
+// NewDecoder creates a new decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 4096)}
+}
+
+// Err returns the first non-EOF error that was encountered by the decoder.
+func (dec *Decoder) Err() error {
+	if dec.err == io.EOF || dec.err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return dec.err
+}
+
+// Message returns the most recent message generated by a call to Next.
+func (dec *Decoder) Message() Message {
+	return dec.msg
+}
+
+// Payload returns the payload of the most recent frame read by Next or NextFrame.
+func (dec *Decoder) Payload() []byte {
+	return dec.payload
+}
+
+// Next reads the next message.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (dec *Decoder) Next() bool {
+	if !dec.NextFrame() {
+		return false
+	}
+	msg, err := Decode(dec.payload)
+	if err != nil {
+		dec.err = err
+		return false
+	}
+	dec.msg = msg
+	return true
+}
+
+// NextFrame reads the next frame without decoding the message, see Payload. Unlike Next, it does not
+// stop at messages that cannot be decoded.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (dec *Decoder) NextFrame() bool {
+	payload, err := dec.readFrame()
+	if err != nil {
+		dec.err = err
+		return false
+	}
+	dec.payload = payload
+	return true
+}
+
+// readFrame reads the next valid frame and returns its payload.
+func (dec *Decoder) readFrame() ([]byte, error) {
+	for {
+		b, err := dec.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != preamble {
+			continue
+		}
+
+		hdr, err := dec.r.Peek(2)
+		if err != nil {
+			return nil, err
+		}
+		if hdr[0]&0xFC != 0 { // reserved bits
+			continue
+		}
+		length := int(hdr[0])<<8 | int(hdr[1])
+		frame, err := dec.r.Peek(2 + length + 3)
+		if err == io.EOF && len(frame) > 0 { // false sync near the end of the input
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := uint32(frame[2+length])<<16 | uint32(frame[2+length+1])<<8 | uint32(frame[2+length+2])
+		if crc.CRC24Q(append([]byte{preamble}, frame[:2+length]...)) != sum {
+			dec.NumCRCErrors++
+			continue
+		}
+		payload := make([]byte, length)
+		copy(payload, frame[2:])
+		if _, err := dec.r.Discard(2 + length + 3); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+}
+
+// Encoder writes RTCM 3 frames to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the message as RTCM 3 frame.
+func (enc *Encoder) Encode(msg Message) error {
+	payload, err := msg.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("encode message %d: %v", msg.Number(), err)
+	}
+	frame, err := EncodeFrame(payload)
+	if err != nil {
+		return fmt.Errorf("encode message %d: %v", msg.Number(), err)
+	}
+	_, err = enc.w.Write(frame)
+	return err
+}
+
+// EncodeFrame returns the RTCM 3 frame for the payload, that is the preamble, the length,
+// the payload and the CRC-24Q.
+func EncodeFrame(payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, fmt.Errorf("payload too long: %d bytes", len(payload))
+	}
+	frame := make([]byte, 0, len(payload)+6)
+	frame = append(frame, preamble, byte(len(payload)>>8), byte(len(payload)))
+	frame = append(frame, payload...)
+	h := crc.New24Q()
+	h.Write(frame)
+	return h.Sum(frame), nil
+}
+
+// bitWriter writes values of arbitrary bit length, MSB first.
+type bitWriter struct {
+	buf []byte
+	n   int // number of bits written
+}
+
+func (w *bitWriter) writeUint(nbits int, v uint64) {
+	for i := nbits - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>uint(i)&1 != 0 {
+			w.buf[w.n/8] |= 1 << uint(7-w.n%8)
+		}
+		w.n++
+	}
+}
+
+func (w *bitWriter) writeInt(nbits int, v int64) {
+	w.writeUint(nbits, uint64(v)&(1<<uint(nbits)-1))
+}
+
+func (w *bitWriter) writeBool(b bool) {
+	if b {
+		w.writeUint(1, 1)
+	} else {
+		w.writeUint(1, 0)
+	}
+}
+
+func (w *bitWriter) writeString(s string) {
+	w.writeUint(8, uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		w.writeUint(8, uint64(s[i]))
+	}
+}
+
+// bitReader reads values of arbitrary bit length, MSB first, and keeps the first error.
+type bitReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *bitReader) readUint(nbits int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos+nbits > len(r.buf)*8 {
+		r.err = fmt.Errorf("message too short: %d bytes", len(r.buf))
+		return 0
+	}
+	var v uint64
+	for i := 0; i < nbits; i++ {
+		v = v<<1 | uint64(r.buf[r.pos/8]>>uint(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) readInt(nbits int) int64 {
+	v := r.readUint(nbits)
+	if v&(1<<uint(nbits-1)) != 0 {
+		return int64(v) - 1<<uint(nbits)
+	}
+	return int64(v)
+}
+
+func (r *bitReader) readBool() bool {
+	return r.readUint(1) == 1
+}
+
+func (r *bitReader) readString() string {
+	n := int(r.readUint(8))
+	b := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		b = append(b, byte(r.readUint(8)))
+	}
+	return string(b)
+}
@@ -0,0 +1,92 @@
+package rtcm3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+// frame1005 is the example message 1005 of the RTCM standard 10403.3.
+var frame1005 = []byte{0xD3, 0x00, 0x13, 0x3E, 0xD7, 0xD3, 0x02, 0x02, 0x98, 0x0E, 0xDE, 0xEF, 0x34, 0xB4, 0xBD,
+	0x62, 0xAC, 0x09, 0x41, 0x98, 0x6F, 0x33, 0x36, 0x0B, 0x98}
+
+func TestEncodeFrame(t *testing.T) {
+	assert := assert.New(t)
+	n := len(frame1005)
+	frame, err := EncodeFrame(frame1005[3 : n-3])
+	assert.NoError(err)
+	assert.Equal(frame1005, frame)
+
+	_, err = EncodeFrame(make([]byte, MaxPayload+1))
+	assert.Error(err)
+}
+
+func TestStationARP(t *testing.T) {
+	assert := assert.New(t)
+	msg, err := Decode(frame1005[3 : len(frame1005)-3])
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	arp, ok := msg.(*StationARP)
+	if !assert.True(ok) {
+		t.FailNow()
+	}
+	assert.Equal(1005, arp.Number())
+	assert.Equal(uint16(2003), arp.StationID)
+	assert.True(arp.GPS)
+	assert.False(arp.GLONASS)
+	assert.InDelta(1114104.5999, arp.Position.X, 1e-6)
+	assert.InDelta(-4850729.7108, arp.Position.Y, 1e-6)
+	assert.InDelta(3975521.4643, arp.Position.Z, 1e-6)
+
+	arp = &StationARP{MsgNum: 1006, StationID: 42, GPS: true, Galileo: true,
+		Position: rinex.Coord{X: 4075580.3515, Y: 931854.0113, Z: 4801568.1924}, AntennaHeight: 0.0712}
+	data, err := arp.MarshalBinary()
+	assert.NoError(err)
+	assert.Len(data, 21)
+	arp2 := &StationARP{}
+	assert.NoError(arp2.UnmarshalBinary(data))
+	assert.InDelta(arp.Position.X, arp2.Position.X, 1e-6)
+	assert.InDelta(arp.AntennaHeight, arp2.AntennaHeight, 1e-6)
+	assert.True(arp2.Galileo)
+}
+
+func TestDescriptor(t *testing.T) {
+	assert := assert.New(t)
+	hdr := &rinex.ObsHeader{AntennaType: "LEIAR25.R3      LEIT", AntennaNumber: "09370013",
+		ReceiverType: "SEPT POLARX5", ReceiverVersion: "5.3.2", ReceiverNumber: "3047937"}
+	desc := NewDescriptor(42, hdr)
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	assert.NoError(enc.Encode(desc))
+	assert.NoError(enc.Encode(&Unknown{Payload: []byte{0x3F, 0x20, 0x01}})) // 1010
+
+	dec := NewDecoder(&buf)
+	assert.True(dec.Next())
+	assert.Equal(desc, dec.Message())
+	assert.True(dec.Next())
+	assert.Equal(1010, dec.Message().Number())
+	assert.False(dec.Next())
+	assert.NoError(dec.Err())
+}
+
+func TestDecoder_resync(t *testing.T) {
+	assert := assert.New(t)
+	corrupt := append([]byte{}, frame1005...)
+	corrupt[10]++
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0x00, 0xD3, 0xFF, 0x12})
+	buf.Write(corrupt)
+	buf.Write(frame1005)
+
+	dec := NewDecoder(&buf)
+	assert.True(dec.Next())
+	assert.Equal(1005, dec.Message().Number())
+	assert.Equal(1, dec.NumCRCErrors)
+	assert.False(dec.Next())
+	assert.NoError(dec.Err())
+}
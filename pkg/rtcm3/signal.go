@@ -0,0 +1,60 @@
+package rtcm3
+
+import (
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// speedOfLight in m/s.
+const speedOfLight = gnss.SpeedOfLight
+
+// rangeMs is the distance in meters light travels in one millisecond.
+const rangeMs = speedOfLight / 1000
+
+// msmSignals maps the MSM signal IDs to the RINEX 3 frequency band and attribute, see RTCM 10403.3 table 3.5-91 ff.
+var msmSignals = map[gnss.System]map[int]string{
+	gnss.SysGPS: {2: "1C", 3: "1P", 4: "1W", 8: "2C", 9: "2P", 10: "2W", 15: "2S", 16: "2L", 17: "2X",
+		22: "5I", 23: "5Q", 24: "5X", 30: "1S", 31: "1L", 32: "1X"},
+	gnss.SysGLO: {2: "1C", 3: "1P", 8: "2C", 9: "2P"},
+	gnss.SysGAL: {2: "1C", 3: "1A", 4: "1B", 5: "1X", 6: "1Z", 8: "6C", 9: "6A", 10: "6B", 11: "6X", 12: "6Z",
+		14: "7I", 15: "7Q", 16: "7X", 18: "8I", 19: "8Q", 20: "8X", 22: "5I", 23: "5Q", 24: "5X"},
+	gnss.SysSBAS: {2: "1C", 22: "5I", 23: "5Q", 24: "5X"},
+	gnss.SysQZSS: {2: "1C", 9: "6S", 10: "6L", 11: "6X", 15: "2S", 16: "2L", 17: "2X", 22: "5I", 23: "5Q", 24: "5X",
+		30: "1S", 31: "1L", 32: "1X"},
+	gnss.SysBDS: {2: "2I", 3: "2Q", 4: "2X", 8: "6I", 9: "6Q", 10: "6X", 14: "7I", 15: "7Q", 16: "7X",
+		22: "5D", 23: "5P", 24: "5X", 25: "7D", 30: "1D", 31: "1P", 32: "1X"},
+	gnss.SysIRNSS: {22: "5A"},
+}
+
+// ObsCode returns the RINEX 3 frequency band and attribute of the MSM signal, e.g. "1C",
+// or an empty string if the signal is unknown.
+func ObsCode(sys gnss.System, sigID int) string {
+	return msmSignals[sys][sigID]
+}
+
+// SignalID returns the MSM signal ID of the RINEX 3 frequency band and attribute, e.g. "1C",
+// or 0 if there is no such signal.
+func SignalID(sys gnss.System, code string) int {
+	for id, c := range msmSignals[sys] {
+		if c == code {
+			return id
+		}
+	}
+	return 0
+}
+
+// wavelength returns the wavelength in meters of the signal, e.g. "1C", or 0 if unknown.
+// The GLONASS frequency channel is required for the FDMA signals.
+func wavelength(sys gnss.System, code string, gloChannel int, hasChannel bool) float64 {
+	info, ok := rinex.ObsCode("L" + code).Lookup(sys)
+	if !ok {
+		return 0
+	}
+	if info.FDMA {
+		if !hasChannel {
+			return 0
+		}
+		return speedOfLight / rinex.GLOFrequency(info.Band, gloChannel)
+	}
+	return info.Wavelength()
+}
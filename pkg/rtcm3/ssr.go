@@ -0,0 +1,671 @@
+package rtcm3
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// SSR message types, as offset to the first SSR message number of the satellite system.
+const (
+	ssrOrbit = iota
+	ssrClock
+	ssrCodeBias
+	ssrOrbitClock
+	ssrURA
+	ssrHighRateClock
+	ssrPhaseBias
+)
+
+// ssrNumbers are the numbers of the SSR orbit messages per satellite system, the other SSR messages follow
+// in the order of the SSR message types.
+var ssrNumbers = map[gnss.System]int{
+	gnss.SysGPS:  1057,
+	gnss.SysGLO:  1063,
+	gnss.SysGAL:  1240,
+	gnss.SysQZSS: 1246,
+	gnss.SysSBAS: 1252,
+	gnss.SysBDS:  1258,
+}
+
+// ssrPhaseBiasNumbers are the numbers of the SSR phase bias messages per satellite system.
+var ssrPhaseBiasNumbers = map[gnss.System]int{
+	gnss.SysGPS:  1265,
+	gnss.SysGLO:  1266,
+	gnss.SysGAL:  1267,
+	gnss.SysQZSS: 1268,
+	gnss.SysSBAS: 1269,
+	gnss.SysBDS:  1270,
+}
+
+// ssrFormat holds the lengths of the system dependent SSR fields in bits.
+type ssrFormat struct {
+	count  int // number of satellites
+	id     int // satellite ID
+	iod    int // issue of data
+	crc    int // IOD CRC
+	offset int // satellite number of ID 0
+}
+
+var ssrFormats = map[gnss.System]ssrFormat{
+	gnss.SysGPS:  {count: 6, id: 6, iod: 8},
+	gnss.SysGLO:  {count: 6, id: 5, iod: 8},
+	gnss.SysGAL:  {count: 6, id: 6, iod: 10},
+	gnss.SysQZSS: {count: 4, id: 4, iod: 8},
+	gnss.SysSBAS: {count: 6, id: 6, iod: 9, crc: 24, offset: 20},
+	gnss.SysBDS:  {count: 6, id: 6, iod: 10, crc: 24, offset: 1},
+}
+
+// ssrSignals maps the signal and tracking mode identifiers of the SSR bias messages to the RINEX 3
+// frequency bands and attributes.
+var ssrSignals = map[gnss.System]map[int]string{
+	gnss.SysGPS: {0: "1C", 1: "1P", 2: "1W", 3: "1S", 4: "1L", 5: "2C", 6: "2D", 7: "2S", 8: "2L", 9: "2X",
+		10: "2P", 11: "2W", 14: "5I", 15: "5Q"},
+	gnss.SysGLO: {0: "1C", 1: "1P", 2: "2C", 3: "2P", 4: "4A", 5: "4B", 6: "6A", 7: "6B", 8: "3I", 9: "3Q"},
+	gnss.SysGAL: {0: "1A", 1: "1B", 2: "1C", 5: "5I", 6: "5Q", 8: "7I", 9: "7Q", 11: "8I", 12: "8Q",
+		14: "6A", 15: "6B", 16: "6C"},
+	gnss.SysQZSS: {0: "1C", 1: "1S", 2: "1L", 3: "2S", 4: "2L", 6: "5I", 7: "5Q", 9: "6S", 10: "6L", 17: "6E"},
+	gnss.SysSBAS: {0: "1C", 1: "5I", 2: "5Q"},
+	gnss.SysBDS: {0: "2I", 1: "2Q", 3: "6I", 4: "6Q", 6: "7I", 7: "7Q", 9: "1D", 10: "1P", 12: "5D", 13: "5P",
+		15: "1A", 18: "6A"},
+}
+
+// ssrIntervals are the SSR update intervals in seconds.
+var ssrIntervals = [16]int{1, 2, 5, 10, 15, 30, 60, 120, 240, 300, 600, 900, 1800, 3600, 7200, 10800}
+
+// SSRSignalCode returns the RINEX 3 frequency band and attribute of the signal identifier of the SSR code
+// and phase bias messages, e.g. "1C", or an empty string if the signal is unknown.
+func SSRSignalCode(sys gnss.System, sigID int) string {
+	return ssrSignals[sys][sigID]
+}
+
+// ssrType returns the satellite system and the SSR message type of the message number.
+func ssrType(num int) (gnss.System, int, bool) {
+	for sys, n := range ssrNumbers {
+		if num >= n && num <= n+ssrHighRateClock {
+			return sys, num - n, true
+		}
+	}
+	for sys, n := range ssrPhaseBiasNumbers {
+		if num == n {
+			return sys, ssrPhaseBias, true
+		}
+	}
+	return 0, 0, false
+}
+
+// newSSR returns the message for the SSR message number, nil for other messages.
+func newSSR(num int) interface {
+	Message
+	UnmarshalBinary(data []byte) error
+} {
+	_, typ, ok := ssrType(num)
+	if !ok {
+		return nil
+	}
+	switch typ {
+	case ssrOrbit:
+		return &SSROrbit{}
+	case ssrClock:
+		return &SSRClock{}
+	case ssrCodeBias:
+		return &SSRCodeBiases{}
+	case ssrOrbitClock:
+		return &SSROrbitClock{}
+	case ssrURA:
+		return &SSRURA{}
+	case ssrHighRateClock:
+		return &SSRHighRateClock{}
+	default:
+		return &SSRPhaseBiases{}
+	}
+}
+
+// SSRHeader is the header of the State Space Representation messages.
+type SSRHeader struct {
+	MsgNum          int    // e.g. 1060 for the GPS orbit and clock corrections
+	Epoch           uint32 // epoch time in seconds of the week, for GLONASS seconds of the day
+	UpdateInterval  uint8  // SSR update interval indicator, see Interval
+	MultipleMessage bool   // more messages follow for the same epoch
+	RegionalDatum   bool   // satellite reference datum of the orbit corrections, ITRF if false
+	IODSSR          uint8  // issue of data SSR
+	ProviderID      uint16
+	SolutionID      uint8
+}
+
+// Number returns the message number.
+func (h *SSRHeader) Number() int { return h.MsgNum }
+
+// System returns the satellite system of the message.
+func (h *SSRHeader) System() gnss.System {
+	sys, _, _ := ssrType(h.MsgNum)
+	return sys
+}
+
+// Interval returns the SSR update interval.
+func (h *SSRHeader) Interval() time.Duration {
+	return time.Duration(ssrIntervals[h.UpdateInterval&0xF]) * time.Second
+}
+
+// Time returns the epoch time in GPS time, resolved like MSM7.Time.
+func (h *SSRHeader) Time(ref time.Time, leapSeconds int) time.Time {
+	sys := h.System()
+	m := &MSM7{MsgNum: msm7Numbers[sys], Epoch: h.Epoch * 1000}
+	if sys == gnss.SysGLO {
+		m.Epoch |= 7 << 27 // day of week unknown
+	}
+	return m.Time(ref, leapSeconds)
+}
+
+// read decodes the header up to the solution ID and returns the satellite system.
+func (h *SSRHeader) read(r *bitReader, typ int) gnss.System {
+	h.MsgNum = int(r.readUint(12))
+	sys, t, ok := ssrType(h.MsgNum)
+	if !ok || t != typ {
+		if r.err == nil {
+			r.err = fmt.Errorf("invalid message number %d", h.MsgNum)
+		}
+		return 0
+	}
+	h.Epoch = uint32(r.readUint(ssrEpochBits(sys)))
+	h.UpdateInterval = uint8(r.readUint(4))
+	h.MultipleMessage = r.readBool()
+	if typ == ssrOrbit || typ == ssrOrbitClock {
+		h.RegionalDatum = r.readBool()
+	}
+	h.IODSSR = uint8(r.readUint(4))
+	h.ProviderID = uint16(r.readUint(16))
+	h.SolutionID = uint8(r.readUint(4))
+	return sys
+}
+
+// write encodes the header up to the solution ID and returns the satellite system.
+func (h *SSRHeader) write(w *bitWriter, typ int) (gnss.System, error) {
+	sys, t, ok := ssrType(h.MsgNum)
+	if !ok || t != typ {
+		return 0, fmt.Errorf("invalid message number %d", h.MsgNum)
+	}
+	w.writeUint(12, uint64(h.MsgNum))
+	w.writeUint(ssrEpochBits(sys), uint64(h.Epoch))
+	w.writeUint(4, uint64(h.UpdateInterval))
+	w.writeBool(h.MultipleMessage)
+	if typ == ssrOrbit || typ == ssrOrbitClock {
+		w.writeBool(h.RegionalDatum)
+	}
+	w.writeUint(4, uint64(h.IODSSR))
+	w.writeUint(16, uint64(h.ProviderID))
+	w.writeUint(4, uint64(h.SolutionID))
+	return sys, nil
+}
+
+func ssrEpochBits(sys gnss.System) int {
+	if sys == gnss.SysGLO {
+		return 17
+	}
+	return 20
+}
+
+// readSatCount reads the number of satellites.
+func readSatCount(r *bitReader, sys gnss.System) int {
+	return int(r.readUint(ssrFormats[sys].count))
+}
+
+// writeSatCount writes the number of satellites.
+func writeSatCount(w *bitWriter, sys gnss.System, n int) error {
+	if bits := ssrFormats[sys].count; n >= 1<<uint(bits) {
+		return fmt.Errorf("too many satellites: %d", n)
+	}
+	w.writeUint(ssrFormats[sys].count, uint64(n))
+	return nil
+}
+
+// readSatID reads the satellite ID and returns the satellite.
+func readSatID(r *bitReader, sys gnss.System) rinex.PRN {
+	f := ssrFormats[sys]
+	return rinex.PRN{Sys: sys, Num: int8(int(r.readUint(f.id)) + f.offset)}
+}
+
+// writeSatID writes the satellite ID of the satellite.
+func writeSatID(w *bitWriter, sys gnss.System, prn rinex.PRN) error {
+	f := ssrFormats[sys]
+	id := int(prn.Num) - f.offset
+	if prn.Sys != sys || id < 0 || id >= 1<<uint(f.id) {
+		return fmt.Errorf("invalid satellite %s", prn)
+	}
+	w.writeUint(f.id, uint64(id))
+	return nil
+}
+
+// SSROrbitSat holds the orbit corrections of a satellite in the radial, along-track and cross-track
+// components, relative to the broadcast ephemeris.
+type SSROrbitSat struct {
+	PRN    rinex.PRN
+	IODE   int // issue of data of the ephemeris, for BDS the toe modulo
+	IODCRC int // BDS and SBAS only
+
+	Radial, AlongTrack, CrossTrack          float64 // m
+	DotRadial, DotAlongTrack, DotCrossTrack float64 // m/s
+}
+
+func (sat *SSROrbitSat) read(r *bitReader, sys gnss.System) {
+	f := ssrFormats[sys]
+	sat.PRN = readSatID(r, sys)
+	sat.IODE = int(r.readUint(f.iod))
+	sat.IODCRC = int(r.readUint(f.crc))
+	sat.Radial = float64(r.readInt(22)) * 1e-4
+	sat.AlongTrack = float64(r.readInt(20)) * 4e-4
+	sat.CrossTrack = float64(r.readInt(20)) * 4e-4
+	sat.DotRadial = float64(r.readInt(21)) * 1e-6
+	sat.DotAlongTrack = float64(r.readInt(19)) * 4e-6
+	sat.DotCrossTrack = float64(r.readInt(19)) * 4e-6
+}
+
+func (sat *SSROrbitSat) write(w *bitWriter, sys gnss.System) error {
+	f := ssrFormats[sys]
+	if err := writeSatID(w, sys, sat.PRN); err != nil {
+		return err
+	}
+	w.writeUint(f.iod, uint64(sat.IODE))
+	w.writeUint(f.crc, uint64(sat.IODCRC))
+	w.writeInt(22, int64(math.Round(sat.Radial/1e-4)))
+	w.writeInt(20, int64(math.Round(sat.AlongTrack/4e-4)))
+	w.writeInt(20, int64(math.Round(sat.CrossTrack/4e-4)))
+	w.writeInt(21, int64(math.Round(sat.DotRadial/1e-6)))
+	w.writeInt(19, int64(math.Round(sat.DotAlongTrack/4e-6)))
+	w.writeInt(19, int64(math.Round(sat.DotCrossTrack/4e-6)))
+	return nil
+}
+
+// SSRClockSat holds the clock correction polynomial of a satellite, relative to the broadcast ephemeris.
+type SSRClockSat struct {
+	PRN rinex.PRN
+	C0  float64 // m
+	C1  float64 // m/s
+	C2  float64 // m/s²
+}
+
+func (sat *SSRClockSat) read(r *bitReader) {
+	sat.C0 = float64(r.readInt(22)) * 1e-4
+	sat.C1 = float64(r.readInt(21)) * 1e-6
+	sat.C2 = float64(r.readInt(27)) * 2e-8
+}
+
+func (sat *SSRClockSat) write(w *bitWriter) {
+	w.writeInt(22, int64(math.Round(sat.C0/1e-4)))
+	w.writeInt(21, int64(math.Round(sat.C1/1e-6)))
+	w.writeInt(27, int64(math.Round(sat.C2/2e-8)))
+}
+
+// SSROrbit is the SSR orbit correction message, e.g. 1057 for GPS.
+type SSROrbit struct {
+	SSRHeader
+	Sats []SSROrbitSat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSROrbit) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrOrbit)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for i := range m.Sats {
+		if err := m.Sats[i].write(w, sys); err != nil {
+			return nil, err
+		}
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSROrbit) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrOrbit)
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		var sat SSROrbitSat
+		sat.read(r, sys)
+		m.Sats = append(m.Sats, sat)
+	}
+	return r.err
+}
+
+// SSRClock is the SSR clock correction message, e.g. 1058 for GPS.
+type SSRClock struct {
+	SSRHeader
+	Sats []SSRClockSat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSRClock) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrClock)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for i := range m.Sats {
+		if err := writeSatID(w, sys, m.Sats[i].PRN); err != nil {
+			return nil, err
+		}
+		m.Sats[i].write(w)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSRClock) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrClock)
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		sat := SSRClockSat{PRN: readSatID(r, sys)}
+		sat.read(r)
+		m.Sats = append(m.Sats, sat)
+	}
+	return r.err
+}
+
+// SSROrbitClockSat holds the orbit and clock corrections of a satellite.
+type SSROrbitClockSat struct {
+	SSROrbitSat
+	C0 float64 // m
+	C1 float64 // m/s
+	C2 float64 // m/s²
+}
+
+// SSROrbitClock is the combined SSR orbit and clock correction message, e.g. 1060 for GPS.
+type SSROrbitClock struct {
+	SSRHeader
+	Sats []SSROrbitClockSat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSROrbitClock) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrOrbitClock)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for i := range m.Sats {
+		sat := &m.Sats[i]
+		if err := sat.SSROrbitSat.write(w, sys); err != nil {
+			return nil, err
+		}
+		clk := SSRClockSat{C0: sat.C0, C1: sat.C1, C2: sat.C2}
+		clk.write(w)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSROrbitClock) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrOrbitClock)
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		var sat SSROrbitClockSat
+		sat.SSROrbitSat.read(r, sys)
+		var clk SSRClockSat
+		clk.read(r)
+		sat.C0, sat.C1, sat.C2 = clk.C0, clk.C1, clk.C2
+		m.Sats = append(m.Sats, sat)
+	}
+	return r.err
+}
+
+// SSRURASat holds the user range accuracy of a satellite.
+type SSRURASat struct {
+	PRN rinex.PRN
+	URA uint8 // SSR URA indicator, see Meters
+}
+
+// Meters returns the user range accuracy in meters, 0 if undefined.
+func (sat *SSRURASat) Meters() float64 {
+	if sat.URA == 0 {
+		return 0
+	}
+	class, value := float64(sat.URA>>3), float64(sat.URA&7)
+	return (math.Pow(3, class)*(1+value/4) - 1) * 1e-3
+}
+
+// SSRURA is the SSR user range accuracy message, e.g. 1061 for GPS.
+type SSRURA struct {
+	SSRHeader
+	Sats []SSRURASat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSRURA) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrURA)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for _, sat := range m.Sats {
+		if err := writeSatID(w, sys, sat.PRN); err != nil {
+			return nil, err
+		}
+		w.writeUint(6, uint64(sat.URA))
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSRURA) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrURA)
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		m.Sats = append(m.Sats, SSRURASat{PRN: readSatID(r, sys), URA: uint8(r.readUint(6))})
+	}
+	return r.err
+}
+
+// SSRHighRateClockSat holds the high rate clock correction of a satellite, to be added to the polynomial
+// of the clock correction message.
+type SSRHighRateClockSat struct {
+	PRN   rinex.PRN
+	Clock float64 // m
+}
+
+// SSRHighRateClock is the SSR high rate clock correction message, e.g. 1062 for GPS.
+type SSRHighRateClock struct {
+	SSRHeader
+	Sats []SSRHighRateClockSat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSRHighRateClock) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrHighRateClock)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for _, sat := range m.Sats {
+		if err := writeSatID(w, sys, sat.PRN); err != nil {
+			return nil, err
+		}
+		w.writeInt(22, int64(math.Round(sat.Clock/1e-4)))
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSRHighRateClock) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrHighRateClock)
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		m.Sats = append(m.Sats, SSRHighRateClockSat{PRN: readSatID(r, sys), Clock: float64(r.readInt(22)) * 1e-4})
+	}
+	return r.err
+}
+
+// SSRCodeBias is the code bias of a signal.
+type SSRCodeBias struct {
+	Signal int     // signal and tracking mode identifier, see SSRSignalCode
+	Bias   float64 // m
+}
+
+// SSRCodeBiasSat holds the code biases of a satellite.
+type SSRCodeBiasSat struct {
+	PRN    rinex.PRN
+	Biases []SSRCodeBias
+}
+
+// SSRCodeBiases is the SSR code bias message, e.g. 1059 for GPS.
+type SSRCodeBiases struct {
+	SSRHeader
+	Sats []SSRCodeBiasSat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSRCodeBiases) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrCodeBias)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for _, sat := range m.Sats {
+		if err := writeSatID(w, sys, sat.PRN); err != nil {
+			return nil, err
+		}
+		if len(sat.Biases) > 31 {
+			return nil, fmt.Errorf("%s: too many biases: %d", sat.PRN, len(sat.Biases))
+		}
+		w.writeUint(5, uint64(len(sat.Biases)))
+		for _, b := range sat.Biases {
+			w.writeUint(5, uint64(b.Signal))
+			w.writeInt(14, int64(math.Round(b.Bias/0.01)))
+		}
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSRCodeBiases) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrCodeBias)
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		sat := SSRCodeBiasSat{PRN: readSatID(r, sys)}
+		nb := int(r.readUint(5))
+		for j := 0; j < nb && r.err == nil; j++ {
+			sat.Biases = append(sat.Biases, SSRCodeBias{Signal: int(r.readUint(5)), Bias: float64(r.readInt(14)) * 0.01})
+		}
+		m.Sats = append(m.Sats, sat)
+	}
+	return r.err
+}
+
+// SSRPhaseBias is the phase bias of a signal.
+type SSRPhaseBias struct {
+	Signal          int     // signal and tracking mode identifier, see SSRSignalCode
+	Integer         bool    // the signal has integer property
+	WideLaneInteger uint8   // wide-lane integer indicator
+	Discontinuity   uint8   // discontinuity counter
+	Bias            float64 // m
+}
+
+// SSRPhaseBiasSat holds the phase biases of a satellite.
+type SSRPhaseBiasSat struct {
+	PRN      rinex.PRN
+	YawAngle float64 // rad
+	YawRate  float64 // rad/s
+	Biases   []SSRPhaseBias
+}
+
+// SSRPhaseBiases is the SSR phase bias message, e.g. 1265 for GPS.
+type SSRPhaseBiases struct {
+	SSRHeader
+	DispersiveBiasConsistency bool
+	MWConsistency             bool // Melbourne-Wübbena consistency
+	Sats                      []SSRPhaseBiasSat
+}
+
+// MarshalBinary returns the message payload.
+func (m *SSRPhaseBiases) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	sys, err := m.write(w, ssrPhaseBias)
+	if err != nil {
+		return nil, err
+	}
+	w.writeBool(m.DispersiveBiasConsistency)
+	w.writeBool(m.MWConsistency)
+	if err := writeSatCount(w, sys, len(m.Sats)); err != nil {
+		return nil, err
+	}
+	for _, sat := range m.Sats {
+		if err := writeSatID(w, sys, sat.PRN); err != nil {
+			return nil, err
+		}
+		if len(sat.Biases) > 31 {
+			return nil, fmt.Errorf("%s: too many biases: %d", sat.PRN, len(sat.Biases))
+		}
+		w.writeUint(5, uint64(len(sat.Biases)))
+		w.writeUint(9, uint64(math.Round(sat.YawAngle/math.Pi*256)))
+		w.writeInt(8, int64(math.Round(sat.YawRate/math.Pi*8192)))
+		for _, b := range sat.Biases {
+			w.writeUint(5, uint64(b.Signal))
+			w.writeBool(b.Integer)
+			w.writeUint(2, uint64(b.WideLaneInteger))
+			w.writeUint(4, uint64(b.Discontinuity))
+			w.writeInt(20, int64(math.Round(b.Bias/1e-4)))
+		}
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *SSRPhaseBiases) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	sys := m.read(r, ssrPhaseBias)
+	m.DispersiveBiasConsistency = r.readBool()
+	m.MWConsistency = r.readBool()
+	n := readSatCount(r, sys)
+	m.Sats = nil
+	for i := 0; i < n && r.err == nil; i++ {
+		sat := SSRPhaseBiasSat{PRN: readSatID(r, sys)}
+		nb := int(r.readUint(5))
+		sat.YawAngle = float64(r.readUint(9)) / 256 * math.Pi
+		sat.YawRate = float64(r.readInt(8)) / 8192 * math.Pi
+		for j := 0; j < nb && r.err == nil; j++ {
+			sat.Biases = append(sat.Biases, SSRPhaseBias{
+				Signal:          int(r.readUint(5)),
+				Integer:         r.readBool(),
+				WideLaneInteger: uint8(r.readUint(2)),
+				Discontinuity:   uint8(r.readUint(4)),
+				Bias:            float64(r.readInt(20)) * 1e-4,
+			})
+		}
+		m.Sats = append(m.Sats, sat)
+	}
+	return r.err
+}
@@ -0,0 +1,149 @@
+package rtcm3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSROrbitClock(t *testing.T) {
+	assert := assert.New(t)
+	g05 := rinex.PRN{Sys: gnss.SysGPS, Num: 5}
+	hdr := SSRHeader{MsgNum: 1060, Epoch: 388818, UpdateInterval: 2, IODSSR: 3, ProviderID: 258, SolutionID: 1}
+	orbit := SSROrbitSat{PRN: g05, IODE: 77, Radial: 0.1234, AlongTrack: -0.5432, CrossTrack: 0.0248,
+		DotRadial: 0.000123, DotAlongTrack: -0.000456, DotCrossTrack: 0.000012}
+	m := &SSROrbitClock{SSRHeader: hdr, Sats: []SSROrbitClockSat{{SSROrbitSat: orbit, C0: -1.2345, C1: 0.000321, C2: 2e-7}}}
+
+	data, err := m.MarshalBinary()
+	assert.NoError(err)
+	assert.Len(data, (69+135+70+7)/8) // header, orbit and clock bits
+	msg, err := Decode(data)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	m2, ok := msg.(*SSROrbitClock)
+	if !assert.True(ok) {
+		t.FailNow()
+	}
+	assert.Equal(hdr, m2.SSRHeader)
+	assert.Equal(gnss.SysGPS, m2.System())
+	assert.Equal(5*time.Second, m2.Interval())
+	assert.Equal(time.Date(2020, 6, 18, 12, 0, 18, 0, time.UTC), m2.Time(time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC), 0))
+	if assert.Len(m2.Sats, 1) {
+		sat := m2.Sats[0]
+		assert.Equal(g05, sat.PRN)
+		assert.Equal(77, sat.IODE)
+		assert.InDelta(0.1234, sat.Radial, 1e-4)
+		assert.InDelta(-0.5432, sat.AlongTrack, 4e-4)
+		assert.InDelta(0.000123, sat.DotRadial, 1e-6)
+		assert.InDelta(-0.000456, sat.DotAlongTrack, 4e-6)
+		assert.InDelta(-1.2345, sat.C0, 1e-4)
+		assert.InDelta(0.000321, sat.C1, 1e-6)
+		assert.InDelta(2e-7, sat.C2, 2e-8)
+	}
+
+	// message types must match the structs
+	_, err = (&SSROrbit{SSRHeader: hdr}).MarshalBinary()
+	assert.Error(err)
+	assert.Error((&SSRClock{}).UnmarshalBinary(data))
+}
+
+func TestSSR_systems(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		num  int
+		prn  rinex.PRN
+		bits int // header and satellite of an orbit message
+	}{
+		{1063, rinex.PRN{Sys: gnss.SysGLO, Num: 24}, 66 + 5 + 8 + 121},
+		{1240, rinex.PRN{Sys: gnss.SysGAL, Num: 36}, 69 + 6 + 10 + 121},
+		{1246, rinex.PRN{Sys: gnss.SysQZSS, Num: 1}, 67 + 4 + 8 + 121},
+		{1252, rinex.PRN{Sys: gnss.SysSBAS, Num: 20}, 69 + 6 + 9 + 24 + 121},
+		{1258, rinex.PRN{Sys: gnss.SysBDS, Num: 1}, 69 + 6 + 10 + 24 + 121},
+	}
+	for _, tt := range tests {
+		m := &SSROrbit{SSRHeader: SSRHeader{MsgNum: tt.num, Epoch: 43200, RegionalDatum: true},
+			Sats: []SSROrbitSat{{PRN: tt.prn, IODE: 100, IODCRC: 123456, Radial: 1}}}
+		data, err := m.MarshalBinary()
+		if !assert.NoError(err, "%d", tt.num) {
+			continue
+		}
+		assert.Len(data, (tt.bits+7)/8, "%d", tt.num)
+		m2 := &SSROrbit{}
+		assert.NoError(m2.UnmarshalBinary(data))
+		assert.Equal(tt.prn.Sys, m2.System())
+		assert.True(m2.RegionalDatum)
+		if assert.Len(m2.Sats, 1) {
+			assert.Equal(tt.prn, m2.Sats[0].PRN, "%d", tt.num)
+			assert.InDelta(1, m2.Sats[0].Radial, 1e-9)
+		}
+	}
+
+	// GLONASS time of day
+	m := &SSRClock{SSRHeader: SSRHeader{MsgNum: 1064, Epoch: 3*3600 + 60}}
+	ref := time.Date(2020, 6, 18, 1, 0, 0, 0, time.UTC)
+	assert.Equal(time.Date(2020, 6, 18, 0, 1, 18, 0, time.UTC), m.Time(ref, 18))
+
+	_, err := (&SSROrbit{SSRHeader: SSRHeader{MsgNum: 1057}, Sats: []SSROrbitSat{{PRN: rinex.PRN{Sys: gnss.SysGAL, Num: 1}}}}).MarshalBinary()
+	assert.Error(err, "satellite system")
+	_, err = (&SSROrbit{SSRHeader: SSRHeader{MsgNum: 1246}, Sats: make([]SSROrbitSat, 16)}).MarshalBinary()
+	assert.Error(err, "too many QZSS satellites")
+}
+
+func TestSSRBiases(t *testing.T) {
+	assert := assert.New(t)
+	e11 := rinex.PRN{Sys: gnss.SysGAL, Num: 11}
+	cb := &SSRCodeBiases{SSRHeader: SSRHeader{MsgNum: 1242, Epoch: 100},
+		Sats: []SSRCodeBiasSat{{PRN: e11, Biases: []SSRCodeBias{{Signal: 2, Bias: -1.23}, {Signal: 6, Bias: 0.45}}}}}
+	pb := &SSRPhaseBiases{SSRHeader: SSRHeader{MsgNum: 1267, Epoch: 100}, MWConsistency: true,
+		Sats: []SSRPhaseBiasSat{{PRN: e11, YawAngle: 1.5, YawRate: -0.01,
+			Biases: []SSRPhaseBias{{Signal: 2, Integer: true, WideLaneInteger: 2, Discontinuity: 7, Bias: 0.1234}}}}}
+	ura := &SSRURA{SSRHeader: SSRHeader{MsgNum: 1244}, Sats: []SSRURASat{{PRN: e11, URA: 9}}}
+	hr := &SSRHighRateClock{SSRHeader: SSRHeader{MsgNum: 1245}, Sats: []SSRHighRateClockSat{{PRN: e11, Clock: 0.0321}}}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, msg := range []Message{cb, pb, ura, hr} {
+		assert.NoError(enc.Encode(msg))
+	}
+	dec := NewDecoder(&buf)
+
+	assert.True(dec.Next())
+	cb2, ok := dec.Message().(*SSRCodeBiases)
+	if assert.True(ok) && assert.Len(cb2.Sats, 1) && assert.Len(cb2.Sats[0].Biases, 2) {
+		assert.Equal(e11, cb2.Sats[0].PRN)
+		assert.Equal("1C", SSRSignalCode(gnss.SysGAL, cb2.Sats[0].Biases[0].Signal))
+		assert.InDelta(-1.23, cb2.Sats[0].Biases[0].Bias, 1e-9)
+		assert.Equal("5Q", SSRSignalCode(gnss.SysGAL, cb2.Sats[0].Biases[1].Signal))
+	}
+
+	assert.True(dec.Next())
+	pb2, ok := dec.Message().(*SSRPhaseBiases)
+	if assert.True(ok) && assert.Len(pb2.Sats, 1) && assert.Len(pb2.Sats[0].Biases, 1) {
+		assert.False(pb2.DispersiveBiasConsistency)
+		assert.True(pb2.MWConsistency)
+		sat := pb2.Sats[0]
+		assert.InDelta(1.5, sat.YawAngle, 0.01)
+		assert.InDelta(-0.01, sat.YawRate, 0.001)
+		assert.Equal(SSRPhaseBias{Signal: 2, Integer: true, WideLaneInteger: 2, Discontinuity: 7, Bias: sat.Biases[0].Bias}, sat.Biases[0])
+		assert.InDelta(0.1234, sat.Biases[0].Bias, 1e-9)
+	}
+
+	assert.True(dec.Next())
+	ura2, ok := dec.Message().(*SSRURA)
+	if assert.True(ok) && assert.Len(ura2.Sats, 1) {
+		assert.InDelta((3*1.25-1)*1e-3, ura2.Sats[0].Meters(), 1e-12)
+	}
+
+	assert.True(dec.Next())
+	hr2, ok := dec.Message().(*SSRHighRateClock)
+	if assert.True(ok) && assert.Len(hr2.Sats, 1) {
+		assert.InDelta(0.0321, hr2.Sats[0].Clock, 1e-9)
+	}
+	assert.False(dec.Next())
+	assert.NoError(dec.Err())
+}
@@ -0,0 +1,156 @@
+package rtcm3
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// StationARP is the stationary antenna reference point message 1005, or 1006 with antenna height.
+type StationARP struct {
+	MsgNum           int // 1005 or 1006
+	StationID        uint16
+	ITRFYear         uint8 // ITRF realization year, 0 if not given
+	GPS              bool  // GPS service supported
+	GLONASS          bool  // GLONASS service supported
+	Galileo          bool  // Galileo service supported
+	ReferenceStation bool  // non-physical or computed reference station
+	Position         rinex.Coord
+	SingleOscillator bool  // all raw data observations are measured at the same instant
+	QuarterCycle     uint8 // quarter cycle indicator
+	AntennaHeight    float64
+}
+
+// NewStationARP returns the message 1006 for the RINEX header.
+func NewStationARP(stationID uint16, hdr *rinex.ObsHeader) *StationARP {
+	m := &StationARP{
+		MsgNum:        1006,
+		StationID:     stationID,
+		Position:      hdr.Position,
+		AntennaHeight: hdr.AntennaDelta.Up,
+	}
+	for sys := range hdr.ObsTypes {
+		switch sys {
+		case gnss.SysGPS:
+			m.GPS = true
+		case gnss.SysGLO:
+			m.GLONASS = true
+		case gnss.SysGAL:
+			m.Galileo = true
+		}
+	}
+	return m
+}
+
+// Number returns the message number.
+func (m *StationARP) Number() int { return m.MsgNum }
+
+// MarshalBinary returns the message payload.
+func (m *StationARP) MarshalBinary() ([]byte, error) {
+	if m.MsgNum != 1005 && m.MsgNum != 1006 {
+		return nil, fmt.Errorf("invalid message number %d", m.MsgNum)
+	}
+	w := &bitWriter{}
+	w.writeUint(12, uint64(m.MsgNum))
+	w.writeUint(12, uint64(m.StationID))
+	w.writeUint(6, uint64(m.ITRFYear))
+	w.writeBool(m.GPS)
+	w.writeBool(m.GLONASS)
+	w.writeBool(m.Galileo)
+	w.writeBool(m.ReferenceStation)
+	w.writeInt(38, int64(math.Round(m.Position.X*1e4)))
+	w.writeBool(m.SingleOscillator)
+	w.writeUint(1, 0) // reserved
+	w.writeInt(38, int64(math.Round(m.Position.Y*1e4)))
+	w.writeUint(2, uint64(m.QuarterCycle))
+	w.writeInt(38, int64(math.Round(m.Position.Z*1e4)))
+	if m.MsgNum == 1006 {
+		w.writeUint(16, uint64(math.Round(m.AntennaHeight*1e4)))
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *StationARP) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	m.MsgNum = int(r.readUint(12))
+	m.StationID = uint16(r.readUint(12))
+	m.ITRFYear = uint8(r.readUint(6))
+	m.GPS = r.readBool()
+	m.GLONASS = r.readBool()
+	m.Galileo = r.readBool()
+	m.ReferenceStation = r.readBool()
+	m.Position.X = float64(r.readInt(38)) * 1e-4
+	m.SingleOscillator = r.readBool()
+	r.readUint(1)
+	m.Position.Y = float64(r.readInt(38)) * 1e-4
+	m.QuarterCycle = uint8(r.readUint(2))
+	m.Position.Z = float64(r.readInt(38)) * 1e-4
+	if m.MsgNum == 1006 {
+		m.AntennaHeight = float64(r.readUint(16)) * 1e-4
+	}
+	return r.err
+}
+
+// Descriptor is the receiver and antenna descriptors message 1033.
+type Descriptor struct {
+	StationID        uint16
+	AntennaType      string // IGS antenna type incl. radome
+	AntennaSetupID   uint8
+	AntennaSerial    string
+	ReceiverType     string
+	ReceiverFirmware string
+	ReceiverSerial   string
+}
+
+// NewDescriptor returns the message 1033 for the RINEX header.
+func NewDescriptor(stationID uint16, hdr *rinex.ObsHeader) *Descriptor {
+	return &Descriptor{
+		StationID:        stationID,
+		AntennaType:      hdr.AntennaType,
+		AntennaSerial:    hdr.AntennaNumber,
+		ReceiverType:     hdr.ReceiverType,
+		ReceiverFirmware: hdr.ReceiverVersion,
+		ReceiverSerial:   hdr.ReceiverNumber,
+	}
+}
+
+// Number returns the message number.
+func (m *Descriptor) Number() int { return 1033 }
+
+// MarshalBinary returns the message payload.
+func (m *Descriptor) MarshalBinary() ([]byte, error) {
+	for _, s := range []string{m.AntennaType, m.AntennaSerial, m.ReceiverType, m.ReceiverFirmware, m.ReceiverSerial} {
+		if len(s) > 31 {
+			return nil, fmt.Errorf("descriptor too long: %q", s)
+		}
+	}
+	w := &bitWriter{}
+	w.writeUint(12, 1033)
+	w.writeUint(12, uint64(m.StationID))
+	w.writeString(m.AntennaType)
+	w.writeUint(8, uint64(m.AntennaSetupID))
+	w.writeString(m.AntennaSerial)
+	w.writeString(m.ReceiverType)
+	w.writeString(m.ReceiverFirmware)
+	w.writeString(m.ReceiverSerial)
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *Descriptor) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1033 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	m.StationID = uint16(r.readUint(12))
+	m.AntennaType = r.readString()
+	m.AntennaSetupID = uint8(r.readUint(8))
+	m.AntennaSerial = r.readString()
+	m.ReceiverType = r.readString()
+	m.ReceiverFirmware = r.readString()
+	m.ReceiverSerial = r.readString()
+	return r.err
+}
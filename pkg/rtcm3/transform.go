@@ -0,0 +1,469 @@
+package rtcm3
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Resolutions of the transformation message fields.
+const (
+	arcsec     = 1.0 / 3600 // in degrees
+	resProjDeg = 0.000000011
+)
+
+// Helmert is the Helmert / abridged Molodenski transformation message 1021, or the Molodenski-Badekas
+// transformation message 1022 with the rotation point. The messages of a transformation from the source
+// to the target system, including the residual grids and projections, share the SystemID.
+type Helmert struct {
+	MsgNum               int // 1021 or 1022
+	SourceName           string
+	TargetName           string
+	SystemID             uint8  // system identification number
+	UsedMessages         uint16 // utilized transformation messages indicator, one bit per message
+	PlateNumber          uint8
+	ComputationIndicator uint8
+	HeightIndicator      uint8
+
+	// Area of validity: latitude and longitude of the origin and the extensions in degrees.
+	ValidLat, ValidLon, ValidDLat, ValidDLon float64
+
+	DX, DY, DZ float64 // translations in m
+	R1, R2, R3 float64 // rotations in arcsec
+	DS         float64 // scale correction in ppm
+	XP, YP, ZP float64 // rotation point in m, 1022 only
+
+	// Semi-major and semi-minor axes of the source and target ellipsoids in m.
+	SourceA, SourceB, TargetA, TargetB float64
+
+	HorizontalQuality, VerticalQuality uint8
+}
+
+// Number returns the message number.
+func (m *Helmert) Number() int { return m.MsgNum }
+
+// Transform transforms the cartesian coordinates from the source to the target system with the linearized
+// seven parameter transformation, the rotations in the coordinate frame rotation convention. Message 1022
+// rotates around the rotation point.
+func (m *Helmert) Transform(c rinex.Coord) rinex.Coord {
+	const rad = math.Pi / 180 / 3600
+	rx, ry, rz := m.R1*rad, m.R2*rad, m.R3*rad
+	s := 1 + m.DS*1e-6
+	x, y, z := c.X-m.XP, c.Y-m.YP, c.Z-m.ZP
+	return rinex.Coord{
+		X: m.XP + m.DX + s*(x+rz*y-ry*z),
+		Y: m.YP + m.DY + s*(-rz*x+y+rx*z),
+		Z: m.ZP + m.DZ + s*(ry*x-rx*y+z),
+	}
+}
+
+// MarshalBinary returns the message payload.
+func (m *Helmert) MarshalBinary() ([]byte, error) {
+	if m.MsgNum != 1021 && m.MsgNum != 1022 {
+		return nil, fmt.Errorf("invalid message number %d", m.MsgNum)
+	}
+	if len(m.SourceName) > 31 || len(m.TargetName) > 31 {
+		return nil, fmt.Errorf("system name too long")
+	}
+	w := &bitWriter{}
+	w.writeUint(12, uint64(m.MsgNum))
+	writeName(w, m.SourceName)
+	writeName(w, m.TargetName)
+	w.writeUint(8, uint64(m.SystemID))
+	w.writeUint(10, uint64(m.UsedMessages))
+	w.writeUint(5, uint64(m.PlateNumber))
+	w.writeUint(4, uint64(m.ComputationIndicator))
+	w.writeUint(2, uint64(m.HeightIndicator))
+	w.writeInt(19, round(m.ValidLat, 2*arcsec))
+	w.writeInt(20, round(m.ValidLon, 2*arcsec))
+	w.writeUint(14, uint64(round(m.ValidDLat, 2*arcsec)))
+	w.writeUint(14, uint64(round(m.ValidDLon, 2*arcsec)))
+	w.writeInt(23, round(m.DX, 0.001))
+	w.writeInt(23, round(m.DY, 0.001))
+	w.writeInt(23, round(m.DZ, 0.001))
+	w.writeInt(32, round(m.R1, 0.00002))
+	w.writeInt(32, round(m.R2, 0.00002))
+	w.writeInt(32, round(m.R3, 0.00002))
+	w.writeInt(25, round(m.DS, 0.00001))
+	if m.MsgNum == 1022 {
+		w.writeInt(35, round(m.XP, 0.001))
+		w.writeInt(35, round(m.YP, 0.001))
+		w.writeInt(35, round(m.ZP, 0.001))
+	}
+	w.writeUint(24, uint64(round(m.SourceA-6370000, 0.001)))
+	w.writeUint(25, uint64(round(m.SourceB-6350000, 0.001)))
+	w.writeUint(24, uint64(round(m.TargetA-6370000, 0.001)))
+	w.writeUint(25, uint64(round(m.TargetB-6350000, 0.001)))
+	w.writeUint(3, uint64(m.HorizontalQuality))
+	w.writeUint(3, uint64(m.VerticalQuality))
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *Helmert) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	m.MsgNum = int(r.readUint(12))
+	m.SourceName = readName(r)
+	m.TargetName = readName(r)
+	m.SystemID = uint8(r.readUint(8))
+	m.UsedMessages = uint16(r.readUint(10))
+	m.PlateNumber = uint8(r.readUint(5))
+	m.ComputationIndicator = uint8(r.readUint(4))
+	m.HeightIndicator = uint8(r.readUint(2))
+	m.ValidLat = float64(r.readInt(19)) * 2 * arcsec
+	m.ValidLon = float64(r.readInt(20)) * 2 * arcsec
+	m.ValidDLat = float64(r.readUint(14)) * 2 * arcsec
+	m.ValidDLon = float64(r.readUint(14)) * 2 * arcsec
+	m.DX = float64(r.readInt(23)) * 0.001
+	m.DY = float64(r.readInt(23)) * 0.001
+	m.DZ = float64(r.readInt(23)) * 0.001
+	m.R1 = float64(r.readInt(32)) * 0.00002
+	m.R2 = float64(r.readInt(32)) * 0.00002
+	m.R3 = float64(r.readInt(32)) * 0.00002
+	m.DS = float64(r.readInt(25)) * 0.00001
+	if m.MsgNum == 1022 {
+		m.XP = float64(r.readInt(35)) * 0.001
+		m.YP = float64(r.readInt(35)) * 0.001
+		m.ZP = float64(r.readInt(35)) * 0.001
+	}
+	m.SourceA = 6370000 + float64(r.readUint(24))*0.001
+	m.SourceB = 6350000 + float64(r.readUint(25))*0.001
+	m.TargetA = 6370000 + float64(r.readUint(24))*0.001
+	m.TargetB = 6350000 + float64(r.readUint(25))*0.001
+	m.HorizontalQuality = uint8(r.readUint(3))
+	m.VerticalQuality = uint8(r.readUint(3))
+	return r.err
+}
+
+// GridResidual is the residual of a grid point of the messages 1023 and 1024.
+type GridResidual struct {
+	DLat, DLon float64 // 1023: latitude and longitude residuals in arcsec
+	DN, DE     float64 // 1024: northing and easting residuals in m
+	DH         float64 // height residual in m
+}
+
+// EllipsoidalGrid is the residuals message 1023 with a grid of 4x4 points in ellipsoidal coordinates.
+type EllipsoidalGrid struct {
+	SystemID        uint8 // system identification number
+	HorizontalShift bool  // horizontal residuals are given
+	VerticalShift   bool  // vertical residuals are given
+
+	Lat0, Lon0 float64 // origin of the grid in degrees
+	DLat, DLon float64 // grid spacing in degrees
+	MeanDLat   float64 // mean latitude offset in arcsec
+	MeanDLon   float64 // mean longitude offset in arcsec
+	MeanDH     float64 // mean height offset in m
+	Residuals  [16]GridResidual
+
+	HorizontalInterpolation, VerticalInterpolation uint8 // interpolation method indicators
+	HorizontalQuality, VerticalQuality             uint8
+	MJD                                            uint16 // modified Julian day
+}
+
+// Number returns the message number.
+func (m *EllipsoidalGrid) Number() int { return 1023 }
+
+// MarshalBinary returns the message payload.
+func (m *EllipsoidalGrid) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	w.writeUint(12, 1023)
+	w.writeUint(8, uint64(m.SystemID))
+	w.writeBool(m.HorizontalShift)
+	w.writeBool(m.VerticalShift)
+	w.writeInt(21, round(m.Lat0, 0.5*arcsec))
+	w.writeInt(22, round(m.Lon0, 0.5*arcsec))
+	w.writeUint(12, uint64(round(m.DLat, 0.5*arcsec)))
+	w.writeUint(12, uint64(round(m.DLon, 0.5*arcsec)))
+	w.writeInt(8, round(m.MeanDLat, 0.001))
+	w.writeInt(8, round(m.MeanDLon, 0.001))
+	w.writeInt(15, round(m.MeanDH, 0.01))
+	for _, res := range m.Residuals {
+		w.writeInt(9, round(res.DLat, 0.00003))
+		w.writeInt(9, round(res.DLon, 0.00003))
+		w.writeInt(9, round(res.DH, 0.001))
+	}
+	writeGridTrailer(w, m.HorizontalInterpolation, m.VerticalInterpolation, m.HorizontalQuality, m.VerticalQuality, m.MJD)
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *EllipsoidalGrid) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1023 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	m.SystemID = uint8(r.readUint(8))
+	m.HorizontalShift = r.readBool()
+	m.VerticalShift = r.readBool()
+	m.Lat0 = float64(r.readInt(21)) * 0.5 * arcsec
+	m.Lon0 = float64(r.readInt(22)) * 0.5 * arcsec
+	m.DLat = float64(r.readUint(12)) * 0.5 * arcsec
+	m.DLon = float64(r.readUint(12)) * 0.5 * arcsec
+	m.MeanDLat = float64(r.readInt(8)) * 0.001
+	m.MeanDLon = float64(r.readInt(8)) * 0.001
+	m.MeanDH = float64(r.readInt(15)) * 0.01
+	for i := range m.Residuals {
+		res := &m.Residuals[i]
+		res.DLat = float64(r.readInt(9)) * 0.00003
+		res.DLon = float64(r.readInt(9)) * 0.00003
+		res.DH = float64(r.readInt(9)) * 0.001
+	}
+	readGridTrailer(r, &m.HorizontalInterpolation, &m.VerticalInterpolation, &m.HorizontalQuality, &m.VerticalQuality, &m.MJD)
+	return r.err
+}
+
+// PlaneGrid is the residuals message 1024 with a grid of 4x4 points in plane coordinates.
+type PlaneGrid struct {
+	SystemID        uint8 // system identification number
+	HorizontalShift bool  // horizontal residuals are given
+	VerticalShift   bool  // vertical residuals are given
+
+	N0, E0    float64 // origin of the grid in m
+	DN, DE    float64 // grid spacing in m
+	MeanDN    float64 // mean northing offset in m
+	MeanDE    float64 // mean easting offset in m
+	MeanDH    float64 // mean height offset in m
+	Residuals [16]GridResidual
+
+	HorizontalInterpolation, VerticalInterpolation uint8 // interpolation method indicators
+	HorizontalQuality, VerticalQuality             uint8
+	MJD                                            uint16 // modified Julian day
+}
+
+// Number returns the message number.
+func (m *PlaneGrid) Number() int { return 1024 }
+
+// MarshalBinary returns the message payload.
+func (m *PlaneGrid) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	w.writeUint(12, 1024)
+	w.writeUint(8, uint64(m.SystemID))
+	w.writeBool(m.HorizontalShift)
+	w.writeBool(m.VerticalShift)
+	w.writeInt(25, round(m.N0, 1))
+	w.writeInt(26, round(m.E0, 1))
+	w.writeUint(12, uint64(round(m.DN, 10)))
+	w.writeUint(12, uint64(round(m.DE, 10)))
+	w.writeInt(10, round(m.MeanDN, 0.01))
+	w.writeInt(10, round(m.MeanDE, 0.01))
+	w.writeInt(15, round(m.MeanDH, 0.01))
+	for _, res := range m.Residuals {
+		w.writeInt(9, round(res.DN, 0.001))
+		w.writeInt(9, round(res.DE, 0.001))
+		w.writeInt(9, round(res.DH, 0.001))
+	}
+	writeGridTrailer(w, m.HorizontalInterpolation, m.VerticalInterpolation, m.HorizontalQuality, m.VerticalQuality, m.MJD)
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *PlaneGrid) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1024 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	m.SystemID = uint8(r.readUint(8))
+	m.HorizontalShift = r.readBool()
+	m.VerticalShift = r.readBool()
+	m.N0 = float64(r.readInt(25))
+	m.E0 = float64(r.readInt(26))
+	m.DN = float64(r.readUint(12)) * 10
+	m.DE = float64(r.readUint(12)) * 10
+	m.MeanDN = float64(r.readInt(10)) * 0.01
+	m.MeanDE = float64(r.readInt(10)) * 0.01
+	m.MeanDH = float64(r.readInt(15)) * 0.01
+	for i := range m.Residuals {
+		res := &m.Residuals[i]
+		res.DN = float64(r.readInt(9)) * 0.001
+		res.DE = float64(r.readInt(9)) * 0.001
+		res.DH = float64(r.readInt(9)) * 0.001
+	}
+	readGridTrailer(r, &m.HorizontalInterpolation, &m.VerticalInterpolation, &m.HorizontalQuality, &m.VerticalQuality, &m.MJD)
+	return r.err
+}
+
+func writeGridTrailer(w *bitWriter, interpolH, interpolV, qualityH, qualityV uint8, mjd uint16) {
+	w.writeUint(2, uint64(interpolH))
+	w.writeUint(2, uint64(interpolV))
+	w.writeUint(3, uint64(qualityH))
+	w.writeUint(3, uint64(qualityV))
+	w.writeUint(16, uint64(mjd))
+}
+
+func readGridTrailer(r *bitReader, interpolH, interpolV, qualityH, qualityV *uint8, mjd *uint16) {
+	*interpolH = uint8(r.readUint(2))
+	*interpolV = uint8(r.readUint(2))
+	*qualityH = uint8(r.readUint(3))
+	*qualityV = uint8(r.readUint(3))
+	*mjd = uint16(r.readUint(16))
+}
+
+// Projection is the projection parameters message 1025, for the projection types except LCC2SP and OM,
+// like transverse Mercator.
+type Projection struct {
+	SystemID         uint8 // system identification number
+	ProjectionType   uint8
+	LatNaturalOrigin float64 // degrees
+	LonNaturalOrigin float64 // degrees
+	Scale            float64 // scale factor at the natural origin
+	FalseEasting     float64 // m
+	FalseNorthing    float64 // m
+}
+
+// Number returns the message number.
+func (m *Projection) Number() int { return 1025 }
+
+// MarshalBinary returns the message payload.
+func (m *Projection) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	w.writeUint(12, 1025)
+	w.writeUint(8, uint64(m.SystemID))
+	w.writeUint(6, uint64(m.ProjectionType))
+	w.writeInt(34, round(m.LatNaturalOrigin, resProjDeg))
+	w.writeInt(35, round(m.LonNaturalOrigin, resProjDeg))
+	w.writeUint(30, uint64(round(m.Scale*1e6-993000, 0.00001)))
+	w.writeUint(36, uint64(round(m.FalseEasting, 0.001)))
+	w.writeInt(35, round(m.FalseNorthing, 0.001))
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *Projection) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1025 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	m.SystemID = uint8(r.readUint(8))
+	m.ProjectionType = uint8(r.readUint(6))
+	m.LatNaturalOrigin = float64(r.readInt(34)) * resProjDeg
+	m.LonNaturalOrigin = float64(r.readInt(35)) * resProjDeg
+	m.Scale = (993000 + float64(r.readUint(30))*0.00001) * 1e-6
+	m.FalseEasting = float64(r.readUint(36)) * 0.001
+	m.FalseNorthing = float64(r.readInt(35)) * 0.001
+	return r.err
+}
+
+// ProjectionLCC2SP is the projection parameters message 1026 for the Lambert conic conformal projection
+// with two standard parallels.
+type ProjectionLCC2SP struct {
+	SystemID            uint8 // system identification number
+	ProjectionType      uint8
+	LatFalseOrigin      float64 // degrees
+	LonFalseOrigin      float64 // degrees
+	LatParallel1        float64 // latitude of the first standard parallel in degrees
+	LatParallel2        float64 // latitude of the second standard parallel in degrees
+	EastingFalseOrigin  float64 // m
+	NorthingFalseOrigin float64 // m
+}
+
+// Number returns the message number.
+func (m *ProjectionLCC2SP) Number() int { return 1026 }
+
+// MarshalBinary returns the message payload.
+func (m *ProjectionLCC2SP) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	w.writeUint(12, 1026)
+	w.writeUint(8, uint64(m.SystemID))
+	w.writeUint(6, uint64(m.ProjectionType))
+	w.writeInt(34, round(m.LatFalseOrigin, resProjDeg))
+	w.writeInt(35, round(m.LonFalseOrigin, resProjDeg))
+	w.writeInt(34, round(m.LatParallel1, resProjDeg))
+	w.writeInt(34, round(m.LatParallel2, resProjDeg))
+	w.writeUint(36, uint64(round(m.EastingFalseOrigin, 0.001)))
+	w.writeInt(35, round(m.NorthingFalseOrigin, 0.001))
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *ProjectionLCC2SP) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1026 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	m.SystemID = uint8(r.readUint(8))
+	m.ProjectionType = uint8(r.readUint(6))
+	m.LatFalseOrigin = float64(r.readInt(34)) * resProjDeg
+	m.LonFalseOrigin = float64(r.readInt(35)) * resProjDeg
+	m.LatParallel1 = float64(r.readInt(34)) * resProjDeg
+	m.LatParallel2 = float64(r.readInt(34)) * resProjDeg
+	m.EastingFalseOrigin = float64(r.readUint(36)) * 0.001
+	m.NorthingFalseOrigin = float64(r.readInt(35)) * 0.001
+	return r.err
+}
+
+// ProjectionOM is the projection parameters message 1027 for the oblique Mercator projection.
+type ProjectionOM struct {
+	SystemID           uint8 // system identification number
+	ProjectionType     uint8
+	Rectified          bool    // rectification flag
+	LatCenter          float64 // latitude of the projection center in degrees
+	LonCenter          float64 // longitude of the projection center in degrees
+	AzimuthInitialLine float64 // degrees
+	DiffRectifiedSkew  float64 // difference between the angle from rectified to skew grid and the azimuth, degrees
+	Scale              float64 // scale factor on the initial line
+	EastingCenter      float64 // m
+	NorthingCenter     float64 // m
+}
+
+// Number returns the message number.
+func (m *ProjectionOM) Number() int { return 1027 }
+
+// MarshalBinary returns the message payload.
+func (m *ProjectionOM) MarshalBinary() ([]byte, error) {
+	w := &bitWriter{}
+	w.writeUint(12, 1027)
+	w.writeUint(8, uint64(m.SystemID))
+	w.writeUint(6, uint64(m.ProjectionType))
+	w.writeBool(m.Rectified)
+	w.writeInt(34, round(m.LatCenter, resProjDeg))
+	w.writeInt(35, round(m.LonCenter, resProjDeg))
+	w.writeUint(35, uint64(round(m.AzimuthInitialLine, resProjDeg)))
+	w.writeInt(26, round(m.DiffRectifiedSkew, resProjDeg))
+	w.writeUint(30, uint64(round(m.Scale*1e6-993000, 0.00001)))
+	w.writeUint(36, uint64(round(m.EastingCenter, 0.001)))
+	w.writeInt(35, round(m.NorthingCenter, 0.001))
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes the message payload.
+func (m *ProjectionOM) UnmarshalBinary(data []byte) error {
+	r := &bitReader{buf: data}
+	if num := r.readUint(12); num != 1027 && r.err == nil {
+		return fmt.Errorf("invalid message number %d", num)
+	}
+	m.SystemID = uint8(r.readUint(8))
+	m.ProjectionType = uint8(r.readUint(6))
+	m.Rectified = r.readBool()
+	m.LatCenter = float64(r.readInt(34)) * resProjDeg
+	m.LonCenter = float64(r.readInt(35)) * resProjDeg
+	m.AzimuthInitialLine = float64(r.readUint(35)) * resProjDeg
+	m.DiffRectifiedSkew = float64(r.readInt(26)) * resProjDeg
+	m.Scale = (993000 + float64(r.readUint(30))*0.00001) * 1e-6
+	m.EastingCenter = float64(r.readUint(36)) * 0.001
+	m.NorthingCenter = float64(r.readInt(35)) * 0.001
+	return r.err
+}
+
+// round returns v in units of res.
+func round(v, res float64) int64 {
+	return int64(math.Round(v / res))
+}
+
+// writeName writes a system name with its 5 bit length.
+func writeName(w *bitWriter, s string) {
+	w.writeUint(5, uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		w.writeUint(8, uint64(s[i]))
+	}
+}
+
+// readName reads a system name with its 5 bit length.
+func readName(r *bitReader) string {
+	n := int(r.readUint(5))
+	b := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		b = append(b, byte(r.readUint(8)))
+	}
+	return string(b)
+}
@@ -0,0 +1,172 @@
+package rtcm3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHelmert(t *testing.T) {
+	assert := assert.New(t)
+	// ETRS89 to DHDN, Bessel ellipsoid
+	m := &Helmert{MsgNum: 1021, SourceName: "ETRS89", TargetName: "DHDN", SystemID: 7, UsedMessages: 0x3,
+		ComputationIndicator: 1, HeightIndicator: 2, ValidLat: 47, ValidLon: 5.5, ValidDLat: 8, ValidDLon: 10,
+		DX: -598.1, DY: -73.7, DZ: -418.2, R1: -0.202, R2: -0.045, R3: 2.455, DS: -6.7,
+		SourceA: 6378137, SourceB: 6356752.314, TargetA: 6377397.155, TargetB: 6356078.963, HorizontalQuality: 2}
+	data, err := m.MarshalBinary()
+	assert.NoError(err)
+	assert.Len(data, (12+5+6*8+5+4*8+8+10+5+4+2+19+20+14+14+3*23+3*32+25+24+25+24+25+3+3+7)/8)
+
+	msg, err := Decode(data)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	m2, ok := msg.(*Helmert)
+	if !assert.True(ok) {
+		t.FailNow()
+	}
+	assert.Equal("ETRS89", m2.SourceName)
+	assert.Equal("DHDN", m2.TargetName)
+	assert.Equal(uint8(7), m2.SystemID)
+	assert.Equal(uint16(3), m2.UsedMessages)
+	assert.InDelta(47, m2.ValidLat, 1e-9)
+	assert.InDelta(5.5, m2.ValidLon, 1e-9)
+	assert.InDelta(-598.1, m2.DX, 1e-9)
+	assert.InDelta(2.455, m2.R3, 1e-9)
+	assert.InDelta(-6.7, m2.DS, 1e-9)
+	assert.InDelta(6377397.155, m2.TargetA, 1e-6)
+	assert.InDelta(6356078.963, m2.TargetB, 1e-6)
+	assert.Equal(uint8(2), m2.HorizontalQuality)
+
+	// Molodenski-Badekas with rotation point
+	m.MsgNum, m.XP, m.YP, m.ZP = 1022, 4000000.123, 500000.456, 4900000.789
+	data, err = m.MarshalBinary()
+	assert.NoError(err)
+	m2 = &Helmert{}
+	assert.NoError(m2.UnmarshalBinary(data))
+	assert.Equal(1022, m2.Number())
+	assert.InDelta(500000.456, m2.YP, 1e-6)
+	assert.InDelta(6356752.314, m2.SourceB, 1e-6)
+
+	m.SourceName = "a name longer than thirty-one bytes"
+	_, err = m.MarshalBinary()
+	assert.Error(err)
+}
+
+func TestHelmert_Transform(t *testing.T) {
+	assert := assert.New(t)
+	c := rinex.Coord{X: 6378137}
+	m := &Helmert{DX: 1, DY: 2, DZ: 3}
+	assert.Equal(rinex.Coord{X: 6378138, Y: 2, Z: 3}, m.Transform(c))
+
+	m = &Helmert{R3: 1, DS: 1}
+	got := m.Transform(c)
+	assert.InDelta(6378137*(1+1e-6), got.X, 1e-6)
+	assert.InDelta(-6378137*(1+1e-6)*4.84813681109536e-6, got.Y, 1e-6)
+
+	// rotation around the rotation point
+	m = &Helmert{MsgNum: 1022, R3: 1, XP: 6378137}
+	assert.InDelta(0, m.Transform(c).Y, 1e-9)
+}
+
+func TestTransformation_grids(t *testing.T) {
+	assert := assert.New(t)
+	eg := &EllipsoidalGrid{SystemID: 7, HorizontalShift: true, VerticalShift: true, Lat0: 50.5, Lon0: -8.25,
+		DLat: 0.25, DLon: 0.5, MeanDLat: 0.012, MeanDLon: -0.034, MeanDH: 1.23, MJD: 59000}
+	for i := range eg.Residuals {
+		eg.Residuals[i] = GridResidual{DLat: float64(i) * 0.00003, DLon: -float64(i) * 0.00003, DH: float64(i) * 0.001}
+	}
+	pg := &PlaneGrid{SystemID: 7, HorizontalShift: true, N0: 5500000, E0: 3500000, DN: 10000, DE: 20000,
+		MeanDN: 0.5, MeanDE: -0.25, MeanDH: -0.75, HorizontalInterpolation: 1, VerticalQuality: 3}
+	pg.Residuals[15] = GridResidual{DN: 0.012, DE: -0.034, DH: 0.056}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	assert.NoError(enc.Encode(eg))
+	assert.NoError(enc.Encode(pg))
+	dec := NewDecoder(&buf)
+
+	assert.True(dec.Next())
+	assert.Len(dec.Payload(), 73)
+	eg2, ok := dec.Message().(*EllipsoidalGrid)
+	if assert.True(ok) {
+		assert.InDelta(50.5, eg2.Lat0, 1e-9)
+		assert.InDelta(-8.25, eg2.Lon0, 1e-9)
+		assert.InDelta(0.5, eg2.DLon, 1e-9)
+		assert.InDelta(-0.034, eg2.MeanDLon, 1e-9)
+		assert.InDelta(1.23, eg2.MeanDH, 1e-9)
+		assert.InDelta(15*0.00003, eg2.Residuals[15].DLat, 1e-12)
+		assert.InDelta(0.015, eg2.Residuals[15].DH, 1e-12)
+		assert.Equal(uint16(59000), eg2.MJD)
+	}
+
+	assert.True(dec.Next())
+	pg2, ok := dec.Message().(*PlaneGrid)
+	if assert.True(ok) {
+		assert.True(pg2.HorizontalShift)
+		assert.False(pg2.VerticalShift)
+		assert.InDelta(5500000, pg2.N0, 1e-9)
+		assert.InDelta(3500000, pg2.E0, 1e-9)
+		assert.InDelta(20000, pg2.DE, 1e-9)
+		assert.InDelta(-0.25, pg2.MeanDE, 1e-9)
+		assert.Equal(GridResidual{DN: 0.012, DE: -0.034, DH: 0.056}, roundResidual(pg2.Residuals[15]))
+		assert.Equal(uint8(1), pg2.HorizontalInterpolation)
+		assert.Equal(uint8(3), pg2.VerticalQuality)
+	}
+}
+
+// roundResidual rounds the residuals to mm.
+func roundResidual(res GridResidual) GridResidual {
+	return GridResidual{DN: float64(round(res.DN, 0.001)) / 1000, DE: float64(round(res.DE, 0.001)) / 1000,
+		DH: float64(round(res.DH, 0.001)) / 1000}
+}
+
+func TestTransformation_projections(t *testing.T) {
+	assert := assert.New(t)
+	// UTM zone 32N, Lambert 93 and the Swiss oblique Mercator
+	tm := &Projection{SystemID: 7, ProjectionType: 1, LonNaturalOrigin: 9, Scale: 0.9996, FalseEasting: 500000}
+	lcc := &ProjectionLCC2SP{SystemID: 8, ProjectionType: 4, LatFalseOrigin: 46.5, LonFalseOrigin: 3,
+		LatParallel1: 49, LatParallel2: 44, EastingFalseOrigin: 700000, NorthingFalseOrigin: 6600000}
+	om := &ProjectionOM{SystemID: 9, ProjectionType: 6, Rectified: true, LatCenter: 46.952405556,
+		LonCenter: 7.439583333, AzimuthInitialLine: 90, DiffRectifiedSkew: -0.000001, Scale: 1,
+		EastingCenter: 2600000, NorthingCenter: 1200000}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, msg := range []Message{tm, lcc, om} {
+		assert.NoError(enc.Encode(msg))
+	}
+	dec := NewDecoder(&buf)
+
+	assert.True(dec.Next())
+	tm2, ok := dec.Message().(*Projection)
+	if assert.True(ok) {
+		assert.Equal(uint8(1), tm2.ProjectionType)
+		assert.InDelta(9, tm2.LonNaturalOrigin, 1e-8)
+		assert.InDelta(0.9996, tm2.Scale, 1e-11)
+		assert.InDelta(500000, tm2.FalseEasting, 1e-9)
+	}
+
+	assert.True(dec.Next())
+	lcc2, ok := dec.Message().(*ProjectionLCC2SP)
+	if assert.True(ok) {
+		assert.InDelta(46.5, lcc2.LatFalseOrigin, 1e-8)
+		assert.InDelta(44, lcc2.LatParallel2, 1e-8)
+		assert.InDelta(6600000, lcc2.NorthingFalseOrigin, 1e-9)
+	}
+
+	assert.True(dec.Next())
+	om2, ok := dec.Message().(*ProjectionOM)
+	if assert.True(ok) {
+		assert.True(om2.Rectified)
+		assert.InDelta(46.952405556, om2.LatCenter, 1e-8)
+		assert.InDelta(90, om2.AzimuthInitialLine, 1e-8)
+		assert.InDelta(-0.000001, om2.DiffRectifiedSkew, 1e-8)
+		assert.InDelta(1, om2.Scale, 1e-11)
+		assert.InDelta(1200000, om2.NorthingCenter, 1e-9)
+	}
+	assert.False(dec.Next())
+	assert.NoError(dec.Err())
+}
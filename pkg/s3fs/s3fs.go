@@ -0,0 +1,370 @@
+// Package s3fs implements the rinex.Filesystem for S3 compatible object stores, like AWS S3 and MinIO,
+// so that RINEX files can be read and written without staging them on the local disk.
+//
+// The objects are addressed path-style, i.e. <endpoint>/<bucket>/<key>, and the requests are signed with
+// AWS Signature Version 4.
+//
+//	fsys := &s3fs.FS{Endpoint: "http://localhost:9000", Bucket: "rinex", AccessKey: "...", SecretKey: "..."}
+//	obsFil, err := rinex.NewObsFile("2020/169/WTZR00DEU_R_20201690000_01D_30S_MO.rnx.gz")
+//	obsFil.FS = fsys
+//	rep, err := obsFil.Report()
+package s3fs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FS is a bucket of an S3 compatible object store. Its methods are safe for concurrent use.
+type FS struct {
+	Endpoint     string       // e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	Region       string       // defaults to us-east-1
+	Bucket       string       // bucket name
+	AccessKey    string       // access key ID
+	SecretKey    string       // secret access key
+	SessionToken string       // optional token of temporary credentials
+	Client       *http.Client // defaults to http.DefaultClient
+	PartSize     int          // size of the parts of multipart uploads, defaults to DefaultPartSize
+}
+
+// DefaultPartSize is the default part size of multipart uploads, the minimum allowed by S3.
+const DefaultPartSize = 5 << 20
+
+// Open opens the object name for reading.
+func (fsys *FS) Open(name string) (io.ReadCloser, error) {
+	resp, err := fsys.do("GET", name, nil, nil, 0, emptyHash)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return resp.Body, nil
+}
+
+// Create returns a writer for the object name. The data is streamed with a multipart upload in parts of
+// PartSize, so that at most one part is buffered in memory. Objects smaller than a part are uploaded
+// with a single PUT by Close. The object is created by Close, a failed upload is aborted.
+func (fsys *FS) Create(name string) (io.WriteCloser, error) {
+	partSize := fsys.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return &writer{fsys: fsys, name: name, partSize: partSize}, nil
+}
+
+// Stat returns the size and modification time of the object name.
+func (fsys *FS) Stat(name string) (os.FileInfo, error) {
+	resp, err := fsys.do("HEAD", name, nil, nil, 0, emptyHash)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	resp.Body.Close()
+	fi := &fileInfo{name: path.Base(name), size: resp.ContentLength}
+	fi.modTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return fi, nil
+}
+
+// Remove removes the object name.
+func (fsys *FS) Remove(name string) error {
+	resp, err := fsys.do("DELETE", name, nil, nil, 0, emptyHash)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends the signed request for the object name. Responses with an error status are returned as error,
+// a missing object as os.ErrNotExist.
+func (fsys *FS) do(method, name string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(fsys.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + fsys.Bucket + "/" + strings.TrimPrefix(name, "/")
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if body != nil && size == 0 {
+		req.Body = http.NoBody
+	}
+	if fsys.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", fsys.SessionToken)
+	}
+	region := fsys.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	sign(req, fsys.AccessKey, fsys.SecretKey, region, payloadHash, time.Now())
+
+	client := fsys.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, os.ErrNotExist
+		}
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s failed: %s: %s", method, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// writer uploads the object data in parts. The current part is buffered in buf.
+type writer struct {
+	fsys     *FS
+	name     string
+	partSize int
+	buf      bytes.Buffer
+	uploadID string          // of the multipart upload, empty until the first part is full
+	parts    []completedPart // uploaded parts
+	err      error
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		k := w.partSize - w.buf.Len()
+		if k > len(p) {
+			k = len(p)
+		}
+		w.buf.Write(p[:k])
+		p = p[k:]
+		if w.buf.Len() < w.partSize {
+			break
+		}
+		if err := w.uploadPart(); err != nil {
+			w.abort()
+			w.err = &os.PathError{Op: "write", Path: w.name, Err: err}
+			return n - len(p) - k, w.err
+		}
+	}
+	return n, nil
+}
+
+// Close uploads the remaining data and completes the multipart upload.
+func (w *writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = os.ErrClosed
+	var err error
+	if w.uploadID == "" {
+		var resp *http.Response
+		data := w.buf.Bytes()
+		resp, err = w.fsys.do("PUT", w.name, nil, bytes.NewReader(data), int64(len(data)), hexSHA256Bytes(data))
+		if err == nil {
+			resp.Body.Close()
+		}
+	} else if err = w.uploadPart(); err == nil {
+		err = w.complete()
+	}
+	if err != nil {
+		w.abort()
+		return &os.PathError{Op: "create", Path: w.name, Err: err}
+	}
+	return nil
+}
+
+// uploadPart uploads the buffered data as next part and starts the multipart upload if necessary.
+func (w *writer) uploadPart() error {
+	if w.uploadID == "" {
+		resp, err := w.fsys.do("POST", w.name, url.Values{"uploads": {""}}, nil, 0, emptyHash)
+		if err != nil {
+			return err
+		}
+		var res struct {
+			UploadID string `xml:"UploadId"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("initiate multipart upload: %v", err)
+		}
+		if res.UploadID == "" {
+			return fmt.Errorf("initiate multipart upload: no upload ID")
+		}
+		w.uploadID = res.UploadID
+	}
+
+	num := len(w.parts) + 1
+	data := w.buf.Bytes()
+	query := url.Values{"partNumber": {strconv.Itoa(num)}, "uploadId": {w.uploadID}}
+	resp, err := w.fsys.do("PUT", w.name, query, bytes.NewReader(data), int64(len(data)), hexSHA256Bytes(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	w.parts = append(w.parts, completedPart{PartNumber: num, ETag: resp.Header.Get("ETag")})
+	w.buf.Reset()
+	return nil
+}
+
+// complete completes the multipart upload. S3 may report an error with status 200 OK.
+func (w *writer) complete() error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: w.parts})
+	if err != nil {
+		return err
+	}
+	resp, err := w.fsys.do("POST", w.name, url.Values{"uploadId": {w.uploadID}}, bytes.NewReader(body),
+		int64(len(body)), hexSHA256Bytes(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		XMLName xml.Name
+		Code    string
+		Message string
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("complete multipart upload: %v", err)
+	}
+	if res.XMLName.Local == "Error" {
+		return fmt.Errorf("complete multipart upload: %s: %s", res.Code, res.Message)
+	}
+	return nil
+}
+
+// abort aborts the multipart upload, so that the uploaded parts are deleted.
+func (w *writer) abort() {
+	if w.uploadID == "" {
+		return
+	}
+	resp, err := w.fsys.do("DELETE", w.name, url.Values{"uploadId": {w.uploadID}}, nil, 0, emptyHash)
+	if err == nil {
+		resp.Body.Close()
+	}
+	w.uploadID = ""
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return 0444 }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return false }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+// emptyHash is the SHA256 of an empty payload.
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign signs the request with AWS Signature Version 4. The host, range and x-amz-* headers are signed.
+func sign(req *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, vals := range req.Header {
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "x-amz-") || key == "range" {
+			headers[key] = strings.TrimSpace(strings.Join(vals, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for key := range headers {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, key := range names {
+		canonHeaders.WriteString(key + ":" + headers[key] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonRequest := strings.Join([]string{req.Method, uriEncode(req.URL.Path, false), canonicalQuery(req.URL.Query()),
+		canonHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256(canonRequest)
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(q url.Values) string {
+	var params []string
+	for key, vals := range q {
+		for _, v := range vals {
+			params = append(params, uriEncode(key, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode encodes s as required by AWS: all bytes but the unreserved characters are percent-encoded.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString("%" + strings.ToUpper(strconv.FormatInt(int64(c)|0x100, 16)[1:]))
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(s string) string {
+	return hexSHA256Bytes([]byte(s))
+}
+
+func hexSHA256Bytes(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
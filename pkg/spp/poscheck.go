@@ -0,0 +1,35 @@
+package spp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Position returns the single point position of the epoch. It implements rinex.Positioner,
+// see rinex.WithPositionCheck.
+func (s *Solver) Position(epo *rinex.Epoch) (rinex.Coord, error) {
+	sol, err := s.Solve(epo)
+	if err != nil {
+		return rinex.Coord{}, err
+	}
+	return sol.Position, nil
+}
+
+// CheckPosition computes the single point positions of the decoder's epochs, at most one per minute,
+// and compares their median with the header's APPROX POSITION XYZ. Differences beyond maxDiff in meters,
+// e.g. for wrongly labeled files, are flagged by PositionCheck.OK being false.
+func (s *Solver) CheckPosition(dec *rinex.ObsDecoder, maxDiff float64) (*rinex.PositionCheck, error) {
+	if s.EphemerisStore == nil {
+		return nil, errors.New("position check needs ephemerides")
+	}
+	pc := rinex.NewPositionChecker(s)
+	for dec.NextEpoch() {
+		pc.Add(dec.Epoch())
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read epochs: %w", err)
+	}
+	return pc.Result(dec.Header.Position, maxDiff)
+}
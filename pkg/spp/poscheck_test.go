@@ -0,0 +1,87 @@
+package spp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeSimulated returns an observation file with the pseudoranges of n epochs every 30 s.
+func encodeSimulated(t *testing.T, list []*rinex.EphGPS, marker, approx rinex.Coord, n int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := rinex.NewObsEncoder(&buf, rinex.ObsHeader{RINEXVersion: 3.04, RINEXType: "O", SatSystem: gnss.SysGPS,
+		MarkerName: "SIMU", Interval: 30, Position: approx, ObsTypes: map[gnss.System][]string{gnss.SysGPS: {"C1C"}}})
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(simulate(list, marker, toc.Add(time.Duration(i)*30*time.Second), 1e-4, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSolver_CheckPosition(t *testing.T) {
+	assert := assert.New(t)
+	ephs, list, marker := constellation(t)
+	data := encodeSimulated(t, list, marker, rinex.Coord{}, 10)
+
+	tests := []struct {
+		approx rinex.Coord
+		ok     bool
+	}{
+		{marker, true},
+		{rinex.CoordNEU{N: 30, E: -20, Up: 10}.Coord(marker), true},
+		{rinex.CoordNEU{N: 5000}.Coord(marker), false}, // wrongly labeled file
+		{rinex.Coord{}, false},                         // position not set
+	}
+	for _, tt := range tests {
+		dec, err := rinex.NewObsDecoder(bytes.NewReader(data))
+		assert.NoError(err)
+		dec.Header.Position = tt.approx
+		_, err = NewSolver(nil).CheckPosition(dec, rinex.DefaultMaxPositionDiff)
+		assert.Error(err, "no ephemerides")
+
+		dec, err = rinex.NewObsDecoder(bytes.NewReader(data))
+		assert.NoError(err)
+		dec.Header.Position = tt.approx
+		chk, err := NewSolver(ephs).CheckPosition(dec, rinex.DefaultMaxPositionDiff)
+		if !assert.NoError(err) {
+			continue
+		}
+		assert.Equal(tt.ok, chk.OK, "%+v", tt.approx)
+		assert.Equal(5, chk.NumEpochs, "one epoch per minute")
+		assert.InDelta(0, chk.Estimated.Distance(marker), 0.05)
+		assert.InDelta(tt.approx.Distance(marker), chk.Distance, 0.05)
+	}
+
+	// too few satellites in all epochs
+	dec, err := rinex.NewObsDecoder(bytes.NewReader(encodeSimulated(t, list[:3], marker, marker, 2)))
+	assert.NoError(err)
+	_, err = NewSolver(ephs).CheckPosition(dec, rinex.DefaultMaxPositionDiff)
+	assert.Error(err)
+}
+
+func TestNewReport_positionCheck(t *testing.T) {
+	assert := assert.New(t)
+	ephs, list, marker := constellation(t)
+	data := encodeSimulated(t, list, marker, rinex.CoordNEU{E: 2000}.Coord(marker), 1)
+
+	dec, err := rinex.NewObsDecoder(bytes.NewReader(data))
+	assert.NoError(err)
+	rep, err := rinex.NewReport(dec, rinex.WithPositionCheck(NewSolver(ephs), rinex.DefaultMaxPositionDiff))
+	assert.NoError(err)
+	if assert.NotNil(rep.Position) {
+		assert.False(rep.Position.OK)
+		assert.InDelta(2000, rep.Position.Distance, 0.1)
+	}
+	var out bytes.Buffer
+	assert.NoError(rep.Write(&out, rinex.ReportText))
+	assert.Contains(out.String(), "Position:      CHECK, 2000.0 m from the header position")
+}
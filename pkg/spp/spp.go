@@ -0,0 +1,293 @@
+// Package spp computes single point positions (SPP) from the pseudoranges of RINEX epochs and the broadcast
+// ephemerides, e.g. for the quality control of observation files or to monitor the position of Ntrip streams.
+//
+// The position, the receiver clock offset and the dilution of precision (DOP) are estimated per epoch
+// by iterative least squares. Currently only GPS L1 code observations are used. If the epoch has
+// Doppler observations, the receiver velocity is estimated as well.
+package spp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+const (
+	speedOfLight = 299792458.0     // [m/s]
+	omegaEarth   = 7.2921151467e-5 // earth's rotation rate [rad/s]
+	maxIter      = 10
+)
+
+// ErrTooFewSatellites is returned if less than 4 satellites are usable for a position.
+var ErrTooFewSatellites = errors.New("spp: too few satellites")
+
+// DefaultCodeTypes are the GPS L1 pseudorange types used by default, in order of preference.
+var DefaultCodeTypes = []string{"C1C", "C1W", "C1P", "C1", "P1"}
+
+// IonoModel returns the ionospheric delay in meters on GPS L1 for a receiver at pos and a satellite
+// at the azimuth and elevation az, el in degrees, at the GPS time t, e.g. iono.Klobuchar.
+type IonoModel interface {
+	Delay(t time.Time, pos rinex.LatLonHeight, az, el float64) float64
+}
+
+// TropoModel returns the tropospheric delay in meters for a receiver at pos and a satellite
+// at the elevation el in degrees, at the time t.
+type TropoModel interface {
+	Delay(t time.Time, pos rinex.LatLonHeight, el float64) float64
+}
+
+// DOP contains the dilution of precision of a solution. The horizontal and vertical DOPs refer to the
+// local North, East, Up frame.
+type DOP struct {
+	GDOP, PDOP, HDOP, VDOP, TDOP float64
+}
+
+// Solution is the single point position of an epoch.
+type Solution struct {
+	Time      time.Time
+	Position  rinex.Coord
+	Clock     float64 // receiver clock offset in seconds
+	NumSats   int     // number of satellites used
+	DOP       DOP
+	Residuals map[rinex.PRN]float64 // post-fit pseudorange residuals in meters
+	Velocity  *Velocity             // nil without Doppler observations
+}
+
+// RMS returns the root mean square of the residuals in meters.
+func (sol *Solution) RMS() float64 {
+	if len(sol.Residuals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, res := range sol.Residuals {
+		sum += res * res
+	}
+	return math.Sqrt(sum / float64(len(sol.Residuals)))
+}
+
+// Solver computes single point positions. The atmospheric models are optional,
+// the delays are not corrected if they are nil.
+type Solver struct {
+	EphemerisStore *rinex.EphemerisStore
+	CodeTypes      []string // pseudorange types in order of preference
+	DopplerTypes   []string // Doppler types in order of preference
+	ElevationMask  float64  // cutoff angle in degrees
+	Iono           IonoModel
+	Tropo          TropoModel
+}
+
+// NewSolver returns a solver using the broadcast ephemerides, the DefaultCodeTypes, the DefaultDopplerTypes
+// and an elevation mask of 10 degrees.
+func NewSolver(ephs *rinex.EphemerisStore) *Solver {
+	return &Solver{EphemerisStore: ephs, CodeTypes: DefaultCodeTypes, DopplerTypes: DefaultDopplerTypes, ElevationMask: 10}
+}
+
+// satRange is a pseudorange with the ephemeris of the satellite.
+type satRange struct {
+	prn rinex.PRN
+	eph *rinex.EphGPS
+	pr  float64
+}
+
+// Solve computes the position of the epoch and, if possible, its velocity with SolveVelocity.
+func (s *Solver) Solve(epo *rinex.Epoch) (*Solution, error) {
+	ranges := s.pseudoranges(epo)
+	if len(ranges) < 4 {
+		return nil, fmt.Errorf("%w: %d with pseudorange and ephemeris", ErrTooFewSatellites, len(ranges))
+	}
+
+	var x [4]float64 // X, Y, Z and receiver clock in meters
+	for iter := 0; iter < maxIter; iter++ {
+		rcv := rinex.Coord{X: x[0], Y: x[1], Z: x[2]}
+
+		// The elevations and the atmospheric delays are meaningless before the first iterations
+		// moved the position from the earth's center to its surface.
+		nearEarth := rcv.Distance(rinex.Coord{}) > 6e6
+		var geo rinex.LatLonHeight
+		if nearEarth {
+			geo = rcv.LatLonHeight(rinex.GRS80)
+		}
+
+		rows := make([][4]float64, 0, len(ranges))
+		res := make([]float64, 0, len(ranges))
+		prns := make([]rinex.PRN, 0, len(ranges))
+		for _, r := range ranges {
+			tTx := epo.Time.Add(-seconds(r.pr / speedOfLight))
+			dts := r.eph.ClockOffset(tTx) - r.eph.TGD
+			sat := r.eph.Position(tTx.Add(-seconds(dts)))
+
+			// earth rotation during the signal travel time
+			sinR, cosR := math.Sincos(omegaEarth * rcv.Distance(sat) / speedOfLight)
+			sat = rinex.Coord{X: cosR*sat.X + sinR*sat.Y, Y: -sinR*sat.X + cosR*sat.Y, Z: sat.Z}
+
+			rho := rcv.Distance(sat)
+			model := rho + x[3] - speedOfLight*dts
+			if nearEarth {
+				az, el := rcv.AzEl(sat)
+				if el < s.ElevationMask {
+					continue
+				}
+				if s.Iono != nil {
+					model += s.Iono.Delay(epo.Time, geo, az, el)
+				}
+				if s.Tropo != nil {
+					model += s.Tropo.Delay(epo.Time, geo, el)
+				}
+			}
+			rows = append(rows, [4]float64{(rcv.X - sat.X) / rho, (rcv.Y - sat.Y) / rho, (rcv.Z - sat.Z) / rho, 1})
+			res = append(res, r.pr-model)
+			prns = append(prns, r.prn)
+		}
+		if len(rows) < 4 {
+			return nil, fmt.Errorf("%w: %d above the elevation mask", ErrTooFewSatellites, len(rows))
+		}
+
+		q, ok := invert4(normalMatrix(rows))
+		if !ok {
+			return nil, errors.New("spp: singular satellite geometry")
+		}
+		var atb [4]float64
+		for i, row := range rows {
+			for j := range row {
+				atb[j] += row[j] * res[i]
+			}
+		}
+		var dx [4]float64
+		for i := range dx {
+			for j := range atb {
+				dx[i] += q[i][j] * atb[j]
+			}
+			x[i] += dx[i]
+		}
+		if math.Sqrt(dx[0]*dx[0]+dx[1]*dx[1]+dx[2]*dx[2]) > 1e-3 {
+			continue
+		}
+
+		sol := &Solution{
+			Time:      epo.Time,
+			Position:  rinex.Coord{X: x[0], Y: x[1], Z: x[2]},
+			Clock:     x[3] / speedOfLight,
+			NumSats:   len(rows),
+			Residuals: make(map[rinex.PRN]float64, len(rows)),
+		}
+		for i, row := range rows {
+			sol.Residuals[prns[i]] = res[i] - (row[0]*dx[0] + row[1]*dx[1] + row[2]*dx[2] + row[3]*dx[3])
+		}
+		sol.DOP, _ = dop(rows, sol.Position)
+		sol.Velocity, _ = s.SolveVelocity(epo, sol.Position)
+		return sol, nil
+	}
+	return nil, errors.New("spp: no convergence")
+}
+
+// pseudoranges returns the GPS pseudoranges of the epoch of the satellites with a valid ephemeris.
+func (s *Solver) pseudoranges(epo *rinex.Epoch) []satRange {
+	codeTypes := s.CodeTypes
+	if len(codeTypes) == 0 {
+		codeTypes = DefaultCodeTypes
+	}
+	ranges := make([]satRange, 0, len(epo.ObsList))
+	for _, satObs := range epo.ObsList {
+		if satObs.Prn.Sys != gnss.SysGPS {
+			continue
+		}
+		var pr float64
+		for _, typ := range codeTypes {
+			if obs, ok := satObs.Get(typ); ok && obs.Val != 0 {
+				pr = obs.Val
+				break
+			}
+		}
+		if pr == 0 {
+			continue
+		}
+		eph, err := s.EphemerisStore.Find(satObs.Prn, epo.Time)
+		if err != nil {
+			continue
+		}
+		ranges = append(ranges, satRange{prn: satObs.Prn, eph: eph, pr: pr})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].prn.Num < ranges[j].prn.Num })
+	return ranges
+}
+
+// dop computes the DOPs of the design matrix rows in the local frame at pos.
+func dop(rows [][4]float64, pos rinex.Coord) (DOP, bool) {
+	local := make([][4]float64, len(rows))
+	for i, row := range rows {
+		// the line of sight vector from the satellite to the receiver, as seen from pos
+		los := rinex.Coord{X: pos.X + row[0], Y: pos.Y + row[1], Z: pos.Z + row[2]}.NEU(pos)
+		local[i] = [4]float64{los.N, los.E, los.Up, 1}
+	}
+	q, ok := invert4(normalMatrix(local))
+	if !ok {
+		return DOP{}, false
+	}
+	return DOP{
+		GDOP: math.Sqrt(q[0][0] + q[1][1] + q[2][2] + q[3][3]),
+		PDOP: math.Sqrt(q[0][0] + q[1][1] + q[2][2]),
+		HDOP: math.Sqrt(q[0][0] + q[1][1]),
+		VDOP: math.Sqrt(q[2][2]),
+		TDOP: math.Sqrt(q[3][3]),
+	}, true
+}
+
+// normalMatrix returns A^T*A of the design matrix A.
+func normalMatrix(rows [][4]float64) [4][4]float64 {
+	var n [4][4]float64
+	for _, row := range rows {
+		for i := range row {
+			for j := range row {
+				n[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	return n
+}
+
+// invert4 inverts the matrix by Gauss-Jordan elimination with partial pivoting.
+func invert4(a [4][4]float64) ([4][4]float64, bool) {
+	var inv [4][4]float64
+	for i := range inv {
+		inv[i][i] = 1
+	}
+	for col := 0; col < 4; col++ {
+		p := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[p][col]) {
+				p = row
+			}
+		}
+		if math.Abs(a[p][col]) < 1e-12 {
+			return inv, false
+		}
+		a[col], a[p] = a[p], a[col]
+		inv[col], inv[p] = inv[p], inv[col]
+		f := a[col][col]
+		for k := 0; k < 4; k++ {
+			a[col][k] /= f
+			inv[col][k] /= f
+		}
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			f := a[row][col]
+			for k := 0; k < 4; k++ {
+				a[row][k] -= f * a[col][k]
+				inv[row][k] -= f * inv[col][k]
+			}
+		}
+	}
+	return inv, true
+}
+
+// seconds converts floating point seconds into a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
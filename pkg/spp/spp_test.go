@@ -0,0 +1,167 @@
+package spp
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+// navDataG20 is a GPS navigation file with one ephemeris.
+const navDataG20 = `     3.04           N: GNSS NAV DATA    G: GPS              RINEX VERSION / TYPE
+BCEmerge            congo               20200619 003025 GMT PGM / RUN BY / DATE
+    18                                                      LEAP SECONDS
+                                                            END OF HEADER
+G20 2020 06 18 00 00 00 5.274894647300E-04-1.136868377216E-13 0.000000000000E+00
+     8.300000000000E+01 2.078125000000E+01 5.373438110980E-09-2.252452975616E+00
+     1.156702637672E-06 5.203154985793E-03 7.405877113342E-06 5.153647661209E+03
+     3.456000000000E+05-1.247972249985E-07-2.679776962713E+00 2.048909664154E-08
+     9.344138223835E-01 2.252500000000E+02 2.669542608731E+00-8.333918569731E-09
+     4.632335812523E-10 1.000000000000E+00 2.110000000000E+03 0.000000000000E+00
+     2.000000000000E+00 0.000000000000E+00-8.847564458847E-09 8.300000000000E+01
+     3.393480000000E+05 4.000000000000E+00
+`
+
+var toc = time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+
+// constTropo is a tropospheric delay of 2.4 m in zenith, mapped by 1/sin(el).
+type constTropo struct{}
+
+func (constTropo) Delay(t time.Time, pos rinex.LatLonHeight, el float64) float64 {
+	return 2.4 / math.Sin(el*math.Pi/180)
+}
+
+// constellation returns G20 and satellites derived from it by shifting the orbit,
+// and a marker on the ground below G20.
+func constellation(t *testing.T) (*rinex.EphemerisStore, []*rinex.EphGPS, rinex.Coord) {
+	t.Helper()
+	dec, err := rinex.NewNavDecoder(strings.NewReader(navDataG20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephs, err := rinex.NewEphemerides(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g20, err := ephs.Find(rinex.PRN{Sys: gnss.SysGPS, Num: 20}, toc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []*rinex.EphGPS{g20}
+	for i, shift := range [][2]float64{{0.4, 0}, {-0.4, 0}, {0, 0.5}, {0, -0.5}, {0.3, 0.4}, {-0.3, -0.4}} {
+		eph := *g20
+		eph.PRN = rinex.PRN{Sys: gnss.SysGPS, Num: int8(i + 1)}
+		eph.M0 += shift[0]
+		eph.Omega0 += shift[1]
+		eph.ClockBias += float64(i) * 1e-5
+		ephs.Add(&eph)
+		list = append(list, &eph)
+	}
+	g := g20.Position(toc).LatLonHeight(rinex.GRS80)
+	g.Height = 100
+	return ephs, list, g.Coord(rinex.GRS80)
+}
+
+// simulate returns an epoch with the C1C pseudoranges observed at marker with the receiver clock offset,
+// delayed by the troposphere if tropo is not nil.
+func simulate(list []*rinex.EphGPS, marker rinex.Coord, t time.Time, rcvClock float64, tropo TropoModel) *rinex.Epoch {
+	epo := &rinex.Epoch{Time: t}
+	for _, eph := range list {
+		tau := 0.07
+		var sat rinex.Coord
+		for i := 0; i < 5; i++ {
+			sat = eph.Position(t.Add(-seconds(rcvClock + tau)))
+			sinR, cosR := math.Sincos(omegaEarth * tau)
+			sat = rinex.Coord{X: cosR*sat.X + sinR*sat.Y, Y: -sinR*sat.X + cosR*sat.Y, Z: sat.Z}
+			tau = marker.Distance(sat) / speedOfLight
+		}
+		dts := eph.ClockOffset(t.Add(-seconds(rcvClock+tau))) - eph.TGD
+		pr := speedOfLight * (tau + rcvClock - dts)
+		if tropo != nil {
+			_, el := marker.AzEl(sat)
+			pr += tropo.Delay(t, marker.LatLonHeight(rinex.GRS80), el)
+		}
+		epo.ObsList = append(epo.ObsList, rinex.NewSatObs(eph.PRN, map[string]rinex.Obs{"C1C": {Val: pr}}))
+	}
+	return epo
+}
+
+func TestSolver_Solve(t *testing.T) {
+	assert := assert.New(t)
+	ephs, list, marker := constellation(t)
+	solver := NewSolver(ephs)
+
+	sol, err := solver.Solve(simulate(list, marker, toc, 1e-4, nil))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.InDelta(0, sol.Position.Distance(marker), 0.01)
+	assert.InDelta(1e-4, sol.Clock, 1e-10)
+	assert.Equal(7, sol.NumSats)
+	assert.Len(sol.Residuals, 7)
+	assert.InDelta(0, sol.RMS(), 0.01)
+	assert.Greater(sol.DOP.PDOP, 1.0)
+	assert.Greater(sol.DOP.GDOP, sol.DOP.PDOP)
+	assert.Greater(sol.DOP.PDOP, sol.DOP.HDOP)
+	assert.InDelta(sol.DOP.PDOP*sol.DOP.PDOP, sol.DOP.HDOP*sol.DOP.HDOP+sol.DOP.VDOP*sol.DOP.VDOP, 1e-9)
+
+	// tropospheric delay
+	epo := simulate(list, marker, toc, 0, constTropo{})
+	sol, err = solver.Solve(epo)
+	assert.NoError(err)
+	assert.Greater(sol.Position.Distance(marker), 1.0, "not modeled")
+	solver.Tropo = constTropo{}
+	sol, err = solver.Solve(epo)
+	assert.NoError(err)
+	assert.InDelta(0, sol.Position.Distance(marker), 0.01, "modeled")
+}
+
+func TestSolver_errors(t *testing.T) {
+	assert := assert.New(t)
+	ephs, list, marker := constellation(t)
+	solver := NewSolver(ephs)
+
+	epo := simulate(list[:3], marker, toc, 0, nil)
+	_, err := solver.Solve(epo)
+	assert.True(errors.Is(err, ErrTooFewSatellites), "%v", err)
+
+	epo = simulate(list, marker, toc.Add(5*time.Hour), 0, nil)
+	_, err = solver.Solve(epo)
+	assert.True(errors.Is(err, ErrTooFewSatellites), "ephemerides too old: %v", err)
+
+	solver.ElevationMask = 89
+	_, err = solver.Solve(simulate(list, marker, toc, 0, nil))
+	assert.True(errors.Is(err, ErrTooFewSatellites), "elevation mask: %v", err)
+
+	solver = NewSolver(ephs)
+	solver.CodeTypes = []string{"C1W"}
+	_, err = solver.Solve(simulate(list, marker, toc, 0, nil))
+	assert.True(errors.Is(err, ErrTooFewSatellites), "code types: %v", err)
+}
+
+func TestInvert4(t *testing.T) {
+	assert := assert.New(t)
+	a := [4][4]float64{{4, 1, 0, 2}, {1, 3, 1, 0}, {0, 1, 5, 1}, {2, 0, 1, 6}}
+	inv, ok := invert4(a)
+	assert.True(ok)
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += a[i][k] * inv[k][j]
+			}
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			assert.InDelta(want, sum, 1e-12)
+		}
+	}
+	_, ok = invert4([4][4]float64{{1, 2, 3, 4}, {2, 4, 6, 8}, {0, 0, 1, 0}, {0, 0, 0, 1}})
+	assert.False(ok, "singular")
+}
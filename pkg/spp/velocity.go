@@ -0,0 +1,165 @@
+package spp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+const freqL1 = 1575.42e6 // GPS L1 carrier frequency [Hz]
+
+// DefaultDopplerTypes are the GPS L1 Doppler types used by default, in order of preference.
+var DefaultDopplerTypes = []string{"D1C", "D1W", "D1P", "D1"}
+
+// DefaultMaxSpeed is the speed in m/s above which CheckStatic regards a station as moving.
+// Doppler velocities from code receivers are accurate to some cm/s.
+const DefaultMaxSpeed = 0.1
+
+// Velocity is the receiver velocity of an epoch, estimated from the Doppler observations.
+type Velocity struct {
+	Time       time.Time
+	Velocity   rinex.Coord // in the earth-fixed frame in m/s
+	ClockDrift float64     // receiver clock drift in s/s
+	NumSats    int
+	Residuals  map[rinex.PRN]float64 // post-fit range rate residuals in m/s
+}
+
+// Speed returns the magnitude of the velocity in m/s.
+func (v *Velocity) Speed() float64 {
+	return v.Velocity.Distance(rinex.Coord{})
+}
+
+// NEU returns the velocity in the local North, East, Up frame at pos in m/s.
+func (v *Velocity) NEU(pos rinex.Coord) rinex.CoordNEU {
+	return rinex.Coord{X: pos.X + v.Velocity.X, Y: pos.Y + v.Velocity.Y, Z: pos.Z + v.Velocity.Z}.NEU(pos)
+}
+
+// SolveVelocity computes the receiver velocity of the epoch at the position pos, e.g. of a Solution or
+// the header position, from the GPS L1 Doppler observations and the satellite velocities of the
+// broadcast ephemerides. Satellites below the elevation mask are skipped.
+func (s *Solver) SolveVelocity(epo *rinex.Epoch, pos rinex.Coord) (*Velocity, error) {
+	dopplerTypes := s.DopplerTypes
+	if len(dopplerTypes) == 0 {
+		dopplerTypes = DefaultDopplerTypes
+	}
+	lambda := speedOfLight / freqL1
+
+	var rows [][4]float64
+	var res []float64
+	var prns []rinex.PRN
+	for _, satObs := range epo.ObsList {
+		if satObs.Prn.Sys != gnss.SysGPS {
+			continue
+		}
+		var doppler float64
+		for _, typ := range dopplerTypes {
+			if obs, ok := satObs.Get(typ); ok && obs.Val != 0 {
+				doppler = obs.Val
+				break
+			}
+		}
+		if doppler == 0 {
+			continue
+		}
+		eph, err := s.EphemerisStore.Find(satObs.Prn, epo.Time)
+		if err != nil {
+			continue
+		}
+
+		tTx := epo.Time.Add(-seconds(pos.Distance(eph.Position(epo.Time)) / speedOfLight))
+		sat := eph.Position(tTx)
+		if _, el := pos.AzEl(sat); el < s.ElevationMask {
+			continue
+		}
+		half := 500 * time.Millisecond
+		p1, p2 := eph.Position(tTx.Add(-half)), eph.Position(tTx.Add(half))
+		vs := rinex.Coord{X: p2.X - p1.X, Y: p2.Y - p1.Y, Z: p2.Z - p1.Z}
+		dtsDot := eph.ClockOffset(tTx.Add(half)) - eph.ClockOffset(tTx.Add(-half))
+
+		// earth rotation during the signal travel time
+		sinR, cosR := math.Sincos(omegaEarth * pos.Distance(sat) / speedOfLight)
+		sat = rinex.Coord{X: cosR*sat.X + sinR*sat.Y, Y: -sinR*sat.X + cosR*sat.Y, Z: sat.Z}
+		vs = rinex.Coord{X: cosR*vs.X + sinR*vs.Y, Y: -sinR*vs.X + cosR*vs.Y, Z: vs.Z}
+
+		// range rate = e*(vs-vr) + c*(drift - dtsDot), e the unit vector from the receiver to the satellite
+		rho := pos.Distance(sat)
+		e := [3]float64{(sat.X - pos.X) / rho, (sat.Y - pos.Y) / rho, (sat.Z - pos.Z) / rho}
+		rangeRate := -lambda * doppler
+		rows = append(rows, [4]float64{-e[0], -e[1], -e[2], 1})
+		res = append(res, rangeRate-(e[0]*vs.X+e[1]*vs.Y+e[2]*vs.Z)+speedOfLight*dtsDot)
+		prns = append(prns, satObs.Prn)
+	}
+	if len(rows) < 4 {
+		return nil, fmt.Errorf("%w: %d with Doppler and ephemeris", ErrTooFewSatellites, len(rows))
+	}
+
+	q, ok := invert4(normalMatrix(rows))
+	if !ok {
+		return nil, errors.New("spp: singular satellite geometry")
+	}
+	var atb, x [4]float64
+	for i, row := range rows {
+		for j := range row {
+			atb[j] += row[j] * res[i]
+		}
+	}
+	for i := range x {
+		for j := range atb {
+			x[i] += q[i][j] * atb[j]
+		}
+	}
+	v := &Velocity{
+		Time:       epo.Time,
+		Velocity:   rinex.Coord{X: x[0], Y: x[1], Z: x[2]},
+		ClockDrift: x[3] / speedOfLight,
+		NumSats:    len(rows),
+		Residuals:  make(map[rinex.PRN]float64, len(rows)),
+	}
+	for i, row := range rows {
+		v.Residuals[prns[i]] = res[i] - (row[0]*x[0] + row[1]*x[1] + row[2]*x[2] + row[3]*x[3])
+	}
+	return v, nil
+}
+
+// MotionCheck is the result of CheckStatic.
+type MotionCheck struct {
+	NumEpochs   int
+	MedianSpeed float64 // in m/s
+	MaxSpeed    float64 // in m/s, of the fastest epoch
+	NumMoving   int     // number of epochs with a speed above the threshold
+	Threshold   float64 // in m/s
+	OK          bool    // the median speed is within the threshold, false without epochs
+}
+
+// CheckStatic checks whether the velocities of a static station are compatible with no motion. Single
+// epochs may exceed the threshold due to noise, so a station is flagged as moving if the median speed
+// exceeds the threshold in m/s, e.g. DefaultMaxSpeed.
+func CheckStatic(vels []*Velocity, threshold float64) MotionCheck {
+	chk := MotionCheck{NumEpochs: len(vels), Threshold: threshold}
+	if len(vels) == 0 {
+		return chk
+	}
+	speeds := make([]float64, len(vels))
+	for i, v := range vels {
+		speeds[i] = v.Speed()
+		if speeds[i] > threshold {
+			chk.NumMoving++
+		}
+		if speeds[i] > chk.MaxSpeed {
+			chk.MaxSpeed = speeds[i]
+		}
+	}
+	sort.Float64s(speeds)
+	n := len(speeds)
+	chk.MedianSpeed = speeds[n/2]
+	if n%2 == 0 {
+		chk.MedianSpeed = (speeds[n/2-1] + speeds[n/2]) / 2
+	}
+	chk.OK = chk.MedianSpeed <= threshold
+	return chk
+}
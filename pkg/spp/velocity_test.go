@@ -0,0 +1,99 @@
+package spp
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+// addDoppler adds the D1C observations of a receiver at marker moving with vel and the clock drift.
+func addDoppler(epo *rinex.Epoch, list []*rinex.EphGPS, marker, vel rinex.Coord, drift float64) {
+	// geometric range incl. the earth rotation during the signal travel time
+	geomRange := func(eph *rinex.EphGPS, rcv rinex.Coord, t time.Time) float64 {
+		tau := 0.07
+		for i := 0; i < 5; i++ {
+			sat := eph.Position(t.Add(-seconds(tau)))
+			sinR, cosR := math.Sincos(omegaEarth * tau)
+			sat = rinex.Coord{X: cosR*sat.X + sinR*sat.Y, Y: -sinR*sat.X + cosR*sat.Y, Z: sat.Z}
+			tau = rcv.Distance(sat) / speedOfLight
+		}
+		return tau * speedOfLight
+	}
+	const h = 0.5
+	move := func(dt float64) rinex.Coord {
+		return rinex.Coord{X: marker.X + vel.X*dt, Y: marker.Y + vel.Y*dt, Z: marker.Z + vel.Z*dt}
+	}
+	for i, eph := range list {
+		t1, t2 := epo.Time.Add(-seconds(h)), epo.Time.Add(seconds(h))
+		rangeRate := (geomRange(eph, move(h), t2) - geomRange(eph, move(-h), t1)) / (2 * h)
+		dtsDot := (eph.ClockOffset(t2) - eph.ClockOffset(t1)) / (2 * h)
+		rangeRate += speedOfLight * (drift - dtsDot)
+		epo.ObsList[i].Set("D1C", rinex.Obs{Val: -rangeRate * freqL1 / speedOfLight})
+	}
+}
+
+func TestSolver_SolveVelocity(t *testing.T) {
+	assert := assert.New(t)
+	ephs, list, marker := constellation(t)
+	solver := NewSolver(ephs)
+
+	vel := rinex.CoordNEU{N: 3, E: -4, Up: 0.5}.Coord(marker)
+	vel = rinex.Coord{X: vel.X - marker.X, Y: vel.Y - marker.Y, Z: vel.Z - marker.Z}
+	epo := simulate(list, marker, toc, 0, nil)
+	addDoppler(epo, list, marker, vel, 1e-8)
+
+	v, err := solver.SolveVelocity(epo, marker)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(7, v.NumSats)
+	assert.InDelta(0, v.Velocity.Distance(vel), 0.005)
+	assert.InDelta(1e-8, v.ClockDrift, 1e-11)
+	assert.InDelta(math.Sqrt(25.25), v.Speed(), 0.005)
+	neu := v.NEU(marker)
+	assert.InDelta(3, neu.N, 0.005)
+	assert.InDelta(-4, neu.E, 0.005)
+	assert.InDelta(0.5, neu.Up, 0.005)
+	for prn, res := range v.Residuals {
+		assert.InDelta(0, res, 0.005, "%s", prn)
+	}
+
+	sol, err := solver.Solve(epo)
+	assert.NoError(err)
+	if assert.NotNil(sol.Velocity) {
+		assert.InDelta(0, sol.Velocity.Velocity.Distance(vel), 0.005)
+	}
+	sol, err = solver.Solve(simulate(list, marker, toc, 0, nil))
+	assert.NoError(err)
+	assert.Nil(sol.Velocity, "no Doppler")
+
+	epo.ObsList = epo.ObsList[:3]
+	_, err = solver.SolveVelocity(epo, marker)
+	assert.True(errors.Is(err, ErrTooFewSatellites), "%v", err)
+}
+
+func TestCheckStatic(t *testing.T) {
+	assert := assert.New(t)
+	vels := []*Velocity{
+		{Velocity: rinex.Coord{X: 0.01}},
+		{Velocity: rinex.Coord{Y: -0.02}},
+		{Velocity: rinex.Coord{Z: 0.5}}, // outlier
+	}
+	chk := CheckStatic(vels, DefaultMaxSpeed)
+	assert.True(chk.OK)
+	assert.Equal(3, chk.NumEpochs)
+	assert.Equal(1, chk.NumMoving)
+	assert.InDelta(0.02, chk.MedianSpeed, 1e-9)
+	assert.InDelta(0.5, chk.MaxSpeed, 1e-9)
+
+	vels = append(vels, &Velocity{Velocity: rinex.Coord{X: 0.3}}, &Velocity{Velocity: rinex.Coord{X: 0.2}})
+	chk = CheckStatic(vels, DefaultMaxSpeed)
+	assert.False(chk.OK, "spurious motion")
+	assert.InDelta(0.2, chk.MedianSpeed, 1e-9)
+
+	assert.False(CheckStatic(nil, DefaultMaxSpeed).OK)
+}
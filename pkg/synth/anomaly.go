@@ -0,0 +1,150 @@
+package synth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// AnomalyType is the kind of an Anomaly.
+type AnomalyType int
+
+// The anomaly types.
+const (
+	AnomalyGap       AnomalyType = iota // no epochs during the anomaly
+	AnomalySlip                         // cycle slip of Value cycles on all phases of the satellites at Start
+	AnomalySNRDrop                      // signal strengths of the satellites reduced by Value dBHz during the anomaly
+	AnomalyClockJump                    // receiver clock jump of Value seconds at Start, in the codes and phases
+)
+
+func (t AnomalyType) String() string {
+	switch t {
+	case AnomalyGap:
+		return "gap"
+	case AnomalySlip:
+		return "slip"
+	case AnomalySNRDrop:
+		return "SNR drop"
+	case AnomalyClockJump:
+		return "clock jump"
+	}
+	return fmt.Sprintf("AnomalyType(%d)", int(t))
+}
+
+// Anomaly is a disturbance of the generated epochs, e.g. to test the detection of data gaps or cycle slips.
+// Slips and clock jumps are permanent, gaps and SNR drops last for the Duration.
+type Anomaly struct {
+	Type     AnomalyType
+	Start    time.Time     // GPS time
+	Duration time.Duration // of gaps and SNR drops
+	Sats     []rinex.PRN   // satellites of slips and SNR drops, all if empty
+	Value    float64       // cycles of a slip, dBHz of a SNR drop, seconds of a clock jump
+}
+
+// active returns true if the gap or SNR drop lasts at t.
+func (a *Anomaly) active(t time.Time) bool {
+	return !t.Before(a.Start) && t.Before(a.Start.Add(a.Duration))
+}
+
+// affects returns true if the anomaly affects the satellite.
+func (a *Anomaly) affects(prn rinex.PRN) bool {
+	if len(a.Sats) == 0 {
+		return true
+	}
+	for _, p := range a.Sats {
+		if p == prn {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the anomaly.
+func (a *Anomaly) validate() error {
+	switch a.Type {
+	case AnomalyGap, AnomalySNRDrop:
+		if a.Duration <= 0 {
+			return fmt.Errorf("%s at %s: no duration", a.Type, a.Start)
+		}
+	case AnomalySlip, AnomalyClockJump:
+	default:
+		return fmt.Errorf("invalid anomaly type: %d", int(a.Type))
+	}
+	if a.Start.IsZero() {
+		return fmt.Errorf("%s: no start time", a.Type)
+	}
+	if a.Type != AnomalyGap && a.Value == 0 {
+		return fmt.Errorf("%s at %s: no value", a.Type, a.Start)
+	}
+	return nil
+}
+
+// inGap returns true if t is in a gap.
+func (g *Generator) inGap(t time.Time) bool {
+	for i := range g.cfg.Anomalies {
+		if a := &g.cfg.Anomalies[i]; a.Type == AnomalyGap && a.active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// clockJump returns the sum of the receiver clock jumps until t in seconds.
+func (g *Generator) clockJump(t time.Time) float64 {
+	var jump float64
+	for i := range g.cfg.Anomalies {
+		if a := &g.cfg.Anomalies[i]; a.Type == AnomalyClockJump && !t.Before(a.Start) {
+			jump += a.Value
+		}
+	}
+	return jump
+}
+
+// disturbance are the anomalies of a satellite at an epoch.
+type disturbance struct {
+	slip    float64 // cycles
+	lli     bool    // loss of lock since the last epoch
+	snrDrop float64 // dBHz
+}
+
+// disturbance returns the slips and SNR drops of the satellite at t. The loss of lock indicator is set
+// at the first epoch after a slip.
+func (g *Generator) disturbance(prn rinex.PRN, t time.Time) disturbance {
+	var d disturbance
+	for i := range g.cfg.Anomalies {
+		a := &g.cfg.Anomalies[i]
+		if !a.affects(prn) {
+			continue
+		}
+		switch a.Type {
+		case AnomalySlip:
+			if !t.Before(a.Start) {
+				d.slip += a.Value
+				d.lli = d.lli || t.Sub(a.Start) < g.cfg.Interval
+			}
+		case AnomalySNRDrop:
+			if a.active(t) {
+				d.snrDrop += a.Value
+			}
+		}
+	}
+	return d
+}
+
+// validAnomalies checks the anomalies of the configuration.
+func validAnomalies(anomalies []Anomaly, signals map[gnss.System][]string) error {
+	for i := range anomalies {
+		a := &anomalies[i]
+		if err := a.validate(); err != nil {
+			return err
+		}
+		for _, prn := range a.Sats {
+			if _, ok := signals[prn.Sys]; !ok {
+				return fmt.Errorf("%s at %s: satellite %s of an unconfigured system", a.Type, a.Start, prn)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package synth
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_anomalies(t *testing.T) {
+	assert := assert.New(t)
+	g0, err := New(Config{Start: start, Duration: 10 * time.Minute})
+	if !assert.NoError(err) {
+		return
+	}
+	prn := g0.Epoch(start).ObsList[0].Prn
+	other := g0.Epoch(start).ObsList[1].Prn
+	g, err := New(Config{Start: start, Duration: 10 * time.Minute, Anomalies: []Anomaly{
+		{Type: AnomalyGap, Start: start.Add(time.Minute), Duration: 2 * time.Minute},
+		{Type: AnomalySlip, Start: start.Add(4 * time.Minute), Sats: []rinex.PRN{prn}, Value: 7},
+		{Type: AnomalySNRDrop, Start: start.Add(5 * time.Minute), Duration: time.Minute, Value: 15},
+		{Type: AnomalyClockJump, Start: start.Add(8*time.Minute + 10*time.Second), Value: 1e-3},
+	}})
+	if !assert.NoError(err) {
+		return
+	}
+
+	get := func(g *Generator, at time.Time, prn rinex.PRN, typ string) rinex.Obs {
+		epo := g.Epoch(at)
+		for _, satObs := range epo.ObsList {
+			if satObs.Prn == prn {
+				obs, _ := satObs.Get(typ)
+				return obs
+			}
+		}
+		t.Fatalf("%s: no observations of %s", at, prn)
+		return rinex.Obs{}
+	}
+
+	assert.NotNil(g.Epoch(start.Add(30 * time.Second)))
+	assert.Nil(g.Epoch(start.Add(time.Minute)))
+	assert.Nil(g.Epoch(start.Add(150 * time.Second)))
+	assert.NotNil(g.Epoch(start.Add(3 * time.Minute)))
+
+	// slip
+	t1 := start.Add(4 * time.Minute)
+	for _, tt := range []time.Time{t1, t1.Add(30 * time.Second)} {
+		obs, obs0 := get(g, tt, prn, "L1C"), get(g0, tt, prn, "L1C")
+		assert.InDelta(7, obs.Val-obs0.Val, 1e-6)
+		assert.Equal(tt == t1, obs.LLI == 1, "%s", tt)
+		obs = get(g, tt, other, "L1C")
+		assert.Equal(get(g0, tt, other, "L1C"), obs)
+	}
+	assert.Equal(get(g0, t1.Add(-30*time.Second), prn, "L1C"), get(g, t1.Add(-30*time.Second), prn, "L1C"))
+
+	// SNR drop
+	t2 := start.Add(5 * time.Minute)
+	assert.InDelta(15, get(g0, t2, other, "S1C").Val-get(g, t2, other, "S1C").Val, 1e-9)
+	assert.True(get(g, t2, other, "L1C").SNR < get(g0, t2, other, "L1C").SNR)
+	assert.Equal(get(g0, t2.Add(time.Minute), other, "S2W"), get(g, t2.Add(time.Minute), other, "S2W"))
+
+	// clock jump
+	t3 := start.Add(8*time.Minute + 30*time.Second)
+	assert.InDelta(gnss.SpeedOfLight*1e-3, get(g, t3, other, "C1C").Val-get(g0, t3, other, "C1C").Val, 1e-6)
+	assert.Equal(get(g0, t3.Add(-time.Minute), other, "C1C"), get(g, t3.Add(-time.Minute), other, "C1C"))
+
+	var buf bytes.Buffer
+	assert.NoError(g.WriteObs(&buf))
+	dec, err := rinex.NewObsDecoder(&buf)
+	if !assert.NoError(err) {
+		return
+	}
+	n := 0
+	for dec.NextEpoch() {
+		n++
+	}
+	assert.NoError(dec.Err())
+	assert.Equal(16, n)
+}
+
+func TestAnomaly_validate(t *testing.T) {
+	assert := assert.New(t)
+	glo, _ := rinex.NewPRN(gnss.SysGLO, 1)
+	for _, a := range []Anomaly{
+		{Type: AnomalyGap, Start: start},
+		{Type: AnomalySlip, Start: start},
+		{Type: AnomalySNRDrop, Start: start, Value: 10},
+		{Type: AnomalyClockJump, Value: 1e-3},
+		{Type: AnomalyType(9), Start: start, Value: 1},
+		{Type: AnomalySlip, Start: start, Value: 1, Sats: []rinex.PRN{glo}},
+	} {
+		_, err := New(Config{Start: start, Anomalies: []Anomaly{a}})
+		assert.Error(err, "%+v", a)
+	}
+	assert.Equal("SNR drop", AnomalySNRDrop.String())
+}
@@ -0,0 +1,135 @@
+package synth
+
+import (
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+const (
+	gm         = 3.986005e14     // earth's gravitational constant of the GPS ephemerides [m^3/s^2]
+	omegaEarth = 7.2921151467e-5 // earth's rotation rate [rad/s]
+
+	// EphemerisInterval is the interval of the clock reference epochs of the generated ephemerides.
+	EphemerisInterval = 2 * time.Hour
+
+	fitInterval = 4.0 // hours
+)
+
+// clockEpoch is the reference epoch of the satellite clock polynomials.
+var clockEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// constellation describes the nominal orbits of a satellite system. The satellites are evenly
+// distributed on the planes, numbered plane by plane.
+type constellation struct {
+	sqrtA       float64 // sqrt(m)
+	inclination float64 // degrees
+	planes      int
+	perPlane    int
+}
+
+// constellations are the supported systems.
+var constellations = map[gnss.System]constellation{
+	gnss.SysGPS: {sqrtA: 5153.7954775, inclination: 55, planes: 6, perPlane: 4},
+	gnss.SysGAL: {sqrtA: 5440.588203, inclination: 56, planes: 3, perPlane: 8},
+}
+
+// satellites returns the satellites of the system.
+func (c constellation) satellites(sys gnss.System) []rinex.PRN {
+	prns := make([]rinex.PRN, 0, c.planes*c.perPlane)
+	for num := 1; num <= c.planes*c.perPlane; num++ {
+		prn, err := rinex.NewPRN(sys, num)
+		if err != nil {
+			panic(err)
+		}
+		prns = append(prns, prn)
+	}
+	return prns
+}
+
+// orbit contains the Keplerian elements of a satellite at the start of the GPS time, without perturbations.
+// The node is given in the inertial frame. The satellite clock is a linear polynomial at clockEpoch.
+type orbit struct {
+	sqrtA, ecc, inc, perigee, node, anomaly float64
+	clockBias, clockDrift                   float64
+}
+
+// orbit returns the orbit of the satellite. The orbits do not depend on the seed, so that the geometry
+// is the same for all seeds, but the clocks do.
+func (g *Generator) orbit(prn rinex.PRN) orbit {
+	c := constellations[prn.Sys]
+	plane, slot := (int(prn.Num)-1)/c.perPlane, (int(prn.Num)-1)%c.perPlane
+	deg := math.Pi / 180
+	return orbit{
+		sqrtA:      c.sqrtA,
+		ecc:        0.001 * float64(1+int(prn.Num)%10),
+		inc:        c.inclination * deg,
+		perigee:    float64(int(prn.Num)*37%360) * deg,
+		node:       float64(plane) * 360 / float64(c.planes) * deg,
+		anomaly:    (float64(slot)*360/float64(c.perPlane) + float64(plane)*15) * deg,
+		clockBias:  3e-4 * (2*g.uniform(0, prn, "clock bias") - 1),
+		clockDrift: 5e-13 * (2*g.uniform(0, prn, "clock drift") - 1),
+	}
+}
+
+// Ephemeris returns the broadcast ephemeris of the satellite with the clock reference epoch toc.
+// All ephemerides of a satellite describe the same orbit and clock, so the choice of the ephemeris does
+// not change the computed positions. Galileo ephemerides have the same Keplerian parameters as GPS ones.
+func (g *Generator) Ephemeris(prn rinex.PRN, toc time.Time) *rinex.EphGPS {
+	o := g.orbit(prn)
+	week, toe := gnsstime.GPSWeek(toc)
+	weekStart := gnsstime.FromGPSWeek(week, 0)
+	n := math.Sqrt(gm / math.Pow(o.sqrtA, 6))
+	dt := toc.Sub(gnsstime.GPSEpoch).Seconds()
+	iod := float64(int(toc.Sub(gnsstime.GPSEpoch)/EphemerisInterval) % 256)
+	return &rinex.EphGPS{
+		PRN:         prn,
+		TOC:         toc,
+		ClockBias:   o.clockBias + o.clockDrift*toc.Sub(clockEpoch).Seconds(),
+		ClockDrift:  o.clockDrift,
+		IODE:        iod,
+		M0:          normAngle(o.anomaly + n*dt),
+		Ecc:         o.ecc,
+		SqrtA:       o.sqrtA,
+		Toe:         toe,
+		Omega0:      normAngle(o.node - omegaEarth*weekStart.Sub(gnsstime.GPSEpoch).Seconds()),
+		I0:          o.inc,
+		Omega:       o.perigee,
+		ToeWeek:     float64(week),
+		URA:         2,
+		IODC:        iod,
+		Tom:         math.Max(toe-EphemerisInterval.Seconds(), 0),
+		FitInterval: fitInterval,
+	}
+}
+
+// ephemeris returns the ephemeris with the clock reference epoch closest to t.
+func (g *Generator) ephemeris(prn rinex.PRN, t time.Time) *rinex.EphGPS {
+	return g.Ephemeris(prn, t.Add(EphemerisInterval/2).Truncate(EphemerisInterval))
+}
+
+// Ephemerides returns the ephemerides of all satellites valid during the configured time span,
+// sorted by satellite and clock reference epoch.
+func (g *Generator) Ephemerides() []*rinex.EphGPS {
+	first := g.cfg.Start.Truncate(EphemerisInterval)
+	end := g.cfg.Start.Add(g.cfg.Duration)
+	var ephs []*rinex.EphGPS
+	for _, prn := range g.prns {
+		for toc := first; !toc.After(end); toc = toc.Add(EphemerisInterval) {
+			ephs = append(ephs, g.Ephemeris(prn, toc))
+		}
+	}
+	return ephs
+}
+
+// normAngle returns the angle in the range [-pi, pi).
+func normAngle(rad float64) float64 {
+	rad = math.Mod(rad+math.Pi, 2*math.Pi)
+	if rad < 0 {
+		rad += 2 * math.Pi
+	}
+	return rad - math.Pi
+}
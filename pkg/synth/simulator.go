@@ -0,0 +1,146 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+)
+
+// SimOptions configures the Simulator.
+type SimOptions struct {
+	// Speed is the pacing factor, e.g. 1 for real time or 10 for ten times faster.
+	// Zero means no pacing, the epochs are emitted as fast as possible.
+	Speed float64
+
+	// Live emits the epochs of the current GPS time, starting with the next multiple of the interval,
+	// until the context is canceled. The start time and the duration of the Config and the Speed are ignored.
+	Live bool
+
+	// Latency delays the epochs after their epoch time in live mode, e.g. to test latency monitoring.
+	Latency time.Duration
+
+	StationID uint16 // RTCM reference station ID
+
+	// StationInterval is the interval of the station messages 1006 and 1033, defaults to 10 seconds.
+	StationInterval time.Duration
+
+	// Ephemerides sends the GPS ephemerides as message 1019, each as soon as it is the closest one.
+	Ephemerides bool
+}
+
+// Simulator emits the epochs of a Generator at their true cadence, like a receiver, e.g. to test casters,
+// clients and real-time quality control end to end. The anomalies of the Config disturb the stream,
+// gaps delay the next epoch.
+type Simulator struct {
+	gen  *Generator
+	opts SimOptions
+	now  func() time.Time
+}
+
+// NewSimulator returns a simulator of the generator's epochs.
+func NewSimulator(gen *Generator, opts SimOptions) *Simulator {
+	if opts.StationInterval <= 0 {
+		opts.StationInterval = 10 * time.Second
+	}
+	return &Simulator{gen: gen, opts: opts, now: time.Now}
+}
+
+// Run passes the epochs to fn at their cadence. Run returns when all epochs of the configured time span
+// are emitted, the context is canceled or fn returns an error.
+func (s *Simulator) Run(ctx context.Context, fn func(*rinex.Epoch) error) error {
+	interval := s.gen.cfg.Interval
+	first, n := s.gen.cfg.Start, s.gen.Epochs()
+	if s.opts.Live {
+		first, n = gnsstime.UTCToGPS(s.now().UTC()).Truncate(interval).Add(interval), -1
+	}
+	start := s.now()
+	for i := 0; n < 0 || i < n; i++ {
+		t := first.Add(time.Duration(i) * interval)
+		var due time.Time
+		if s.opts.Live {
+			due = gnsstime.GPSToUTC(t).Add(s.opts.Latency)
+		} else if s.opts.Speed > 0 {
+			due = start.Add(time.Duration(float64(t.Sub(first)) / s.opts.Speed))
+		}
+		if err := s.wait(ctx, due); err != nil {
+			return err
+		}
+		epo := s.gen.Epoch(t)
+		if epo == nil {
+			continue
+		}
+		if err := fn(epo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait waits until due, a zero due does not wait.
+func (s *Simulator) wait(ctx context.Context, due time.Time) error {
+	if err := ctx.Err(); err != nil || due.IsZero() {
+		return err
+	}
+	timer := time.NewTimer(due.Sub(s.now()))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RunRTCM writes the epochs as RTCM 3 MSM7 messages to w, together with the station messages 1006 and 1033
+// and optionally the GPS ephemerides, like rtcm3.Replay. The messages of an epoch are written at once.
+func (s *Simulator) RunRTCM(ctx context.Context, w io.Writer) error {
+	hdr := s.gen.ObsHeader()
+	msmEnc := rtcm3.NewMSMEncoder(s.opts.StationID)
+	arp := rtcm3.NewStationARP(s.opts.StationID, &hdr)
+	desc := rtcm3.NewDescriptor(s.opts.StationID, &hdr)
+
+	var buf bytes.Buffer
+	enc := rtcm3.NewEncoder(&buf)
+	var lastStation time.Time
+	sentEph := make(map[rinex.PRN]time.Time) // clock reference epoch of the last sent ephemeris
+	return s.Run(ctx, func(epo *rinex.Epoch) error {
+		buf.Reset()
+		if lastStation.IsZero() || epo.Time.Sub(lastStation) >= s.opts.StationInterval {
+			if err := enc.Encode(arp); err != nil {
+				return err
+			}
+			if err := enc.Encode(desc); err != nil {
+				return err
+			}
+			lastStation = epo.Time
+		}
+		if s.opts.Ephemerides {
+			for _, prn := range s.gen.prns {
+				if prn.Sys != gnss.SysGPS {
+					continue
+				}
+				eph := s.gen.ephemeris(prn, epo.Time)
+				if sentEph[prn].Equal(eph.TOC) {
+					continue
+				}
+				if err := enc.Encode(&rtcm3.GPSEphemeris{EphGPS: *eph}); err != nil {
+					return err
+				}
+				sentEph[prn] = eph.TOC
+			}
+		}
+		for _, msg := range msmEnc.Messages(epo) {
+			if err := enc.Encode(msg); err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
@@ -0,0 +1,99 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/rtcm3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulator_Run(t *testing.T) {
+	assert := assert.New(t)
+	g, err := New(Config{Start: start, Interval: time.Second, Duration: 20 * time.Second, Anomalies: []Anomaly{
+		{Type: AnomalyGap, Start: start.Add(5 * time.Second), Duration: 5 * time.Second},
+	}})
+	if !assert.NoError(err) {
+		return
+	}
+	sim := NewSimulator(g, SimOptions{Speed: 100})
+	var times []time.Time
+	begin := time.Now()
+	err = sim.Run(context.Background(), func(epo *rinex.Epoch) error {
+		times = append(times, epo.Time)
+		return nil
+	})
+	assert.NoError(err)
+	assert.True(time.Since(begin) >= 190*time.Millisecond, "elapsed %s", time.Since(begin))
+	assert.Len(times, 15)
+	assert.Equal(start.Add(10*time.Second), times[5])
+
+	// canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	sim = NewSimulator(g, SimOptions{Speed: 1})
+	n := 0
+	err = sim.Run(ctx, func(epo *rinex.Epoch) error {
+		n++
+		cancel()
+		return nil
+	})
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, n)
+}
+
+func TestSimulator_live(t *testing.T) {
+	assert := assert.New(t)
+	g, err := New(Config{Start: start, Interval: 100 * time.Millisecond})
+	if !assert.NoError(err) {
+		return
+	}
+	sim := NewSimulator(g, SimOptions{Live: true, Latency: 10 * time.Millisecond})
+	errStop := errors.New("stop")
+	var times []time.Time
+	err = sim.Run(context.Background(), func(epo *rinex.Epoch) error {
+		latency := gnsstime.UTCToGPS(time.Now().UTC()).Sub(epo.Time)
+		assert.True(latency >= 10*time.Millisecond && latency < time.Second, "latency %s", latency)
+		times = append(times, epo.Time)
+		if len(times) == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(errStop, err)
+	if assert.Len(times, 3) {
+		assert.Equal(times[0], times[0].Truncate(100*time.Millisecond))
+		assert.Equal(100*time.Millisecond, times[2].Sub(times[1]))
+	}
+}
+
+func TestSimulator_RunRTCM(t *testing.T) {
+	assert := assert.New(t)
+	g, err := New(Config{Start: start.Add(30 * time.Minute), Duration: time.Hour})
+	if !assert.NoError(err) {
+		return
+	}
+	var buf bytes.Buffer
+	sim := NewSimulator(g, SimOptions{StationID: 7, StationInterval: time.Minute, Ephemerides: true})
+	if !assert.NoError(sim.RunRTCM(context.Background(), &buf)) {
+		return
+	}
+	counts := make(map[int]int)
+	dec := rtcm3.NewDecoder(&buf)
+	for dec.Next() {
+		msg := dec.Message()
+		counts[msg.Number()]++
+		if msm, ok := msg.(*rtcm3.MSM7); ok {
+			assert.Equal(uint16(7), msm.StationID)
+		}
+	}
+	assert.Equal(60, counts[1006])
+	assert.Equal(60, counts[1033])
+	assert.Equal(120, counts[1077])
+	assert.Equal(120, counts[1097])
+	assert.Equal(48, counts[1019], "24 satellites with two ephemerides each")
+}
@@ -0,0 +1,377 @@
+// Package synth generates synthetic RINEX observation and navigation files, e.g. to build large test
+// corpora without shipping real files. The satellites move on Keplerian orbits of nominal GPS and Galileo
+// constellations, the observations are computed from the geometric ranges, the satellite clocks, optionally
+// the atmospheric delays and the configured noise. The noise is derived from the seed, the epoch time,
+// the satellite and the observation type, so the same configuration always gives the same files and the
+// epochs do not depend on the order in which they are generated. Anomalies like gaps, cycle slips, SNR drops
+// and receiver clock jumps can be added, e.g. to test quality checks.
+//
+//	gen, err := synth.New(synth.Config{Start: time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC), Noise: synth.DefaultNoise})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	obsPath, navPath, err := gen.WriteFiles(dir)
+//
+// The navigation file contains the ephemerides of the orbits, so that positioning from the generated
+// pseudoranges, e.g. with the spp package, gives the station position back.
+//
+// The Simulator emits the epochs in real time, as RINEX epochs or as RTCM 3 stream, for end-to-end tests
+// of casters, clients and real-time quality control.
+package synth
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/gnsstime"
+	"github.com/de-bkg/gognss/pkg/iono"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/tropo"
+)
+
+// Defaults of the Config.
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultDuration      = time.Hour
+	DefaultElevationMask = 5.0
+)
+
+// DefaultStation is the station used if the Config has no station name.
+var DefaultStation = Station{
+	Name:     "SYNT00DEU",
+	Position: rinex.Coord{X: 4075580.3, Y: 931853.9, Z: 4801568.2},
+	Receiver: "SYNTH",
+	Antenna:  "SYNTH           NONE",
+}
+
+// DefaultSignals are the observation types used if the Config has no signals.
+var DefaultSignals = map[gnss.System][]string{
+	gnss.SysGPS: {"C1C", "L1C", "D1C", "S1C", "C2W", "L2W", "D2W", "S2W"},
+	gnss.SysGAL: {"C1C", "L1C", "D1C", "S1C", "C5Q", "L5Q", "D5Q", "S5Q"},
+}
+
+// DefaultNoise is a noise model of a geodetic receiver.
+var DefaultNoise = Noise{Code: 0.3, Phase: 0.01, SNR: 1}
+
+// Klobuchar is the ionosphere model used if Config.Atmosphere is set. Its parameters are written to the
+// header of the navigation file.
+var Klobuchar = iono.Klobuchar{
+	Alpha: [4]float64{1.1176e-08, 7.4506e-09, -5.9605e-08, -5.9605e-08},
+	Beta:  [4]float64{9.0112e+04, 0, -1.9661e+05, -6.5536e+04},
+}
+
+// Station describes the receiving station.
+type Station struct {
+	Name     string      // 9 char station name, e.g. WTZR00DEU
+	Position rinex.Coord // XYZ in m
+	Receiver string      // receiver type
+	Antenna  string      // antenna type and radome
+}
+
+// Noise is the standard deviation of the white noise added to the observations. The zero value
+// generates observations without noise.
+type Noise struct {
+	Code  float64 // pseudoranges in m
+	Phase float64 // carrier phases in cycles
+	SNR   float64 // signal strengths in dBHz
+}
+
+// Config configures the Generator.
+type Config struct {
+	Station Station // default DefaultStation
+
+	// Signals are the observation types per system, in the order of the RINEX header, default DefaultSignals.
+	// Only GPS and Galileo are supported, and code, phase, Doppler and signal strength types.
+	Signals map[gnss.System][]string
+
+	Start         time.Time     // GPS time of the first epoch
+	Interval      time.Duration // default DefaultInterval
+	Duration      time.Duration // time span of the epochs, default DefaultDuration
+	ElevationMask float64       // in degrees, default DefaultElevationMask, negative for none
+
+	// Atmosphere adds the ionospheric delays of the Klobuchar model and the tropospheric delays of the
+	// Saastamoinen model in the standard atmosphere.
+	Atmosphere bool
+
+	Noise Noise
+	Seed  int64 // seed of the noise, the satellite clocks and the phase ambiguities
+
+	Anomalies []Anomaly // e.g. gaps and cycle slips
+}
+
+// Generator generates the epochs and ephemerides of a Config.
+type Generator struct {
+	cfg   Config
+	prns  []rinex.PRN
+	geo   rinex.LatLonHeight
+	tropo tropo.Saastamoinen
+}
+
+// New returns a generator for the configuration.
+func New(cfg Config) (*Generator, error) {
+	if cfg.Station.Name == "" {
+		cfg.Station = DefaultStation
+	}
+	if len(cfg.Station.Name) != 9 {
+		return nil, fmt.Errorf("invalid station name: %q", cfg.Station.Name)
+	}
+	if cfg.Station.Position.Distance(rinex.Coord{}) < 6e6 {
+		return nil, fmt.Errorf("invalid station position: %v", cfg.Station.Position)
+	}
+	if cfg.Start.IsZero() {
+		return nil, fmt.Errorf("no start time")
+	}
+	cfg.Start = cfg.Start.UTC()
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Duration == 0 {
+		cfg.Duration = DefaultDuration
+	}
+	if cfg.Interval < 0 || cfg.Duration < 0 {
+		return nil, fmt.Errorf("invalid interval or duration: %s, %s", cfg.Interval, cfg.Duration)
+	}
+	if cfg.ElevationMask == 0 {
+		cfg.ElevationMask = DefaultElevationMask
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = DefaultSignals
+	}
+
+	g := &Generator{cfg: cfg, geo: cfg.Station.Position.LatLonHeight(rinex.GRS80)}
+	for sys, types := range cfg.Signals {
+		c, ok := constellations[sys]
+		if !ok {
+			return nil, fmt.Errorf("unsupported satellite system: %v", sys)
+		}
+		if len(types) == 0 {
+			return nil, fmt.Errorf("no signals for %v", sys)
+		}
+		for _, typ := range types {
+			code := rinex.ObsCode(typ)
+			if len(typ) != 3 || code.Frequency(sys) == 0 || !isObsType(code.Type()) {
+				return nil, fmt.Errorf("unsupported observation type for %v: %q", sys, typ)
+			}
+		}
+		g.prns = append(g.prns, c.satellites(sys)...)
+	}
+	if err := validAnomalies(cfg.Anomalies, cfg.Signals); err != nil {
+		return nil, err
+	}
+	sort.Slice(g.prns, func(i, j int) bool {
+		if g.prns[i].Sys != g.prns[j].Sys {
+			return g.prns[i].Sys < g.prns[j].Sys
+		}
+		return g.prns[i].Num < g.prns[j].Num
+	})
+	return g, nil
+}
+
+func isObsType(typ byte) bool {
+	return typ == 'C' || typ == 'L' || typ == 'D' || typ == 'S'
+}
+
+// Config returns the configuration with the defaults applied.
+func (g *Generator) Config() Config {
+	return g.cfg
+}
+
+// Satellites returns the satellites of the configured systems.
+func (g *Generator) Satellites() []rinex.PRN {
+	return append([]rinex.PRN(nil), g.prns...)
+}
+
+// Epochs returns the number of epochs in the configured time span.
+func (g *Generator) Epochs() int {
+	return int((g.cfg.Duration + g.cfg.Interval - 1) / g.cfg.Interval)
+}
+
+// ObsHeader returns the header of the observation file.
+func (g *Generator) ObsHeader() rinex.ObsHeader {
+	st := g.cfg.Station
+	sys := gnss.SysMIXED
+	if len(g.cfg.Signals) == 1 {
+		sys = g.prns[0].Sys
+	}
+	obsTypes := make(map[gnss.System][]string, len(g.cfg.Signals))
+	for s, types := range g.cfg.Signals {
+		obsTypes[s] = append([]string(nil), types...)
+	}
+	return rinex.ObsHeader{
+		RINEXVersion:       3.04,
+		RINEXType:          "O",
+		SatSystem:          sys,
+		Pgm:                "gognss synth",
+		Date:               g.cfg.Start.Format("20060102 150405") + " GPS",
+		Comments:           []string{fmt.Sprintf("synthetic data, seed %d", g.cfg.Seed)},
+		MarkerName:         st.Name[:4],
+		MarkerType:         "GEODETIC",
+		ReceiverType:       st.Receiver,
+		AntennaType:        st.Antenna,
+		Position:           st.Position,
+		ObsTypes:           obsTypes,
+		SignalStrengthUnit: "DBHZ",
+		Interval:           g.cfg.Interval.Seconds(),
+		TimeOfFirstObs:     g.cfg.Start,
+		TimeOfLastObs:      g.cfg.Start.Add(time.Duration(g.Epochs()-1) * g.cfg.Interval),
+		TimeSystem:         "GPS",
+		LeapSeconds:        gnsstime.LeapSecondsGPS(g.cfg.Start),
+	}
+}
+
+// NavHeader returns the header of the navigation file.
+func (g *Generator) NavHeader() rinex.NavHeader {
+	sys := gnss.SysMIXED
+	if len(g.cfg.Signals) == 1 {
+		sys = g.prns[0].Sys
+	}
+	return rinex.NavHeader{
+		RINEXVersion: 3.04,
+		RINEXType:    "N",
+		SatSystem:    sys,
+		Pgm:          "gognss synth",
+		Date:         g.cfg.Start.Format("20060102 150405") + " GPS",
+		IonoCorr:     map[string][4]float64{"GPSA": Klobuchar.Alpha, "GPSB": Klobuchar.Beta},
+		LeapSeconds:  gnsstime.LeapSecondsGPS(g.cfg.Start),
+	}
+}
+
+// Epoch returns the observations at the GPS time t of the satellites above the elevation mask,
+// in the order of Satellites. It returns nil if t is in a gap.
+func (g *Generator) Epoch(t time.Time) *rinex.Epoch {
+	if g.inGap(t) {
+		return nil
+	}
+	epo := &rinex.Epoch{Time: t, Flag: rinex.EpochFlagOK}
+	clock := gnss.SpeedOfLight * g.clockJump(t)
+	for _, prn := range g.prns {
+		satObs, ok := g.observe(prn, t, clock)
+		if ok {
+			epo.ObsList = append(epo.ObsList, satObs)
+		}
+	}
+	epo.NumSat = uint8(len(epo.ObsList))
+	return epo
+}
+
+// observe returns the observations of the satellite at t, with the receiver clock error in meters,
+// false if it is below the elevation mask.
+func (g *Generator) observe(prn rinex.PRN, t time.Time, clock float64) (rinex.SatObs, bool) {
+	eph := g.ephemeris(prn, t)
+	rng, sat := g.satRange(eph, t)
+	az, el := g.cfg.Station.Position.AzEl(sat)
+	if g.cfg.ElevationMask >= 0 && el < g.cfg.ElevationMask {
+		return rinex.SatObs{}, false
+	}
+	half := 500 * time.Millisecond
+	r1, _ := g.satRange(eph, t.Add(-half))
+	r2, _ := g.satRange(eph, t.Add(half))
+	rangeRate := r2 - r1
+
+	var ionoL1, trop float64
+	if g.cfg.Atmosphere {
+		ionoL1 = Klobuchar.Delay(t, g.geo, az, el)
+		trop = g.tropo.Delay(t, g.geo, el)
+	}
+	dist := g.disturbance(prn, t)
+	snr := 25 + 25*math.Sin(el*math.Pi/180) - dist.snrDrop
+
+	types := g.cfg.Signals[prn.Sys]
+	obs := make(map[string]rinex.Obs, len(types))
+	for _, typ := range types {
+		code := rinex.ObsCode(typ)
+		freq := code.Frequency(prn.Sys)
+		lambda := gnss.SpeedOfLight / freq
+		ionoDelay := ionoL1 * (iono.FreqL1 / freq) * (iono.FreqL1 / freq)
+		noise := g.normal(t, prn, typ)
+		switch code.Type() {
+		case 'C':
+			obs[typ] = rinex.Obs{Val: rng + clock + ionoDelay + trop + g.cfg.Noise.Code*noise}
+		case 'L':
+			sig := snr + g.cfg.Noise.SNR*g.normal(t, prn, "S"+typ[1:])
+			phase := rinex.Obs{
+				Val: (rng+clock-ionoDelay+trop)/lambda + g.ambiguity(prn, typ) + dist.slip + g.cfg.Noise.Phase*noise,
+				SNR: snrIndicator(sig),
+			}
+			if dist.lli {
+				phase.LLI = 1
+			}
+			obs[typ] = phase
+		case 'D':
+			obs[typ] = rinex.Obs{Val: -rangeRate / lambda}
+		case 'S':
+			obs[typ] = rinex.Obs{Val: snr + g.cfg.Noise.SNR*noise}
+		}
+	}
+	return rinex.NewSatObs(prn, obs), true
+}
+
+// satRange returns the range at the GPS time t between the station and the satellite, including the
+// satellite clock offset, and the satellite position at the transmission time in the earth-fixed frame
+// of t.
+func (g *Generator) satRange(eph *rinex.EphGPS, t time.Time) (float64, rinex.Coord) {
+	rcv := g.cfg.Station.Position
+	var sat rinex.Coord
+	tau := 0.075
+	for i := 0; i < 3; i++ {
+		pos := eph.Position(t.Add(-seconds(tau)))
+
+		// earth rotation during the signal travel time
+		sinR, cosR := math.Sincos(omegaEarth * tau)
+		sat = rinex.Coord{X: cosR*pos.X + sinR*pos.Y, Y: -sinR*pos.X + cosR*pos.Y, Z: pos.Z}
+		tau = rcv.Distance(sat) / gnss.SpeedOfLight
+	}
+	dts := eph.ClockOffset(t.Add(-seconds(tau)))
+	return rcv.Distance(sat) - gnss.SpeedOfLight*dts, sat
+}
+
+// ambiguity returns the integer phase ambiguity of the satellite and observation type.
+func (g *Generator) ambiguity(prn rinex.PRN, typ string) float64 {
+	return math.Floor(2e6*g.uniform(0, prn, "ambiguity "+typ)) - 1e6
+}
+
+// snrIndicator maps the signal strength in dBHz to the RINEX signal strength indicator 1-9.
+func snrIndicator(dbhz float64) int8 {
+	snr := int8(dbhz / 6)
+	if snr < 1 {
+		return 1
+	}
+	if snr > 9 {
+		return 9
+	}
+	return snr
+}
+
+// uniform returns a number in [0,1) derived from the seed, the time in nanoseconds, the satellite and the label.
+func (g *Generator) uniform(t int64, prn rinex.PRN, label string) float64 {
+	h := mix(uint64(g.cfg.Seed))
+	h = mix(h ^ uint64(t))
+	h = mix(h ^ uint64(prn.Sys)<<8 ^ uint64(prn.Num))
+	for i := 0; i < len(label); i++ {
+		h = mix(h ^ uint64(label[i]))
+	}
+	return float64(h>>11) / (1 << 53)
+}
+
+// normal returns a standard normally distributed number derived from the seed, the time, the satellite
+// and the label, by the Box-Muller transform.
+func (g *Generator) normal(t time.Time, prn rinex.PRN, label string) float64 {
+	u1 := g.uniform(t.UnixNano(), prn, label)
+	u2 := g.uniform(t.UnixNano(), prn, label+"'")
+	return math.Sqrt(-2*math.Log(1-u1)) * math.Cos(2*math.Pi*u2)
+}
+
+// mix is the finalizer of the SplitMix64 generator.
+func mix(h uint64) uint64 {
+	h += 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,167 @@
+package synth
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/spp"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2020, 6, 17, 0, 0, 0, 0, time.UTC)
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	g, err := New(Config{Start: start})
+	if !assert.NoError(err) {
+		return
+	}
+	cfg := g.Config()
+	assert.Equal(DefaultStation, cfg.Station)
+	assert.Equal(DefaultInterval, cfg.Interval)
+	assert.Equal(120, g.Epochs())
+	assert.Len(g.Satellites(), 48)
+
+	for _, cfg := range []Config{
+		{},
+		{Start: start, Station: Station{Name: "WTZR"}},
+		{Start: start, Station: Station{Name: "WTZR00DEU"}},
+		{Start: start, Signals: map[gnss.System][]string{gnss.SysGLO: {"C1C"}}},
+		{Start: start, Signals: map[gnss.System][]string{gnss.SysGPS: {"C1C", "L3X"}}},
+		{Start: start, Signals: map[gnss.System][]string{gnss.SysGPS: {"X1C"}}},
+		{Start: start, Interval: -time.Second},
+	} {
+		_, err := New(cfg)
+		assert.Error(err, "%+v", cfg)
+	}
+}
+
+func TestGenerator_deterministic(t *testing.T) {
+	assert := assert.New(t)
+	cfg := Config{Start: start, Duration: 10 * time.Minute, Noise: DefaultNoise, Atmosphere: true, Seed: 42}
+	write := func(cfg Config) []byte {
+		g, err := New(cfg)
+		if !assert.NoError(err) {
+			return nil
+		}
+		var buf bytes.Buffer
+		assert.NoError(g.WriteObs(&buf))
+		return buf.Bytes()
+	}
+	data := write(cfg)
+	assert.Equal(data, write(cfg))
+	cfg.Seed = 43
+	assert.NotEqual(data, write(cfg))
+
+	// independent of the order of the epochs
+	g, _ := New(cfg)
+	t1 := start.Add(5 * time.Minute)
+	epo := g.Epoch(t1)
+	g.Epoch(start)
+	assert.Equal(epo, g.Epoch(t1))
+}
+
+func TestGenerator_Epoch(t *testing.T) {
+	assert := assert.New(t)
+	g, err := New(Config{Start: start, Signals: map[gnss.System][]string{gnss.SysGPS: {"C1C", "L1C", "S1C"}}})
+	if !assert.NoError(err) {
+		return
+	}
+	epo := g.Epoch(start)
+	assert.True(epo.NumSat >= 6, "satellites: %d", epo.NumSat)
+	assert.Equal(int(epo.NumSat), len(epo.ObsList))
+	for _, satObs := range epo.ObsList {
+		assert.Equal(gnss.SysGPS, satObs.Prn.Sys)
+		assert.Equal([]string{"C1C", "L1C", "S1C"}, satObs.Types)
+		code, _ := satObs.Get("C1C")
+		assert.True(code.Val > 19e6 && code.Val < 27e6, "%s: %.3f", satObs.Prn, code.Val)
+		phase, _ := satObs.Get("L1C")
+		assert.True(phase.SNR >= 4 && phase.SNR <= 8, "%s: %d", satObs.Prn, phase.SNR)
+	}
+}
+
+func TestGenerator_WriteFiles(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	for _, noise := range []Noise{{}, DefaultNoise} {
+		g, err := New(Config{Start: start, Noise: noise})
+		if !assert.NoError(err) {
+			return
+		}
+		obsPath, navPath, err := g.WriteFiles(dir)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal("SYNT00DEU_U_20201690000_01H_30S_MO.rnx", filepath.Base(obsPath))
+		assert.Equal("SYNT00DEU_U_20201690000_01H_MN.rnx", filepath.Base(navPath))
+
+		f, err := os.Open(navPath)
+		if !assert.NoError(err) {
+			return
+		}
+		dec, err := rinex.NewNavDecoder(f)
+		if !assert.NoError(err) {
+			return
+		}
+		ephs, err := rinex.NewEphemerides(dec)
+		f.Close()
+		assert.NoError(err)
+		assert.Equal(len(g.Ephemerides()), ephs.Len())
+
+		f, err = os.Open(obsPath)
+		if !assert.NoError(err) {
+			return
+		}
+		obsDec, err := rinex.NewObsDecoder(f)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal("SYNT", obsDec.Header.MarkerName)
+		solver := spp.NewSolver(ephs)
+		n := 0
+		for obsDec.NextEpoch() {
+			n++
+			sol, err := solver.Solve(obsDec.Epoch())
+			if !assert.NoError(err) {
+				break
+			}
+			maxDiff := 0.01
+			if noise.Code > 0 {
+				maxDiff = 5
+			}
+			diff := sol.Position.Distance(DefaultStation.Position)
+			assert.True(diff < maxDiff, "%s: %.3f m", sol.Time, diff)
+		}
+		f.Close()
+		assert.NoError(obsDec.Err())
+		assert.Equal(g.Epochs(), n)
+	}
+}
+
+func TestNavRecord(t *testing.T) {
+	assert := assert.New(t)
+	g, err := New(Config{Start: start})
+	if !assert.NoError(err) {
+		return
+	}
+	prn, _ := rinex.NewPRN(gnss.SysGPS, 5)
+	eph := g.Ephemeris(prn, start.Add(EphemerisInterval))
+	var got rinex.EphGPS
+	if assert.NoError(rinex.UnmarshalEph([]byte(navRecord(eph)), &got)) {
+		assert.Equal(eph.TOC, got.TOC)
+		assert.Equal(eph.Toe, got.Toe)
+		assert.InDelta(eph.M0, got.M0, 1e-11)
+		assert.InDelta(eph.Omega0, got.Omega0, 1e-11)
+		assert.InDelta(0, eph.Position(start).Distance(got.Position(start)), 1e-3)
+	}
+
+	// all ephemerides describe the same orbit
+	t1 := start.Add(90 * time.Minute)
+	assert.InDelta(0, eph.Position(t1).Distance(g.Ephemeris(prn, start).Position(t1)), 1e-3)
+	assert.InDelta(eph.ClockOffset(t1), g.Ephemeris(prn, start).ClockOffset(t1), 1e-15)
+}
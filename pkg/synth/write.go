@@ -0,0 +1,136 @@
+package synth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// WriteObs writes the observation file of the configured time span to w, without the epochs in gaps.
+func (g *Generator) WriteObs(w io.Writer) error {
+	enc := rinex.NewObsEncoder(w, g.ObsHeader())
+	for i, n := 0, g.Epochs(); i < n; i++ {
+		epo := g.Epoch(g.cfg.Start.Add(time.Duration(i) * g.cfg.Interval))
+		if epo == nil {
+			continue
+		}
+		if err := enc.Encode(epo); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+// WriteNav writes the navigation file with the Ephemerides to w.
+func (g *Generator) WriteNav(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	hdr := g.NavHeader()
+	if err := hdr.Write(bw); err != nil {
+		return err
+	}
+	for _, eph := range g.Ephemerides() {
+		if _, err := bw.WriteString(navRecord(eph)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFiles writes the observation and the navigation file into dir, named after the RINEX 3
+// convention, like SYNT00DEU_U_20201690000_01H_30S_MO.rnx and SYNT00DEU_U_20201690000_01H_MN.rnx.
+// It returns the paths of the files.
+func (g *Generator) WriteFiles(dir string) (obsPath, navPath string, err error) {
+	start := g.cfg.Start
+	prefix := fmt.Sprintf("%s_U_%s%03d%s_%s", strings.ToUpper(g.cfg.Station.Name), start.Format("2006"), start.YearDay(),
+		start.Format("1504"), periodCode(g.cfg.Duration))
+	navType := "MN"
+	if len(g.cfg.Signals) == 1 {
+		navType = g.prns[0].Sys.Abbr() + "N"
+	}
+	obsPath = filepath.Join(dir, fmt.Sprintf("%s_%s_MO.rnx", prefix, freqCode(g.cfg.Interval)))
+	navPath = filepath.Join(dir, fmt.Sprintf("%s_%s.rnx", prefix, navType))
+	if err := writeFile(obsPath, g.WriteObs); err != nil {
+		return "", "", err
+	}
+	if err := writeFile(navPath, g.WriteNav); err != nil {
+		return "", "", err
+	}
+	return obsPath, navPath, nil
+}
+
+// writeFile creates the file and writes it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// navRecord returns the RINEX 3 navigation record of the GPS or Galileo ephemeris.
+func navRecord(eph *rinex.EphGPS) string {
+	var sb strings.Builder
+	sb.WriteString(eph.PRN.String())
+	sb.WriteString(eph.TOC.Format(" 2006 01 02 15 04 05"))
+	writeNavValues(&sb, "", eph.ClockBias, eph.ClockDrift, eph.ClockDriftRate)
+	writeNavValues(&sb, "    ", eph.IODE, eph.Crs, eph.DeltaN, eph.M0)
+	writeNavValues(&sb, "    ", eph.Cuc, eph.Ecc, eph.Cus, eph.SqrtA)
+	writeNavValues(&sb, "    ", eph.Toe, eph.Cic, eph.Omega0, eph.Cis)
+	writeNavValues(&sb, "    ", eph.I0, eph.Crc, eph.Omega, eph.OmegaDot)
+	if eph.PRN.Sys == gnss.SysGAL {
+		// data sources I/NAV E1-B and E5b with the clock for E5b/E1, Galileo week aligned to the GPS week
+		writeNavValues(&sb, "    ", eph.IDOT, 517, eph.ToeWeek, 0)
+		writeNavValues(&sb, "    ", 3.12, eph.Health, eph.TGD, eph.TGD)
+		writeNavValues(&sb, "    ", eph.Tom)
+		return sb.String()
+	}
+	writeNavValues(&sb, "    ", eph.IDOT, eph.L2Codes, eph.ToeWeek, eph.L2PFlag)
+	writeNavValues(&sb, "    ", eph.URA, eph.Health, eph.TGD, eph.IODC)
+	writeNavValues(&sb, "    ", eph.Tom, eph.FitInterval)
+	return sb.String()
+}
+
+// writeNavValues writes a line of the values in the format D19.12 after the prefix.
+func writeNavValues(sb *strings.Builder, prefix string, vals ...float64) {
+	sb.WriteString(prefix)
+	for _, v := range vals {
+		fmt.Fprintf(sb, "%19.12E", v)
+	}
+	sb.WriteByte('\n')
+}
+
+// periodCode returns the RINEX 3 filename code of the file period, e.g. 15M or 01D.
+func periodCode(period time.Duration) string {
+	switch {
+	case period%(24*time.Hour) == 0:
+		return fmt.Sprintf("%02dD", int(period/(24*time.Hour)))
+	case period%time.Hour == 0:
+		return fmt.Sprintf("%02dH", int(period/time.Hour))
+	}
+	return fmt.Sprintf("%02dM", int(math.Ceil(period.Minutes())))
+}
+
+// freqCode returns the RINEX 3 filename code of the data frequency for the sampling interval, e.g. 30S or 05Z.
+func freqCode(interval time.Duration) string {
+	switch {
+	case interval < time.Second:
+		return fmt.Sprintf("%02dZ", int(math.Round(float64(time.Second)/float64(interval))))
+	case interval < time.Minute:
+		return fmt.Sprintf("%02dS", int(interval/time.Second))
+	case interval < time.Hour:
+		return fmt.Sprintf("%02dM", int(interval/time.Minute))
+	}
+	return fmt.Sprintf("%02dH", int(interval/time.Hour))
+}
@@ -0,0 +1,84 @@
+package tropo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// maxExtrapolation is the time span before the first and after the last record in which the
+// values of the first and last record are used.
+const maxExtrapolation = 30 * time.Minute
+
+// MetSeries is a time series of meteorological data, e.g. from a RINEX meteo file.
+// The pressure is used as measured, without a reduction from the sensor to the receiver height.
+type MetSeries struct {
+	Times []time.Time // ascending
+	Met   []Met
+}
+
+// NewMetSeries reads the records of a RINEX meteo file. The pressure PR and the temperature TD are
+// required, the humidity HR defaults to 50 %.
+func NewMetSeries(dec *rinex.MeteoDecoder) (*MetSeries, error) {
+	types := dec.Header.ObsTypes
+	if indexOf(types, "PR") < 0 || indexOf(types, "TD") < 0 {
+		return nil, errors.New("meteo file without pressure PR and temperature TD")
+	}
+	s := &MetSeries{}
+	for dec.NextRecord() {
+		rec := dec.Record()
+		met := Met{Humidity: defaultHumidity}
+		met.Pressure, _ = rec.Get("PR")
+		met.Temperature, _ = rec.Get("TD")
+		if hr, ok := rec.Get("HR"); ok && hr > 0 {
+			met.Humidity = hr
+		}
+		if met.Pressure <= 0 {
+			continue // missing
+		}
+		s.Times = append(s.Times, rec.Time)
+		s.Met = append(s.Met, met)
+	}
+	if err := dec.Err(); err != nil {
+		return nil, fmt.Errorf("read meteo records: %w", err)
+	}
+	if !sort.SliceIsSorted(s.Times, func(i, j int) bool { return s.Times[i].Before(s.Times[j]) }) {
+		return nil, errors.New("meteo records not in time order")
+	}
+	return s, nil
+}
+
+// At returns the meteorological data at t, interpolated linearly between the records.
+// It returns false if t is outside the series.
+func (s *MetSeries) At(t time.Time) (Met, bool) {
+	n := len(s.Times)
+	if n == 0 || t.Before(s.Times[0].Add(-maxExtrapolation)) || t.After(s.Times[n-1].Add(maxExtrapolation)) {
+		return Met{}, false
+	}
+	i := sort.Search(n, func(i int) bool { return !s.Times[i].Before(t) })
+	if i == 0 {
+		return s.Met[0], true
+	}
+	if i == n {
+		return s.Met[n-1], true
+	}
+	m0, m1 := s.Met[i-1], s.Met[i]
+	f := t.Sub(s.Times[i-1]).Seconds() / s.Times[i].Sub(s.Times[i-1]).Seconds()
+	return Met{
+		Pressure:    m0.Pressure + (m1.Pressure-m0.Pressure)*f,
+		Temperature: m0.Temperature + (m1.Temperature-m0.Temperature)*f,
+		Humidity:    m0.Humidity + (m1.Humidity-m0.Humidity)*f,
+	}, true
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package tropo
+
+import (
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// Coefficients of the Niell mapping functions (Niell 1996) at the latitudes 15, 30, 45, 60 and 75 degrees.
+var (
+	niellHydAvg = [3][5]float64{
+		{1.2769934e-3, 1.2683230e-3, 1.2465397e-3, 1.2196049e-3, 1.2045996e-3},
+		{2.9153695e-3, 2.9152299e-3, 2.9288445e-3, 2.9022565e-3, 2.9024912e-3},
+		{62.610505e-3, 62.837393e-3, 63.721774e-3, 63.824265e-3, 64.258455e-3},
+	}
+	niellHydAmp = [3][5]float64{
+		{0.0, 1.2709626e-5, 2.6523662e-5, 3.4000452e-5, 4.1202191e-5},
+		{0.0, 2.1414979e-5, 3.0160779e-5, 7.2562722e-5, 11.723375e-5},
+		{0.0, 9.0128400e-5, 4.3497037e-5, 84.795348e-5, 170.37206e-5},
+	}
+	niellWet = [3][5]float64{
+		{5.8021897e-4, 5.6794847e-4, 5.8118019e-4, 5.9727542e-4, 6.1641693e-4},
+		{1.4275268e-3, 1.5138625e-3, 1.4572752e-3, 1.5007428e-3, 1.7599082e-3},
+		{4.3472961e-2, 4.6729510e-2, 4.3908931e-2, 4.4626982e-2, 5.4736038e-2},
+	}
+	niellHeight = [3]float64{2.53e-5, 5.49e-3, 1.14e-3}
+)
+
+// Niell returns the hydrostatic and the wet mapping function of Niell for a receiver at pos and a satellite
+// at the elevation el in degrees, at the time t.
+func Niell(t time.Time, pos rinex.LatLonHeight, el float64) (hyd, wet float64) {
+	sinEl := math.Sin(deg2rad(el))
+
+	// seasonal variation, with a phase of half a year on the southern hemisphere
+	doy := float64(t.YearDay()) + float64(t.Hour())/24
+	phase := 2 * math.Pi * (doy - 28) / 365.25
+	if pos.Lat < 0 {
+		phase += math.Pi
+	}
+	cosPhase := math.Cos(phase)
+
+	lat := math.Abs(pos.Lat)
+	var h, w [3]float64
+	for i := range h {
+		h[i] = interpolateLat(niellHydAvg[i], lat) - interpolateLat(niellHydAmp[i], lat)*cosPhase
+		w[i] = interpolateLat(niellWet[i], lat)
+	}
+
+	// height correction of the hydrostatic mapping
+	dm := (1/sinEl - marini(sinEl, niellHeight[0], niellHeight[1], niellHeight[2])) * pos.Height / 1000
+	return marini(sinEl, h[0], h[1], h[2]) + dm, marini(sinEl, w[0], w[1], w[2])
+}
+
+// marini returns the continued fraction form of the mapping functions, normalized to 1 in the zenith.
+func marini(sinEl, a, b, c float64) float64 {
+	return (1 + a/(1+b/(1+c))) / (sinEl + a/(sinEl+b/(sinEl+c)))
+}
+
+// interpolateLat interpolates linearly the coefficients given at 15, 30, ..., 75 degrees at the absolute latitude lat.
+func interpolateLat(coeffs [5]float64, lat float64) float64 {
+	if lat <= 15 {
+		return coeffs[0]
+	}
+	if lat >= 75 {
+		return coeffs[4]
+	}
+	i := int(lat/15) - 1
+	frac := (lat - float64(i+1)*15) / 15
+	return coeffs[i] + (coeffs[i+1]-coeffs[i])*frac
+}
@@ -0,0 +1,74 @@
+// Package tropo provides tropospheric delay models: the zenith delays of Saastamoinen with measured
+// meteorological data or the standard atmosphere, and the Niell mapping functions.
+//
+// The Saastamoinen type can be used as troposphere model of the SPP solver and for residual analysis.
+package tropo
+
+import (
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// defaultHumidity is the relative humidity in % of the standard atmosphere.
+const defaultHumidity = 50.0
+
+// Met contains meteorological data at the receiver.
+type Met struct {
+	Pressure    float64 // total pressure in hPa
+	Temperature float64 // temperature in degrees Celsius
+	Humidity    float64 // relative humidity in %
+}
+
+// StandardAtmosphere returns the meteorological data of the standard atmosphere at the ellipsoidal
+// height in meters, with a relative humidity of 50 %.
+func StandardAtmosphere(height float64) Met {
+	return Met{
+		Pressure:    1013.25 * math.Pow(1-2.2557e-5*height, 5.2568),
+		Temperature: 15 - 6.5e-3*height,
+		Humidity:    defaultHumidity,
+	}
+}
+
+// WaterVaporPressure returns the partial pressure of the water vapor in hPa, according to the Magnus formula.
+func (met Met) WaterVaporPressure() float64 {
+	return met.Humidity / 100 * 6.1078 * math.Exp(17.27*met.Temperature/(met.Temperature+237.3))
+}
+
+// ZenithDelay returns the hydrostatic and the wet zenith delay in meters according to Saastamoinen,
+// the hydrostatic delay in the formulation of Davis et al. (1985).
+func ZenithDelay(pos rinex.LatLonHeight, met Met) (hyd, wet float64) {
+	f := 1 - 0.00266*math.Cos(2*deg2rad(pos.Lat)) - 0.28e-6*pos.Height
+	hyd = 0.0022768 * met.Pressure / f
+	wet = 0.002277 * (1255/(met.Temperature+273.15) + 0.05) * met.WaterVaporPressure()
+	return
+}
+
+// Saastamoinen is the tropospheric delay model with the zenith delays of Saastamoinen and the Niell mapping
+// functions. It uses the meteorological data of Met, or the standard atmosphere at the receiver height
+// if Met is nil or has no data at the time.
+type Saastamoinen struct {
+	Met *MetSeries
+}
+
+// Delay returns the slant tropospheric delay in meters for a receiver at pos and a satellite
+// at the elevation el in degrees, at the time t. It returns 0 for satellites below the horizon.
+func (s *Saastamoinen) Delay(t time.Time, pos rinex.LatLonHeight, el float64) float64 {
+	if el <= 0 {
+		return 0
+	}
+	met := StandardAtmosphere(pos.Height)
+	if s.Met != nil {
+		if m, ok := s.Met.At(t); ok {
+			met = m
+		}
+	}
+	zhd, zwd := ZenithDelay(pos, met)
+	mfh, mfw := Niell(t, pos, el)
+	return zhd*mfh + zwd*mfw
+}
+
+func deg2rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,93 @@
+package tropo
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/de-bkg/gognss/pkg/spp"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ spp.TropoModel = (*Saastamoinen)(nil)
+
+func TestZenithDelay(t *testing.T) {
+	assert := assert.New(t)
+	met := StandardAtmosphere(0)
+	assert.Equal(Met{Pressure: 1013.25, Temperature: 15, Humidity: 50}, met)
+
+	hyd, wet := ZenithDelay(rinex.LatLonHeight{Lat: 45}, met)
+	assert.InDelta(2.3070, hyd, 1e-4)
+	assert.InDelta(0.0855, wet, 1e-3)
+
+	hyd0, _ := ZenithDelay(rinex.LatLonHeight{Lat: 0}, met)
+	assert.Greater(hyd0, hyd, "equator")
+
+	high := StandardAtmosphere(2000)
+	assert.InDelta(795, high.Pressure, 1)
+	assert.InDelta(2, high.Temperature, 1e-9)
+	hyd, _ = ZenithDelay(rinex.LatLonHeight{Lat: 45, Height: 2000}, high)
+	assert.InDelta(1.81, hyd, 0.01)
+}
+
+func TestNiell(t *testing.T) {
+	assert := assert.New(t)
+	ts := time.Date(2020, 6, 18, 0, 0, 0, 0, time.UTC)
+	pos := rinex.LatLonHeight{Lat: 45, Lon: 10}
+
+	hyd, wet := Niell(ts, pos, 90)
+	assert.InDelta(1, hyd, 1e-9, "zenith")
+	assert.InDelta(1, wet, 1e-9, "zenith")
+
+	hyd, wet = Niell(ts, pos, 5)
+	assert.InDelta(10.15, hyd, 0.1)
+	assert.InDelta(10.75, wet, 0.2)
+	hyd30, _ := Niell(ts, pos, 30)
+	assert.InDelta(2.0, hyd30, 0.01, "about 1/sin(el)")
+
+	south, _ := Niell(ts, rinex.LatLonHeight{Lat: -45, Lon: 10}, 5)
+	assert.NotEqual(hyd, south, "seasons")
+
+	assert.Equal(niellWet[0][0], interpolateLat(niellWet[0], 10))
+	assert.Equal(niellWet[0][4], interpolateLat(niellWet[0], 80))
+	assert.InDelta((niellWet[0][1]+niellWet[0][2])/2, interpolateLat(niellWet[0], 37.5), 1e-15)
+}
+
+func TestSaastamoinen(t *testing.T) {
+	assert := assert.New(t)
+	r, err := os.Open("../rinex/testdata/white/DIEP00DEU_R_20202941900_01H_10S_MM.rnx")
+	assert.NoError(err)
+	defer r.Close()
+	dec, err := rinex.NewMeteoDecoder(r)
+	assert.NoError(err)
+	series, err := NewMetSeries(dec)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(series.Times, 360)
+
+	t0 := time.Date(2020, 10, 20, 19, 0, 2, 0, time.UTC)
+	met, ok := series.At(t0)
+	assert.True(ok)
+	assert.Equal(Met{Pressure: 1004.7, Temperature: 12.8, Humidity: 88.1}, met)
+	met, ok = series.At(t0.Add(-10 * time.Minute))
+	assert.True(ok, "extrapolated")
+	assert.Equal(1004.7, met.Pressure)
+	_, ok = series.At(t0.Add(-time.Hour))
+	assert.False(ok)
+	met, ok = series.At(t0.Add(5 * time.Second))
+	assert.True(ok)
+	assert.InDelta(1004.7, met.Pressure, 0.1, "interpolated")
+
+	pos := rinex.LatLonHeight{Lat: 52.6, Lon: 8.4, Height: 81}
+	std := &Saastamoinen{}
+	measured := &Saastamoinen{Met: series}
+	zenith := measured.Delay(t0, pos, 90)
+	assert.InDelta(2.40, zenith, 0.05)
+	assert.NotEqual(std.Delay(t0, pos, 90), zenith)
+	assert.InDelta(std.Delay(t0.Add(5*time.Hour), pos, 90), measured.Delay(t0.Add(5*time.Hour), pos, 90), 1e-9,
+		"standard atmosphere outside the series")
+	assert.InDelta(2*zenith, measured.Delay(t0, pos, 30), 0.05)
+	assert.Equal(0.0, measured.Delay(t0, pos, -1))
+}
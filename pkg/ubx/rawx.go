@@ -0,0 +1,196 @@
+package ubx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// gpsEpoch is the start of the GPS time.
+var gpsEpoch = time.Date(1980, 1, 6, 0, 0, 0, 0, time.UTC)
+
+// GNSS identifiers used by u-blox.
+const (
+	gnssIDGPS   = 0
+	gnssIDSBAS  = 1
+	gnssIDGAL   = 2
+	gnssIDBDS   = 3
+	gnssIDQZSS  = 5
+	gnssIDGLO   = 6
+	gnssIDNavIC = 7
+)
+
+// Tracking status bits of RXM-RAWX.
+const (
+	trkPrValid    = 0x01 // pseudorange valid
+	trkCpValid    = 0x02 // carrier phase valid
+	trkHalfCyc    = 0x04 // half cycle valid
+	trkSubHalfCyc = 0x08 // half cycle subtracted from phase
+)
+
+// RawMeas is a single measurement of RXM-RAWX.
+type RawMeas struct {
+	Pseudorange  float64 // meters
+	CarrierPhase float64 // cycles
+	Doppler      float32 // Hz
+	GnssID       uint8
+	SvID         uint8
+	SigID        uint8
+	FreqID       uint8  // GLONASS frequency slot + 7
+	Locktime     uint16 // carrier phase locktime counter in ms
+	CNo          uint8  // carrier-to-noise density ratio in dBHz
+	PrStdev      uint8
+	CpStdev      uint8
+	DoStdev      uint8
+	TrkStat      uint8
+}
+
+// RAWX is the multi-GNSS raw measurement message UBX-RXM-RAWX.
+type RAWX struct {
+	RcvTow  float64 // receiver time of week in seconds
+	Week    uint16  // GPS week number
+	LeapS   int8    // GPS leap seconds
+	RecStat uint8
+	Version uint8
+	Meas    []RawMeas
+}
+
+// ClassID returns the class and ID of the message.
+func (m *RAWX) ClassID() (byte, byte) { return ClassRXM, IDRXMRAWX }
+
+// Time returns the receiver time of the measurements in GPS time.
+func (m *RAWX) Time() time.Time {
+	sec, frac := math.Modf(m.RcvTow)
+	return gpsEpoch.AddDate(0, 0, int(m.Week)*7).Add(time.Duration(sec)*time.Second + time.Duration(math.Round(frac*1e9)))
+}
+
+func decodeRAWX(payload []byte) (*RAWX, error) {
+	if len(payload) < 16 {
+		return nil, fmt.Errorf("RXM-RAWX: invalid length %d", len(payload))
+	}
+	m := &RAWX{
+		RcvTow:  math.Float64frombits(binary.LittleEndian.Uint64(payload[0:])),
+		Week:    binary.LittleEndian.Uint16(payload[8:]),
+		LeapS:   int8(payload[10]),
+		RecStat: payload[12],
+		Version: payload[13],
+	}
+	numMeas := int(payload[11])
+	if len(payload) != 16+32*numMeas {
+		return nil, fmt.Errorf("RXM-RAWX: invalid length %d for %d measurements", len(payload), numMeas)
+	}
+	m.Meas = make([]RawMeas, 0, numMeas)
+	for i := 0; i < numMeas; i++ {
+		p := payload[16+32*i:]
+		m.Meas = append(m.Meas, RawMeas{
+			Pseudorange:  math.Float64frombits(binary.LittleEndian.Uint64(p[0:])),
+			CarrierPhase: math.Float64frombits(binary.LittleEndian.Uint64(p[8:])),
+			Doppler:      math.Float32frombits(binary.LittleEndian.Uint32(p[16:])),
+			GnssID:       p[20],
+			SvID:         p[21],
+			SigID:        p[22],
+			FreqID:       p[23],
+			Locktime:     binary.LittleEndian.Uint16(p[24:]),
+			CNo:          p[26],
+			PrStdev:      p[27],
+			CpStdev:      p[28],
+			DoStdev:      p[29],
+			TrkStat:      p[30],
+		})
+	}
+	return m, nil
+}
+
+// sigCodes maps the u-blox gnssId and sigId to the RINEX 3 frequency band and attribute.
+var sigCodes = map[uint8]map[uint8]string{
+	gnssIDGPS:   {0: "1C", 3: "2L", 4: "2S", 6: "5I", 7: "5Q"},
+	gnssIDSBAS:  {0: "1C"},
+	gnssIDGAL:   {0: "1C", 1: "1B", 3: "5I", 4: "5Q", 5: "7I", 6: "7Q"},
+	gnssIDBDS:   {0: "2I", 1: "2I", 2: "7I", 3: "7I", 5: "1P", 7: "5P"},
+	gnssIDQZSS:  {0: "1C", 1: "1Z", 4: "2S", 5: "2L", 8: "5I", 9: "5Q"},
+	gnssIDGLO:   {0: "1C", 2: "2C"},
+	gnssIDNavIC: {0: "5A"},
+}
+
+// prn returns the satellite for the u-blox gnssId and svId.
+func prn(gnssID, svID uint8) (rinex.PRN, bool) {
+	var sys gnss.System
+	switch gnssID {
+	case gnssIDGPS:
+		sys = gnss.SysGPS
+	case gnssIDSBAS:
+		sys = gnss.SysSBAS
+	case gnssIDGAL:
+		sys = gnss.SysGAL
+	case gnssIDBDS:
+		sys = gnss.SysBDS
+	case gnssIDQZSS:
+		sys = gnss.SysQZSS
+	case gnssIDGLO:
+		sys = gnss.SysGLO
+	case gnssIDNavIC:
+		sys = gnss.SysIRNSS
+	default:
+		return rinex.PRN{}, false
+	}
+	p, err := rinex.NewPRN(sys, int(svID))
+	return p, err == nil
+}
+
+// Epoch converts the measurements into a RINEX epoch. The observation codes are the RINEX 3 codes,
+// e.g. C1C, L1C, D1C and S1C. Measurements of unknown signals are skipped, as well as invalid
+// pseudoranges and phases. A phase with unresolved half-cycle ambiguity gets the LLI bit 1,
+// a zero locktime the LLI bit 0.
+func (m *RAWX) Epoch() *rinex.Epoch {
+	epo := &rinex.Epoch{Time: m.Time(), Flag: rinex.EpochFlagOK}
+	satIdx := make(map[rinex.PRN]int, len(m.Meas))
+	for _, meas := range m.Meas {
+		sat, ok := prn(meas.GnssID, meas.SvID)
+		if !ok {
+			continue
+		}
+		code, ok := sigCodes[meas.GnssID][meas.SigID]
+		if !ok {
+			continue
+		}
+
+		i, ok := satIdx[sat]
+		if !ok {
+			i = len(epo.ObsList)
+			satIdx[sat] = i
+			epo.ObsList = append(epo.ObsList, rinex.SatObs{Prn: sat})
+		}
+		satObs := &epo.ObsList[i]
+
+		if meas.TrkStat&trkPrValid != 0 {
+			satObs.Set("C"+code, rinex.Obs{Val: meas.Pseudorange})
+		}
+		if meas.TrkStat&trkCpValid != 0 {
+			var lli int8
+			if meas.Locktime == 0 {
+				lli |= 0x01
+			}
+			if meas.TrkStat&trkHalfCyc == 0 {
+				lli |= 0x02
+			}
+			satObs.Set("L"+code, rinex.Obs{Val: meas.CarrierPhase, LLI: lli})
+		}
+		satObs.Set("D"+code, rinex.Obs{Val: float64(meas.Doppler)})
+		satObs.Set("S"+code, rinex.Obs{Val: float64(meas.CNo)})
+	}
+
+	sort.Slice(epo.ObsList, func(i, j int) bool {
+		pi, pj := epo.ObsList[i].Prn, epo.ObsList[j].Prn
+		if pi.Sys != pj.Sys {
+			return pi.Sys < pj.Sys
+		}
+		return pi.Num < pj.Num
+	})
+	epo.NumSat = uint8(len(epo.ObsList))
+	return epo
+}
@@ -0,0 +1,217 @@
+package ubx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/rinex"
+)
+
+// gpsPi is the value of pi used in the GPS interface specification.
+const gpsPi = 3.1415926535898
+
+// uraMeters maps the GPS URA index to the accuracy in meters, see IS-GPS-200 20.3.3.3.1.3.
+var uraMeters = [16]float64{2.4, 3.4, 4.85, 6.85, 9.65, 13.65, 24, 48, 96, 192, 384, 768, 1536, 3072, 6144, 0}
+
+// SFRBX is the broadcast navigation data subframe message UBX-RXM-SFRBX.
+type SFRBX struct {
+	GnssID  uint8
+	SvID    uint8
+	SigID   uint8
+	FreqID  uint8
+	Chn     uint8
+	Version uint8
+	Words   []uint32 // the data words
+}
+
+// ClassID returns the class and ID of the message.
+func (m *SFRBX) ClassID() (byte, byte) { return ClassRXM, IDRXMSFRBX }
+
+func decodeSFRBX(payload []byte) (*SFRBX, error) {
+	if len(payload) < 8 {
+		return nil, fmt.Errorf("RXM-SFRBX: invalid length %d", len(payload))
+	}
+	numWords := int(payload[4])
+	if len(payload) != 8+4*numWords {
+		return nil, fmt.Errorf("RXM-SFRBX: invalid length %d for %d words", len(payload), numWords)
+	}
+	m := &SFRBX{
+		GnssID:  payload[0],
+		SvID:    payload[1],
+		SigID:   payload[2],
+		FreqID:  payload[3],
+		Chn:     payload[5],
+		Version: payload[6],
+		Words:   make([]uint32, numWords),
+	}
+	for i := range m.Words {
+		m.Words[i] = binary.LittleEndian.Uint32(payload[8+4*i:])
+	}
+	return m, nil
+}
+
+// EphAssembler assembles ephemerides from the navigation data subframes. Currently only GPS LNAV is supported.
+type EphAssembler struct {
+	// Week is the full GPS week used to resolve the 10-bit week number of the navigation message.
+	// It should be set from the receiver time, e.g. RXM-RAWX. If zero, the week is assumed to be
+	// after the rollover in April 2019.
+	Week int
+
+	subframes map[rinex.PRN]*[3][]byte // subframes 1-3, 30 bytes each without parity
+}
+
+// NewEphAssembler returns a new EphAssembler.
+func NewEphAssembler() *EphAssembler {
+	return &EphAssembler{subframes: make(map[rinex.PRN]*[3][]byte)}
+}
+
+// Add adds a subframe. It returns the ephemeris if the subframe completes a consistent set
+// of subframes 1-3.
+func (a *EphAssembler) Add(m *SFRBX) (*rinex.EphGPS, bool) {
+	if m.GnssID != gnssIDGPS || len(m.Words) != 10 {
+		return nil, false
+	}
+	sat, ok := prn(m.GnssID, m.SvID)
+	if !ok {
+		return nil, false
+	}
+
+	// The 30-bit words are right-aligned, strip the 6 parity bits.
+	buf := make([]byte, 30)
+	for i, w := range m.Words {
+		setBits(buf, 24*i, 24, uint64(w>>6&0xFFFFFF))
+	}
+	id := getBits(buf, 43, 3)
+	if id < 1 || id > 3 {
+		return nil, false
+	}
+
+	sfs, ok := a.subframes[sat]
+	if !ok {
+		sfs = &[3][]byte{}
+		a.subframes[sat] = sfs
+	}
+	sfs[id-1] = buf
+	if sfs[0] == nil || sfs[1] == nil || sfs[2] == nil {
+		return nil, false
+	}
+
+	// IODE of subframes 2 and 3 and the 8 LSBs of the IODC must match.
+	iodc := getBits(sfs[0], 168, 8)
+	if getBits(sfs[1], 48, 8) != iodc || getBits(sfs[2], 216, 8) != iodc {
+		return nil, false
+	}
+	eph := a.decodeEph(sat, sfs)
+	*sfs = [3][]byte{}
+	return eph, true
+}
+
+// decodeEph decodes the GPS LNAV subframes 1-3, see IS-GPS-200 20.3.3.
+func (a *EphAssembler) decodeEph(sat rinex.PRN, sfs *[3][]byte) *rinex.EphGPS {
+	sf1, sf2, sf3 := sfs[0], sfs[1], sfs[2]
+	eph := &rinex.EphGPS{PRN: sat}
+
+	// subframe 1
+	week := a.fullWeek(int(getBits(sf1, 48, 10)))
+	eph.L2Codes = float64(getBits(sf1, 58, 2))
+	eph.URA = uraMeters[getBits(sf1, 60, 4)]
+	eph.Health = float64(getBits(sf1, 64, 6))
+	eph.IODC = float64(getBits(sf1, 70, 2)<<8 | getBits(sf1, 168, 8))
+	eph.L2PFlag = float64(getBits(sf1, 72, 1))
+	eph.TGD = float64(getSignedBits(sf1, 160, 8)) * math.Pow(2, -31)
+	toc := float64(getBits(sf1, 176, 16)) * 16
+	eph.ClockDriftRate = float64(getSignedBits(sf1, 192, 8)) * math.Pow(2, -55)
+	eph.ClockDrift = float64(getSignedBits(sf1, 200, 16)) * math.Pow(2, -43)
+	eph.ClockBias = float64(getSignedBits(sf1, 216, 22)) * math.Pow(2, -31)
+	tow := float64(getBits(sf1, 24, 17)) * 6
+	eph.Tom = tow - 6
+	if eph.Tom < 0 {
+		eph.Tom += 604800
+	}
+
+	// subframe 2
+	eph.IODE = float64(getBits(sf2, 48, 8))
+	eph.Crs = float64(getSignedBits(sf2, 56, 16)) * math.Pow(2, -5)
+	eph.DeltaN = float64(getSignedBits(sf2, 72, 16)) * math.Pow(2, -43) * gpsPi
+	eph.M0 = float64(getSignedBits(sf2, 88, 32)) * math.Pow(2, -31) * gpsPi
+	eph.Cuc = float64(getSignedBits(sf2, 120, 16)) * math.Pow(2, -29)
+	eph.Ecc = float64(getBits(sf2, 136, 32)) * math.Pow(2, -33)
+	eph.Cus = float64(getSignedBits(sf2, 168, 16)) * math.Pow(2, -29)
+	eph.SqrtA = float64(getBits(sf2, 184, 32)) * math.Pow(2, -19)
+	eph.Toe = float64(getBits(sf2, 216, 16)) * 16
+	eph.FitInterval = 4
+	if getBits(sf2, 232, 1) == 1 {
+		eph.FitInterval = 6
+	}
+
+	// subframe 3
+	eph.Cic = float64(getSignedBits(sf3, 48, 16)) * math.Pow(2, -29)
+	eph.Omega0 = float64(getSignedBits(sf3, 64, 32)) * math.Pow(2, -31) * gpsPi
+	eph.Cis = float64(getSignedBits(sf3, 96, 16)) * math.Pow(2, -29)
+	eph.I0 = float64(getSignedBits(sf3, 112, 32)) * math.Pow(2, -31) * gpsPi
+	eph.Crc = float64(getSignedBits(sf3, 144, 16)) * math.Pow(2, -5)
+	eph.Omega = float64(getSignedBits(sf3, 160, 32)) * math.Pow(2, -31) * gpsPi
+	eph.OmegaDot = float64(getSignedBits(sf3, 192, 24)) * math.Pow(2, -43) * gpsPi
+	eph.IDOT = float64(getSignedBits(sf3, 224, 14)) * math.Pow(2, -43) * gpsPi
+
+	// The week refers to the transmission time, adjust for a week change between toe and tom.
+	toeWeek := week
+	if eph.Toe-eph.Tom > 302400 {
+		toeWeek--
+	} else if eph.Toe-eph.Tom < -302400 {
+		toeWeek++
+	}
+	eph.ToeWeek = float64(toeWeek)
+	tocWeek := week
+	if toc-eph.Tom > 302400 {
+		tocWeek--
+	} else if toc-eph.Tom < -302400 {
+		tocWeek++
+	}
+	eph.TOC = gpsEpoch.AddDate(0, 0, tocWeek*7).Add(time.Duration(toc) * time.Second)
+	return eph
+}
+
+// fullWeek resolves the 10-bit week number.
+func (a *EphAssembler) fullWeek(week10 int) int {
+	ref := a.Week
+	if ref == 0 {
+		ref = 2048
+	}
+	week := ref - ref%1024 + week10
+	if week < ref-512 {
+		week += 1024
+	} else if week > ref+512 {
+		week -= 1024
+	}
+	return week
+}
+
+// getBits returns the unsigned value of n bits starting at bit pos, MSB first.
+func getBits(buf []byte, pos, n int) uint64 {
+	var v uint64
+	for i := pos; i < pos+n; i++ {
+		v = v<<1 | uint64(buf[i/8]>>(7-i%8)&1)
+	}
+	return v
+}
+
+// getSignedBits returns the two's complement value of n bits starting at bit pos.
+func getSignedBits(buf []byte, pos, n int) int64 {
+	v := getBits(buf, pos, n)
+	if v&(1<<(n-1)) != 0 {
+		return int64(v) - 1<<n
+	}
+	return int64(v)
+}
+
+// setBits sets n bits starting at bit pos to the value v.
+func setBits(buf []byte, pos, n int, v uint64) {
+	for i := 0; i < n; i++ {
+		bit := byte(v >> (n - 1 - i) & 1)
+		j := pos + i
+		buf[j/8] = buf[j/8]&^(1<<(7-j%8)) | bit<<(7-j%8)
+	}
+}
@@ -0,0 +1,167 @@
+// Package ubx provides functions for decoding the u-blox UBX binary protocol.
+//
+// The raw measurements UBX-RXM-RAWX are decoded into RINEX epochs and the broadcast navigation data
+// UBX-RXM-SFRBX into ephemerides, so that streams of low-cost receivers can be converted to RINEX.
+// Currently only the GPS LNAV ephemerides are assembled.
+package ubx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	sync1 = 0xB5
+	sync2 = 0x62
+
+	// maxPayload limits the payload length, to avoid reading garbage after a false sync.
+	maxPayload = 8192
+)
+
+// Message classes and IDs.
+const (
+	ClassRXM     = 0x02
+	IDRXMSFRBX   = 0x13
+	IDRXMRAWX    = 0x15
+	classIDRAWX  = ClassRXM<<8 | IDRXMRAWX
+	classIDSFRBX = ClassRXM<<8 | IDRXMSFRBX
+)
+
+// Message is a decoded UBX message.
+type Message interface {
+	// ClassID returns the class and ID of the message.
+	ClassID() (class, id byte)
+}
+
+// Unknown is a message not decoded by this package.
+type Unknown struct {
+	Class, ID byte
+	Payload   []byte
+}
+
+// ClassID returns the class and ID of the message.
+func (m *Unknown) ClassID() (byte, byte) { return m.Class, m.ID }
+
+// Decoder reads and decodes UBX messages from an input stream. Non-UBX data like NMEA sentences
+// and frames with invalid checksums are skipped.
+type Decoder struct {
+	r   *bufio.Reader
+	msg Message
+	err error
+
+	// NumChecksumErrors counts the frames skipped due to invalid checksums.
+	NumChecksumErrors int
+}
+
+// NewDecoder creates a new decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Err returns the first non-EOF error that was encountered by the decoder.
+func (dec *Decoder) Err() error {
+	if dec.err == io.EOF || dec.err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return dec.err
+}
+
+// Message returns the most recent message generated by a call to Next.
+func (dec *Decoder) Message() Message {
+	return dec.msg
+}
+
+// Next reads the next UBX message.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (dec *Decoder) Next() bool {
+	for {
+		class, id, payload, err := dec.readFrame()
+		if err == errChecksum {
+			dec.NumChecksumErrors++
+			continue
+		}
+		if err != nil {
+			dec.err = err
+			return false
+		}
+
+		msg, err := decodeMessage(class, id, payload)
+		if err != nil {
+			dec.err = fmt.Errorf("decode message 0x%02x 0x%02x: %v", class, id, err)
+			return false
+		}
+		dec.msg = msg
+		return true
+	}
+}
+
+var errChecksum = fmt.Errorf("ubx: invalid checksum")
+
+// readFrame reads the next frame, synchronizing on the sync chars.
+func (dec *Decoder) readFrame() (class, id byte, payload []byte, err error) {
+	// sync
+	var prev byte
+	for {
+		b, err := dec.r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if prev == sync1 && b == sync2 {
+			break
+		}
+		prev = b
+	}
+
+	var hdr [4]byte
+	if _, err = io.ReadFull(dec.r, hdr[:]); err != nil {
+		return
+	}
+	length := int(binary.LittleEndian.Uint16(hdr[2:]))
+	if length > maxPayload {
+		return 0, 0, nil, errChecksum
+	}
+
+	buf := make([]byte, length+2)
+	if _, err = io.ReadFull(dec.r, buf); err != nil {
+		return
+	}
+	ckA, ckB := checksum(hdr[:], buf[:length])
+	if ckA != buf[length] || ckB != buf[length+1] {
+		return 0, 0, nil, errChecksum
+	}
+	return hdr[0], hdr[1], buf[:length], nil
+}
+
+// checksum computes the 8-bit Fletcher checksum over class, ID, length and payload.
+func checksum(hdr, payload []byte) (ckA, ckB byte) {
+	for _, data := range [][]byte{hdr, payload} {
+		for _, b := range data {
+			ckA += b
+			ckB += ckA
+		}
+	}
+	return
+}
+
+// Encode returns the UBX frame for the given message class, ID and payload.
+func Encode(class, id byte, payload []byte) []byte {
+	frame := make([]byte, 0, len(payload)+8)
+	frame = append(frame, sync1, sync2, class, id, byte(len(payload)), byte(len(payload)>>8))
+	frame = append(frame, payload...)
+	ckA, ckB := checksum(frame[2:6], payload)
+	return append(frame, ckA, ckB)
+}
+
+// decodeMessage decodes the payload.
+func decodeMessage(class, id byte, payload []byte) (Message, error) {
+	switch uint16(class)<<8 | uint16(id) {
+	case classIDRAWX:
+		return decodeRAWX(payload)
+	case classIDSFRBX:
+		return decodeSFRBX(payload)
+	default:
+		return &Unknown{Class: class, ID: id, Payload: payload}, nil
+	}
+}
@@ -0,0 +1,191 @@
+package ubx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/de-bkg/gognss/pkg/gnss"
+	"github.com/de-bkg/gognss/pkg/rinex"
+	"github.com/stretchr/testify/assert"
+)
+
+// rawxPayload builds an RXM-RAWX payload.
+func rawxPayload(tow float64, week uint16, meas []RawMeas) []byte {
+	p := make([]byte, 16+32*len(meas))
+	binary.LittleEndian.PutUint64(p[0:], math.Float64bits(tow))
+	binary.LittleEndian.PutUint16(p[8:], week)
+	p[10] = 18
+	p[11] = byte(len(meas))
+	p[13] = 1
+	for i, m := range meas {
+		q := p[16+32*i:]
+		binary.LittleEndian.PutUint64(q[0:], math.Float64bits(m.Pseudorange))
+		binary.LittleEndian.PutUint64(q[8:], math.Float64bits(m.CarrierPhase))
+		binary.LittleEndian.PutUint32(q[16:], math.Float32bits(m.Doppler))
+		q[20], q[21], q[22], q[23] = m.GnssID, m.SvID, m.SigID, m.FreqID
+		binary.LittleEndian.PutUint16(q[24:], m.Locktime)
+		q[26], q[30] = m.CNo, m.TrkStat
+	}
+	return p
+}
+
+func TestDecoder_RAWX(t *testing.T) {
+	assert := assert.New(t)
+	meas := []RawMeas{
+		{Pseudorange: 23456789.123, CarrierPhase: 123456789.25, Doppler: -1234.5, GnssID: gnssIDGAL, SvID: 11, SigID: 0, Locktime: 1000, CNo: 45, TrkStat: 0x07},
+		{Pseudorange: 21234567.891, CarrierPhase: 111222333.5, Doppler: 567.25, GnssID: gnssIDGPS, SvID: 5, SigID: 0, Locktime: 0, CNo: 38, TrkStat: 0x03},
+		{Pseudorange: 21234569.5, GnssID: gnssIDGPS, SvID: 5, SigID: 3, CNo: 30, TrkStat: 0x01},
+		{Pseudorange: 20000000, GnssID: gnssIDGPS, SvID: 7, SigID: 99, TrkStat: 0x01}, // unknown signal
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+	buf.Write(Encode(ClassRXM, IDRXMRAWX, rawxPayload(345600.5, 2100, meas)))
+	frame := Encode(0x01, 0x07, []byte{1, 2, 3})
+	frame[len(frame)-1]++ // corrupt checksum
+	buf.Write(frame)
+	buf.Write(Encode(0x01, 0x07, []byte{1, 2, 3}))
+
+	dec := NewDecoder(&buf)
+	assert.True(dec.Next())
+	rawx, ok := dec.Message().(*RAWX)
+	if !assert.True(ok) {
+		t.FailNow()
+	}
+	assert.Equal(uint16(2100), rawx.Week)
+	assert.Equal(int8(18), rawx.LeapS)
+	assert.Len(rawx.Meas, 4)
+
+	epo := rawx.Epoch()
+	assert.Equal(time.Date(2020, 4, 9, 0, 0, 0, 500000000, time.UTC), epo.Time)
+	assert.Equal(uint8(2), epo.NumSat)
+	assert.Equal(rinex.PRN{Sys: gnss.SysGPS, Num: 5}, epo.ObsList[0].Prn)
+	assert.Equal(rinex.PRN{Sys: gnss.SysGAL, Num: 11}, epo.ObsList[1].Prn)
+
+	get := func(satObs rinex.SatObs, typ string) rinex.Obs {
+		obs, _ := satObs.Get(typ)
+		return obs
+	}
+	g05 := epo.ObsList[0]
+	assert.Equal(21234567.891, get(g05, "C1C").Val)
+	assert.Equal(rinex.Obs{Val: 111222333.5, LLI: 3}, get(g05, "L1C"))
+	assert.Equal(567.25, get(g05, "D1C").Val)
+	assert.Equal(38.0, get(g05, "S1C").Val)
+	assert.Equal(21234569.5, get(g05, "C2L").Val)
+	_, ok = g05.Get("L2L")
+	assert.False(ok, "invalid phase")
+
+	e11 := epo.ObsList[1]
+	assert.Equal(rinex.Obs{Val: 123456789.25}, get(e11, "L1C"))
+	assert.Equal(-1234.5, get(e11, "D1C").Val)
+
+	assert.True(dec.Next())
+	unknown, ok := dec.Message().(*Unknown)
+	if assert.True(ok) {
+		assert.Equal(byte(0x01), unknown.Class)
+		assert.Equal([]byte{1, 2, 3}, unknown.Payload)
+	}
+	assert.Equal(1, dec.NumChecksumErrors)
+	assert.False(dec.Next())
+	assert.NoError(dec.Err())
+
+	dec = NewDecoder(bytes.NewReader(Encode(ClassRXM, IDRXMRAWX, make([]byte, 10))))
+	assert.False(dec.Next())
+	assert.Error(dec.Err())
+}
+
+// lnavSubframe builds the SFRBX words of a GPS LNAV subframe from its 240 data bits.
+func lnavSubframe(data []byte) []uint32 {
+	words := make([]uint32, 10)
+	for i := range words {
+		words[i] = uint32(getBits(data, 24*i, 24)) << 6
+	}
+	return words
+}
+
+func TestEphAssembler(t *testing.T) {
+	assert := assert.New(t)
+	sf1, sf2, sf3 := make([]byte, 30), make([]byte, 30), make([]byte, 30)
+	signed := func(v int64, n int) uint64 { return uint64(v) & (1<<n - 1) }
+
+	// HOW: TOW count and subframe ID
+	towCount := uint64(345612 / 6)
+	for i, sf := range [][]byte{sf1, sf2, sf3} {
+		setBits(sf, 24, 17, towCount+uint64(i))
+		setBits(sf, 43, 3, uint64(i+1))
+	}
+
+	setBits(sf1, 48, 10, 2100%1024)
+	setBits(sf1, 60, 4, 0) // URA 2.4 m
+	setBits(sf1, 70, 2, 1) // IODC MSB
+	setBits(sf1, 160, 8, signed(-12, 8))
+	setBits(sf1, 168, 8, 42) // IODC LSB
+	setBits(sf1, 176, 16, 352800/16)
+	setBits(sf1, 200, 16, signed(-100, 16))
+	setBits(sf1, 216, 22, signed(-123456, 22))
+
+	setBits(sf2, 48, 8, 42) // IODE
+	setBits(sf2, 56, 16, signed(-320, 16))
+	setBits(sf2, 88, 32, signed(-1000000000, 32))
+	setBits(sf2, 136, 32, 42949673) // e ~ 0.005
+	setBits(sf2, 184, 32, 2702238720)
+	setBits(sf2, 216, 16, 352800/16)
+
+	setBits(sf3, 64, 32, signed(123456789, 32))
+	setBits(sf3, 112, 32, 650000000)
+	setBits(sf3, 192, 24, signed(-2000, 24))
+	setBits(sf3, 216, 8, 42) // IODE
+	setBits(sf3, 224, 14, signed(-50, 14))
+
+	asm := NewEphAssembler()
+	asm.Week = 2100
+	_, ok := asm.Add(&SFRBX{GnssID: gnssIDGPS, SvID: 12, Words: lnavSubframe(sf1)})
+	assert.False(ok)
+	_, ok = asm.Add(&SFRBX{GnssID: gnssIDGPS, SvID: 12, Words: lnavSubframe(sf2)})
+	assert.False(ok)
+	_, ok = asm.Add(&SFRBX{GnssID: gnssIDGAL, SvID: 12, Words: lnavSubframe(sf3)})
+	assert.False(ok, "other system")
+	eph, ok := asm.Add(&SFRBX{GnssID: gnssIDGPS, SvID: 12, Words: lnavSubframe(sf3)})
+	if !assert.True(ok) {
+		t.FailNow()
+	}
+
+	assert.Equal(rinex.PRN{Sys: gnss.SysGPS, Num: 12}, eph.PRN)
+	assert.Equal(time.Date(2020, 4, 9, 2, 0, 0, 0, time.UTC), eph.TOC)
+	assert.Equal(2100.0, eph.ToeWeek)
+	assert.Equal(352800.0, eph.Toe)
+	assert.Equal(345606.0, eph.Tom)
+	assert.Equal(298.0, eph.IODC)
+	assert.Equal(42.0, eph.IODE)
+	assert.Equal(2.4, eph.URA)
+	assert.Equal(4.0, eph.FitInterval)
+	assert.Equal(-12*math.Pow(2, -31), eph.TGD)
+	assert.Equal(-123456*math.Pow(2, -31), eph.ClockBias)
+	assert.Equal(-100*math.Pow(2, -43), eph.ClockDrift)
+	assert.Equal(-10.0, eph.Crs)
+	assert.InDelta(-1000000000*math.Pow(2, -31)*math.Pi, eph.M0, 1e-12)
+	assert.InDelta(0.005, eph.Ecc, 1e-9)
+	assert.Equal(5154.111328125, eph.SqrtA)
+	assert.InDelta(-2000*math.Pow(2, -43)*math.Pi, eph.OmegaDot, 1e-20)
+	assert.InDelta(-50*math.Pow(2, -43)*math.Pi, eph.IDOT, 1e-20)
+
+	// IODE mismatch
+	setBits(sf3, 216, 8, 43)
+	asm.Add(&SFRBX{GnssID: gnssIDGPS, SvID: 12, Words: lnavSubframe(sf1)})
+	asm.Add(&SFRBX{GnssID: gnssIDGPS, SvID: 12, Words: lnavSubframe(sf2)})
+	_, ok = asm.Add(&SFRBX{GnssID: gnssIDGPS, SvID: 12, Words: lnavSubframe(sf3)})
+	assert.False(ok)
+}
+
+func TestEphAssembler_fullWeek(t *testing.T) {
+	assert := assert.New(t)
+	asm := NewEphAssembler()
+	assert.Equal(2100, asm.fullWeek(2100%1024))
+	asm.Week = 1500
+	assert.Equal(1500, asm.fullWeek(1500%1024))
+	asm.Week = 2049
+	assert.Equal(2047, asm.fullWeek(1023))
+}